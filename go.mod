@@ -9,6 +9,7 @@ require (
 	github.com/alecthomas/kong v0.2.18-0.20210927063154-5c7b038540ab
 	github.com/aymerick/raymond v2.0.2+incompatible
 	github.com/fatih/color v1.13.0
+	github.com/gdamore/tcell/v2 v2.4.0
 	github.com/go-testfixtures/testfixtures/v3 v3.4.1
 	github.com/google/go-cmp v0.5.6
 	github.com/gosimple/slug v1.10.0
@@ -16,6 +17,7 @@ require (
 	github.com/lestrrat-go/strftime v1.0.5
 	github.com/mattn/go-colorable v0.1.11 // indirect
 	github.com/mattn/go-isatty v0.0.14
+	github.com/mattn/go-runewidth v0.0.13
 	github.com/mattn/go-sqlite3 v1.14.8
 	github.com/mgutz/ansi v0.0.0-20200706080929-d51e80ef957d // indirect
 	github.com/mickael-menu/pretty v0.2.3
@@ -25,6 +27,7 @@ require (
 	github.com/rogpeppe/go-internal v1.6.2 // indirect
 	github.com/rvflash/elapsed v0.2.0
 	github.com/schollz/progressbar/v3 v3.8.3
+	github.com/sourcegraph/jsonrpc2 v0.1.0
 	github.com/tj/go-naturaldate v1.3.0
 	github.com/tliron/glsp v0.0.0-20210824162824-d103e5701036
 	github.com/tliron/kutil v0.1.49
@@ -35,4 +38,5 @@ require (
 	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211 // indirect
 	golang.org/x/text v0.3.7 // indirect
 	gopkg.in/djherbis/times.v1 v1.3.0
+	gopkg.in/yaml.v2 v2.4.0
 )
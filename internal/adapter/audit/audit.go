@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// FileLog implements the port core.AuditLog by appending JSON lines to a
+// file, e.g. `.zk/audit.log`.
+type FileLog struct {
+	path   string
+	logger util.Logger
+}
+
+// NewFileLog creates a new FileLog recording entries in the file at path.
+func NewFileLog(path string, logger util.Logger) *FileLog {
+	return &FileLog{
+		path:   path,
+		logger: logger,
+	}
+}
+
+// Append implements core.AuditLog.
+func (l *FileLog) Append(entry core.AuditEntry) error {
+	wrap := errors.Wrapper("failed to write to the audit log")
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return wrap(err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(l.path), os.ModePerm)
+	if err != nil {
+		return wrap(err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return wrap(err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return wrap(err)
+}
+
+// Entries implements core.AuditLog.
+func (l *FileLog) Entries() ([]core.AuditEntry, error) {
+	wrap := errors.Wrapper("failed to read the audit log")
+
+	entries := []core.AuditEntry{}
+
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	} else if err != nil {
+		return entries, wrap(err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry core.AuditEntry
+		err := json.Unmarshal(line, &entry)
+		if err != nil {
+			// A corrupted line should not prevent reading the rest of the log.
+			l.logger.Err(wrap(err))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, wrap(scanner.Err())
+}
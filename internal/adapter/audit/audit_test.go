@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestFileLogAppendAndRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zk-audit")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	log := NewFileLog(filepath.Join(dir, ".zk/audit.log"), &util.NullLogger)
+
+	entries, err := log.Entries()
+	assert.Nil(t, err)
+	assert.Equal(t, entries, []core.AuditEntry{})
+
+	date := time.Date(2021, 10, 5, 12, 43, 0, 0, time.UTC)
+	first := core.AuditEntry{
+		Time:      date,
+		Operation: core.AuditOperationCreate,
+		Interface: "cli",
+		Command:   "new",
+		Paths:     []string{"ref/7fd3.md"},
+	}
+	second := core.AuditEntry{
+		Time:      date.Add(time.Minute),
+		Operation: core.AuditOperationMove,
+		Interface: "lsp",
+		Command:   "zk.mv",
+		Paths:     []string{"ref/7fd3.md", "archive/7fd3.md"},
+	}
+	assert.Nil(t, log.Append(first))
+	assert.Nil(t, log.Append(second))
+
+	entries, err = log.Entries()
+	assert.Nil(t, err)
+	assert.Equal(t, entries, []core.AuditEntry{first, second})
+}
+
+func TestAuditEntryString(t *testing.T) {
+	entry := core.AuditEntry{
+		Time:      time.Date(2021, 10, 5, 12, 43, 0, 0, time.Local),
+		Operation: core.AuditOperationCreate,
+		Interface: "cli",
+		Command:   "new",
+		Paths:     []string{"ref/7fd3.md"},
+	}
+	assert.Equal(t, entry.String(), "2021-10-05 12:43:00 cli:new create ref/7fd3.md")
+}
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// IndexEventFileLog implements the port core.IndexEventLog by appending JSON
+// lines to a file, e.g. `.zk/events.log`.
+//
+// The positions in the log are byte offsets in the file.
+type IndexEventFileLog struct {
+	path   string
+	logger util.Logger
+}
+
+// NewIndexEventFileLog creates a new IndexEventFileLog recording events in
+// the file at path.
+func NewIndexEventFileLog(path string, logger util.Logger) *IndexEventFileLog {
+	return &IndexEventFileLog{
+		path:   path,
+		logger: logger,
+	}
+}
+
+// Append implements core.IndexEventLog.
+func (l *IndexEventFileLog) Append(events []core.IndexEvent) error {
+	wrap := errors.Wrapper("failed to write to the event log")
+
+	lines := []byte{}
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return wrap(err)
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+
+	err := os.MkdirAll(filepath.Dir(l.path), os.ModePerm)
+	if err != nil {
+		return wrap(err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return wrap(err)
+	}
+	defer f.Close()
+
+	// A single write keeps the events of concurrent processes contiguous.
+	_, err = f.Write(lines)
+	return wrap(err)
+}
+
+// Events implements core.IndexEventLog.
+func (l *IndexEventFileLog) Events(from int64) ([]core.IndexEvent, int64, error) {
+	wrap := errors.Wrapper("failed to read the event log")
+
+	events := []core.IndexEvent{}
+
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return events, 0, nil
+	} else if err != nil {
+		return events, from, wrap(err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return events, from, wrap(err)
+	}
+	if from > info.Size() {
+		// The log was truncated, start over.
+		from = 0
+	}
+	_, err = f.Seek(from, io.SeekStart)
+	if err != nil {
+		return events, from, wrap(err)
+	}
+
+	next := from
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// An incomplete line is still being written, it will be read
+			// next time.
+			break
+		} else if err != nil {
+			return events, next, wrap(err)
+		}
+		next += int64(len(line))
+
+		if len(line) <= 1 {
+			continue
+		}
+		var event core.IndexEvent
+		err = json.Unmarshal(line, &event)
+		if err != nil {
+			// A corrupted line should not prevent reading the rest of the log.
+			l.logger.Err(wrap(err))
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, next, nil
+}
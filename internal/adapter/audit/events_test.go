@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestIndexEventFileLogAppendAndRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zk-events")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ".zk/events.log")
+	log := NewIndexEventFileLog(path, &util.NullLogger)
+
+	events, next, err := log.Events(0)
+	assert.Nil(t, err)
+	assert.Equal(t, events, []core.IndexEvent{})
+	assert.Equal(t, next, int64(0))
+
+	date := time.Date(2021, 10, 5, 12, 43, 0, 0, time.UTC)
+	added := core.IndexEvent{Time: date, Kind: core.IndexEventNoteAdded, Path: "ref/7fd3.md"}
+	removed := core.IndexEvent{Time: date, Kind: core.IndexEventNoteRemoved, Path: "ref/a1b2.md"}
+	broken := core.IndexEvent{Time: date.Add(time.Minute), Kind: core.IndexEventLinkBroken, Path: "index.md", Href: "ref/a1b2"}
+
+	assert.Nil(t, log.Append([]core.IndexEvent{added, removed}))
+	events, next, err = log.Events(0)
+	assert.Nil(t, err)
+	assert.Equal(t, events, []core.IndexEvent{added, removed})
+
+	// Only the new events are read from the last position.
+	assert.Nil(t, log.Append([]core.IndexEvent{broken}))
+	events, next, err = log.Events(next)
+	assert.Nil(t, err)
+	assert.Equal(t, events, []core.IndexEvent{broken})
+
+	events, last, err := log.Events(next)
+	assert.Nil(t, err)
+	assert.Equal(t, events, []core.IndexEvent{})
+	assert.Equal(t, last, next)
+
+	// An incomplete line is read once it is written entirely.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	_, err = f.WriteString(`{"time":"2021-10-05T12:45:00Z","event":"note-changed",`)
+	assert.Nil(t, err)
+	events, next, err = log.Events(next)
+	assert.Nil(t, err)
+	assert.Equal(t, events, []core.IndexEvent{})
+	assert.Equal(t, next, last)
+
+	_, err = f.WriteString(`"path":"index.md"}` + "\n")
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+	events, _, err = log.Events(next)
+	assert.Nil(t, err)
+	assert.Equal(t, events, []core.IndexEvent{
+		{Time: date.Add(2 * time.Minute), Kind: core.IndexEventNoteChanged, Path: "index.md"},
+	})
+
+	// A truncated log is read from the start.
+	assert.Nil(t, os.Truncate(path, 0))
+	assert.Nil(t, log.Append([]core.IndexEvent{added}))
+	events, _, err = log.Events(last)
+	assert.Nil(t, err)
+	assert.Equal(t, events, []core.IndexEvent{added})
+}
@@ -0,0 +1,33 @@
+package bibliography
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/core"
+)
+
+// Parse implements core.BibliographyParser, reading BibTeX (.bib) and
+// CSL-JSON (.json) bibliography files.
+func Parse(path string, content []byte) ([]core.Citation, error) {
+	var citations []core.Citation
+	var err error
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".bib", ".bibtex":
+		citations = parseBibTeX(string(content))
+	case ".json":
+		citations, err = parseCSLJSON(content)
+	default:
+		return nil, fmt.Errorf("%s: unsupported bibliography format, try .bib or .json", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range citations {
+		citations[i].Path = path
+	}
+	return citations, nil
+}
@@ -0,0 +1,133 @@
+package bibliography
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestParseBibTeX(t *testing.T) {
+	citations, err := Parse("/notes/refs.bib", []byte(`% Exported from Zotero
+@string{cj = "The Computer Journal"}
+
+@Article{knuth1984,
+  author  = {Knuth, Donald E.},
+  title   = {{Literate} Programming},
+  journal = cj,
+  year    = 1984,
+  pages   = {97--111},
+  doi     = {10.1093/comjnl/27.2.97}
+}
+
+@book{ahrens2017, title = "How to Take Smart Notes: {One} Simple Technique",
+  author = {Ahrens, S{\"o}nke and Luhmann, Niklas}, publisher = {CreateSpace},
+  date = {2017-02-24}}
+
+@comment{ignored, not an entry}
+@misc( empty, )
+@inproceedings{escaped,
+  title = "Notes \& Links " # {in } # "\emph{Zettelkasten}",
+  editor = {Doe, J.},
+  booktitle = {Proc. of the Fa\c{c}ade Workshop}
+}
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, citations, []core.Citation{
+		{
+			Key:       "knuth1984",
+			Type:      "article",
+			Title:     "Literate Programming",
+			Authors:   []string{"Knuth, Donald E."},
+			Year:      "1984",
+			Container: "cj",
+			DOI:       "10.1093/comjnl/27.2.97",
+			Path:      "/notes/refs.bib",
+			Line:      4,
+		},
+		{
+			Key:       "ahrens2017",
+			Type:      "book",
+			Title:     "How to Take Smart Notes: One Simple Technique",
+			Authors:   []string{"Ahrens, So\u0308nke", "Luhmann, Niklas"},
+			Year:      "2017",
+			Container: "CreateSpace",
+			Path:      "/notes/refs.bib",
+			Line:      13,
+		},
+		{
+			Key:     "empty",
+			Type:    "misc",
+			Authors: []string{},
+			Path:    "/notes/refs.bib",
+			Line:    18,
+		},
+		{
+			Key:       "escaped",
+			Type:      "inproceedings",
+			Title:     "Notes & Links in Zettelkasten",
+			Authors:   []string{"Doe, J."},
+			Container: "Proc. of the Fac\u0327ade Workshop",
+			Path:      "/notes/refs.bib",
+			Line:      19,
+		},
+	})
+}
+
+func TestParseCSLJSON(t *testing.T) {
+	citations, err := Parse("/notes/refs.json", []byte(`[
+  {
+    "id": "knuth1984",
+    "type": "article-journal",
+    "title": "Literate Programming",
+    "author": [{"family": "Knuth", "given": "Donald E."}],
+    "issued": {"date-parts": [[1984, 5]]},
+    "container-title": "The Computer Journal",
+    "DOI": "10.1093/comjnl/27.2.97"
+  },
+  {"id": "ahrens2017", "type": "book", "title": "How to Take Smart Notes",
+   "editor": [{"family": "Ahrens"}, {"literal": "Zettelkasten Society"}],
+   "issued": {"raw": "2017-02-24"}, "publisher": "CreateSpace", "URL": "https://example.com"},
+  {"title": "Without ID"},
+  {"id": 42}
+]`))
+	assert.Nil(t, err)
+	assert.Equal(t, citations, []core.Citation{
+		{
+			Key:       "knuth1984",
+			Type:      "article-journal",
+			Title:     "Literate Programming",
+			Authors:   []string{"Knuth, Donald E."},
+			Year:      "1984",
+			Container: "The Computer Journal",
+			DOI:       "10.1093/comjnl/27.2.97",
+			Path:      "/notes/refs.json",
+			Line:      2,
+		},
+		{
+			Key:       "ahrens2017",
+			Type:      "book",
+			Title:     "How to Take Smart Notes",
+			Authors:   []string{"Ahrens", "Zettelkasten Society"},
+			Year:      "2017",
+			Container: "CreateSpace",
+			URL:       "https://example.com",
+			Path:      "/notes/refs.json",
+			Line:      11,
+		},
+		{
+			Key:     "42",
+			Authors: []string{},
+			Path:    "/notes/refs.json",
+			Line:    15,
+		},
+	})
+
+	_, err = Parse("/notes/refs.json", []byte(`{"id": "not-an-array"}`))
+	assert.Err(t, err, "expected an array of CSL-JSON items")
+}
+
+func TestParseUnsupportedFormat(t *testing.T) {
+	_, err := Parse("/notes/refs.yaml", []byte(""))
+	assert.Err(t, err, ".yaml: unsupported bibliography format, try .bib or .json")
+}
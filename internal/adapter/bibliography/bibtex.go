@@ -0,0 +1,209 @@
+package bibliography
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/core"
+)
+
+// bibTeXEntryRegex matches the start of a BibTeX entry, e.g.
+// `@article{knuth1984,`, capturing its type and key.
+var bibTeXEntryRegex = regexp.MustCompile(`@(\w+)\s*[{(]\s*([^,\s{}()]*)\s*,`)
+
+// bibTeXAuthorSeparatorRegex matches the separator of the names in the
+// author and editor fields.
+var bibTeXAuthorSeparatorRegex = regexp.MustCompile(`\s+and\s+`)
+
+// bibTeXCommandRegex matches the LaTeX commands and escaped characters of
+// the field values, e.g. `\"` in `{\"o}` or `\&`.
+var bibTeXCommandRegex = regexp.MustCompile(`\\[a-zA-Z]+\s*|\\.`)
+
+// bibTeXAccents are the combining characters of the common LaTeX accent
+// commands.
+var bibTeXAccents = map[string]string{
+	"\\'":  "\u0301",
+	"\\`":  "\u0300",
+	"\\^":  "\u0302",
+	"\\\"": "\u0308",
+	"\\~":  "\u0303",
+	"\\c":  "\u0327",
+}
+
+// parseBibTeX reads the entries of a BibTeX bibliography. The parsing is
+// lenient: malformed entries are skipped, and the `@string` abbreviations
+// are not expanded.
+func parseBibTeX(content string) []core.Citation {
+	citations := []core.Citation{}
+
+	for _, match := range bibTeXEntryRegex.FindAllStringSubmatchIndex(content, -1) {
+		kind := strings.ToLower(content[match[2]:match[3]])
+		key := content[match[4]:match[5]]
+		if key == "" || kind == "comment" || kind == "string" || kind == "preamble" {
+			continue
+		}
+
+		fields := parseBibTeXFields(content[match[1]:])
+		citation := core.Citation{
+			Key:     key,
+			Type:    kind,
+			Title:   fields["title"],
+			Authors: []string{},
+			Year:    fields["year"],
+			DOI:     fields["doi"],
+			URL:     fields["url"],
+			Line:    strings.Count(content[:match[0]], "\n") + 1,
+		}
+		if citation.Year == "" && len(fields["date"]) >= 4 {
+			citation.Year = fields["date"][:4]
+		}
+		authors := fields["author"]
+		if authors == "" {
+			authors = fields["editor"]
+		}
+		if authors != "" {
+			for _, author := range bibTeXAuthorSeparatorRegex.Split(authors, -1) {
+				citation.Authors = append(citation.Authors, strings.TrimSpace(author))
+			}
+		}
+		for _, name := range []string{"journal", "journaltitle", "booktitle", "publisher", "school", "institution"} {
+			if fields[name] != "" {
+				citation.Container = fields[name]
+				break
+			}
+		}
+
+		citations = append(citations, citation)
+	}
+
+	return citations
+}
+
+// parseBibTeXFields reads the `name = value` fields of an entry, until its
+// closing delimiter.
+func parseBibTeXFields(content string) map[string]string {
+	fields := map[string]string{}
+	i := 0
+	skipSpaces := func() {
+		for i < len(content) && strings.ContainsRune(" \t\r\n,", rune(content[i])) {
+			i++
+		}
+	}
+
+	for {
+		skipSpaces()
+		if i >= len(content) || content[i] == '}' || content[i] == ')' || content[i] == '@' {
+			return fields
+		}
+
+		start := i
+		for i < len(content) && content[i] != '=' && content[i] != '}' && content[i] != '\n' {
+			i++
+		}
+		if i >= len(content) || content[i] != '=' {
+			// Not a field, the entry is malformed.
+			return fields
+		}
+		name := strings.ToLower(strings.TrimSpace(content[start:i]))
+		i++
+
+		// The value can be a concatenation of several parts with `#`.
+		value := ""
+		for {
+			skipSpaces()
+			if i >= len(content) {
+				break
+			}
+			switch content[i] {
+			case '{':
+				end := matchingBrace(content, i)
+				value += content[i+1 : end]
+				i = end + 1
+			case '"':
+				end := i + 1
+				for depth := 0; end < len(content) && (content[end] != '"' || depth > 0); end++ {
+					if content[end] == '{' {
+						depth++
+					} else if content[end] == '}' {
+						depth--
+					}
+				}
+				value += content[i+1 : min(end, len(content))]
+				i = end + 1
+			default:
+				start := i
+				for i < len(content) && !strings.ContainsRune(" \t\r\n,#})", rune(content[i])) {
+					i++
+				}
+				value += content[start:i]
+			}
+
+			skipSpaces()
+			if i < len(content) && content[i] == '#' {
+				i++
+				continue
+			}
+			break
+		}
+
+		fields[name] = cleanBibTeXValue(value)
+	}
+}
+
+// matchingBrace returns the index of the brace closing the one at start, or
+// the end of the content.
+func matchingBrace(content string, start int) int {
+	depth := 0
+	for i := start; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(content)
+}
+
+// cleanBibTeXValue converts the LaTeX markup of a field value to plain text.
+func cleanBibTeXValue(value string) string {
+	value = bibTeXCommandRegex.ReplaceAllStringFunc(value, func(command string) string {
+		command = strings.TrimSpace(command)
+		if accent, ok := bibTeXAccents[command]; ok {
+			return accent
+		}
+		if len(command) == 2 && strings.ContainsRune(`&%$#_{}`, rune(command[1])) {
+			return command[1:]
+		}
+		// Commands such as \emph are dropped, keeping their argument.
+		return ""
+	})
+	value = strings.NewReplacer("{", "", "}", "", "---", "—", "--", "–", "~", " ").Replace(value)
+	value = strings.Join(strings.Fields(value), " ")
+	return composeAccents(value)
+}
+
+// composeAccents moves the combining accents after the letter they modify,
+// as `\"o` is read as the accent followed by `o`.
+func composeAccents(value string) string {
+	runes := []rune(value)
+	for i := 0; i < len(runes)-1; i++ {
+		if runes[i] >= 0x0300 && runes[i] <= 0x036f {
+			runes[i], runes[i+1] = runes[i+1], runes[i]
+			i++
+		}
+	}
+	return string(runes)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
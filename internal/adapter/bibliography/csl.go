@@ -0,0 +1,118 @@
+package bibliography
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mickael-menu/zk/internal/core"
+)
+
+// cslItem is a reference of a CSL-JSON bibliography, as exported by Zotero
+// or Pandoc.
+// See https://citeproc-js.readthedocs.io/en/latest/csl-json/markup.html
+type cslItem struct {
+	ID             interface{} `json:"id"`
+	Type           string      `json:"type"`
+	Title          string      `json:"title"`
+	Author         []cslName   `json:"author"`
+	Editor         []cslName   `json:"editor"`
+	Issued         cslDate     `json:"issued"`
+	ContainerTitle string      `json:"container-title"`
+	Publisher      string      `json:"publisher"`
+	DOI            string      `json:"DOI"`
+	URL            string      `json:"URL"`
+}
+
+type cslName struct {
+	Family  string `json:"family"`
+	Given   string `json:"given"`
+	Literal string `json:"literal"`
+}
+
+func (n cslName) String() string {
+	if n.Literal != "" {
+		return n.Literal
+	}
+	if n.Given == "" {
+		return n.Family
+	}
+	return n.Family + ", " + n.Given
+}
+
+type cslDate struct {
+	DateParts [][]interface{} `json:"date-parts"`
+	Raw       string          `json:"raw"`
+	Literal   string          `json:"literal"`
+}
+
+// Year returns the year of the date, if any.
+func (d cslDate) Year() string {
+	if len(d.DateParts) > 0 && len(d.DateParts[0]) > 0 {
+		return fmt.Sprint(d.DateParts[0][0])
+	}
+	for _, date := range []string{d.Raw, d.Literal} {
+		if len(date) >= 4 {
+			return date[:4]
+		}
+	}
+	return ""
+}
+
+// parseCSLJSON reads the references of a CSL-JSON bibliography, which is an
+// array of items.
+func parseCSLJSON(content []byte) ([]core.Citation, error) {
+	citations := []core.Citation{}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("expected an array of CSL-JSON items")
+	}
+
+	for decoder.More() {
+		// The offset is located before the separator of the previous item.
+		offset := int(decoder.InputOffset())
+		if start := bytes.IndexByte(content[offset:], '{'); start >= 0 {
+			offset += start
+		}
+
+		var item cslItem
+		if err := decoder.Decode(&item); err != nil {
+			return nil, err
+		}
+		if item.ID == nil {
+			continue
+		}
+
+		names := item.Author
+		if len(names) == 0 {
+			names = item.Editor
+		}
+		authors := []string{}
+		for _, name := range names {
+			authors = append(authors, name.String())
+		}
+		container := item.ContainerTitle
+		if container == "" {
+			container = item.Publisher
+		}
+
+		citations = append(citations, core.Citation{
+			Key:       fmt.Sprint(item.ID),
+			Type:      item.Type,
+			Title:     item.Title,
+			Authors:   authors,
+			Year:      item.Issued.Year(),
+			Container: container,
+			DOI:       item.DOI,
+			URL:       item.URL,
+			Line:      bytes.Count(content[:offset], []byte("\n")) + 1,
+		})
+	}
+
+	return citations, nil
+}
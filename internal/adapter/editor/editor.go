@@ -3,6 +3,7 @@ package editor
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/kballard/go-shellquote"
@@ -15,11 +16,14 @@ import (
 // Editor represents an external editor able to edit the notes.
 type Editor struct {
 	editor string
+	// Arguments used to open files side by side.
+	splitArgs opt.String
 }
 
 // NewEditor creates a new Editor from the given editor user setting or the
-// matching environment variables.
-func NewEditor(editor opt.String) (*Editor, error) {
+// matching environment variables. splitArgs are the arguments used to open
+// several files side by side, overriding the ones of well-known editors.
+func NewEditor(editor opt.String, splitArgs opt.String) (*Editor, error) {
 	editor = osutil.GetOptEnv("ZK_EDITOR").
 		Or(editor).
 		Or(osutil.GetOptEnv("VISUAL")).
@@ -29,19 +33,53 @@ func NewEditor(editor opt.String) (*Editor, error) {
 		return nil, fmt.Errorf("no editor set in config")
 	}
 
-	return &Editor{editor.Unwrap()}, nil
+	return &Editor{editor: editor.Unwrap(), splitArgs: splitArgs}, nil
 }
 
 // Open launches the editor with the notes at given paths.
 func (e *Editor) Open(paths ...string) error {
+	return e.open(e.editor, paths)
+}
+
+// OpenSplit launches the editor with the files at given paths side by side,
+// when the editor supports it. Otherwise, they are opened as with Open.
+func (e *Editor) OpenSplit(paths ...string) error {
+	editor := e.editor
+	if args := e.splitArgs.Or(defaultSplitArgs(editor)); !args.IsNull() {
+		editor += " " + args.Unwrap()
+	}
+	return e.open(editor, paths)
+}
+
+// splitArgsByBinary are the arguments used by well-known editors to open
+// files side by side.
+var splitArgsByBinary = map[string]string{
+	"vim":  "-O",
+	"nvim": "-O",
+	"gvim": "-O",
+	"mvim": "-O",
+	"hx":   "--vsplit",
+}
+
+// defaultSplitArgs returns the arguments used to open files side by side
+// with the given editor command, if it is a well-known editor.
+func defaultSplitArgs(editor string) opt.String {
+	args, err := shellquote.Split(editor)
+	if err != nil || len(args) == 0 {
+		return opt.NullString
+	}
+	return opt.NewNotEmptyString(splitArgsByBinary[filepath.Base(args[0])])
+}
+
+func (e *Editor) open(editor string, paths []string) error {
 	// /dev/tty is restored as stdin, in case the user used a pipe to feed
 	// initial note content to `zk new`. Without this, Vim doesn't work
 	// properly in this case.
 	// See https://github.com/mickael-menu/zk/issues/4
-	cmd := executil.CommandFromString(e.editor + " " + shellquote.Join(paths...) + " </dev/tty")
+	cmd := executil.CommandFromString(editor + " " + shellquote.Join(paths...) + " </dev/tty")
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	return errors.Wrapf(cmd.Run(), "failed to launch editor: %s %s", e.editor, strings.Join(paths, " "))
+	return errors.Wrapf(cmd.Run(), "failed to launch editor: %s %s", editor, strings.Join(paths, " "))
 }
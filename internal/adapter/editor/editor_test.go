@@ -13,7 +13,7 @@ func TestEditorUsesZkEditorFirst(t *testing.T) {
 	os.Setenv("VISUAL", "visual")
 	os.Setenv("EDITOR", "editor")
 
-	editor, err := NewEditor(opt.NewString("custom-editor"))
+	editor, err := NewEditor(opt.NewString("custom-editor"), opt.NullString)
 	assert.Nil(t, err)
 	assert.Equal(t, editor.editor, "zk-editor")
 }
@@ -23,7 +23,7 @@ func TestEditorFallsbackOnUserConfig(t *testing.T) {
 	os.Setenv("VISUAL", "visual")
 	os.Setenv("EDITOR", "editor")
 
-	editor, err := NewEditor(opt.NewString("custom-editor"))
+	editor, err := NewEditor(opt.NewString("custom-editor"), opt.NullString)
 	assert.Nil(t, err)
 	assert.Equal(t, editor.editor, "custom-editor")
 }
@@ -33,7 +33,7 @@ func TestEditorFallsbackOnVisual(t *testing.T) {
 	os.Setenv("VISUAL", "visual")
 	os.Setenv("EDITOR", "editor")
 
-	editor, err := NewEditor(opt.NullString)
+	editor, err := NewEditor(opt.NullString, opt.NullString)
 	assert.Nil(t, err)
 	assert.Equal(t, editor.editor, "visual")
 }
@@ -43,7 +43,7 @@ func TestEditorFallsbackOnEditor(t *testing.T) {
 	os.Unsetenv("VISUAL")
 	os.Setenv("EDITOR", "editor")
 
-	editor, err := NewEditor(opt.NullString)
+	editor, err := NewEditor(opt.NullString, opt.NullString)
 	assert.Nil(t, err)
 	assert.Equal(t, editor.editor, "editor")
 }
@@ -53,7 +53,14 @@ func TestEditorFailsWhenUnset(t *testing.T) {
 	os.Unsetenv("VISUAL")
 	os.Unsetenv("EDITOR")
 
-	editor, err := NewEditor(opt.NullString)
+	editor, err := NewEditor(opt.NullString, opt.NullString)
 	assert.Err(t, err, "no editor set in config")
 	assert.Nil(t, editor)
 }
+
+func TestEditorDefaultSplitArgs(t *testing.T) {
+	assert.Equal(t, defaultSplitArgs("nvim"), opt.NewString("-O"))
+	assert.Equal(t, defaultSplitArgs("/usr/bin/vim -u NONE"), opt.NewString("-O"))
+	assert.Equal(t, defaultSplitArgs("hx"), opt.NewString("--vsplit"))
+	assert.Equal(t, defaultSplitArgs("code --wait"), opt.NullString)
+}
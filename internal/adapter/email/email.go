@@ -0,0 +1,169 @@
+package email
+
+import (
+	"encoding/base64"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// Email is a message parsed from a RFC 822 / MIME source.
+type Email struct {
+	Subject string
+	From    string
+	Date    time.Time
+	// Plain text body of the email.
+	Body string
+	// Files attached to the email.
+	Attachments []core.Attachment
+}
+
+// Parse reads an email message, such as the ones piped by mail filters.
+func Parse(r io.Reader) (*Email, error) {
+	wrap := errors.Wrapper("failed to parse the email")
+
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	decoder := new(mime.WordDecoder)
+	email := Email{
+		Subject:     decodeHeader(decoder, msg.Header.Get("Subject")),
+		From:        decodeHeader(decoder, msg.Header.Get("From")),
+		Attachments: []core.Attachment{},
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		email.Date = date
+	}
+
+	p := parser{email: &email}
+	err = p.parsePart(
+		msg.Header.Get("Content-Type"),
+		msg.Header.Get("Content-Transfer-Encoding"),
+		msg.Header.Get("Content-Disposition"),
+		msg.Body,
+	)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	email.Body = p.plainBody
+	if email.Body == "" {
+		email.Body = htmlToText(p.htmlBody)
+	}
+	email.Body = strings.TrimSpace(strings.ReplaceAll(email.Body, "\r\n", "\n"))
+
+	return &email, nil
+}
+
+type parser struct {
+	email     *Email
+	plainBody string
+	htmlBody  string
+}
+
+// parsePart walks recursively the MIME parts of the message to find its body
+// and attachments.
+func (p *parser) parsePart(contentType string, encoding string, disposition string, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// Content-Type defaults to plain text, according to RFC 2045.
+		mediaType = "text/plain"
+		params = map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			// NextPart already decodes the quoted-printable parts, and
+			// removes their Content-Transfer-Encoding header.
+			err = p.parsePart(
+				part.Header.Get("Content-Type"),
+				part.Header.Get("Content-Transfer-Encoding"),
+				part.Header.Get("Content-Disposition"),
+				part,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := ioutil.ReadAll(decodeTransferEncoding(encoding, body))
+	if err != nil {
+		return err
+	}
+
+	filename := ""
+	if _, params, err := mime.ParseMediaType(disposition); err == nil {
+		filename = params["filename"]
+	}
+	if filename == "" {
+		filename = params["name"]
+	}
+
+	switch {
+	case filename != "" || strings.HasPrefix(disposition, "attachment"):
+		p.email.Attachments = append(p.email.Attachments, core.Attachment{
+			Filename: decodeHeader(new(mime.WordDecoder), filename),
+			Content:  content,
+		})
+	case mediaType == "text/plain" && p.plainBody == "":
+		p.plainBody = string(content)
+	case mediaType == "text/html" && p.htmlBody == "":
+		p.htmlBody = string(content)
+	}
+
+	return nil
+}
+
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+func decodeHeader(decoder *mime.WordDecoder, header string) string {
+	decoded, err := decoder.DecodeHeader(header)
+	if err != nil {
+		return header
+	}
+	return decoded
+}
+
+var (
+	htmlBlockRegex = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|h[1-6]|li|tr)>`)
+	htmlTagRegex   = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlStyleRegex = regexp.MustCompile(`(?is)<(style|script)[^>]*>.*?</(style|script)>`)
+)
+
+// htmlToText converts roughly an HTML body to plain text, for the emails
+// without a plain text alternative.
+func htmlToText(body string) string {
+	body = htmlStyleRegex.ReplaceAllString(body, "")
+	body = htmlBlockRegex.ReplaceAllString(body, "\n")
+	body = htmlTagRegex.ReplaceAllString(body, "")
+	return html.UnescapeString(body)
+}
@@ -0,0 +1,88 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestParsePlainText(t *testing.T) {
+	email, err := Parse(strings.NewReader("From: Mickael <mickael@example.com>\r\n" +
+		"Subject: =?utf-8?q?Caf=C3=A9_notes?=\r\n" +
+		"Date: Tue, 19 Oct 2021 10:30:00 +0200\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"\r\n" +
+		"A caf=C3=A9 with =\r\n" +
+		"soft breaks.\r\n"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, email, &Email{
+		Subject:     "Café notes",
+		From:        "Mickael <mickael@example.com>",
+		Date:        time.Date(2021, 10, 19, 10, 30, 0, 0, time.FixedZone("", 2*60*60)),
+		Body:        "A café with soft breaks.",
+		Attachments: []core.Attachment{},
+	})
+}
+
+func TestParseMultipartWithAttachments(t *testing.T) {
+	email, err := Parse(strings.NewReader(`From: mickael@example.com
+Subject: Receipt
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain
+
+Plain body
+--inner
+Content-Type: text/html
+
+<p>HTML body</p>
+--inner--
+--outer
+Content-Type: application/pdf; name="receipt.pdf"
+Content-Disposition: attachment; filename="receipt.pdf"
+Content-Transfer-Encoding: base64
+
+UERGIGNv
+bnRlbnQ=
+--outer
+Content-Type: image/png
+Content-Disposition: inline; filename="=?utf-8?q?photo_=C3=A9t=C3=A9.png?="
+
+PNG content
+--outer--
+`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, email.Subject, "Receipt")
+	assert.Equal(t, email.Body, "Plain body")
+	assert.Equal(t, email.Attachments, []core.Attachment{
+		{Filename: "receipt.pdf", Content: []byte("PDF content")},
+		{Filename: "photo été.png", Content: []byte("PNG content")},
+	})
+}
+
+func TestParseHTMLOnly(t *testing.T) {
+	email, err := Parse(strings.NewReader(`Subject: Newsletter
+Content-Type: text/html
+
+<html><head><style>p { color: red; }</style></head>
+<body><p>First &amp; foremost</p><p>Second<br>line</p></body></html>
+`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, email.Body, "First & foremost\nSecond\nline")
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse(strings.NewReader("Not an email"))
+	assert.NotNil(t, err)
+}
@@ -135,3 +135,23 @@ func (fs *FileStorage) Write(path string, content []byte) error {
 	_, err = f.Write(content)
 	return err
 }
+
+func (fs *FileStorage) Rename(source string, target string) error {
+	dir := filepath.Dir(target)
+	if dir != "." && dir != ".." {
+		err := os.MkdirAll(dir, os.ModePerm)
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(source, target)
+}
+
+func (fs *FileStorage) Remove(path string) error {
+	return os.Remove(path)
+}
+
+func (fs *FileStorage) CreateDir(path string) error {
+	return os.MkdirAll(path, os.ModePerm)
+}
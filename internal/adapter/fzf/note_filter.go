@@ -118,6 +118,7 @@ func (f *NoteFilter) Apply(notes []core.ContextualNote) ([]core.ContextualNote,
 			Body:         stringsutil.JoinLines(note.Body),
 			RawContent:   stringsutil.JoinLines(note.RawContent),
 			WordCount:    note.WordCount,
+			ReadingTime:  core.ReadingTime(note.WordCount),
 			Tags:         note.Tags,
 			Metadata:     note.Metadata,
 			Created:      note.Created,
@@ -169,6 +170,7 @@ type lineRenderContext struct {
 	Body         string
 	RawContent   string `handlebars:"raw-content"`
 	WordCount    int    `handlebars:"word-count"`
+	ReadingTime  int    `handlebars:"reading-time"`
 	Tags         []string
 	Metadata     map[string]interface{}
 	Created      time.Time
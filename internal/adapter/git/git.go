@@ -0,0 +1,156 @@
+package git
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// Repo implements the port core.VersionControl with the git command, for a
+// notebook located anywhere in a git working tree.
+type Repo struct {
+	// Root of the notebook, used as working directory for the git commands.
+	path string
+}
+
+// NewRepo creates a new Repo for the notebook at path.
+//
+// The git commands are run lazily, so it doesn't fail when the notebook is
+// not versioned.
+func NewRepo(path string) *Repo {
+	return &Repo{path: path}
+}
+
+// Commit implements core.VersionControl.
+func (r *Repo) Commit(message string, paths ...string) error {
+	wrap := errors.Wrapper("git commit failed")
+
+	// git add fails with paths which don't exist anymore, so the deleted
+	// notes are removed from the index instead.
+	existing := []string{}
+	deleted := []string{}
+	for _, path := range paths {
+		if _, err := os.Lstat(filepath.Join(r.path, path)); os.IsNotExist(err) {
+			deleted = append(deleted, path)
+		} else {
+			existing = append(existing, path)
+		}
+	}
+	if len(existing) > 0 {
+		_, err := r.run(append([]string{"add", "--all", "--"}, existing...)...)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	if len(deleted) > 0 {
+		_, err := r.run(append([]string{"rm", "-r", "--cached", "--ignore-unmatch", "--quiet", "--"}, deleted...)...)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+
+	staged, err := r.runPaths(append([]string{"diff", "--cached", "--name-only", "--relative", "--no-renames", "-z", "--"}, paths...)...)
+	if err != nil || len(staged) == 0 {
+		return wrap(err)
+	}
+
+	// Only the given paths are committed, even if other changes were staged
+	// by the user.
+	_, err = r.run(append([]string{"commit", "--quiet", "--message", message, "--"}, staged...)...)
+	return wrap(err)
+}
+
+// notebookPathspec matches all the files of the notebook, except its index,
+// audit log and generated backlinks buffer which are specific to each
+// machine.
+//
+// The patterns contain wildcards, otherwise git add fails when the files are
+// already ignored.
+var notebookPathspec = []string{".", ":!.zk/*.db", ":!.zk/*.log", ":!.zk/backlinks*.md", ":!.zk/site"}
+
+// ChangedSince implements core.VersionControl.
+func (r *Repo) ChangedSince(revision string) ([]string, error) {
+	// Committed and uncommitted changes of the tracked files.
+	paths, err := r.runPaths(append([]string{"diff", "--name-only", "--relative", "--no-renames", "-z", revision, "--"}, notebookPathspec...)...)
+	if err != nil {
+		return nil, err
+	}
+	// New files which were never committed.
+	untracked, err := r.runPaths(append([]string{"ls-files", "--others", "--exclude-standard", "-z", "--"}, notebookPathspec...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(paths, untracked...), nil
+}
+
+// LastCommit implements core.VersionControl.
+func (r *Repo) LastCommit(path string) (string, error) {
+	out, err := r.run("log", "-1", "--format=%H", "--", path)
+	return strings.TrimSpace(out), errors.Wrapf(err, "%s: git log failed", path)
+}
+
+// Sync implements core.VersionControl.
+func (r *Repo) Sync(message string) error {
+	wrap := errors.Wrapper("git sync failed")
+
+	_, err := r.run(append([]string{"add", "--all", "--"}, notebookPathspec...)...)
+	if err != nil {
+		return wrap(err)
+	}
+	staged, err := r.runPaths(append([]string{"diff", "--cached", "--name-only", "--relative", "-z", "--"}, notebookPathspec...)...)
+	if err != nil {
+		return wrap(err)
+	}
+	if len(staged) > 0 {
+		_, err = r.run(append([]string{"commit", "--quiet", "--message", message, "--"}, notebookPathspec...)...)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+
+	_, err = r.run("pull", "--rebase", "--quiet")
+	if err != nil {
+		return wrap(err)
+	}
+	_, err = r.run("push", "--quiet")
+	return wrap(err)
+}
+
+// run executes git with the given arguments in the notebook directory, and
+// returns its standard output.
+func (r *Repo) run(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.path
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.New(msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// runPaths executes git with the given arguments, and returns the list of
+// NUL-separated paths it outputs.
+func (r *Repo) runPaths(args ...string) ([]string, error) {
+	out, err := r.run(args...)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, path := range strings.Split(out, "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
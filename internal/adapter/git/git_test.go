@@ -0,0 +1,140 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+// newTestRepo creates a git repository with a notebook in its `notes`
+// sub-directory.
+func newTestRepo(t *testing.T) (*Repo, string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	path := filepath.Join(root, "notes")
+	assert.Nil(t, os.MkdirAll(path, 0755))
+
+	repo := NewRepo(path)
+	for _, args := range [][]string{
+		{"init", "--quiet", root},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		_, err := repo.run(args...)
+		assert.Nil(t, err)
+	}
+	return repo, path
+}
+
+func writeFile(t *testing.T, dir string, path string, content string) {
+	path = filepath.Join(dir, path)
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func commitCount(t *testing.T, repo *Repo) string {
+	out, err := repo.run("rev-list", "--count", "HEAD")
+	assert.Nil(t, err)
+	return out
+}
+
+func TestCommitOnlyGivenPaths(t *testing.T) {
+	repo, path := newTestRepo(t)
+	writeFile(t, path, "a.md", "A")
+	writeFile(t, path, "dir/b.md", "B")
+
+	assert.Nil(t, repo.Commit("create: a.md", "a.md", "unknown.md"))
+
+	out, err := repo.run("log", "--format=%s", "--name-only")
+	assert.Nil(t, err)
+	assert.Equal(t, out, "create: a.md\n\nnotes/a.md\n")
+
+	untracked, err := repo.runPaths("ls-files", "--others", "-z")
+	assert.Nil(t, err)
+	assert.Equal(t, untracked, []string{"dir/b.md"})
+}
+
+func TestCommitDeletedAndUnchangedPaths(t *testing.T) {
+	repo, path := newTestRepo(t)
+	writeFile(t, path, "a.md", "A")
+	assert.Nil(t, repo.Commit("create", "a.md"))
+
+	// Nothing changed.
+	assert.Nil(t, repo.Commit("edit", "a.md"))
+	assert.Equal(t, commitCount(t, repo), "1\n")
+
+	assert.Nil(t, os.Remove(filepath.Join(path, "a.md")))
+	assert.Nil(t, repo.Commit("delete", "a.md"))
+	assert.Equal(t, commitCount(t, repo), "2\n")
+
+	tracked, err := repo.runPaths("ls-files", "-z")
+	assert.Nil(t, err)
+	assert.Equal(t, tracked, []string{})
+}
+
+func TestChangedSince(t *testing.T) {
+	repo, path := newTestRepo(t)
+	writeFile(t, path, "a.md", "A")
+	writeFile(t, path, "b.md", "B")
+	writeFile(t, path, "c.md", "C")
+	assert.Nil(t, repo.Commit("create", "a.md", "b.md", "c.md"))
+	first, err := repo.LastCommit("a.md")
+	assert.Nil(t, err)
+	assert.Equal(t, len(first), 40)
+
+	writeFile(t, path, "a.md", "A, committed")
+	assert.Nil(t, repo.Commit("edit", "a.md"))
+	writeFile(t, path, "b.md", "B, uncommitted")
+	writeFile(t, path, "new/d.md", "D, untracked")
+
+	changed, err := repo.ChangedSince(first)
+	assert.Nil(t, err)
+	sort.Strings(changed)
+	assert.Equal(t, changed, []string{"a.md", "b.md", "new/d.md"})
+
+	last, err := repo.LastCommit("a.md")
+	assert.Nil(t, err)
+	assert.NotEqual(t, last, first)
+	last, err = repo.LastCommit("new/d.md")
+	assert.Nil(t, err)
+	assert.Equal(t, last, "")
+
+	_, err = repo.ChangedSince("unknown-revision")
+	assert.NotNil(t, err)
+}
+
+func TestSync(t *testing.T) {
+	repo, path := newTestRepo(t)
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	_, err := repo.run("init", "--quiet", "--bare", remote)
+	assert.Nil(t, err)
+
+	writeFile(t, path, "a.md", "A")
+	writeFile(t, path, ".zk/notebook.db", "index")
+	writeFile(t, path, ".zk/audit.log", "log")
+	writeFile(t, path, ".gitignore", ".zk/notebook.db")
+	assert.Nil(t, repo.Commit("create", "a.md", ".gitignore"))
+	for _, args := range [][]string{
+		{"remote", "add", "origin", remote},
+		{"push", "--quiet", "--set-upstream", "origin", "HEAD"},
+	} {
+		_, err := repo.run(args...)
+		assert.Nil(t, err)
+	}
+
+	writeFile(t, path, "b.md", "B")
+	assert.Nil(t, repo.Sync("sync"))
+
+	out, err := repo.run("log", "--format=%s", "--name-only", "@{upstream}", "-1")
+	assert.Nil(t, err)
+	assert.Equal(t, out, "sync\n\nnotes/b.md\n")
+}
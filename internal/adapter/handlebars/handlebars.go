@@ -15,6 +15,7 @@ import (
 
 func Init(supportsUTF8 bool, logger util.Logger) {
 	helpers.RegisterConcat()
+	helpers.RegisterCSV(logger)
 	helpers.RegisterSubstring()
 	helpers.RegisterDate(logger)
 	helpers.RegisterJoin()
@@ -22,12 +23,19 @@ func Init(supportsUTF8 bool, logger util.Logger) {
 	helpers.RegisterList(supportsUTF8)
 	helpers.RegisterPrepend(logger)
 	helpers.RegisterShell(logger)
+	helpers.RegisterTOC()
+	helpers.RegisterWordCount()
 }
 
 // Template renders a parsed handlebars template.
 type Template struct {
+	// Content or path of the template.
+	name     string
 	template *raymond.Template
 	styler   core.Styler
+	// Indicates whether the template renders HTML, whose variables are
+	// escaped.
+	html bool
 }
 
 // Styler implements core.Template.
@@ -39,7 +47,10 @@ func (t *Template) Styler() core.Styler {
 func (t *Template) Render(context interface{}) (string, error) {
 	res, err := t.template.Exec(context)
 	if err != nil {
-		return "", errors.Wrap(err, "render template failed")
+		return "", errors.Wrap(core.ErrTemplate{Template: t.name, Err: err}, "render template failed")
+	}
+	if t.html {
+		return res, nil
 	}
 	return html.UnescapeString(res), nil
 }
@@ -47,6 +58,7 @@ func (t *Template) Render(context interface{}) (string, error) {
 // Loader loads and holds parsed handlebars templates.
 type Loader struct {
 	strings     map[string]*Template
+	htmls       map[string]*Template
 	files       map[string]*Template
 	lookupPaths []string
 	styler      core.Styler
@@ -64,6 +76,7 @@ type LoaderOpts struct {
 func NewLoader(opts LoaderOpts) *Loader {
 	return &Loader{
 		strings:     make(map[string]*Template),
+		htmls:       make(map[string]*Template),
 		files:       make(map[string]*Template),
 		lookupPaths: opts.LookupPaths,
 		styler:      opts.Styler,
@@ -89,20 +102,41 @@ func (l *Loader) LoadTemplate(content string) (core.Template, error) {
 	// Load new template.
 	vendorTempl, err := raymond.Parse(content)
 	if err != nil {
-		return nil, wrap(err)
+		return nil, wrap(core.ErrTemplate{Template: content, Err: err})
 	}
-	template = l.newTemplate(vendorTempl)
+	template = l.newTemplate(content, vendorTempl)
 	l.strings[content] = template
 	return template, nil
 }
 
+// LoadHTMLTemplate implements core.TemplateLoader.
+func (l *Loader) LoadHTMLTemplate(content string) (core.Template, error) {
+	wrap := errors.Wrapperf("load HTML template failed")
+
+	// Already loaded?
+	template, ok := l.htmls[content]
+	if ok {
+		return template, nil
+	}
+
+	// Load new template.
+	vendorTempl, err := raymond.Parse(content)
+	if err != nil {
+		return nil, wrap(core.ErrTemplate{Template: content, Err: err})
+	}
+	template = l.newTemplate(content, vendorTempl)
+	template.html = true
+	l.htmls[content] = template
+	return template, nil
+}
+
 // LoadTemplateAt implements core.TemplateLoader.
 func (l *Loader) LoadTemplateAt(path string) (core.Template, error) {
 	wrap := errors.Wrapper("load template file failed")
 
 	path, ok := l.locateTemplate(path)
 	if !ok {
-		return nil, wrap(fmt.Errorf("cannot find template at %s", path))
+		return nil, wrap(core.ErrTemplate{Template: path, Err: fmt.Errorf("cannot find template at %s", path)})
 	}
 
 	// Already loaded?
@@ -114,9 +148,9 @@ func (l *Loader) LoadTemplateAt(path string) (core.Template, error) {
 	// Load new template.
 	vendorTempl, err := raymond.ParseFile(path)
 	if err != nil {
-		return nil, wrap(err)
+		return nil, wrap(core.ErrTemplate{Template: path, Err: err})
 	}
-	template = l.newTemplate(vendorTempl)
+	template = l.newTemplate(path, vendorTempl)
 	l.files[path] = template
 	return template, nil
 }
@@ -146,7 +180,7 @@ func (l *Loader) locateTemplate(path string) (string, bool) {
 	return path, false
 }
 
-func (l *Loader) newTemplate(vendorTempl *raymond.Template) *Template {
+func (l *Loader) newTemplate(name string, vendorTempl *raymond.Template) *Template {
 	vendorTempl.RegisterHelpers(l.helpers)
-	return &Template{vendorTempl, l.styler}
+	return &Template{name: name, template: vendorTempl, styler: l.styler}
 }
@@ -1,6 +1,7 @@
 package handlebars
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -95,6 +96,25 @@ func TestLookupPaths(t *testing.T) {
 	test("subdir/test3.tpl", "Test 3") // relative
 }
 
+func TestTemplateErrors(t *testing.T) {
+	sut := testLoader(LoaderOpts{})
+
+	_, err := sut.LoadTemplate("{{#if}}")
+	var templateErr core.ErrTemplate
+	assert.True(t, errors.As(err, &templateErr))
+	assert.Equal(t, templateErr.Template, "{{#if}}")
+
+	templ, err := sut.LoadTemplate(`{{substring "abc"}}`)
+	assert.Nil(t, err)
+	_, err = templ.Render(nil)
+	templateErr = core.ErrTemplate{}
+	assert.True(t, errors.As(err, &templateErr))
+	assert.Equal(t, templateErr.Template, `{{substring "abc"}}`)
+
+	_, err = sut.LoadTemplateAt("missing.tpl")
+	assert.True(t, errors.As(err, &templateErr))
+}
+
 func TestRenderString(t *testing.T) {
 	testString(t,
 		"Goodbye, {{name}}",
@@ -133,6 +153,20 @@ func TestDoesntEscapeHTML(t *testing.T) {
 	)
 }
 
+func TestHTMLTemplateEscapesVariables(t *testing.T) {
+	sut := testLoader(LoaderOpts{})
+
+	templ, err := sut.LoadHTMLTemplate("<h1>{{title}}</h1>{{{content}}}")
+	assert.Nil(t, err)
+
+	actual, err := templ.Render(map[string]string{
+		"title":   "Tom & <Jerry>",
+		"content": "<p>A &lt;b&gt; tag</p>",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, actual, "<h1>Tom &amp; &lt;Jerry&gt;</h1><p>A &lt;b&gt; tag</p>")
+}
+
 func TestConcatHelper(t *testing.T) {
 	testString(t, "{{concat '> ' 'A quote'}}", nil, "> A quote")
 }
@@ -163,6 +197,24 @@ type testJSONObject struct {
 	List    []string `json:"stringList"`
 }
 
+func TestCSVHelper(t *testing.T) {
+	test := func(value interface{}, expected string) {
+		context := map[string]interface{}{"value": value}
+		testString(t, "{{csv value}}", context, expected)
+	}
+
+	test(nil, "")
+	test("foo", "foo")
+	test(`a "quoted", title`, `"a ""quoted"", title"`)
+	test("two\nlines", "\"two\nlines\"")
+	test([]string{"foo", "bar"}, `"foo,bar"`)
+	test([]interface{}{"foo", 42}, `"foo,42"`)
+	test(42, "42")
+	test(time.Date(2009, 11, 17, 20, 34, 58, 0, time.UTC), "2009-11-17T20:34:58Z")
+	test(time.Time{}, "")
+	test(map[string]interface{}{"foo": "bar"}, `"{""foo"":""bar""}"`)
+}
+
 func TestJSONHelper(t *testing.T) {
 	test := func(value interface{}, expected string) {
 		context := map[string]interface{}{"value": value}
@@ -177,6 +229,11 @@ func TestJSONHelper(t *testing.T) {
 		Foo:  "baz",
 		List: []string{"foo", "bar"},
 	}, `{"Foo":"baz","stringList":["foo","bar"]}`)
+
+	// extra variables of a new note
+	testString(t, "{{json extra}}", map[string]interface{}{
+		"extra": map[string]string{"source": "https://example.com", "author": "Jane"},
+	}, `{"author":"Jane","source":"https://example.com"}`)
 }
 
 func TestPrependHelper(t *testing.T) {
@@ -211,6 +268,12 @@ func TestLinkHelper(t *testing.T) {
 	assert.Equal(t, actual, "path/to note.md - An interesting subject")
 }
 
+func TestURLTitleHelper(t *testing.T) {
+	testString(t, `{{url-title "https://example.com"}}`, nil, "Example Domain")
+	// Falls back on the URL when its title was not fetched.
+	testString(t, `{{url-title "https://unknown.com"}}`, nil, "https://unknown.com")
+}
+
 func TestSlugHelper(t *testing.T) {
 	// inline
 	testString(t,
@@ -226,6 +289,87 @@ func TestSlugHelper(t *testing.T) {
 	)
 }
 
+func TestSlugIDHelper(t *testing.T) {
+	context := map[string]interface{}{"id": "4ufx"}
+	testString(t, `{{slug-id "This will be slugified!"}}`, context, "this-will-be-slugified-4ufx")
+	testString(t, `{{slug-id ""}}`, context, "4ufx")
+	testString(t, `{{slug-id "No ID"}}`, nil, "no-id")
+}
+
+func TestWordCountHelper(t *testing.T) {
+	testString(t, `{{word-count text="A few words,  spread on two lines"}}`, nil, "7")
+	testString(t, `{{word-count text=""}}`, nil, "0")
+	// Falls back on the variable of the context.
+	testString(t, `{{word-count}}`, map[string]interface{}{"word-count": 42}, "42")
+	testString(t, `{{#each notes}}{{word-count}} {{/each}}`, map[string]interface{}{
+		"notes": []map[string]interface{}{{"word-count": 1}, {"word-count": 2}},
+	}, "1 2 ")
+}
+
+func TestTOCHelper(t *testing.T) {
+	context := map[string]interface{}{"text": "# Title\n## Ideas\n### Sub-ideas"}
+	testString(t, `{{toc content=text}}`, context, "- [Ideas](#ideas)\n  - [Sub-ideas](#sub-ideas)\n")
+	testString(t, `{{toc content=text depth=1}}`, context, "- [Ideas](#ideas)\n")
+	// Falls back on the content of the note.
+	testString(t, `{{toc}}`, map[string]interface{}{"raw-content": "## Ideas"}, "- [Ideas](#ideas)\n")
+}
+
+func TestLinkCountHelper(t *testing.T) {
+	testString(t, `{{link-count "popular.md"}}`, nil, "3")
+	testString(t, `{{link-count "orphan.md"}}`, nil, "0")
+}
+
+func TestReviewDueHelper(t *testing.T) {
+	testString(t, `{{review-due "flashcard.md"}}`, nil, "2021-10-12")
+	testString(t, `{{review-due "note.md"}}`, nil, "")
+}
+
+func TestRelPathHelper(t *testing.T) {
+	testString(t, `{{rel-path to="assets/logo.png" from="journal/2021"}}`, nil, "../../assets/logo.png")
+	testString(t, `{{rel-path to="/notebook/ref/book.md"}}`, nil, "ref/book.md")
+	testString(t, `{{rel-path to="ref/book.md" from="/notebook/ref"}}`, nil, "book.md")
+	testString(t, `{{rel-path from="ref"}}`, nil, "")
+	// Falls back on the variable of the context.
+	testString(t, `{{rel-path}}`, map[string]interface{}{"rel-path": "journal/note.md"}, "journal/note.md")
+}
+
+func TestCustomTemplateHelper(t *testing.T) {
+	sut := testLoader(LoaderOpts{})
+	sut.RegisterHelper("greet", helpers.NewCustomHelper("greet", core.HelperConfig{
+		Params:   []string{"name", "greeting"},
+		Template: "{{greeting}}, {{slug name}}!",
+	}, sut, &util.NullLogger))
+	sut.RegisterHelper("hello", helpers.NewCustomHelper("hello", core.HelperConfig{
+		Template: `{{greet "World" "Hello"}}`,
+	}, sut, &util.NullLogger))
+
+	templ, err := sut.LoadTemplate(`{{greet title "Hi"}} {{hello}}`)
+	assert.Nil(t, err)
+	actual, err := templ.Render(map[string]interface{}{"title": "Jane Doe"})
+	assert.Nil(t, err)
+	assert.Equal(t, actual, "Hi, jane-doe! Hello, world!")
+
+	// The number of arguments is checked.
+	templ, err = sut.LoadTemplate(`{{greet "Jane"}}`)
+	assert.Nil(t, err)
+	_, err = templ.Render(nil)
+	assert.NotNil(t, err)
+}
+
+func TestCustomCommandHelper(t *testing.T) {
+	sut := testLoader(LoaderOpts{})
+	sut.RegisterHelper("initials", helpers.NewCustomHelper("initials", core.HelperConfig{
+		Params:  []string{"first", "last"},
+		Command: `echo "$1" "$2" | sed 's/\([A-Z]\)[a-z]* */\1/g'`,
+	}, sut, &util.NullLogger))
+
+	templ, err := sut.LoadTemplate(`{{initials "Jane" name}}`)
+	assert.Nil(t, err)
+	actual, err := templ.Render(map[string]interface{}{"name": "Doe"})
+	assert.Nil(t, err)
+	assert.Equal(t, actual, "JD")
+}
+
 func TestDateHelper(t *testing.T) {
 	context := map[string]interface{}{"now": time.Date(2009, 11, 17, 20, 34, 58, 651387237, time.UTC)}
 	testString(t, "{{date now}}", context, "2009-11-17")
@@ -259,6 +403,17 @@ func TestShellHelper(t *testing.T) {
 	testString(t, `{{sh "echo hello | tr '[:lower:]' '[:upper:]'"}}`, nil, "HELLO")
 }
 
+func TestUntrustedShellHelper(t *testing.T) {
+	loader := testLoader(LoaderOpts{})
+	loader.RegisterHelper("sh", helpers.NewUntrustedShellHelper(&util.NullLogger))
+
+	template, err := loader.LoadTemplate(`{{sh "echo 'Hello, world!'"}}{{#sh "cat"}}Block{{/sh}}`)
+	assert.Nil(t, err)
+	actual, err := template.Render(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, actual, "")
+}
+
 func TestStyleHelper(t *testing.T) {
 	// inline
 	testString(t, "{{style 'single' 'Some text'}}", nil, "single(Some text)")
@@ -286,5 +441,32 @@ func testLoader(opts LoaderOpts) *Loader {
 	}
 	loader.RegisterHelper("format-link", helpers.NewLinkHelper(formatter, &util.NullLogger))
 
+	findURLMetadata := func(url string) (*core.URLMetadata, error) {
+		if url == "https://example.com" {
+			return &core.URLMetadata{URL: url, Title: "Example Domain", Status: 200}, nil
+		}
+		return nil, nil
+	}
+	loader.RegisterHelper("url-title", helpers.NewURLTitleHelper(findURLMetadata, &util.NullLogger))
+	loader.RegisterHelper("slug-id", helpers.NewSlugIDHelper("en", &util.NullLogger))
+	loader.RegisterHelper("rel-path", helpers.NewRelPathHelper("/notebook", &util.NullLogger))
+
+	countLinks := func(path string) (int, error) {
+		if path == "popular.md" {
+			return 3, nil
+		}
+		return 0, nil
+	}
+	loader.RegisterHelper("link-count", helpers.NewLinkCountHelper(countLinks, &util.NullLogger))
+
+	reviewDue := func(path string) (*time.Time, error) {
+		if path == "flashcard.md" {
+			due := time.Date(2021, 10, 12, 0, 0, 0, 0, time.Local)
+			return &due, nil
+		}
+		return nil, nil
+	}
+	loader.RegisterHelper("review-due", helpers.NewReviewDueHelper(reviewDue, &util.NullLogger))
+
 	return loader
 }
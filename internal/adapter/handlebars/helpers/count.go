@@ -0,0 +1,41 @@
+package helpers
+
+import (
+	"strings"
+
+	"github.com/aymerick/raymond"
+	"github.com/mickael-menu/zk/internal/util"
+)
+
+// RegisterWordCount registers a {{word-count}} template helper which counts
+// the words of the given `text`.
+//
+// Without arguments, it renders the `word-count` variable of the template
+// context instead, e.g. when formatting a note.
+//
+// {{word-count text="A few words"}} -> 3
+// {{word-count}} -> 42
+func RegisterWordCount() {
+	raymond.RegisterHelper("word-count", func(options *raymond.Options) interface{} {
+		text, ok := options.HashProp("text").(string)
+		if !ok {
+			return options.Value("word-count")
+		}
+		return len(strings.Fields(text))
+	})
+}
+
+// NewLinkCountHelper creates a new template helper returning the number of
+// notes linking to the note at the given path.
+//
+// {{link-count "path/to/note.md"}} -> 3
+func NewLinkCountHelper(count func(path string) (int, error), logger util.Logger) interface{} {
+	return func(path string) int {
+		res, err := count(path)
+		if err != nil {
+			logger.Err(err)
+			return 0
+		}
+		return res
+	}
+}
@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aymerick/raymond"
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// RegisterCSV registers a {{csv}} template helper which serializes its
+// parameter to a CSV field, quoted when needed.
+//
+// Lists are joined with commas, dates use the RFC 3339 format and
+// dictionaries are serialized to JSON.
+//
+// {{csv tags}} -> "fiction,literature"
+func RegisterCSV(logger util.Logger) {
+	raymond.RegisterHelper("csv", func(arg interface{}) string {
+		var field string
+		switch arg := arg.(type) {
+		case nil:
+			field = ""
+		case string:
+			field = arg
+		case []string:
+			field = strings.Join(arg, ",")
+		case []interface{}:
+			items := []string{}
+			for _, item := range arg {
+				items = append(items, fmt.Sprint(item))
+			}
+			field = strings.Join(items, ",")
+		case time.Time:
+			if !arg.IsZero() {
+				field = arg.Format(time.RFC3339)
+			}
+		case fmt.Stringer:
+			field = arg.String()
+		case map[string]interface{}:
+			jsonBytes, err := json.Marshal(arg)
+			if err != nil {
+				logger.Err(errors.Wrapf(err, "%v: not a serializable argument for {{csv}}", arg))
+				return ""
+			}
+			field = string(jsonBytes)
+		default:
+			field = fmt.Sprint(arg)
+		}
+
+		if strings.ContainsAny(field, ",\"\r\n") {
+			field = `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+		}
+		return field
+	})
+}
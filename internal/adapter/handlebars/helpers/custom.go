@@ -0,0 +1,93 @@
+package helpers
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aymerick/raymond"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/exec"
+)
+
+// NewCustomHelper creates a template helper defined in the notebook config,
+// under [helper.<name>].
+//
+// A template helper renders its snippet with the named parameters as context:
+//
+// [helper.greet]
+// params = ["name"]
+// template = "Hello, {{name}}!"
+//
+// {{greet "Mickael"}} -> Hello, Mickael!
+//
+// A command helper runs a shell command with the parameters as positional
+// arguments, and renders its output:
+//
+// [helper.initial]
+// params = ["name"]
+// command = "echo \"$1\" | cut -c1"
+//
+// {{initial "Mickael"}} -> M
+func NewCustomHelper(name string, config core.HelperConfig, loader core.TemplateLoader, logger util.Logger) interface{} {
+	var run func(args []string) (string, error)
+	if config.Command != "" {
+		run = func(args []string) (string, error) {
+			output, err := exec.CommandFromString(config.Command, args...).Output()
+			return strings.TrimSpace(string(output)), err
+		}
+	} else {
+		// The snippet is parsed on first use, to let it call any helper
+		// registered with the loader, including other custom helpers.
+		var (
+			once     sync.Once
+			template core.Template
+			err      error
+		)
+		run = func(args []string) (string, error) {
+			once.Do(func() {
+				template, err = loader.LoadTemplate(config.Template)
+			})
+			if err != nil {
+				return "", err
+			}
+			context := map[string]interface{}{}
+			for i, param := range config.Params {
+				context[param] = args[i]
+			}
+			return template.Render(context)
+		}
+	}
+
+	return newFixedArityHelper(len(config.Params), func(args []string) string {
+		output, err := run(args)
+		if err != nil {
+			logger.Err(errors.Wrapf(err, "{{%s}} template helper failed", name))
+			return ""
+		}
+		return output
+	})
+}
+
+// newFixedArityHelper creates a helper function expecting exactly `arity`
+// string arguments, as Handlebars checks the number of arguments given to a
+// helper against its signature.
+func newFixedArityHelper(arity int, fn func(args []string) string) interface{} {
+	stringType := reflect.TypeOf("")
+	in := make([]reflect.Type, arity+1)
+	for i := 0; i < arity; i++ {
+		in[i] = stringType
+	}
+	in[arity] = reflect.TypeOf(&raymond.Options{})
+	funcType := reflect.FuncOf(in, []reflect.Type{stringType}, false)
+
+	return reflect.MakeFunc(funcType, func(values []reflect.Value) []reflect.Value {
+		args := make([]string, arity)
+		for i := 0; i < arity; i++ {
+			args[i] = values[i].String()
+		}
+		return []reflect.Value{reflect.ValueOf(fn(args))}
+	}).Interface()
+}
@@ -0,0 +1,47 @@
+package helpers
+
+import (
+	"path/filepath"
+
+	"github.com/aymerick/raymond"
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// NewRelPathHelper creates a new template helper returning the path `to` a
+// file, relative to the directory `from`. Relative paths are resolved from
+// the notebook root, which is the default `from` directory.
+//
+// Without arguments, it renders the `rel-path` variable of the template
+// context instead, e.g. when formatting a note.
+//
+// {{rel-path to="assets/logo.png" from="journal/2021"}} -> ../../assets/logo.png
+// {{rel-path to="/home/user/notes/ref/book.md"}} -> ref/book.md
+// {{rel-path}} -> journal/2021/note.md
+func NewRelPathHelper(notebookPath string, logger util.Logger) interface{} {
+	abs := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(notebookPath, path)
+	}
+
+	return func(options *raymond.Options) interface{} {
+		if len(options.Hash()) == 0 {
+			return options.Value("rel-path")
+		}
+
+		to := options.HashStr("to")
+		if to == "" {
+			logger.Printf("the {{rel-path}} template helper is expecting a `to` argument")
+			return ""
+		}
+
+		path, err := filepath.Rel(abs(options.HashStr("from")), abs(to))
+		if err != nil {
+			logger.Err(errors.Wrap(err, "{{rel-path}} failed"))
+			return ""
+		}
+		return filepath.ToSlash(path)
+	}
+}
@@ -0,0 +1,26 @@
+package helpers
+
+import (
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util"
+)
+
+// NewReviewDueHelper creates a new template helper returning the date of the
+// next review of the note at the given path, or an empty string if it is not
+// in the review queue.
+//
+// {{review-due "path/to/note.md"}} -> 2021-10-12
+func NewReviewDueHelper(due func(path string) (*time.Time, error), logger util.Logger) interface{} {
+	return func(path string) string {
+		date, err := due(path)
+		if err != nil {
+			logger.Err(err)
+			return ""
+		}
+		if date == nil {
+			return ""
+		}
+		return date.Format("2006-01-02")
+	}
+}
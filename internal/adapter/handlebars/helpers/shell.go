@@ -28,3 +28,12 @@ func RegisterShell(logger util.Logger) {
 		return strings.TrimSpace(string(output))
 	})
 }
+
+// NewUntrustedShellHelper creates a {{sh}} template helper which refuses to
+// run shell commands, for the notebooks not trusted by the user.
+func NewUntrustedShellHelper(logger util.Logger) interface{} {
+	return func(arg string, options *raymond.Options) string {
+		logger.Printf("{{sh}} is disabled in untrusted notebooks, run `zk trust` to allow it: %s", arg)
+		return ""
+	}
+}
@@ -1,6 +1,8 @@
 package helpers
 
 import (
+	"strings"
+
 	"github.com/aymerick/raymond"
 	"github.com/gosimple/slug"
 	"github.com/mickael-menu/zk/internal/util"
@@ -23,3 +25,22 @@ func NewSlugHelper(lang string, logger util.Logger) interface{} {
 		}
 	}
 }
+
+// NewSlugIDHelper creates a new template helper to slugify text, suffixed
+// with the `id` of the template context to keep it unique. Either part is
+// omitted when empty.
+//
+// {{slug-id "An interesting subject"}} -> an-interesting-subject-4ufx
+// {{slug-id ""}} -> 4ufx
+func NewSlugIDHelper(lang string, logger util.Logger) interface{} {
+	return func(text string, options *raymond.Options) string {
+		parts := []string{}
+		if s := slug.MakeLang(text, lang); s != "" {
+			parts = append(parts, s)
+		}
+		if id := options.ValueStr("id"); id != "" {
+			parts = append(parts, id)
+		}
+		return strings.Join(parts, "-")
+	}
+}
@@ -0,0 +1,26 @@
+package helpers
+
+import (
+	"github.com/aymerick/raymond"
+	"github.com/mickael-menu/zk/internal/core"
+)
+
+// RegisterTOC registers a {{toc}} template helper which generates a table of
+// contents linking to the headings of the given `content`. The optional
+// `depth` limits the levels of headings listed.
+//
+// Without `content`, it uses the `raw-content` variable of the template
+// context instead, e.g. when formatting a note.
+//
+// {{toc content="## Ideas"}} -> - [Ideas](#ideas)
+// {{toc depth=2}}
+func RegisterTOC() {
+	raymond.RegisterHelper("toc", func(options *raymond.Options) string {
+		content, ok := options.HashProp("content").(string)
+		if !ok {
+			content = options.ValueStr("raw-content")
+		}
+		depth, _ := options.HashProp("depth").(int)
+		return core.TableOfContents(content, depth)
+	})
+}
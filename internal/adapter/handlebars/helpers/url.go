@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util"
+)
+
+// NewURLTitleHelper creates a new template helper returning the title of an
+// external URL, as fetched in the background by the LSP server. Falls back on
+// the URL itself when the title is unknown.
+//
+// {{url-title "https://example.com"}} -> Example Domain
+func NewURLTitleHelper(find func(url string) (*core.URLMetadata, error), logger util.Logger) interface{} {
+	return func(url string) string {
+		metadata, err := find(url)
+		if err != nil {
+			logger.Err(err)
+		}
+		if metadata == nil || metadata.Title == "" {
+			return url
+		}
+		return metadata.Title
+	}
+}
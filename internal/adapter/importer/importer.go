@@ -0,0 +1,233 @@
+package importer
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+	"gopkg.in/yaml.v2"
+)
+
+// sourceFile is a file read from an import source.
+type sourceFile struct {
+	// Path relative to the source root, with forward slashes.
+	Path     string
+	Content  []byte
+	Modified time.Time
+}
+
+// ReadObsidianVault reads the notes and attachments of the Obsidian vault at
+// the given path.
+//
+// The frontmatter of the notes is removed and its values are exposed as extra
+// variables to the zk templates.
+func ReadObsidianVault(path string) (*core.ImportSource, error) {
+	files, err := readDir(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: failed to read the Obsidian vault", path)
+	}
+
+	return newSource(files, func(file sourceFile) core.ImportedNote {
+		extra, body := parseFrontmatter(string(file.Content))
+		return core.ImportedNote{
+			Path:    file.Path,
+			Title:   paths.FilenameStem(file.Path),
+			Content: body,
+			Extra:   extra,
+			Created: file.Modified,
+		}
+	}), nil
+}
+
+// ReadNotionExport reads the notes and attachments of a Notion Markdown
+// export, either unzipped or as a ZIP archive.
+//
+// Notion appends a unique ID to the exported filenames, which is removed from
+// the titles. The title heading of the notes is removed as well, since the zk
+// templates usually generate it.
+func ReadNotionExport(path string) (*core.ImportSource, error) {
+	var files []sourceFile
+	var err error
+	if strings.ToLower(filepath.Ext(path)) == ".zip" {
+		files, err = readZip(path)
+	} else {
+		files, err = readDir(path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: failed to read the Notion export", path)
+	}
+
+	return newSource(files, func(file sourceFile) core.ImportedNote {
+		title, body := parseNotionTitle(string(file.Content))
+		if title == "" {
+			title = notionIDRegex.ReplaceAllString(paths.FilenameStem(file.Path), "")
+		}
+		return core.ImportedNote{
+			Path:    file.Path,
+			Title:   title,
+			Content: body,
+			Extra:   map[string]string{},
+			Created: file.Modified,
+		}
+	}), nil
+}
+
+// newSource splits the given files between notes and assets.
+func newSource(files []sourceFile, parseNote func(file sourceFile) core.ImportedNote) *core.ImportSource {
+	source := core.ImportSource{
+		Notes:  []core.ImportedNote{},
+		Assets: []core.ImportedAsset{},
+	}
+	for _, file := range files {
+		if strings.ToLower(filepath.Ext(file.Path)) == ".md" {
+			source.Notes = append(source.Notes, parseNote(file))
+		} else {
+			source.Assets = append(source.Assets, core.ImportedAsset{
+				Path:    file.Path,
+				Content: file.Content,
+			})
+		}
+	}
+	return &source
+}
+
+// readDir reads recursively the files in the given directory, ignoring the
+// hidden ones such as the .obsidian config directory.
+func readDir(root string) ([]sourceFile, error) {
+	files := []sourceFile{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, sourceFile{
+			Path:     filepath.ToSlash(relPath),
+			Content:  content,
+			Modified: info.ModTime(),
+		})
+		return nil
+	})
+
+	return files, err
+}
+
+// readZip reads the files in the given ZIP archive.
+func readZip(path string) ([]sourceFile, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	files := []sourceFile{}
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(filepath.Base(f.Name), ".") {
+			continue
+		}
+		name := filepath.ToSlash(filepath.Clean("/" + f.Name))[1:]
+		if name == "" {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, f.Name)
+		}
+		files = append(files, sourceFile{
+			Path:     name,
+			Content:  content,
+			Modified: f.Modified,
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+var frontmatterRegex = regexp.MustCompile(`(?s)^---[ \t]*\r?\n(.*?)\r?\n---[ \t]*(?:\r?\n|$)`)
+
+// parseFrontmatter extracts the scalar values of the YAML frontmatter from a
+// note content. Lists are joined with commas.
+func parseFrontmatter(content string) (map[string]string, string) {
+	extra := map[string]string{}
+
+	loc := frontmatterRegex.FindStringSubmatchIndex(content)
+	if loc == nil {
+		return extra, content
+	}
+	values := yaml.MapSlice{}
+	if err := yaml.Unmarshal([]byte(content[loc[2]:loc[3]]), &values); err != nil {
+		// Not a valid frontmatter, keep it in the content.
+		return extra, content
+	}
+
+	for _, item := range values {
+		key, ok := item.Key.(string)
+		if !ok {
+			continue
+		}
+		switch value := item.Value.(type) {
+		case nil, yaml.MapSlice:
+			continue
+		case []interface{}:
+			items := []string{}
+			for _, v := range value {
+				items = append(items, fmt.Sprint(v))
+			}
+			extra[key] = strings.Join(items, ", ")
+		default:
+			extra[key] = fmt.Sprint(value)
+		}
+	}
+
+	return extra, strings.TrimLeft(content[loc[1]:], "\r\n")
+}
+
+// notionIDRegex matches the unique ID appended by Notion to the exported
+// filenames.
+var notionIDRegex = regexp.MustCompile(`\s+[0-9a-f]{32}$`)
+
+var notionTitleRegex = regexp.MustCompile(`^\s*#\s+(.+?)\s*(?:\r?\n|$)`)
+
+// parseNotionTitle extracts the title from the leading heading of a Notion
+// note.
+func parseNotionTitle(content string) (string, string) {
+	loc := notionTitleRegex.FindStringSubmatchIndex(content)
+	if loc == nil {
+		return "", content
+	}
+	return content[loc[2]:loc[3]], strings.TrimLeft(content[loc[1]:], "\r\n")
+}
@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+var modified = time.Date(2021, 10, 19, 10, 30, 0, 0, time.UTC)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for path, content := range files {
+		path = filepath.Join(root, path)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.Nil(t, ioutil.WriteFile(path, []byte(content), os.ModePerm))
+		assert.Nil(t, os.Chtimes(path, modified, modified))
+	}
+}
+
+func TestReadObsidianVault(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".obsidian/app.json":      "{}",
+		"Index.md":                "---\ntags: [project, idea]\nstatus: draft\naliases:\n---\nSee [[Daily/Meeting]].\n",
+		"Daily/Meeting.md":        "No frontmatter\n",
+		"attachments/diagram.png": "PNG",
+		"Daily/.trash/Deleted.md": "Deleted",
+	})
+
+	source, err := ReadObsidianVault(root)
+
+	assert.Nil(t, err)
+	assert.Equal(t, source, &core.ImportSource{
+		Notes: []core.ImportedNote{
+			{
+				Path:    "Daily/Meeting.md",
+				Title:   "Meeting",
+				Content: "No frontmatter\n",
+				Extra:   map[string]string{},
+				Created: modified.Local(),
+			},
+			{
+				Path:    "Index.md",
+				Title:   "Index",
+				Content: "See [[Daily/Meeting]].\n",
+				Extra:   map[string]string{"tags": "project, idea", "status": "draft"},
+				Created: modified.Local(),
+			},
+		},
+		Assets: []core.ImportedAsset{
+			{Path: "attachments/diagram.png", Content: []byte("PNG")},
+		},
+	})
+}
+
+func TestReadNotionExportZip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.zip")
+	f, err := os.Create(path)
+	assert.Nil(t, err)
+	archive := zip.NewWriter(f)
+	for _, file := range []struct{ name, content string }{
+		{"Project 0123456789abcdef0123456789abcdef.md", "# My project\n\nSee [Task](Project%200123456789abcdef0123456789abcdef/Task%20fedcba9876543210fedcba9876543210.md)\n"},
+		{"Project 0123456789abcdef0123456789abcdef/Task fedcba9876543210fedcba9876543210.md", "No title heading\n"},
+		{"Project 0123456789abcdef0123456789abcdef/photo.jpg", "JPG"},
+	} {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Modified: modified})
+		assert.Nil(t, err)
+		_, err = w.Write([]byte(file.content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, archive.Close())
+	assert.Nil(t, f.Close())
+
+	source, err := ReadNotionExport(path)
+
+	assert.Nil(t, err)
+	assert.Equal(t, len(source.Notes), 2)
+	assert.Equal(t, source.Notes[0].Path, "Project 0123456789abcdef0123456789abcdef.md")
+	assert.Equal(t, source.Notes[0].Title, "My project")
+	assert.Equal(t, source.Notes[0].Content, "See [Task](Project%200123456789abcdef0123456789abcdef/Task%20fedcba9876543210fedcba9876543210.md)\n")
+	assert.Equal(t, source.Notes[1].Title, "Task")
+	assert.Equal(t, source.Notes[1].Content, "No title heading\n")
+	assert.Equal(t, source.Assets, []core.ImportedAsset{
+		{Path: "Project 0123456789abcdef0123456789abcdef/photo.jpg", Content: []byte("JPG")},
+	})
+}
@@ -0,0 +1,155 @@
+package lsp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+const cmdPasteAsset = "zk.pasteAsset"
+
+type cmdPasteAssetOpts struct {
+	Location protocol.Location `json:"location"`
+	Data     string            `json:"data,omitempty"`
+	Path     string            `json:"path,omitempty"`
+	Filename string            `json:"filename,omitempty"`
+	Title    string            `json:"title,omitempty"`
+	// The edit is applied by the server unless false.
+	ApplyEdit *bool `json:"applyEdit,omitempty"`
+}
+
+// pastedAsset is the result of the zk.pasteAsset command.
+type pastedAsset struct {
+	Path    string `json:"path"`
+	AbsPath string `json:"absPath"`
+	Link    string `json:"link"`
+	// Edit inserting the link at the given location.
+	Edit protocol.WorkspaceEdit `json:"edit"`
+}
+
+// executeCommandPasteAsset saves the given data or file in the asset
+// directory of the notebook, and inserts a link to it at the given location,
+// e.g. to paste an image from the clipboard.
+func (s *Server) executeCommandPasteAsset(context *glsp.Context, args []interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("zk.pasteAsset expects a notebook path and a dictionary of options as arguments")
+	}
+	wd, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.pasteAsset expects a notebook path as first argument, got: %v", args[0])
+	}
+	arg, ok := args[1].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("zk.pasteAsset expects a dictionary of options as second argument, got: %v", args[1])
+	}
+	var opts cmdPasteAssetOpts
+	err := unmarshalJSON(arg, &opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse zk.pasteAsset args, got: %v", arg)
+	}
+	if (opts.Data == "") == (opts.Path == "") {
+		return nil, fmt.Errorf("zk.pasteAsset expects either the data or the path of the asset")
+	}
+
+	notePath, err := uriToPath(opts.Location.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := opts.Filename
+	var content []byte
+	if opts.Path != "" {
+		content, err = ioutil.ReadFile(opts.Path)
+		if err != nil {
+			return nil, err
+		}
+		if filename == "" {
+			filename = filepath.Base(opts.Path)
+		}
+	} else {
+		content, err = decodeAssetData(opts.Data)
+		if err != nil {
+			return nil, errors.Wrap(err, "zk.pasteAsset expects base64 data")
+		}
+	}
+
+	notebook, err := s.notebooks.Open(wd)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWritable(notebook, cmdPasteAsset); err != nil {
+		return nil, err
+	}
+
+	relNotePath, err := notebook.RelPath(notePath)
+	if err != nil {
+		return nil, err
+	}
+	path, err := notebook.NewAsset(core.NewAssetOpts{
+		Content:  content,
+		Filename: filename,
+		NotePath: relNotePath,
+		Date:     time.Now(),
+		Origin:   lspOrigin(cmdPasteAsset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	absPath := filepath.Join(notebook.Path, path)
+	href, err := filepath.Rel(filepath.Dir(notePath), absPath)
+	if err != nil {
+		return nil, err
+	}
+	href = strings.ReplaceAll(url.PathEscape(filepath.ToSlash(href)), "%2F", "/")
+	title := opts.Title
+	if title == "" {
+		title = filepath.Base(path)
+	}
+	link := fmt.Sprintf("[%s](%s)", title, href)
+	if core.IsImagePath(path) {
+		link = "!" + link
+	}
+
+	result := pastedAsset{
+		Path:    path,
+		AbsPath: absPath,
+		Link:    link,
+		Edit: protocol.WorkspaceEdit{
+			Changes: map[string][]protocol.TextEdit{
+				opts.Location.URI: {{Range: opts.Location.Range, NewText: link}},
+			},
+		},
+	}
+
+	if opts.ApplyEdit == nil || *opts.ApplyEdit {
+		go context.Call(protocol.ServerWorkspaceApplyEdit, protocol.ApplyWorkspaceEditParams{
+			Edit: result.Edit,
+		}, nil)
+	}
+
+	return result, nil
+}
+
+// decodeAssetData decodes the base64 content of an asset, which can be
+// given as a data URL, e.g. `data:image/png;base64,iVBORw0KGgo...`.
+func decodeAssetData(data string) ([]byte, error) {
+	if strings.HasPrefix(data, "data:") {
+		i := strings.Index(data, ",")
+		if i < 0 || !strings.HasSuffix(data[:i], ";base64") {
+			return nil, fmt.Errorf("invalid data URL")
+		}
+		data = data[i+1:]
+	}
+	data = strings.Join(strings.Fields(data), "")
+	return base64.StdEncoding.DecodeString(data)
+}
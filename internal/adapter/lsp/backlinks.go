@@ -0,0 +1,292 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// methodBacklinks is a custom request returning the backlinks of a note with
+// their context, for clients displaying them in a dedicated panel.
+const methodBacklinks = "zk/backlinks"
+
+// backlink is a link to a note, found in another note.
+type backlink struct {
+	URI   protocol.DocumentUri `json:"uri"`
+	Range protocol.Range       `json:"range"`
+	// Path of the linking note, relative to the notebook root.
+	Path string `json:"path"`
+	// Title of the linking note.
+	Title string `json:"title"`
+	// Nearest heading preceding the link in the linking note.
+	Heading string `json:"heading,omitempty"`
+	// Line of the linking note containing the link.
+	Context string `json:"context"`
+	// Human-readable location of the link, made of the title of the linking
+	// note and its nearest heading.
+	ContainerName string `json:"containerName"`
+}
+
+// handler wraps the LSP protocol handler to serve the custom requests of zk.
+type handler struct {
+	protocol.Handler
+	server *Server
+}
+
+// glsp.Handler interface
+func (h *handler) Handle(context *glsp.Context) (r interface{}, validMethod bool, validParams bool, err error) {
+	id := h.server.metrics.nextRequestID()
+	start := time.Now()
+	r, validMethod, validParams, err = h.dispatch(context)
+	h.server.logRequest(id, context.Method, time.Since(start), err)
+	return
+}
+
+// dispatch handles a request with the GLSP handler, or the custom requests
+// of zk.
+func (h *handler) dispatch(context *glsp.Context) (r interface{}, validMethod bool, validParams bool, err error) {
+	if context.Method != methodBacklinks && context.Method != methodPreview {
+		r, validMethod, validParams, err = h.Handler.Handle(context)
+		return r, validMethod, validParams, userError(err)
+	}
+	if !h.IsInitialized() {
+		return nil, true, true, errors.New("server not initialized")
+	}
+
+	validMethod = true
+	switch context.Method {
+	case methodBacklinks:
+		var params protocol.TextDocumentPositionParams
+		if err = json.Unmarshal(context.Params, &params); err == nil {
+			validParams = true
+			doc, ok := h.server.documents.Get(params.TextDocument.URI)
+			if !ok {
+				return []backlink{}, validMethod, validParams, nil
+			}
+			r, err = h.server.backlinksAt(doc, params.Position)
+			err = userError(err)
+		}
+
+	case methodPreview:
+		var params previewParams
+		if err = json.Unmarshal(context.Params, &params); err == nil {
+			validParams = true
+			r, err = h.server.preview(params.TextDocument.URI)
+			err = userError(err)
+		}
+	}
+	return
+}
+
+// backlinksAt returns the links to the note targeted by the link at the given
+// position, or to the document itself when there's no link.
+func (s *Server) backlinksAt(doc *document, pos protocol.Position) ([]backlink, error) {
+	backlinks := []backlink{}
+
+	notebook, err := s.notebookOf(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := doc.DocumentLinkAt(pos)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		href, err := notebook.RelPath(doc.Path)
+		if err != nil {
+			return nil, err
+		}
+		link = &documentLink{Href: href}
+	}
+
+	target, err := s.noteForLink(*link, doc, notebook)
+	if target == nil || err != nil {
+		return backlinks, err
+	}
+	return s.backlinksTo(target.MinimalNote, notebook)
+}
+
+// backlinksTo returns the links to the given note, found in other notes.
+func (s *Server) backlinksTo(target core.MinimalNote, notebook *core.Notebook) ([]backlink, error) {
+	backlinks := []backlink{}
+
+	notes, err := notebook.FindNotes(core.NoteFindOpts{
+		LinkTo: &core.LinkFilter{Paths: []string{target.Path}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, note := range notes {
+		path := filepath.Join(notebook.Path, note.Path)
+		linkingDoc := s.documents.New(pathToURI(path), path, note.RawContent)
+		// Prefer the content of the opened documents, which might not be
+		// saved yet.
+		if openedDoc, ok := s.documents.Get(path); ok {
+			linkingDoc = openedDoc
+		}
+
+		ranges := []protocol.Range{}
+		links, err := linkingDoc.DocumentLinks()
+		if err != nil {
+			return nil, err
+		}
+		for _, link := range links {
+			linked, err := s.noteForLink(link, linkingDoc, notebook)
+			if err != nil {
+				s.logger.Err(err)
+				continue
+			}
+			if linked != nil && linked.Path == target.Path {
+				ranges = append(ranges, link.Range)
+			}
+		}
+
+		// The index might resolve links which are not recognized by the
+		// LSP server, e.g. in HTML.
+		if len(ranges) == 0 {
+			line := lineMentioning(linkingDoc.Content, target.Path)
+			ranges = append(ranges, protocol.Range{
+				Start: protocol.Position{Line: protocol.UInteger(line)},
+				End:   protocol.Position{Line: protocol.UInteger(line)},
+			})
+		}
+
+		lines := linkingDoc.GetLines()
+		for _, rng := range ranges {
+			context, _ := linkingDoc.GetLine(int(rng.Start.Line))
+			heading := headingBefore(lines, int(rng.Start.Line))
+			backlinks = append(backlinks, backlink{
+				URI:           linkingDoc.URI,
+				Range:         rng,
+				Path:          note.Path,
+				Title:         note.Title,
+				Heading:       heading,
+				Context:       strings.TrimSpace(context),
+				ContainerName: backlinkContainerName(note.AsMinimalNote(), heading),
+			})
+		}
+	}
+
+	return backlinks, nil
+}
+
+// lineMentioning returns the index of the first line of content mentioning
+// the note at path, without its extension, or 0 if there's none.
+func lineMentioning(content string, path string) int {
+	if pos := strings.Index(content, strings.TrimSuffix(path, filepath.Ext(path))); pos >= 0 {
+		return strings.Count(content[:pos], "\n")
+	}
+	return 0
+}
+
+// backlinkContainerName returns the human-readable location of a backlink,
+// made of the title of the linking note, or its path, and the nearest
+// heading preceding the link.
+func backlinkContainerName(note core.MinimalNote, heading string) string {
+	name := note.Title
+	if name == "" {
+		name = note.Path
+	}
+	if heading != "" {
+		name += " › " + heading
+	}
+	return name
+}
+
+// headingBefore returns the text of the nearest Markdown heading preceding
+// the given line, ignoring the fenced code blocks.
+func headingBefore(lines []string, lineIndex int) string {
+	heading := ""
+	inCodeBlock := false
+	for i, line := range lines {
+		if i > lineIndex {
+			break
+		}
+		if fenceRegex.MatchString(line) {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock {
+			continue
+		}
+		if loc := headingRegex.FindStringIndex(line); loc != nil {
+			heading = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(line[loc[1]:]), "#"))
+		}
+	}
+	return heading
+}
+
+// clientShowReferences is the client command displaying a list of
+// locations, which is run when clicking a backlinks code lens. It is built
+// in VS Code, and can be registered in other editors.
+const clientShowReferences = "editor.action.showReferences"
+
+// backlinksCodeLens returns a code lens displaying the number of backlinks of
+// the document above its title, which lists them when clicked.
+func (s *Server) backlinksCodeLens(doc *document) ([]protocol.CodeLens, error) {
+	notebook, err := s.notebookOf(doc)
+	if err != nil {
+		return nil, err
+	}
+	if !s.configOf(notebook, doc).LSP.CodeLens.Backlinks {
+		return []protocol.CodeLens{}, nil
+	}
+	path, err := notebook.RelPath(doc.Path)
+	if err != nil {
+		return nil, err
+	}
+	backlinks, err := s.backlinksTo(core.MinimalNote{Path: path}, notebook)
+	if err != nil {
+		return nil, err
+	}
+
+	lineIndex := titleLine(doc.GetLines())
+	line, _ := doc.GetLine(lineIndex)
+	rng := doc.rangeAt(lineIndex, 0, len(line))
+
+	count := len(backlinks)
+	command := protocol.Command{
+		Title: fmt.Sprintf("%d %s", count, strutil.Pluralize("backlink", count)),
+	}
+	if count > 0 {
+		locations := []protocol.Location{}
+		for _, backlink := range backlinks {
+			locations = append(locations, protocol.Location{URI: backlink.URI, Range: backlink.Range})
+		}
+		command.Command = clientShowReferences
+		command.Arguments = []interface{}{doc.URI, rng.Start, locations}
+	}
+
+	return []protocol.CodeLens{{Range: rng, Command: &command}}, nil
+}
+
+// titleLine returns the index of the line holding the title of a note: its
+// first heading, or the first line after its YAML frontmatter.
+func titleLine(lines []string) int {
+	start := frontmatterEnd(lines)
+
+	inCodeBlock := false
+	for i := start; i < len(lines); i++ {
+		if fenceRegex.MatchString(lines[i]) {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if !inCodeBlock && headingRegex.MatchString(lines[i]) {
+			return i
+		}
+	}
+	if start >= len(lines) {
+		return 0
+	}
+	return start
+}
@@ -0,0 +1,54 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestHeadingBefore(t *testing.T) {
+	lines := strings.Split(`# Title
+Intro
+## Section ##
+Text
+`+"```"+`
+# Not a heading
+`+"```"+`
+After the code
+###### Deep
+`, "\n")
+
+	test := func(lineIndex int, expected string) {
+		t.Helper()
+		assert.Equal(t, headingBefore(lines, lineIndex), expected)
+	}
+
+	test(0, "Title")
+	test(1, "Title")
+	test(3, "Section")
+	test(5, "Section")
+	test(7, "Section")
+	test(8, "Deep")
+	test(20, "Deep")
+	assert.Equal(t, headingBefore([]string{"No heading"}, 0), "")
+}
+
+func TestLineMentioning(t *testing.T) {
+	content := "# Title\nSee <a href=\"dir/note\">it</a>\nand dir/note.md"
+	assert.Equal(t, lineMentioning(content, "dir/note.md"), 1)
+	assert.Equal(t, lineMentioning(content, "other.md"), 0)
+}
+
+func TestBacklinkContainerName(t *testing.T) {
+	test := func(note core.MinimalNote, heading string, expected string) {
+		t.Helper()
+		assert.Equal(t, backlinkContainerName(note, heading), expected)
+	}
+
+	test(core.MinimalNote{Path: "dir/a.md", Title: "Alpha"}, "", "Alpha")
+	test(core.MinimalNote{Path: "dir/a.md", Title: "Alpha"}, "Section", "Alpha › Section")
+	test(core.MinimalNote{Path: "dir/a.md"}, "", "dir/a.md")
+	test(core.MinimalNote{Path: "dir/a.md"}, "Section", "dir/a.md › Section")
+}
@@ -0,0 +1,202 @@
+package lsp
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// apiVersion is the version of the custom commands and requests of the LSP
+// server, advertised to the editor plugins in the initialize result and with
+// zk.capabilities.
+//
+// It is incremented every time a command, a request or an option is added or
+// changed, so that the plugins can check the features they rely on with a
+// simple comparison, independently of the zk release.
+const apiVersion = 9
+
+const cmdCapabilities = "zk.capabilities"
+
+// serverInfo extends the server information of the initialize result with
+// the API version.
+type serverInfo struct {
+	Name       string  `json:"name"`
+	Version    *string `json:"version,omitempty"`
+	APIVersion int     `json:"apiVersion"`
+}
+
+// capabilitiesManifest is the result of the zk.capabilities command.
+type capabilitiesManifest struct {
+	APIVersion int               `json:"apiVersion"`
+	Version    string            `json:"version"`
+	Commands   []commandManifest `json:"commands"`
+	Requests   []string          `json:"requests"`
+	Notebook   *notebookManifest `json:"notebook,omitempty"`
+}
+
+type commandManifest struct {
+	Name    string           `json:"name"`
+	Options []optionManifest `json:"options"`
+}
+
+type optionManifest struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+}
+
+// notebookManifest summarizes the settings of a notebook relevant to the
+// editor plugins.
+type notebookManifest struct {
+	Path          string   `json:"path"`
+	Extension     string   `json:"extension"`
+	LinkFormat    string   `json:"linkFormat"`
+	Hashtags      bool     `json:"hashtags"`
+	ColonTags     bool     `json:"colonTags"`
+	MultiwordTags bool     `json:"multiwordTags"`
+	Groups        []string `json:"groups"`
+	DateTrigger   string   `json:"dateTrigger"`
+	AutoCommit    bool     `json:"autoCommit"`
+	// The commands modifying the notebook are disabled.
+	ReadOnly bool `json:"readOnly"`
+}
+
+// commandOpts lists the custom commands with the dictionary of options they
+// accept, used to describe them in the manifest of zk.capabilities.
+var commandOpts = []struct {
+	name string
+	opts interface{}
+}{
+	{cmdCapabilities, nil},
+	{cmdExpandLink, cmdExpandLinkOpts{}},
+	{cmdExtractListItems, cmdExtractListItemsOpts{}},
+	{cmdFixDeadLinks, cmdFixDeadLinksOpts{}},
+	{cmdIndex, struct {
+		Force bool `json:"force,omitempty"`
+	}{}},
+	{cmdLink, cmdLinkOpts{}},
+	{cmdList, cmdListOpts{}},
+	{cmdNew, cmdNewOpts{}},
+	{cmdPasteAsset, cmdPasteAssetOpts{}},
+	{cmdRecents, cmdRecentsOpts{}},
+	{cmdRelated, cmdRelatedOpts{}},
+	{cmdStats, nil},
+	{cmdSync, nil},
+	{cmdTagList, cmdTagListOpts{}},
+	{cmdTaskToggle, cmdTaskToggleOpts{}},
+	{cmdTemplateList, nil},
+	{cmdTree, cmdTreeOpts{}},
+}
+
+// customCommands returns the names of the commands supported by the server.
+func customCommands() []string {
+	names := []string{}
+	for _, cmd := range commandOpts {
+		names = append(names, cmd.name)
+	}
+	return names
+}
+
+// executeCommandCapabilities returns a manifest of the commands and requests
+// supported by the server, with a summary of the notebook settings when a
+// notebook path is given.
+func (s *Server) executeCommandCapabilities(args []interface{}) (interface{}, error) {
+	manifest := capabilitiesManifest{
+		APIVersion: apiVersion,
+		Version:    s.version,
+		Commands:   []commandManifest{},
+		Requests:   []string{methodBacklinks, methodPreview},
+	}
+	for _, cmd := range commandOpts {
+		manifest.Commands = append(manifest.Commands, commandManifest{
+			Name:    cmd.name,
+			Options: optionsManifest(cmd.opts),
+		})
+	}
+
+	if len(args) == 0 {
+		return manifest, nil
+	}
+	wd, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.capabilities expects a notebook path as first argument, got: %v", args[0])
+	}
+	notebook, err := s.notebooks.Open(wd)
+	if err != nil {
+		return nil, err
+	}
+
+	config := notebook.Config
+	groups := []string{}
+	for name := range config.Groups {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+
+	manifest.Notebook = &notebookManifest{
+		Path:          notebook.Path,
+		Extension:     config.Note.Extension,
+		LinkFormat:    config.Format.Markdown.LinkFormat,
+		Hashtags:      config.Format.Markdown.Hashtags,
+		ColonTags:     config.Format.Markdown.ColonTags,
+		MultiwordTags: config.Format.Markdown.MultiwordTags,
+		Groups:        groups,
+		DateTrigger:   config.LSP.Completion.DateTrigger,
+		AutoCommit:    config.Git.AutoCommit,
+		ReadOnly:      s.isReadOnly(notebook),
+	}
+	return manifest, nil
+}
+
+// optionsManifest describes the options of a command from the JSON tags of
+// its options struct, so that the manifest can't drift from the options
+// actually parsed.
+func optionsManifest(opts interface{}) []optionManifest {
+	options := []optionManifest{}
+	if opts == nil {
+		return options
+	}
+	t := reflect.TypeOf(opts)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		options = append(options, optionManifest{
+			Name:     parts[0],
+			Type:     optionType(field.Type),
+			Required: len(parts) == 1 || parts[1] != "omitempty",
+		})
+	}
+	return options
+}
+
+// optionType returns the name of the JSON type of an option, as written in
+// the documentation of the commands.
+func optionType(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(protocol.Location{}) {
+		return "location"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Int:
+		return "integer"
+	case reflect.Float64:
+		return "float"
+	case reflect.Slice:
+		return optionType(t.Elem()) + "[]"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,206 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/core"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// citationRegex matches a Pandoc citation, e.g. `@knuth1984` in
+// `[see @knuth1984, p. 33]`, capturing its key. The email addresses are not
+// matched, as the `@` must follow a space or a bracket.
+var citationRegex = regexp.MustCompile(`(?:^|[\s\[;(-])@([\p{L}\p{N}_](?:[\p{L}\p{N}_:.#$%&+?<>~/-]*[\p{L}\p{N}_])?)`)
+
+// citationQueryRegex matches a citation key being typed at the end of a line.
+var citationQueryRegex = regexp.MustCompile(`(?:^|[\s\[;(-])@([\p{L}\p{N}_:.#$%&+?<>~/-]*)$`)
+
+// documentCitation is a citation of a bibliographic reference, e.g.
+// `@knuth1984`.
+type documentCitation struct {
+	Key   string
+	Range protocol.Range
+}
+
+// Citations returns the citations found in the document, outside of the
+// frontmatter and the code.
+func (d *document) Citations() []documentCitation {
+	citations := []documentCitation{}
+
+	lines := d.GetLines()
+	i := frontmatterEnd(lines)
+	for ; i < len(lines); i++ {
+		line := lines[i]
+
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			fence := match[1]
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+					break
+				}
+			}
+			continue
+		}
+
+		for _, match := range citationRegex.FindAllStringSubmatchIndex(line, -1) {
+			// The range includes the `@`.
+			start := match[2] - 1
+			if isInInlineCode(line[:start]) {
+				continue
+			}
+			citations = append(citations, documentCitation{
+				Key:   line[match[2]:match[3]],
+				Range: d.rangeAt(i, start, match[3]),
+			})
+		}
+	}
+
+	return citations
+}
+
+// CitationAt returns the citation found in the document at the given
+// position.
+func (d *document) CitationAt(pos protocol.Position) (*documentCitation, bool) {
+	for _, citation := range d.Citations() {
+		if d.InRange(citation.Range, pos) {
+			return &citation, true
+		}
+	}
+	return nil, false
+}
+
+// CitationQueryBefore returns the beginning of a citation key typed before
+// the given position, e.g. `knu` in `[see @knu`.
+func (d *document) CitationQueryBefore(pos protocol.Position) (string, bool) {
+	line, ok := d.GetLine(int(pos.Line))
+	if !ok || d.isCodeLine(int(pos.Line)) {
+		return "", false
+	}
+	before := line[:d.charIndex(line, pos)]
+	match := citationQueryRegex.FindStringSubmatchIndex(before)
+	if match == nil || isInInlineCode(before[:match[2]]) {
+		return "", false
+	}
+	return before[match[2]:match[3]], true
+}
+
+// buildCitationCompletionList completes the keys of the references matching
+// the query, among their key, title and authors. Returns nil when none
+// matches, to let the other completions kick in.
+func (s *Server) buildCitationCompletionList(doc *document, notebook *core.Notebook, params *protocol.CompletionParams, query string) (*protocol.CompletionList, error) {
+	citations, err := notebook.Citations()
+	if err != nil {
+		return nil, err
+	}
+
+	maxItems := s.configOf(notebook, doc).LSP.Completion.MaxItems
+	replacedRange := doc.rangeAround(params.Position, -len(query)-1, 0)
+	query = strings.ToLower(query)
+	kind := protocol.CompletionItemKindReference
+	items := []protocol.CompletionItem{}
+	isIncomplete := false
+	for _, citation := range citations {
+		filterText := "@" + citation.Key + " " + citation.Title + " " + strings.Join(citation.Authors, " ")
+		if !strings.Contains(strings.ToLower(filterText), query) {
+			continue
+		}
+		if maxItems > 0 && len(items) >= maxItems {
+			isIncomplete = true
+			break
+		}
+		items = append(items, protocol.CompletionItem{
+			Label:      citation.Key,
+			Kind:       &kind,
+			Detail:     stringPtr(citation.Reference()),
+			FilterText: stringPtr(filterText),
+			TextEdit:   protocol.TextEdit{Range: replacedRange, NewText: "@" + citation.Key},
+		})
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	return &protocol.CompletionList{
+		IsIncomplete: isIncomplete,
+		Items:        items,
+	}, nil
+}
+
+// hoverForCitation formats the bibliographic reference of a citation.
+func hoverForCitation(citation core.Citation, kind protocol.MarkupKind) string {
+	if kind != protocol.MarkupKindMarkdown {
+		return citation.Reference()
+	}
+
+	content := ""
+	if citation.Title != "" {
+		content += fmt.Sprintf("**%s**\n\n", escapeMarkdown(citation.Title))
+	}
+	reference := escapeMarkdown(strings.Join(citation.Authors, "; "))
+	if citation.Year != "" {
+		reference = strings.TrimSpace(reference + " (" + citation.Year + ")")
+	}
+	if citation.Container != "" {
+		if reference != "" {
+			reference += ". "
+		}
+		reference += "*" + escapeMarkdown(citation.Container) + "*"
+	}
+	if reference != "" {
+		content += reference + "\n\n"
+	}
+	if citation.DOI != "" {
+		content += "<https://doi.org/" + citation.DOI + ">"
+	} else if citation.URL != "" {
+		content += "<" + citation.URL + ">"
+	}
+	return strings.TrimSpace(content)
+}
+
+// citationLocation returns the location of the reference in its
+// bibliography file.
+func citationLocation(citation core.Citation) protocol.Location {
+	line := protocol.UInteger(0)
+	if citation.Line > 0 {
+		line = protocol.UInteger(citation.Line - 1)
+	}
+	pos := protocol.Position{Line: line}
+	return protocol.Location{
+		URI:   pathToURI(citation.Path),
+		Range: protocol.Range{Start: pos, End: pos},
+	}
+}
+
+// citationDiagnostics reports the citations of keys which are not declared
+// in the bibliography of the notebook.
+func (s *Server) citationDiagnostics(notebook *core.Notebook, doc *document, severity core.LSPDiagnosticSeverity) ([]protocol.Diagnostic, error) {
+	diagnostics := []protocol.Diagnostic{}
+	if severity == core.LSPDiagnosticNone || len(notebook.Config.Bibliography.Files) == 0 {
+		return diagnostics, nil
+	}
+
+	citations, err := notebook.Citations()
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string]bool{}
+	for _, citation := range citations {
+		keys[citation.Key] = true
+	}
+
+	diagSeverity := protocol.DiagnosticSeverity(severity)
+	for _, citation := range doc.Citations() {
+		if keys[citation.Key] {
+			continue
+		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    citation.Range,
+			Severity: &diagSeverity,
+			Source:   stringPtr("zk"),
+			Message:  "unknown citation key: " + citation.Key,
+		})
+	}
+	return diagnostics, nil
+}
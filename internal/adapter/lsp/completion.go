@@ -1,10 +1,15 @@
 package lsp
 
 import (
+	"encoding/json"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/mickael-menu/zk/internal/core"
 	"github.com/mickael-menu/zk/internal/util/paths"
+	protocol "github.com/tliron/glsp/protocol_3_16"
 )
 
 // completionTemplates holds templates to render the various elements of an LSP
@@ -29,6 +34,85 @@ func newCompletionTemplates(loader core.TemplateLoader, templates core.LSPComple
 	return
 }
 
+// completionTrigger is the text typed before the query of a completed link,
+// e.g. `[[`, which is replaced by the inserted link.
+type completionTrigger struct {
+	Text string
+	// Deletes the trigger with an additional text edit, instead of replacing
+	// it with the main one.
+	AdditionalTextEdit bool
+}
+
+// newCompletionTrigger creates a completionTrigger deleted according to the
+// notebook config, or to the client capabilities when not configured.
+func (s *Server) newCompletionTrigger(config core.Config, text string) completionTrigger {
+	trigger := completionTrigger{
+		Text:               text,
+		AdditionalTextEdit: s.additionalTextEdits,
+	}
+	if use := config.LSP.Completion.UseAdditionalTextEdits; use != nil {
+		trigger.AdditionalTextEdit = *use
+	}
+	return trigger
+}
+
+// ClosingBrackets returns the brackets closing the trigger, which are
+// inserted by the clients auto-pairing them, e.g. `]]` for `[[`.
+func (t completionTrigger) ClosingBrackets() string {
+	closing := ""
+	for i := len(t.Text) - 1; i >= 0; i-- {
+		switch t.Text[i] {
+		case '[':
+			closing += "]"
+		case '(':
+			closing += ")"
+		case '{':
+			closing += "}"
+		case '<':
+			closing += ">"
+		default:
+			return closing
+		}
+	}
+	return closing
+}
+
+// negotiateAdditionalTextEdits returns whether the trigger of the completed
+// links must be deleted with an additional text edit for the client.
+//
+// The clients supporting the insert and replace edits, such as VS Code,
+// filter the items with the text of the main edit range, which must then
+// start after the trigger. The clients not telling their completion
+// capabilities are given the additional edit as well, which is supported by
+// most of them.
+func negotiateAdditionalTextEdits(capabilities protocol.ClientCapabilities) bool {
+	textDocument := capabilities.TextDocument
+	if textDocument == nil || textDocument.Completion == nil || textDocument.Completion.CompletionItem == nil {
+		return true
+	}
+	return isTrue(textDocument.Completion.CompletionItem.InsertReplaceSupport)
+}
+
+// completionItemData is attached to the note completion items, to render
+// their detail and documentation when the editor resolves them.
+type completionItemData struct {
+	// Absolute path to the completed note.
+	Path string `json:"path"`
+	// Absolute path to the document in which the note is completed.
+	DocPath string `json:"docPath"`
+}
+
+// completionItemDataOf decodes the data of a completion item sent back by the
+// editor.
+func completionItemDataOf(item *protocol.CompletionItem) (completionItemData, bool) {
+	var data completionItemData
+	raw, err := json.Marshal(item.Data)
+	if err != nil || json.Unmarshal(raw, &data) != nil || data.Path == "" {
+		return data, false
+	}
+	return data, true
+}
+
 type completionItemRenderContext struct {
 	ID           int64
 	Filename     string
@@ -38,6 +122,9 @@ type completionItemRenderContext struct {
 	RelPath      string `handlebars:"rel-path"`
 	Title        string
 	TitleOrPath  string `handlebars:"title-or-path"`
+	Summary      string
+	WordCount    int `handlebars:"word-count"`
+	ReadingTime  int `handlebars:"reading-time"`
 	Metadata     map[string]interface{}
 }
 
@@ -57,6 +144,9 @@ func newCompletionItemRenderContext(note core.MinimalNote, notebookDir string, c
 		RelPath:      relPath,
 		Title:        note.Title,
 		TitleOrPath:  note.Title,
+		Summary:      note.Summary,
+		WordCount:    note.WordCount,
+		ReadingTime:  core.ReadingTime(note.WordCount),
 		Metadata:     note.Metadata,
 	}
 	if context.TitleOrPath == "" {
@@ -64,3 +154,152 @@ func newCompletionItemRenderContext(note core.MinimalNote, notebookDir string, c
 	}
 	return context, nil
 }
+
+// noteCompletionCache keeps in memory the notes offered when completing
+// links, to avoid querying the whole index on every keystroke. The notes of a
+// notebook are reloaded after its index is modified.
+type noteCompletionCache struct {
+	mutex   sync.Mutex
+	entries map[string]noteCompletionEntry
+	// Number of lookups served from the cache, or reloading the notes.
+	hits   int
+	misses int
+}
+
+type noteCompletionEntry struct {
+	revision uint32
+	notes    []core.MinimalNote
+	// Lowercased title, aliases and path of each note, used to filter them.
+	keys []string
+	// Frontmatter keys used in the notes, from the most common.
+	metadataKeys []metadataKeyUsage
+}
+
+type metadataKeyUsage struct {
+	Name string
+	// Number of notes using this key.
+	Count int
+}
+
+func newNoteCompletionCache() *noteCompletionCache {
+	return &noteCompletionCache{
+		entries: map[string]noteCompletionEntry{},
+	}
+}
+
+// Find returns at most limit notes of the notebook whose title, aliases or path
+// contain all the words of query, ignoring case. A limit of 0 returns all
+// the matching notes. isIncomplete is true when more notes are matching.
+//
+// The archived notes are skipped unless includeArchived is true.
+func (c *noteCompletionCache) Find(notebook *core.Notebook, query string, limit int, includeArchived bool) (notes []core.MinimalNote, isIncomplete bool, err error) {
+	entry, err := c.entry(notebook)
+	if err != nil {
+		return nil, false, err
+	}
+
+	terms := strings.Fields(strings.ToLower(query))
+	for i, note := range entry.notes {
+		if !containsAll(entry.keys[i], terms) || (!includeArchived && notebook.IsArchived(note.Path)) {
+			continue
+		}
+		if limit > 0 && len(notes) == limit {
+			return notes, true, nil
+		}
+		notes = append(notes, note)
+	}
+	return notes, false, nil
+}
+
+func (c *noteCompletionCache) entry(notebook *core.Notebook) (noteCompletionEntry, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	revision := notebook.IndexRevision()
+	entry, ok := c.entries[notebook.Path]
+	if ok && entry.revision == revision {
+		c.hits++
+		return entry, nil
+	}
+	c.misses++
+
+	// The recently modified notes are the most likely to be linked, so they
+	// are offered first when the number of items is limited.
+	notes, err := notebook.FindMinimalNotes(core.NoteFindOpts{
+		Trash:   core.TrashFilterExclude,
+		Sorters: []core.NoteSorter{{Field: core.NoteSortModified, Ascending: false}},
+	})
+	if err != nil {
+		return entry, err
+	}
+	entry = noteCompletionEntry{
+		revision: revision,
+		notes:    notes,
+		keys:     make([]string, len(notes)),
+	}
+	for i, note := range notes {
+		entry.keys[i] = strings.ToLower(strings.Join(append([]string{note.Title, note.Path}, note.Aliases()...), " "))
+	}
+	entry.metadataKeys = countMetadataKeys(notes)
+	c.entries[notebook.Path] = entry
+	return entry, nil
+}
+
+// countMetadataKeys returns the frontmatter keys used in the given notes,
+// from the most common.
+func countMetadataKeys(notes []core.MinimalNote) []metadataKeyUsage {
+	counts := map[string]int{}
+	for _, note := range notes {
+		for key := range note.Metadata {
+			counts[key]++
+		}
+	}
+	usages := []metadataKeyUsage{}
+	for key, count := range counts {
+		usages = append(usages, metadataKeyUsage{Name: key, Count: count})
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		a, b := usages[i], usages[j]
+		return a.Count > b.Count || (a.Count == b.Count && a.Name < b.Name)
+	})
+	return usages
+}
+
+// MetadataKeys returns the frontmatter keys used in the notes of the
+// notebook, from the most common.
+func (c *noteCompletionCache) MetadataKeys(notebook *core.Notebook) ([]metadataKeyUsage, error) {
+	entry, err := c.entry(notebook)
+	return entry.metadataKeys, err
+}
+
+// stats returns the efficiency of the cache and the number of cached notes
+// per notebook.
+func (c *noteCompletionCache) stats() completionStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := completionStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Notebooks: []notebookCacheStats{},
+	}
+	if lookups := c.hits + c.misses; lookups > 0 {
+		stats.HitRate = float64(c.hits) / float64(lookups)
+	}
+	for path, entry := range c.entries {
+		stats.Notebooks = append(stats.Notebooks, notebookCacheStats{Path: path, Notes: len(entry.notes)})
+	}
+	sort.Slice(stats.Notebooks, func(i, j int) bool {
+		return stats.Notebooks[i].Path < stats.Notebooks[j].Path
+	})
+	return stats
+}
+
+func containsAll(s string, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(s, term) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,189 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tliron/glsp"
+)
+
+// Listen serves the LSP clients connecting to the given address, until the
+// listener fails.
+//
+// The address is either:
+//   - tcp:PORT, to listen on the loopback interface
+//   - tcp:HOST:PORT or HOST:PORT, to listen on a TCP address
+//   - unix:PATH, to listen on a Unix domain socket
+//
+// Each client is served by its own Server, to isolate its open documents,
+// diagnostics and trace setting from the other editors attached to the
+// daemon. The notebooks and their indexes are shared by all the clients.
+func Listen(address string, opts ServerOpts) error {
+	wrap := errors.Wrapper("lsp")
+
+	network, address := parseListenAddress(address)
+	if network == "unix" {
+		err := removeStaleSocket(address)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return wrap(err)
+	}
+	defer listener.Close()
+
+	// Closes the listener when the daemon is interrupted, which removes the
+	// Unix socket file.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	interrupted := make(chan struct{})
+	go func() {
+		<-signals
+		close(interrupted)
+		listener.Close()
+	}()
+
+	configureLogging(opts)
+	urlMetadata := newURLMetadataJob(opts.URLMetadataFetcher, opts.Logger)
+	completion := newNoteCompletionCache()
+	metrics := newServerMetrics()
+	if opts.MetricsAddress != "" {
+		go serveMetrics(opts.MetricsAddress, metrics, completion, opts.Logger)
+	}
+
+	opts.Logger.Printf("listening for LSP clients on %s:%s", network, listener.Addr())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-interrupted:
+				return nil
+			default:
+				return wrap(err)
+			}
+		}
+		client := clientName(conn)
+		opts.Logger.Printf("LSP client connected from %s", client)
+
+		server := newServer(opts, urlMetadata, completion, metrics)
+		go func() {
+			server.serve(conn)
+			opts.Logger.Printf("LSP client disconnected from %s", client)
+		}()
+	}
+}
+
+// parseListenAddress splits a --listen address into the network and address
+// expected by net.Listen. Addresses without a network are TCP addresses.
+func parseListenAddress(address string) (network string, addr string) {
+	switch {
+	case strings.HasPrefix(address, "unix:"):
+		return "unix", strings.TrimPrefix(address, "unix:")
+	case strings.HasPrefix(address, "tcp:"):
+		addr = strings.TrimPrefix(address, "tcp:")
+		if !strings.Contains(addr, ":") {
+			// Only a port, which is not exposed outside of this machine.
+			addr = "localhost:" + addr
+		}
+		return "tcp", addr
+	default:
+		return "tcp", address
+	}
+}
+
+// removeStaleSocket deletes the Unix socket file left at the given path by a
+// server which didn't exit cleanly. A socket still accepting connections is
+// left untouched, so that net.Listen reports that it is in use.
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s: not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s: another server is already listening on this socket", path)
+	}
+	return os.Remove(path)
+}
+
+// clientName identifies a connected client in the logs. Clients connected to
+// a Unix socket are unnamed, so the socket path is used instead.
+func clientName(conn net.Conn) string {
+	if conn.LocalAddr().Network() == "unix" {
+		return conn.LocalAddr().String()
+	}
+	return conn.RemoteAddr().String()
+}
+
+// serve handles the JSON-RPC messages of a single client, until it
+// disconnects.
+func (s *Server) serve(conn net.Conn) {
+	stream := jsonrpc2.NewBufferedStream(conn, jsonrpc2.VSCodeObjectCodec{})
+	<-jsonrpc2.NewConn(context.Background(), stream, jsonrpc2.HandlerWithError(s.handle)).DisconnectNotify()
+}
+
+// handle dispatches a JSON-RPC request to the GLSP handler of the server.
+func (s *Server) handle(ctx context.Context, conn *jsonrpc2.Conn, request *jsonrpc2.Request) (interface{}, error) {
+	glspContext := glsp.Context{
+		Method: request.Method,
+		Notify: func(method string, params interface{}) {
+			if err := conn.Notify(ctx, method, params); err != nil {
+				s.logger.Err(err)
+			}
+		},
+		Call: func(method string, params interface{}, result interface{}) {
+			if err := conn.Call(ctx, method, params, result); err != nil {
+				s.logger.Err(err)
+			}
+		},
+	}
+	if request.Params != nil {
+		glspContext.Params = *request.Params
+	}
+
+	result, validMethod, validParams, err := s.server.Handler.Handle(&glspContext)
+	if request.Method == "exit" {
+		// Only this client is leaving, the daemon keeps running.
+		return nil, conn.Close()
+	}
+
+	switch {
+	case !validMethod:
+		return nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeMethodNotFound,
+			Message: fmt.Sprintf("method not supported: %s", request.Method),
+		}
+	case !validParams:
+		rpcErr := &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+		if err != nil {
+			rpcErr.Message = err.Error()
+		}
+		return nil, rpcErr
+	case err != nil:
+		return nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.CodeInvalidRequest,
+			Message: err.Error(),
+		}
+	default:
+		return result, nil
+	}
+}
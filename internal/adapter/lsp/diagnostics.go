@@ -0,0 +1,164 @@
+package lsp
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+const (
+	// Delay before refreshing the diagnostics of an edited document, to not
+	// recompute them on every keystroke.
+	diagnosticsEditDelay = 1 * time.Second
+	// Delay before refreshing the diagnostics of the other documents, to
+	// coalesce the requests made at the same time, e.g. after indexing.
+	diagnosticsBatchDelay = 100 * time.Millisecond
+	// Maximum number of diagnostics notifications sent to the client per
+	// diagnosticsPublishInterval, as a burst of notifications freezes some
+	// editors.
+	diagnosticsPublishBurst    = 20
+	diagnosticsPublishInterval = 200 * time.Millisecond
+)
+
+// diagnosticsPublisher coalesces the refreshes of the diagnostics of the
+// opened documents, to avoid flooding the client with notifications when many
+// documents are opened or after a bulk indexing.
+//
+// The documents are refreshed in batches per notebook, and only the
+// diagnostics which changed since they were last published are sent.
+type diagnosticsPublisher struct {
+	notebookOf func(doc *document) (*core.Notebook, error)
+	compute    func(notebook *core.Notebook, doc *document) ([]protocol.Diagnostic, error)
+	logger     util.Logger
+
+	mutex  sync.Mutex
+	notify glsp.NotifyFunc
+	// Documents waiting for a refresh, in the order of the requests.
+	queue  []*document
+	queued map[protocol.DocumentUri]bool
+	// Pending flush of the queue, nil when idle.
+	timer   *time.Timer
+	flushAt time.Time
+	// Diagnostics last published for each document, serialized to JSON.
+	published map[protocol.DocumentUri]string
+
+	// Prevents two flushes from running concurrently.
+	flushing sync.Mutex
+}
+
+func newDiagnosticsPublisher(
+	notebookOf func(doc *document) (*core.Notebook, error),
+	compute func(notebook *core.Notebook, doc *document) ([]protocol.Diagnostic, error),
+	logger util.Logger,
+) *diagnosticsPublisher {
+	return &diagnosticsPublisher{
+		notebookOf: notebookOf,
+		compute:    compute,
+		logger:     logger,
+		queued:     map[protocol.DocumentUri]bool{},
+		published:  map[protocol.DocumentUri]string{},
+	}
+}
+
+// Schedule refreshes the diagnostics of the given document after the delay,
+// unless a refresh is already pending.
+func (p *diagnosticsPublisher) Schedule(doc *document, notify glsp.NotifyFunc, delay time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.notify = notify
+	if !p.queued[doc.URI] {
+		p.queued[doc.URI] = true
+		p.queue = append(p.queue, doc)
+	}
+
+	flushAt := time.Now().Add(delay)
+	if p.timer == nil {
+		p.timer = time.AfterFunc(delay, p.flush)
+		p.flushAt = flushAt
+	} else if flushAt.Before(p.flushAt) && p.timer.Stop() {
+		p.timer.Reset(delay)
+		p.flushAt = flushAt
+	}
+}
+
+// Forget clears the diagnostics published for a closed document.
+func (p *diagnosticsPublisher) Forget(uri protocol.DocumentUri) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.published, uri)
+}
+
+func (p *diagnosticsPublisher) flush() {
+	p.flushing.Lock()
+	defer p.flushing.Unlock()
+
+	p.mutex.Lock()
+	queue := p.queue
+	notify := p.notify
+	p.queue = nil
+	p.queued = map[protocol.DocumentUri]bool{}
+	p.timer = nil
+	p.mutex.Unlock()
+
+	// Group the documents per notebook, keeping the order of the requests.
+	notebooks := []*core.Notebook{}
+	docs := map[*core.Notebook][]*document{}
+	for _, doc := range queue {
+		notebook, err := p.notebookOf(doc)
+		if err != nil {
+			p.logger.Err(err)
+			continue
+		}
+		if _, ok := docs[notebook]; !ok {
+			notebooks = append(notebooks, notebook)
+		}
+		docs[notebook] = append(docs[notebook], doc)
+	}
+
+	sent := 0
+	for _, notebook := range notebooks {
+		for _, doc := range docs[notebook] {
+			diagnostics, err := p.compute(notebook, doc)
+			if err != nil {
+				p.logger.Err(err)
+				continue
+			}
+			if !p.markPublished(doc.URI, diagnostics) {
+				continue
+			}
+
+			if sent > 0 && sent%diagnosticsPublishBurst == 0 {
+				time.Sleep(diagnosticsPublishInterval)
+			}
+			notify(protocol.ServerTextDocumentPublishDiagnostics, protocol.PublishDiagnosticsParams{
+				URI:         doc.URI,
+				Diagnostics: diagnostics,
+			})
+			sent++
+		}
+	}
+}
+
+// markPublished records the diagnostics about to be published for the given
+// document, and returns false if they were already published.
+func (p *diagnosticsPublisher) markPublished(uri protocol.DocumentUri, diagnostics []protocol.Diagnostic) bool {
+	data, err := json.Marshal(diagnostics)
+	if err != nil {
+		p.logger.Err(err)
+		return true
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if previous, ok := p.published[uri]; ok && previous == string(data) {
+		return false
+	}
+	p.published[uri] = string(data)
+	return true
+}
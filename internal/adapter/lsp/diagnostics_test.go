@@ -0,0 +1,178 @@
+package lsp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// diagnosticsTest holds the documents of two notebooks, and records the
+// diagnostics published by the publisher under test.
+type diagnosticsTest struct {
+	notebookA *core.Notebook
+	notebookB *core.Notebook
+	notebooks map[protocol.DocumentUri]*core.Notebook
+	// Messages of the diagnostics computed for each document.
+	messages map[protocol.DocumentUri][]string
+	computed []protocol.DocumentUri
+	notified []protocol.DocumentUri
+}
+
+func newDiagnosticsTest() *diagnosticsTest {
+	return &diagnosticsTest{
+		notebookA: &core.Notebook{Path: "/a"},
+		notebookB: &core.Notebook{Path: "/b"},
+		notebooks: map[protocol.DocumentUri]*core.Notebook{},
+		messages:  map[protocol.DocumentUri][]string{},
+	}
+}
+
+func (t *diagnosticsTest) document(notebook *core.Notebook, name string) *document {
+	uri := protocol.DocumentUri("file://" + notebook.Path + "/" + name)
+	t.notebooks[uri] = notebook
+	return &document{URI: uri, Path: notebook.Path + "/" + name}
+}
+
+func (t *diagnosticsTest) publisher() *diagnosticsPublisher {
+	return newDiagnosticsPublisher(
+		func(doc *document) (*core.Notebook, error) {
+			notebook, ok := t.notebooks[doc.URI]
+			if !ok {
+				return nil, fmt.Errorf("%s: not in a notebook", doc.Path)
+			}
+			return notebook, nil
+		},
+		func(notebook *core.Notebook, doc *document) ([]protocol.Diagnostic, error) {
+			t.computed = append(t.computed, doc.URI)
+			diagnostics := []protocol.Diagnostic{}
+			for _, message := range t.messages[doc.URI] {
+				diagnostics = append(diagnostics, protocol.Diagnostic{Message: message})
+			}
+			return diagnostics, nil
+		},
+		&util.NullLogger,
+	)
+}
+
+func (t *diagnosticsTest) notify() glsp.NotifyFunc {
+	return func(method string, params interface{}) {
+		if method == protocol.ServerTextDocumentPublishDiagnostics {
+			t.notified = append(t.notified, params.(protocol.PublishDiagnosticsParams).URI)
+		}
+	}
+}
+
+// flushDiagnostics runs the pending refresh right away, instead of waiting
+// for its timer.
+func flushDiagnostics(p *diagnosticsPublisher) {
+	p.mutex.Lock()
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.mutex.Unlock()
+	p.flush()
+}
+
+func TestDiagnosticsPublisherCoalescesRequests(t *testing.T) {
+	test := newDiagnosticsTest()
+	a1 := test.document(test.notebookA, "1.md")
+	b1 := test.document(test.notebookB, "1.md")
+	a2 := test.document(test.notebookA, "2.md")
+	outside := &document{URI: "file:///outside.md", Path: "/outside.md"}
+
+	publisher := test.publisher()
+	notify := test.notify()
+	publisher.Schedule(a1, notify, time.Hour)
+	publisher.Schedule(b1, notify, time.Hour)
+	publisher.Schedule(a1, notify, time.Hour)
+	publisher.Schedule(outside, notify, time.Hour)
+	publisher.Schedule(a2, notify, time.Hour)
+	flushDiagnostics(publisher)
+
+	// Each document is refreshed once, grouped per notebook.
+	assert.Equal(t, test.computed, []protocol.DocumentUri{a1.URI, a2.URI, b1.URI})
+	assert.Equal(t, test.notified, []protocol.DocumentUri{a1.URI, a2.URI, b1.URI})
+	assert.True(t, publisher.timer == nil)
+	assert.Equal(t, len(publisher.queue), 0)
+}
+
+func TestDiagnosticsPublisherSkipsUnchangedDiagnostics(t *testing.T) {
+	test := newDiagnosticsTest()
+	a1 := test.document(test.notebookA, "1.md")
+	a2 := test.document(test.notebookA, "2.md")
+	test.messages[a1.URI] = []string{"not found"}
+
+	publisher := test.publisher()
+	notify := test.notify()
+	refresh := func() {
+		test.computed = nil
+		test.notified = nil
+		publisher.Schedule(a1, notify, time.Hour)
+		publisher.Schedule(a2, notify, time.Hour)
+		flushDiagnostics(publisher)
+	}
+
+	refresh()
+	assert.Equal(t, test.notified, []protocol.DocumentUri{a1.URI, a2.URI})
+
+	refresh()
+	assert.Equal(t, test.computed, []protocol.DocumentUri{a1.URI, a2.URI})
+	assert.True(t, test.notified == nil)
+
+	test.messages[a1.URI] = []string{"not found", "fuzzy match: A (80%)"}
+	refresh()
+	assert.Equal(t, test.notified, []protocol.DocumentUri{a1.URI})
+
+	// The diagnostics of a reopened document are published again.
+	publisher.Forget(a2.URI)
+	refresh()
+	assert.Equal(t, test.notified, []protocol.DocumentUri{a2.URI})
+}
+
+func TestDiagnosticsPublisherKeepsEarliestFlush(t *testing.T) {
+	test := newDiagnosticsTest()
+	a1 := test.document(test.notebookA, "1.md")
+	a2 := test.document(test.notebookA, "2.md")
+
+	publisher := test.publisher()
+	notify := test.notify()
+	publisher.Schedule(a1, notify, time.Hour)
+	flushAt := publisher.flushAt
+
+	// A shorter delay brings the pending flush forward…
+	publisher.Schedule(a2, notify, time.Minute)
+	assert.True(t, publisher.flushAt.Before(flushAt))
+	flushAt = publisher.flushAt
+
+	// …but a longer one does not postpone it.
+	publisher.Schedule(a1, notify, time.Hour)
+	assert.Equal(t, publisher.flushAt, flushAt)
+
+	flushDiagnostics(publisher)
+}
+
+func TestHasDiagnosticsEnabled(t *testing.T) {
+	none := core.LSPDiagnosticConfig{
+		WikiTitle: core.LSPDiagnosticNone,
+		DeadLink:  core.LSPDiagnosticNone,
+		FuzzyLink: core.LSPDiagnosticNone,
+		Footnote:  core.LSPDiagnosticNone,
+		DeadURL:   core.LSPDiagnosticNone,
+		Citation:  core.LSPDiagnosticNone,
+	}
+	assert.False(t, hasDiagnosticsEnabled(none))
+
+	config := none
+	config.Citation = core.LSPDiagnosticWarning
+	assert.True(t, hasDiagnosticsEnabled(config))
+
+	config = none
+	config.DeadLink = core.LSPDiagnosticError
+	assert.True(t, hasDiagnosticsEnabled(config))
+}
@@ -2,8 +2,11 @@ package lsp
 
 import (
 	"net/url"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/mickael-menu/zk/internal/core"
 	"github.com/mickael-menu/zk/internal/util"
@@ -17,6 +20,11 @@ type documentStore struct {
 	documents map[string]*document
 	fs        core.FileStorage
 	logger    util.Logger
+	// encoding is the position encoding negotiated with the client.
+	encoding positionEncoding
+	// wikiLinkAliasFirst returns whether the alias of the wiki-links comes
+	// before their target, in the notebook of the document at path.
+	wikiLinkAliasFirst func(path string) bool
 }
 
 func newDocumentStore(fs core.FileStorage, logger util.Logger) *documentStore {
@@ -24,6 +32,7 @@ func newDocumentStore(fs core.FileStorage, logger util.Logger) *documentStore {
 		documents: map[string]*document{},
 		fs:        fs,
 		logger:    logger,
+		encoding:  positionEncodingUTF16,
 	}
 }
 
@@ -38,15 +47,23 @@ func (s *documentStore) DidOpen(params protocol.DidOpenTextDocumentParams, notif
 	if err != nil {
 		return nil, err
 	}
-	doc := &document{
-		URI:     uri,
-		Path:    path,
-		Content: params.TextDocument.Text,
-	}
+	doc := s.New(uri, path, params.TextDocument.Text)
 	s.documents[path] = doc
 	return doc, nil
 }
 
+// New creates a document which is not opened in the client, using the
+// negotiated position encoding.
+func (s *documentStore) New(uri protocol.DocumentUri, path string, content string) *document {
+	return &document{
+		URI:                uri,
+		Path:               path,
+		Content:            content,
+		encoding:           s.encoding,
+		wikiLinkAliasFirst: s.wikiLinkAliasFirst,
+	}
+}
+
 func (s *documentStore) Close(uri protocol.DocumentUri) {
 	delete(s.documents, uri)
 }
@@ -61,6 +78,17 @@ func (s *documentStore) Get(pathOrURI string) (*document, bool) {
 	return d, ok
 }
 
+// InDir returns the opened documents located under the given directory.
+func (s *documentStore) InDir(dir string) []*document {
+	docs := []*document{}
+	for path, doc := range s.documents {
+		if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
 func (s *documentStore) normalizePath(pathOrUri string) (string, error) {
 	path, err := uriToPath(pathOrUri)
 	if err != nil {
@@ -71,11 +99,14 @@ func (s *documentStore) normalizePath(pathOrUri string) (string, error) {
 
 // document represents an opened file.
 type document struct {
-	URI                     protocol.DocumentUri
-	Path                    string
-	NeedsRefreshDiagnostics bool
-	Content                 string
-	lines                   []string
+	URI      protocol.DocumentUri
+	Path     string
+	Content  string
+	lines    []string
+	encoding positionEncoding
+	// wikiLinkAliasFirst returns whether the alias of the wiki-links comes
+	// before their target. Nil for the usual [[target|alias]] order.
+	wikiLinkAliasFirst func(path string) bool
 }
 
 // ApplyChanges updates the content of the document from LSP textDocument/didChange events.
@@ -83,14 +114,84 @@ func (d *document) ApplyChanges(changes []interface{}) {
 	for _, change := range changes {
 		switch c := change.(type) {
 		case protocol.TextDocumentContentChangeEvent:
-			startIndex, endIndex := c.Range.IndexesIn(d.Content)
+			startIndex, endIndex := d.offsetAt(c.Range.Start), d.offsetAt(c.Range.End)
+			if endIndex < startIndex {
+				endIndex = startIndex
+			}
 			d.Content = d.Content[:startIndex] + c.Text + d.Content[endIndex:]
 		case protocol.TextDocumentContentChangeEventWhole:
 			d.Content = c.Text
 		}
+		// The next changes are relative to the updated content.
+		d.lines = nil
+	}
+}
+
+// offsetAt returns the byte offset of the given position in the content of
+// the document. Positions out of the document are clamped.
+func (d *document) offsetAt(pos protocol.Position) int {
+	lines := d.GetLines()
+	if int(pos.Line) >= len(lines) {
+		return len(d.Content)
+	}
+
+	offset := 0
+	for _, line := range lines[:pos.Line] {
+		offset += len(line) + 1
+	}
+	return offset + d.charIndex(lines[pos.Line], pos)
+}
+
+// positionAt returns the position of the given byte offset in the content
+// of the document.
+func (d *document) positionAt(offset int) protocol.Position {
+	if offset > len(d.Content) {
+		offset = len(d.Content)
+	}
+	line := strings.Count(d.Content[:offset], "\n")
+	lineStart := strings.LastIndex(d.Content[:offset], "\n") + 1
+	lineContent, _ := d.GetLine(line)
+	return protocol.Position{
+		Line:      protocol.UInteger(line),
+		Character: protocol.UInteger(d.encoding.character(lineContent, offset-lineStart)),
+	}
+}
+
+// charIndex returns the byte index in line of the character at the given
+// position. A position past the end of the line is clamped before its
+// carriage return, if any.
+func (d *document) charIndex(line string, pos protocol.Position) int {
+	return d.encoding.byteIndex(strings.TrimSuffix(line, "\r"), int(pos.Character))
+}
+
+// rangeAt returns the range between the byte indexes start and end of the
+// line at lineIndex.
+func (d *document) rangeAt(lineIndex int, start int, end int) protocol.Range {
+	line, _ := d.GetLine(lineIndex)
+	return protocol.Range{
+		Start: protocol.Position{
+			Line:      protocol.UInteger(lineIndex),
+			Character: protocol.UInteger(d.encoding.character(line, start)),
+		},
+		End: protocol.Position{
+			Line:      protocol.UInteger(lineIndex),
+			Character: protocol.UInteger(d.encoding.character(line, end)),
+		},
 	}
+}
+
+// rangeAround returns the range between the given byte offsets relative to
+// pos, on the same line.
+func (d *document) rangeAround(pos protocol.Position, startOffset int, endOffset int) protocol.Range {
+	line, _ := d.GetLine(int(pos.Line))
+	charIdx := d.charIndex(line, pos)
+	return d.rangeAt(int(pos.Line), charIdx+startOffset, charIdx+endOffset)
+}
 
-	d.lines = nil
+// InRange returns whether pos is located inside rng.
+func (d *document) InRange(rng protocol.Range, pos protocol.Position) bool {
+	i := d.offsetAt(pos)
+	return i >= d.offsetAt(rng.Start) && i <= d.offsetAt(rng.End)
 }
 
 var nonEmptyString = regexp.MustCompile(`\S+`)
@@ -103,7 +204,7 @@ func (d *document) WordAt(pos protocol.Position) string {
 		return ""
 	}
 
-	charIdx := int(pos.Character)
+	charIdx := d.charIndex(line, pos)
 	wordIdxs := nonEmptyString.FindAllStringIndex(line, -1)
 	for _, wordIdx := range wordIdxs {
 		if wordIdx[0] <= charIdx && charIdx <= wordIdx[1] {
@@ -116,14 +217,17 @@ func (d *document) WordAt(pos protocol.Position) string {
 
 // ContentAtRange returns the document text at given range.
 func (d *document) ContentAtRange(rng protocol.Range) string {
-	startIndex, endIndex := rng.IndexesIn(d.Content)
+	startIndex, endIndex := d.offsetAt(rng.Start), d.offsetAt(rng.End)
+	if endIndex < startIndex {
+		return ""
+	}
 	return d.Content[startIndex:endIndex]
 }
 
 // GetLine returns the line at the given index.
 func (d *document) GetLine(index int) (string, bool) {
 	lines := d.GetLines()
-	if index < 0 || index > len(lines) {
+	if index < 0 || index >= len(lines) {
 		return "", false
 	}
 	return lines[index], true
@@ -138,21 +242,21 @@ func (d *document) GetLines() []string {
 	return d.lines
 }
 
-// LookBehind returns the n characters before the given position, on the same line.
+// LookBehind returns the n bytes before the given position, on the same line.
 func (d *document) LookBehind(pos protocol.Position, length int) string {
 	line, ok := d.GetLine(int(pos.Line))
 	if !ok {
 		return ""
 	}
 
-	charIdx := int(pos.Character)
+	charIdx := d.charIndex(line, pos)
 	if length > charIdx {
 		return line[0:charIdx]
 	}
 	return line[(charIdx - length):charIdx]
 }
 
-// LookForward returns the n characters after the given position, on the same line.
+// LookForward returns the n bytes after the given position, on the same line.
 func (d *document) LookForward(pos protocol.Position, length int) string {
 	line, ok := d.GetLine(int(pos.Line))
 	if !ok {
@@ -160,15 +264,150 @@ func (d *document) LookForward(pos protocol.Position, length int) string {
 	}
 
 	lineLength := len(line)
-	charIdx := int(pos.Character)
+	charIdx := d.charIndex(line, pos)
 	if lineLength <= charIdx+length {
 		return line[charIdx:]
 	}
 	return line[charIdx:(charIdx + length)]
 }
 
+// LinkQueryBefore returns the text typed after the opening of a link being
+// completed at the given position, e.g. `foo` in `[[foo`, with the opening
+// trigger among the given ones, e.g. `[[` or `](()`.
+func (d *document) LinkQueryBefore(pos protocol.Position, triggers []string) (query string, trigger string, ok bool) {
+	line, ok := d.GetLine(int(pos.Line))
+	if !ok {
+		return "", "", false
+	}
+
+	before := line[:d.charIndex(line, pos)]
+
+	start := -1
+	for _, t := range triggers {
+		if i := strings.LastIndex(before, t); i >= 0 && i+len(t) > start+len(trigger) {
+			start = i
+			trigger = t
+		}
+	}
+	if start < 0 {
+		return "", "", false
+	}
+
+	query = before[start+len(trigger):]
+	if strings.ContainsAny(query, "[]()") {
+		return "", "", false
+	}
+	return query, trigger, true
+}
+
+// DateQueryBefore returns the natural language date typed after the given
+// trigger at the given position, e.g. `next mon` in `@next mon`.
+//
+// The trigger must start a word, to not complete e-mail addresses.
+func (d *document) DateQueryBefore(pos protocol.Position, trigger string) (string, bool) {
+	line, ok := d.GetLine(int(pos.Line))
+	if !ok || trigger == "" {
+		return "", false
+	}
+
+	before := line[:d.charIndex(line, pos)]
+	start := strings.LastIndex(before, trigger)
+	if start < 0 {
+		return "", false
+	}
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(before[:start])
+		if !unicode.IsSpace(r) && !strings.ContainsRune(`([{"'`, r) {
+			return "", false
+		}
+	}
+
+	query := before[start+len(trigger):]
+	if len(query) > 30 || !dateQueryRegex.MatchString(query) {
+		return "", false
+	}
+	return query, true
+}
+
+var dateQueryRegex = regexp.MustCompile(`^(?:[\p{L}\p{N}\-]+ ?)*$`)
+
+// ParentTagBefore returns the parent of a hierarchical hashtag being typed
+// at the given position, e.g. `project` in `#project/`.
+func (d *document) ParentTagBefore(pos protocol.Position) (string, bool) {
+	line, ok := d.GetLine(int(pos.Line))
+	if !ok {
+		return "", false
+	}
+
+	match := parentTagBeforeRegex.FindStringSubmatch(line[:d.charIndex(line, pos)])
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+var parentTagBeforeRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#])#([\p{L}\p{N}_\-/]+)/$`)
+
+// MarkdownLinkTextBefore returns the text of a regular Markdown link whose
+// destination is about to be typed at the given position, e.g. `[text](`.
+// isImage is true for image links, e.g. `![alt](`.
+func (d *document) MarkdownLinkTextBefore(pos protocol.Position) (text string, isImage bool, ok bool) {
+	line, ok := d.GetLine(int(pos.Line))
+	if !ok {
+		return "", false, false
+	}
+
+	before := line[:d.charIndex(line, pos)]
+	if !strings.HasSuffix(before, "](") {
+		return "", false, false
+	}
+	before = strings.TrimSuffix(before, "](")
+
+	start := strings.LastIndex(before, "[")
+	if start < 0 {
+		return "", false, false
+	}
+	// Ignore wiki-links.
+	if start > 0 && before[start-1] == '[' {
+		return "", false, false
+	}
+
+	text = before[start+1:]
+	if strings.Contains(text, "]") {
+		return "", false, false
+	}
+	isImage = start > 0 && before[start-1] == '!'
+	return text, isImage, true
+}
+
 var wikiLinkRegex = regexp.MustCompile(`\[?\[\[(.+?)(?:\|(.+?))?\]\]`)
 var markdownLinkRegex = regexp.MustCompile(`\[([^\]]+?[^\\])\]\((.+?[^\\])\)`)
+var emptyImageLinkRegex = regexp.MustCompile(`!\[\]\((.+?[^\\])\)`)
+
+// isWikiLinkAliasFirst returns whether the alias of the wiki-links comes
+// before their target in this document, e.g. [[alias|target]].
+func (d *document) isWikiLinkAliasFirst() bool {
+	return d.wikiLinkAliasFirst != nil && d.wikiLinkAliasFirst(d.Path)
+}
+
+// wikiLinkParts returns the byte indexes of the target and alias of the
+// wiki-link matched by wikiLinkRegex in line. The alias is nil when the link
+// doesn't have one.
+func wikiLinkParts(line string, match []int, aliasFirst bool) (target []int, alias []int) {
+	first := []int{match[2], match[3]}
+	if match[4] == -1 {
+		return first, nil
+	}
+	// The pipe is escaped in Markdown tables, e.g. [[target\|alias]].
+	if strings.HasSuffix(line[first[0]:first[1]], `\`) {
+		first[1]--
+	}
+	second := []int{match[4], match[5]}
+	if aliasFirst {
+		return second, first
+	}
+	return first, second
+}
 
 // DocumentLinkAt returns the internal or external link found in the document
 // at the given position.
@@ -179,7 +418,7 @@ func (d *document) DocumentLinkAt(pos protocol.Position) (*documentLink, error)
 	}
 
 	for _, link := range links {
-		if positionInRange(d.Content, link.Range, pos) {
+		if d.InRange(link.Range, pos) {
 			return &link, nil
 		}
 	}
@@ -192,28 +431,22 @@ func (d *document) DocumentLinkAt(pos protocol.Position) (*documentLink, error)
 func (d *document) DocumentLinks() ([]documentLink, error) {
 	links := []documentLink{}
 
+	aliasFirst := d.isWikiLinkAliasFirst()
 	lines := d.GetLines()
 	for lineIndex, line := range lines {
 
-		appendLink := func(href string, start, end int, hasTitle bool, isWikiLink bool) {
+		appendLink := func(href string, start, end int, alias string, isWikiLink bool, isImage bool) {
 			if href == "" {
 				return
 			}
 
 			links = append(links, documentLink{
-				Href: href,
-				Range: protocol.Range{
-					Start: protocol.Position{
-						Line:      protocol.UInteger(lineIndex),
-						Character: protocol.UInteger(start),
-					},
-					End: protocol.Position{
-						Line:      protocol.UInteger(lineIndex),
-						Character: protocol.UInteger(end),
-					},
-				},
-				HasTitle:   hasTitle,
+				Href:       href,
+				Range:      d.rangeAt(lineIndex, start, end),
+				HasTitle:   alias != "",
+				Alias:      alias,
 				IsWikiLink: isWikiLink,
+				IsImage:    isImage,
 			})
 		}
 
@@ -223,13 +456,27 @@ func (d *document) DocumentLinks() ([]documentLink, error) {
 			if decodedHref, err := url.PathUnescape(href); err == nil {
 				href = decodedHref
 			}
-			appendLink(href, match[0], match[1], false, false)
+			isImage := match[0] > 0 && line[match[0]-1] == '!'
+			appendLink(href, match[0], match[1], "", false, isImage)
 		}
 
-		for _, match := range wikiLinkRegex.FindAllStringSubmatchIndex(line, -1) {
+		// Images without alternative text are not matched by markdownLinkRegex.
+		for _, match := range emptyImageLinkRegex.FindAllStringSubmatchIndex(line, -1) {
 			href := line[match[2]:match[3]]
-			hasTitle := match[4] != -1
-			appendLink(href, match[0], match[1], hasTitle, true)
+			if decodedHref, err := url.PathUnescape(href); err == nil {
+				href = decodedHref
+			}
+			appendLink(href, match[0], match[1], "", false, true)
+		}
+
+		for _, match := range wikiLinkRegex.FindAllStringSubmatchIndex(line, -1) {
+			target, alias := wikiLinkParts(line, match, aliasFirst)
+			href := line[target[0]:target[1]]
+			aliasText := ""
+			if alias != nil {
+				aliasText = strings.TrimSpace(line[alias[0]:alias[1]])
+			}
+			appendLink(href, match[0], match[1], aliasText, true, false)
 		}
 	}
 
@@ -242,7 +489,13 @@ type documentLink struct {
 	// HasTitle indicates whether this link has a title information. For
 	// example [[filename]] doesn't but [[filename|title]] does.
 	HasTitle bool
+	// Alias displayed instead of the target of a wiki-link, e.g. title in
+	// [[filename|title]].
+	Alias string
 	// IsWikiLink indicates whether this link is a [[WikiLink]] instead of a
 	// regular Markdown link.
 	IsWikiLink bool
+	// IsImage indicates whether this link is an embedded image, e.g.
+	// ![alt](image.png).
+	IsImage bool
 }
@@ -0,0 +1,73 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// newTestDocument creates a document with the given content and position
+// encoding.
+func newTestDocument(content string, encoding positionEncoding) *document {
+	return &document{Path: "/notebook/note.md", Content: content, encoding: encoding}
+}
+
+func pos(line int, character int) protocol.Position {
+	return protocol.Position{Line: protocol.UInteger(line), Character: protocol.UInteger(character)}
+}
+
+func TestDocumentOffsetAt(t *testing.T) {
+	// Lines of 7, 9 and 3 bytes, with CRLF line endings.
+	content := "a😀b\r\n日本語\r\nend"
+
+	test := func(encoding positionEncoding, position protocol.Position, expected int) {
+		t.Helper()
+		doc := newTestDocument(content, encoding)
+		assert.Equal(t, doc.offsetAt(position), expected)
+	}
+
+	test(positionEncodingUTF16, pos(0, 0), 0)
+	test(positionEncodingUTF16, pos(0, 3), 5)
+	test(positionEncodingUTF8, pos(0, 5), 5)
+	test(positionEncodingUTF16, pos(1, 0), 8)
+	test(positionEncodingUTF16, pos(1, 2), 14)
+	test(positionEncodingUTF8, pos(1, 6), 14)
+	test(positionEncodingUTF16, pos(2, 3), 22)
+
+	// Past the end of a line, before the carriage return.
+	test(positionEncodingUTF16, pos(0, 10), 6)
+	test(positionEncodingUTF8, pos(1, 20), 17)
+	test(positionEncodingUTF16, pos(2, 10), 22)
+	// Past the end of the document.
+	test(positionEncodingUTF16, pos(3, 0), 22)
+	test(positionEncodingUTF16, pos(10, 5), 22)
+}
+
+func TestDocumentPositionAt(t *testing.T) {
+	content := "a😀b\r\n日本語\r\nend"
+
+	test := func(encoding positionEncoding, offset int, expected protocol.Position) {
+		t.Helper()
+		doc := newTestDocument(content, encoding)
+		assert.Equal(t, doc.positionAt(offset), expected)
+	}
+
+	test(positionEncodingUTF16, 0, pos(0, 0))
+	test(positionEncodingUTF16, 5, pos(0, 3))
+	test(positionEncodingUTF8, 5, pos(0, 5))
+	test(positionEncodingUTF16, 14, pos(1, 2))
+	test(positionEncodingUTF16, 22, pos(2, 3))
+	test(positionEncodingUTF16, 100, pos(2, 3))
+}
+
+func TestDocumentApplyChanges(t *testing.T) {
+	doc := newTestDocument("a😀b\r\n日本語\r\n", positionEncodingUTF16)
+	doc.ApplyChanges([]interface{}{
+		// Replaces 本 with "x".
+		protocol.TextDocumentContentChangeEvent{Range: protocol.Range{Start: pos(1, 1), End: pos(1, 2)}, Text: "x"},
+		// Appends to the first line, past its end.
+		protocol.TextDocumentContentChangeEvent{Range: protocol.Range{Start: pos(0, 10), End: pos(0, 10)}, Text: "!"},
+	})
+	assert.Equal(t, doc.Content, "a😀b!\r\n日x語\r\n")
+}
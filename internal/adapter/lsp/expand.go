@@ -0,0 +1,107 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+const cmdExpandLink = "zk.expandLink"
+
+type cmdExpandLinkOpts struct {
+	Location  protocol.Location `json:"location"`
+	LinkStyle string            `json:"linkStyle,omitempty"`
+}
+
+// executeCommandExpandLink adds the title of the target note to the bare
+// wiki-link found at the given location, e.g. `[[id]]` becomes
+// `[[id|Title]]`, or a Markdown link depending on the link format.
+func (s *Server) executeCommandExpandLink(context *glsp.Context, args []interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("zk.expandLink expects a notebook path and a dictionary of options as arguments")
+	}
+	wd, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.expandLink expects a notebook path as first argument, got: %v", args[0])
+	}
+	arg, ok := args[1].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("zk.expandLink expects a dictionary of options as second argument, got: %v", args[1])
+	}
+	var opts cmdExpandLinkOpts
+	err := unmarshalJSON(arg, &opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse zk.expandLink args, got: %v", arg)
+	}
+
+	doc, ok := s.documents.Get(opts.Location.URI)
+	if !ok {
+		return nil, fmt.Errorf("can't expand the link in %s", opts.Location.URI)
+	}
+	link, err := doc.DocumentLinkAt(opts.Location.Range.Start)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil || !link.IsWikiLink || link.HasTitle {
+		return nil, fmt.Errorf("no wiki-link without title found at the given location")
+	}
+
+	notebook, err := s.notebooks.Open(wd)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWritable(notebook, cmdExpandLink); err != nil {
+		return nil, err
+	}
+	target, err := s.noteForLink(*link, doc, notebook)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, fmt.Errorf("%s: note not found", link.Href)
+	}
+
+	note := target.MinimalNote
+	note.Title = wikiLinkTitle(*link, target.MinimalNote)
+	if note.Title == "" {
+		return nil, fmt.Errorf("%s: the note has no title", link.Href)
+	}
+
+	config := s.configOf(notebook, doc)
+	style := opts.LinkStyle
+	if style == "" && config.Format.Markdown.LinkFormat == "wiki" {
+		style = "wiki"
+	}
+	var newText string
+	if style == "wiki" {
+		// The original href is kept, e.g. the ID of the note.
+		newText = "[[" + link.Href + "|" + note.Title + "]]"
+	} else {
+		formatter, err := newLinkFormatterWithStyle(notebook, config, style)
+		if err != nil {
+			return nil, err
+		}
+		context, err := core.NewLinkFormatterContext(note, notebook.Path, filepath.Dir(doc.Path))
+		if err != nil {
+			return nil, err
+		}
+		newText, err = formatter(context)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	go context.Call(protocol.ServerWorkspaceApplyEdit, protocol.ApplyWorkspaceEditParams{
+		Edit: protocol.WorkspaceEdit{
+			Changes: map[string][]protocol.TextEdit{
+				doc.URI: {{Range: link.Range, NewText: newText}},
+			},
+		},
+	}, nil)
+
+	return map[string]interface{}{"link": newText}, nil
+}
@@ -0,0 +1,251 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// newNoteCodeActions returns the refactoring actions creating new notes from
+// the selected range of the document.
+func newNoteCodeActions(doc *document, rng protocol.Range, notebook *core.Notebook) ([]protocol.CodeAction, error) {
+	wd := filepath.Dir(doc.Path)
+	actions := []protocol.CodeAction{}
+
+	addAction := func(title string, command string, opts interface{}) error {
+		var jsonOpts map[string]interface{}
+		err := unmarshalJSON(opts, &jsonOpts)
+		if err != nil {
+			return err
+		}
+
+		actions = append(actions, protocol.CodeAction{
+			Title: title,
+			Kind:  stringPtr(protocol.CodeActionKindRefactor),
+			Command: &protocol.Command{
+				Title:     title,
+				Command:   command,
+				Arguments: []interface{}{wd, jsonOpts},
+			},
+		})
+		return nil
+	}
+
+	selection := doc.ContentAtRange(rng)
+	location := &protocol.Location{URI: doc.URI, Range: rng}
+	newNote := func(title string, dir string, group string, location *protocol.Location, linkStyle string) error {
+		return addAction(title, cmdNew, cmdNewOpts{
+			Title:                selection,
+			Dir:                  dir,
+			Group:                group,
+			InsertLinkAtLocation: location,
+			LinkStyle:            linkStyle,
+		})
+	}
+
+	err := newNote("New note in current directory", wd, "", location, "")
+	if err == nil {
+		err = newNote("New note in top directory", "", "", location, "")
+	}
+	if err == nil {
+		err = newNote("New note in current directory, keeping the selection", wd, "", &protocol.Location{
+			URI:   doc.URI,
+			Range: protocol.Range{Start: rng.End, End: rng.End},
+		}, "")
+	}
+	if err == nil {
+		err = newNote("New note in current directory, with a titled wiki-link", wd, "", location, "wiki")
+	}
+	if err == nil {
+		err = newNote("New note in current directory, with a Markdown link", wd, "", location, "markdown")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	groupNames := []string{}
+	for name := range notebook.Config.Groups {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+	for _, name := range groupNames {
+		dir := wd
+		// The paths of a group might be globs.
+		if paths := notebook.Config.Groups[name].Paths; len(paths) > 0 && !strings.ContainsAny(paths[0], "*?[") {
+			dir = filepath.Join(notebook.Path, paths[0])
+		}
+		err := newNote(fmt.Sprintf("New note in group %s", name), dir, name, location, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Multi-line selections are extracted as the content of a new note,
+	// titled with their first line.
+	if text := strings.TrimRightFunc(selection, unicode.IsSpace); strings.Contains(text, "\n") {
+		lines := strings.SplitN(text, "\n", 2)
+		err := addAction("Extract selection into a new note", cmdNew, cmdNewOpts{
+			Title:   listItemRegex.ReplaceAllString(strings.TrimLeft(lines[0], "#> \t"), ""),
+			Content: strings.TrimSpace(lines[1]),
+			Dir:     wd,
+			// The line break after the selection is preserved.
+			InsertLinkAtLocation: &protocol.Location{
+				URI: doc.URI,
+				Range: protocol.Range{
+					Start: rng.Start,
+					End:   doc.positionAt(doc.offsetAt(rng.Start) + len(text)),
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(doc.ListItemsIn(rng)) > 0 {
+		err := addAction("Extract each list item into its own note", cmdExtractListItems, cmdExtractListItemsOpts{
+			Location: *location,
+			Dir:      wd,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return actions, nil
+}
+
+// listItem is an item of a Markdown list, in a document.
+type listItem struct {
+	Text string
+	// Range of the item text, without its bullet.
+	Range protocol.Range
+}
+
+var listItemRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?`)
+
+// ListItemsIn returns the list items found on the lines of the given range.
+// The items which are only a link are ignored.
+func (d *document) ListItemsIn(rng protocol.Range) []listItem {
+	items := []listItem{}
+	lines := d.GetLines()
+	for i := int(rng.Start.Line); i <= int(rng.End.Line) && i < len(lines); i++ {
+		// A selection ending at the start of a line doesn't include it.
+		if i == int(rng.End.Line) && i > int(rng.Start.Line) && rng.End.Character == 0 {
+			break
+		}
+		line := strings.TrimRightFunc(lines[i], unicode.IsSpace)
+		bullet := listItemRegex.FindStringIndex(line)
+		if bullet == nil || bullet[1] >= len(line) {
+			continue
+		}
+		text := line[bullet[1]:]
+		if isLinkOnly(text) {
+			continue
+		}
+		items = append(items, listItem{
+			Text:  text,
+			Range: d.rangeAt(i, bullet[1], len(line)),
+		})
+	}
+	return items
+}
+
+// isLinkOnly returns whether text consists of a single link.
+func isLinkOnly(text string) bool {
+	for _, regex := range []*regexp.Regexp{wikiLinkRegex, markdownLinkRegex} {
+		if loc := regex.FindStringIndex(text); loc != nil && loc[0] == 0 && loc[1] == len(text) {
+			return true
+		}
+	}
+	return false
+}
+
+const cmdExtractListItems = "zk.extractListItems"
+
+type cmdExtractListItemsOpts struct {
+	Location  protocol.Location `json:"location"`
+	Dir       string            `json:"dir,omitempty"`
+	Group     string            `json:"group,omitempty"`
+	LinkStyle string            `json:"linkStyle,omitempty"`
+}
+
+func (s *Server) executeCommandExtractListItems(context *glsp.Context, args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("zk.extractListItems expects a notebook path as first argument")
+	}
+	wd, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.extractListItems expects a notebook path as first argument, got: %v", args[0])
+	}
+
+	var opts cmdExtractListItemsOpts
+	if len(args) > 1 {
+		arg, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("zk.extractListItems expects a dictionary of options as second argument, got: %v", args[1])
+		}
+		err := unmarshalJSON(arg, &opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse zk.extractListItems args, got: %v", arg)
+		}
+	}
+
+	doc, ok := s.documents.Get(opts.Location.URI)
+	if !ok {
+		return nil, fmt.Errorf("can't extract the list items of %s", opts.Location.URI)
+	}
+	items := doc.ListItemsIn(opts.Location.Range)
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no list item found in %s", opts.Location.URI)
+	}
+
+	notebook, err := s.notebooks.Open(wd)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWritable(notebook, cmdExtractListItems); err != nil {
+		return nil, err
+	}
+	linkFormatter, err := newLinkFormatterWithStyle(notebook, s.configOf(notebook, doc), opts.LinkStyle)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	edits := []protocol.TextEdit{}
+	for _, item := range items {
+		note, err := newOrExistingNote(notebook, core.NewNoteOpts{
+			Title:     opt.NewNotEmptyString(item.Text),
+			Directory: opt.NewNotEmptyString(opts.Dir),
+			Group:     opt.NewNotEmptyString(opts.Group),
+			Origin:    lspOrigin(cmdExtractListItems),
+		})
+		if err != nil {
+			return nil, err
+		}
+		link, err := formatLinkIn(doc, note, notebook, linkFormatter)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, filepath.Join(notebook.Path, note.Path))
+		edits = append(edits, protocol.TextEdit{Range: item.Range, NewText: link})
+	}
+
+	go context.Call(protocol.ServerWorkspaceApplyEdit, protocol.ApplyWorkspaceEditParams{
+		Edit: protocol.WorkspaceEdit{
+			Changes: map[string][]protocol.TextEdit{doc.URI: edits},
+		},
+	}, nil)
+
+	return map[string]interface{}{"paths": paths}, nil
+}
@@ -0,0 +1,193 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+const cmdFixDeadLinks = "zk.fixDeadLinks"
+
+type cmdFixDeadLinksOpts struct {
+	Retarget    bool    `json:"retarget,omitempty"`
+	Threshold   float64 `json:"threshold,omitempty"`
+	CreateStubs bool    `json:"createStubs,omitempty"`
+	Template    string  `json:"template,omitempty"`
+	DryRun      bool    `json:"dryRun,omitempty"`
+}
+
+// fixedDeadLinks is the summary returned by the zk.fixDeadLinks command.
+type fixedDeadLinks struct {
+	Retargeted []core.RewrittenLink `json:"retargeted"`
+	Stubs      []deadLinkStub       `json:"stubs"`
+	Unfixed    []deadLinkLocation   `json:"unfixed"`
+	// Edit rewriting the retargeted links, applied unless dryRun is true.
+	Edit protocol.WorkspaceEdit `json:"edit"`
+}
+
+type deadLinkStub struct {
+	Path    string             `json:"path"`
+	AbsPath string             `json:"absPath"`
+	Title   string             `json:"title"`
+	Links   []deadLinkLocation `json:"links"`
+}
+
+type deadLinkLocation struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Href string `json:"href"`
+}
+
+// executeCommandFixDeadLinks fixes all the dead links of the notebook, by
+// retargeting them to a similar note or creating stub notes, depending on
+// the options.
+//
+// The stubs are created on disk like with zk.new, but the retargeted links
+// are rewritten with a workspace edit, to support unsaved documents and
+// undoing the changes.
+func (s *Server) executeCommandFixDeadLinks(context *glsp.Context, args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("zk.fixDeadLinks expects a notebook path as first argument")
+	}
+	wd, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.fixDeadLinks expects a notebook path as first argument, got: %v", args[0])
+	}
+
+	var opts cmdFixDeadLinksOpts
+	if len(args) > 1 {
+		arg, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("zk.fixDeadLinks expects a dictionary of options as second argument, got: %v", args[1])
+		}
+		err := unmarshalJSON(arg, &opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse zk.fixDeadLinks args, got: %v", arg)
+		}
+	}
+
+	notebook, err := s.notebooks.Open(wd)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.DryRun {
+		if err := s.checkWritable(notebook, cmdFixDeadLinks); err != nil {
+			return nil, err
+		}
+	}
+
+	plan, err := notebook.PlanDeadLinkFixes(core.DeadLinksFixOpts{
+		Retarget:          opts.Retarget,
+		RetargetThreshold: opts.Threshold,
+		CreateStubs:       opts.CreateStubs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.DryRun {
+		_, err = notebook.CreateDeadLinkStubs(lspOrigin(cmdFixDeadLinks), plan.Stubs, opt.NewNotEmptyString(opts.Template))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := fixedDeadLinks{
+		Retargeted: []core.RewrittenLink{},
+		Stubs:      []deadLinkStub{},
+		Unfixed:    []deadLinkLocation{},
+		Edit:       protocol.WorkspaceEdit{Changes: map[string][]protocol.TextEdit{}},
+	}
+
+	fixesByNote := map[string][]core.DeadLinkFix{}
+	for _, fix := range plan.Retargets {
+		fixesByNote[fix.Link.Path] = append(fixesByNote[fix.Link.Path], fix)
+	}
+	notePaths := []string{}
+	for path := range fixesByNote {
+		notePaths = append(notePaths, path)
+	}
+	sort.Strings(notePaths)
+
+	for _, path := range notePaths {
+		doc, err := s.documentAt(pathToURI(filepath.Join(notebook.Path, path)))
+		if err != nil {
+			return nil, err
+		}
+		content, rewritten := core.FixDeadLinksInNote(doc.Content, path, fixesByNote[path])
+		if len(rewritten) == 0 {
+			continue
+		}
+		result.Retargeted = append(result.Retargeted, rewritten...)
+		result.Edit.Changes[doc.URI] = []protocol.TextEdit{minimalTextEdit(doc, content)}
+	}
+
+	for _, stub := range plan.Stubs {
+		links := []deadLinkLocation{}
+		for _, link := range stub.Links {
+			links = append(links, newDeadLinkLocation(link))
+		}
+		result.Stubs = append(result.Stubs, deadLinkStub{
+			Path:    stub.Path,
+			AbsPath: filepath.Join(notebook.Path, stub.Path),
+			Title:   stub.Title,
+			Links:   links,
+		})
+	}
+	for _, link := range plan.Unfixed {
+		result.Unfixed = append(result.Unfixed, newDeadLinkLocation(link))
+	}
+
+	if !opts.DryRun && len(result.Edit.Changes) > 0 {
+		go context.Call(protocol.ServerWorkspaceApplyEdit, protocol.ApplyWorkspaceEditParams{
+			Edit: result.Edit,
+		}, nil)
+	}
+
+	return result, nil
+}
+
+func newDeadLinkLocation(link core.DeadLink) deadLinkLocation {
+	return deadLinkLocation{Path: link.Path, Line: link.Line, Href: link.Href}
+}
+
+// minimalTextEdit returns a single edit replacing the content of the
+// document with newContent, which spans only the modified region to keep the
+// cursors and marks of the editor.
+func minimalTextEdit(doc *document, newContent string) protocol.TextEdit {
+	oldContent := doc.Content
+
+	start := 0
+	for start < len(oldContent) && start < len(newContent) && oldContent[start] == newContent[start] {
+		start++
+	}
+	// Avoids splitting a multi-byte character.
+	for start > 0 && start < len(oldContent) && !utf8.RuneStart(oldContent[start]) {
+		start--
+	}
+
+	oldEnd, newEnd := len(oldContent), len(newContent)
+	for oldEnd > start && newEnd > start && oldContent[oldEnd-1] == newContent[newEnd-1] {
+		oldEnd--
+		newEnd--
+	}
+	for oldEnd < len(oldContent) && !utf8.RuneStart(oldContent[oldEnd]) {
+		oldEnd++
+		newEnd++
+	}
+
+	return protocol.TextEdit{
+		Range: protocol.Range{
+			Start: doc.positionAt(start),
+			End:   doc.positionAt(oldEnd),
+		},
+		NewText: newContent[start:newEnd],
+	}
+}
@@ -0,0 +1,122 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+var headingRegex = regexp.MustCompile(`^ {0,3}(#{1,6})(?:\s|$)`)
+var fenceRegex = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+var linkItemRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+.*(?:\[\[.+?\]\]|\[.+?\]\(.+?\))`)
+
+// Minimum number of consecutive list items containing links to be folded
+// together, for example a list of backlinks.
+const minLinkListFoldLength = 3
+
+// FoldingRanges returns the folding ranges for the heading sections, YAML
+// frontmatter, fenced code blocks and long lists of links in the document.
+func (d *document) FoldingRanges() []protocol.FoldingRange {
+	ranges := []protocol.FoldingRange{}
+	lines := d.GetLines()
+
+	appendRange := func(start, end int, kind *protocol.FoldingRangeKind) {
+		if end <= start {
+			return
+		}
+		ranges = append(ranges, protocol.FoldingRange{
+			StartLine: protocol.UInteger(start),
+			EndLine:   protocol.UInteger(end),
+			Kind:      (*string)(kind),
+		})
+	}
+
+	region := protocol.FoldingRangeKindRegion
+
+	type section struct {
+		level int
+		start int
+	}
+	sections := []section{}
+
+	// Closes the sections at the given level or below, ending before the
+	// given line index.
+	closeSections := func(level int, end int) {
+		for len(sections) > 0 && sections[len(sections)-1].level >= level {
+			s := sections[len(sections)-1]
+			sections = sections[:len(sections)-1]
+			appendRange(s.start, lastNonBlankLine(lines, s.start, end), nil)
+		}
+	}
+
+	i := 0
+
+	// YAML frontmatter
+	if end := frontmatterEnd(lines); end > 0 {
+		appendRange(0, end-1, &region)
+		i = end
+	}
+
+	linkListStart := -1
+	closeLinkList := func(end int) {
+		if linkListStart >= 0 && end-linkListStart >= minLinkListFoldLength {
+			appendRange(linkListStart, end-1, &region)
+		}
+		linkListStart = -1
+	}
+
+	for ; i < len(lines); i++ {
+		line := lines[i]
+
+		// Fenced code blocks
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			closeLinkList(i)
+			fence := match[1]
+			end := len(lines) - 1
+			for j := i + 1; j < len(lines); j++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[j]), fence) {
+					end = j
+					break
+				}
+			}
+			appendRange(i, end, &region)
+			i = end
+			continue
+		}
+
+		// Heading sections
+		if match := headingRegex.FindStringSubmatch(line); match != nil {
+			closeLinkList(i)
+			level := len(match[1])
+			closeSections(level, i)
+			sections = append(sections, section{level: level, start: i})
+			continue
+		}
+
+		// Lists of links
+		if linkItemRegex.MatchString(line) {
+			if linkListStart < 0 {
+				linkListStart = i
+			}
+		} else {
+			closeLinkList(i)
+		}
+	}
+
+	closeLinkList(len(lines))
+	closeSections(1, len(lines))
+
+	return ranges
+}
+
+// lastNonBlankLine returns the index of the last line which is not blank in
+// lines[start:end].
+func lastNonBlankLine(lines []string, start int, end int) int {
+	for i := end - 1; i > start; i-- {
+		if strings.TrimSpace(lines[i]) != "" {
+			return i
+		}
+	}
+	return start
+}
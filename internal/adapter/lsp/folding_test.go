@@ -0,0 +1,88 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestDocumentFoldingRanges(t *testing.T) {
+	test := func(content string, expected []protocol.FoldingRange) {
+		t.Helper()
+		doc := newTestDocument(content, positionEncodingUTF16)
+		assert.Equal(t, doc.FoldingRanges(), expected)
+	}
+
+	test("", []protocol.FoldingRange{})
+	test("# Title", []protocol.FoldingRange{})
+
+	test(`---
+title: Note
+---
+# Title
+
+Intro
+
+## Section
+
+`+"```go"+`
+# Not a heading
+`+"```"+`
+
+- [[a]]
+- [[b]]
+- [c](c.md)
+
+- [[d]]
+- [[e]]
+
+## Other
+Text
+`, []protocol.FoldingRange{
+		foldingRegion(0, 2),
+		foldingRegion(9, 11),
+		foldingRegion(13, 15),
+		foldingSection(7, 18),
+		foldingSection(20, 21),
+		foldingSection(3, 21),
+	})
+
+	// Sections of the same level are siblings, and the deeper ones are
+	// closed by a higher heading.
+	test("# A\nText\n### A.1\nText\n## A.2\nText\n# B\nText\n", []protocol.FoldingRange{
+		foldingSection(2, 3),
+		foldingSection(4, 5),
+		foldingSection(0, 5),
+		foldingSection(6, 7),
+	})
+
+	// An unterminated fence or frontmatter.
+	test("Text\n~~~\ncode\n```\nmore", []protocol.FoldingRange{
+		foldingRegion(1, 4),
+	})
+	test("---\ntitle: Note\n", []protocol.FoldingRange{})
+
+	// Lists of links need at least three consecutive items, which can be
+	// numbered.
+	test("1. [[a]]\n2. [[b]]\n3) See [[c]]\n- No link\n", []protocol.FoldingRange{
+		foldingRegion(0, 2),
+	})
+	test("- [[a]]\n- [[b]]\n- No link\n", []protocol.FoldingRange{})
+}
+
+func foldingRegion(start int, end int) protocol.FoldingRange {
+	kind := protocol.FoldingRangeKindRegion
+	return protocol.FoldingRange{
+		StartLine: protocol.UInteger(start),
+		EndLine:   protocol.UInteger(end),
+		Kind:      (*string)(&kind),
+	}
+}
+
+func foldingSection(start int, end int) protocol.FoldingRange {
+	return protocol.FoldingRange{
+		StartLine: protocol.UInteger(start),
+		EndLine:   protocol.UInteger(end),
+	}
+}
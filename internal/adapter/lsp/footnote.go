@@ -0,0 +1,141 @@
+package lsp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+var footnoteRefRegex = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+var footnoteDefRegex = regexp.MustCompile(`^ {0,3}\[\^([^\]\s]+)\]:[ \t]?(.*)$`)
+
+// documentFootnote is a footnote reference, e.g. `[^1]`, or the marker of a
+// footnote definition, e.g. `[^1]: Text`.
+type documentFootnote struct {
+	Label string
+	Range protocol.Range
+	// Text of the footnote, for a definition. The indented lines following
+	// the marker are included.
+	Text string
+}
+
+// Footnotes returns the footnote references and definitions found in the
+// document, outside of the code blocks. The definitions are indexed by
+// label, the first one wins when a label is defined several times.
+func (d *document) Footnotes() (refs []documentFootnote, defs map[string]documentFootnote) {
+	refs = []documentFootnote{}
+	defs = map[string]documentFootnote{}
+
+	lines := d.GetLines()
+	i := frontmatterEnd(lines)
+	for ; i < len(lines); i++ {
+		line := lines[i]
+
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			fence := match[1]
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+					break
+				}
+			}
+			continue
+		}
+
+		refsStart := 0
+		if match := footnoteDefRegex.FindStringSubmatchIndex(line); match != nil {
+			label := line[match[2]:match[3]]
+			text := []string{line[match[4]:match[5]]}
+			// Continuation lines are indented.
+			for j := i + 1; j < len(lines); j++ {
+				next := lines[j]
+				if strings.TrimSpace(next) != "" && !strings.HasPrefix(next, "    ") && !strings.HasPrefix(next, "\t") {
+					break
+				}
+				text = append(text, strings.TrimSpace(next))
+			}
+
+			if _, ok := defs[label]; !ok {
+				defs[label] = documentFootnote{
+					Label: label,
+					Range: d.rangeAt(i, match[2]-2, match[3]+1),
+					Text:  strings.TrimSpace(strings.Join(text, "\n")),
+				}
+			}
+			// References can be nested in the footnote text.
+			refsStart = match[4]
+		}
+
+		for _, match := range footnoteRefRegex.FindAllStringSubmatchIndex(line[refsStart:], -1) {
+			refs = append(refs, documentFootnote{
+				Label: line[refsStart+match[2] : refsStart+match[3]],
+				Range: d.rangeAt(i, refsStart+match[0], refsStart+match[1]),
+			})
+		}
+	}
+
+	return refs, defs
+}
+
+// FootnoteAt returns the footnote reference or definition marker found in
+// the document at the given position, with its definition if any.
+func (d *document) FootnoteAt(pos protocol.Position) (label string, def *documentFootnote, ok bool) {
+	refs, defs := d.Footnotes()
+	found := func(label string) (string, *documentFootnote, bool) {
+		if def, ok := defs[label]; ok {
+			return label, &def, true
+		}
+		return label, nil, true
+	}
+
+	for _, ref := range refs {
+		if d.InRange(ref.Range, pos) {
+			return found(ref.Label)
+		}
+	}
+	for _, def := range defs {
+		if d.InRange(def.Range, pos) {
+			return found(def.Label)
+		}
+	}
+	return "", nil, false
+}
+
+// FootnoteDiagnostics reports the footnote references without definition,
+// and the definitions which are never referenced.
+func (d *document) FootnoteDiagnostics(severity protocol.DiagnosticSeverity) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+	appendDiagnostic := func(rng protocol.Range, message string) {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    rng,
+			Severity: &severity,
+			Source:   stringPtr("zk"),
+			Message:  message,
+		})
+	}
+
+	refs, defs := d.Footnotes()
+	referenced := map[string]bool{}
+	for _, ref := range refs {
+		referenced[ref.Label] = true
+		if _, ok := defs[ref.Label]; !ok {
+			appendDiagnostic(ref.Range, "footnote not defined")
+		}
+	}
+
+	unused := []documentFootnote{}
+	for label, def := range defs {
+		if !referenced[label] {
+			unused = append(unused, def)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		return unused[i].Range.Start.Line < unused[j].Range.Start.Line
+	})
+	for _, def := range unused {
+		appendDiagnostic(def.Range, "unused footnote")
+	}
+
+	return diagnostics
+}
@@ -0,0 +1,205 @@
+package lsp
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/core"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// onTypeFormattingOptions are the characters triggering the on-type
+// formatting of a document.
+var onTypeFormattingOptions = protocol.DocumentOnTypeFormattingOptions{
+	FirstTriggerCharacter: "[",
+	MoreTriggerCharacter:  []string{"]", "\n"},
+}
+
+// listMarkerRegex matches the marker of a list item, capturing its
+// indentation, bullet or number with its delimiter, the spacing and the
+// checkbox of a task.
+var listMarkerRegex = regexp.MustCompile(`^(\s*)(?:([-*+])|(\d{1,9})([.)]))(\s+)(\[[ xX]\]\s+)?`)
+
+// OnTypeFormatting returns the edits formatting the document after typing ch
+// at the given position, according to the enabled settings:
+//
+//   - `[[` is closed with `]]`, which is skipped over when typed.
+//   - A new line after a list item starts a new item, and renumbers the
+//     following items of an ordered list. A new line after an empty item
+//     ends the list instead.
+//
+// The code blocks and the frontmatter are never formatted.
+func (d *document) OnTypeFormatting(pos protocol.Position, ch string, config core.LSPFormattingConfig) []protocol.TextEdit {
+	if d.isCodeLine(int(pos.Line)) {
+		return nil
+	}
+
+	switch ch {
+	case "[":
+		if config.CloseWikiLinks {
+			return d.closeWikiLink(pos)
+		}
+	case "]":
+		if config.CloseWikiLinks {
+			return d.skipWikiLinkClosing(pos)
+		}
+	case "\n":
+		if config.ContinueLists {
+			return d.continueList(pos)
+		}
+	}
+	return nil
+}
+
+// closeWikiLink inserts `]]` after a `[[` typed before the position, unless
+// it was already auto-paired by the editor.
+func (d *document) closeWikiLink(pos protocol.Position) []protocol.TextEdit {
+	line, ok := d.GetLine(int(pos.Line))
+	if !ok {
+		return nil
+	}
+	charIdx := d.charIndex(line, pos)
+	before, after := line[:charIdx], line[charIdx:]
+	if !strings.HasSuffix(before, "[[") || strings.HasPrefix(after, "]") || isInInlineCode(before) {
+		return nil
+	}
+
+	return []protocol.TextEdit{{
+		Range:   d.rangeAt(int(pos.Line), charIdx, charIdx),
+		NewText: "]]",
+	}}
+}
+
+// skipWikiLinkClosing removes the `]` following the position when the one
+// typed before it closes the link instead, e.g. `[[note]|]]`.
+func (d *document) skipWikiLinkClosing(pos protocol.Position) []protocol.TextEdit {
+	line, ok := d.GetLine(int(pos.Line))
+	if !ok {
+		return nil
+	}
+	charIdx := d.charIndex(line, pos)
+	before, after := line[:charIdx], line[charIdx:]
+	if !strings.HasSuffix(before, "]") || !strings.HasPrefix(after, "]") || isInInlineCode(before) {
+		return nil
+	}
+	// Only the extra closing brackets are removed.
+	if strings.Count(line, "]") <= strings.Count(line, "[") {
+		return nil
+	}
+
+	return []protocol.TextEdit{{
+		Range:   d.rangeAt(int(pos.Line), charIdx, charIdx+1),
+		NewText: "",
+	}}
+}
+
+// continueList starts a new list item on the line of the position, when
+// the previous line is a list item.
+func (d *document) continueList(pos protocol.Position) []protocol.TextEdit {
+	lineIndex := int(pos.Line)
+	line, ok := d.GetLine(lineIndex)
+	if !ok || lineIndex == 0 {
+		return nil
+	}
+	prev, _ := d.GetLine(lineIndex - 1)
+	prev = strings.TrimRight(prev, "\r")
+	charIdx := d.charIndex(line, pos)
+	// The editor may have indented the new line already.
+	if strings.TrimSpace(line[:charIdx]) != "" {
+		return nil
+	}
+
+	match := listMarkerRegex.FindStringSubmatch(prev)
+	if match == nil {
+		return nil
+	}
+	indent, bullet, number, delimiter, spacing, checkbox := match[1], match[2], match[3], match[4], match[5], match[6]
+
+	// An empty item ends the list.
+	if strings.TrimSpace(prev[len(match[0]):]) == "" {
+		return []protocol.TextEdit{
+			{Range: d.rangeAt(lineIndex-1, 0, len(prev)), NewText: ""},
+			{Range: d.rangeAt(lineIndex, 0, charIdx), NewText: ""},
+		}
+	}
+
+	marker := bullet
+	next := 0
+	if number != "" {
+		next, _ = strconv.Atoi(number)
+		next++
+		marker = strconv.Itoa(next) + delimiter
+	}
+	if checkbox != "" {
+		checkbox = "[ ] "
+	}
+	edits := []protocol.TextEdit{{
+		Range:   d.rangeAt(lineIndex, 0, charIdx),
+		NewText: indent + marker + spacing + checkbox,
+	}}
+
+	if number != "" {
+		edits = append(edits, d.renumberList(lineIndex+1, indent, delimiter, next+1)...)
+	}
+	return edits
+}
+
+// renumberList returns the edits renumbering the ordered list items with
+// the given indentation and delimiter, from the line at start until the end
+// of the list.
+func (d *document) renumberList(start int, indent string, delimiter string, number int) []protocol.TextEdit {
+	edits := []protocol.TextEdit{}
+	lines := d.GetLines()
+	for i := start; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if len(lineIndent) > len(indent) {
+			// Nested content of the previous item.
+			continue
+		}
+		match := listMarkerRegex.FindStringSubmatchIndex(line)
+		if match == nil || lineIndent != indent || match[6] < 0 || line[match[8]:match[9]] != delimiter {
+			break
+		}
+		if line[match[6]:match[7]] != strconv.Itoa(number) {
+			edits = append(edits, protocol.TextEdit{
+				Range:   d.rangeAt(i, match[6], match[7]),
+				NewText: strconv.Itoa(number),
+			})
+		}
+		number++
+	}
+	return edits
+}
+
+// isCodeLine returns whether the line at the given index is part of the
+// frontmatter or of a fenced code block.
+func (d *document) isCodeLine(lineIndex int) bool {
+	lines := d.GetLines()
+	start := frontmatterEnd(lines)
+	if lineIndex < start {
+		return true
+	}
+
+	fence := ""
+	for i := start; i < lineIndex && i < len(lines); i++ {
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+				fence = ""
+			}
+		} else if match := fenceRegex.FindStringSubmatch(lines[i]); match != nil {
+			fence = match[1]
+		}
+	}
+	return fence != ""
+}
+
+// isInInlineCode returns whether the end of the given text is inside an
+// inline code span.
+func isInInlineCode(text string) bool {
+	return strings.Count(text, "`")%2 == 1
+}
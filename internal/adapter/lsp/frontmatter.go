@@ -0,0 +1,133 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/core"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Frontmatter keys offered even when no note of the notebook uses them yet.
+var frontmatterBuiltinKeys = []string{"title", "date", "tags", "aliases"}
+
+// Frontmatter keys holding the tags of a note, as parsed by the Markdown
+// parser.
+var frontmatterTagKeys = map[string]bool{
+	"tag": true, "tags": true, "keyword": true, "keywords": true,
+}
+
+var frontmatterKeyRegex = regexp.MustCompile(`^([\w-]*)$`)
+var frontmatterEntryRegex = regexp.MustCompile(`^([\w-]+):(.*)$`)
+var frontmatterListItemRegex = regexp.MustCompile(`^\s+-\s*[^\s,]*$`)
+
+// frontmatterQuery describes what is being typed in the YAML frontmatter.
+type frontmatterQuery struct {
+	// Key whose value is typed, or empty when typing a key.
+	Key string
+	// Keys already defined in the frontmatter.
+	ExistingKeys map[string]bool
+}
+
+// FrontmatterQueryAt returns what is typed at the given position, when it is
+// inside the YAML frontmatter of the document.
+func (d *document) FrontmatterQueryAt(pos protocol.Position) (frontmatterQuery, bool) {
+	query := frontmatterQuery{ExistingKeys: map[string]bool{}}
+
+	lines := d.GetLines()
+	end := frontmatterEnd(lines)
+	lineIndex := int(pos.Line)
+	if lineIndex == 0 || lineIndex >= end-1 {
+		return query, false
+	}
+
+	for i := 1; i < end-1; i++ {
+		if match := frontmatterEntryRegex.FindStringSubmatch(lines[i]); match != nil && i != lineIndex {
+			query.ExistingKeys[match[1]] = true
+		}
+	}
+
+	line := lines[lineIndex]
+	before := line[:d.charIndex(line, pos)]
+
+	switch {
+	case frontmatterKeyRegex.MatchString(before):
+		return query, true
+
+	case frontmatterEntryRegex.MatchString(before):
+		query.Key = frontmatterEntryRegex.FindStringSubmatch(before)[1]
+		return query, true
+
+	case frontmatterListItemRegex.MatchString(before):
+		// The key of a block sequence is on the closest non-indented line.
+		for i := lineIndex - 1; i > 0; i-- {
+			if strings.HasPrefix(lines[i], " ") || strings.HasPrefix(lines[i], "\t") {
+				continue
+			}
+			if match := frontmatterEntryRegex.FindStringSubmatch(lines[i]); match != nil && strings.TrimSpace(match[2]) == "" {
+				query.Key = match[1]
+				return query, true
+			}
+			break
+		}
+	}
+
+	return query, false
+}
+
+// buildFrontmatterCompletionList completes the keys of the YAML frontmatter
+// with the ones used in the notebook, or the values of the tags keys with
+// the tags of the notebook.
+func (s *Server) buildFrontmatterCompletionList(notebook *core.Notebook, query frontmatterQuery) ([]protocol.CompletionItem, error) {
+	if query.Key != "" {
+		if !frontmatterTagKeys[strings.ToLower(query.Key)] {
+			return nil, nil
+		}
+		return s.buildTagCompletionList(notebook, "")
+	}
+
+	usages, err := s.completion.MetadataKeys(notebook)
+	if err != nil {
+		return nil, err
+	}
+	return frontmatterKeyItems(usages, query.ExistingKeys), nil
+}
+
+// frontmatterKeyItems returns the completion items of the frontmatter keys
+// used in the notebook, followed by the built-in keys. The keys already
+// defined in the frontmatter are skipped.
+func frontmatterKeyItems(usages []metadataKeyUsage, existingKeys map[string]bool) []protocol.CompletionItem {
+	counts := map[string]int{}
+	keys := []string{}
+	for _, usage := range usages {
+		counts[usage.Name] = usage.Count
+		keys = append(keys, usage.Name)
+	}
+	for _, key := range frontmatterBuiltinKeys {
+		if _, ok := counts[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	kind := protocol.CompletionItemKindProperty
+	items := []protocol.CompletionItem{}
+	for i, key := range keys {
+		if existingKeys[key] {
+			continue
+		}
+		item := protocol.CompletionItem{
+			Label:      key,
+			Kind:       &kind,
+			InsertText: stringPtr(key + ": "),
+			// Keeps the most common keys first.
+			SortText: stringPtr(fmt.Sprintf("%05d", i)),
+		}
+		if count := counts[key]; count > 0 {
+			item.Detail = stringPtr(fmt.Sprintf("%d %s", count, strutil.Pluralize("note", count)))
+		}
+		items = append(items, item)
+	}
+	return items
+}
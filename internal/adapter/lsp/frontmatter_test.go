@@ -0,0 +1,95 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestDocumentFrontmatterQueryAt(t *testing.T) {
+	doc := newTestDocument(`---
+title: Note
+ti
+tags:
+  - pro
+  - 
+aliases: [a]
+date: 
+---
+tags: body
+`, positionEncodingUTF16)
+
+	test := func(line int, character int, expectedKey string, expectedOK bool) {
+		t.Helper()
+		query, ok := doc.FrontmatterQueryAt(pos(line, character))
+		assert.Equal(t, ok, expectedOK)
+		if ok {
+			assert.Equal(t, query.Key, expectedKey)
+		}
+	}
+
+	// Keys.
+	test(2, 2, "", true)
+	test(2, 0, "", true)
+	// Values.
+	test(1, 7, "title", true)
+	test(7, 6, "date", true)
+	// Items of a block sequence.
+	test(4, 7, "tags", true)
+	test(5, 4, "tags", true)
+	test(6, 12, "aliases", true)
+	// Out of the frontmatter.
+	test(0, 3, "", false)
+	test(8, 3, "", false)
+	test(9, 5, "", false)
+
+	query, _ := doc.FrontmatterQueryAt(pos(2, 2))
+	assert.Equal(t, query.ExistingKeys, map[string]bool{"title": true, "tags": true, "aliases": true, "date": true})
+	// The key on the current line is not considered as existing.
+	query, _ = doc.FrontmatterQueryAt(pos(1, 3))
+	assert.False(t, query.ExistingKeys["title"])
+
+	_, ok := newTestDocument("# No frontmatter\ntitle", positionEncodingUTF16).FrontmatterQueryAt(pos(1, 2))
+	assert.False(t, ok)
+}
+
+func TestCountMetadataKeys(t *testing.T) {
+	assert.Equal(t, countMetadataKeys([]core.MinimalNote{}), []metadataKeyUsage{})
+	assert.Equal(t,
+		countMetadataKeys([]core.MinimalNote{
+			{Metadata: map[string]interface{}{"title": "A", "source": "web"}},
+			{Metadata: map[string]interface{}{"title": "B", "author": "me"}},
+			{Metadata: map[string]interface{}{"title": "C", "source": "book"}},
+			{},
+		}),
+		[]metadataKeyUsage{{"title", 3}, {"source", 2}, {"author", 1}},
+	)
+}
+
+func TestFrontmatterKeyItems(t *testing.T) {
+	items := frontmatterKeyItems(
+		[]metadataKeyUsage{{"title", 3}, {"source", 1}},
+		map[string]bool{"source": true},
+	)
+
+	labels := []string{}
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	// The built-in keys come after the used ones, which are skipped when
+	// already defined.
+	assert.Equal(t, labels, []string{"title", "date", "tags", "aliases"})
+
+	kind := protocol.CompletionItemKindProperty
+	assert.Equal(t, items[0], protocol.CompletionItem{
+		Label:      "title",
+		Kind:       &kind,
+		InsertText: stringPtr("title: "),
+		SortText:   stringPtr("00000"),
+		Detail:     stringPtr("3 notes"),
+	})
+	assert.Nil(t, items[1].Detail)
+	assert.Equal(t, *items[1].SortText, "00002")
+}
@@ -0,0 +1,144 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+const cmdLink = "zk.link"
+
+type cmdLinkOpts struct {
+	// Path of the target note, absolute or relative to the notebook root,
+	// or a portion of it such as its ID.
+	Path     string            `json:"path"`
+	Location protocol.Location `json:"location"`
+	// Label of the link, instead of the title of the note.
+	Title     string `json:"title,omitempty"`
+	LinkStyle string `json:"linkStyle,omitempty"`
+	// The edit is applied by the server unless false.
+	ApplyEdit *bool `json:"applyEdit,omitempty"`
+}
+
+// insertedLink is the result of the zk.link command.
+type insertedLink struct {
+	Path    string `json:"path"`
+	AbsPath string `json:"absPath"`
+	Link    string `json:"link"`
+	// Edit inserting the link at the given location.
+	Edit protocol.WorkspaceEdit `json:"edit"`
+}
+
+// executeCommandLink formats a link to the given note with the link format of
+// the notebook, and inserts it at the given location. It lets the pickers of
+// the editor plugins insert a link without formatting it themselves.
+func (s *Server) executeCommandLink(context *glsp.Context, args []interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("zk.link expects a notebook path and a dictionary of options as arguments")
+	}
+	wd, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.link expects a notebook path as first argument, got: %v", args[0])
+	}
+	arg, ok := args[1].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("zk.link expects a dictionary of options as second argument, got: %v", args[1])
+	}
+	var opts cmdLinkOpts
+	err := unmarshalJSON(arg, &opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse zk.link args, got: %v", arg)
+	}
+	if opts.Path == "" {
+		return nil, fmt.Errorf("zk.link expects the path of the target note")
+	}
+	applyEdit := opts.ApplyEdit == nil || *opts.ApplyEdit
+
+	notebook, err := s.notebooks.Open(wd)
+	if err != nil {
+		return nil, err
+	}
+	if applyEdit {
+		if err := s.checkWritable(notebook, cmdLink); err != nil {
+			return nil, err
+		}
+	}
+	doc, err := s.documentAt(opts.Location.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := findLinkTarget(notebook, opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	if note == nil {
+		return nil, fmt.Errorf("%s: note not found", opts.Path)
+	}
+	if opts.Title != "" {
+		note.Title = opts.Title
+	}
+
+	formatter, err := newLinkFormatterWithStyle(notebook, s.configOf(notebook, doc), opts.LinkStyle)
+	if err != nil {
+		return nil, err
+	}
+	linkContext, err := core.NewLinkFormatterContext(*note, notebook.Path, filepath.Dir(doc.Path))
+	if err != nil {
+		return nil, err
+	}
+	link, err := formatter(linkContext)
+	if err != nil {
+		return nil, err
+	}
+
+	result := insertedLink{
+		Path:    note.Path,
+		AbsPath: filepath.Join(notebook.Path, note.Path),
+		Link:    link,
+		Edit:    insertLinkEdit(doc, opts.Location, link),
+	}
+	if applyEdit {
+		go context.Call(protocol.ServerWorkspaceApplyEdit, protocol.ApplyWorkspaceEditParams{
+			Edit: result.Edit,
+		}, nil)
+	}
+
+	return result, nil
+}
+
+// findLinkTarget returns the note at the given path, absolute or relative to
+// the notebook root. Otherwise the path is resolved like a wiki-link, e.g.
+// with the ID, an alias or the title of the note.
+func findLinkTarget(notebook *core.Notebook, path string) (*core.MinimalNote, error) {
+	if filepath.IsAbs(path) {
+		relPath, err := notebook.RelPath(path)
+		if err != nil {
+			return nil, err
+		}
+		path = relPath
+	}
+	path = filepath.ToSlash(path)
+
+	note, _, err := notebook.ResolveLink("", path, core.ResolveLinkOpts{IsWikiLink: true})
+	return note, err
+}
+
+// insertLinkEdit returns the edit inserting link at the given location. A
+// link inserted right after some text is kept apart from it.
+func insertLinkEdit(doc *document, location protocol.Location, link string) protocol.WorkspaceEdit {
+	rng := location.Range
+	if isRangeEmpty(rng) && strings.TrimSpace(doc.LookBehind(rng.Start, 1)) != "" {
+		link = " " + link
+	}
+	return protocol.WorkspaceEdit{
+		Changes: map[string][]protocol.TextEdit{
+			location.URI: {{Range: rng, NewText: link}},
+		},
+	}
+}
@@ -2,7 +2,10 @@ package lsp
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/mickael-menu/zk/internal/util"
 	"github.com/tliron/kutil/logging"
 )
 
@@ -33,6 +36,38 @@ func (l *glspLogger) Println(vs ...interface{}) {
 
 func (l *glspLogger) Err(err error) {
 	if err != nil {
-		l.log.Debugf("zk: warning: %v", err)
+		l.log.Debugf("zk: event=warning error=%s", logfmtValue(err.Error()))
 	}
 }
+
+// logEvent logs a structured event with the given key/value pairs, following
+// the logfmt convention to be filtered with the usual tools, e.g.
+//
+//	event=request id=42 method=textDocument/completion duration=1.2ms
+//
+// The nil values are omitted.
+func logEvent(logger util.Logger, event string, keyvals ...interface{}) {
+	if logger == nil {
+		return
+	}
+	fields := []string{"event=" + logfmtValue(event)}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		value := keyvals[i+1]
+		if value == nil {
+			continue
+		}
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		fields = append(fields, fmt.Sprint(keyvals[i])+"="+logfmtValue(fmt.Sprint(value)))
+	}
+	logger.Println(strings.Join(fields, " "))
+}
+
+// logfmtValue quotes a logfmt value when needed.
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,98 @@
+package lsp
+
+import (
+	"strings"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// markupKinds holds the formats used to display rich content in the client,
+// negotiated from its capabilities.
+type markupKinds struct {
+	// Format of the hover contents.
+	hover protocol.MarkupKind
+	// Format of the completion items documentation.
+	documentation protocol.MarkupKind
+}
+
+func negotiateMarkupKinds(capabilities protocol.ClientCapabilities) markupKinds {
+	kinds := markupKinds{
+		hover:         protocol.MarkupKindMarkdown,
+		documentation: protocol.MarkupKindMarkdown,
+	}
+	textDocument := capabilities.TextDocument
+	if textDocument == nil {
+		return kinds
+	}
+	if textDocument.Hover != nil {
+		kinds.hover = preferredMarkupKind(textDocument.Hover.ContentFormat)
+	}
+	if textDocument.Completion != nil && textDocument.Completion.CompletionItem != nil {
+		kinds.documentation = preferredMarkupKind(textDocument.Completion.CompletionItem.DocumentationFormat)
+	}
+	return kinds
+}
+
+// preferredMarkupKind returns the first format supported by the server among
+// the ones accepted by the client, from its preferred one. Markdown is used
+// when the client doesn't tell.
+func preferredMarkupKind(formats []protocol.MarkupKind) protocol.MarkupKind {
+	for _, kind := range formats {
+		if kind == protocol.MarkupKindMarkdown || kind == protocol.MarkupKindPlainText {
+			return kind
+		}
+	}
+	return protocol.MarkupKindMarkdown
+}
+
+// noteMarkupContent renders the content of a note, e.g. to preview it in a
+// hover. In plain text, the YAML frontmatter is removed as it is not
+// rendered as metadata by the client.
+func noteMarkupContent(kind protocol.MarkupKind, content string) protocol.MarkupContent {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if kind == protocol.MarkupKindPlainText {
+		lines := strings.Split(content, "\n")
+		content = strings.Join(lines[frontmatterEnd(lines):], "\n")
+	}
+	return protocol.MarkupContent{
+		Kind:  kind,
+		Value: strings.TrimSpace(content),
+	}
+}
+
+// frontmatterEnd returns the index of the first line following the YAML
+// frontmatter of a note, or 0 if it has none.
+func frontmatterEnd(lines []string) int {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		if trimmed := strings.TrimSpace(lines[i]); trimmed == "---" || trimmed == "..." {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+// escapeMarkdown escapes the Markdown syntax of an external text, e.g. the
+// title of a web page, to display it verbatim.
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// withSummaryHeader prepends the summary of a note to its rendered content.
+func withSummaryHeader(content protocol.MarkupContent, summary string) protocol.MarkupContent {
+	if summary == "" {
+		return content
+	}
+	if content.Kind == protocol.MarkupKindMarkdown {
+		summary = "> " + strings.Join(strings.Split(summary, "\n"), "\n> ")
+	}
+	content.Value = summary + "\n\n" + content.Value
+	return content
+}
@@ -0,0 +1,214 @@
+package lsp
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/prometheus"
+)
+
+// serverMetrics measures the activity of the LSP server, to debug the
+// performance issues with zk.stats or the Prometheus endpoint of
+// `zk lsp --metrics`.
+//
+// In daemon mode, it is shared by the servers of all the clients.
+type serverMetrics struct {
+	started time.Time
+	// Sequence of the requests, used as request IDs in the logs. Accessed
+	// atomically.
+	lastRequestID uint64
+
+	mutex    sync.Mutex
+	handlers map[string]*handlerMetrics
+}
+
+// handlerMetrics aggregates the requests and notifications handled for a
+// single LSP method.
+type handlerMetrics struct {
+	count    int
+	errors   int
+	duration time.Duration
+	max      time.Duration
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{
+		started:  time.Now(),
+		handlers: map[string]*handlerMetrics{},
+	}
+}
+
+// nextRequestID returns a new identifier for a request, unique in this
+// process.
+func (m *serverMetrics) nextRequestID() uint64 {
+	return atomic.AddUint64(&m.lastRequestID, 1)
+}
+
+// record adds a handled request for the given method.
+func (m *serverMetrics) record(method string, duration time.Duration, failed bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	handler, ok := m.handlers[method]
+	if !ok {
+		handler = &handlerMetrics{}
+		m.handlers[method] = handler
+	}
+	handler.count++
+	if failed {
+		handler.errors++
+	}
+	handler.duration += duration
+	if duration > handler.max {
+		handler.max = duration
+	}
+}
+
+// lspStats is the result of the zk.stats command.
+type lspStats struct {
+	Version         string          `json:"version"`
+	UptimeSeconds   float64         `json:"uptimeSeconds"`
+	OpenDocuments   int             `json:"openDocuments"`
+	Handlers        []handlerStats  `json:"handlers"`
+	CompletionCache completionStats `json:"completionCache"`
+}
+
+// handlerStats is the latency of the requests handled for an LSP method.
+type handlerStats struct {
+	Method    string  `json:"method"`
+	Count     int     `json:"count"`
+	Errors    int     `json:"errors"`
+	TotalMs   float64 `json:"totalMs"`
+	AverageMs float64 `json:"averageMs"`
+	MaxMs     float64 `json:"maxMs"`
+}
+
+// completionStats reports the efficiency of the note completion cache.
+type completionStats struct {
+	Hits    int     `json:"hits"`
+	Misses  int     `json:"misses"`
+	HitRate float64 `json:"hitRate"`
+	// Number of notes cached for each notebook.
+	Notebooks []notebookCacheStats `json:"notebooks"`
+}
+
+type notebookCacheStats struct {
+	Path  string `json:"path"`
+	Notes int    `json:"notes"`
+}
+
+// handlerStats returns the metrics of the handled methods, the slowest in
+// total first.
+func (m *serverMetrics) handlerStats() []handlerStats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stats := []handlerStats{}
+	for method, handler := range m.handlers {
+		stats = append(stats, handlerStats{
+			Method:    method,
+			Count:     handler.count,
+			Errors:    handler.errors,
+			TotalMs:   milliseconds(handler.duration),
+			AverageMs: milliseconds(handler.duration / time.Duration(handler.count)),
+			MaxMs:     milliseconds(handler.max),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		return a.TotalMs > b.TotalMs || (a.TotalMs == b.TotalMs && a.Method < b.Method)
+	})
+	return stats
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
+
+// logRequest records the metrics of a handled request, and logs it with its
+// ID and duration.
+func (s *Server) logRequest(id uint64, method string, duration time.Duration, err error) {
+	s.metrics.record(method, duration, err != nil)
+	logEvent(s.logger, "request",
+		"id", id,
+		"method", method,
+		"duration", duration.Round(time.Microsecond),
+		"error", err,
+	)
+}
+
+const cmdStats = "zk.stats"
+
+func (s *Server) executeCommandStats() (interface{}, error) {
+	return lspStats{
+		Version:         s.version,
+		UptimeSeconds:   time.Since(s.metrics.started).Seconds(),
+		OpenDocuments:   len(s.documents.documents),
+		Handlers:        s.metrics.handlerStats(),
+		CompletionCache: s.completion.stats(),
+	}, nil
+}
+
+// serveMetrics exposes the metrics of the LSP server to Prometheus over
+// HTTP, until the server fails.
+func serveMetrics(address string, metrics *serverMetrics, completion *noteCompletionCache, logger util.Logger) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprint(w, formatPrometheusMetrics(metrics, completion.stats(), time.Now()))
+	})
+
+	logEvent(logger, "metrics", "address", address)
+	err := http.ListenAndServe(address, mux)
+	logger.Err(errors.Wrap(err, "metrics server"))
+}
+
+// formatPrometheusMetrics renders the metrics of the LSP server in the
+// Prometheus text exposition format.
+func formatPrometheusMetrics(metrics *serverMetrics, completion completionStats, now time.Time) string {
+	out := prometheus.Writer{}
+	// handlerMetric reports a metric for each handled method, labeled with
+	// its name.
+	handlerMetric := func(name string, help string, kind string, handlers []handlerStats, value func(handlerStats) float64) {
+		if len(handlers) == 0 {
+			return
+		}
+		out.Header(name, help, kind)
+		for _, handler := range handlers {
+			out.Sample(name, []prometheus.Label{{Name: "method", Value: handler.Method}}, value(handler))
+		}
+	}
+
+	out.Header("zk_lsp_uptime_seconds", "Time elapsed since the LSP server started.", "gauge")
+	out.Sample("zk_lsp_uptime_seconds", nil, now.Sub(metrics.started).Seconds())
+
+	handlers := metrics.handlerStats()
+	handlerMetric("zk_lsp_requests_total", "Number of requests and notifications handled.", "counter", handlers,
+		func(h handlerStats) float64 { return float64(h.Count) })
+	handlerMetric("zk_lsp_request_errors_total", "Number of requests which failed.", "counter", handlers,
+		func(h handlerStats) float64 { return float64(h.Errors) })
+	handlerMetric("zk_lsp_request_duration_seconds_total", "Total time spent handling the requests.", "counter", handlers,
+		func(h handlerStats) float64 { return h.TotalMs / 1000 })
+	handlerMetric("zk_lsp_request_duration_seconds_max", "Duration of the slowest request.", "gauge", handlers,
+		func(h handlerStats) float64 { return h.MaxMs / 1000 })
+
+	out.Header("zk_lsp_completion_cache_hits_total", "Number of completions served from the cached notes.", "counter")
+	out.Sample("zk_lsp_completion_cache_hits_total", nil, float64(completion.Hits))
+	out.Header("zk_lsp_completion_cache_misses_total", "Number of completions which reloaded the notes from the index.", "counter")
+	out.Sample("zk_lsp_completion_cache_misses_total", nil, float64(completion.Misses))
+
+	if len(completion.Notebooks) > 0 {
+		out.Header("zk_lsp_notes", "Number of notes of a notebook cached for the completion, excluding the trash.", "gauge")
+		for _, notebook := range completion.Notebooks {
+			out.Sample("zk_lsp_notes", []prometheus.Label{{Name: "notebook", Value: notebook.Path}}, float64(notebook.Notes))
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,111 @@
+package lsp
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// positionEncoding is the unit used to count the characters of an LSP
+// position. It defaults to UTF-16 code units, but LSP 3.17 lets the client
+// and server negotiate another one.
+type positionEncoding string
+
+const (
+	positionEncodingUTF8  positionEncoding = "utf-8"
+	positionEncodingUTF16 positionEncoding = "utf-16"
+)
+
+// negotiatePositionEncoding picks the position encoding from the ones
+// supported by the client, given in the raw params of the initialize
+// request. UTF-8 is preferred as it matches the Go strings, otherwise the
+// mandatory UTF-16 is used.
+func negotiatePositionEncoding(initializeParams json.RawMessage) positionEncoding {
+	// The `general.positionEncodings` capability is not known by the
+	// LSP 3.16 protocol structures.
+	var params struct {
+		Capabilities struct {
+			General struct {
+				PositionEncodings []string `json:"positionEncodings"`
+			} `json:"general"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(initializeParams, &params); err != nil {
+		return positionEncodingUTF16
+	}
+
+	for _, encoding := range params.Capabilities.General.PositionEncodings {
+		if positionEncoding(encoding) == positionEncodingUTF8 {
+			return positionEncodingUTF8
+		}
+	}
+	return positionEncodingUTF16
+}
+
+// byteIndex returns the index of the byte in line at the given character
+// offset, counted in code units of the encoding. The index is clamped to
+// the length of the line.
+func (e positionEncoding) byteIndex(line string, character int) int {
+	if character <= 0 {
+		return 0
+	}
+	if e == positionEncodingUTF8 {
+		if character > len(line) {
+			return len(line)
+		}
+		return character
+	}
+
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += utf16Len(r)
+	}
+	return len(line)
+}
+
+// character returns the character offset of the given byte index in line,
+// counted in code units of the encoding.
+func (e positionEncoding) character(line string, index int) int {
+	if index > len(line) {
+		index = len(line)
+	}
+	if index <= 0 {
+		return 0
+	}
+	if e == positionEncodingUTF8 {
+		return index
+	}
+
+	units := 0
+	for _, r := range line[:index] {
+		units += utf16Len(r)
+	}
+	return units
+}
+
+// utf16Len returns the number of UTF-16 code units needed to encode r.
+// Characters outside the Basic Multilingual Plane, such as most emojis, are
+// encoded with a surrogate pair.
+func utf16Len(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
+
+// initializeResult is the result of the initialize request, with the
+// negotiated position encoding unknown by the LSP 3.16 structures and the
+// API version of the custom commands.
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+	ServerInfo   *serverInfo        `json:"serverInfo,omitempty"`
+}
+
+type serverCapabilities struct {
+	protocol.ServerCapabilities
+	PositionEncoding positionEncoding `json:"positionEncoding,omitempty"`
+}
@@ -0,0 +1,76 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestNegotiatePositionEncoding(t *testing.T) {
+	test := func(params string, expected positionEncoding) {
+		t.Helper()
+		assert.Equal(t, negotiatePositionEncoding(json.RawMessage(params)), expected)
+	}
+
+	test(`{"capabilities":{"general":{"positionEncodings":["utf-16","utf-8"]}}}`, positionEncodingUTF8)
+	test(`{"capabilities":{"general":{"positionEncodings":["utf-8"]}}}`, positionEncodingUTF8)
+	test(`{"capabilities":{"general":{"positionEncodings":["utf-16"]}}}`, positionEncodingUTF16)
+	test(`{"capabilities":{"general":{"positionEncodings":["utf-32"]}}}`, positionEncodingUTF16)
+	test(`{"capabilities":{"general":{}}}`, positionEncodingUTF16)
+	test(`{"capabilities":{}}`, positionEncodingUTF16)
+	test(`not json`, positionEncodingUTF16)
+}
+
+func TestPositionEncodingByteIndex(t *testing.T) {
+	test := func(encoding positionEncoding, line string, character int, expected int) {
+		t.Helper()
+		assert.Equal(t, encoding.byteIndex(line, character), expected)
+	}
+
+	// The emoji is encoded with 4 bytes, and a surrogate pair in UTF-16.
+	emoji := "a😀b"
+	test(positionEncodingUTF16, emoji, 0, 0)
+	test(positionEncodingUTF16, emoji, 1, 1)
+	test(positionEncodingUTF16, emoji, 3, 5)
+	test(positionEncodingUTF16, emoji, 4, 6)
+	test(positionEncodingUTF8, emoji, 5, 5)
+	// Inside the surrogate pair, the index moves after the emoji.
+	test(positionEncodingUTF16, emoji, 2, 5)
+
+	// CJK characters are encoded with 3 bytes, and a single UTF-16 unit.
+	cjk := "日本語"
+	test(positionEncodingUTF16, cjk, 1, 3)
+	test(positionEncodingUTF16, cjk, 2, 6)
+	test(positionEncodingUTF8, cjk, 6, 6)
+
+	// Out of the line.
+	test(positionEncodingUTF16, emoji, -1, 0)
+	test(positionEncodingUTF16, emoji, 10, 6)
+	test(positionEncodingUTF8, emoji, 10, 6)
+	test(positionEncodingUTF16, "", 1, 0)
+}
+
+func TestPositionEncodingCharacter(t *testing.T) {
+	test := func(encoding positionEncoding, line string, index int, expected int) {
+		t.Helper()
+		assert.Equal(t, encoding.character(line, index), expected)
+	}
+
+	emoji := "a😀b"
+	test(positionEncodingUTF16, emoji, 0, 0)
+	test(positionEncodingUTF16, emoji, 1, 1)
+	test(positionEncodingUTF16, emoji, 5, 3)
+	test(positionEncodingUTF16, emoji, 6, 4)
+	test(positionEncodingUTF8, emoji, 5, 5)
+
+	cjk := "日本語"
+	test(positionEncodingUTF16, cjk, 3, 1)
+	test(positionEncodingUTF16, cjk, 9, 3)
+	test(positionEncodingUTF8, cjk, 9, 9)
+
+	// Out of the line.
+	test(positionEncodingUTF16, emoji, -1, 0)
+	test(positionEncodingUTF16, emoji, 10, 4)
+	test(positionEncodingUTF8, emoji, 10, 6)
+}
@@ -0,0 +1,231 @@
+package lsp
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/core"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// methodPreview is a custom request returning the rendered HTML of a note,
+// for clients displaying a preview in a webview.
+const methodPreview = "zk/preview"
+
+type previewParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+}
+
+// preview is the rendered HTML of a note.
+type preview struct {
+	URI protocol.DocumentUri `json:"uri"`
+	// Sanitized HTML of the note, with the links to other notes rewritten to
+	// their URIs and the embedded notes expanded.
+	HTML string `json:"html"`
+}
+
+// preview renders the note at the given URI, which doesn't need to be opened
+// in the editor.
+func (s *Server) preview(uri protocol.DocumentUri) (*preview, error) {
+	doc, err := s.documentAt(uri)
+	if err != nil {
+		return nil, err
+	}
+	html, err := s.renderHTML(doc, map[string]bool{doc.Path: true})
+	if err != nil {
+		return nil, err
+	}
+	return &preview{URI: doc.URI, HTML: html}, nil
+}
+
+// documentAt returns the opened document at the given URI, or reads it from
+// the notebook.
+func (s *Server) documentAt(uri protocol.DocumentUri) (*document, error) {
+	if doc, ok := s.documents.Get(uri); ok {
+		return doc, nil
+	}
+
+	path, err := uriToPath(uri)
+	if err != nil {
+		return nil, err
+	}
+	path = s.fs.Canonical(path)
+	notebook, err := s.notebooks.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := notebook.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.documents.New(pathToURI(path), path, string(content)), nil
+}
+
+// renderHTML renders the given document, resolving its links with the same
+// rules as the other LSP features. The embedded notes are expanded unless
+// they are in embedding, to prevent cycles.
+func (s *Server) renderHTML(doc *document, embedding map[string]bool) (string, error) {
+	notebook, err := s.notebookOf(doc)
+	if err != nil {
+		return "", err
+	}
+
+	return notebook.RenderHTML(doc.Content, func(link core.RenderedLink) (*core.ResolvedLink, error) {
+		href := strings.SplitN(link.Href, "#", 2)[0]
+		// The anchors in the same note and the external links are kept.
+		if href == "" || strutil.IsURL(link.Href) {
+			return nil, nil
+		}
+		anchor := strings.TrimPrefix(link.Href, href)
+
+		if path := s.assetPath(href, doc, notebook); path != "" {
+			return &core.ResolvedLink{URL: pathToURI(path) + anchor}, nil
+		}
+
+		docLink := documentLink{Href: link.Href, IsWikiLink: link.IsWikiLink, IsImage: link.IsEmbed}
+		target, err := s.noteForLink(docLink, doc, notebook)
+		if err != nil || target == nil {
+			return nil, err
+		}
+		resolved := &core.ResolvedLink{URL: target.URI + anchor}
+
+		path, err := uriToPath(target.URI)
+		if err != nil {
+			return nil, err
+		}
+		if link.IsEmbed && !embedding[path] && len(embedding) < core.MaxEmbedDepth {
+			targetDoc, err := s.documentAt(target.URI)
+			if err != nil {
+				return nil, err
+			}
+			embedding[path] = true
+			resolved.HTML, err = s.renderHTML(targetDoc, embedding)
+			delete(embedding, path)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return resolved, nil
+	})
+}
+
+// expandEmbeds returns the content of the given document with the embedded
+// notes, e.g. ![[note]], replaced by their quoted content, to display them in
+// a Markdown hover. The embedded notes are expanded recursively unless they
+// are in embedding, to prevent cycles.
+func (s *Server) expandEmbeds(doc *document, embedding map[string]bool) (string, error) {
+	notebook, err := s.notebookOf(doc)
+	if err != nil {
+		return "", err
+	}
+	links, err := doc.DocumentLinks()
+	if err != nil {
+		return "", err
+	}
+
+	// The links are not sorted by kind of link in a line.
+	sort.SliceStable(links, func(i, j int) bool {
+		return doc.offsetAt(links[i].Range.Start) < doc.offsetAt(links[j].Range.Start)
+	})
+
+	var content strings.Builder
+	last := 0
+	for _, link := range links {
+		start := doc.offsetAt(link.Range.Start)
+		end := doc.offsetAt(link.Range.End)
+		// The ! of ![](note.md) is part of the link range.
+		if start > 0 && doc.Content[start] != '!' {
+			start--
+		}
+		if start < last || doc.Content[start] != '!' {
+			continue
+		}
+		href := strings.SplitN(link.Href, "#", 2)[0]
+		if href == "" || strutil.IsURL(link.Href) || s.assetPath(href, doc, notebook) != "" {
+			continue
+		}
+
+		target, err := s.noteForLink(link, doc, notebook)
+		if err != nil {
+			return "", err
+		}
+		if target == nil {
+			continue
+		}
+		path, err := uriToPath(target.URI)
+		if err != nil {
+			return "", err
+		}
+		if embedding[path] || len(embedding) >= core.MaxEmbedDepth {
+			continue
+		}
+
+		targetDoc, err := s.documentAt(target.URI)
+		if err != nil {
+			return "", err
+		}
+		embedding[path] = true
+		embedded, err := s.expandEmbeds(targetDoc, embedding)
+		delete(embedding, path)
+		if err != nil {
+			return "", err
+		}
+
+		content.WriteString(doc.Content[last:start])
+		content.WriteString(quoteEmbed(doc.Content, start, end, embedded))
+		last = end
+	}
+	content.WriteString(doc.Content[last:])
+	return content.String(), nil
+}
+
+// quoteEmbed returns the blockquote replacing the embed found between the
+// byte offsets start and end of content, quoting the embedded content.
+func quoteEmbed(content string, start int, end int, embedded string) string {
+	var quote strings.Builder
+	// A blockquote must start and end its own lines.
+	lineStart := strings.LastIndex(content[:start], "\n") + 1
+	if strings.TrimSpace(content[lineStart:start]) != "" {
+		quote.WriteString("\n")
+	}
+	quote.WriteString(quoteMarkdown(embedded))
+	lineEnd := strings.Index(content[end:], "\n")
+	if lineEnd == -1 {
+		lineEnd = len(content) - end
+	}
+	if strings.TrimSpace(content[end:end+lineEnd]) != "" {
+		quote.WriteString("\n\n")
+	}
+	return quote.String()
+}
+
+// quoteMarkdown returns the content of a note without its frontmatter, as a
+// Markdown blockquote.
+func quoteMarkdown(content string) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	lines = strings.Split(strings.TrimSpace(strings.Join(lines[frontmatterEnd(lines):], "\n")), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// assetPath returns the absolute path of the asset targeted by href, relative
+// to the document or to the notebook root, or an empty string if no such
+// file exists.
+func (s *Server) assetPath(href string, doc *document, notebook *core.Notebook) string {
+	for _, dir := range []string{filepath.Dir(doc.Path), notebook.Path} {
+		path := filepath.Join(dir, href)
+		if filepath.Ext(path) == "."+notebook.Config.Note.Extension {
+			continue
+		}
+		exists, err := s.fs.FileExists(path)
+		s.logger.Err(err)
+		if exists {
+			return path
+		}
+	}
+	return ""
+}
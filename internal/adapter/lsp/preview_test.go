@@ -0,0 +1,43 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestQuoteMarkdown(t *testing.T) {
+	test := func(content string, expected string) {
+		t.Helper()
+		assert.Equal(t, quoteMarkdown(content), expected)
+	}
+
+	test("# Title\n\nBody\n", "> # Title\n>\n> Body")
+	// The frontmatter is stripped.
+	test("---\ntitle: Title\ntags: [a]\n---\n\n# Title\nBody", "> # Title\n> Body")
+	test("---\ntitle: Title\n...\nBody", "> Body")
+	// An unterminated frontmatter is kept.
+	test("---\nBody", "> ---\n> Body")
+	// CRLF line endings.
+	test("---\r\ntitle: Title\r\n---\r\n# Title\r\n\r\nBody\r\n", "> # Title\n>\n> Body")
+	// Nested quotes.
+	test("> Quote\n>\n> > Nested", "> > Quote\n> >\n> > > Nested")
+}
+
+func TestQuoteEmbed(t *testing.T) {
+	test := func(content string, embed string, expected string) {
+		t.Helper()
+		start := strings.Index(content, embed)
+		end := start + len(embed)
+		assert.Equal(t, content[:start]+quoteEmbed(content, start, end, "# B\nBody")+content[end:], expected)
+	}
+
+	// On its own line.
+	test("A\n![[b]]\nC", "![[b]]", "A\n> # B\n> Body\nC")
+	test("![[b]]", "![[b]]", "> # B\n> Body")
+	// In the middle of a line, the blockquote is moved to its own lines.
+	test("See ![[b]] here.\nC", "![[b]]", "See \n> # B\n> Body\n\n here.\nC")
+	test("See ![note](b.md)", "![note](b.md)", "See \n> # B\n> Body")
+	test("![[b]] first", "![[b]]", "> # B\n> Body\n\n first")
+}
@@ -0,0 +1,188 @@
+package lsp
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// indexNotebook indexes the content of the notebook, reporting the progress
+// of long runs to the client with work done progress notifications, when it
+// supports them.
+//
+// token is the work done token provided by the client with the request, if
+// any. Otherwise, a token created in advance is used.
+func (s *Server) indexNotebook(notebook *core.Notebook, origin core.AuditOrigin, force bool, context *glsp.Context, token *protocol.ProgressToken) (core.NoteIndexingStats, error) {
+	if context == nil {
+		return notebook.Index(origin, force)
+	}
+	// Prepares a token for the next run.
+	defer s.progressTokens.prepare(context)
+
+	if token != nil && token.Value == nil {
+		// glsp fails to decode some client tokens.
+		token = nil
+	}
+	prepared := token == nil
+	if prepared {
+		token = s.progressTokens.take()
+	}
+	if token == nil {
+		return notebook.Index(origin, force)
+	}
+
+	progress := indexProgress{
+		context: context,
+		token:   *token,
+		start:   time.Now(),
+	}
+	stats, err := notebook.IndexWithProgress(origin, force, progress.report)
+	progress.end(stats, err)
+	if prepared && !progress.begun {
+		// The token can be reused, as no progress was reported with it.
+		s.progressTokens.release(*token)
+	}
+	return stats, err
+}
+
+// progressTokens keeps a work done token created in advance by the client.
+//
+// The handlers can't await the response of the client, so a token can't be
+// created on demand when a long operation starts.
+type progressTokens struct {
+	enabled bool
+	mutex   sync.Mutex
+	ready   *protocol.ProgressToken
+	pending bool
+}
+
+var progressTokenCounter uint32
+
+// prepare requests the client to create a new token, if there is none ready
+// yet.
+func (t *progressTokens) prepare(context *glsp.Context) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.enabled || context == nil || t.ready != nil || t.pending {
+		return
+	}
+	t.pending = true
+
+	id := atomic.AddUint32(&progressTokenCounter, 1)
+	token := protocol.ProgressToken{Value: fmt.Sprintf("zk-progress-%d", id)}
+	go func() {
+		context.Call(protocol.ServerWindowWorkDoneProgressCreate, protocol.WorkDoneProgressCreateParams{
+			Token: token,
+		}, nil)
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		t.pending = false
+		if t.ready == nil {
+			t.ready = &token
+		}
+	}()
+}
+
+// take returns the token ready to be used, if any.
+func (t *progressTokens) take() *protocol.ProgressToken {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	token := t.ready
+	t.ready = nil
+	return token
+}
+
+// release gives back a token which was not used to report any progress.
+func (t *progressTokens) release(token protocol.ProgressToken) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.ready == nil {
+		t.ready = &token
+	}
+}
+
+// indexProgress reports the progress of an indexing run to the client.
+//
+// The progress is only displayed when the run takes more than a fraction of
+// a second, to not flash the progress for each saved note.
+type indexProgress struct {
+	context    *glsp.Context
+	token      protocol.ProgressToken
+	start      time.Time
+	lastReport time.Time
+	begun      bool
+}
+
+func (p *indexProgress) report(progress core.IndexProgress) {
+	now := time.Now()
+	if now.Sub(p.start) < 500*time.Millisecond || now.Sub(p.lastReport) < 200*time.Millisecond {
+		return
+	}
+	p.lastReport = now
+
+	message := indexProgressMessage(progress)
+	var percentage *protocol.UInteger
+	if progress.Phase == core.IndexPhaseIndexing && progress.Total > 0 {
+		value := protocol.UInteger(progress.Done * 100 / progress.Total)
+		percentage = &value
+	}
+
+	if !p.begun {
+		p.begun = true
+		p.notify(protocol.WorkDoneProgressBegin{
+			Kind:       "begin",
+			Title:      "Indexing the notebook",
+			Message:    &message,
+			Percentage: percentage,
+		})
+		return
+	}
+
+	p.notify(protocol.WorkDoneProgressReport{
+		Kind:       "report",
+		Message:    &message,
+		Percentage: percentage,
+	})
+}
+
+func (p *indexProgress) end(stats core.NoteIndexingStats, err error) {
+	if !p.begun {
+		return
+	}
+	message := fmt.Sprintf("%d added, %d modified, %d removed", stats.AddedCount, stats.ModifiedCount, stats.RemovedCount)
+	if err != nil {
+		message = err.Error()
+	}
+	p.notify(protocol.WorkDoneProgressEnd{
+		Kind:    "end",
+		Message: &message,
+	})
+}
+
+func (p *indexProgress) notify(value interface{}) {
+	p.context.Notify(protocol.MethodProgress, protocol.ProgressParams{
+		Token: p.token,
+		Value: value,
+	})
+}
+
+// indexProgressMessage describes the current phase of an indexing run.
+func indexProgressMessage(progress core.IndexProgress) string {
+	switch progress.Phase {
+	case core.IndexPhaseScanning:
+		return fmt.Sprintf("%d notes scanned", progress.Scanned)
+	case core.IndexPhaseIndexing:
+		message := fmt.Sprintf("%d/%d: %d parsed, %d indexed", progress.Done, progress.Total, progress.Parsed, progress.Indexed)
+		if progress.Removed > 0 {
+			message += fmt.Sprintf(", %d removed", progress.Removed)
+		}
+		return message
+	default:
+		return "Extracting the text of " + progress.Path
+	}
+}
@@ -0,0 +1,27 @@
+package lsp
+
+import (
+	"fmt"
+
+	"github.com/mickael-menu/zk/internal/core"
+)
+
+// isReadOnly returns whether the server is forbidden to modify the given
+// notebook, either with the --read-only flag or the readonly setting of the
+// notebook.
+func (s *Server) isReadOnly(notebook *core.Notebook) bool {
+	return s.readOnly || (notebook != nil && notebook.Config.LSP.ReadOnly)
+}
+
+// checkWritable returns an error when the given command would modify a
+// read-only notebook.
+func (s *Server) checkWritable(notebook *core.Notebook, command string) error {
+	switch {
+	case s.readOnly:
+		return fmt.Errorf("%s is disabled, the zk LSP server is in read-only mode", command)
+	case notebook != nil && notebook.Config.LSP.ReadOnly:
+		return fmt.Errorf("%s is disabled, the notebook at %s is read-only", command, notebook.Path)
+	default:
+		return nil
+	}
+}
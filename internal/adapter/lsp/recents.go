@@ -0,0 +1,66 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+const cmdRecents = "zk.recents"
+
+type cmdRecentsOpts struct {
+	Limit int `json:"limit,omitempty"`
+}
+
+// recentNote is a note returned by the zk.recents command.
+type recentNote struct {
+	core.NoteHistoryEntry
+	AbsPath string `json:"absPath"`
+}
+
+// executeCommandRecents lists the notes recently created or opened, the most
+// recent first, e.g. to show them in an editor picker.
+func (s *Server) executeCommandRecents(args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("zk.recents expects a notebook path as first argument")
+	}
+	wd, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.recents expects a notebook path as first argument, got: %v", args[0])
+	}
+	var opts cmdRecentsOpts
+	if len(args) > 1 {
+		arg, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("zk.recents expects a dictionary of options as second argument, got: %v", args[1])
+		}
+		err := unmarshalJSON(arg, &opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse zk.recents args, got: %v", arg)
+		}
+	}
+	if opts.Limit == 0 {
+		opts.Limit = 20
+	}
+
+	notebook, err := s.notebooks.Open(wd)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := notebook.FindHistory(opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	recents := []recentNote{}
+	for _, entry := range entries {
+		recents = append(recents, recentNote{
+			NoteHistoryEntry: entry,
+			AbsPath:          filepath.Join(notebook.Path, entry.Path),
+		})
+	}
+	return recents, nil
+}
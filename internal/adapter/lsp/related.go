@@ -0,0 +1,116 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+const cmdRelated = "zk.related"
+
+type cmdRelatedOpts struct {
+	// Path of the note, absolute or relative to the notebook root.
+	Path  string `json:"path"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// relatedNote is a note returned by the zk.related command.
+type relatedNote struct {
+	core.RelatedNote
+	AbsPath string `json:"absPath"`
+}
+
+// executeCommandRelated lists the notes sharing rare terms or tags with the
+// given note, which are not linked to or from it yet.
+func (s *Server) executeCommandRelated(args []interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("zk.related expects a notebook path and a dictionary of options as arguments")
+	}
+	wd, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.related expects a notebook path as first argument, got: %v", args[0])
+	}
+	arg, ok := args[1].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("zk.related expects a dictionary of options as second argument, got: %v", args[1])
+	}
+	var opts cmdRelatedOpts
+	err := unmarshalJSON(arg, &opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse zk.related args, got: %v", arg)
+	}
+	if opts.Path == "" {
+		return nil, errors.New("zk.related expects a `path` option with the path of the note")
+	}
+
+	notebook, err := s.notebooks.Open(wd)
+	if err != nil {
+		return nil, err
+	}
+	path := opts.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(notebook.Path, path)
+	}
+
+	notes, err := notebook.FindRelatedNotes(path, core.RelatedNotesOpts{Limit: opts.Limit})
+	if err != nil {
+		return nil, err
+	}
+
+	related := []relatedNote{}
+	for _, note := range notes {
+		related = append(related, relatedNote{
+			RelatedNote: note,
+			AbsPath:     filepath.Join(notebook.Path, note.Path),
+		})
+	}
+	return related, nil
+}
+
+// suggestLinksCodeLens returns a code lens above the title of the document
+// when related notes are not linked yet, which lists them when clicked.
+func (s *Server) suggestLinksCodeLens(doc *document) ([]protocol.CodeLens, error) {
+	notebook, err := s.notebookOf(doc)
+	if err != nil {
+		return nil, err
+	}
+	if !s.configOf(notebook, doc).LSP.CodeLens.SuggestLinks {
+		return []protocol.CodeLens{}, nil
+	}
+
+	notes, err := notebook.FindRelatedNotes(doc.Path, core.RelatedNotesOpts{})
+	if err != nil {
+		var notFound core.ErrNoteNotFound
+		if errors.As(err, &notFound) {
+			// The document is not indexed yet.
+			return []protocol.CodeLens{}, nil
+		}
+		return nil, err
+	}
+	if len(notes) == 0 {
+		return []protocol.CodeLens{}, nil
+	}
+
+	lineIndex := titleLine(doc.GetLines())
+	line, _ := doc.GetLine(lineIndex)
+	rng := doc.rangeAt(lineIndex, 0, len(line))
+
+	locations := []protocol.Location{}
+	for _, note := range notes {
+		locations = append(locations, protocol.Location{
+			URI: pathToURI(filepath.Join(notebook.Path, note.Path)),
+		})
+	}
+
+	return []protocol.CodeLens{{
+		Range: rng,
+		Command: &protocol.Command{
+			Title:     "Suggest links",
+			Command:   clientShowReferences,
+			Arguments: []interface{}{doc.URI, rng.Start, locations},
+		},
+	}}, nil
+}
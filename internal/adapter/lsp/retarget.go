@@ -0,0 +1,162 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/core"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// retargetedLink is an existing internal link of a document, whose target is
+// replaced by completing a note while the cursor is inside it.
+type retargetedLink struct {
+	// Text typed before the cursor in the last segment of the link path, used
+	// to filter the notes.
+	Query string
+	// Text between the start of the link and the query, which is replaced as
+	// well.
+	Prefix string
+	// End of the link, which is replaced as well.
+	End protocol.Position
+	// Custom label of the link, kept by the replacement links.
+	Label      string
+	IsWikiLink bool
+}
+
+var markdownLinkLabelUnescaper = strings.NewReplacer(`\\`, `\`, `\]`, `]`)
+
+// RetargetedLinkAt returns the internal link surrounding the given position,
+// when its target can be replaced with completion.
+//
+// External links, images and links to other notebooks are ignored, as well as
+// the ones whose path contains brackets, which are usually made of a link
+// being typed followed by an existing one.
+func (d *document) RetargetedLinkAt(pos protocol.Position) (retargetedLink, bool) {
+	line, ok := d.GetLine(int(pos.Line))
+	if !ok {
+		return retargetedLink{}, false
+	}
+	charIdx := d.charIndex(line, pos)
+
+	// find returns the link spanning the byte indexes of match, given the
+	// indexes of its href and label, which is nil when missing.
+	find := func(match []int, hrefSpan []int, labelSpan []int, isWikiLink bool) (retargetedLink, bool) {
+		start, end := match[0], match[1]
+		if charIdx <= start || charIdx >= end {
+			return retargetedLink{}, false
+		}
+		hrefStart, hrefEnd := hrefSpan[0], hrefSpan[1]
+		href := line[hrefStart:hrefEnd]
+		// Skips the URLs and the links to other notebooks, e.g. work:note.
+		if href == "" || strings.ContainsAny(href, "[]:") || strings.HasPrefix(href, "#") {
+			return retargetedLink{}, false
+		}
+
+		link := retargetedLink{
+			End:        d.rangeAt(int(pos.Line), end, end).End,
+			IsWikiLink: isWikiLink,
+		}
+		if labelSpan != nil && labelSpan[0] >= 0 {
+			link.Label = line[labelSpan[0]:labelSpan[1]]
+			if !isWikiLink {
+				link.Label = markdownLinkLabelUnescaper.Replace(link.Label)
+			}
+		}
+
+		// The query is the last segment of the path typed before the cursor,
+		// as the editors filter the items with it. Outside of the path, all
+		// the notes are offered.
+		queryStart := charIdx
+		if charIdx >= hrefStart && charIdx <= hrefEnd {
+			queryStart = hrefStart + strings.LastIndex(line[hrefStart:charIdx], "/") + 1
+		}
+		link.Query = line[queryStart:charIdx]
+		link.Prefix = line[start:queryStart]
+		return link, true
+	}
+
+	aliasFirst := d.isWikiLinkAliasFirst()
+	for _, match := range wikiLinkRegex.FindAllStringSubmatchIndex(line, -1) {
+		target, alias := wikiLinkParts(line, match, aliasFirst)
+		if link, ok := find(match, target, alias, true); ok {
+			return link, true
+		}
+	}
+	for _, match := range markdownLinkRegex.FindAllStringSubmatchIndex(line, -1) {
+		if match[0] > 0 && line[match[0]-1] == '!' {
+			continue
+		}
+		if link, ok := find(match, match[4:6], match[2:4], false); ok {
+			return link, true
+		}
+	}
+	return retargetedLink{}, false
+}
+
+// buildRetargetLinkCompletionList completes the notes which can replace the
+// target of an existing link, when the completion is requested inside it.
+// The items rewrite the whole link, keeping its custom label.
+func (s *Server) buildRetargetLinkCompletionList(doc *document, notebook *core.Notebook, params *protocol.CompletionParams, link retargetedLink) (*protocol.CompletionList, error) {
+	config := s.configOf(notebook, doc)
+	var linkFormatter core.LinkFormatter
+	var err error
+	if link.IsWikiLink && link.Label != "" && config.Format.Markdown.LinkFormat == "wiki" {
+		// Keeps the alias of the wiki-link, in the configured order.
+		linkFormatter, err = core.NewTitledWikiLinkFormatter(config.Format.Markdown)
+	} else if link.IsWikiLink {
+		linkFormatter, err = notebook.NewLinkFormatterWithConfig(config.Format.Markdown)
+	} else {
+		// Paths must always be encoded in a Markdown link destination, to
+		// support spaces.
+		markdownConfig := config.Format.Markdown
+		markdownConfig.LinkEncodePath = true
+		linkFormatter, err = core.NewMarkdownLinkFormatter(markdownConfig, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if link.Label != "" {
+		formatLink := linkFormatter
+		linkFormatter = func(context core.LinkFormatterContext) (string, error) {
+			context.Title = link.Label
+			return formatLink(context)
+		}
+	}
+
+	templates, err := newCompletionTemplates(s.templateLoader, config.LSP.Completion.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, isIncomplete, err := s.completion.Find(notebook, link.Query, config.LSP.Completion.MaxItems, config.LSP.Completion.IncludeArchived)
+	if err != nil {
+		return nil, err
+	}
+
+	trigger := s.newCompletionTrigger(config, link.Prefix)
+	var items []protocol.CompletionItem
+	for _, note := range notes {
+		item, err := s.newCompletionItem(notebook, note, doc, params.Position, linkFormatter, templates, len(link.Query), trigger)
+		if err != nil {
+			s.logger.Err(err)
+			continue
+		}
+		noteItems := []protocol.CompletionItem{item}
+		// The aliases would be replaced by the custom label anyway.
+		if link.Label == "" {
+			noteItems = append(noteItems, s.newAliasCompletionItems(notebook, note, doc, params.Position, linkFormatter, len(link.Query), trigger)...)
+		}
+		for _, item := range noteItems {
+			if edit, ok := item.TextEdit.(protocol.TextEdit); ok {
+				edit.Range.End = link.End
+				item.TextEdit = edit
+			}
+			items = append(items, item)
+		}
+	}
+
+	return &protocol.CompletionList{
+		IsIncomplete: isIncomplete,
+		Items:        items,
+	}, nil
+}
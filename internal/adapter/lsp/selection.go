@@ -0,0 +1,175 @@
+package lsp
+
+import (
+	"strings"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// SelectionRangeAt returns the ranges to select successively when expanding
+// the selection from pos: the text of the link under pos, the whole link,
+// the sentence, the paragraph, the enclosing heading sections and finally
+// the whole document.
+func (d *document) SelectionRangeAt(pos protocol.Position) protocol.SelectionRange {
+	// Byte offsets of the ranges, from the innermost.
+	type span struct{ start, end int }
+	spans := []span{}
+	appendSpan := func(start, end int) {
+		if len(spans) > 0 {
+			last := spans[len(spans)-1]
+			// Each range must contain the previous one.
+			if start > last.start || end < last.end || (start == last.start && end == last.end) {
+				return
+			}
+		}
+		spans = append(spans, span{start, end})
+	}
+
+	lines := d.GetLines()
+	offset := d.offsetAt(pos)
+	lineIndex := int(pos.Line)
+	if lineIndex >= len(lines) {
+		return protocol.SelectionRange{Range: d.rangeOf(0, len(d.Content))}
+	}
+	lineStart := d.offsetAt(protocol.Position{Line: pos.Line})
+
+	// Link under the cursor
+	if text, link, ok := linkSpansAt(lines[lineIndex], offset-lineStart, d.isWikiLinkAliasFirst()); ok {
+		appendSpan(lineStart+text[0], lineStart+text[1])
+		appendSpan(lineStart+link[0], lineStart+link[1])
+	}
+
+	// Paragraph and sentence
+	if strings.TrimSpace(lines[lineIndex]) != "" {
+		// A heading is a paragraph of its own.
+		continues := func(a, b string) bool {
+			return strings.TrimSpace(b) != "" && !headingRegex.MatchString(a) && !headingRegex.MatchString(b)
+		}
+		first, last := lineIndex, lineIndex
+		for first > 0 && continues(lines[first], lines[first-1]) {
+			first--
+		}
+		for last < len(lines)-1 && continues(lines[last], lines[last+1]) {
+			last++
+		}
+		paraStart := d.offsetAt(protocol.Position{Line: protocol.UInteger(first)})
+		paraStart += len(lines[first]) - len(strings.TrimLeft(lines[first], " \t"))
+		paraEnd := d.offsetAt(protocol.Position{Line: protocol.UInteger(last)}) + len(strings.TrimRight(lines[last], " \t"))
+
+		start, end := sentenceSpan(d.Content[paraStart:paraEnd], offset-paraStart)
+		appendSpan(paraStart+start, paraStart+end)
+		appendSpan(paraStart, paraEnd)
+	}
+
+	// Heading sections
+	for _, section := range headingSectionsAt(lines, lineIndex) {
+		start := d.offsetAt(protocol.Position{Line: protocol.UInteger(section[0])})
+		end := d.offsetAt(protocol.Position{Line: protocol.UInteger(section[1])}) + len(lines[section[1]])
+		appendSpan(start, end)
+	}
+
+	appendSpan(0, len(d.Content))
+
+	var selection *protocol.SelectionRange
+	for i := len(spans) - 1; i >= 0; i-- {
+		selection = &protocol.SelectionRange{
+			Range:  d.rangeOf(spans[i].start, spans[i].end),
+			Parent: selection,
+		}
+	}
+	return *selection
+}
+
+// rangeOf returns the range between the given byte offsets of the content.
+func (d *document) rangeOf(start int, end int) protocol.Range {
+	return protocol.Range{
+		Start: d.positionAt(start),
+		End:   d.positionAt(end),
+	}
+}
+
+// linkSpansAt returns the byte indexes of the text of the link found in line
+// at the byte index i, and of the whole link.
+func linkSpansAt(line string, i int, wikiLinkAliasFirst bool) (text []int, link []int, ok bool) {
+	for _, match := range wikiLinkRegex.FindAllStringSubmatchIndex(line, -1) {
+		if i < match[0] || i > match[1] {
+			continue
+		}
+		target, alias := wikiLinkParts(line, match, wikiLinkAliasFirst)
+		if alias != nil {
+			return alias, match[0:2], true
+		}
+		return target, match[0:2], true
+	}
+	for _, match := range markdownLinkRegex.FindAllStringSubmatchIndex(line, -1) {
+		if i < match[0] || i > match[1] {
+			continue
+		}
+		return match[2:4], match[0:2], true
+	}
+	return nil, nil, false
+}
+
+// sentenceSpan returns the byte indexes of the sentence of text found at the
+// byte index i.
+func sentenceSpan(text string, i int) (start int, end int) {
+	if i < 0 {
+		i = 0
+	} else if i > len(text) {
+		i = len(text)
+	}
+	isEnd := func(j int) bool {
+		return strings.ContainsRune(".!?", rune(text[j])) && (j+1 == len(text) || strings.ContainsRune(" \t\n", rune(text[j+1])))
+	}
+	// A cursor right after the punctuation is still in the sentence.
+	if i > 0 && isEnd(i-1) {
+		i--
+	}
+
+	start = 0
+	for j := i - 1; j >= 0; j-- {
+		if isEnd(j) {
+			start = j + 1
+			break
+		}
+	}
+	for start < len(text) && strings.ContainsRune(" \t\n", rune(text[start])) {
+		start++
+	}
+
+	end = len(text)
+	for j := i; j < len(text); j++ {
+		if isEnd(j) {
+			end = j + 1
+			break
+		}
+	}
+	if end < start {
+		end = start
+	}
+	return start, end
+}
+
+// headingSectionsAt returns the first and last line indexes of the heading
+// sections enclosing the line at lineIndex, from the innermost.
+func headingSectionsAt(lines []string, lineIndex int) [][2]int {
+	sections := [][2]int{}
+	level := 7
+	for i := lineIndex; i >= 0 && level > 1; i-- {
+		match := headingRegex.FindStringSubmatch(lines[i])
+		if match == nil || len(match[1]) >= level {
+			continue
+		}
+		level = len(match[1])
+
+		end := len(lines) - 1
+		for j := i + 1; j < len(lines); j++ {
+			if next := headingRegex.FindStringSubmatch(lines[j]); next != nil && len(next[1]) <= level {
+				end = j - 1
+				break
+			}
+		}
+		sections = append(sections, [2]int{i, lastNonBlankLine(lines, i, end+1)})
+	}
+	return sections
+}
@@ -0,0 +1,113 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestDocumentSelectionRangeAt(t *testing.T) {
+	content := `# Title
+
+Intro sentence. See [[note|the note]] now! Last one.
+Continued line.
+
+## Section
+
+Text with [a link](a.md).
+`
+	doc := newTestDocument(content, positionEncodingUTF16)
+
+	test := func(line int, character int, expected []string) {
+		t.Helper()
+		// Texts of the successive selection ranges, from the innermost.
+		texts := []string{}
+		selection := doc.SelectionRangeAt(pos(line, character))
+		for s := &selection; s != nil; s = s.Parent {
+			texts = append(texts, doc.ContentAtRange(s.Range))
+		}
+		assert.Equal(t, texts, expected)
+	}
+
+	paragraph := "Intro sentence. See [[note|the note]] now! Last one.\nContinued line."
+	section := "## Section\n\nText with [a link](a.md)."
+	title := "# Title\n\n" + paragraph + "\n\n" + section
+
+	test(2, 30, []string{"the note", "[[note|the note]]", "See [[note|the note]] now!", paragraph, title, content})
+	test(2, 3, []string{"Intro sentence.", paragraph, title, content})
+	test(3, 4, []string{"Continued line.", paragraph, title, content})
+	// Right after the end of a sentence.
+	test(2, 15, []string{"Intro sentence.", paragraph, title, content})
+	// The sentence and paragraph are the same.
+	test(7, 12, []string{"a link", "[a link](a.md)", "Text with [a link](a.md).", section, title, content})
+	// A heading is a paragraph of its own.
+	test(5, 4, []string{"## Section", section, title, content})
+	// Blank lines and positions out of the document.
+	test(1, 0, []string{title, content})
+	test(20, 0, []string{content})
+}
+
+func TestDocumentSelectionRangeAtWithAliasFirst(t *testing.T) {
+	doc := newTestDocument("See [[the note|note]].", positionEncodingUTF16)
+	doc.wikiLinkAliasFirst = func(path string) bool { return true }
+
+	selection := doc.SelectionRangeAt(pos(0, 7))
+	assert.Equal(t, doc.ContentAtRange(selection.Range), "the note")
+	assert.Equal(t, doc.ContentAtRange(selection.Parent.Range), "[[the note|note]]")
+}
+
+func TestSentenceSpan(t *testing.T) {
+	test := func(text string, i int, expected string) {
+		t.Helper()
+		start, end := sentenceSpan(text, i)
+		assert.Equal(t, text[start:end], expected)
+	}
+
+	text := "First one. Second one? Third!"
+	test(text, 0, "First one.")
+	test(text, 9, "First one.")
+	test(text, 10, "First one.")
+	test(text, 11, "Second one?")
+	test(text, 15, "Second one?")
+	test(text, len(text), "Third!")
+	test(text, -1, "First one.")
+	// A dot inside a word doesn't end the sentence.
+	test("See v1.2 now. Done", 5, "See v1.2 now.")
+	test("", 0, "")
+}
+
+func TestDocumentLinkAtExpandableWikiLink(t *testing.T) {
+	doc := newTestDocument("See [[4fz2]], [[4fz2|Beta]] and [Beta](4fz2.md).", positionEncodingUTF16)
+
+	test := func(character int, expectedHref string, expectedExpandable bool) {
+		t.Helper()
+		link, err := doc.DocumentLinkAt(pos(0, character))
+		assert.Nil(t, err)
+		assert.NotNil(t, link)
+		assert.Equal(t, link.Href, expectedHref)
+		assert.Equal(t, link.IsWikiLink && !link.HasTitle, expectedExpandable)
+	}
+
+	test(7, "4fz2", true)
+	test(18, "4fz2", false)
+	test(36, "4fz2.md", false)
+
+	link, err := doc.DocumentLinkAt(pos(0, 1))
+	assert.Nil(t, err)
+	assert.Nil(t, link)
+}
+
+func TestWikiLinkTitle(t *testing.T) {
+	note := core.MinimalNote{
+		Title:    "Beta note",
+		Metadata: map[string]interface{}{"aliases": []interface{}{"Second", "B"}},
+	}
+	assert.Equal(t, wikiLinkTitle(documentLink{Href: "4fz2"}, note), "Beta note")
+	// The alias targeted by the link is kept, with its case.
+	assert.Equal(t, wikiLinkTitle(documentLink{Href: " second "}, note), "Second")
+
+	note.Title = ""
+	assert.Equal(t, wikiLinkTitle(documentLink{Href: "4fz2"}, note), "Second")
+	assert.Equal(t, wikiLinkTitle(documentLink{Href: "4fz2"}, core.MinimalNote{}), "")
+}
@@ -3,10 +3,12 @@ package lsp
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"net/url"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/mickael-menu/zk/internal/core"
 	"github.com/mickael-menu/zk/internal/util"
@@ -28,7 +30,25 @@ type Server struct {
 	documents      *documentStore
 	templateLoader core.TemplateLoader
 	fs             core.FileStorage
+	urlMetadata    *urlMetadataJob
+	completion     *noteCompletionCache
+	diagnostics    *diagnosticsPublisher
+	metrics        *serverMetrics
 	logger         util.Logger
+	// Version of zk, reported to the client.
+	version string
+	// Prevents the server from modifying the notebooks.
+	readOnly bool
+
+	// Trace setting requested by the client.
+	trace protocol.TraceValue
+	// Formats of the rich content supported by the client.
+	markup markupKinds
+	// Whether the client deletes the trigger of a completed link with an
+	// additional text edit, unless configured in the notebook.
+	additionalTextEdits bool
+	// Work done tokens used to report the progress of long operations.
+	progressTokens progressTokens
 }
 
 // ServerOpts holds the options to create a new Server.
@@ -40,17 +60,40 @@ type ServerOpts struct {
 	Notebooks      *core.NotebookStore
 	TemplateLoader core.TemplateLoader
 	FS             core.FileStorage
+	// URLMetadataFetcher is used to fetch in the background the metadata of
+	// the external links, when enabled in the notebook config.
+	URLMetadataFetcher core.URLMetadataFetcher
+	// Address where the metrics of the server are served in the Prometheus
+	// text format, at /metrics. Disabled when empty.
+	MetricsAddress string
+	// ReadOnly prevents the server from modifying the notebooks.
+	ReadOnly bool
 }
 
 // NewServer creates a new Server instance.
 func NewServer(opts ServerOpts) *Server {
-	fs := opts.FS
-	debug := !opts.LogFile.IsNull()
-	if debug {
+	configureLogging(opts)
+	server := newServer(opts, newURLMetadataJob(opts.URLMetadataFetcher, opts.Logger), newNoteCompletionCache(), newServerMetrics())
+	if opts.MetricsAddress != "" {
+		go serveMetrics(opts.MetricsAddress, server.metrics, server.completion, server.logger)
+	}
+	return server
+}
+
+func configureLogging(opts ServerOpts) {
+	if !opts.LogFile.IsNull() {
 		logging.Configure(10, opts.LogFile.Value)
 	}
+}
+
+// newServer creates a new Server instance for a single client. The URL
+// metadata job, the completion cache and the metrics can be shared between
+// the servers of several clients.
+func newServer(opts ServerOpts, urlMetadata *urlMetadataJob, completion *noteCompletionCache, metrics *serverMetrics) *Server {
+	fs := opts.FS
+	debug := !opts.LogFile.IsNull()
 
-	handler := protocol.Handler{}
+	handler := handler{}
 	glspServer := glspserv.NewServer(&handler, opts.Name, debug)
 
 	// Redirect zk's logger to GLSP's to avoid breaking the JSON-RPC protocol
@@ -65,20 +108,46 @@ func NewServer(opts ServerOpts) *Server {
 		documents:      newDocumentStore(fs, opts.Logger),
 		templateLoader: opts.TemplateLoader,
 		fs:             fs,
+		urlMetadata:    urlMetadata,
+		completion:     completion,
+		metrics:        metrics,
 		logger:         opts.Logger,
-	}
+		version:        opts.Version,
+		readOnly:       opts.ReadOnly,
+		trace:          protocol.TraceValueOff,
+		markup: markupKinds{
+			hover:         protocol.MarkupKindMarkdown,
+			documentation: protocol.MarkupKindMarkdown,
+		},
+		additionalTextEdits: true,
+	}
+	server.diagnostics = newDiagnosticsPublisher(server.notebookOf, server.documentDiagnostics, opts.Logger)
+	server.documents.wikiLinkAliasFirst = func(path string) bool {
+		notebook, err := server.notebooks.Open(path)
+		return err == nil && notebook.Config.Format.Markdown.WikiLinkAliasFirst
+	}
+	handler.server = server
 
 	var clientCapabilities protocol.ClientCapabilities
+	// Notebook opened at the root of the workspace, if any.
+	var workspaceNotebook *core.Notebook
 
 	handler.Initialize = func(context *glsp.Context, params *protocol.InitializeParams) (interface{}, error) {
 		clientCapabilities = params.Capabilities
+		server.markup = negotiateMarkupKinds(params.Capabilities)
+		server.additionalTextEdits = negotiateAdditionalTextEdits(params.Capabilities)
+		if window := params.Capabilities.Window; window != nil {
+			server.progressTokens.enabled = isTrue(window.WorkDoneProgress)
+		}
 
 		// To see the logs with coc.nvim, run :CocCommand workspace.showOutput
 		// https://github.com/neoclide/coc.nvim/wiki/Debug-language-server#using-output-channel
 		if params.Trace != nil {
-			protocol.SetTraceValue(*params.Trace)
+			server.setTrace(*params.Trace)
 		}
 
+		server.documents.encoding = negotiatePositionEncoding(context.Params)
+
 		capabilities := handler.CreateServerCapabilities()
 		capabilities.HoverProvider = true
 		capabilities.DefinitionProvider = true
@@ -94,41 +163,79 @@ func NewServer(opts ServerOpts) *Server {
 			ResolveProvider: boolPtr(true),
 		}
 
-		triggerChars := []string{"(", "[", "#", ":"}
+		workspaceNotebook = server.workspaceNotebook(params)
+
+		triggerChars := []string{"(", "[", "#", ":", "/"}
+		for _, trigger := range completionTriggerChars(workspaceNotebook) {
+			if !strutil.InList(triggerChars, trigger) {
+				triggerChars = append(triggerChars, trigger)
+			}
+		}
 
 		capabilities.ExecuteCommandProvider = &protocol.ExecuteCommandOptions{
-			Commands: []string{
-				cmdIndex,
-				cmdNew,
-			},
+			Commands: customCommands(),
 		}
 		capabilities.CompletionProvider = &protocol.CompletionOptions{
 			TriggerCharacters: triggerChars,
 			ResolveProvider:   boolPtr(true),
 		}
 
+		capabilities.DocumentOnTypeFormattingProvider = &onTypeFormattingOptions
 		capabilities.ReferencesProvider = &protocol.ReferenceOptions{}
+		capabilities.CodeLensProvider = &protocol.CodeLensOptions{}
+
+		// Notebooks may use any file extension for their notes, so we
+		// filter the deleted notes ourselves.
+		fileKind := protocol.FileOperationPatternKindFile
+		capabilities.Workspace.FileOperations.WillDelete.Filters = []protocol.FileOperationFilter{{
+			Scheme: stringPtr("file"),
+			Pattern: protocol.FileOperationPattern{
+				Glob:    "**/*",
+				Matches: &fileKind,
+			},
+		}}
 
-		return protocol.InitializeResult{
-			Capabilities: capabilities,
-			ServerInfo: &protocol.InitializeResultServerInfo{
-				Name:    opts.Name,
-				Version: &opts.Version,
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				ServerCapabilities: capabilities,
+				PositionEncoding:   server.documents.encoding,
+			},
+			ServerInfo: &serverInfo{
+				Name:       opts.Name,
+				Version:    &opts.Version,
+				APIVersion: apiVersion,
 			},
 		}, nil
 	}
 
 	handler.Initialized = func(context *glsp.Context, params *protocol.InitializedParams) error {
+		server.progressTokens.prepare(context)
+
+		// The file watchers can only be registered dynamically.
+		workspace := clientCapabilities.Workspace
+		if workspaceNotebook != nil && workspace != nil && workspace.DidChangeWatchedFiles != nil && isTrue(workspace.DidChangeWatchedFiles.DynamicRegistration) {
+			go context.Call(protocol.ServerClientRegisterCapability, protocol.RegistrationParams{
+				Registrations: []protocol.Registration{{
+					ID:     "zk-watched-files",
+					Method: string(protocol.MethodWorkspaceDidChangeWatchedFiles),
+					RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{
+						Watchers: []protocol.FileSystemWatcher{{
+							GlobPattern: noteFilesGlob(workspaceNotebook.Config),
+						}},
+					},
+				}},
+			}, nil)
+		}
 		return nil
 	}
 
 	handler.Shutdown = func(context *glsp.Context) error {
-		protocol.SetTraceValue(protocol.TraceValueOff)
+		server.setTrace(protocol.TraceValueOff)
 		return nil
 	}
 
 	handler.SetTrace = func(context *glsp.Context, params *protocol.SetTraceParams) error {
-		protocol.SetTraceValue(params.Value)
+		server.setTrace(params.Value)
 		return nil
 	}
 
@@ -139,6 +246,12 @@ func NewServer(opts ServerOpts) *Server {
 		}
 		if doc != nil {
 			server.refreshDiagnosticsOfDocument(doc, context.Notify, false)
+
+			notebook, err := server.notebookOf(doc)
+			if err == nil {
+				server.urlMetadata.Watch(notebook)
+				server.logger.Err(notebook.RecordHistory(core.NoteHistoryOpened, doc.Path))
+			}
 		}
 		return nil
 	}
@@ -156,6 +269,7 @@ func NewServer(opts ServerOpts) *Server {
 
 	handler.TextDocumentDidClose = func(context *glsp.Context, params *protocol.DidCloseTextDocumentParams) error {
 		server.documents.Close(params.TextDocument.URI)
+		server.diagnostics.Forget(params.TextDocument.URI)
 		return nil
 	}
 
@@ -171,8 +285,12 @@ func NewServer(opts ServerOpts) *Server {
 			return nil
 		}
 
-		_, err = notebook.Index(false)
+		notebook.CommitEdits(doc.Path)
+
+		_, err = server.indexNotebook(notebook, lspOrigin(protocol.MethodTextDocumentDidSave), false, context, nil)
 		server.logger.Err(err)
+		// New external links might have been added.
+		server.urlMetadata.Wake()
 		return nil
 	}
 
@@ -188,15 +306,54 @@ func NewServer(opts ServerOpts) *Server {
 		if err != nil {
 			return nil, err
 		}
+		// The completion items insert text in the notes.
+		if server.isReadOnly(notebook) {
+			return nil, nil
+		}
+		config := server.configOf(notebook, doc)
+
+		// The citations take precedence over the dates when both use the
+		// `@` trigger, unless no reference matches.
+		if len(config.Bibliography.Files) > 0 {
+			if query, ok := doc.CitationQueryBefore(params.Position); ok {
+				list, err := server.buildCitationCompletionList(doc, notebook, params, query)
+				if err != nil || list != nil {
+					return list, err
+				}
+			}
+		}
+
+		if trigger := config.LSP.Completion.DateTrigger; trigger != "" {
+			if query, ok := doc.DateQueryBefore(params.Position, trigger); ok {
+				return server.buildDateCompletionList(doc, notebook, params, query, trigger)
+			}
+		}
+
+		// Completing inside an existing link replaces its target, unless a
+		// new construct is being typed.
+		isTriggerCharacter := params.Context != nil && params.Context.TriggerKind == protocol.CompletionTriggerKindTriggerCharacter
+		if link, ok := doc.RetargetedLinkAt(params.Position); ok && !isTriggerCharacter {
+			return server.buildRetargetLinkCompletionList(doc, notebook, params, link)
+		}
+
+		// Clients request the link completion again after each keystroke
+		// when the list was incomplete.
+		if query, trigger, ok := doc.LinkQueryBefore(params.Position, config.LSP.Completion.TriggerStrings); ok {
+			return server.buildLinkCompletionList(doc, notebook, params, query, trigger)
+		}
 
-		switch doc.LookBehind(params.Position, 3) {
-		case "]((":
-			return server.buildLinkCompletionList(doc, notebook, params)
+		if query, ok := doc.FrontmatterQueryAt(params.Position); ok {
+			return server.buildFrontmatterCompletionList(notebook, query)
 		}
 
 		switch doc.LookBehind(params.Position, 2) {
-		case "[[":
-			return server.buildLinkCompletionList(doc, notebook, params)
+		case "](":
+			text, isImage, ok := doc.MarkdownLinkTextBefore(params.Position)
+			if ok && isImage {
+				return server.buildAssetCompletionList(doc, notebook)
+			} else if ok && config.LSP.Completion.MarkdownLinks {
+				return server.buildMarkdownLinkCompletionList(doc, notebook, params, text)
+			}
 		}
 
 		switch doc.LookBehind(params.Position, 1) {
@@ -208,24 +365,21 @@ func NewServer(opts ServerOpts) *Server {
 			if notebook.Config.Format.Markdown.ColonTags {
 				return server.buildTagCompletionList(notebook, ":")
 			}
+		case "/":
+			if parent, ok := doc.ParentTagBefore(params.Position); ok && notebook.Config.Format.Markdown.Hashtags {
+				return server.buildChildTagCompletionList(notebook, parent)
+			}
 		}
 
 		return nil, nil
 	}
 
 	handler.CompletionItemResolve = func(context *glsp.Context, params *protocol.CompletionItem) (*protocol.CompletionItem, error) {
-		if path, ok := params.Data.(string); ok {
-			content, err := ioutil.ReadFile(path)
-			if err != nil {
-				return params, err
-			}
-			params.Documentation = protocol.MarkupContent{
-				Kind:  protocol.MarkupKindMarkdown,
-				Value: string(content),
-			}
+		data, ok := completionItemDataOf(params)
+		if !ok {
+			return params, nil
 		}
-
-		return params, nil
+		return params, server.resolveCompletionItem(params, data)
 	}
 
 	handler.TextDocumentHover = func(context *glsp.Context, params *protocol.HoverParams) (*protocol.Hover, error) {
@@ -234,9 +388,16 @@ func NewServer(opts ServerOpts) *Server {
 			return nil, nil
 		}
 
-		link, err := doc.DocumentLinkAt(params.Position)
-		if link == nil || err != nil {
-			return nil, err
+		if _, def, ok := doc.FootnoteAt(params.Position); ok {
+			if def == nil || def.Text == "" {
+				return nil, nil
+			}
+			return &protocol.Hover{
+				Contents: protocol.MarkupContent{
+					Kind:  server.markup.hover,
+					Value: def.Text,
+				},
+			}, nil
 		}
 
 		notebook, err := server.notebookOf(doc)
@@ -244,6 +405,38 @@ func NewServer(opts ServerOpts) *Server {
 			return nil, err
 		}
 
+		if citation, ok := doc.CitationAt(params.Position); ok {
+			found, err := notebook.FindCitation(citation.Key)
+			if found == nil || err != nil {
+				return nil, err
+			}
+			return &protocol.Hover{
+				Contents: protocol.MarkupContent{
+					Kind:  server.markup.hover,
+					Value: hoverForCitation(*found, server.markup.hover),
+				},
+				Range: &citation.Range,
+			}, nil
+		}
+
+		link, err := doc.DocumentLinkAt(params.Position)
+		if link == nil || err != nil {
+			return nil, err
+		}
+
+		if strutil.IsURL(link.Href) {
+			contents, err := hoverForURL(link.Href, notebook, server.markup.hover)
+			if contents == "" || err != nil {
+				return nil, err
+			}
+			return &protocol.Hover{
+				Contents: protocol.MarkupContent{
+					Kind:  server.markup.hover,
+					Value: contents,
+				},
+			}, nil
+		}
+
 		target, err := server.noteForLink(*link, doc, notebook)
 		if err != nil || target == nil {
 			return nil, err
@@ -251,24 +444,86 @@ func NewServer(opts ServerOpts) *Server {
 
 		path, err := uriToPath(target.URI)
 		if err != nil {
-			server.logger.Printf("unable to parse URI: %v", err)
+			logEvent(server.logger, "warning", "error", errors.Wrap(err, "unable to parse URI"))
 			return nil, err
 		}
 		path = fs.Canonical(path)
 
-		contents, err := ioutil.ReadFile(path)
+		// The note might belong to another notebook.
+		targetNotebook, err := server.notebooks.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		targetDoc, err := server.documentAt(target.URI)
+		if err != nil {
+			return nil, err
+		}
+		// Show the content of the embedded notes instead of their links.
+		contents, err := server.expandEmbeds(targetDoc, map[string]bool{path: true})
 		if err != nil {
 			return nil, err
 		}
 
+		hover := noteMarkupContent(server.markup.hover, contents)
+		// Show the summary declared in the frontmatter above the content.
+		if relPath, err := targetNotebook.RelPath(path); err == nil {
+			if note, _ := targetNotebook.FindByHref(relPath, false); note != nil {
+				hover = withSummaryHeader(hover, core.NoteSummary(note.Metadata, ""))
+			}
+		}
+
 		return &protocol.Hover{
-			Contents: protocol.MarkupContent{
-				Kind:  protocol.MarkupKindMarkdown,
-				Value: string(contents),
-			},
+			Contents: hover,
 		}, nil
 	}
 
+	handler.TextDocumentOnTypeFormatting = func(context *glsp.Context, params *protocol.DocumentOnTypeFormattingParams) ([]protocol.TextEdit, error) {
+		doc, ok := server.documents.Get(params.TextDocument.URI)
+		if !ok {
+			return nil, nil
+		}
+
+		notebook, err := server.notebookOf(doc)
+		if err != nil {
+			return nil, err
+		}
+		if server.isReadOnly(notebook) {
+			return nil, nil
+		}
+		config := server.configOf(notebook, doc)
+		return doc.OnTypeFormatting(params.Position, params.Ch, config.LSP.Formatting), nil
+	}
+
+	handler.TextDocumentFoldingRange = func(context *glsp.Context, params *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
+		doc, ok := server.documents.Get(params.TextDocument.URI)
+		if !ok {
+			return nil, nil
+		}
+
+		return doc.FoldingRanges(), nil
+	}
+
+	handler.TextDocumentSelectionRange = func(context *glsp.Context, params *protocol.SelectionRangeParams) ([]protocol.SelectionRange, error) {
+		doc, ok := server.documents.Get(params.TextDocument.URI)
+		if !ok {
+			return nil, nil
+		}
+
+		ranges := []protocol.SelectionRange{}
+		for _, pos := range params.Positions {
+			ranges = append(ranges, doc.SelectionRangeAt(pos))
+		}
+		return ranges, nil
+	}
+
+	handler.TextDocumentDocumentSymbol = func(context *glsp.Context, params *protocol.DocumentSymbolParams) (interface{}, error) {
+		doc, ok := server.documents.Get(params.TextDocument.URI)
+		if !ok {
+			return nil, nil
+		}
+		return doc.TaskSymbols(), nil
+	}
+
 	handler.TextDocumentDocumentLink = func(context *glsp.Context, params *protocol.DocumentLinkParams) ([]protocol.DocumentLink, error) {
 		doc, ok := server.documents.Get(params.TextDocument.URI)
 		if !ok {
@@ -285,6 +540,8 @@ func NewServer(opts ServerOpts) *Server {
 			return nil, err
 		}
 
+		publishedURL := notebook.Config.LSP.Links.PublishedURL
+
 		documentLinks := []protocol.DocumentLink{}
 		for _, link := range links {
 			target, err := server.noteForLink(link, doc, notebook)
@@ -292,9 +549,14 @@ func NewServer(opts ServerOpts) *Server {
 				continue
 			}
 
+			uri := target.URI
+			if !publishedURL.IsNull() {
+				uri = target.PublishedURL(publishedURL.String())
+			}
+
 			documentLinks = append(documentLinks, protocol.DocumentLink{
 				Range:  link.Range,
-				Target: &target.URI,
+				Target: &uri,
 			})
 		}
 
@@ -307,9 +569,15 @@ func NewServer(opts ServerOpts) *Server {
 			return nil, nil
 		}
 
-		link, err := doc.DocumentLinkAt(params.Position)
-		if link == nil || err != nil {
-			return nil, err
+		// Footnote references are resolved in the same document.
+		if _, def, ok := doc.FootnoteAt(params.Position); ok {
+			if def == nil {
+				return nil, nil
+			}
+			return protocol.Location{
+				URI:   doc.URI,
+				Range: def.Range,
+			}, nil
 		}
 
 		notebook, err := server.notebookOf(doc)
@@ -317,6 +585,20 @@ func NewServer(opts ServerOpts) *Server {
 			return nil, err
 		}
 
+		// Citations are resolved in the bibliography files.
+		if citation, ok := doc.CitationAt(params.Position); ok {
+			found, err := notebook.FindCitation(citation.Key)
+			if found == nil || err != nil {
+				return nil, err
+			}
+			return citationLocation(*found), nil
+		}
+
+		link, err := doc.DocumentLinkAt(params.Position)
+		if link == nil || err != nil {
+			return nil, err
+		}
+
 		target, err := server.noteForLink(*link, doc, notebook)
 		if link == nil || target == nil || err != nil {
 			return nil, err
@@ -338,58 +620,78 @@ func NewServer(opts ServerOpts) *Server {
 
 	handler.WorkspaceExecuteCommand = func(context *glsp.Context, params *protocol.ExecuteCommandParams) (interface{}, error) {
 		switch params.Command {
+		case cmdCapabilities:
+			return server.executeCommandCapabilities(params.Arguments)
 		case cmdIndex:
-			return server.executeCommandIndex(params.Arguments)
+			return server.executeCommandIndex(context, params.WorkDoneToken, params.Arguments)
+		case cmdLink:
+			return server.executeCommandLink(context, params.Arguments)
+		case cmdList:
+			return server.executeCommandList(params.Arguments)
+		case cmdExpandLink:
+			return server.executeCommandExpandLink(context, params.Arguments)
+		case cmdExtractListItems:
+			return server.executeCommandExtractListItems(context, params.Arguments)
+		case cmdFixDeadLinks:
+			return server.executeCommandFixDeadLinks(context, params.Arguments)
 		case cmdNew:
 			return server.executeCommandNew(context, params.Arguments)
+		case cmdPasteAsset:
+			return server.executeCommandPasteAsset(context, params.Arguments)
+		case cmdRecents:
+			return server.executeCommandRecents(params.Arguments)
+		case cmdRelated:
+			return server.executeCommandRelated(params.Arguments)
+		case cmdStats:
+			return server.executeCommandStats()
+		case cmdSync:
+			return server.executeCommandSync(context, params.WorkDoneToken, params.Arguments)
+		case cmdTagList:
+			return server.executeCommandTagList(params.Arguments)
+		case cmdTaskToggle:
+			return server.executeCommandTaskToggle(context, params.Arguments)
+		case cmdTemplateList:
+			return server.executeCommandTemplateList(params.Arguments)
+		case cmdTree:
+			return server.executeCommandTree(params.Arguments)
 		default:
 			return nil, fmt.Errorf("unknown zk LSP command: %s", params.Command)
 		}
 	}
 
 	handler.TextDocumentCodeAction = func(context *glsp.Context, params *protocol.CodeActionParams) (interface{}, error) {
-		if isRangeEmpty(params.Range) {
-			return nil, nil
-		}
-
 		doc, ok := server.documents.Get(params.TextDocument.URI)
 		if !ok {
 			return nil, nil
 		}
-		wd := filepath.Dir(doc.Path)
 
 		actions := []protocol.CodeAction{}
 
-		addAction := func(dir string, actionTitle string) error {
-			opts := cmdNewOpts{
-				Title: doc.ContentAtRange(params.Range),
-				Dir:   dir,
-				InsertLinkAtLocation: &protocol.Location{
-					URI:   params.TextDocument.URI,
-					Range: params.Range,
-				},
-			}
-
-			var jsonOpts map[string]interface{}
-			err := unmarshalJSON(opts, &jsonOpts)
-			if err != nil {
-				return err
-			}
-
-			actions = append(actions, protocol.CodeAction{
-				Title: actionTitle,
-				Kind:  stringPtr(protocol.CodeActionKindRefactor),
-				Command: &protocol.Command{
-					Command:   cmdNew,
-					Arguments: []interface{}{wd, jsonOpts},
-				},
-			})
+		notebook, err := server.notebookOf(doc)
+		if err != nil {
+			return nil, err
+		}
+		// All the code actions modify the notebook.
+		if server.isReadOnly(notebook) {
+			return actions, nil
+		}
+		archiveAction, err := archivedURLCodeAction(doc, params.Range.Start, notebook)
+		if err != nil {
+			return nil, err
+		}
+		if archiveAction != nil {
+			actions = append(actions, *archiveAction)
+		}
 
-			return nil
+		if isRangeEmpty(params.Range) {
+			return actions, nil
 		}
 
-		addAction(wd, "New note in current directory")
-		addAction("", "New note in top directory")
+		newNoteActions, err := newNoteCodeActions(doc, params.Range, notebook)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, newNoteActions...)
 
 		return actions, nil
 	}
@@ -400,70 +702,91 @@ func NewServer(opts ServerOpts) *Server {
 			return nil, nil
 		}
 
-		notebook, err := server.notebookOf(doc)
+		backlinks, err := server.backlinksAt(doc, params.Position)
 		if err != nil {
 			return nil, err
 		}
 
-		link, err := doc.DocumentLinkAt(params.Position)
-		if err != nil {
-			return nil, err
-		}
-		if link == nil {
-			href, err := notebook.RelPath(doc.Path)
-			if err != nil {
-				return nil, err
-			}
-			link = &documentLink{Href: href}
+		var locations []protocol.Location
+		for _, backlink := range backlinks {
+			locations = append(locations, protocol.Location{
+				URI:   backlink.URI,
+				Range: backlink.Range,
+			})
 		}
 
-		target, err := server.noteForLink(*link, doc, notebook)
-		if link == nil || target == nil || err != nil {
-			return nil, err
-		}
+		return locations, nil
+	}
 
-		p, err := notebook.RelPath(target.Path)
+	handler.TextDocumentCodeLens = func(context *glsp.Context, params *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
+		doc, ok := server.documents.Get(params.TextDocument.URI)
+		if !ok {
+			return nil, nil
+		}
+		lenses, err := server.backlinksCodeLens(doc)
 		if err != nil {
 			return nil, err
 		}
-
-		opts := core.NoteFindOpts{
-			LinkTo: &core.LinkFilter{Paths: []string{p}},
-		}
-
-		notes, err := notebook.FindNotes(opts)
+		suggestLinks, err := server.suggestLinksCodeLens(doc)
 		if err != nil {
 			return nil, err
 		}
+		return append(lenses, suggestLinks...), nil
+	}
 
-		var locations []protocol.Location
+	handler.WorkspaceDidChangeWatchedFiles = func(context *glsp.Context, params *protocol.DidChangeWatchedFilesParams) error {
+		// The notes were modified by another program, e.g. git pull, so the
+		// index is refreshed once for each notebook affected.
+		notebooks := []*core.Notebook{}
+		for _, change := range params.Changes {
+			path, err := server.documents.normalizePath(change.URI)
+			if err != nil {
+				server.logger.Err(err)
+				continue
+			}
+			notebook, err := server.notebooks.Open(path)
+			if err != nil {
+				// Not part of a notebook.
+				continue
+			}
+			if !isNoteFile(notebook, path) {
+				continue
+			}
+			if !containsNotebook(notebooks, notebook) {
+				notebooks = append(notebooks, notebook)
+			}
+		}
 
-		for _, note := range notes {
-			pos := strings.Index(note.RawContent, target.Path[0:len(target.Path)-3])
-			var line uint32 = 0
-			if pos < 0 {
-				line = 0
-			} else {
-				linePos := strings.Count(note.RawContent[0:pos], "\n")
-				line = uint32(linePos)
+		for _, notebook := range notebooks {
+			_, err := server.indexNotebook(notebook, lspOrigin(protocol.MethodWorkspaceDidChangeWatchedFiles), false, context, nil)
+			if err != nil {
+				server.logger.Err(err)
+				continue
+			}
+			for _, doc := range server.documents.InDir(notebook.Path) {
+				server.refreshDiagnosticsOfDocument(doc, context.Notify, false)
 			}
+		}
+		if len(notebooks) > 0 {
+			// New external links might have been added.
+			server.urlMetadata.Wake()
+		}
+		return nil
+	}
 
-			locations = append(locations, protocol.Location{
-				URI: pathToURI(filepath.Join(notebook.Path, note.Path)),
-				Range: protocol.Range{
-					Start: protocol.Position{
-						Line:      line,
-						Character: 0,
-					},
-					End: protocol.Position{
-						Line:      line,
-						Character: 0,
-					},
-				},
-			})
+	handler.WorkspaceWillDeleteFiles = func(context *glsp.Context, params *protocol.DeleteFilesParams) (*protocol.WorkspaceEdit, error) {
+		deletedURIs := []string{}
+		for _, file := range params.Files {
+			deletedURIs = append(deletedURIs, file.URI)
 		}
 
-		return locations, nil
+		for _, uri := range deletedURIs {
+			err := server.warnAboutDeletedNote(uri, deletedURIs, context.Notify)
+			server.logger.Err(err)
+		}
+
+		// The deletion is never prevented, the user is only warned.
+		return nil, nil
 	}
 
 	return server
@@ -474,9 +797,19 @@ func (s *Server) Run() error {
 	return errors.Wrap(s.server.RunStdio(), "lsp")
 }
 
+// setTrace changes the trace setting of the client. The trace setting is
+// isolated to this client, instead of being global to the process.
+func (s *Server) setTrace(value protocol.TraceValue) {
+	// The spec says "message", but some clients use "messages" instead.
+	if value == "messages" {
+		value = protocol.TraceValueMessage
+	}
+	s.trace = value
+}
+
 const cmdIndex = "zk.index"
 
-func (s *Server) executeCommandIndex(args []interface{}) (interface{}, error) {
+func (s *Server) executeCommandIndex(context *glsp.Context, workDoneToken *protocol.ProgressToken, args []interface{}) (interface{}, error) {
 	if len(args) == 0 {
 		return nil, fmt.Errorf("zk.index expects a notebook path as first argument")
 	}
@@ -501,63 +834,393 @@ func (s *Server) executeCommandIndex(args []interface{}) (interface{}, error) {
 		return nil, err
 	}
 
-	return notebook.Index(force)
+	return s.indexNotebook(notebook, lspOrigin(cmdIndex), force, context, workDoneToken)
 }
 
-const cmdNew = "zk.new"
-
-type cmdNewOpts struct {
-	Title                string             `json:"title,omitempty"`
-	Content              string             `json:"content,omitempty"`
-	Dir                  string             `json:"dir,omitempty"`
-	Group                string             `json:"group,omitempty"`
-	Template             string             `json:"template,omitempty"`
-	Extra                map[string]string  `json:"extra,omitempty"`
-	Date                 string             `json:"date,omitempty"`
-	Edit                 jsonBoolean        `json:"edit,omitempty"`
-	InsertLinkAtLocation *protocol.Location `json:"insertLinkAtLocation,omitempty"`
-}
+const cmdSync = "zk.sync"
 
-func (s *Server) executeCommandNew(context *glsp.Context, args []interface{}) (interface{}, error) {
+func (s *Server) executeCommandSync(context *glsp.Context, workDoneToken *protocol.ProgressToken, args []interface{}) (interface{}, error) {
 	if len(args) == 0 {
-		return nil, fmt.Errorf("zk.index expects a notebook path as first argument")
+		return nil, fmt.Errorf("zk.sync expects a notebook path as first argument")
 	}
-	wd, ok := args[0].(string)
+	path, ok := args[0].(string)
 	if !ok {
-		return nil, fmt.Errorf("zk.index expects a notebook path as first argument, got: %v", args[0])
-	}
-
-	var opts cmdNewOpts
-	if len(args) > 1 {
-		arg, ok := args[1].(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("zk.new expects a dictionary of options as second argument, got: %v", args[1])
-		}
-		err := unmarshalJSON(arg, &opts)
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to parse zk.new args, got: %v", arg)
-		}
+		return nil, fmt.Errorf("zk.sync expects a notebook path as first argument, got: %v", args[0])
 	}
 
-	notebook, err := s.notebooks.Open(wd)
+	notebook, err := s.notebooks.Open(path)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.checkWritable(notebook, cmdSync); err != nil {
+		return nil, err
+	}
 
-	date, err := dateutil.TimeFromNatural(opts.Date)
+	err = notebook.Sync()
 	if err != nil {
-		return nil, errors.Wrapf(err, "%s, failed to parse the `date` option", opts.Date)
+		return nil, err
 	}
 
-	note, err := notebook.NewNote(core.NewNoteOpts{
-		Title:     opt.NewNotEmptyString(opts.Title),
-		Content:   opts.Content,
-		Directory: opt.NewNotEmptyString(opts.Dir),
-		Group:     opt.NewNotEmptyString(opts.Group),
-		Template:  opt.NewNotEmptyString(opts.Template),
-		Extra:     opts.Extra,
-		Date:      date,
+	// Index the notes pulled from the remote.
+	return s.indexNotebook(notebook, lspOrigin(cmdSync), false, context, workDoneToken)
+}
+
+const cmdTemplateList = "zk.template.list"
+
+func (s *Server) executeCommandTemplateList(args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("zk.template.list expects a notebook path as first argument")
+	}
+	path, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.template.list expects a notebook path as first argument, got: %v", args[0])
+	}
+
+	notebook, err := s.notebooks.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return notebook.NoteTemplates()
+}
+
+const cmdTree = "zk.tree"
+
+type cmdTreeOpts struct {
+	// Path or ID of the root note of the returned subtree. The whole
+	// hierarchy is returned when empty.
+	Path string `json:"path,omitempty"`
+}
+
+func (s *Server) executeCommandTree(args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("zk.tree expects a notebook path as first argument")
+	}
+	wd, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.tree expects a notebook path as first argument, got: %v", args[0])
+	}
+
+	var opts cmdTreeOpts
+	if len(args) > 1 {
+		arg, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("zk.tree expects a dictionary of options as second argument, got: %v", args[1])
+		}
+		err := unmarshalJSON(arg, &opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse zk.tree args, got: %v", arg)
+		}
+	}
+
+	notebook, err := s.notebooks.Open(wd)
+	if err != nil {
+		return nil, err
+	}
+	hierarchy, err := notebook.NoteHierarchy()
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := notebook.FindMinimalNotes(core.NoteFindOpts{
+		Trash:   core.TrashFilterExclude,
+		Sorters: []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
 	})
+	if err != nil {
+		return nil, err
+	}
+	included := map[string]bool{}
+	for _, note := range notes {
+		included[note.Path] = true
+	}
+
+	roots := []string{}
+	if opts.Path == "" {
+		for _, note := range notes {
+			if !hasIncludedAncestor(hierarchy, note.Path, included) {
+				roots = append(roots, note.Path)
+			}
+		}
+	} else {
+		ref := opts.Path
+		if filepath.IsAbs(ref) {
+			ref, err = notebook.RelPath(ref)
+			if err != nil {
+				return nil, err
+			}
+		}
+		root, err := hierarchy.Find(ref)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+
+	// The children are listed depth first, in the order of their IDs.
+	paths := []string{}
+	for _, root := range roots {
+		for _, path := range append([]string{root}, hierarchy.Children(root, true)...) {
+			if included[path] {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	return hierarchy.Tree(paths), nil
+}
+
+func hasIncludedAncestor(hierarchy *core.NoteHierarchy, path string, included map[string]bool) bool {
+	for _, ancestor := range hierarchy.Ancestors(path) {
+		if included[ancestor] {
+			return true
+		}
+	}
+	return false
+}
+
+const cmdList = "zk.list"
+
+type cmdListOpts struct {
+	Select     []string    `json:"select"`
+	Match      string      `json:"match,omitempty"`
+	ExactMatch jsonBoolean `json:"exactMatch,omitempty"`
+	Hrefs      []string    `json:"hrefs,omitempty"`
+	Tags       []string    `json:"tags,omitempty"`
+	Limit      int         `json:"limit,omitempty"`
+	Sort       []string    `json:"sort,omitempty"`
+	// Includes the notes of the archive directory, which are hidden
+	// otherwise.
+	IncludeArchived jsonBoolean `json:"includeArchived,omitempty"`
+}
+
+func (s *Server) executeCommandList(args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("zk.list expects a notebook path as first argument")
+	}
+	wd, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.list expects a notebook path as first argument, got: %v", args[0])
+	}
+
+	var opts cmdListOpts
+	if len(args) > 1 {
+		arg, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("zk.list expects a dictionary of options as second argument, got: %v", args[1])
+		}
+		err := unmarshalJSON(arg, &opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse zk.list args, got: %v", arg)
+		}
+	}
+	if len(opts.Select) == 0 {
+		return nil, errors.New("zk.list expects a `select` option with the list of fields to return")
+	}
+
+	notebook, err := s.notebooks.Open(wd)
+	if err != nil {
+		return nil, err
+	}
+
+	findOpts := core.NoteFindOpts{
+		Match:           opt.NewNotEmptyString(opts.Match),
+		ExactMatch:      bool(opts.ExactMatch),
+		Tags:            opts.Tags,
+		Limit:           opts.Limit,
+		Trash:           core.TrashFilterExclude,
+		ExcludeArchived: !bool(opts.IncludeArchived),
+	}
+	if len(opts.Hrefs) > 0 {
+		findOpts.IncludePaths = opts.Hrefs
+	}
+	findOpts.Sorters, err = core.NoteSortersFromStrings(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := notebook.FindNotes(findOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	listNotes := []map[string]interface{}{}
+	for _, note := range notes {
+		listNote := map[string]interface{}{}
+		for _, field := range opts.Select {
+			switch field {
+			case "path":
+				listNote[field] = note.Path
+			case "absPath":
+				listNote[field] = filepath.Join(notebook.Path, note.Path)
+			case "title":
+				listNote[field] = note.Title
+			case "lead":
+				listNote[field] = note.Lead
+			case "summary":
+				listNote[field] = note.Summary
+			case "body":
+				listNote[field] = note.Body
+			case "snippets":
+				listNote[field] = highlightSnippets(note.Snippets)
+			case "rawContent":
+				listNote[field] = note.RawContent
+			case "wordCount":
+				listNote[field] = note.WordCount
+			case "readingTime":
+				listNote[field] = core.ReadingTime(note.WordCount)
+			case "tags":
+				listNote[field] = note.Tags
+			case "metadata":
+				listNote[field] = note.Metadata
+			case "created":
+				listNote[field] = note.Created
+			case "modified":
+				listNote[field] = note.Modified
+			case "checksum":
+				listNote[field] = note.Checksum
+			case "score":
+				listNote[field] = note.Score
+			default:
+				return nil, fmt.Errorf("%s: unknown zk.list field", field)
+			}
+		}
+		listNotes = append(listNotes, listNote)
+	}
+
+	return listNotes, nil
+}
+
+// highlightSnippets converts the search terms matched in the snippets into
+// Markdown strong emphasis, to be displayed by the editors.
+func highlightSnippets(snippets []string) []string {
+	highlighted := make([]string, 0, len(snippets))
+	for _, snippet := range snippets {
+		snippet = strings.ReplaceAll(snippet, "<zk:match>", "**")
+		snippet = strings.ReplaceAll(snippet, "</zk:match>", "**")
+		highlighted = append(highlighted, snippet)
+	}
+	return highlighted
+}
+
+const cmdNew = "zk.new"
+
+type cmdNewOpts struct {
+	Title                string             `json:"title,omitempty"`
+	Content              string             `json:"content,omitempty"`
+	Dir                  string             `json:"dir,omitempty"`
+	Group                string             `json:"group,omitempty"`
+	Template             string             `json:"template,omitempty"`
+	Extra                map[string]string  `json:"extra,omitempty"`
+	Date                 string             `json:"date,omitempty"`
+	Parent               string             `json:"parent,omitempty"`
+	Edit                 jsonBoolean        `json:"edit,omitempty"`
+	InsertLinkAtLocation *protocol.Location `json:"insertLinkAtLocation,omitempty"`
+	LinkStyle            string             `json:"linkStyle,omitempty"`
+	// Returns the variables declared by the template which are missing from
+	// Extra instead of creating the note, to prompt the user for them.
+	Interactive bool `json:"interactive,omitempty"`
+}
+
+func (s *Server) executeCommandNew(context *glsp.Context, args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("zk.index expects a notebook path as first argument")
+	}
+	wd, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.index expects a notebook path as first argument, got: %v", args[0])
+	}
+
+	var opts cmdNewOpts
+	if len(args) > 1 {
+		arg, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("zk.new expects a dictionary of options as second argument, got: %v", args[1])
+		}
+		err := unmarshalJSON(arg, &opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse zk.new args, got: %v", arg)
+		}
+	}
+
+	notebook, err := s.notebooks.Open(wd)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWritable(notebook, cmdNew); err != nil {
+		return nil, err
+	}
+
+	date, err := dateutil.TimeFromNatural(opts.Date)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s, failed to parse the `date` option", opts.Date)
+	}
+
+	noteOpts := core.NewNoteOpts{
+		Title:     opt.NewNotEmptyString(opts.Title),
+		Content:   opts.Content,
+		Directory: opt.NewNotEmptyString(opts.Dir),
+		Group:     opt.NewNotEmptyString(opts.Group),
+		Template:  opt.NewNotEmptyString(opts.Template),
+		Extra:     opts.Extra,
+		Date:      date,
+		Parent:    opt.NewNotEmptyString(opts.Parent),
+		Origin:    lspOrigin(cmdNew),
+	}
+	if opts.Interactive {
+		vars, err := notebook.MissingTemplateVars(noteOpts)
+		if err != nil {
+			return nil, err
+		}
+		if len(vars) > 0 {
+			return map[string]interface{}{"needsInput": vars}, nil
+		}
+	}
+
+	note, err := newOrExistingNote(notebook, noteOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.InsertLinkAtLocation != nil {
+		doc, ok := s.documents.Get(opts.InsertLinkAtLocation.URI)
+		if !ok {
+			return nil, fmt.Errorf("can't insert link in %s", opts.InsertLinkAtLocation.URI)
+		}
+		linkFormatter, err := newLinkFormatterWithStyle(notebook, s.configOf(notebook, doc), opts.LinkStyle)
+		if err != nil {
+			return nil, err
+		}
+		link, err := formatLinkIn(doc, note, notebook, linkFormatter)
+		if err != nil {
+			return nil, err
+		}
+
+		go context.Call(protocol.ServerWorkspaceApplyEdit, protocol.ApplyWorkspaceEditParams{
+			Edit: insertLinkEdit(doc, *opts.InsertLinkAtLocation, link),
+		}, nil)
+	}
+
+	absPath := filepath.Join(notebook.Path, note.Path)
+	if opts.Edit {
+		go context.Call(protocol.ServerWindowShowDocument, protocol.ShowDocumentParams{
+			URI:       pathToURI(absPath),
+			TakeFocus: boolPtr(true),
+		}, nil)
+	}
+
+	return map[string]interface{}{"path": absPath}, nil
+}
+
+// lspOrigin identifies the LSP command or notification performing an
+// operation on a notebook, for the audit log and the hooks.
+func lspOrigin(command string) core.AuditOrigin {
+	return core.AuditOrigin{Interface: "lsp", Command: command}
+}
+
+// newOrExistingNote creates a new note, or returns the existing note with
+// the same path.
+func newOrExistingNote(notebook *core.Notebook, opts core.NewNoteOpts) (*core.Note, error) {
+	note, err := notebook.NewNote(opts)
 	if err != nil {
 		var noteExists core.ErrNoteExists
 		if !errors.As(err, &noteExists) {
@@ -571,204 +1234,599 @@ func (s *Server) executeCommandNew(context *glsp.Context, args []interface{}) (i
 		}
 	}
 	if note == nil {
-		return nil, errors.New("zk.new could not generate a new note")
+		return nil, errors.New("could not generate a new note")
+	}
+	return note, nil
+}
+
+// newLinkFormatterWithStyle creates a link formatter for the given style:
+// "wiki" for wiki-links labeled with the note title, "markdown" for regular
+// Markdown links, or the link format of the given config by default.
+func newLinkFormatterWithStyle(notebook *core.Notebook, config core.Config, style string) (core.LinkFormatter, error) {
+	switch style {
+	case "":
+		return notebook.NewLinkFormatterWithConfig(config.Format.Markdown)
+	case "wiki":
+		return core.NewTitledWikiLinkFormatter(config.Format.Markdown)
+	case "markdown":
+		return core.NewMarkdownLinkFormatter(config.Format.Markdown, false)
+	default:
+		return nil, fmt.Errorf("%s: unknown link style, expected wiki or markdown", style)
 	}
+}
 
-	if opts.InsertLinkAtLocation != nil {
-		doc, ok := s.documents.Get(opts.InsertLinkAtLocation.URI)
-		if !ok {
-			return nil, fmt.Errorf("can't insert link in %s", opts.InsertLinkAtLocation.URI)
+// formatLinkIn formats a link to note, from the given document.
+func formatLinkIn(doc *document, note *core.Note, notebook *core.Notebook, formatter core.LinkFormatter) (string, error) {
+	context, err := core.NewLinkFormatterContext(note.AsMinimalNote(), notebook.Path, filepath.Dir(doc.Path))
+	if err != nil {
+		return "", err
+	}
+	return formatter(context)
+}
+
+func (s *Server) notebookOf(doc *document) (*core.Notebook, error) {
+	return s.notebooks.Open(doc.Path)
+}
+
+// configOf returns the config of the notebook applying to the given document,
+// with the format and LSP settings overridden by its group.
+func (s *Server) configOf(notebook *core.Notebook, doc *document) core.Config {
+	return configForPath(notebook, doc.Path, s.logger)
+}
+
+// configForPath returns the config of the notebook applying to the file at
+// the given absolute path, falling back on the notebook config.
+func configForPath(notebook *core.Notebook, path string, logger util.Logger) core.Config {
+	relPath, err := filepath.Rel(notebook.Path, path)
+	if err != nil {
+		logger.Err(err)
+		return notebook.Config
+	}
+	config, err := notebook.Config.ForPath(filepath.ToSlash(relPath))
+	if err != nil {
+		logger.Err(err)
+		return notebook.Config
+	}
+	return config
+}
+
+// noteForLink returns the LSP documentUri for the note targeted by the given
+// link, resolved like the notebook-wide checks with core.Notebook.ResolveLink.
+//
+// The links to images and existing attachments are not matched fuzzily.
+func (s *Server) noteForLink(link documentLink, doc *document, notebook *core.Notebook) (*Note, error) {
+	if notebookPath, href, ok := notebook.FederatedHref(link.Href); ok {
+		return s.federatedNote(notebookPath, href)
+	}
+
+	sourcePath, err := filepath.Rel(notebook.Path, doc.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve href: %s", link.Href)
+	}
+	note, confidence, err := notebook.ResolveLink(sourcePath, link.Href, core.ResolveLinkOpts{
+		IsWikiLink: link.IsWikiLink,
+		// Last resort, the note might have been renamed slightly.
+		Fuzzy: !link.IsImage && !s.isExistingAsset(link, doc),
+	})
+	if err != nil {
+		logEvent(s.logger, "warning", "href", link.Href, "error", err)
+	}
+	if note == nil || err != nil {
+		return nil, err
+	}
+
+	joined_path := filepath.Join(notebook.Path, note.Path)
+	return &Note{*note, pathToURI(joined_path), confidence}, nil
+}
+
+// federatedNote returns the note targeted by href in another notebook,
+// registered with a prefix in the [notebooks] config section.
+func (s *Server) federatedNote(notebookPath string, href string) (*Note, error) {
+	notebook, err := s.notebooks.Open(notebookPath)
+	if err != nil {
+		return nil, err
+	}
+
+	note, confidence, err := notebook.ResolveHref(href)
+	if note == nil || err != nil {
+		return nil, err
+	}
+
+	return &Note{*note, pathToURI(filepath.Join(notebook.Path, note.Path)), confidence}, nil
+}
+
+// warnAboutDeletedNote shows a warning to the user when the note at the
+// given URI is about to be deleted but is still linked from other notes.
+// The other deleted notes are not reported as backlinks.
+func (s *Server) warnAboutDeletedNote(uri string, deletedURIs []string, notify glsp.NotifyFunc) error {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return err
+	}
+	notebook, err := s.notebooks.Open(path)
+	if err != nil {
+		// The file is not part of a notebook.
+		return nil
+	}
+	relPath, err := notebook.RelPath(path)
+	if err != nil {
+		return err
+	}
+	note, err := notebook.FindByHref(relPath, false)
+	if note == nil || err != nil {
+		return err
+	}
+
+	backlinks, err := notebook.FindMinimalNotes(core.NoteFindOpts{
+		LinkTo:       &core.LinkFilter{Paths: []string{note.Path}},
+		ExcludePaths: notebookPathsOf(notebook, deletedURIs),
+	})
+	if err != nil || len(backlinks) == 0 {
+		return err
+	}
+
+	paths := []string{}
+	for _, backlink := range backlinks {
+		paths = append(paths, backlink.Path)
+	}
+	go notify(protocol.ServerWindowShowMessage, protocol.ShowMessageParams{
+		Type:    protocol.MessageTypeWarning,
+		Message: deletedNoteWarning(note.Path, paths),
+	})
+	return nil
+}
+
+// notebookPathsOf returns the paths relative to the notebook root of the
+// files at the given URIs. The files out of the notebook are skipped.
+func notebookPathsOf(notebook *core.Notebook, uris []string) []string {
+	paths := []string{}
+	for _, uri := range uris {
+		path, err := uriToPath(uri)
+		if err != nil {
+			continue
+		}
+		if path, err = notebook.RelPath(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// deletedNoteWarning returns the warning shown when deleting the note at
+// path, which is still linked from the notes at backlinkPaths.
+func deletedNoteWarning(path string, backlinkPaths []string) string {
+	return fmt.Sprintf("%s is still linked from %d %s: %s",
+		path, len(backlinkPaths),
+		strutil.Pluralize("note", len(backlinkPaths)),
+		strings.Join(backlinkPaths, ", "),
+	)
+}
+
+// isExistingAsset returns whether the given link targets an existing file
+// which is not a note, e.g. an image.
+func (s *Server) isExistingAsset(link documentLink, doc *document) bool {
+	if link.IsWikiLink {
+		return false
+	}
+	href := strings.SplitN(link.Href, "#", 2)[0]
+	if href == "" {
+		return false
+	}
+	exists, err := s.fs.FileExists(filepath.Join(filepath.Dir(doc.Path), href))
+	s.logger.Err(err)
+	return exists
+}
+
+type Note struct {
+	core.MinimalNote
+	URI protocol.DocumentUri
+	// Confidence of the link resolution, 1 unless the note was found with a
+	// fuzzy match.
+	Confidence float64
+}
+
+// refreshDiagnosticsOfDocument schedules the refresh of the diagnostics of
+// the given document. When delay is true, the refresh waits for the user to
+// stop typing.
+func (s *Server) refreshDiagnosticsOfDocument(doc *document, notify glsp.NotifyFunc, delay bool) {
+	notebook, err := s.notebookOf(doc)
+	if err != nil {
+		s.logger.Err(err)
+		return
+	}
+	if !hasDiagnosticsEnabled(s.configOf(notebook, doc).LSP.Diagnostics) {
+		return
+	}
+
+	if delay {
+		s.diagnostics.Schedule(doc, notify, diagnosticsEditDelay)
+	} else {
+		s.diagnostics.Schedule(doc, notify, diagnosticsBatchDelay)
+	}
+}
+
+// hasDiagnosticsEnabled returns whether at least one diagnostic is reported.
+func hasDiagnosticsEnabled(diagConfig core.LSPDiagnosticConfig) bool {
+	return diagConfig.WikiTitle != core.LSPDiagnosticNone || diagConfig.DeadLink != core.LSPDiagnosticNone || diagConfig.FuzzyLink != core.LSPDiagnosticNone || diagConfig.Footnote != core.LSPDiagnosticNone || diagConfig.DeadURL != core.LSPDiagnosticNone || diagConfig.Citation != core.LSPDiagnosticNone
+}
+
+// documentDiagnostics computes the diagnostics of the given document.
+func (s *Server) documentDiagnostics(notebook *core.Notebook, doc *document) ([]protocol.Diagnostic, error) {
+	diagConfig := s.configOf(notebook, doc).LSP.Diagnostics
+	diagnostics := []protocol.Diagnostic{}
+	links, err := doc.DocumentLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, link := range links {
+		if strutil.IsURL(link.Href) {
+			if diagnostic := s.deadURLDiagnostic(notebook, link, diagConfig.DeadURL); diagnostic != nil {
+				diagnostics = append(diagnostics, *diagnostic)
+			}
+			continue
 		}
-		linkFormatter, err := notebook.NewLinkFormatter()
+		target, err := s.noteForLink(link, doc, notebook)
 		if err != nil {
-			return nil, err
+			s.logger.Err(err)
+			continue
+		}
+
+		var severity protocol.DiagnosticSeverity
+		var message string
+		if target == nil {
+			if diagConfig.DeadLink == core.LSPDiagnosticNone || s.isExistingAsset(link, doc) {
+				continue
+			}
+			severity = protocol.DiagnosticSeverity(diagConfig.DeadLink)
+			message = "not found"
+			if link.IsImage {
+				message = "attachment not found"
+			}
+		} else if target.Confidence < 1 {
+			if diagConfig.FuzzyLink == core.LSPDiagnosticNone {
+				continue
+			}
+			severity = protocol.DiagnosticSeverity(diagConfig.FuzzyLink)
+			name := target.Title
+			if name == "" {
+				name = target.Path
+			}
+			message = fmt.Sprintf("fuzzy match: %s (%d%%)", name, int(target.Confidence*100))
+		} else {
+			if link.HasTitle || diagConfig.WikiTitle == core.LSPDiagnosticNone {
+				continue
+			}
+			severity = protocol.DiagnosticSeverity(diagConfig.WikiTitle)
+			message = wikiLinkTitle(link, target.MinimalNote)
 		}
 
-		currentDir := filepath.Dir(doc.Path)
-		linkFormatterContext, err := core.NewLinkFormatterContext(note.AsMinimalNote(), notebook.Path, currentDir)
-		if err != nil {
-			return nil, err
-		}
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    link.Range,
+			Severity: &severity,
+			Source:   stringPtr("zk"),
+			Message:  message,
+		})
+	}
+
+	if diagConfig.Footnote != core.LSPDiagnosticNone {
+		diagnostics = append(diagnostics, doc.FootnoteDiagnostics(protocol.DiagnosticSeverity(diagConfig.Footnote))...)
+	}
+
+	citationDiagnostics, err := s.citationDiagnostics(notebook, doc, diagConfig.Citation)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics = append(diagnostics, citationDiagnostics...)
+
+	return diagnostics, nil
+}
+
+// deadURLDiagnostic reports an external link which was unreachable when its
+// URL was last fetched or checked. The URLs are never requested here, only
+// their cached status is used.
+func (s *Server) deadURLDiagnostic(notebook *core.Notebook, link documentLink, severity core.LSPDiagnosticSeverity) *protocol.Diagnostic {
+	if severity == core.LSPDiagnosticNone || !notebook.Config.Lint.URLs.ChecksURL(link.Href) {
+		return nil
+	}
+	metadata, err := notebook.FindURLMetadata(link.Href)
+	if err != nil {
+		s.logger.Err(err)
+		return nil
+	}
+	if metadata == nil || !metadata.IsDead() {
+		return nil
+	}
+
+	diagSeverity := protocol.DiagnosticSeverity(severity)
+	message := fmt.Sprintf("dead URL (%s)", metadata.StatusText())
+	if metadata.Archive != "" {
+		message += ", archived at " + metadata.Archive
+	}
+	return &protocol.Diagnostic{
+		Range:    link.Range,
+		Severity: &diagSeverity,
+		Source:   stringPtr("zk"),
+		Message:  message,
+	}
+}
 
-		link, err := linkFormatter(linkFormatterContext)
-		if err != nil {
-			return nil, err
+// wikiLinkTitle returns the title suggested for a wiki-link to note. The
+// alias used by the link is preferred to the note title, as it is usually
+// the one fitting the surrounding text.
+func wikiLinkTitle(link documentLink, note core.MinimalNote) string {
+	aliases := note.Aliases()
+	for _, alias := range aliases {
+		if strings.EqualFold(alias, strings.TrimSpace(link.Href)) {
+			return alias
 		}
+	}
+	if note.Title == "" && len(aliases) > 0 {
+		return aliases[0]
+	}
+	return note.Title
+}
 
-		go context.Call(protocol.ServerWorkspaceApplyEdit, protocol.ApplyWorkspaceEditParams{
-			Edit: protocol.WorkspaceEdit{
-				Changes: map[string][]protocol.TextEdit{
-					opts.InsertLinkAtLocation.URI: {{Range: opts.InsertLinkAtLocation.Range, NewText: link}},
-				},
-			},
-		}, nil)
+// buildTagCompletionList completes the full name of the tags, including the
+// parents of hierarchical tags which are not used directly.
+func (s *Server) buildTagCompletionList(notebook *core.Notebook, triggerChar string) ([]protocol.CompletionItem, error) {
+	tags, err := notebook.FindCollections(core.CollectionKindTag, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	absPath := filepath.Join(notebook.Path, note.Path)
-	if opts.Edit {
-		go context.Call(protocol.ServerWindowShowDocument, protocol.ShowDocumentParams{
-			URI:       pathToURI(absPath),
-			TakeFocus: boolPtr(true),
-		}, nil)
+	var items []protocol.CompletionItem
+	var visit func(trees []core.TagTree)
+	visit = func(trees []core.TagTree) {
+		for _, tag := range trees {
+			items = append(items, protocol.CompletionItem{
+				Label:      tag.Path,
+				InsertText: s.buildInsertForTag(tag.Path, triggerChar, notebook.Config),
+				Detail:     stringPtr(tagIconPrefix(tag.Path, notebook.Config) + tagCompletionDetail(tag)),
+			})
+			visit(tag.Children)
+		}
 	}
+	visit(core.NewTagTrees(tags))
 
-	return map[string]interface{}{"path": absPath}, nil
+	return items, nil
 }
 
-func (s *Server) notebookOf(doc *document) (*core.Notebook, error) {
-	return s.notebooks.Open(doc.Path)
+// workspaceNotebook returns the notebook found at the root of the workspace
+// opened by the client, if any. The server capabilities are declared before
+// any document is opened, so they are configured from this notebook.
+func (s *Server) workspaceNotebook(params *protocol.InitializeParams) *core.Notebook {
+	var path string
+	if params.RootURI != nil {
+		path, _ = uriToPath(*params.RootURI)
+	} else if params.RootPath != nil {
+		path = *params.RootPath
+	}
+	if path == "" {
+		return nil
+	}
+	notebook, err := s.notebooks.Open(path)
+	if err != nil {
+		// The workspace is not necessarily a notebook.
+		return nil
+	}
+	return notebook
 }
 
-// noteForLink returns the LSP documentUri for the note targeted by the given link.
-//
-// Match by order of precedence:
-//  1. Prefix of relative path
-//  2. Find any occurrence of the href in a note path (substring)
-//  3. Match the href as a term in the note titles
-func (s *Server) noteForLink(link documentLink, doc *document, notebook *core.Notebook) (*Note, error) {
-	note, err := s.noteForHref(link.Href, doc, notebook)
-	if note == nil && err == nil && link.IsWikiLink {
-		// Try to find a partial href match.
-		note, err = notebook.FindByHref(link.Href, true)
-		if note == nil && err == nil {
-			// Fallback on matching the note title.
-			note, err = s.noteMatchingTitle(link.Href, notebook)
+// completionTriggerChars returns the last characters of the link and date
+// completion triggers configured in the given notebook and its groups, if
+// any, and `@` for the citations when it has a bibliography.
+func completionTriggerChars(notebook *core.Notebook) []string {
+	chars := []string{}
+	if notebook == nil {
+		return chars
+	}
+	groups := []string{""}
+	for name := range notebook.Config.Groups {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	for _, name := range groups {
+		config, err := notebook.Config.ForGroup(name)
+		if err != nil {
+			continue
+		}
+		triggers := append([]string{config.LSP.Completion.DateTrigger}, config.LSP.Completion.TriggerStrings...)
+		for _, trigger := range triggers {
+			if trigger == "" {
+				continue
+			}
+			_, size := utf8.DecodeLastRuneInString(trigger)
+			chars = append(chars, trigger[len(trigger)-size:])
 		}
 	}
-	if note == nil || err != nil {
-		return nil, err
+	if len(notebook.Config.Bibliography.Files) > 0 {
+		chars = append(chars, "@")
 	}
-
-	joined_path := filepath.Join(notebook.Path, note.Path)
-	return &Note{*note, pathToURI(joined_path)}, nil
+	return strutil.RemoveDuplicates(chars)
 }
 
-// noteForHref returns the LSP documentUri for the note targeted by the given HREF.
-func (s *Server) noteForHref(href string, doc *document, notebook *core.Notebook) (*core.MinimalNote, error) {
-	if strutil.IsURL(href) {
-		return nil, nil
+// noteFilesGlob returns the glob pattern matching the note files of a
+// notebook, according to the extensions of its note groups.
+func noteFilesGlob(config core.Config) string {
+	exts := []string{config.Note.Extension}
+	for _, group := range config.Groups {
+		exts = append(exts, group.Note.Extension)
+	}
+	exts = strutil.RemoveDuplicates(exts)
+	sort.Strings(exts)
+	if len(exts) == 1 {
+		return "**/*." + exts[0]
 	}
+	return "**/*.{" + strings.Join(exts, ",") + "}"
+}
 
-	path := filepath.Clean(filepath.Join(filepath.Dir(doc.Path), href))
-	path, err := filepath.Rel(notebook.Path, path)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to resolve href: %s", href)
+// isNoteFile returns whether the file at path is a note of the notebook,
+// ignoring the hidden files such as the content of the .zk directory.
+func isNoteFile(notebook *core.Notebook, path string) bool {
+	rel, err := filepath.Rel(notebook.Path, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	for _, segment := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(segment, ".") {
+			return false
+		}
 	}
-	note, err := notebook.FindByHref(path, false)
+	group, err := notebook.Config.GroupConfigForPath(rel)
 	if err != nil {
-		s.logger.Printf("findByHref(%s): %s", href, err.Error())
+		return false
 	}
-	return note, err
+	return strings.TrimPrefix(filepath.Ext(rel), ".") == group.Note.Extension
 }
 
-// noteMatchingTitle returns the LSP documentUri for the note matching the given search terms.
-func (s *Server) noteMatchingTitle(terms string, notebook *core.Notebook) (*core.MinimalNote, error) {
-	if terms == "" {
-		return nil, nil
-	}
-
-	note, err := notebook.FindMatching("title:(" + terms + ")")
-	if err != nil {
-		s.logger.Printf("findMatching(title: %s): %s", terms, err.Error())
+func containsNotebook(notebooks []*core.Notebook, notebook *core.Notebook) bool {
+	for _, n := range notebooks {
+		if n == notebook {
+			return true
+		}
 	}
-	return note, err
+	return false
 }
 
-type Note struct {
-	core.MinimalNote
-	URI protocol.DocumentUri
+// dateCompletionExpressions are the natural language dates offered after
+// typing the date trigger.
+var dateCompletionExpressions = []string{
+	"today", "tomorrow", "yesterday",
+	"next monday", "next tuesday", "next wednesday", "next thursday", "next friday", "next saturday", "next sunday",
+	"last monday", "last tuesday", "last wednesday", "last thursday", "last friday", "last saturday", "last sunday",
+	"next week", "last week",
 }
 
-func (s *Server) refreshDiagnosticsOfDocument(doc *document, notify glsp.NotifyFunc, delay bool) {
-	if doc.NeedsRefreshDiagnostics { // Already refreshing
-		return
+// buildDateCompletionList completes a natural language date typed after the
+// date trigger, e.g. `@next monday`. Each date is offered as formatted text
+// and as a link to its journal note, when there is one.
+func (s *Server) buildDateCompletionList(doc *document, notebook *core.Notebook, params *protocol.CompletionParams, query string, trigger string) (*protocol.CompletionList, error) {
+	expressions := dateCompletionExpressions
+	if q := strings.TrimSpace(query); q != "" && !strutil.InList(expressions, strings.ToLower(q)) {
+		// Unknown expressions are parsed as the current date, which is
+		// already offered with "today".
+		if date, err := dateutil.TimeFromNatural(q); err == nil && !isSameDay(date, time.Now().UTC()) {
+			expressions = append([]string{q}, expressions...)
+		}
 	}
 
-	notebook, err := s.notebookOf(doc)
+	config := s.configOf(notebook, doc)
+	linkFormatter, err := notebook.NewLinkFormatterWithConfig(config.Format.Markdown)
 	if err != nil {
-		s.logger.Err(err)
-		return
-	}
-
-	diagConfig := notebook.Config.LSP.Diagnostics
-	if diagConfig.WikiTitle == core.LSPDiagnosticNone && diagConfig.DeadLink == core.LSPDiagnosticNone {
-		// No diagnostic enabled.
-		return
+		return nil, err
 	}
 
-	doc.NeedsRefreshDiagnostics = true
-	go func() {
-		if delay {
-			time.Sleep(1 * time.Second)
+	replacedRange := doc.rangeAround(params.Position, -len(trigger)-len(query), 0)
+	items := []protocol.CompletionItem{}
+	for i, expression := range expressions {
+		date, err := dateutil.TimeFromNatural(expression)
+		if err != nil {
+			continue
 		}
-		doc.NeedsRefreshDiagnostics = false
-
-		diagnostics := []protocol.Diagnostic{}
-		links, err := doc.DocumentLinks()
+		text, err := dateutil.Format(date, config.LSP.Completion.DateFormat)
 		if err != nil {
-			s.logger.Err(err)
-			return
+			return nil, err
 		}
 
-		for _, link := range links {
-			if strutil.IsURL(link.Href) {
-				continue
-			}
-			target, err := s.noteForLink(link, doc, notebook)
-			if err != nil {
-				s.logger.Err(err)
-				continue
-			}
-
-			var severity protocol.DiagnosticSeverity
-			var message string
-			if target == nil {
-				if diagConfig.DeadLink == core.LSPDiagnosticNone {
-					continue
-				}
-				severity = protocol.DiagnosticSeverity(diagConfig.DeadLink)
-				message = "not found"
-			} else {
-				if link.HasTitle || diagConfig.WikiTitle == core.LSPDiagnosticNone {
-					continue
-				}
-				severity = protocol.DiagnosticSeverity(diagConfig.WikiTitle)
-				message = target.Title
-			}
+		kind := protocol.CompletionItemKindValue
+		items = append(items, protocol.CompletionItem{
+			Label:      expression,
+			Kind:       &kind,
+			Detail:     stringPtr(text),
+			FilterText: stringPtr(trigger + expression),
+			SortText:   stringPtr(fmt.Sprintf("%03d-0", i)),
+			TextEdit:   protocol.TextEdit{Range: replacedRange, NewText: text},
+		})
 
-			diagnostics = append(diagnostics, protocol.Diagnostic{
-				Range:    link.Range,
-				Severity: &severity,
-				Source:   stringPtr("zk"),
-				Message:  message,
-			})
+		note, err := notebook.FindJournalNote(date)
+		if err != nil {
+			s.logger.Err(err)
+			continue
 		}
-
-		go notify(protocol.ServerTextDocumentPublishDiagnostics, protocol.PublishDiagnosticsParams{
-			URI:         doc.URI,
-			Diagnostics: diagnostics,
+		if note == nil {
+			continue
+		}
+		context, err := core.NewLinkFormatterContext(*note, notebook.Path, filepath.Dir(doc.Path))
+		if err != nil {
+			return nil, err
+		}
+		link, err := linkFormatter(context)
+		if err != nil {
+			return nil, err
+		}
+		linkKind := protocol.CompletionItemKindReference
+		items = append(items, protocol.CompletionItem{
+			Label:      expression + " (link)",
+			Kind:       &linkKind,
+			Detail:     stringPtr(note.Path),
+			FilterText: stringPtr(trigger + expression),
+			SortText:   stringPtr(fmt.Sprintf("%03d-1", i)),
+			TextEdit:   protocol.TextEdit{Range: replacedRange, NewText: link},
 		})
-	}()
+	}
+
+	return &protocol.CompletionList{
+		// The typed expression is offered only once it is a valid date.
+		IsIncomplete: true,
+		Items:        items,
+	}, nil
 }
 
-func (s *Server) buildTagCompletionList(notebook *core.Notebook, triggerChar string) ([]protocol.CompletionItem, error) {
+func isSameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// buildChildTagCompletionList completes the next segment of a hierarchical
+// tag, after typing its parent, e.g. `#project/`.
+func (s *Server) buildChildTagCompletionList(notebook *core.Notebook, parent string) ([]protocol.CompletionItem, error) {
 	tags, err := notebook.FindCollections(core.CollectionKindTag, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	var items []protocol.CompletionItem
-	for _, tag := range tags {
-		items = append(items, protocol.CompletionItem{
-			Label:      tag.Name,
-			InsertText: s.buildInsertForTag(tag.Name, triggerChar, notebook.Config),
-			Detail:     stringPtr(fmt.Sprintf("%d %s", tag.NoteCount, strutil.Pluralize("note", tag.NoteCount))),
-		})
+	var visit func(trees []core.TagTree)
+	visit = func(trees []core.TagTree) {
+		for _, tag := range trees {
+			if tag.Path != parent {
+				visit(tag.Children)
+				continue
+			}
+			for _, child := range tag.Children {
+				items = append(items, protocol.CompletionItem{
+					Label:      child.Name,
+					InsertText: s.buildInsertForTag(child.Name, "#", notebook.Config),
+					Detail:     stringPtr(tagIconPrefix(child.Path, notebook.Config) + child.Path + ", " + tagCompletionDetail(child)),
+				})
+			}
+		}
 	}
+	visit(core.NewTagTrees(tags))
 
 	return items, nil
 }
 
+func tagCompletionDetail(tag core.TagTree) string {
+	details := []string{}
+	if tag.NoteCount > 0 || len(tag.Children) == 0 {
+		details = append(details, fmt.Sprintf("%d %s", tag.NoteCount, strutil.Pluralize("note", tag.NoteCount)))
+	}
+	if count := len(tag.Children); count > 0 {
+		details = append(details, fmt.Sprintf("%d %s", count, strutil.Pluralize("subtag", count)))
+	}
+	return strings.Join(details, ", ")
+}
+
+// tagIconPrefix returns the icon of the given tag declared in the config,
+// followed by a space, if any.
+func tagIconPrefix(tag string, config core.Config) string {
+	if icon := config.TagStyle(tag).Icon; icon != "" {
+		return icon + " "
+	}
+	return ""
+}
+
 func (s *Server) buildInsertForTag(name string, triggerChar string, config core.Config) *string {
 	switch triggerChar {
 	case ":":
@@ -785,49 +1843,213 @@ func (s *Server) buildInsertForTag(name string, triggerChar string, config core.
 	return &name
 }
 
-func (s *Server) buildLinkCompletionList(doc *document, notebook *core.Notebook, params *protocol.CompletionParams) ([]protocol.CompletionItem, error) {
-	linkFormatter, err := newLinkFormatter(doc, notebook, params)
+// buildLinkCompletionList completes a link to a note, after typing the given
+// trigger (e.g. `[[`) followed by query. The notes are filtered against the
+// query and limited to the configured maximum number of items.
+func (s *Server) buildLinkCompletionList(doc *document, notebook *core.Notebook, params *protocol.CompletionParams, query string, trigger string) (*protocol.CompletionList, error) {
+	if notebookPath, href, ok := notebook.FederatedHref(query); ok {
+		return s.buildFederatedLinkCompletionList(doc, notebook, notebookPath, params, query, href, trigger)
+	}
+
+	config := s.configOf(notebook, doc)
+	linkFormatter, err := newLinkFormatter(notebook, config, trigger)
 	if err != nil {
 		return nil, err
 	}
 
-	templates, err := newCompletionTemplates(s.templateLoader, notebook.Config.LSP.Completion.Note)
+	templates, err := newCompletionTemplates(s.templateLoader, config.LSP.Completion.Note)
 	if err != nil {
 		return nil, err
 	}
 
-	notes, err := notebook.FindMinimalNotes(core.NoteFindOpts{})
+	notes, isIncomplete, err := s.completion.Find(notebook, query, config.LSP.Completion.MaxItems, config.LSP.Completion.IncludeArchived)
 	if err != nil {
 		return nil, err
 	}
 
+	completionTrigger := s.newCompletionTrigger(config, trigger)
 	var items []protocol.CompletionItem
 	for _, note := range notes {
-		item, err := s.newCompletionItem(notebook, note, doc, params.Position, linkFormatter, templates)
+		item, err := s.newCompletionItem(notebook, note, doc, params.Position, linkFormatter, templates, len(query), completionTrigger)
 		if err != nil {
 			s.logger.Err(err)
 			continue
 		}
+		items = append(items, item)
+		items = append(items, s.newAliasCompletionItems(notebook, note, doc, params.Position, linkFormatter, len(query), completionTrigger)...)
+	}
+
+	return &protocol.CompletionList{
+		IsIncomplete: isIncomplete,
+		Items:        items,
+	}, nil
+}
+
+// buildFederatedLinkCompletionList completes a link to a note of another
+// notebook, after typing its prefix, e.g. `[[work:`. The notes are filtered
+// against the href typed after the prefix.
+func (s *Server) buildFederatedLinkCompletionList(doc *document, notebook *core.Notebook, otherPath string, params *protocol.CompletionParams, query string, href string, trigger string) (*protocol.CompletionList, error) {
+	other, err := s.notebooks.Open(otherPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := s.configOf(notebook, doc)
+	prefix := strings.TrimSuffix(query, href)
+	linkFormatter := func(context core.LinkFormatterContext) (string, error) {
+		path := context.Path
+		if config.Format.Markdown.LinkDropExtension {
+			path = strings.TrimSuffix(path, filepath.Ext(path))
+		}
+		if trigger == "]((" {
+			return fmt.Sprintf("[%s](%s%s)", context.Title, prefix, path), nil
+		}
+		return "[[" + prefix + path + "]]", nil
+	}
+
+	templates, err := newCompletionTemplates(s.templateLoader, config.LSP.Completion.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, isIncomplete, err := s.completion.Find(other, href, config.LSP.Completion.MaxItems, config.LSP.Completion.IncludeArchived)
+	if err != nil {
+		return nil, err
+	}
 
+	completionTrigger := s.newCompletionTrigger(config, trigger)
+	var items []protocol.CompletionItem
+	for _, note := range notes {
+		item, err := s.newCompletionItem(other, note, doc, params.Position, linkFormatter, templates, len(query), completionTrigger)
+		if err != nil {
+			s.logger.Err(err)
+			continue
+		}
+		// The editor filters the items with the prefix typed in the
+		// document.
+		item.FilterText = stringPtr(prefix + *item.FilterText)
 		items = append(items, item)
 	}
 
+	return &protocol.CompletionList{
+		IsIncomplete: isIncomplete,
+		Items:        items,
+	}, nil
+}
+
+// buildMarkdownLinkCompletionList completes the destination of a regular
+// Markdown link, e.g. `[text](`, using the link text to search the note
+// titles.
+func (s *Server) buildMarkdownLinkCompletionList(doc *document, notebook *core.Notebook, params *protocol.CompletionParams, text string) ([]protocol.CompletionItem, error) {
+	// Paths must always be encoded in a Markdown link destination, to
+	// support spaces.
+	config := s.configOf(notebook, doc)
+	markdownConfig := config.Format.Markdown
+	markdownConfig.LinkEncodePath = true
+	linkFormatter, err := core.NewMarkdownLinkFormatter(markdownConfig, true)
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := newCompletionTemplates(s.templateLoader, config.LSP.Completion.Note)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []core.MinimalNote
+	if terms := strings.TrimSpace(text); terms != "" {
+		notes, err = notebook.FindMinimalNotes(core.NoteFindOpts{
+			Match:           opt.NewString("title:(" + terms + ")"),
+			Trash:           core.TrashFilterExclude,
+			ExcludeArchived: !config.LSP.Completion.IncludeArchived,
+		})
+		// An invalid search query is expected while typing, so we fallback
+		// on all the notes.
+		s.logger.Err(err)
+	}
+	if len(notes) == 0 {
+		notes, err = notebook.FindMinimalNotes(core.NoteFindOpts{
+			Trash:           core.TrashFilterExclude,
+			ExcludeArchived: !config.LSP.Completion.IncludeArchived,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Only the opening parenthesis of the destination needs to be replaced.
+	return s.buildNoteCompletionItems(notebook, notes, doc, params.Position, linkFormatter, templates, s.newCompletionTrigger(config, "(")), nil
+}
+
+// buildAssetCompletionList completes the destination of an image link, e.g.
+// `![alt](`, with the paths of the notebook assets.
+func (s *Server) buildAssetCompletionList(doc *document, notebook *core.Notebook) ([]protocol.CompletionItem, error) {
+	assets, err := notebook.FindAssets()
+	if err != nil {
+		return nil, err
+	}
+
+	kind := protocol.CompletionItemKindFile
+	var items []protocol.CompletionItem
+	for _, asset := range assets {
+		path, err := filepath.Rel(filepath.Dir(doc.Path), filepath.Join(notebook.Path, asset.Path))
+		if err != nil {
+			s.logger.Err(err)
+			continue
+		}
+		// Paths in Markdown links are percent-encoded.
+		path = strings.ReplaceAll(url.PathEscape(filepath.ToSlash(path)), "%2F", "/")
+
+		items = append(items, protocol.CompletionItem{
+			Label:      asset.Path,
+			Kind:       &kind,
+			InsertText: &path,
+		})
+	}
+
 	return items, nil
 }
 
-func newLinkFormatter(doc *document, notebook *core.Notebook, params *protocol.CompletionParams) (core.LinkFormatter, error) {
-	if doc.LookBehind(params.Position, 3) == "]((" {
-		return core.NewMarkdownLinkFormatter(notebook.Config.Format.Markdown, true)
+// buildNoteCompletionItems creates the completion items to insert a link to
+// the given notes. The trigger typed before the position is replaced by the
+// link.
+func (s *Server) buildNoteCompletionItems(notebook *core.Notebook, notes []core.MinimalNote, doc *document, pos protocol.Position, linkFormatter core.LinkFormatter, templates completionTemplates, trigger completionTrigger) []protocol.CompletionItem {
+	var items []protocol.CompletionItem
+	for _, note := range notes {
+		item, err := s.newCompletionItem(notebook, note, doc, pos, linkFormatter, templates, 0, trigger)
+		if err != nil {
+			s.logger.Err(err)
+			continue
+		}
+
+		items = append(items, item)
+		items = append(items, s.newAliasCompletionItems(notebook, note, doc, pos, linkFormatter, 0, trigger)...)
+	}
+
+	return items
+}
+
+func newLinkFormatter(notebook *core.Notebook, config core.Config, trigger string) (core.LinkFormatter, error) {
+	if trigger == "]((" {
+		return core.NewMarkdownLinkFormatter(config.Format.Markdown, true)
+	} else if config.Format.Markdown.LinkFormat == "wiki" && config.LSP.Completion.WikiTitle {
+		return core.NewTitledWikiLinkFormatter(config.Format.Markdown)
 	} else {
-		return notebook.NewLinkFormatter()
+		return notebook.NewLinkFormatterWithConfig(config.Format.Markdown)
 	}
 }
 
-func (s *Server) newCompletionItem(notebook *core.Notebook, note core.MinimalNote, doc *document, pos protocol.Position, linkFormatter core.LinkFormatter, templates completionTemplates) (protocol.CompletionItem, error) {
+// newCompletionItem creates a completion item inserting a link to note.
+// queryLength is the number of bytes typed after the trigger, which are
+// replaced by the link as well.
+func (s *Server) newCompletionItem(notebook *core.Notebook, note core.MinimalNote, doc *document, pos protocol.Position, linkFormatter core.LinkFormatter, templates completionTemplates, queryLength int, trigger completionTrigger) (protocol.CompletionItem, error) {
 	kind := protocol.CompletionItemKindReference
 	item := protocol.CompletionItem{
 		Kind: &kind,
-		Data: filepath.Join(notebook.Path, note.Path),
+		Data: completionItemData{
+			Path:    filepath.Join(notebook.Path, note.Path),
+			DocPath: doc.Path,
+		},
 	}
 
 	templateContext, err := newCompletionItemRenderContext(note, notebook.Path, doc.Path)
@@ -860,36 +2082,95 @@ func (s *Server) newCompletionItem(notebook *core.Notebook, note core.MinimalNot
 		item.FilterText = stringPtr(item.Label + " " + note.Path)
 	}
 
-	if templates.Detail != nil {
-		detail, err := templates.Detail.Render(templateContext)
-		if err != nil {
-			return item, err
-		}
-		item.Detail = &detail
+	// Give some context beyond the title, unless a custom detail is rendered
+	// when the item is resolved.
+	if note.Summary != "" && templates.Detail == nil {
+		item.Detail = stringPtr(note.Summary)
 	}
 
-	item.TextEdit, err = s.newTextEditForLink(notebook, note, doc, pos, linkFormatter)
+	item.TextEdit, err = s.newTextEditForLink(notebook, note, doc, pos, queryLength, trigger, linkFormatter)
 	if err != nil {
 		err = errors.Wrapf(err, "failed to build TextEdit for note at %s", note.Path)
 		return item, err
 	}
 
-	addTextEdits := []protocol.TextEdit{}
-
 	// Some LSP clients (e.g. VSCode) don't support deleting the trigger
 	// characters with the main TextEdit. So let's add an additional
 	// TextEdit for that.
-	addTextEdits = append(addTextEdits, protocol.TextEdit{
-		NewText: "",
-		Range:   rangeFromPosition(pos, -2, 0),
-	})
-
-	item.AdditionalTextEdits = addTextEdits
+	if trigger.AdditionalTextEdit {
+		item.AdditionalTextEdits = []protocol.TextEdit{{
+			NewText: "",
+			Range:   doc.rangeAround(pos, -(queryLength + len(trigger.Text)), -queryLength),
+		}}
+	}
 
 	return item, nil
 }
 
-func (s *Server) newTextEditForLink(notebook *core.Notebook, note core.MinimalNote, doc *document, pos protocol.Position, linkFormatter core.LinkFormatter) (interface{}, error) {
+// newAliasCompletionItems creates an additional completion item for each
+// alias of note, inserting a link titled with the alias.
+func (s *Server) newAliasCompletionItems(notebook *core.Notebook, note core.MinimalNote, doc *document, pos protocol.Position, linkFormatter core.LinkFormatter, queryLength int, trigger completionTrigger) []protocol.CompletionItem {
+	name := note.Title
+	if name == "" {
+		name = note.Path
+	}
+
+	var items []protocol.CompletionItem
+	for _, alias := range note.Aliases() {
+		aliasNote := note
+		aliasNote.Title = alias
+		item, err := s.newCompletionItem(notebook, aliasNote, doc, pos, linkFormatter, completionTemplates{}, queryLength, trigger)
+		if err != nil {
+			s.logger.Err(err)
+			continue
+		}
+		item.Detail = stringPtr("alias of " + name)
+		items = append(items, item)
+	}
+	return items
+}
+
+// resolveCompletionItem renders the properties of a note completion item
+// which are only needed when the item is selected, to keep the completion
+// list cheap to build in large notebooks.
+func (s *Server) resolveCompletionItem(item *protocol.CompletionItem, data completionItemData) error {
+	notebook, err := s.notebooks.Open(data.Path)
+	if err != nil {
+		return err
+	}
+
+	content, err := notebook.ReadFile(data.Path)
+	if err != nil {
+		return err
+	}
+	item.Documentation = noteMarkupContent(s.markup.documentation, string(content))
+
+	config := configForPath(notebook, data.DocPath, s.logger)
+	templates, err := newCompletionTemplates(s.templateLoader, config.LSP.Completion.Note)
+	if err != nil || templates.Detail == nil {
+		return err
+	}
+	relPath, err := notebook.RelPath(data.Path)
+	if err != nil {
+		return err
+	}
+	note, err := notebook.FindByHref(relPath, false)
+	if note == nil || err != nil {
+		return err
+	}
+	templateContext, err := newCompletionItemRenderContext(*note, notebook.Path, data.DocPath)
+	if err != nil {
+		return err
+	}
+	detail, err := templates.Detail.Render(templateContext)
+	if err != nil {
+		return err
+	}
+	item.Detail = &detail
+	return nil
+}
+
+func (s *Server) newTextEditForLink(notebook *core.Notebook, note core.MinimalNote, doc *document, pos protocol.Position, queryLength int, trigger completionTrigger, linkFormatter core.LinkFormatter) (interface{}, error) {
 	currentDir := filepath.Dir(doc.Path)
 	context, err := core.NewLinkFormatterContext(note, notebook.Path, currentDir)
 	if err != nil {
@@ -901,42 +2182,23 @@ func (s *Server) newTextEditForLink(notebook *core.Notebook, note core.MinimalNo
 	}
 
 	// Some LSP clients (e.g. VSCode) auto-pair brackets, so we need to
-	// remove the closing ]] or )) after the completion.
+	// remove the brackets closing the trigger after the completion.
 	endOffset := 0
-	suffix := doc.LookForward(pos, 2)
-	if suffix == "]]" || suffix == "))" {
-		endOffset = 2
+	if closing := trigger.ClosingBrackets(); closing != "" && doc.LookForward(pos, len(closing)) == closing {
+		endOffset = len(closing)
+	}
+
+	startOffset := -queryLength
+	if !trigger.AdditionalTextEdit {
+		startOffset -= len(trigger.Text)
 	}
 
 	return protocol.TextEdit{
 		NewText: link,
-		Range:   rangeFromPosition(pos, 0, endOffset),
+		Range:   doc.rangeAround(pos, startOffset, endOffset),
 	}, nil
 }
 
-func positionInRange(content string, rng protocol.Range, pos protocol.Position) bool {
-	start, end := rng.IndexesIn(content)
-	i := pos.IndexIn(content)
-	return i >= start && i <= end
-}
-
-func rangeFromPosition(pos protocol.Position, startOffset, endOffset int) protocol.Range {
-	offsetPos := func(offset int) protocol.Position {
-		newPos := pos
-		if offset < 0 {
-			newPos.Character -= uint32(-offset)
-		} else {
-			newPos.Character += uint32(offset)
-		}
-		return newPos
-	}
-
-	return protocol.Range{
-		Start: offsetPos(startOffset),
-		End:   offsetPos(endOffset),
-	}
-}
-
 func isRangeEmpty(pos protocol.Range) bool {
 	return pos.Start == pos.End
 }
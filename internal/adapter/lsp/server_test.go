@@ -0,0 +1,40 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/adapter/fs"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+// newTestNotebook creates a notebook at the given path, without any index.
+func newTestNotebook(t *testing.T, path string) *core.Notebook {
+	fs, err := fs.NewFileStorage(path, &util.NullLogger)
+	assert.Nil(t, err)
+	return core.NewNotebook(path, core.NewDefaultConfig(), core.NotebookPorts{
+		FS:     fs,
+		Logger: &util.NullLogger,
+	})
+}
+
+func TestNotebookPathsOf(t *testing.T) {
+	notebook := newTestNotebook(t, "/notebook")
+
+	assert.Equal(t, notebookPathsOf(notebook, []string{}), []string{})
+	assert.Equal(t,
+		notebookPathsOf(notebook, []string{
+			"file:///notebook/a.md",
+			"file:///notebook/dir/my%20note.md",
+			"file:///other/b.md",
+			"https://example.com/c.md",
+		}),
+		[]string{"a.md", "dir/my note.md"},
+	)
+}
+
+func TestDeletedNoteWarning(t *testing.T) {
+	assert.Equal(t, deletedNoteWarning("a.md", []string{"b.md"}), "a.md is still linked from 1 note: b.md")
+	assert.Equal(t, deletedNoteWarning("a.md", []string{"b.md", "dir/c.md"}), "a.md is still linked from 2 notes: b.md, dir/c.md")
+}
@@ -0,0 +1,70 @@
+package lsp
+
+import (
+	"fmt"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+const cmdTagList = "zk.tag.list"
+
+type cmdTagListOpts struct {
+	Sort []string `json:"sort,omitempty"`
+}
+
+// tagItem is a tag returned by the zk.tag.list command.
+type tagItem struct {
+	Name      string `json:"name"`
+	NoteCount int    `json:"noteCount"`
+	Color     string `json:"color,omitempty"`
+	Icon      string `json:"icon,omitempty"`
+}
+
+// executeCommandTagList lists the tags of the notebook with their style, for
+// example to display them in a picker.
+func (s *Server) executeCommandTagList(args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("zk.tag.list expects a notebook path as first argument")
+	}
+	wd, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("zk.tag.list expects a notebook path as first argument, got: %v", args[0])
+	}
+
+	var opts cmdTagListOpts
+	if len(args) > 1 {
+		arg, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("zk.tag.list expects a dictionary of options as second argument, got: %v", args[1])
+		}
+		err := unmarshalJSON(arg, &opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse zk.tag.list args, got: %v", arg)
+		}
+	}
+
+	notebook, err := s.notebooks.Open(wd)
+	if err != nil {
+		return nil, err
+	}
+	sorters, err := core.CollectionSortersFromStrings(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := notebook.FindCollections(core.CollectionKindTag, sorters)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []tagItem{}
+	for _, tag := range tags {
+		items = append(items, tagItem{
+			Name:      tag.Name,
+			NoteCount: tag.NoteCount,
+			Color:     tag.Color,
+			Icon:      tag.Icon,
+		})
+	}
+	return items, nil
+}
@@ -0,0 +1,170 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// documentTask is a checkbox list item found in a document.
+type documentTask struct {
+	core.Task
+	// Range of the whole line of the task.
+	Range protocol.Range
+	// Range of the checkbox mark, e.g. the `x` of `- [x] Done`.
+	CheckboxRange protocol.Range
+}
+
+// Tasks returns the checkbox list items of the document, outside of the
+// frontmatter and code blocks.
+func (d *document) Tasks() []documentTask {
+	tasks := []documentTask{}
+
+	lines := d.GetLines()
+	for i := frontmatterEnd(lines); i < len(lines); i++ {
+		line := lines[i]
+
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			fence := match[1]
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+					break
+				}
+			}
+			continue
+		}
+
+		task, ok := core.ParseTask(line)
+		if !ok {
+			continue
+		}
+		task.Line = i + 1
+		checkbox := core.TaskCheckboxIndex(line)
+		tasks = append(tasks, documentTask{
+			Task:          task,
+			Range:         d.rangeAt(i, 0, len(strings.TrimRight(line, "\r"))),
+			CheckboxRange: d.rangeAt(i, checkbox, checkbox+1),
+		})
+	}
+
+	return tasks
+}
+
+// TaskSymbols returns the tasks of the document as symbols, e.g. to list
+// them in the outline of the editor.
+func (d *document) TaskSymbols() []protocol.DocumentSymbol {
+	symbols := []protocol.DocumentSymbol{}
+	for _, task := range d.Tasks() {
+		check := " "
+		if task.Done {
+			check = "x"
+		}
+		symbol := protocol.DocumentSymbol{
+			Name:           fmt.Sprintf("[%s] %s", check, task.Text),
+			Kind:           protocol.SymbolKindBoolean,
+			Range:          task.Range,
+			SelectionRange: task.Range,
+		}
+		if task.Due != nil {
+			symbol.Detail = stringPtr("due " + task.Due.Format("2006-01-02"))
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+const cmdTaskToggle = "zk.task.toggle"
+
+type cmdTaskToggleOpts struct {
+	Location protocol.Location `json:"location"`
+	DoneDate bool              `json:"doneDate,omitempty"`
+	DryRun   bool              `json:"dryRun,omitempty"`
+}
+
+// executeCommandTaskToggle checks or unchecks the task found on the line of
+// the given location.
+//
+// The edit is applied by the editor when the note is opened, and indexed
+// when it is saved. Otherwise, the note is modified on disk and indexed
+// right away, e.g. when toggling a task from a list of the notebook tasks.
+func (s *Server) executeCommandTaskToggle(context *glsp.Context, args []interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("zk.task.toggle expects a notebook path and a dictionary of options as arguments")
+	}
+	arg, ok := args[1].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("zk.task.toggle expects a dictionary of options as second argument, got: %v", args[1])
+	}
+	var opts cmdTaskToggleOpts
+	err := unmarshalJSON(arg, &opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse zk.task.toggle args, got: %v", arg)
+	}
+
+	_, opened := s.documents.Get(opts.Location.URI)
+	doc, err := s.documentAt(opts.Location.URI)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't toggle the task in %s", opts.Location.URI)
+	}
+	var task *documentTask
+	for _, t := range doc.Tasks() {
+		if t.Range.Start.Line == opts.Location.Range.Start.Line {
+			task = &t
+			break
+		}
+	}
+	if task == nil {
+		return nil, fmt.Errorf("no task found at the given location")
+	}
+	if !opts.DryRun {
+		notebook, err := s.notebookOf(doc)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkWritable(notebook, cmdTaskToggle); err != nil {
+			return nil, err
+		}
+	}
+
+	var doneDate *time.Time
+	if opts.DoneDate {
+		now := time.Now()
+		doneDate = &now
+	}
+	line, _ := doc.GetLine(int(task.Range.Start.Line))
+	newLine, _ := core.ToggleTaskLine(strings.TrimRight(line, "\r"), doneDate)
+	edit := protocol.TextEdit{Range: task.Range, NewText: newLine}
+
+	switch {
+	case opts.DryRun:
+	case opened:
+		go context.Call(protocol.ServerWorkspaceApplyEdit, protocol.ApplyWorkspaceEditParams{
+			Edit: protocol.WorkspaceEdit{
+				Changes: map[string][]protocol.TextEdit{doc.URI: {edit}},
+			},
+		}, nil)
+	default:
+		notebook, err := s.notebookOf(doc)
+		if err != nil {
+			return nil, err
+		}
+		path, err := notebook.RelPath(doc.Path)
+		if err != nil {
+			return nil, err
+		}
+		_, err = notebook.ToggleTask(path, task.Line, core.TaskToggleOpts{
+			DoneDate: doneDate,
+			Origin:   lspOrigin(cmdTaskToggle),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]interface{}{"done": !task.Done, "edit": edit}, nil
+}
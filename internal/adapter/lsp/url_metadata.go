@@ -0,0 +1,172 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+const (
+	// Delay between two URL fetches, to avoid hammering the web servers and
+	// slowing down the interactive requests.
+	urlFetchDelay = 2 * time.Second
+	// Delay before checking again for unfetched URLs, when there are none
+	// left.
+	urlIdleDelay = 10 * time.Minute
+)
+
+// urlMetadataJob fetches in the background the metadata of the external URLs
+// linked in the notebooks opened in the editor.
+type urlMetadataJob struct {
+	fetch  core.URLMetadataFetcher
+	logger util.Logger
+
+	mutex     sync.Mutex
+	notebooks map[string]*core.Notebook
+	started   bool
+	wake      chan struct{}
+}
+
+func newURLMetadataJob(fetch core.URLMetadataFetcher, logger util.Logger) *urlMetadataJob {
+	return &urlMetadataJob{
+		fetch:     fetch,
+		logger:    logger,
+		notebooks: map[string]*core.Notebook{},
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Watch adds the given notebook to the fetched ones, if enabled in its
+// configuration. The job is started with the first watched notebook.
+func (j *urlMetadataJob) Watch(notebook *core.Notebook) {
+	if j.fetch == nil || !notebook.Config.LSP.Links.FetchURLMetadata {
+		return
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if _, ok := j.notebooks[notebook.Path]; ok {
+		return
+	}
+	j.notebooks[notebook.Path] = notebook
+
+	if !j.started {
+		j.started = true
+		go j.run()
+	}
+	j.Wake()
+}
+
+// Wake resumes an idle job, e.g. when new links might have been added.
+func (j *urlMetadataJob) Wake() {
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (j *urlMetadataJob) run() {
+	for {
+		fetched := 0
+		for _, notebook := range j.watchedNotebooks() {
+			count, err := notebook.FetchURLMetadata(j.fetch, 1)
+			if err != nil {
+				j.logger.Err(err)
+			}
+			fetched += count
+		}
+
+		if fetched > 0 {
+			time.Sleep(urlFetchDelay)
+		} else {
+			select {
+			case <-j.wake:
+			case <-time.After(urlIdleDelay):
+			}
+		}
+	}
+}
+
+func (j *urlMetadataJob) watchedNotebooks() []*core.Notebook {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	notebooks := []*core.Notebook{}
+	for _, notebook := range j.notebooks {
+		notebooks = append(notebooks, notebook)
+	}
+	return notebooks
+}
+
+// hoverForURL returns the hover content of an external link in the given
+// format, from its cached metadata.
+func hoverForURL(url string, notebook *core.Notebook, kind protocol.MarkupKind) (string, error) {
+	metadata, err := notebook.FindURLMetadata(url)
+	if metadata == nil || err != nil {
+		return "", err
+	}
+
+	markdown := kind == protocol.MarkupKindMarkdown
+	content := ""
+	if metadata.Title != "" {
+		if markdown {
+			content += fmt.Sprintf("**%s**\n\n", escapeMarkdown(metadata.Title))
+		} else {
+			content += metadata.Title + "\n\n"
+		}
+	}
+	if markdown {
+		content += "<" + url + ">"
+	} else {
+		content += url
+	}
+	if metadata.IsDead() {
+		content += fmt.Sprintf("\n\n⚠️ This link is dead (%s), as of %s.", metadata.StatusText(), metadata.Fetched.Format("2006-01-02"))
+		if metadata.Archive != "" {
+			if markdown {
+				content += fmt.Sprintf(" [Archived version](%s)", metadata.Archive)
+			} else {
+				content += " Archived version: " + metadata.Archive
+			}
+		}
+	}
+	return content, nil
+}
+
+// archivedURLCodeAction returns a quick fix replacing the dead external link
+// at the given position with its archived version, if there is one.
+func archivedURLCodeAction(doc *document, pos protocol.Position, notebook *core.Notebook) (*protocol.CodeAction, error) {
+	link, err := doc.DocumentLinkAt(pos)
+	if link == nil || err != nil || !strutil.IsURL(link.Href) {
+		return nil, err
+	}
+	metadata, err := notebook.FindURLMetadata(link.Href)
+	if metadata == nil || err != nil || !metadata.IsDead() || metadata.Archive == "" {
+		return nil, err
+	}
+
+	// The link range covers the whole link, including its label.
+	content := doc.ContentAtRange(link.Range)
+	index := strings.LastIndex(content, link.Href)
+	if index == -1 {
+		return nil, nil
+	}
+	newContent := content[:index] + metadata.Archive + content[index+len(link.Href):]
+
+	return &protocol.CodeAction{
+		Title: "Replace with the archived version",
+		Kind:  stringPtr(protocol.CodeActionKindQuickFix),
+		Edit: &protocol.WorkspaceEdit{
+			Changes: map[string][]protocol.TextEdit{
+				doc.URI: {{Range: link.Range, NewText: newContent}},
+			},
+		},
+	}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/mickael-menu/zk/internal/core"
 	"github.com/mickael-menu/zk/internal/util/errors"
 )
 
@@ -41,3 +42,29 @@ func (b *jsonBoolean) UnmarshalJSON(data []byte) error {
 	}
 	return nil
 }
+
+// userError converts the errors reported to the editor into messages
+// actionable by the user, without the details of the failing operation.
+func userError(err error) error {
+	var (
+		notebookNotFound core.ErrNotebookNotFound
+		noteNotFound     core.ErrNoteNotFound
+		indexLocked      core.ErrIndexLocked
+		templateErr      core.ErrTemplate
+	)
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &notebookNotFound):
+		return fmt.Errorf("%v, create one with `zk init`", notebookNotFound)
+	case errors.As(err, &noteNotFound):
+		return noteNotFound
+	case errors.As(err, &indexLocked):
+		return indexLocked
+	case errors.As(err, &templateErr):
+		return fmt.Errorf("invalid template: %v", templateErr.Err)
+	default:
+		return err
+	}
+}
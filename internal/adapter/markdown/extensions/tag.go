@@ -3,6 +3,7 @@ package extensions
 import (
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
@@ -94,8 +95,8 @@ func (p *hashtagParser) Parse(parent ast.Node, block text.Reader, pc parser.Cont
 	var (
 		escaping            = false // Found a backslash, next character will be literal
 		parsingMultiWordTag = false // Finished parsing a hashtag, now attempt parsing a Bear multi-word tag
-		endPos              = 0     // Last position of the tag in the line
-		multiWordTagEndPos  = 0     // Last position of the multi-word tag in the line
+		endPos              = 0     // End position of the tag in the line
+		multiWordTagEndPos  = 0     // End position of the multi-word tag in the line
 	)
 
 	appendChar := func(c rune) {
@@ -107,15 +108,19 @@ func (p *hashtagParser) Parse(parent ast.Node, block text.Reader, pc parser.Cont
 	}
 
 	for i, char := range string(line[1:]) {
+		// The characters are indexed after the leading #.
 		if parsingMultiWordTag {
-			multiWordTagEndPos = i
+			multiWordTagEndPos = i + 2
 		} else {
-			endPos = i
+			endPos = i + 1
 		}
 
 		if escaping {
 			// Currently escaping? The character will be appended literally.
 			appendChar(char)
+			if !parsingMultiWordTag {
+				endPos += utf8.RuneLen(char)
+			}
 			escaping = false
 
 		} else if char == '\\' {
@@ -153,6 +158,7 @@ func (p *hashtagParser) Parse(parent ast.Node, block text.Reader, pc parser.Cont
 
 		} else {
 			appendChar(char)
+			endPos += utf8.RuneLen(char)
 		}
 	}
 
@@ -201,7 +207,7 @@ func (p *colontagParser) Parse(parent ast.Node, block text.Reader, pc parser.Con
 
 	var (
 		escaping = false // Found a backslash, next character will be literal
-		endPos   = 0     // Last position of the colontags in the line
+		endPos   = 0     // End position of the colontags in the line
 	)
 
 	appendChar := func(c rune) {
@@ -209,8 +215,6 @@ func (p *colontagParser) Parse(parent ast.Node, block text.Reader, pc parser.Con
 	}
 
 	for i, char := range string(line[1:]) {
-		endPos = i
-
 		if escaping {
 			// Currently escaping? The character will be appended literally.
 			appendChar(char)
@@ -227,6 +231,8 @@ func (p *colontagParser) Parse(parent ast.Node, block text.Reader, pc parser.Con
 			}
 			tags = append(tags, tag)
 			tag = ""
+			// The characters are indexed after the leading :.
+			endPos = i + 2
 
 		} else if !isValidTagChar(char, ':') {
 			// Found an invalid character, the colontag is complete.
@@ -13,28 +13,36 @@ import (
 
 // WikiLinkExt is an extension parsing wiki links and Neuron's Folgezettel.
 //
-// For example, [[wiki link]], [[[legacy downlink]]], #[[uplink]], [[downlink]]#.
-var WikiLinkExt = &wikiLink{}
-
-type wikiLink struct{}
+// For example, [[wiki link]], [[[legacy downlink]]], #[[uplink]], [[downlink]]#,
+// ![[embedded note]] or [[target|alias]].
+type WikiLinkExt struct {
+	// Indicates whether the alias of a wiki link comes before its target,
+	// e.g. [[alias|target]].
+	AliasFirst bool
+}
 
 // WikiLink represents a wiki link found in a Markdown document.
 type WikiLink struct {
 	ast.Link
+	// Embed indicates whether the target is embedded in the document, e.g.
+	// ![[note]].
+	Embed bool
 }
 
-func (w *wikiLink) Extend(m goldmark.Markdown) {
+func (w *WikiLinkExt) Extend(m goldmark.Markdown) {
 	m.Parser().AddOptions(
 		parser.WithInlineParsers(
-			util.Prioritized(&wlParser{}, 199),
+			util.Prioritized(&wlParser{aliasFirst: w.AliasFirst}, 199),
 		),
 	)
 }
 
-type wlParser struct{}
+type wlParser struct {
+	aliasFirst bool
+}
 
 func (p *wlParser) Trigger() []byte {
-	return []byte{'[', '#'}
+	return []byte{'[', '#', '!'}
 }
 
 func (p *wlParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
@@ -44,6 +52,7 @@ func (p *wlParser) Parse(parent ast.Node, block text.Reader, pc parser.Context)
 		href  string
 		label string
 		rel   core.LinkRelation
+		embed bool
 	)
 
 	var (
@@ -53,7 +62,7 @@ func (p *wlParser) Parse(parent ast.Node, block text.Reader, pc parser.Context)
 		parsingLabel    = false // Found a | in a Wikilink, now we parse the link's label
 		openerCharCount = 0     // Number of [ encountered
 		closerCharCount = 0     // Number of ] encountered
-		endPos          = 0     // Last position of the link in the line
+		endPos          = 0     // End position of the link in the line
 	)
 
 	appendRune := func(c rune) {
@@ -65,12 +74,11 @@ func (p *wlParser) Parse(parent ast.Node, block text.Reader, pc parser.Context)
 	}
 
 	for i, char := range string(line) {
-		endPos = i
-
 		if closed {
 			// Supports trailing hash syntax for Neuron's Folgezettel, e.g. [[id]]#
 			if char == '#' {
 				rel = core.LinkRelationDown
+				endPos = i + 1
 			}
 			break
 		}
@@ -81,6 +89,13 @@ func (p *wlParser) Parse(parent ast.Node, block text.Reader, pc parser.Context)
 			case '#':
 				rel = core.LinkRelationUp
 				continue
+			// Supports embedded wiki links, e.g. ![[note]]
+			case '!':
+				if i > 0 {
+					return nil
+				}
+				embed = true
+				continue
 			case '[':
 				openerCharCount += 1
 				continue
@@ -92,13 +107,16 @@ func (p *wlParser) Parse(parent ast.Node, block text.Reader, pc parser.Context)
 		}
 		opened = true
 
+		// The pipe is escaped in Markdown tables, e.g. [[href\|label]].
+		if char == '|' && !parsingLabel {
+			parsingLabel = true
+			escaping = false
+			continue
+		}
+
 		if !escaping {
 			switch char {
 
-			case '|': // [[href | label]]
-				parsingLabel = true
-				continue
-
 			case '\\':
 				escaping = true
 				continue
@@ -107,6 +125,7 @@ func (p *wlParser) Parse(parent ast.Node, block text.Reader, pc parser.Context)
 				closerCharCount += 1
 				if closerCharCount == openerCharCount {
 					closed = true
+					endPos = i + 1
 					// Neuron's legacy [[[Folgezettel]]].
 					if closerCharCount == 3 {
 						rel = core.LinkRelationDown
@@ -128,6 +147,9 @@ func (p *wlParser) Parse(parent ast.Node, block text.Reader, pc parser.Context)
 		appendRune(char)
 	}
 
+	if p.aliasFirst && parsingLabel {
+		href, label = label, href
+	}
 	if !closed || len(href) == 0 {
 		return nil
 	}
@@ -140,7 +162,7 @@ func (p *wlParser) Parse(parent ast.Node, block text.Reader, pc parser.Context)
 		label = href
 	}
 
-	link := &WikiLink{Link: *ast.NewLink()}
+	link := &WikiLink{Link: *ast.NewLink(), Embed: embed}
 	link.Destination = []byte(href)
 	// Title will be parsed as the link's rel by the Markdown parser.
 	link.Title = []byte(rel)
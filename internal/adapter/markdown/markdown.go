@@ -35,6 +35,8 @@ type ParserOpts struct {
 	MultiWordTagEnabled bool
 	// Indicates whether :colon:tags: are parsed.
 	ColontagEnabled bool
+	// Indicates whether the alias of a wiki link comes before its target.
+	WikiLinkAliasFirst bool
 }
 
 // NewParser creates a new Markdown Parser.
@@ -52,7 +54,9 @@ func NewParser(options ParserOpts, logger util.Logger) *Parser {
 						xurls.Strict,
 					),
 				),
-				extensions.WikiLinkExt,
+				&extensions.WikiLinkExt{
+					AliasFirst: options.WikiLinkAliasFirst,
+				},
 				&extensions.TagExt{
 					HashtagEnabled:      options.HashtagEnabled,
 					MultiWordTagEnabled: options.MultiWordTagEnabled,
@@ -101,6 +105,7 @@ func (p *Parser) ParseNoteContent(content string) (*core.NoteContent, error) {
 		Lead:     parseLead(body),
 		Links:    links,
 		Tags:     tags,
+		Tasks:    parseTasks(root, bytes),
 		Metadata: frontmatter.values,
 	}, nil
 }
@@ -234,6 +239,23 @@ func (p *Parser) parseLinks(root ast.Node, source []byte) ([]core.Link, error) {
 					})
 				}
 
+			case *ast.Image:
+				// Images are indexed to know which notes are using an asset.
+				href, err := url.PathUnescape(string(link.Destination))
+				p.logger.Err(err)
+				if href != "" {
+					snippet, snStart, snEnd := extractLines(n, source)
+					links = append(links, core.Link{
+						Title:        string(link.Text(source)),
+						Href:         href,
+						Rels:         core.LinkRels(strings.Fields(string(link.Title))...),
+						IsExternal:   strutil.IsURL(href),
+						Snippet:      snippet,
+						SnippetStart: snStart,
+						SnippetEnd:   snEnd,
+					})
+				}
+
 			case *ast.AutoLink:
 				if href := string(link.URL(source)); href != "" && link.AutoLinkType == ast.AutoLinkURL {
 					snippet, snStart, snEnd := extractLines(n, source)
@@ -269,6 +291,37 @@ func (p *Parser) parseLinks(root ast.Node, source []byte) ([]core.Link, error) {
 	return links, err
 }
 
+// parseTasks extracts the checkbox items of the lists, e.g. `- [ ] Call Bob`.
+func parseTasks(root ast.Node, source []byte) []core.Task {
+	tasks := make([]core.Task, 0)
+
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Kind() != ast.KindListItem || n.FirstChild() == nil {
+			return ast.WalkContinue, nil
+		}
+		lines := n.FirstChild().Lines()
+		if lines.Len() == 0 {
+			return ast.WalkContinue, nil
+		}
+
+		// The list marker is not part of the item content.
+		start := lines.At(0).Start
+		for start > 0 && source[start-1] != '\n' {
+			start--
+		}
+		end := lines.At(0).Stop
+		line := strings.TrimRight(string(source[start:end]), "\n")
+
+		if task, ok := core.ParseTask(line); ok {
+			task.Line = strings.Count(string(source[:start]), "\n") + 1
+			tasks = append(tasks, task)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return tasks
+}
+
 func extractLines(n ast.Node, source []byte) (content string, start, end int) {
 	if n == nil {
 		return
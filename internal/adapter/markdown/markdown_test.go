@@ -2,6 +2,7 @@ package markdown
 
 import (
 	"testing"
+	"time"
 
 	"github.com/mickael-menu/zk/internal/core"
 	"github.com/mickael-menu/zk/internal/util"
@@ -573,6 +574,79 @@ A link can have [one relation](one "rel-1") or [several relations](several "rel-
 	})
 }
 
+func TestParseImageLinks(t *testing.T) {
+	content := parse(t, "An ![image](assets/my%20image.png \"rel-1\") attachment.")
+	assert.Equal(t, content.Links, []core.Link{
+		{
+			Title:        "image",
+			Href:         "assets/my image.png",
+			Rels:         core.LinkRels("rel-1"),
+			IsExternal:   false,
+			Snippet:      `An ![image](assets/my%20image.png "rel-1") attachment.`,
+			SnippetStart: 0,
+			SnippetEnd:   54,
+		},
+	})
+}
+
+func TestParseEmbeddedWikiLinks(t *testing.T) {
+	content := parse(t, "An embedded ![[note]] and !not a link.")
+	assert.Equal(t, content.Links, []core.Link{
+		{
+			Title:        "note",
+			Href:         "note",
+			Rels:         []core.LinkRelation{},
+			IsExternal:   false,
+			Snippet:      "An embedded ![[note]] and !not a link.",
+			SnippetStart: 0,
+			SnippetEnd:   38,
+		},
+	})
+}
+
+func TestParseWikiLinkAliases(t *testing.T) {
+	test := func(source string, aliasFirst bool, title string, href string) {
+		content := parseWithOptions(t, source, ParserOpts{WikiLinkAliasFirst: aliasFirst})
+		assert.Equal(t, len(content.Links), 1)
+		assert.Equal(t, content.Links[0].Title, title)
+		assert.Equal(t, content.Links[0].Href, href)
+	}
+
+	test("[[target|An alias]]", false, "An alias", "target")
+	test("[[target | A|piped alias]]", false, "A|piped alias", "target")
+	// The pipe is escaped in Markdown tables.
+	test(`| [[target\|An alias]] |`, false, "An alias", "target")
+	test("[[target]]", true, "target", "target")
+	test("[[An alias|target]]", true, "An alias", "target")
+	test(`| [[An alias\|target]] |`, true, "An alias", "target")
+}
+
+func TestParseTasks(t *testing.T) {
+	due := time.Date(2021, 10, 12, 0, 0, 0, 0, time.Local)
+	content := parse(t, `---
+title: Tasks
+---
+
+- [ ] Call Bob due:2021-10-12
+- [x] Done
+  * [X] Nested
+- Not a task
+- [ ]
+
+1. [ ] Numbered
+
+`+"```"+`
+- [ ] In a code block
+`+"```"+`
+`)
+	assert.Equal(t, content.Tasks, []core.Task{
+		{Line: 5, Text: "Call Bob due:2021-10-12", Due: &due},
+		{Line: 6, Text: "Done", Done: true},
+		{Line: 7, Text: "Nested", Done: true},
+		{Line: 11, Text: "Numbered"},
+	})
+}
+
 func TestParseMetadataFromFrontmatter(t *testing.T) {
 	test := func(source string, expectedMetadata map[string]interface{}) {
 		content := parse(t, source)
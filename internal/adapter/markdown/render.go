@@ -0,0 +1,231 @@
+package markdown
+
+import (
+	"bytes"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/adapter/markdown/extensions"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// RenderHTML implements core.NoteContentRenderer.
+func (p *Parser) RenderHTML(content string, resolve core.LinkResolver, renderBlock core.BlockRenderer) (string, error) {
+	source := []byte(content)
+	root := p.md.Parser().Parse(text.NewReader(source))
+
+	htmlRenderer := html.NewRenderer()
+	r := renderer.NewRenderer(renderer.WithNodeRenderers(
+		util.Prioritized(htmlRenderer, 1000),
+		util.Prioritized(&linkRenderer{resolve: resolve}, 100),
+		util.Prioritized(newBlockRenderer(renderBlock, htmlRenderer), 100),
+	))
+
+	var out bytes.Buffer
+	if err := r.Render(&out, source, root); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// linkRenderer renders the links, images and tags of a note, resolving the
+// links with zk.
+type linkRenderer struct {
+	resolve core.LinkResolver
+	// Indicates whether only the label of the current link is rendered.
+	labelOnly bool
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (r *linkRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindLink, r.renderLink)
+	reg.Register(ast.KindImage, r.renderImage)
+	reg.Register(extensions.KindTags, r.renderTags)
+}
+
+func (r *linkRenderer) renderLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	link := core.RenderedLink{}
+	var title []byte
+	switch n := node.(type) {
+	case *extensions.WikiLink:
+		link.Href = string(n.Destination)
+		link.IsWikiLink = true
+		link.IsEmbed = n.Embed
+	case *ast.Link:
+		link.Href = string(n.Destination)
+		title = n.Title
+	default:
+		return ast.WalkContinue, nil
+	}
+
+	if !entering {
+		if !link.IsEmbed && !r.labelOnly {
+			w.WriteString("</a>")
+		}
+		return ast.WalkContinue, nil
+	}
+
+	target, err := r.resolve(link)
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	if link.IsEmbed {
+		r.writeEmbed(w, link, string(node.Text(source)), target)
+		return ast.WalkSkipChildren, nil
+	}
+	r.labelOnly = target != nil && target.LabelOnly
+	if r.labelOnly {
+		return ast.WalkContinue, nil
+	}
+
+	w.WriteString(`<a href="`)
+	w.WriteString(resolvedURL(link.Href, target))
+	w.WriteByte('"')
+	if title != nil {
+		w.WriteString(` title="`)
+		w.Write(util.EscapeHTML(title))
+		w.WriteByte('"')
+	}
+	w.WriteByte('>')
+	return ast.WalkContinue, nil
+}
+
+func (r *linkRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Image)
+	link := core.RenderedLink{Href: string(n.Destination), IsEmbed: true}
+	target, err := r.resolve(link)
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	r.writeEmbed(w, link, string(n.Text(source)), target)
+	return ast.WalkSkipChildren, nil
+}
+
+// writeEmbed renders the content of an embedded note, an image, or a link
+// to the other embedded files.
+func (r *linkRenderer) writeEmbed(w util.BufWriter, link core.RenderedLink, label string, target *core.ResolvedLink) {
+	if target != nil && target.HTML != "" {
+		w.WriteString(`<div class="zk-embed">`)
+		w.WriteString(target.HTML)
+		w.WriteString(`</div>`)
+		return
+	}
+	if target != nil && target.LabelOnly {
+		w.Write(util.EscapeHTML([]byte(label)))
+		return
+	}
+
+	url := resolvedURL(link.Href, target)
+	path := strings.SplitN(link.Href, "#", 2)[0]
+	isImage := strings.HasPrefix(mime.TypeByExtension(filepath.Ext(path)), "image/")
+	// Markdown images are kept unless they target another kind of file.
+	if isImage || (!link.IsWikiLink && target == nil) {
+		w.WriteString(`<img src="`)
+		w.WriteString(url)
+		w.WriteString(`" alt="`)
+		w.Write(util.EscapeHTML([]byte(label)))
+		w.WriteString(`">`)
+	} else {
+		w.WriteString(`<a href="`)
+		w.WriteString(url)
+		w.WriteString(`">`)
+		w.Write(util.EscapeHTML([]byte(label)))
+		w.WriteString(`</a>`)
+	}
+}
+
+func (r *linkRenderer) renderTags(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		for i, tag := range node.(*extensions.Tags).Tags {
+			if i > 0 {
+				w.WriteByte(' ')
+			}
+			w.WriteString(`<span class="zk-tag">#`)
+			w.Write(util.EscapeHTML([]byte(tag)))
+			w.WriteString(`</span>`)
+		}
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// blockRenderer renders the fenced code blocks with the external programs
+// configured for their language, falling back on the default rendering.
+type blockRenderer struct {
+	render   core.BlockRenderer
+	fallback renderer.NodeRendererFunc
+	// Indicates whether the current block was rendered by an external
+	// program.
+	rendered bool
+}
+
+func newBlockRenderer(render core.BlockRenderer, defaultRenderer renderer.NodeRenderer) *blockRenderer {
+	funcs := nodeRendererFuncs{}
+	defaultRenderer.RegisterFuncs(funcs)
+	return &blockRenderer{
+		render:   render,
+		fallback: funcs[ast.KindFencedCodeBlock],
+	}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (r *blockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
+}
+
+func (r *blockRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering && r.rendered {
+		r.rendered = false
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*ast.FencedCodeBlock)
+	language := string(n.Language(source))
+	if entering && language != "" && r.render != nil {
+		var code bytes.Buffer
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			code.Write(line.Value(source))
+		}
+		if html, ok := r.render(language, code.String()); ok {
+			w.WriteString(`<div class="zk-render zk-render-`)
+			w.Write(util.EscapeHTML([]byte(strings.ToLower(language))))
+			w.WriteString(`">`)
+			w.WriteString(html)
+			w.WriteString("</div>\n")
+			r.rendered = true
+			return ast.WalkSkipChildren, nil
+		}
+	}
+	return r.fallback(w, source, node, entering)
+}
+
+// nodeRendererFuncs collects the rendering functions registered by a
+// renderer.NodeRenderer.
+type nodeRendererFuncs map[ast.NodeKind]renderer.NodeRendererFunc
+
+// Register implements renderer.NodeRendererFuncRegisterer.
+func (f nodeRendererFuncs) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	f[kind] = fn
+}
+
+// resolvedURL returns the escaped URL of a link. An unresolved link is
+// emptied when it is dangerous, e.g. javascript:.
+func resolvedURL(href string, target *core.ResolvedLink) string {
+	url := []byte(href)
+	if target != nil && target.URL != "" {
+		url = []byte(target.URL)
+	} else if html.IsDangerousURL(url) {
+		return ""
+	}
+	return string(util.EscapeHTML(util.URLEscape(url, true)))
+}
@@ -0,0 +1,95 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestRenderHTML(t *testing.T) {
+	test := func(source string, expected string) {
+		assert.Equal(t, render(t, source, nil), expected)
+	}
+
+	test("", "")
+	test("---\ntitle: Title\n---\n# Heading\n\nA paragraph.\n", "<h1>Heading</h1>\n<p>A paragraph.</p>\n")
+	test("A [link](other.md \"Title\") and a [[wiki link|label]]#.\n",
+		"<p>A <a href=\"other.md\" title=\"Title\">link</a> and a <a href=\"wiki%20link\">label</a>.</p>\n")
+	test("Tags: #one :two:three: and #multi word#\n",
+		"<p>Tags: <span class=\"zk-tag\">#one</span> <span class=\"zk-tag\">#two</span> <span class=\"zk-tag\">#three</span> and <span class=\"zk-tag\">#multi word</span></p>\n")
+	test("An ![image](assets/image.png) and an embedded ![[image.png]] or ![[note]].\n",
+		"<p>An <img src=\"assets/image.png\" alt=\"image\"> and an embedded <img src=\"image.png\" alt=\"image.png\"> or <a href=\"note\">note</a>.</p>\n")
+}
+
+func TestRenderHTMLIsSanitized(t *testing.T) {
+	html := render(t, "Some <b>raw</b> HTML and a [dangerous link](javascript:alert(1)).\n\n<script>alert(1)</script>\n", nil)
+	assert.Equal(t, html, "<p>Some <!-- raw HTML omitted -->raw<!-- raw HTML omitted --> HTML and a <a href=\"\">dangerous link</a>.</p>\n<!-- raw HTML omitted -->\n")
+}
+
+func TestRenderHTMLResolvesLinks(t *testing.T) {
+	resolved := []core.RenderedLink{}
+	html := render(t, "A [[note]], a [link](other), a [[private|secret **note**]], an ![[embed]] and an ![image](image.png).\n", func(link core.RenderedLink) (*core.ResolvedLink, error) {
+		resolved = append(resolved, link)
+		switch link.Href {
+		case "note", "other":
+			return &core.ResolvedLink{URL: "file:///notebook/" + link.Href + ".md"}, nil
+		case "private":
+			return &core.ResolvedLink{LabelOnly: true}, nil
+		case "embed":
+			return &core.ResolvedLink{URL: "file:///notebook/embed.md", HTML: "<p>Embedded</p>\n"}, nil
+		default:
+			return &core.ResolvedLink{URL: "file:///notebook/" + link.Href}, nil
+		}
+	})
+
+	assert.Equal(t, resolved, []core.RenderedLink{
+		{Href: "note", IsWikiLink: true},
+		{Href: "other"},
+		{Href: "private", IsWikiLink: true},
+		{Href: "embed", IsWikiLink: true, IsEmbed: true},
+		{Href: "image.png", IsEmbed: true},
+	})
+	assert.Equal(t, html, "<p>A <a href=\"file:///notebook/note.md\">note</a>, a <a href=\"file:///notebook/other.md\">link</a>, a secret **note**, an <div class=\"zk-embed\"><p>Embedded</p>\n</div> and an <img src=\"file:///notebook/image.png\" alt=\"image\">.</p>\n")
+}
+
+func TestRenderHTMLRendersBlocks(t *testing.T) {
+	rendered := []string{}
+	html := renderWithBlocks(t, "```mermaid\ngraph TD\n  A --> B\n```\n\n```math\nE = mc^2\n```\n\n```go\nfunc main() {}\n```\n\n```\n<raw>\n```\n", nil, func(language string, code string) (string, bool) {
+		rendered = append(rendered, language+": "+code)
+		switch language {
+		case "mermaid":
+			return "<svg>A B</svg>", true
+		default:
+			return "", false
+		}
+	})
+
+	assert.Equal(t, rendered, []string{
+		"mermaid: graph TD\n  A --> B\n",
+		"math: E = mc^2\n",
+		"go: func main() {}\n",
+	})
+	assert.Equal(t, html, "<div class=\"zk-render zk-render-mermaid\"><svg>A B</svg></div>\n<pre><code class=\"language-math\">E = mc^2\n</code></pre>\n<pre><code class=\"language-go\">func main() {}\n</code></pre>\n<pre><code>&lt;raw&gt;\n</code></pre>\n")
+}
+
+func render(t *testing.T, source string, resolve core.LinkResolver) string {
+	return renderWithBlocks(t, source, resolve, nil)
+}
+
+func renderWithBlocks(t *testing.T, source string, resolve core.LinkResolver, renderBlock core.BlockRenderer) string {
+	if resolve == nil {
+		resolve = func(link core.RenderedLink) (*core.ResolvedLink, error) {
+			return nil, nil
+		}
+	}
+	parser := NewParser(ParserOpts{
+		HashtagEnabled:      true,
+		MultiWordTagEnabled: true,
+		ColontagEnabled:     true,
+	}, &util.NullLogger)
+	html, err := parser.RenderHTML(source, resolve, renderBlock)
+	assert.Nil(t, err)
+	return html
+}
@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// AssetDAO persists the text extracted from the notebook assets in the
+// SQLite database.
+type AssetDAO struct {
+	tx     Transaction
+	logger util.Logger
+
+	// Prepared SQL statements
+	indexedStmt *LazyStmt
+	setTextStmt *LazyStmt
+	removeStmt  *LazyStmt
+}
+
+// NewAssetDAO creates a new instance of a DAO working on the given database
+// transaction.
+func NewAssetDAO(tx Transaction, logger util.Logger) *AssetDAO {
+	return &AssetDAO{
+		tx:     tx,
+		logger: logger,
+
+		// Get file info of all the assets with an indexed text.
+		indexedStmt: tx.PrepareLazy(`
+			SELECT path, modified FROM asset_texts
+			 ORDER BY path
+		`),
+
+		// Add or replace the text of an asset.
+		setTextStmt: tx.PrepareLazy(`
+			INSERT INTO asset_texts (path, text, modified)
+			VALUES (?, ?, ?)
+			ON CONFLICT(path) DO UPDATE SET text = excluded.text, modified = excluded.modified
+		`),
+
+		// Remove the text of an asset.
+		removeStmt: tx.PrepareLazy(`
+			DELETE FROM asset_texts
+			 WHERE path = ?
+		`),
+	}
+}
+
+// Indexed returns file info of all the assets with an indexed text.
+func (d *AssetDAO) Indexed() (<-chan paths.Metadata, error) {
+	rows, err := d.indexedStmt.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(chan paths.Metadata)
+	go func() {
+		defer close(c)
+		defer rows.Close()
+		var (
+			path     string
+			modified time.Time
+		)
+
+		for rows.Next() {
+			err := rows.Scan(&path, &modified)
+			if err != nil {
+				d.logger.Err(err)
+			}
+
+			c <- paths.Metadata{
+				Path:     path,
+				Modified: modified,
+			}
+		}
+
+		err = rows.Err()
+		if err != nil {
+			d.logger.Err(err)
+		}
+	}()
+
+	return c, nil
+}
+
+// SetText indexes the text extracted from the given asset.
+func (d *AssetDAO) SetText(asset paths.Metadata, text string) error {
+	_, err := d.setTextStmt.Exec(asset.Path, text, asset.Modified)
+	return errors.Wrapf(err, "%s: failed to index the asset text", asset.Path)
+}
+
+// RemoveText deletes the indexed text of the asset at the given path.
+func (d *AssetDAO) RemoveText(path string) error {
+	_, err := d.removeStmt.Exec(path)
+	return errors.Wrapf(err, "%s: failed to remove the asset text", path)
+}
+
+// hrefPath returns the path targeted by a link href, without any anchor and
+// percent-encoding.
+// It is exposed as a custom SQLite function as `href_path()`.
+func hrefPath(href string) string {
+	href = strings.SplitN(href, "#", 2)[0]
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return href
+}
@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/paths"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestAssetDAOSetText(t *testing.T) {
+	testAssetDAO(t, func(tx Transaction, dao *AssetDAO) {
+		err := dao.SetText(paths.Metadata{Path: "doc.pdf", Modified: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}, "Old text")
+		assert.Nil(t, err)
+		// Replaces the existing text.
+		err = dao.SetText(paths.Metadata{Path: "doc.pdf", Modified: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)}, "New text")
+		assert.Nil(t, err)
+
+		var text string
+		err = tx.QueryRow("SELECT text FROM asset_texts_fts WHERE asset_texts_fts MATCH 'new'").Scan(&text)
+		assert.Nil(t, err)
+		assert.Equal(t, text, "New text")
+
+		assertAssetsIndexed(t, dao, []paths.Metadata{
+			{Path: "doc.pdf", Modified: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)},
+		})
+	})
+}
+
+func TestAssetDAORemoveText(t *testing.T) {
+	testAssetDAO(t, func(tx Transaction, dao *AssetDAO) {
+		err := dao.SetText(paths.Metadata{Path: "doc.pdf"}, "Text")
+		assert.Nil(t, err)
+		err = dao.SetText(paths.Metadata{Path: "image.png"}, "Text")
+		assert.Nil(t, err)
+
+		err = dao.RemoveText("doc.pdf")
+		assert.Nil(t, err)
+
+		assertAssetsIndexed(t, dao, []paths.Metadata{{Path: "image.png"}})
+	})
+}
+
+func TestHrefPath(t *testing.T) {
+	assert.Equal(t, hrefPath("doc.pdf"), "doc.pdf")
+	assert.Equal(t, hrefPath("dir/my%20doc.pdf"), "dir/my doc.pdf")
+	assert.Equal(t, hrefPath("doc.pdf#page=3"), "doc.pdf")
+}
+
+func assertAssetsIndexed(t *testing.T, dao *AssetDAO, expected []paths.Metadata) {
+	c, err := dao.Indexed()
+	assert.Nil(t, err)
+
+	actual := []paths.Metadata{}
+	for a := range c {
+		actual = append(actual, a)
+	}
+	assert.Equal(t, actual, expected)
+}
+
+func testAssetDAO(t *testing.T, callback func(tx Transaction, dao *AssetDAO)) {
+	testTransaction(t, func(tx Transaction) {
+		callback(tx, NewAssetDAO(tx, &util.NullLogger))
+	})
+}
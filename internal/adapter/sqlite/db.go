@@ -2,6 +2,10 @@ package sqlite
 
 import (
 	"database/sql"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	sqlite "github.com/mattn/go-sqlite3"
 	"github.com/mickael-menu/zk/internal/core"
@@ -15,6 +19,21 @@ func init() {
 			if err := conn.RegisterFunc("mention_query", buildMentionQuery, true); err != nil {
 				return err
 			}
+			if err := conn.RegisterFunc("href_path", hrefPath, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("is_trashed", isTrashed, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("has_alias", hasAlias, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("in_review", inReview, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("maturity", maturity, true); err != nil {
+				return err
+			}
 			return nil
 		},
 	})
@@ -23,37 +42,112 @@ func init() {
 // DB holds the connections to a SQLite database.
 type DB struct {
 	db *sql.DB
+	// Connections beginning the transactions with a write lock, to fail or
+	// wait before running any statement when the database is busy.
+	writer *sql.DB
+	// URI of the database opened with a read-only connection, to run the
+	// queries of the user. Empty for an in-memory database.
+	readOnlyURI string
+	// Number of attempts to acquire a write lock after the busy timeout.
+	lockRetries int
+	// FTS5 tokenizer of the full-text indexes.
+	tokenize string
 }
 
+// OpenOpts holds the options used to open a SQLite database.
+type OpenOpts struct {
+	// Enables the write-ahead log, which lets several processes read the
+	// database while another one is writing to it.
+	WAL bool
+	// Duration to wait for a lock held by another connection before failing.
+	BusyTimeout time.Duration
+	// Number of times the write lock is requested again when the database is
+	// still locked after the busy timeout.
+	LockRetries int
+	// Tokenizer of the full-text indexes, which are rebuilt when it changes.
+	// The zero value uses core.DefaultSearchTokenizer.
+	Tokenizer core.SearchTokenizer
+}
+
+// lockRetryDelay is the pause between two attempts to lock the database.
+const lockRetryDelay = 100 * time.Millisecond
+
 // Open creates a new DB instance for the SQLite database at the given path.
-func Open(path string) (*DB, error) {
-	return open("file:" + path)
+func Open(path string, opts OpenOpts) (*DB, error) {
+	wrap := errors.Wrapper("failed to open the database")
+
+	journalMode := "DELETE"
+	if opts.WAL {
+		journalMode = "WAL"
+	}
+	params := url.Values{}
+	params.Set("_journal_mode", journalMode)
+	params.Set("_busy_timeout", strconv.FormatInt(opts.BusyTimeout.Milliseconds(), 10))
+	uri := "file:" + path + "?" + params.Encode()
+
+	nativeDB, err := openNative(uri)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	params.Set("_txlock", "immediate")
+	writer, err := openNative("file:" + path + "?" + params.Encode())
+	if err != nil {
+		nativeDB.Close()
+		return nil, wrap(err)
+	}
+
+	// The journal mode can't be changed with a read-only connection.
+	params.Del("_journal_mode")
+	params.Del("_txlock")
+	params.Set("mode", "ro")
+	params.Set("_query_only", "true")
+
+	return open(&DB{
+		db:          nativeDB,
+		writer:      writer,
+		readOnlyURI: "file:" + path + "?" + params.Encode(),
+		lockRetries: opts.LockRetries,
+		tokenize:    ftsTokenize(opts.Tokenizer),
+	})
 }
 
 // OpenInMemory creates a new in-memory DB instance.
 func OpenInMemory() (*DB, error) {
-	return open(":memory:")
-}
+	nativeDB, err := openNative(":memory:")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open the database")
+	}
 
-func open(uri string) (*DB, error) {
-	wrap := errors.Wrapper("failed to open the database")
+	// Each connection to an in-memory database opens a distinct database,
+	// so the write transactions can't use their own connections.
+	return open(&DB{
+		db:       nativeDB,
+		writer:   nativeDB,
+		tokenize: ftsTokenize(core.DefaultSearchTokenizer),
+	})
+}
 
+func openNative(uri string) (*sql.DB, error) {
 	nativeDB, err := sql.Open("sqlite3_custom", uri)
 	if err != nil {
-		return nil, wrap(err)
+		return nil, err
 	}
 
 	// Make sure that CASCADE statements are properly applied by enabling
 	// foreign keys.
 	_, err = nativeDB.Exec("PRAGMA foreign_keys = ON")
 	if err != nil {
-		return nil, wrap(err)
+		nativeDB.Close()
+		return nil, translateErr(err)
 	}
 
-	db := &DB{nativeDB}
+	return nativeDB, nil
+}
 
-	err = db.migrate()
+func open(db *DB) (*DB, error) {
+	err := db.migrate()
 	if err != nil {
+		db.Close()
 		return nil, errors.Wrap(err, "failed to migrate the database")
 	}
 
@@ -63,12 +157,71 @@ func open(uri string) (*DB, error) {
 // Close terminates the connections to the SQLite database.
 func (db *DB) Close() error {
 	err := db.db.Close()
+	if db.writer != db.db {
+		if werr := db.writer.Close(); err == nil {
+			err = werr
+		}
+	}
 	return errors.Wrap(err, "failed to close the database")
 }
 
+// Vacuum rebuilds the database file to reclaim its unused space.
+func (db *DB) Vacuum() error {
+	_, err := db.db.Exec("VACUUM")
+	return errors.Wrap(translateErr(err), "failed to vacuum the database")
+}
+
+// QueryReadOnly runs a SQL query with a read-only connection, which can't
+// modify the database even with several statements.
+func (db *DB) QueryReadOnly(query string) (core.IndexQueryResult, error) {
+	wrap := errors.Wrapper("failed to run the query")
+
+	res := core.IndexQueryResult{
+		Columns: []string{},
+		Rows:    [][]interface{}{},
+	}
+	if db.readOnlyURI == "" {
+		return res, wrap(errors.New("an in-memory database can't be queried"))
+	}
+	nativeDB, err := openNative(db.readOnlyURI)
+	if err != nil {
+		return res, wrap(err)
+	}
+	defer nativeDB.Close()
+
+	rows, err := nativeDB.Query(query)
+	if err != nil {
+		return res, wrap(translateErr(err))
+	}
+	defer rows.Close()
+
+	res.Columns, err = rows.Columns()
+	if err != nil {
+		return res, wrap(err)
+	}
+	for rows.Next() {
+		row := make([]interface{}, len(res.Columns))
+		dest := make([]interface{}, len(row))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return res, wrap(err)
+		}
+		for i, value := range row {
+			if bytes, ok := value.([]byte); ok {
+				row[i] = string(bytes)
+			}
+		}
+		res.Rows = append(res.Rows, row)
+	}
+
+	return res, wrap(translateErr(rows.Err()))
+}
+
 // migrate upgrades the SQL schema of the database.
 func (db *DB) migrate() error {
-	err := db.WithTransaction(func(tx Transaction) error {
+	err := db.WithWriteTransaction(func(tx Transaction) error {
 		var version int
 		err := tx.QueryRow("PRAGMA user_version").Scan(&version)
 		if err != nil {
@@ -198,12 +351,306 @@ func (db *DB) migrate() error {
 					key TEXT PRIMARY KEY NOT NULL,
 					value TEXT NO NULL
 				)`,
+
+				// Text extracted from the assets (e.g. PDF, images), to
+				// find the notes linking to them when searching.
+				`CREATE TABLE IF NOT EXISTS asset_texts (
+					id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+					path TEXT NOT NULL,
+					text TEXT DEFAULT('') NOT NULL,
+					modified DATETIME DEFAULT(CURRENT_TIMESTAMP) NOT NULL,
+					UNIQUE(path)
+				)`,
+				`CREATE VIRTUAL TABLE IF NOT EXISTS asset_texts_fts USING fts5(
+					path, text,
+					content = asset_texts,
+					content_rowid = id,
+					tokenize = "porter unicode61 remove_diacritics 1 tokenchars '''&/'"
+				)`,
+				`CREATE TRIGGER IF NOT EXISTS trigger_asset_texts_ai AFTER INSERT ON asset_texts BEGIN
+					INSERT INTO asset_texts_fts(rowid, path, text) VALUES (new.id, new.path, new.text);
+				END`,
+				`CREATE TRIGGER IF NOT EXISTS trigger_asset_texts_ad AFTER DELETE ON asset_texts BEGIN
+					INSERT INTO asset_texts_fts(asset_texts_fts, rowid, path, text) VALUES('delete', old.id, old.path, old.text);
+				END`,
+				`CREATE TRIGGER IF NOT EXISTS trigger_asset_texts_au AFTER UPDATE ON asset_texts BEGIN
+					INSERT INTO asset_texts_fts(asset_texts_fts, rowid, path, text) VALUES('delete', old.id, old.path, old.text);
+					INSERT INTO asset_texts_fts(rowid, path, text) VALUES (new.id, new.path, new.text);
+				END`,
+
+				// Metadata fetched from the external URLs linked in the
+				// notes.
+				`CREATE TABLE IF NOT EXISTS url_metadata (
+					url TEXT PRIMARY KEY NOT NULL,
+					title TEXT DEFAULT('') NOT NULL,
+					status INTEGER DEFAULT(0) NOT NULL,
+					fetched DATETIME DEFAULT(CURRENT_TIMESTAMP) NOT NULL
+				)`,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = tx.ExecStmts([]string{
+				// Add a `tag_names` column to `notes`, to match and boost
+				// the tags in full-text searches.
+				`ALTER TABLE notes ADD COLUMN tag_names TEXT DEFAULT('') NOT NULL`,
+
+				// Recreate the FTS index with the tags.
+				`DROP TRIGGER IF EXISTS trigger_notes_ai`,
+				`DROP TRIGGER IF EXISTS trigger_notes_ad`,
+				`DROP TRIGGER IF EXISTS trigger_notes_au`,
+				`DROP TABLE IF EXISTS notes_fts`,
+				`CREATE VIRTUAL TABLE notes_fts USING fts5(
+					path, title, body, tag_names,
+					content = notes,
+					content_rowid = id,
+					tokenize = "porter unicode61 remove_diacritics 1 tokenchars '''&/'"
+				)`,
+				`CREATE TRIGGER trigger_notes_ai AFTER INSERT ON notes BEGIN
+					INSERT INTO notes_fts(rowid, path, title, body, tag_names) VALUES (new.id, new.path, new.title, new.body, new.tag_names);
+				END`,
+				`CREATE TRIGGER trigger_notes_ad AFTER DELETE ON notes BEGIN
+					INSERT INTO notes_fts(notes_fts, rowid, path, title, body, tag_names) VALUES('delete', old.id, old.path, old.title, old.body, old.tag_names);
+				END`,
+				`CREATE TRIGGER trigger_notes_au AFTER UPDATE ON notes BEGIN
+					INSERT INTO notes_fts(notes_fts, rowid, path, title, body, tag_names) VALUES('delete', old.id, old.path, old.title, old.body, old.tag_names);
+					INSERT INTO notes_fts(rowid, path, title, body, tag_names) VALUES (new.id, new.path, new.title, new.body, new.tag_names);
+				END`,
+				`INSERT INTO notes_fts(notes_fts) VALUES('rebuild')`,
+
+				`PRAGMA user_version = 4`,
+			})
+			if err != nil {
+				return err
+			}
+
+			needsReindexing = true
+		}
+
+		if version <= 4 {
+			err = tx.ExecStmts([]string{
+				// Add the URL of the archived version of the external URLs.
+				`ALTER TABLE url_metadata ADD COLUMN archive TEXT DEFAULT('') NOT NULL`,
+
+				`PRAGMA user_version = 5`,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if version <= 5 {
+			err = tx.ExecStmts([]string{
+				// Stable views for the queries of the user, see
+				// docs/index-queries.md. Their existing columns must not be
+				// changed or removed, to keep the user queries working.
+				`CREATE VIEW zk_notes AS
+				 SELECT path, title, lead, body, raw_content, word_count,
+				        metadata, is_trashed(metadata) AS trashed,
+				        checksum, created, modified
+				   FROM notes`,
+				`CREATE VIEW zk_links AS
+				 SELECT s.path AS source_path, t.path AS target_path,
+				        l.href, l.title, l.external, l.snippet
+				   FROM links l
+				   JOIN notes s ON s.id = l.source_id
+				   LEFT JOIN notes t ON t.id = l.target_id`,
+				`CREATE VIEW zk_tags AS
+				 SELECT n.path, c.name AS tag
+				   FROM notes_collections nc
+				   JOIN notes n ON n.id = nc.note_id
+				   JOIN collections c ON c.id = nc.collection_id
+				  WHERE c.kind = '` + string(core.CollectionKindTag) + `'`,
+
+				`PRAGMA user_version = 6`,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if version <= 6 {
+			err = tx.ExecStmts([]string{
+				// Modification date of the note file, which can change
+				// without any change of the note content, e.g. when touched
+				// by a sync tool.
+				`ALTER TABLE notes ADD COLUMN file_modified DATETIME`,
+				`UPDATE notes SET file_modified = modified`,
+
+				`PRAGMA user_version = 7`,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if version <= 7 {
+			err = tx.ExecStmts([]string{
+				// Checkbox items of the notes
+				`CREATE TABLE IF NOT EXISTS tasks (
+					id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+					note_id INTEGER NOT NULL REFERENCES notes(id)
+						ON DELETE CASCADE,
+					line INTEGER NOT NULL,
+					text TEXT DEFAULT('') NOT NULL,
+					done INT DEFAULT(0) NOT NULL,
+					due DATETIME
+				)`,
+				`CREATE INDEX IF NOT EXISTS index_tasks_note_id ON tasks (note_id)`,
+				`CREATE VIEW zk_tasks AS
+				 SELECT n.path, t.line, t.text, t.done, t.due
+				   FROM tasks t
+				   JOIN notes n ON n.id = t.note_id`,
+
+				`PRAGMA user_version = 8`,
+			})
+			if err != nil {
+				return err
+			}
+			// The tasks of the notes already indexed must be parsed.
+			needsReindexing = true
+		}
+
+		if version <= 8 {
+			err = tx.ExecStmts([]string{
+				`ALTER TABLE notes ADD COLUMN summary TEXT DEFAULT('') NOT NULL`,
+				`DROP VIEW IF EXISTS zk_notes`,
+				`CREATE VIEW zk_notes AS
+				 SELECT path, title, lead, summary, body, raw_content, word_count,
+				        metadata, is_trashed(metadata) AS trashed,
+				        checksum, created, modified
+				   FROM notes`,
+
+				`PRAGMA user_version = 9`,
+			})
+			if err != nil {
+				return err
+			}
+			// The summaries of the notes already indexed must be extracted.
+			needsReindexing = true
+		}
+
+		if version <= 9 {
+			err = tx.ExecStmts([]string{
+				// Spaced repetition schedule of the notes in the review queue
+				`CREATE TABLE IF NOT EXISTS reviews (
+					note_id INTEGER PRIMARY KEY NOT NULL REFERENCES notes(id)
+						ON DELETE CASCADE,
+					reviewed DATETIME NOT NULL,
+					due DATETIME NOT NULL,
+					interval INTEGER NOT NULL,
+					ease REAL NOT NULL,
+					repetitions INTEGER NOT NULL
+				)`,
+
+				`PRAGMA user_version = 10`,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if version <= 10 {
+			err = tx.ExecStmts([]string{
+				// Terms of the full-text search index, used to find the
+				// related notes. The rows give the number of notes
+				// containing each term, and the instances each occurrence
+				// of a term in a note.
+				`CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts_rows USING fts5vocab(notes_fts, row)`,
+				`CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts_instances USING fts5vocab(notes_fts, instance)`,
+
+				`PRAGMA user_version = 11`,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if version <= 11 {
+			err = tx.ExecStmts([]string{
+				// Number of changes of the note content since it was first
+				// indexed, used to measure the maturity of the notes.
+				`ALTER TABLE notes ADD COLUMN edit_count INTEGER DEFAULT(0) NOT NULL`,
+				// Counts the inbound links of the notes.
+				`CREATE INDEX IF NOT EXISTS index_links_target_id ON links (target_id)`,
+
+				`PRAGMA user_version = 12`,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if version <= 12 {
+			err = tx.ExecStmts([]string{
+				// Flattened frontmatter of the notes, to filter and sort
+				// them by any key. A list has a row per item, and the
+				// numeric values are also stored as numbers.
+				`CREATE TABLE IF NOT EXISTS notes_metadata (
+					note_id INTEGER NOT NULL REFERENCES notes(id)
+						ON DELETE CASCADE,
+					key TEXT NOT NULL,
+					value TEXT NOT NULL,
+					number REAL
+				)`,
+				`CREATE INDEX IF NOT EXISTS index_notes_metadata_note_id ON notes_metadata (note_id)`,
+				`CREATE INDEX IF NOT EXISTS index_notes_metadata_key ON notes_metadata (key, value)`,
+				`CREATE VIEW zk_metadata AS
+				 SELECT n.path, m.key, m.value, m.number
+				   FROM notes_metadata m
+				   JOIN notes n ON n.id = m.note_id`,
+
+				`PRAGMA user_version = 13`,
+			})
+			if err != nil {
+				return err
+			}
+			// The frontmatter of the notes already indexed must be
+			// flattened.
+			needsReindexing = true
+		}
+
+		if version <= 13 {
+			err = tx.ExecStmts([]string{
+				// Last action done on the notes recently created or
+				// opened.
+				`CREATE TABLE IF NOT EXISTS history (
+					note_id INTEGER PRIMARY KEY NOT NULL REFERENCES notes(id)
+						ON DELETE CASCADE,
+					action TEXT NOT NULL,
+					date DATETIME NOT NULL
+				)`,
+				`CREATE INDEX IF NOT EXISTS index_history_date ON history (date)`,
+
+				`PRAGMA user_version = 14`,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if version <= 14 {
+			err = tx.ExecStmts([]string{
+				// IDs reserved for new notes, to prevent conflicts when
+				// notes are created simultaneously by several processes.
+				`CREATE TABLE IF NOT EXISTS reserved_ids (
+					id TEXT PRIMARY KEY NOT NULL,
+					reserved DATETIME DEFAULT(CURRENT_TIMESTAMP) NOT NULL
+				)`,
+
+				`PRAGMA user_version = 15`,
 			})
 			if err != nil {
 				return err
 			}
 		}
 
+		err = migrateTokenizer(tx, db.tokenize)
+		if err != nil {
+			return err
+		}
+
 		if needsReindexing {
 			metadata := NewMetadataDAO(tx)
 			// During the next indexing, all notes will be reindexed.
@@ -218,3 +665,82 @@ func (db *DB) migrate() error {
 
 	return errors.Wrap(err, "database migration failed")
 }
+
+// ftsTokenize returns the tokenize option of the FTS5 tables for the given
+// tokenizer, see https://www.sqlite.org/fts5.html#tokenizers
+func ftsTokenize(tokenizer core.SearchTokenizer) string {
+	if tokenizer.Kind == "" {
+		tokenizer = core.DefaultSearchTokenizer
+	}
+	if tokenizer.Kind == core.TokenizerTrigram {
+		return "trigram"
+	}
+
+	quote := func(value string) string {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	args := []string{"unicode61", "remove_diacritics", strconv.Itoa(tokenizer.RemoveDiacritics)}
+	if tokenizer.TokenChars != "" {
+		args = append(args, "tokenchars", quote(tokenizer.TokenChars))
+	}
+	if tokenizer.Separators != "" {
+		args = append(args, "separators", quote(tokenizer.Separators))
+	}
+	if tokenizer.Kind == core.TokenizerPorter {
+		args = append([]string{"porter"}, args...)
+	}
+	return strings.Join(args, " ")
+}
+
+// defaultTokenize is the tokenizer of the FTS5 tables created by the schema
+// migrations.
+const defaultTokenize = "porter unicode61 remove_diacritics 1 tokenchars '''&/'"
+
+// migrateTokenizer recreates the full-text indexes with the given tokenizer,
+// if they were built with a different one.
+func migrateTokenizer(tx Transaction, tokenize string) error {
+	metadata := NewMetadataDAO(tx)
+	current, err := metadata.Get(ftsTokenizerKey)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		current = defaultTokenize
+	}
+	if current == tokenize {
+		return nil
+	}
+
+	option := `tokenize = "` + strings.ReplaceAll(tokenize, `"`, `""`) + `"`
+	err = tx.ExecStmts([]string{
+		`DROP TABLE IF EXISTS notes_fts_rows`,
+		`DROP TABLE IF EXISTS notes_fts_instances`,
+		`DROP TABLE IF EXISTS notes_fts`,
+		`DROP TABLE IF EXISTS asset_texts_fts`,
+
+		// The triggers of the content tables don't need to be recreated,
+		// as they refer to the FTS tables by name.
+		`CREATE VIRTUAL TABLE notes_fts USING fts5(
+			path, title, body, tag_names,
+			content = notes,
+			content_rowid = id,
+			` + option + `
+		)`,
+		`CREATE VIRTUAL TABLE asset_texts_fts USING fts5(
+			path, text,
+			content = asset_texts,
+			content_rowid = id,
+			` + option + `
+		)`,
+		`CREATE VIRTUAL TABLE notes_fts_rows USING fts5vocab(notes_fts, row)`,
+		`CREATE VIRTUAL TABLE notes_fts_instances USING fts5vocab(notes_fts, instance)`,
+
+		// Index the existing notes and asset texts with the new tokenizer.
+		`INSERT INTO notes_fts(notes_fts) VALUES('rebuild')`,
+		`INSERT INTO asset_texts_fts(asset_texts_fts) VALUES('rebuild')`,
+	})
+	if err != nil {
+		return err
+	}
+	return metadata.Set(ftsTokenizerKey, tokenize)
+}
@@ -1,19 +1,23 @@
 package sqlite
 
 import (
+	"errors"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/mickael-menu/zk/internal/core"
 	"github.com/mickael-menu/zk/internal/util/fixtures"
 	"github.com/mickael-menu/zk/internal/util/test/assert"
 )
 
 func TestOpen(t *testing.T) {
-	_, err := Open(fixtures.Path("sample.db"))
+	_, err := Open(fixtures.Path("sample.db"), OpenOpts{})
 	assert.Nil(t, err)
 }
 
 func TestClose(t *testing.T) {
-	db, err := Open(fixtures.Path("sample.db"))
+	db, err := Open(fixtures.Path("sample.db"), OpenOpts{})
 	assert.Nil(t, err)
 	err = db.Close()
 	assert.Nil(t, err)
@@ -27,7 +31,7 @@ func TestMigrateFrom0(t *testing.T) {
 		var version int
 		err := tx.QueryRow("PRAGMA user_version").Scan(&version)
 		assert.Nil(t, err)
-		assert.Equal(t, version, 3)
+		assert.Equal(t, version, 15)
 
 		_, err = tx.Exec(`
 			INSERT INTO notes (path, sortable_path, title, body, word_count, checksum)
@@ -39,3 +43,203 @@ func TestMigrateFrom0(t *testing.T) {
 	})
 	assert.Nil(t, err)
 }
+
+func TestMigrateReservedIDsFrom14(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notebook.db")
+	db, err := Open(path, OpenOpts{})
+	assert.Nil(t, err)
+	// Index created before the reserved IDs.
+	_, err = db.db.Exec("DROP TABLE reserved_ids; PRAGMA user_version = 14")
+	assert.Nil(t, err)
+	assert.Nil(t, db.Close())
+
+	db, err = Open(path, OpenOpts{})
+	assert.Nil(t, err)
+	defer db.Close()
+
+	var version int
+	err = db.db.QueryRow("PRAGMA user_version").Scan(&version)
+	assert.Nil(t, err)
+	assert.Equal(t, version, 15)
+	_, err = db.db.Exec("INSERT INTO reserved_ids (id) VALUES ('abcd')")
+	assert.Nil(t, err)
+}
+
+func TestConcurrentAccessWithWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notebook.db")
+	opts := OpenOpts{WAL: true, BusyTimeout: 10 * time.Millisecond}
+
+	db1, err := Open(path, opts)
+	assert.Nil(t, err)
+	defer db1.Close()
+	db2, err := Open(path, opts)
+	assert.Nil(t, err)
+	defer db2.Close()
+
+	var mode string
+	err = db1.db.QueryRow("PRAGMA journal_mode").Scan(&mode)
+	assert.Nil(t, err)
+	assert.Equal(t, mode, "wal")
+
+	err = db1.WithWriteTransaction(func(tx Transaction) error {
+		_, err := tx.Exec("INSERT INTO metadata (key, value) VALUES ('a', 'b')")
+		assert.Nil(t, err)
+
+		// Readers are not blocked by the writer.
+		err = db2.WithTransaction(func(tx Transaction) error {
+			var count int
+			return tx.QueryRow("SELECT COUNT(*) FROM notes").Scan(&count)
+		})
+		assert.Nil(t, err)
+
+		// Another writer gives up after the busy timeout.
+		err = db2.WithWriteTransaction(func(tx Transaction) error {
+			return nil
+		})
+		var lockedErr core.ErrIndexLocked
+		assert.True(t, errors.As(err, &lockedErr))
+		return nil
+	})
+	assert.Nil(t, err)
+
+	// The lock was released by the commit.
+	err = db2.WithWriteTransaction(func(tx Transaction) error {
+		_, err := tx.Exec("DELETE FROM metadata WHERE key = 'a'")
+		return err
+	})
+	assert.Nil(t, err)
+}
+
+func TestQueryReadOnly(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "notebook.db"), OpenOpts{})
+	assert.Nil(t, err)
+	defer db.Close()
+
+	err = db.WithWriteTransaction(func(tx Transaction) error {
+		return tx.ExecStmts([]string{
+			`INSERT INTO notes (id, path, sortable_path, title, word_count, checksum, metadata, created)
+			 VALUES (1, 'a.md', 'a.md', 'A', 2, 'qwfpg', '{}', '2021-10-12 08:00:00'),
+			        (2, 'b.md', 'b.md', 'B', 5, 'arstd', '{"status": "trash"}', '2021-10-14 08:00:00')`,
+			`INSERT INTO links (source_id, target_id, href, title) VALUES (1, 2, 'b', 'Link'), (2, NULL, 'c', '')`,
+			`INSERT INTO collections (id, kind, name) VALUES (1, 'tag', 'fiction'), (2, 'tag', 'poem')`,
+			`INSERT INTO notes_collections (note_id, collection_id) VALUES (1, 1), (1, 2), (2, 1)`,
+		})
+	})
+	assert.Nil(t, err)
+
+	res, err := db.QueryReadOnly("SELECT path, title, word_count, trashed, created FROM zk_notes ORDER BY path")
+	assert.Nil(t, err)
+	assert.Equal(t, res, core.IndexQueryResult{
+		Columns: []string{"path", "title", "word_count", "trashed", "created"},
+		Rows: [][]interface{}{
+			{"a.md", "A", int64(2), int64(0), time.Date(2021, 10, 12, 8, 0, 0, 0, time.UTC)},
+			{"b.md", "B", int64(5), int64(1), time.Date(2021, 10, 14, 8, 0, 0, 0, time.UTC)},
+		},
+	})
+
+	res, err = db.QueryReadOnly("SELECT source_path, target_path, href FROM zk_links ORDER BY source_path")
+	assert.Nil(t, err)
+	assert.Equal(t, res.Rows, [][]interface{}{
+		{"a.md", "b.md", "b"},
+		{"b.md", nil, "c"},
+	})
+
+	res, err = db.QueryReadOnly("SELECT tag, COUNT(*) FROM zk_tags GROUP BY tag ORDER BY tag")
+	assert.Nil(t, err)
+	assert.Equal(t, res.Rows, [][]interface{}{
+		{"fiction", int64(2)},
+		{"poem", int64(1)},
+	})
+
+	// The queries can't modify the database.
+	_, err = db.QueryReadOnly("DELETE FROM notes")
+	assert.NotNil(t, err)
+	_, err = db.QueryReadOnly("PRAGMA query_only = OFF; DELETE FROM notes")
+	assert.NotNil(t, err)
+	res, err = db.QueryReadOnly("SELECT COUNT(*) FROM notes")
+	assert.Nil(t, err)
+	assert.Equal(t, res.Rows, [][]interface{}{{int64(2)}})
+
+	_, err = db.QueryReadOnly("SELECT * FROM unknown")
+	assert.Err(t, err, "failed to run the query: no such table: unknown")
+}
+
+func TestQueryReadOnlyInMemory(t *testing.T) {
+	db, err := OpenInMemory()
+	assert.Nil(t, err)
+	_, err = db.QueryReadOnly("SELECT 1")
+	assert.Err(t, err, "failed to run the query: an in-memory database can't be queried")
+}
+
+func TestFTSTokenize(t *testing.T) {
+	assert.Equal(t, ftsTokenize(core.SearchTokenizer{}), defaultTokenize)
+	assert.Equal(t, ftsTokenize(core.DefaultSearchTokenizer), defaultTokenize)
+	assert.Equal(t, ftsTokenize(core.SearchTokenizer{
+		Kind:             core.TokenizerUnicode61,
+		RemoveDiacritics: 2,
+		TokenChars:       "-_",
+		Separators:       `."`,
+	}), `unicode61 remove_diacritics 2 tokenchars '-_' separators '."'`)
+	assert.Equal(t, ftsTokenize(core.SearchTokenizer{Kind: core.TokenizerTrigram, TokenChars: "-"}), "trigram")
+}
+
+func TestMigrateTokenizer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notebook.db")
+
+	match := func(db *DB, query string) []string {
+		paths := []string{}
+		err := db.WithTransaction(func(tx Transaction) error {
+			rows, err := tx.Query("SELECT path FROM notes_fts WHERE notes_fts MATCH ? ORDER BY path", query)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var path string
+				if err := rows.Scan(&path); err != nil {
+					return err
+				}
+				paths = append(paths, path)
+			}
+			return rows.Err()
+		})
+		assert.Nil(t, err)
+		return paths
+	}
+
+	db, err := Open(path, OpenOpts{})
+	assert.Nil(t, err)
+	err = db.WithWriteTransaction(func(tx Transaction) error {
+		_, err := tx.Exec(`
+			INSERT INTO notes (path, sortable_path, title, body, checksum)
+			VALUES ('a.md', 'a.md', 'Running', '全文検索の設定', 'qwfpg')
+		`)
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, match(db, "runs"), []string{"a.md"})
+	assert.Equal(t, match(db, "検索の"), []string{})
+	assert.Nil(t, db.Close())
+
+	// The existing notes are indexed again with the new tokenizer.
+	db, err = Open(path, OpenOpts{Tokenizer: core.SearchTokenizer{Kind: core.TokenizerTrigram}})
+	assert.Nil(t, err)
+	assert.Equal(t, match(db, "runs"), []string{})
+	assert.Equal(t, match(db, "検索の"), []string{"a.md"})
+	err = db.WithWriteTransaction(func(tx Transaction) error {
+		_, err := tx.Exec(`
+			INSERT INTO notes (path, sortable_path, title, body, checksum)
+			VALUES ('b.md', 'b.md', 'B', '検索エンジン', 'arstd')
+		`)
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, match(db, "検索"), []string{})
+	assert.Equal(t, match(db, "検索エ"), []string{"b.md"})
+	assert.Nil(t, db.Close())
+
+	db, err = Open(path, OpenOpts{Tokenizer: core.DefaultSearchTokenizer})
+	assert.Nil(t, err)
+	defer db.Close()
+	assert.Equal(t, match(db, "runs"), []string{"a.md"})
+}
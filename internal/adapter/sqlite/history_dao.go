@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// HistoryDAO persists the history of the recently created or opened notes
+// in the SQLite database.
+type HistoryDAO struct {
+	tx Transaction
+
+	// Prepared SQL statements
+	addStmt *LazyStmt
+}
+
+// NewHistoryDAO creates a new instance of a DAO working on the given
+// database transaction.
+func NewHistoryDAO(tx Transaction) *HistoryDAO {
+	return &HistoryDAO{
+		tx: tx,
+
+		// Replace the last action done on a note.
+		addStmt: tx.PrepareLazy(`
+			INSERT OR REPLACE INTO history (note_id, action, date)
+			VALUES (?, ?, ?)
+		`),
+	}
+}
+
+// Add records the last action done on the given note.
+func (d *HistoryDAO) Add(noteID core.NoteID, action core.NoteHistoryAction, date time.Time) error {
+	_, err := d.addStmt.Exec(int64(noteID), string(action), date.UTC())
+	return errors.Wrap(err, "failed to add the note to the history")
+}
+
+// Find returns the notes of the history, the most recent first. The notes in
+// the trash are ignored.
+func (d *HistoryDAO) Find(limit int) ([]core.NoteHistoryEntry, error) {
+	wrap := errors.Wrapper("failed to find the history of the notes")
+
+	query := `
+		SELECT n.path, n.title, h.action, h.date
+		  FROM history h
+		  JOIN notes n ON n.id = h.note_id
+		 WHERE NOT is_trashed(n.metadata)
+		 ORDER BY h.date DESC, n.sortable_path
+	`
+	if limit > 0 {
+		query += fmt.Sprintf("LIMIT %d", limit)
+	}
+
+	rows, err := d.tx.Query(query)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	defer rows.Close()
+
+	entries := []core.NoteHistoryEntry{}
+	for rows.Next() {
+		var (
+			entry  core.NoteHistoryEntry
+			action string
+		)
+		err := rows.Scan(&entry.Path, &entry.Title, &action, &entry.Date)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		entry.Action = core.NoteHistoryAction(action)
+		entry.Date = entry.Date.Local()
+		entries = append(entries, entry)
+	}
+
+	return entries, wrap(rows.Err())
+}
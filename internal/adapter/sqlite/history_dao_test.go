@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestHistoryDAOFind(t *testing.T) {
+	testHistoryDAO(t, func(tx Transaction, dao *HistoryDAO) {
+		entries, err := dao.Find(0)
+		assert.Nil(t, err)
+		assert.Equal(t, entries, []core.NoteHistoryEntry{})
+
+		date1 := time.Date(2021, 10, 1, 9, 30, 0, 0, time.Local)
+		date2 := time.Date(2021, 10, 2, 9, 30, 0, 0, time.Local)
+		date3 := time.Date(2021, 10, 3, 9, 30, 0, 0, time.Local)
+		assert.Nil(t, dao.Add(core.NoteID(1), core.NoteHistoryCreated, date1))
+		assert.Nil(t, dao.Add(core.NoteID(3), core.NoteHistoryOpened, date2))
+		assert.Nil(t, dao.Add(core.NoteID(4), core.NoteHistoryOpened, date3))
+
+		test := func(limit int, expected []core.NoteHistoryEntry) {
+			t.Helper()
+			actual, err := dao.Find(limit)
+			assert.Nil(t, err)
+			assert.Equal(t, actual, expected)
+		}
+
+		daily := core.NoteHistoryEntry{Path: "log/2021-01-03.md", Title: "Daily note", Action: core.NoteHistoryCreated, Date: date1}
+		index := core.NoteHistoryEntry{Path: "index.md", Title: "Index", Action: core.NoteHistoryOpened, Date: date2}
+		interesting := core.NoteHistoryEntry{Path: "f39c8.md", Title: "An interesting note", Action: core.NoteHistoryOpened, Date: date3}
+
+		// The most recent first.
+		test(0, []core.NoteHistoryEntry{interesting, index, daily})
+		test(2, []core.NoteHistoryEntry{interesting, index})
+
+		// Replaces the last action of a note.
+		date4 := time.Date(2021, 10, 4, 9, 30, 0, 0, time.Local)
+		assert.Nil(t, dao.Add(core.NoteID(1), core.NoteHistoryOpened, date4))
+		daily.Action = core.NoteHistoryOpened
+		daily.Date = date4
+		test(0, []core.NoteHistoryEntry{daily, interesting, index})
+
+		// The notes in the trash are ignored.
+		_, err = tx.Exec(`UPDATE notes SET metadata = '{"status":"trash"}' WHERE id = 4`)
+		assert.Nil(t, err)
+		test(0, []core.NoteHistoryEntry{daily, index})
+	})
+}
+
+func testHistoryDAO(t *testing.T, callback func(tx Transaction, dao *HistoryDAO)) {
+	testTransaction(t, func(tx Transaction) {
+		callback(tx, NewHistoryDAO(tx))
+	})
+}
@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// IDDAO reserves note IDs in the SQLite database.
+type IDDAO struct {
+	tx Transaction
+
+	// Prepared SQL statements
+	pruneStmt   *LazyStmt
+	reserveStmt *LazyStmt
+}
+
+// NewIDDAO creates a new instance of a DAO working on the given database
+// transaction.
+func NewIDDAO(tx Transaction) *IDDAO {
+	return &IDDAO{
+		tx: tx,
+		// A reservation is only needed until the note file is created, so
+		// old ones can be safely discarded.
+		pruneStmt: tx.PrepareLazy(`
+			DELETE FROM reserved_ids
+			 WHERE reserved < datetime('now', '-1 day')
+		`),
+		reserveStmt: tx.PrepareLazy(`
+			INSERT OR IGNORE INTO reserved_ids(id)
+			VALUES (?)
+		`),
+	}
+}
+
+// Reserve marks the given ID as taken. Returns false if the ID was already
+// reserved.
+func (d *IDDAO) Reserve(id string) (bool, error) {
+	wrap := errors.Wrapperf("failed to reserve ID %s", id)
+
+	_, err := d.pruneStmt.Exec()
+	if err != nil {
+		return false, wrap(err)
+	}
+
+	res, err := d.reserveStmt.Exec(id)
+	if err != nil {
+		return false, wrap(err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return false, wrap(err)
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,42 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestIDDAOReserveUnknown(t *testing.T) {
+	testIDDAO(t, func(tx Transaction, dao *IDDAO) {
+		reserved, err := dao.Reserve("new")
+		assert.Nil(t, err)
+		assert.True(t, reserved)
+
+		// Can't be reserved twice.
+		reserved, err = dao.Reserve("new")
+		assert.Nil(t, err)
+		assert.False(t, reserved)
+	})
+}
+
+func TestIDDAOReserveTaken(t *testing.T) {
+	testIDDAO(t, func(tx Transaction, dao *IDDAO) {
+		reserved, err := dao.Reserve("taken")
+		assert.Nil(t, err)
+		assert.False(t, reserved)
+	})
+}
+
+func TestIDDAOReserveExpired(t *testing.T) {
+	testIDDAO(t, func(tx Transaction, dao *IDDAO) {
+		reserved, err := dao.Reserve("expired")
+		assert.Nil(t, err)
+		assert.True(t, reserved)
+	})
+}
+
+func testIDDAO(t *testing.T, callback func(tx Transaction, dao *IDDAO)) {
+	testTransaction(t, func(tx Transaction) {
+		callback(tx, NewIDDAO(tx))
+	})
+}
@@ -0,0 +1,16 @@
+//go:build cgo
+// +build cgo
+
+package sqlite
+
+import (
+	sqlite "github.com/mattn/go-sqlite3"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// isLockedErr returns whether the given error was caused by another
+// connection locking the database.
+func isLockedErr(err error) bool {
+	var sqliteErr sqlite.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite.ErrBusy || sqliteErr.Code == sqlite.ErrLocked)
+}
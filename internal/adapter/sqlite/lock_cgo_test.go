@@ -0,0 +1,25 @@
+//go:build cgo
+// +build cgo
+
+package sqlite
+
+import (
+	"errors"
+	"testing"
+
+	sqlite "github.com/mattn/go-sqlite3"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestTranslateLockErrors(t *testing.T) {
+	test := func(err error, locked bool) {
+		var lockedErr core.ErrIndexLocked
+		assert.Equal(t, errors.As(translateErr(err), &lockedErr), locked)
+	}
+
+	test(nil, false)
+	test(sqlite.Error{Code: sqlite.ErrConstraint}, false)
+	test(sqlite.Error{Code: sqlite.ErrBusy}, true)
+	test(sqlite.Error{Code: sqlite.ErrLocked}, true)
+}
@@ -0,0 +1,13 @@
+//go:build !cgo
+// +build !cgo
+
+package sqlite
+
+// isLockedErr returns whether the given error was caused by another
+// connection locking the database.
+//
+// Without cgo, the SQLite driver is a stub which can't open any database, so
+// no error is caused by a lock.
+func isLockedErr(err error) bool {
+	return false
+}
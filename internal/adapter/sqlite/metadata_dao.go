@@ -7,7 +7,12 @@ import (
 )
 
 // Known metadata keys.
-var reindexingRequiredKey = "zk.reindexing_required"
+var (
+	reindexingRequiredKey   = "zk.reindexing_required"
+	lastIndexingDateKey     = "zk.last_indexing_date"
+	lastIndexingDurationKey = "zk.last_indexing_duration"
+	ftsTokenizerKey         = "zk.fts_tokenizer"
+)
 
 // MetadataDAO persists arbitrary key/value pairs in the SQLite database.
 type MetadataDAO struct {
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/mickael-menu/zk/internal/core"
 	"github.com/mickael-menu/zk/internal/util"
@@ -28,6 +29,7 @@ type NoteDAO struct {
 	indexedStmt            *LazyStmt
 	addStmt                *LazyStmt
 	updateStmt             *LazyStmt
+	touchStmt              *LazyStmt
 	removeStmt             *LazyStmt
 	findIdByPathStmt       *LazyStmt
 	findIdByPathPrefixStmt *LazyStmt
@@ -35,6 +37,10 @@ type NoteDAO struct {
 	addLinkStmt            *LazyStmt
 	setLinksTargetStmt     *LazyStmt
 	removeLinksStmt        *LazyStmt
+	addMetadataStmt        *LazyStmt
+	removeMetadataStmt     *LazyStmt
+	unresolvedLinksStmt    *LazyStmt
+	noteLinksStmt          *LazyStmt
 }
 
 // NewNoteDAO creates a new instance of a DAO working on the given database
@@ -46,20 +52,29 @@ func NewNoteDAO(tx Transaction, logger util.Logger) *NoteDAO {
 
 		// Get file info about all indexed notes.
 		indexedStmt: tx.PrepareLazy(`
-			SELECT path, modified from notes
+			SELECT path, modified, file_modified, checksum from notes
 			 ORDER BY sortable_path ASC
 		`),
 
 		// Add a new note to the index.
 		addStmt: tx.PrepareLazy(`
-			INSERT INTO notes (path, sortable_path, title, lead, body, raw_content, word_count, metadata, checksum, created, modified)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			INSERT INTO notes (path, sortable_path, title, lead, summary, body, raw_content, word_count, metadata, tag_names, checksum, created, modified, file_modified)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`),
 
-		// Update the content of a note.
+		// Update the content of a note, counting the edits which changed
+		// its checksum.
 		updateStmt: tx.PrepareLazy(`
 			UPDATE notes
-			   SET title = ?, lead = ?, body = ?, raw_content = ?, word_count = ?, metadata = ?, checksum = ?, modified = ?
+			   SET title = ?, lead = ?, summary = ?, body = ?, raw_content = ?, word_count = ?, metadata = ?, tag_names = ?, edit_count = edit_count + (checksum != ?), checksum = ?, modified = ?, file_modified = ?
+			 WHERE path = ?
+		`),
+
+		// Update the modification date of a note file whose content didn't
+		// change.
+		touchStmt: tx.PrepareLazy(`
+			UPDATE notes
+			   SET file_modified = ?
 			 WHERE path = ?
 		`),
 
@@ -108,6 +123,36 @@ func NewNoteDAO(tx Transaction, logger util.Logger) *NoteDAO {
 			DELETE FROM links
 			 WHERE source_id = ?
 		`),
+
+		// Add a flattened frontmatter value to a note.
+		addMetadataStmt: tx.PrepareLazy(`
+			INSERT INTO notes_metadata (note_id, key, value, number)
+			VALUES (?, ?, ?, ?)
+		`),
+
+		// Remove all the flattened frontmatter values of a note.
+		removeMetadataStmt: tx.PrepareLazy(`
+			DELETE FROM notes_metadata
+			 WHERE note_id = ?
+		`),
+
+		// Find the internal links without any target note.
+		unresolvedLinksStmt: tx.PrepareLazy(`
+			SELECT n.path, l.href
+			  FROM links l
+			  JOIN notes n ON n.id = l.source_id
+			 WHERE l.target_id IS NULL AND l.external = 0
+			 ORDER BY n.sortable_path, l.id
+		`),
+
+		// Find the links between two notes.
+		noteLinksStmt: tx.PrepareLazy(`
+			SELECT DISTINCT s.path, t.path, s.sortable_path, t.sortable_path
+			  FROM links l
+			  JOIN notes s ON s.id = l.source_id
+			  JOIN notes t ON t.id = l.target_id
+			 ORDER BY s.sortable_path, t.sortable_path
+		`),
 	}
 }
 
@@ -123,19 +168,27 @@ func (d *NoteDAO) Indexed() (<-chan paths.Metadata, error) {
 		defer close(c)
 		defer rows.Close()
 		var (
-			path     string
-			modified time.Time
+			path         string
+			modified     time.Time
+			fileModified sql.NullTime
+			checksum     string
 		)
 
 		for rows.Next() {
-			err := rows.Scan(&path, &modified)
+			err := rows.Scan(&path, &modified, &fileModified, &checksum)
 			if err != nil {
 				d.logger.Err(err)
 			}
+			// The modification date of the file is missing for the notes
+			// indexed before it was recorded.
+			if fileModified.Valid {
+				modified = fileModified.Time
+			}
 
 			c <- paths.Metadata{
 				Path:     path,
 				Modified: modified,
+				Checksum: checksum,
 			}
 		}
 
@@ -149,6 +202,53 @@ func (d *NoteDAO) Indexed() (<-chan paths.Metadata, error) {
 }
 
 // Add inserts a new note to the index.
+// FindUnresolvedLinks returns the internal links which don't target any
+// indexed note.
+func (d *NoteDAO) FindUnresolvedLinks() ([]core.UnresolvedLink, error) {
+	links := []core.UnresolvedLink{}
+
+	rows, err := d.unresolvedLinksStmt.Query()
+	if err != nil {
+		return links, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link core.UnresolvedLink
+		err := rows.Scan(&link.SourcePath, &link.Href)
+		if err != nil {
+			return links, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// FindNoteLinks returns the links between two notes, once per source and
+// target.
+func (d *NoteDAO) FindNoteLinks() ([]core.NoteLink, error) {
+	links := []core.NoteLink{}
+
+	rows, err := d.noteLinksStmt.Query()
+	if err != nil {
+		return links, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link core.NoteLink
+		var sourceSortablePath, targetSortablePath string
+		err := rows.Scan(&link.SourcePath, &link.TargetPath, &sourceSortablePath, &targetSortablePath)
+		if err != nil {
+			return links, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
 func (d *NoteDAO) Add(note core.Note) (core.NoteID, error) {
 	// For sortable_path, we replace in path / by the shortest non printable
 	// character available to make it sortable. Without this, sorting by the
@@ -160,9 +260,9 @@ func (d *NoteDAO) Add(note core.Note) (core.NoteID, error) {
 
 	metadata := d.metadataToJSON(note)
 	res, err := d.addStmt.Exec(
-		note.Path, sortablePath, note.Title, note.Lead, note.Body,
-		note.RawContent, note.WordCount, metadata, note.Checksum, note.Created,
-		note.Modified,
+		note.Path, sortablePath, note.Title, note.Lead, note.Summary, note.Body,
+		note.RawContent, note.WordCount, metadata, tagNames(note), note.Checksum,
+		note.Created, note.Modified, note.Modified,
 	)
 	if err != nil {
 		return 0, err
@@ -175,6 +275,10 @@ func (d *NoteDAO) Add(note core.Note) (core.NoteID, error) {
 
 	id := core.NoteID(lastId)
 	err = d.addLinks(id, note)
+	if err != nil {
+		return id, err
+	}
+	err = d.addMetadata(id, note)
 	return id, err
 }
 
@@ -185,13 +289,13 @@ func (d *NoteDAO) Update(note core.Note) (core.NoteID, error) {
 		return 0, err
 	}
 	if !id.IsValid() {
-		return 0, errors.New("note not found in the index")
+		return 0, core.ErrNoteNotFound(note.Path)
 	}
 
 	metadata := d.metadataToJSON(note)
 	_, err = d.updateStmt.Exec(
-		note.Title, note.Lead, note.Body, note.RawContent, note.WordCount,
-		metadata, note.Checksum, note.Modified, note.Path,
+		note.Title, note.Lead, note.Summary, note.Body, note.RawContent, note.WordCount,
+		metadata, tagNames(note), note.Checksum, note.Checksum, note.Modified, note.Modified, note.Path,
 	)
 	if err != nil {
 		return id, err
@@ -203,9 +307,30 @@ func (d *NoteDAO) Update(note core.Note) (core.NoteID, error) {
 	}
 
 	err = d.addLinks(id, note)
+	if err != nil {
+		return id, err
+	}
+
+	_, err = d.removeMetadataStmt.Exec(d.idToSql(id))
+	if err != nil {
+		return id, err
+	}
+	err = d.addMetadata(id, note)
 	return id, err
 }
 
+// Touch updates the modification date of a note file, without changing the
+// indexed note.
+func (d *NoteDAO) Touch(path string, modified time.Time) error {
+	_, err := d.touchStmt.Exec(modified, path)
+	return err
+}
+
+// tagNames returns the tags of the note indexed in the full-text search.
+func tagNames(note core.Note) string {
+	return strings.Join(note.Tags, " ")
+}
+
 func (d *NoteDAO) metadataToJSON(note core.Note) string {
 	json, err := json.Marshal(note.Metadata)
 	if err != nil {
@@ -217,6 +342,57 @@ func (d *NoteDAO) metadataToJSON(note core.Note) string {
 	return string(json)
 }
 
+// addMetadata inserts the flattened frontmatter values of the given note, to
+// filter and sort the notes by any key.
+func (d *NoteDAO) addMetadata(id core.NoteID, note core.Note) error {
+	for _, field := range flattenMetadata("", note.Metadata) {
+		_, err := d.addMetadataStmt.Exec(id, field.key, field.value, field.number)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metadataField is a frontmatter value indexed in the notes_metadata table.
+type metadataField struct {
+	key    string
+	value  string
+	number sql.NullFloat64
+}
+
+// flattenMetadata converts frontmatter values into a list of fields. The
+// nested keys are joined with dots and lowercased, and a list gives a field
+// per item.
+func flattenMetadata(key string, value interface{}) []metadataField {
+	fields := []metadataField{}
+	switch value := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		for k, v := range value {
+			k = strings.ToLower(k)
+			if key != "" {
+				k = key + "." + k
+			}
+			fields = append(fields, flattenMetadata(k, v)...)
+		}
+	case []interface{}:
+		for _, item := range value {
+			fields = append(fields, flattenMetadata(key, item)...)
+		}
+	default:
+		str := strings.TrimSpace(fmt.Sprint(value))
+		field := metadataField{key: key, value: str}
+		if _, ok := value.(bool); !ok {
+			if number, err := strconv.ParseFloat(str, 64); err == nil {
+				field.number = sql.NullFloat64{Float64: number, Valid: true}
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
 // addLinks inserts all the outbound links of the given note.
 func (d *NoteDAO) addLinks(id core.NoteID, note core.Note) error {
 	for _, link := range note.Links {
@@ -256,7 +432,7 @@ func (d *NoteDAO) Remove(path string) error {
 		return err
 	}
 	if !id.IsValid() {
-		return errors.New("note not found in the index")
+		return core.ErrNoteNotFound(path)
 	}
 
 	_, err = d.removeStmt.Exec(id)
@@ -340,16 +516,16 @@ func (d *NoteDAO) FindMinimal(opts core.NoteFindOpts) ([]core.MinimalNote, error
 		}
 	}
 
-	return notes, nil
+	return notes, rows.Err()
 }
 
 func (d *NoteDAO) scanMinimalNote(row RowScanner) (*core.MinimalNote, error) {
 	var (
-		id                        int
-		path, title, metadataJSON string
+		id, wordCount                      int
+		path, title, summary, metadataJSON string
 	)
 
-	err := row.Scan(&id, &path, &title, &metadataJSON)
+	err := row.Scan(&id, &path, &title, &summary, &metadataJSON, &wordCount)
 	switch {
 	case err == sql.ErrNoRows:
 		return nil, nil
@@ -362,10 +538,12 @@ func (d *NoteDAO) scanMinimalNote(row RowScanner) (*core.MinimalNote, error) {
 		}
 
 		return &core.MinimalNote{
-			ID:       core.NoteID(id),
-			Path:     path,
-			Title:    title,
-			Metadata: metadata,
+			ID:        core.NoteID(id),
+			Path:      path,
+			Title:     title,
+			Summary:   summary,
+			WordCount: wordCount,
+			Metadata:  metadata,
 		}, nil
 	}
 }
@@ -396,21 +574,24 @@ func (d *NoteDAO) Find(opts core.NoteFindOpts) ([]core.ContextualNote, error) {
 		}
 	}
 
-	return notes, nil
+	return notes, rows.Err()
 }
 
 func (d *NoteDAO) scanNote(row RowScanner) (*core.ContextualNote, error) {
 	var (
-		id, wordCount                 int
-		title, lead, body, rawContent string
-		snippets, tags                sql.NullString
-		path, metadataJSON, checksum  string
-		created, modified             time.Time
+		id, wordCount                          int
+		title, lead, summary, body, rawContent string
+		snippets, tags                         sql.NullString
+		path, metadataJSON, checksum           string
+		created, modified                      time.Time
+		score, maturity                        float64
+		distance                               int
 	)
 
 	err := row.Scan(
-		&id, &path, &title, &metadataJSON, &lead, &body, &rawContent,
-		&wordCount, &created, &modified, &checksum, &tags, &snippets,
+		&id, &path, &title, &summary, &metadataJSON, &wordCount, &lead, &body,
+		&rawContent, &created, &modified, &checksum, &tags, &snippets, &score,
+		&distance, &maturity,
 	)
 	switch {
 	case err == sql.ErrNoRows:
@@ -425,11 +606,15 @@ func (d *NoteDAO) scanNote(row RowScanner) (*core.ContextualNote, error) {
 
 		return &core.ContextualNote{
 			Snippets: parseListFromNullString(snippets),
+			Score:    score,
+			Distance: distance,
+			Maturity: maturity,
 			Note: core.Note{
 				ID:         core.NoteID(id),
 				Path:       path,
 				Title:      title,
 				Lead:       lead,
+				Summary:    summary,
 				Body:       body,
 				RawContent: rawContent,
 				WordCount:  wordCount,
@@ -509,6 +694,8 @@ func (d *NoteDAO) expandMentionsIntoMatch(opts core.NoteFindOpts) (core.NoteFind
 
 func (d *NoteDAO) findRows(opts core.NoteFindOpts, minimal bool) (*sql.Rows, error) {
 	snippetCol := `n.lead`
+	scoreCol := `0`
+	distanceCol := `0`
 	joinClauses := []string{}
 	whereExprs := []string{}
 	additionalOrderTerms := []string{}
@@ -539,6 +726,11 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, minimal bool) (*sql.Rows, err
 			if direction != 0 {
 				snippetCol = "GROUP_CONCAT(REPLACE(l.snippet, l.title, '<zk:match>' || l.title || '</zk:match>'), '\x01')"
 			}
+			if recursive {
+				distanceCol = "MIN(l.distance)"
+			} else {
+				distanceCol = "1"
+			}
 
 			joinOns := make([]string, 0)
 			if direction <= 0 {
@@ -592,11 +784,48 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, minimal bool) (*sql.Rows, err
 			whereExprs = append(whereExprs, `n.raw_content LIKE '%' || ? || '%' ESCAPE '\'`)
 			args = append(args, escapeLikeTerm(opts.Match.String(), '\\'))
 		} else {
-			snippetCol = `snippet(fts_match.notes_fts, 2, '<zk:match>', '</zk:match>', '…', 20)`
-			joinClauses = append(joinClauses, "JOIN notes_fts fts_match ON n.id = fts_match.rowid")
-			additionalOrderTerms = append(additionalOrderTerms, `bm25(fts_match.notes_fts, 1000.0, 500.0, 1.0)`)
-			whereExprs = append(whereExprs, "fts_match.notes_fts MATCH ?")
-			args = append(args, fts5.ConvertQuery(opts.Match.String()))
+			weights := core.DefaultSearchWeights
+			if opts.MatchWeights != nil {
+				weights = *opts.MatchWeights
+			}
+			match := fts5.ConvertQuery(opts.Match.String())
+			args = append(args, weights.Path, weights.Title, weights.Body, weights.Tags, match)
+
+			// Notes linking to an asset with a matching extracted text are
+			// matched as well. When a note matches several times, the
+			// snippet of the best ranked match is kept.
+			//
+			// The column filters target the fields of the notes, so the
+			// assets can't match them. The UNION is kept though, as it
+			// prevents SQLite from flattening the subquery, which breaks
+			// bm25().
+			assetMatchExpr := `asset_texts_fts MATCH ?`
+			if len(fts5.ColumnFilters(opts.Match.String())) > 0 {
+				assetMatchExpr = `0`
+			} else {
+				args = append(args, match)
+			}
+
+			snippetCol = `fts_match.snippet`
+			scoreCol = `-fts_match.rank`
+			joinClauses = append(joinClauses, `JOIN (
+SELECT note_id, MIN(rank) AS rank, snippet FROM (
+    SELECT rowid AS note_id,
+           bm25(notes_fts, ?, ?, ?, ?) AS rank,
+           snippet(notes_fts, 2, '<zk:match>', '</zk:match>', '…', 20) AS snippet
+      FROM notes_fts
+     WHERE notes_fts MATCH ?
+     UNION ALL
+    SELECT l.source_id,
+           bm25(asset_texts_fts, 0.0, 1.0),
+           snippet(asset_texts_fts, 1, '<zk:match>', '</zk:match>', '…', 20)
+      FROM asset_texts_fts
+      JOIN links l ON l.target_id IS NULL AND l.external = 0 AND href_path(l.href) = asset_texts_fts.path
+     WHERE `+assetMatchExpr+`
+)
+GROUP BY note_id
+) fts_match ON n.id = fts_match.note_id`)
+			additionalOrderTerms = append(additionalOrderTerms, `fts_match.rank`)
 		}
 	}
 
@@ -609,7 +838,7 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, minimal bool) (*sql.Rows, err
 			}
 			args = append(args, path)
 		}
-		whereExprs = append(whereExprs, strings.Join(regexes, " OR "))
+		whereExprs = append(whereExprs, "("+strings.Join(regexes, " OR ")+")")
 	}
 
 	if opts.ExcludePaths != nil {
@@ -624,6 +853,36 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, minimal bool) (*sql.Rows, err
 		whereExprs = append(whereExprs, strings.Join(regexes, " AND "))
 	}
 
+	for _, dir := range opts.ExcludeDirs {
+		prefix := strings.TrimSuffix(dir, "/") + "/"
+		whereExprs = append(whereExprs, "substr(n.path, 1, ?) != ?")
+		args = append(args, utf8.RuneCountInString(prefix), prefix)
+	}
+
+	for _, filter := range opts.Metadata {
+		expr, filterArgs := metadataFilterExpr(filter)
+		whereExprs = append(whereExprs, expr)
+		args = append(args, filterArgs...)
+	}
+
+	if opts.ExactPaths != nil {
+		placeholders := make([]string, 0, len(opts.ExactPaths))
+		for _, path := range opts.ExactPaths {
+			placeholders = append(placeholders, "?")
+			args = append(args, path)
+		}
+		whereExprs = append(whereExprs, "n.path IN ("+strings.Join(placeholders, ",")+")")
+	}
+
+	if opts.Aliases != nil {
+		expr := "has_alias(n.metadata"
+		for _, alias := range opts.Aliases {
+			expr += ", ?"
+			args = append(args, alias)
+		}
+		whereExprs = append(whereExprs, expr+")")
+	}
+
 	if opts.Tags != nil {
 		separatorRegex := regexp.MustCompile(`(\ OR\ )|\|`)
 		for _, tagsArg := range opts.Tags {
@@ -646,8 +905,10 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, minimal bool) (*sql.Rows, err
 				if len(tag) == 0 {
 					continue
 				}
-				globs = append(globs, "t.name GLOB ?")
-				args = append(args, tag)
+				// A parent tag matches its descendants in the tag hierarchy,
+				// e.g. project matches project/alpha.
+				globs = append(globs, "t.name GLOB ? OR t.name GLOB ?")
+				args = append(args, tag, tag+core.TagSeparator+"*")
 			}
 
 			if len(globs) == 0 {
@@ -705,6 +966,25 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 		}
 	}
 
+	if opts.LinkPath != nil {
+		ids, err := d.findShortestPath(*opts.LinkPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			whereExprs = append(whereExprs, "0")
+		} else {
+			// Lists the notes in the order of the path.
+			positions := ""
+			for i, id := range ids {
+				positions += fmt.Sprintf(" WHEN %d THEN %d", id, i)
+			}
+			distanceCol = "CASE n.id" + positions + " END"
+			whereExprs = append(whereExprs, "n.id IN ("+d.joinIds(ids, ",")+")")
+			additionalOrderTerms = append([]string{distanceCol}, additionalOrderTerms...)
+		}
+	}
+
 	if opts.Related != nil {
 		maxDistance = 2
 		err := setupLinkFilter(opts.Related, 0, false, true)
@@ -720,6 +1000,18 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 		)`)
 	}
 
+	if opts.RecentlyOpened {
+		whereExprs = append(whereExprs, "n.id IN (SELECT note_id FROM history)")
+		additionalOrderTerms = append(additionalOrderTerms, orderTerm(core.NoteSorter{Field: core.NoteSortOpened}))
+	}
+
+	switch opts.Trash {
+	case core.TrashFilterExclude:
+		whereExprs = append(whereExprs, "NOT is_trashed(n.metadata)")
+	case core.TrashFilterOnly:
+		whereExprs = append(whereExprs, "is_trashed(n.metadata)")
+	}
+
 	if opts.CreatedStart != nil {
 		whereExprs = append(whereExprs, "created >= ?")
 		args = append(args, opts.CreatedStart)
@@ -746,6 +1038,9 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 
 	orderTerms := []string{}
 	for _, sorter := range opts.Sorters {
+		if sorter.Field == core.NoteSortRelevance && scoreCol == `0` {
+			return nil, errors.New("sorting by relevance requires a full-text search with --match")
+		}
 		orderTerms = append(orderTerms, orderTerm(sorter))
 	}
 	orderTerms = append(orderTerms, additionalOrderTerms...)
@@ -783,9 +1078,9 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 		query += "\n)\n"
 	}
 
-	query += "SELECT n.id, n.path, n.title, n.metadata"
+	query += "SELECT n.id, n.path, n.title, n.summary, n.metadata, n.word_count"
 	if !minimal {
-		query += fmt.Sprintf(", n.lead, n.body, n.raw_content, n.word_count, n.created, n.modified, n.checksum, n.tags, %s AS snippet", snippetCol)
+		query += fmt.Sprintf(", n.lead, n.body, n.raw_content, n.created, n.modified, n.checksum, n.tags, %s AS snippet, %s AS score, %s AS distance, %s AS maturity", snippetCol, scoreCol, distanceCol, maturityExpr)
 	}
 
 	query += "\nFROM notes_with_metadata n\n"
@@ -814,6 +1109,120 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 	return d.tx.Query(query, args...)
 }
 
+// findShortestPath returns the IDs of the notes on the shortest path of links
+// between two notes, or nil if they are not connected.
+func (d *NoteDAO) findShortestPath(filter core.LinkPathFilter) ([]core.NoteID, error) {
+	wrap := errors.Wrapperf("%s: failed to find a link path to %s", filter.From, filter.To)
+
+	fromIDs, err := d.findIdsByPathPrefixes([]string{filter.From})
+	if err != nil {
+		return nil, wrap(err)
+	}
+	toIDs, err := d.findIdsByPathPrefixes([]string{filter.To})
+	if err != nil {
+		return nil, wrap(err)
+	}
+	from := fromIDs[0]
+	to := toIDs[0]
+	if from == to {
+		return []core.NoteID{from}, nil
+	}
+
+	distanceExpr := ""
+	if filter.MaxDistance > 0 {
+		distanceExpr = fmt.Sprintf(" AND w.distance < %d", filter.MaxDistance)
+	}
+
+	// The links are walked breadth-first, so the first path reaching the
+	// target is one of the shortest.
+	row := d.tx.QueryRow(`
+WITH RECURSIVE walk(id, distance, path) AS (
+    SELECT ?, 0, '.' || ? || '.'
+
+     UNION ALL
+
+    SELECT l.target_id, w.distance + 1, w.path || l.target_id || '.'
+      FROM links AS l
+      JOIN walk AS w
+        ON l.source_id = w.id
+     WHERE l.target_id IS NOT NULL
+       AND w.id != ?
+       AND w.path NOT LIKE '%.' || l.target_id || '.%'`+distanceExpr+`
+     LIMIT 100000
+)
+SELECT path FROM walk WHERE id = ? LIMIT 1
+`, from, from, to, to)
+
+	var path string
+	err = row.Scan(&path)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, nil
+	case err != nil:
+		return nil, wrap(err)
+	}
+
+	ids := []core.NoteID{}
+	for _, id := range strings.Split(strings.Trim(path, "."), ".") {
+		i, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		ids = append(ids, core.NoteID(i))
+	}
+	return ids, nil
+}
+
+// metadataFilterExpr returns the SQL expression matching the notes with a
+// frontmatter value satisfying the given filter, with its arguments.
+//
+// The values are compared as numbers when the filter value is a number,
+// otherwise as text ignoring case.
+func metadataFilterExpr(filter core.MetadataFilter) (string, []interface{}) {
+	exists := "EXISTS (SELECT 1 FROM notes_metadata m WHERE m.note_id = n.id AND m.key = ?%s)"
+	args := []interface{}{filter.Key}
+	if filter.Op == core.MetadataOpExists {
+		return fmt.Sprintf(exists, ""), args
+	}
+
+	op := string(filter.Op)
+	if filter.Op == core.MetadataOpNotEqual {
+		// The notes without the key don't have the value either.
+		exists = "NOT " + exists
+		op = "="
+	}
+
+	number, err := strconv.ParseFloat(filter.Value, 64)
+	if err != nil {
+		args = append(args, filter.Value)
+		return fmt.Sprintf(exists, " AND m.value "+op+" ? COLLATE NOCASE"), args
+	}
+	if op == "=" {
+		// A number can be written in different ways, e.g. 2 and 2.0.
+		args = append(args, number, filter.Value)
+		return fmt.Sprintf(exists, " AND (m.number = ? OR m.value = ?)"), args
+	}
+	args = append(args, number)
+	return fmt.Sprintf(exists, " AND m.number "+op+" ?"), args
+}
+
+// metadataOrderTerm returns the SQL order term sorting the notes by the value
+// of a frontmatter key. The numbers come before the other values, and the
+// notes without the key come last.
+func metadataOrderTerm(key string, order string) string {
+	aggregate := "MIN"
+	if strings.TrimSpace(order) == "DESC" {
+		aggregate = "MAX"
+	}
+	value := func(column string) string {
+		return "(SELECT " + aggregate + "(m." + column + ") FROM notes_metadata m WHERE m.note_id = n.id AND m.key = " + quoteSQLString(key) + ")"
+	}
+	return value("value") + " IS NULL, " +
+		value("number") + " IS NULL, " +
+		value("number") + order + ", " +
+		value("value COLLATE NOCASE") + " COLLATE NOCASE" + order
+}
+
 func orderTerm(sorter core.NoteSorter) string {
 	order := " ASC"
 	if !sorter.Ascending {
@@ -835,6 +1244,18 @@ func orderTerm(sorter core.NoteSorter) string {
 		return "n.word_count" + order
 	case core.NoteSortPathLength:
 		return "LENGTH(path)" + order
+	case core.NoteSortMaturity:
+		return maturityExpr + order
+	case core.NoteSortMetadata:
+		return metadataOrderTerm(sorter.Key, order)
+	case core.NoteSortOpened:
+		return "(SELECT h.date FROM history h WHERE h.note_id = n.id)" + order
+	case core.NoteSortRelevance:
+		// The BM25 rank is lower for the most relevant notes.
+		if sorter.Ascending {
+			return "fts_match.rank DESC"
+		}
+		return "fts_match.rank ASC"
 	default:
 		panic(fmt.Sprintf("%v: unknown core.NoteSortField", sorter.Field))
 	}
@@ -869,6 +1290,57 @@ func unmarshalMetadata(metadataJSON string) (metadata map[string]interface{}, er
 	return
 }
 
+// maturityExpr computes the maturity of the note n from its age, edits,
+// inbound links and word count. The links from the note to itself are
+// ignored.
+const maturityExpr = `maturity(
+	julianday('now') - julianday(n.created), n.edit_count,
+	(SELECT COUNT(DISTINCT source_id) FROM links WHERE target_id = n.id AND source_id != n.id),
+	n.word_count
+)`
+
+// maturity returns the maturity of a note created the given number of days
+// ago.
+//
+// It is exposed as a custom SQLite function as `maturity()`.
+func maturity(ageDays float64, editCount int, inboundLinks int, wordCount int) float64 {
+	return core.NoteMaturity(time.Duration(ageDays*24*float64(time.Hour)), editCount, inboundLinks, wordCount)
+}
+
+// isTrashed returns whether the given JSON metadata marks a note as deleted.
+//
+// It is exposed as a custom SQLite function as `is_trashed()`.
+func isTrashed(metadataJSON string) bool {
+	metadata, err := unmarshalMetadata(metadataJSON)
+	return err == nil && core.IsTrashed(metadata)
+}
+
+// hasAlias returns whether the given note metadata declares one of the given
+// aliases, ignoring case.
+//
+// It is exposed as a custom SQLite function as `has_alias()`.
+func hasAlias(metadataJSON string, aliases ...string) bool {
+	metadata, err := unmarshalMetadata(metadataJSON)
+	if err != nil {
+		return false
+	}
+	for _, declared := range (core.MinimalNote{Metadata: metadata}).Aliases() {
+		for _, alias := range aliases {
+			if strings.EqualFold(declared, strings.TrimSpace(alias)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inReview returns whether the given JSON metadata adds a note to the review
+// queue.
+func inReview(metadataJSON string) bool {
+	metadata, err := unmarshalMetadata(metadataJSON)
+	return err == nil && core.IsInReview(metadata)
+}
+
 // buildMentionQuery creates an FTS5 predicate to match the given note's title
 // (or aliases from the metadata) in the content of another note.
 //
@@ -886,19 +1358,10 @@ func buildMentionQuery(title, metadataJSON string) string {
 
 	appendTitle(title)
 
-	// Support `aliases` key in the YAML frontmatter, like Obsidian:
-	// https://publish.obsidian.md/help/How+to/Add+aliases+to+note
 	metadata, err := unmarshalMetadata(metadataJSON)
 	if err == nil {
-		if aliases, ok := metadata["aliases"]; ok {
-			switch aliases := aliases.(type) {
-			case []interface{}:
-				for _, alias := range aliases {
-					appendTitle(fmt.Sprint(alias))
-				}
-			case string:
-				appendTitle(aliases)
-			}
+		for _, alias := range (core.MinimalNote{Metadata: metadata}).Aliases() {
+			appendTitle(alias)
 		}
 	}
 
@@ -102,12 +102,37 @@ func TestNoteDAOIndexed(t *testing.T) {
 	})
 }
 
+func TestNoteDAOFindUnresolvedLinks(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		links, err := dao.FindUnresolvedLinks()
+		assert.Nil(t, err)
+		assert.Equal(t, links, []core.UnresolvedLink{
+			{SourcePath: "index.md", Href: "missing"},
+		})
+	})
+}
+
+func TestNoteDAOFindNoteLinks(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		links, err := dao.FindNoteLinks()
+		assert.Nil(t, err)
+		assert.Equal(t, links, []core.NoteLink{
+			{SourcePath: "f39c8.md", TargetPath: "log/2021-01-03.md"},
+			{SourcePath: "f39c8.md", TargetPath: "ref/test/a.md"},
+			{SourcePath: "index.md", TargetPath: "f39c8.md"},
+			{SourcePath: "log/2021-01-03.md", TargetPath: "log/2021-01-04.md"},
+			{SourcePath: "log/2021-01-04.md", TargetPath: "index.md"},
+		})
+	})
+}
+
 func TestNoteDAOAdd(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
 		_, err := dao.Add(core.Note{
 			Path:       "log/added.md",
 			Title:      "Added note",
 			Lead:       "Note",
+			Summary:    "Note summary",
 			Body:       "Note body",
 			RawContent: "# Added note\nNote body",
 			WordCount:  2,
@@ -124,6 +149,7 @@ func TestNoteDAOAdd(t *testing.T) {
 			Path:       "log/added.md",
 			Title:      "Added note",
 			Lead:       "Note",
+			Summary:    "Note summary",
 			Body:       "Note body",
 			RawContent: "# Added note\nNote body",
 			WordCount:  2,
@@ -251,6 +277,7 @@ func TestNoteDAOUpdate(t *testing.T) {
 			Path:       "ref/test/a.md",
 			Title:      "Updated note",
 			Lead:       "Updated lead",
+			Summary:    "Updated summary",
 			Body:       "Updated body",
 			RawContent: "Updated raw content",
 			Checksum:   "updated checksum",
@@ -268,6 +295,7 @@ func TestNoteDAOUpdate(t *testing.T) {
 			Path:       "ref/test/a.md",
 			Title:      "Updated note",
 			Lead:       "Updated lead",
+			Summary:    "Updated summary",
 			Body:       "Updated body",
 			RawContent: "Updated raw content",
 			Checksum:   "updated checksum",
@@ -279,12 +307,105 @@ func TestNoteDAOUpdate(t *testing.T) {
 	})
 }
 
+func TestNoteDAOUpdateMetadata(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Update(core.Note{
+			Path: "ref/test/a.md",
+			Metadata: map[string]interface{}{
+				"status":   "draft",
+				"priority": 2,
+				"done":     true,
+				"aliases":  []interface{}{"A", "B"},
+				"project":  map[string]interface{}{"Name": "zk", "year": 2021.5},
+			},
+		})
+		assert.Nil(t, err)
+
+		rows, err := tx.Query(`
+			SELECT key, value, number FROM notes_metadata
+			 WHERE note_id = 6
+			 ORDER BY key, value
+		`)
+		assert.Nil(t, err)
+		defer rows.Close()
+
+		type metadataRow struct {
+			Key    string
+			Value  string
+			Number sql.NullFloat64
+		}
+		actual := []metadataRow{}
+		for rows.Next() {
+			var row metadataRow
+			assert.Nil(t, rows.Scan(&row.Key, &row.Value, &row.Number))
+			actual = append(actual, row)
+		}
+		assert.Equal(t, actual, []metadataRow{
+			{Key: "aliases", Value: "A"},
+			{Key: "aliases", Value: "B"},
+			{Key: "done", Value: "true"},
+			{Key: "priority", Value: "2", Number: sql.NullFloat64{Float64: 2, Valid: true}},
+			{Key: "project.name", Value: "zk"},
+			{Key: "project.year", Value: "2021.5", Number: sql.NullFloat64{Float64: 2021.5, Valid: true}},
+			{Key: "status", Value: "draft"},
+		})
+	})
+}
+
+func TestNoteDAOTouch(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		err := dao.Touch("ref/test/a.md", time.Date(2021, 3, 4, 10, 11, 12, 0, time.UTC))
+		assert.Nil(t, err)
+
+		// The note modification date is left unchanged.
+		row, err := queryNoteRow(tx, `path = "ref/test/a.md"`)
+		assert.Nil(t, err)
+		assert.Equal(t, row.Modified, time.Date(2019, 11, 20, 20, 34, 6, 0, time.UTC))
+
+		c, err := dao.Indexed()
+		assert.Nil(t, err)
+		for metadata := range c {
+			if metadata.Path == "ref/test/a.md" {
+				assert.Equal(t, metadata, paths.Metadata{
+					Path:     "ref/test/a.md",
+					Modified: time.Date(2021, 3, 4, 10, 11, 12, 0, time.UTC),
+					Checksum: "iecywst",
+				})
+			}
+		}
+	})
+}
+
+func TestNoteDAOUpdateCountsEdits(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		editCount := func() int {
+			var count int
+			err := tx.QueryRow(`SELECT edit_count FROM notes WHERE path = "ref/test/a.md"`).Scan(&count)
+			assert.Nil(t, err)
+			return count
+		}
+		update := func(checksum string) {
+			_, err := dao.Update(core.Note{Path: "ref/test/a.md", Checksum: checksum})
+			assert.Nil(t, err)
+		}
+
+		assert.Equal(t, editCount(), 0)
+		update("updated checksum")
+		assert.Equal(t, editCount(), 1)
+		// Reindexing an unchanged note is not an edit.
+		update("updated checksum")
+		assert.Equal(t, editCount(), 1)
+		update("another checksum")
+		assert.Equal(t, editCount(), 2)
+	})
+}
+
 func TestNoteDAOUpdateUnknown(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
 		_, err := dao.Update(core.Note{
 			Path: "unknown/unknown.md",
 		})
-		assert.Err(t, err, "note not found in the index")
+		assert.Equal(t, err, core.ErrNoteNotFound("unknown/unknown.md"))
 	})
 }
 
@@ -367,7 +488,7 @@ func TestNoteDAORemove(t *testing.T) {
 func TestNoteDAORemoveUnknown(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
 		err := dao.Remove("unknown/unknown.md")
-		assert.Err(t, err, "note not found in the index")
+		assert.Equal(t, err, core.ErrNoteNotFound("unknown/unknown.md"))
 	})
 }
 
@@ -397,19 +518,19 @@ func TestNoteDAOFindMinimalAll(t *testing.T) {
 		assert.Nil(t, err)
 
 		assert.Equal(t, notes, []core.MinimalNote{
-			{ID: 5, Path: "ref/test/b.md", Title: "A nested note", Metadata: map[string]interface{}{}},
-			{ID: 4, Path: "f39c8.md", Title: "An interesting note", Metadata: map[string]interface{}{}},
-			{ID: 6, Path: "ref/test/a.md", Title: "Another nested note", Metadata: map[string]interface{}{
+			{ID: 5, Path: "ref/test/b.md", Title: "A nested note", WordCount: 8, Metadata: map[string]interface{}{}},
+			{ID: 4, Path: "f39c8.md", Title: "An interesting note", WordCount: 5, Metadata: map[string]interface{}{}},
+			{ID: 6, Path: "ref/test/a.md", Title: "Another nested note", WordCount: 5, Metadata: map[string]interface{}{
 				"alias": "a.md",
 			}},
-			{ID: 1, Path: "log/2021-01-03.md", Title: "Daily note", Metadata: map[string]interface{}{
+			{ID: 1, Path: "log/2021-01-03.md", Title: "Daily note", WordCount: 3, Metadata: map[string]interface{}{
 				"author": "Dom",
 			}},
-			{ID: 7, Path: "log/2021-02-04.md", Title: "February 4, 2021", Metadata: map[string]interface{}{}},
-			{ID: 3, Path: "index.md", Title: "Index", Metadata: map[string]interface{}{
+			{ID: 7, Path: "log/2021-02-04.md", Title: "February 4, 2021", WordCount: 4, Metadata: map[string]interface{}{}},
+			{ID: 3, Path: "index.md", Title: "Index", WordCount: 4, Metadata: map[string]interface{}{
 				"aliases": []interface{}{"First page"},
 			}},
-			{ID: 2, Path: "log/2021-01-04.md", Title: "January 4, 2021", Metadata: map[string]interface{}{}},
+			{ID: 2, Path: "log/2021-01-04.md", Title: "January 4, 2021", WordCount: 4, Metadata: map[string]interface{}{}},
 		})
 	})
 }
@@ -424,13 +545,13 @@ func TestNoteDAOFindMinimalWithFilter(t *testing.T) {
 		assert.Nil(t, err)
 
 		assert.Equal(t, notes, []core.MinimalNote{
-			{ID: 1, Path: "log/2021-01-03.md", Title: "Daily note", Metadata: map[string]interface{}{
+			{ID: 1, Path: "log/2021-01-03.md", Title: "Daily note", WordCount: 3, Metadata: map[string]interface{}{
 				"author": "Dom",
 			}},
-			{ID: 3, Path: "index.md", Title: "Index", Metadata: map[string]interface{}{
+			{ID: 3, Path: "index.md", Title: "Index", WordCount: 4, Metadata: map[string]interface{}{
 				"aliases": []interface{}{"First page"},
 			}},
-			{ID: 7, Path: "log/2021-02-04.md", Title: "February 4, 2021", Metadata: map[string]interface{}{}},
+			{ID: 7, Path: "log/2021-02-04.md", Title: "February 4, 2021", WordCount: 4, Metadata: map[string]interface{}{}},
 		})
 	})
 }
@@ -468,6 +589,49 @@ func TestNoteDAOFindTag(t *testing.T) {
 	test([]string{"NOTfiction"}, []string{"ref/test/b.md", "f39c8.md", "ref/test/a.md", "log/2021-02-04.md", "index.md", "log/2021-01-04.md"})
 }
 
+func TestNoteDAOFindAliases(t *testing.T) {
+	test := func(aliases []string, expectedPaths []string) {
+		testNoteDAOFindPaths(t, core.NoteFindOpts{Aliases: aliases}, expectedPaths)
+	}
+
+	test([]string{"First page"}, []string{"index.md"})
+	test([]string{" first PAGE "}, []string{"index.md"})
+	test([]string{"unknown", "First page"}, []string{"index.md"})
+	test([]string{"First"}, []string{})
+	test([]string{}, []string{})
+}
+
+func TestNoteDAOFindTagDescendants(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := tx.Exec(`
+			INSERT INTO collections (id, kind, name)
+			VALUES (8, 'tag', 'fiction/classic'), (9, 'tag', 'fictional')
+		`)
+		assert.Nil(t, err)
+		_, err = tx.Exec(`
+			INSERT INTO notes_collections (note_id, collection_id)
+			VALUES (4, 8), (3, 9)
+		`)
+		assert.Nil(t, err)
+
+		test := func(tags []string, expectedPaths []string) {
+			notes, err := dao.Find(core.NoteFindOpts{Tags: tags})
+			assert.Nil(t, err)
+			paths := []string{}
+			for _, note := range notes {
+				paths = append(paths, note.Path)
+			}
+			assert.Equal(t, paths, expectedPaths)
+		}
+
+		test([]string{"fiction"}, []string{"f39c8.md", "log/2021-01-03.md"})
+		test([]string{"fiction/classic"}, []string{"f39c8.md"})
+		test([]string{"fiction/*"}, []string{"f39c8.md"})
+		test([]string{"classic"}, []string{})
+		test([]string{"-fiction"}, []string{"ref/test/b.md", "ref/test/a.md", "log/2021-02-04.md", "index.md", "log/2021-01-04.md"})
+	})
+}
+
 func TestNoteDAOFindMatch(t *testing.T) {
 	testNoteDAOFind(t,
 		core.NoteFindOpts{Match: opt.NewString("daily | index")},
@@ -491,6 +655,7 @@ func TestNoteDAOFindMatch(t *testing.T) {
 					Checksum: "iaefhv",
 				},
 				Snippets: []string{"<zk:match>Index</zk:match> of the Zettelkasten"},
+				Score:    3.2241130861459517,
 			},
 			{
 				Note: core.Note{
@@ -511,6 +676,7 @@ func TestNoteDAOFindMatch(t *testing.T) {
 					Checksum: "qwfpgj",
 				},
 				Snippets: []string{"A <zk:match>daily</zk:match> note\n\nWith lot of content"},
+				Score:    0.551434732781826,
 			},
 			{
 				Note: core.Note{
@@ -529,6 +695,7 @@ func TestNoteDAOFindMatch(t *testing.T) {
 					Checksum:   "earkte",
 				},
 				Snippets: []string{"A third <zk:match>daily</zk:match> note"},
+				Score:    0.2552298867711454,
 			},
 			{
 				Note: core.Note{
@@ -547,6 +714,7 @@ func TestNoteDAOFindMatch(t *testing.T) {
 					Checksum:   "arstde",
 				},
 				Snippets: []string{"A second <zk:match>daily</zk:match> note"},
+				Score:    0.2552298867711454,
 			},
 		},
 	)
@@ -569,6 +737,94 @@ func TestNoteDAOFindMatchWithSort(t *testing.T) {
 	)
 }
 
+func TestNoteDAOFindMatchSortedByRelevance(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			Match: opt.NewString("daily | index"),
+			Sorters: []core.NoteSorter{
+				{Field: core.NoteSortRelevance, Ascending: false},
+			},
+		},
+		[]string{"index.md", "log/2021-01-03.md", "log/2021-02-04.md", "log/2021-01-04.md"},
+	)
+
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			Match: opt.NewString("daily | index"),
+			Sorters: []core.NoteSorter{
+				{Field: core.NoteSortRelevance, Ascending: true},
+			},
+		},
+		[]string{"log/2021-02-04.md", "log/2021-01-04.md", "log/2021-01-03.md", "index.md"},
+	)
+}
+
+func TestNoteDAOFindRelevanceRequiresMatch(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Find(core.NoteFindOpts{
+			Sorters: []core.NoteSorter{{Field: core.NoteSortRelevance}},
+		})
+		assert.Err(t, err, "sorting by relevance requires a full-text search with --match")
+	})
+}
+
+func TestNoteDAOFindMatchWithWeights(t *testing.T) {
+	// Without the title boost, "Daily note" is ranked by its longer body.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			Match:        opt.NewString("daily | index"),
+			MatchWeights: &core.SearchWeights{Body: 1},
+		},
+		[]string{"index.md", "log/2021-02-04.md", "log/2021-01-04.md", "log/2021-01-03.md"},
+	)
+}
+
+func TestNoteDAOFindMatchTags(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Add(core.Note{
+			Path:  "tagged.md",
+			Title: "Tagged note",
+			Body:  "Some content",
+			Tags:  []string{"gardening", "tomato"},
+		})
+		assert.Nil(t, err)
+
+		notes, err := dao.Find(core.NoteFindOpts{Match: opt.NewString("gardening")})
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 1)
+		assert.Equal(t, notes[0].Path, "tagged.md")
+
+		// Ignoring the tags.
+		notes, err = dao.Find(core.NoteFindOpts{
+			Match:        opt.NewString("gardening"),
+			MatchWeights: &core.SearchWeights{Path: 1, Title: 1, Body: 1},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 1)
+		assert.True(t, notes[0].Score == 0)
+	})
+}
+
+func TestNoteDAOFindMatchColumn(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{Match: opt.NewString("title: daily")},
+		[]string{"log/2021-01-03.md"},
+	)
+}
+
+func TestNoteDAOFindMatchInLinkedAssets(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		err := NewAssetDAO(tx, &util.NullLogger).SetText(paths.Metadata{Path: "missing"}, "A scanned receipt")
+		assert.Nil(t, err)
+
+		notes, err := dao.Find(core.NoteFindOpts{Match: opt.NewString("receipt")})
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 1)
+		assert.Equal(t, notes[0].Path, "index.md")
+		assert.Equal(t, notes[0].Snippets, []string{"A scanned <zk:match>receipt</zk:match>"})
+	})
+}
+
 func TestNoteDAOFindExactMatch(t *testing.T) {
 	test := func(match string, expected []string) {
 		testNoteDAOFindPaths(t,
@@ -659,6 +915,83 @@ func TestNoteDAOFindExcludingMultiplePaths(t *testing.T) {
 	)
 }
 
+// Only the notes inside the directories are excluded, not the ones with a
+// similar name.
+func TestNoteDAOFindExcludingDirs(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			ExcludeDirs: []string{"ref/test", "log/2021"},
+		},
+		[]string{"f39c8.md", "log/2021-01-03.md", "log/2021-02-04.md", "index.md", "log/2021-01-04.md"},
+	)
+}
+
+func TestNoteDAOFindMetadata(t *testing.T) {
+	test := func(filters []string, expected []string) {
+		t.Helper()
+		opts := core.NoteFindOpts{Sorters: []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}}}
+		for _, str := range filters {
+			filter, err := core.MetadataFilterFromString(str)
+			assert.Nil(t, err)
+			opts.Metadata = append(opts.Metadata, filter)
+		}
+		testNoteDAOFindPaths(t, opts, expected)
+	}
+
+	test([]string{"status"}, []string{"index.md", "log/2021-01-03.md", "ref/test/b.md"})
+	test([]string{"status=draft"}, []string{"log/2021-01-03.md", "ref/test/b.md"})
+	test([]string{"status=published"}, []string{"index.md"})
+	test([]string{"status!=draft"}, []string{"f39c8.md", "index.md", "log/2021-01-04.md", "log/2021-02-04.md", "ref/test/a.md"})
+	test([]string{"priority=2.0"}, []string{"log/2021-01-03.md"})
+	test([]string{"priority>=2"}, []string{"index.md", "log/2021-01-03.md"})
+	test([]string{"priority>2"}, []string{"index.md"})
+	test([]string{"priority<10"}, []string{"log/2021-01-03.md"})
+	test([]string{"priority=high"}, []string{"ref/test/b.md"})
+	test([]string{"status=draft", "priority>1"}, []string{"log/2021-01-03.md"})
+	test([]string{"project.name=zk"}, []string{"ref/test/a.md"})
+	test([]string{"unknown"}, []string{})
+}
+
+func TestNoteDAOFindSortMetadata(t *testing.T) {
+	test := func(sorter string, expected []string) {
+		t.Helper()
+		sorters, err := core.NoteSortersFromStrings([]string{"path", sorter})
+		assert.Nil(t, err)
+		testNoteDAOFindPaths(t, core.NoteFindOpts{
+			Sorters:  sorters,
+			Metadata: []core.MetadataFilter{{Key: "priority"}},
+		}, expected)
+	}
+
+	// The numbers are sorted numerically before the other values.
+	test("meta.priority", []string{"log/2021-01-03.md", "index.md", "ref/test/b.md"})
+	test("meta.priority-", []string{"index.md", "log/2021-01-03.md", "ref/test/b.md"})
+
+	// The notes without the key come last.
+	sorters, err := core.NoteSortersFromStrings([]string{"path", "meta.status"})
+	assert.Nil(t, err)
+	testNoteDAOFindPaths(t, core.NoteFindOpts{Sorters: sorters, Limit: 4},
+		[]string{"log/2021-01-03.md", "ref/test/b.md", "index.md", "f39c8.md"},
+	)
+}
+
+func TestNoteDAOFindExactPaths(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			IncludePaths: []string{"ref", "index.md"},
+			ExactPaths:   []string{"ref/test/a.md", "index.md", "log/2021-01-03.md", "unknown.md"},
+		},
+		[]string{"ref/test/a.md", "index.md"},
+	)
+}
+
+func TestNoteDAOFindEmptyExactPaths(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{ExactPaths: []string{}},
+		[]string{},
+	)
+}
+
 func TestNoteDAOFindMentions(t *testing.T) {
 	testNoteDAOFind(t,
 		core.NoteFindOpts{Mention: []string{"log/2021-01-03.md", "index.md"}},
@@ -680,6 +1013,7 @@ func TestNoteDAOFindMentions(t *testing.T) {
 					Checksum:   "yvwbae",
 				},
 				Snippets: []string{"This one is in a sub sub directory, not the <zk:match>first page</zk:match>"},
+				Score:    1.2225264050575233,
 			},
 			{
 				Note: core.Note{
@@ -698,6 +1032,7 @@ func TestNoteDAOFindMentions(t *testing.T) {
 					Checksum:   "earkte",
 				},
 				Snippets: []string{"A third <zk:match>daily note</zk:match>"},
+				Score:    0.2552298867711454,
 			},
 			{
 				Note: core.Note{
@@ -716,6 +1051,7 @@ func TestNoteDAOFindMentions(t *testing.T) {
 					Checksum:   "arstde",
 				},
 				Snippets: []string{"A second <zk:match>daily note</zk:match>"},
+				Score:    0.2552298867711454,
 			},
 		},
 	)
@@ -867,6 +1203,7 @@ func TestNoteDAOFindLinkedByWithSnippets(t *testing.T) {
 					"[[<zk:match>Link from 4 to 6</zk:match>]]",
 					"[[<zk:match>Duplicated link</zk:match>]]",
 				},
+				Distance: 1,
 			},
 			{
 				Note: core.Note{
@@ -889,11 +1226,52 @@ func TestNoteDAOFindLinkedByWithSnippets(t *testing.T) {
 				Snippets: []string{
 					"[[<zk:match>Another link</zk:match>]]",
 				},
+				Distance: 1,
 			},
 		},
 	)
 }
 
+func TestNoteDAOFindLinkedByRecursiveWithDistance(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		notes, err := dao.Find(core.NoteFindOpts{
+			LinkedBy: &core.LinkFilter{
+				Paths:     []string{"log/2021-01-04.md"},
+				Recursive: true,
+			},
+		})
+		assert.Nil(t, err)
+
+		distances := map[string]int{}
+		for _, note := range notes {
+			distances[note.Path] = note.Distance
+		}
+		assert.Equal(t, distances, map[string]int{
+			"index.md":          1,
+			"f39c8.md":          2,
+			"ref/test/a.md":     3,
+			"log/2021-01-03.md": 3,
+		})
+	})
+}
+
+func TestNoteDAOFindLinkPath(t *testing.T) {
+	test := func(from, to string, maxDistance int, expected []string) {
+		testNoteDAOFindPaths(t,
+			core.NoteFindOpts{
+				LinkPath: &core.LinkPathFilter{From: from, To: to, MaxDistance: maxDistance},
+			},
+			expected,
+		)
+	}
+
+	test("log/2021-01-04.md", "log/2021-01-03.md", 0, []string{"log/2021-01-04.md", "index.md", "f39c8.md", "log/2021-01-03.md"})
+	test("log/2021-01-04", "log/2021-01-03", 3, []string{"log/2021-01-04.md", "index.md", "f39c8.md", "log/2021-01-03.md"})
+	test("log/2021-01-04.md", "log/2021-01-03.md", 2, []string{})
+	test("log/2021-01-03.md", "log/2021-01-04.md", 0, []string{"log/2021-01-03.md", "log/2021-01-04.md"})
+	test("index.md", "index.md", 0, []string{"index.md"})
+}
+
 func TestNoteDAOFindNotLinkedBy(t *testing.T) {
 	testNoteDAOFindPaths(t,
 		core.NoteFindOpts{
@@ -979,6 +1357,70 @@ func TestNoteDAOFindOrphan(t *testing.T) {
 	)
 }
 
+func TestNoteDAOFindRecentlyOpened(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		history := NewHistoryDAO(tx)
+		assert.Nil(t, history.Add(core.NoteID(3), core.NoteHistoryOpened, time.Date(2021, 10, 1, 9, 30, 0, 0, time.Local)))
+		assert.Nil(t, history.Add(core.NoteID(1), core.NoteHistoryCreated, time.Date(2021, 10, 2, 9, 30, 0, 0, time.Local)))
+
+		test := func(opts core.NoteFindOpts, expected []string) {
+			t.Helper()
+			notes, err := dao.Find(opts)
+			assert.Nil(t, err)
+			actual := []string{}
+			for _, note := range notes {
+				actual = append(actual, note.Path)
+			}
+			assert.Equal(t, actual, expected)
+		}
+
+		// The most recent first.
+		test(core.NoteFindOpts{RecentlyOpened: true}, []string{"log/2021-01-03.md", "index.md"})
+		test(core.NoteFindOpts{
+			RecentlyOpened: true,
+			Sorters:        []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+		}, []string{"index.md", "log/2021-01-03.md"})
+		test(core.NoteFindOpts{
+			RecentlyOpened: true,
+			Sorters:        []core.NoteSorter{{Field: core.NoteSortOpened, Ascending: true}},
+		}, []string{"index.md", "log/2021-01-03.md"})
+
+		// The notes never opened come last.
+		test(core.NoteFindOpts{
+			Sorters: []core.NoteSorter{{Field: core.NoteSortOpened}, {Field: core.NoteSortPath, Ascending: true}},
+			Limit:   3,
+		}, []string{"log/2021-01-03.md", "index.md", "f39c8.md"})
+	})
+}
+
+func TestNoteDAOFindTrash(t *testing.T) {
+	test := func(filter core.TrashFilter, expected []string) {
+		testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+			_, err := tx.Exec(`UPDATE notes SET metadata = '{"status":"Trash"}' WHERE path = 'index.md'`)
+			assert.Nil(t, err)
+			_, err = tx.Exec(`UPDATE notes SET metadata = '{"status":"deleted"}' WHERE path = 'f39c8.md'`)
+			assert.Nil(t, err)
+			_, err = tx.Exec(`UPDATE notes SET metadata = '{"status":"draft"}' WHERE path = 'ref/test/a.md'`)
+			assert.Nil(t, err)
+
+			notes, err := dao.Find(core.NoteFindOpts{
+				Trash:   filter,
+				Sorters: []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+			})
+			assert.Nil(t, err)
+			actual := []string{}
+			for _, note := range notes {
+				actual = append(actual, note.Path)
+			}
+			assert.Equal(t, actual, expected)
+		})
+	}
+
+	test(core.TrashFilterInclude, []string{"f39c8.md", "index.md", "log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md", "ref/test/a.md", "ref/test/b.md"})
+	test(core.TrashFilterExclude, []string{"log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md", "ref/test/a.md", "ref/test/b.md"})
+	test(core.TrashFilterOnly, []string{"f39c8.md", "index.md"})
+}
+
 func TestNoteDAOFindCreatedOn(t *testing.T) {
 	start := time.Date(2020, 11, 22, 0, 0, 0, 0, time.UTC)
 	end := time.Date(2020, 11, 23, 0, 0, 0, 0, time.UTC)
@@ -1098,6 +1540,32 @@ func TestNoteDAOFindSortWordCount(t *testing.T) {
 	})
 }
 
+func TestNoteDAOFindSortMaturity(t *testing.T) {
+	// The notes are chosen to keep the same order whatever the current date.
+	test := func(ascending bool, expected []string) {
+		testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+			_, err := tx.Exec(`UPDATE notes SET edit_count = 20 WHERE path = "log/2021-02-04.md"`)
+			assert.Nil(t, err)
+
+			matches, err := dao.Find(core.NoteFindOpts{
+				ExactPaths: []string{"ref/test/a.md", "ref/test/b.md", "log/2021-01-03.md", "log/2021-02-04.md"},
+				Sorters:    []core.NoteSorter{{Field: core.NoteSortMaturity, Ascending: ascending}},
+			})
+			assert.Nil(t, err)
+
+			actual := make([]string, 0)
+			for _, m := range matches {
+				assert.True(t, m.Maturity > 0 && m.Maturity < 100)
+				actual = append(actual, m.Path)
+			}
+			assert.Equal(t, actual, expected)
+		})
+	}
+
+	test(true, []string{"ref/test/b.md", "log/2021-01-03.md", "ref/test/a.md", "log/2021-02-04.md"})
+	test(false, []string{"log/2021-02-04.md", "ref/test/a.md", "log/2021-01-03.md", "ref/test/b.md"})
+}
+
 func testNoteDAOFindSort(t *testing.T, field core.NoteSortField, ascending bool, expected []string) {
 	testNoteDAOFindPaths(t,
 		core.NoteFindOpts{
@@ -1124,6 +1592,10 @@ func testNoteDAOFind(t *testing.T, opts core.NoteFindOpts, expected []core.Conte
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
 		actual, err := dao.Find(opts)
 		assert.Nil(t, err)
+		// The maturity depends on the current date, see TestNoteDAOFindSortMaturity.
+		for i := range actual {
+			actual[i].Maturity = 0
+		}
 		assert.Equal(t, actual, expected)
 	})
 }
@@ -1141,18 +1613,18 @@ func testNoteDAOWithFixtures(t *testing.T, fixtures string, callback func(tx Tra
 }
 
 type noteRow struct {
-	Path, Title, Lead, Body, RawContent, Checksum, Metadata string
-	WordCount                                               int
-	Created, Modified                                       time.Time
+	Path, Title, Lead, Summary, Body, RawContent, Checksum, Metadata string
+	WordCount                                                        int
+	Created, Modified                                                time.Time
 }
 
 func queryNoteRow(tx Transaction, where string) (noteRow, error) {
 	var row noteRow
 	err := tx.QueryRow(fmt.Sprintf(`
-		SELECT path, title, lead, body, raw_content, word_count, checksum, created, modified, metadata
+		SELECT path, title, lead, summary, body, raw_content, word_count, checksum, created, modified, metadata
 		  FROM notes
 		 WHERE %v
-	`, where)).Scan(&row.Path, &row.Title, &row.Lead, &row.Body, &row.RawContent, &row.WordCount, &row.Checksum, &row.Created, &row.Modified, &row.Metadata)
+	`, where)).Scan(&row.Path, &row.Title, &row.Lead, &row.Summary, &row.Body, &row.RawContent, &row.WordCount, &row.Checksum, &row.Created, &row.Modified, &row.Metadata)
 	return row, err
 }
 
@@ -1,6 +1,9 @@
 package sqlite
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/mickael-menu/zk/internal/core"
 	"github.com/mickael-menu/zk/internal/util"
 	"github.com/mickael-menu/zk/internal/util/errors"
@@ -19,6 +22,13 @@ type dao struct {
 	notes       *NoteDAO
 	collections *CollectionDAO
 	metadata    *MetadataDAO
+	ids         *IDDAO
+	assets      *AssetDAO
+	urls        *URLDAO
+	tasks       *TaskDAO
+	reviews     *ReviewDAO
+	related     *RelatedDAO
+	history     *HistoryDAO
 }
 
 func NewNoteIndex(db *DB, logger util.Logger) *NoteIndex {
@@ -55,6 +65,24 @@ func (ni *NoteIndex) FindCollections(kind core.CollectionKind, sorters []core.Co
 	return
 }
 
+// FindUnresolvedLinks implements core.NoteIndex.
+func (ni *NoteIndex) FindUnresolvedLinks() (links []core.UnresolvedLink, err error) {
+	err = ni.commit(func(dao *dao) error {
+		links, err = dao.notes.FindUnresolvedLinks()
+		return err
+	})
+	return
+}
+
+// FindNoteLinks implements core.NoteIndex.
+func (ni *NoteIndex) FindNoteLinks() (links []core.NoteLink, err error) {
+	err = ni.commit(func(dao *dao) error {
+		links, err = dao.notes.FindNoteLinks()
+		return err
+	})
+	return
+}
+
 // IndexedPaths implements core.NoteIndex.
 func (ni *NoteIndex) IndexedPaths() (metadata <-chan paths.Metadata, err error) {
 	err = ni.commit(func(dao *dao) error {
@@ -67,12 +95,17 @@ func (ni *NoteIndex) IndexedPaths() (metadata <-chan paths.Metadata, err error)
 
 // Add implements core.NoteIndex.
 func (ni *NoteIndex) Add(note core.Note) (id core.NoteID, err error) {
-	err = ni.commit(func(dao *dao) error {
+	err = ni.commitWrite(func(dao *dao) error {
 		id, err = dao.notes.Add(note)
 		if err != nil {
 			return err
 		}
 
+		err = dao.tasks.Set(id, note.Tasks)
+		if err != nil {
+			return err
+		}
+
 		return ni.associateTags(dao.collections, id, note.Tags)
 	})
 
@@ -82,12 +115,17 @@ func (ni *NoteIndex) Add(note core.Note) (id core.NoteID, err error) {
 
 // Update implements core.NoteIndex.
 func (ni *NoteIndex) Update(note core.Note) error {
-	err := ni.commit(func(dao *dao) error {
+	err := ni.commitWrite(func(dao *dao) error {
 		noteId, err := dao.notes.Update(note)
 		if err != nil {
 			return err
 		}
 
+		err = dao.tasks.Set(noteId, note.Tasks)
+		if err != nil {
+			return err
+		}
+
 		err = dao.collections.RemoveAssociations(noteId)
 		if err != nil {
 			return err
@@ -114,17 +152,179 @@ func (ni *NoteIndex) associateTags(collections *CollectionDAO, noteId core.NoteI
 	return nil
 }
 
+// Touch implements core.NoteIndex.
+func (ni *NoteIndex) Touch(path string, modified time.Time) error {
+	err := ni.commitWrite(func(dao *dao) error {
+		return dao.notes.Touch(path, modified)
+	})
+	return errors.Wrapf(err, "%v: failed to update note index", path)
+}
+
 // Remove implements core.NoteIndex
 func (ni *NoteIndex) Remove(path string) error {
-	err := ni.commit(func(dao *dao) error {
+	err := ni.commitWrite(func(dao *dao) error {
 		return dao.notes.Remove(path)
 	})
 	return errors.Wrapf(err, "%v: failed to remove note from index", path)
 }
 
+// ReserveID implements core.NoteIndex.
+func (ni *NoteIndex) ReserveID(id string) (reserved bool, err error) {
+	err = ni.commitWrite(func(dao *dao) error {
+		reserved, err = dao.ids.Reserve(id)
+		return err
+	})
+	return
+}
+
+// IndexedAssetPaths implements core.NoteIndex.
+func (ni *NoteIndex) IndexedAssetPaths() (metadata <-chan paths.Metadata, err error) {
+	err = ni.commit(func(dao *dao) error {
+		metadata, err = dao.assets.Indexed()
+		return err
+	})
+	err = errors.Wrap(err, "failed to get indexed assets")
+	return
+}
+
+// SetAssetText implements core.NoteIndex.
+func (ni *NoteIndex) SetAssetText(asset paths.Metadata, text string) error {
+	return ni.commitWrite(func(dao *dao) error {
+		return dao.assets.SetText(asset, text)
+	})
+}
+
+// RemoveAssetText implements core.NoteIndex.
+func (ni *NoteIndex) RemoveAssetText(path string) error {
+	return ni.commitWrite(func(dao *dao) error {
+		return dao.assets.RemoveText(path)
+	})
+}
+
+// FindUnfetchedURLs implements core.NoteIndex.
+func (ni *NoteIndex) FindUnfetchedURLs(fetchedBefore time.Time, limit int) (urls []string, err error) {
+	err = ni.commit(func(dao *dao) error {
+		urls, err = dao.urls.FindUnfetched(fetchedBefore, limit)
+		return err
+	})
+	return
+}
+
+// FindURLMetadata implements core.NoteIndex.
+func (ni *NoteIndex) FindURLMetadata(url string) (metadata *core.URLMetadata, err error) {
+	err = ni.commit(func(dao *dao) error {
+		metadata, err = dao.urls.Find(url)
+		return err
+	})
+	return
+}
+
+// SetURLMetadata implements core.NoteIndex.
+func (ni *NoteIndex) SetURLMetadata(metadata core.URLMetadata) error {
+	return ni.commitWrite(func(dao *dao) error {
+		return dao.urls.Set(metadata)
+	})
+}
+
+// FindUnarchivedURLs implements core.NoteIndex.
+func (ni *NoteIndex) FindUnarchivedURLs(limit int) (urls []string, err error) {
+	err = ni.commit(func(dao *dao) error {
+		urls, err = dao.urls.FindUnarchived(limit)
+		return err
+	})
+	return
+}
+
+// SetURLArchive implements core.NoteIndex.
+func (ni *NoteIndex) SetURLArchive(url string, archive string) error {
+	return ni.commitWrite(func(dao *dao) error {
+		return dao.urls.SetArchive(url, archive)
+	})
+}
+
+// FindDeadURLs implements core.NoteIndex.
+func (ni *NoteIndex) FindDeadURLs() (urls []core.URLMetadata, err error) {
+	err = ni.commit(func(dao *dao) error {
+		urls, err = dao.urls.FindDead()
+		return err
+	})
+	return
+}
+
+// FindTasks implements core.NoteIndex.
+func (ni *NoteIndex) FindTasks(opts core.TaskFindOpts) (tasks []core.NoteTask, err error) {
+	err = ni.commit(func(dao *dao) error {
+		tasks, err = dao.tasks.Find(opts)
+		return err
+	})
+	return
+}
+
+// FindReviews implements core.NoteIndex.
+func (ni *NoteIndex) FindReviews(opts core.ReviewFindOpts) (reviews []core.NoteReview, err error) {
+	err = ni.commit(func(dao *dao) error {
+		reviews, err = dao.reviews.Find(opts)
+		return err
+	})
+	return
+}
+
+// SetReview implements core.NoteIndex.
+func (ni *NoteIndex) SetReview(path string, review core.Review) error {
+	return ni.commitWrite(func(dao *dao) error {
+		id, err := dao.notes.findIdByPath(path)
+		if err != nil {
+			return err
+		}
+		if !id.IsValid() {
+			return core.ErrNoteNotFound(path)
+		}
+		return dao.reviews.Set(id, review)
+	})
+}
+
+// FindRelated implements core.NoteIndex.
+func (ni *NoteIndex) FindRelated(path string, opts core.RelatedNotesOpts) (notes []core.RelatedNote, err error) {
+	err = ni.commit(func(dao *dao) error {
+		id, err := dao.notes.findIdByPath(path)
+		if err != nil {
+			return err
+		}
+		if !id.IsValid() {
+			return core.ErrNoteNotFound(path)
+		}
+		notes, err = dao.related.Find(id, opts)
+		return err
+	})
+	return
+}
+
+// FindHistory implements core.NoteIndex.
+func (ni *NoteIndex) FindHistory(limit int) (entries []core.NoteHistoryEntry, err error) {
+	err = ni.commit(func(dao *dao) error {
+		entries, err = dao.history.Find(limit)
+		return err
+	})
+	return
+}
+
+// AddHistory implements core.NoteIndex.
+func (ni *NoteIndex) AddHistory(path string, action core.NoteHistoryAction, date time.Time) error {
+	return ni.commitWrite(func(dao *dao) error {
+		id, err := dao.notes.findIdByPath(path)
+		if err != nil {
+			return err
+		}
+		if !id.IsValid() {
+			return core.ErrNoteNotFound(path)
+		}
+		return dao.history.Add(id, action, date)
+	})
+}
+
 // Commit implements core.NoteIndex.
 func (ni *NoteIndex) Commit(transaction func(idx core.NoteIndex) error) error {
-	return ni.commit(func(dao *dao) error {
+	return ni.commitWrite(func(dao *dao) error {
 		return transaction(&NoteIndex{
 			db:     ni.db,
 			dao:    dao,
@@ -145,7 +345,7 @@ func (ni *NoteIndex) NeedsReindexing() (needsReindexing bool, err error) {
 
 // SetNeedsReindexing implements core.NoteIndex.
 func (ni *NoteIndex) SetNeedsReindexing(needsReindexing bool) error {
-	return ni.commit(func(dao *dao) error {
+	return ni.commitWrite(func(dao *dao) error {
 		value := "false"
 		if needsReindexing {
 			value = "true"
@@ -155,15 +355,80 @@ func (ni *NoteIndex) SetNeedsReindexing(needsReindexing bool) error {
 	})
 }
 
+// LastIndexing implements core.NoteIndex.
+func (ni *NoteIndex) LastIndexing() (date time.Time, duration time.Duration, err error) {
+	err = ni.commit(func(dao *dao) error {
+		value, err := dao.metadata.Get(lastIndexingDateKey)
+		if err != nil || value == "" {
+			return err
+		}
+		date, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return err
+		}
+
+		value, err = dao.metadata.Get(lastIndexingDurationKey)
+		if err != nil || value == "" {
+			return err
+		}
+		nanoseconds, err := strconv.ParseInt(value, 10, 64)
+		duration = time.Duration(nanoseconds)
+		return err
+	})
+	return
+}
+
+// SetLastIndexing implements core.NoteIndex.
+func (ni *NoteIndex) SetLastIndexing(date time.Time, duration time.Duration) error {
+	return ni.commitWrite(func(dao *dao) error {
+		err := dao.metadata.Set(lastIndexingDateKey, date.UTC().Format(time.RFC3339Nano))
+		if err != nil {
+			return err
+		}
+		return dao.metadata.Set(lastIndexingDurationKey, strconv.FormatInt(int64(duration), 10))
+	})
+}
+
+// Vacuum implements core.NoteIndex.
+func (ni *NoteIndex) Vacuum() error {
+	if ni.dao != nil {
+		return errors.New("the index can't be vacuumed during a transaction")
+	}
+	return ni.db.Vacuum()
+}
+
+// Query implements core.NoteIndex.
+func (ni *NoteIndex) Query(query string) (core.IndexQueryResult, error) {
+	return ni.db.QueryReadOnly(query)
+}
+
+// commit performs a read-only transaction, or joins the current one.
 func (ni *NoteIndex) commit(transaction func(dao *dao) error) error {
+	return ni.run(ni.db.WithTransaction, transaction)
+}
+
+// commitWrite performs a transaction writing to the index, or joins the
+// current one.
+func (ni *NoteIndex) commitWrite(transaction func(dao *dao) error) error {
+	return ni.run(ni.db.WithWriteTransaction, transaction)
+}
+
+func (ni *NoteIndex) run(withTransaction func(fn TxFn) error, transaction func(dao *dao) error) error {
 	if ni.dao != nil {
 		return transaction(ni.dao)
 	} else {
-		return ni.db.WithTransaction(func(tx Transaction) error {
+		return withTransaction(func(tx Transaction) error {
 			dao := dao{
 				notes:       NewNoteDAO(tx, ni.logger),
 				collections: NewCollectionDAO(tx, ni.logger),
 				metadata:    NewMetadataDAO(tx),
+				ids:         NewIDDAO(tx),
+				assets:      NewAssetDAO(tx, ni.logger),
+				urls:        NewURLDAO(tx),
+				tasks:       NewTaskDAO(tx),
+				reviews:     NewReviewDAO(tx),
+				related:     NewRelatedDAO(tx),
+				history:     NewHistoryDAO(tx),
 			}
 			return transaction(&dao)
 		})
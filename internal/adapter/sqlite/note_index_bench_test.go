@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/adapter/fs"
+	"github.com/mickael-menu/zk/internal/adapter/markdown"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util"
+)
+
+// Compare the worker pool sizes with the -cpu flag, e.g.
+// go test -run '^$' -bench Index -cpu 1,4,8 ./internal/adapter/sqlite
+func BenchmarkIndex(b *testing.B) {
+	for _, size := range []int{100, 1000, 5000} {
+		b.Run(fmt.Sprintf("%d notes", size), func(b *testing.B) {
+			dir := b.TempDir()
+			writeBenchmarkNotes(b, dir, size)
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				notebook := benchmarkNotebook(b, dir)
+				b.StartTimer()
+
+				stats, err := notebook.Index(core.AuditOrigin{}, false)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if stats.AddedCount != size {
+					b.Fatalf("expected %d added notes, got %d", size, stats.AddedCount)
+				}
+			}
+		})
+	}
+}
+
+func benchmarkNotebook(b *testing.B, dir string) *core.Notebook {
+	logger := &util.NullLogger
+	db, err := OpenInMemory()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	fs, err := fs.NewFileStorage(dir, logger)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return core.NewNotebook(dir, core.NewDefaultConfig(), core.NotebookPorts{
+		NoteIndex: NewNoteIndex(db, logger),
+		NoteContentParser: markdown.NewParser(markdown.ParserOpts{
+			HashtagEnabled:  true,
+			ColontagEnabled: true,
+		}, logger),
+		FS:     fs,
+		Logger: logger,
+	})
+}
+
+// writeBenchmarkNotes generates count linked and tagged notes in dir.
+func writeBenchmarkNotes(b *testing.B, dir string, count int) {
+	for i := 0; i < count; i++ {
+		subdir := filepath.Join(dir, fmt.Sprintf("dir%d", i%10))
+		if err := os.MkdirAll(subdir, 0755); err != nil {
+			b.Fatal(err)
+		}
+		content := fmt.Sprintf(`---
+date: 2021-01-%02d
+tags: [bench, topic%d]
+---
+
+# Note %d
+
+A paragraph introducing the note, with a [link to the next one](../dir%d/note%d.md)
+and a [[note%d]] wiki-link, tagged with #tag%d.
+
+## Details
+
+- An item with an external link to https://example.com/%d
+- [ ] A task to do
+- Another item :colontag:
+
+`+"```go\nfmt.Println(%d)\n```\n", i%28+1, i%20, i, (i+1)%10, i+1, i+2, i%50, i, i)
+
+		err := ioutil.WriteFile(filepath.Join(subdir, fmt.Sprintf("note%d.md", i)), []byte(content), 0644)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
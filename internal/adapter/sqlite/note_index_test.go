@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"testing"
+	"time"
 
 	"github.com/mickael-menu/zk/internal/core"
 	"github.com/mickael-menu/zk/internal/util"
@@ -48,6 +49,24 @@ func TestNoteIndexUpdateWithTags(t *testing.T) {
 	assertSQL(true)
 }
 
+func TestNoteIndexLastIndexing(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	date, duration, err := index.LastIndexing()
+	assert.Nil(t, err)
+	assert.True(t, date.IsZero())
+	assert.Equal(t, duration, time.Duration(0))
+
+	indexingDate := time.Date(2021, 10, 12, 14, 8, 55, 123, time.UTC)
+	err = index.SetLastIndexing(indexingDate, 1500*time.Millisecond)
+	assert.Nil(t, err)
+
+	date, duration, err = index.LastIndexing()
+	assert.Nil(t, err)
+	assert.Equal(t, date, indexingDate)
+	assert.Equal(t, duration, 1500*time.Millisecond)
+}
+
 func testNoteIndex(t *testing.T) (*DB, *NoteIndex) {
 	db := testDB(t)
 	return db, NewNoteIndex(db, &util.NullLogger)
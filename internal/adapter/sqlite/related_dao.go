@@ -0,0 +1,299 @@
+package sqlite
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+const (
+	// relatedMaxTerms is the number of most significant terms of the target
+	// note used to find the related notes.
+	relatedMaxTerms = 25
+	// relatedMaxSharedTerms is the number of shared terms reported for each
+	// related note.
+	relatedMaxSharedTerms = 5
+	// relatedChunkSize bounds the number of terms bound to a single query.
+	relatedChunkSize = 200
+)
+
+// relatedColumnWeights are the weights of the occurrences of a term in each
+// column of the full-text search index. The paths are ignored, as most of
+// them share the same directories or date patterns.
+var relatedColumnWeights = map[string]float64{
+	"title":     2,
+	"body":      1,
+	"tag_names": 2,
+}
+
+// RelatedDAO finds the notes related to each other from the terms of the
+// full-text search index.
+type RelatedDAO struct {
+	tx Transaction
+}
+
+// NewRelatedDAO creates a new instance of a DAO working on the given database
+// transaction.
+func NewRelatedDAO(tx Transaction) *RelatedDAO {
+	return &RelatedDAO{tx: tx}
+}
+
+// Find returns the notes sharing the most significant terms of the given
+// note, which are not linked to or from it, ranked with the TF-IDF weights of
+// the terms.
+func (d *RelatedDAO) Find(noteID core.NoteID, opts core.RelatedNotesOpts) ([]core.RelatedNote, error) {
+	wrap := errors.Wrapper("failed to find the related notes")
+
+	var noteCount int
+	err := d.tx.QueryRow(`SELECT COUNT(*) FROM notes`).Scan(&noteCount)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	// Weights of the terms of the target note.
+	frequencies, err := d.termFrequencies(`doc = ?`, int64(noteID))
+	if err != nil {
+		return nil, wrap(err)
+	}
+	terms := []string{}
+	for term := range frequencies[noteID] {
+		terms = append(terms, term)
+	}
+	idfs, err := d.inverseDocumentFrequencies(terms, noteCount)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	weights := map[string]float64{}
+	terms = []string{}
+	for term, frequency := range frequencies[noteID] {
+		if idf, ok := idfs[term]; ok {
+			weights[term] = tfidf(frequency, idf)
+			terms = append(terms, term)
+		}
+	}
+	sortTermsByWeight(terms, weights)
+	if len(terms) > relatedMaxTerms {
+		terms = terms[:relatedMaxTerms]
+	}
+	if len(terms) == 0 {
+		return []core.RelatedNote{}, nil
+	}
+
+	// Similarity of the other notes containing these terms.
+	frequencies = map[core.NoteID]map[string]float64{}
+	for _, chunk := range chunkArgs(stringsToArgs(terms), relatedChunkSize) {
+		chunkFrequencies, err := d.termFrequencies(`term IN (`+placeholders(len(chunk))+`)`, chunk...)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		for id, noteFrequencies := range chunkFrequencies {
+			if frequencies[id] == nil {
+				frequencies[id] = map[string]float64{}
+			}
+			for term, frequency := range noteFrequencies {
+				frequencies[id][term] = frequency
+			}
+		}
+	}
+	delete(frequencies, noteID)
+
+	candidates, err := d.unlinkedNotes(noteID, frequencies)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	related := []core.RelatedNote{}
+	for id, note := range candidates {
+		contributions := map[string]float64{}
+		shared := []string{}
+		for term, frequency := range frequencies[id] {
+			contributions[term] = weights[term] * tfidf(frequency, idfs[term])
+			note.Score += contributions[term]
+			shared = append(shared, term)
+		}
+		sortTermsByWeight(shared, contributions)
+		if len(shared) > relatedMaxSharedTerms {
+			shared = shared[:relatedMaxSharedTerms]
+		}
+		note.Terms = shared
+		related = append(related, note)
+	}
+
+	sort.SliceStable(related, func(i, j int) bool {
+		if related[i].Score != related[j].Score {
+			return related[i].Score > related[j].Score
+		}
+		return related[i].Path < related[j].Path
+	})
+	if opts.Limit > 0 && len(related) > opts.Limit {
+		related = related[:opts.Limit]
+	}
+	return related, nil
+}
+
+// termFrequencies returns the weighted number of occurrences of the terms
+// matching the given condition, for each note.
+func (d *RelatedDAO) termFrequencies(where string, args ...interface{}) (map[core.NoteID]map[string]float64, error) {
+	rows, err := d.tx.Query(`
+		SELECT doc, term, col, COUNT(*)
+		  FROM notes_fts_instances
+		 WHERE `+where+`
+		 GROUP BY doc, term, col
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	frequencies := map[core.NoteID]map[string]float64{}
+	for rows.Next() {
+		var (
+			id        int64
+			term, col string
+			count     int
+		)
+		err := rows.Scan(&id, &term, &col, &count)
+		if err != nil {
+			return nil, err
+		}
+		weight, ok := relatedColumnWeights[col]
+		if !ok {
+			continue
+		}
+		noteID := core.NoteID(id)
+		if frequencies[noteID] == nil {
+			frequencies[noteID] = map[string]float64{}
+		}
+		frequencies[noteID][term] += weight * float64(count)
+	}
+	return frequencies, rows.Err()
+}
+
+// inverseDocumentFrequencies returns the IDF of the given terms. The terms
+// found in a single note or in most of them are omitted, as they can't relate
+// notes together.
+func (d *RelatedDAO) inverseDocumentFrequencies(terms []string, noteCount int) (map[string]float64, error) {
+	idfs := map[string]float64{}
+	for _, chunk := range chunkArgs(stringsToArgs(terms), relatedChunkSize) {
+		rows, err := d.tx.Query(`
+			SELECT term, doc
+			  FROM notes_fts_rows
+			 WHERE term IN (`+placeholders(len(chunk))+`)
+		`, chunk...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var (
+				term     string
+				docCount int
+			)
+			err := rows.Scan(&term, &docCount)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if docCount > 1 && docCount*2 <= noteCount {
+				idfs[term] = math.Log(float64(noteCount) / float64(docCount))
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return idfs, nil
+}
+
+// unlinkedNotes returns the notes among the given candidates which are not
+// linked to or from the target note, excluding the trashed ones.
+func (d *RelatedDAO) unlinkedNotes(targetID core.NoteID, candidates map[core.NoteID]map[string]float64) (map[core.NoteID]core.RelatedNote, error) {
+	notes := map[core.NoteID]core.RelatedNote{}
+
+	ids := []interface{}{}
+	for id := range candidates {
+		ids = append(ids, int64(id))
+	}
+
+	for _, chunk := range chunkArgs(ids, relatedChunkSize) {
+		args := append(append([]interface{}{}, chunk...), int64(targetID), int64(targetID))
+		rows, err := d.tx.Query(`
+			SELECT n.id, n.path, n.title
+			  FROM notes n
+			 WHERE n.id IN (`+placeholders(len(chunk))+`)
+			   AND NOT is_trashed(n.metadata)
+			   AND n.id NOT IN (SELECT target_id FROM links WHERE source_id = ? AND target_id IS NOT NULL)
+			   AND n.id NOT IN (SELECT source_id FROM links WHERE target_id = ?)
+		`, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var (
+				id   int64
+				note core.RelatedNote
+			)
+			err := rows.Scan(&id, &note.Path, &note.Title)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			notes[core.NoteID(id)] = note
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return notes, nil
+}
+
+// tfidf returns the weight of a term in a note, with a sublinear term
+// frequency to not favor the long notes too much.
+func tfidf(frequency float64, idf float64) float64 {
+	return (1 + math.Log(frequency)) * idf
+}
+
+// sortTermsByWeight sorts the given terms from the heaviest one, then
+// alphabetically.
+func sortTermsByWeight(terms []string, weights map[string]float64) {
+	sort.SliceStable(terms, func(i, j int) bool {
+		if weights[terms[i]] != weights[terms[j]] {
+			return weights[terms[i]] > weights[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+}
+
+// chunkArgs splits the given query arguments into chunks of the given size.
+func chunkArgs(values []interface{}, size int) [][]interface{} {
+	chunks := [][]interface{}{}
+	for len(values) > size {
+		chunks = append(chunks, values[:size])
+		values = values[size:]
+	}
+	if len(values) > 0 {
+		chunks = append(chunks, values)
+	}
+	return chunks
+}
+
+func placeholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
+}
+
+func stringsToArgs(values []string) []interface{} {
+	args := []interface{}{}
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return args
+}
@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestRelatedDAOFind(t *testing.T) {
+	testRelatedDAO(t, func(tx Transaction, dao *RelatedDAO) {
+		test := func(id core.NoteID, opts core.RelatedNotesOpts, expected []core.RelatedNote) {
+			t.Helper()
+			actual, err := dao.Find(id, opts)
+			assert.Nil(t, err)
+			// The scores are only compared relatively.
+			previousScore := 0.0
+			for i, note := range actual {
+				assert.True(t, note.Score > 0)
+				if i > 0 {
+					assert.True(t, note.Score <= previousScore)
+				}
+				previousScore = note.Score
+				actual[i].Score = 0
+			}
+			assert.Equal(t, actual, expected)
+		}
+
+		// The notes already linked are ignored: log/2021-01-03.md links to
+		// log/2021-01-04.md.
+		test(2, core.RelatedNotesOpts{}, []core.RelatedNote{
+			{Path: "log/2021-02-04.md", Title: "February 4, 2021", Terms: []string{"2021", "4", "daili"}},
+		})
+		test(7, core.RelatedNotesOpts{}, []core.RelatedNote{
+			{Path: "log/2021-01-04.md", Title: "January 4, 2021", Terms: []string{"2021", "4", "daili"}},
+			{Path: "log/2021-01-03.md", Title: "Daily note", Terms: []string{"daili"}},
+		})
+		test(7, core.RelatedNotesOpts{Limit: 1}, []core.RelatedNote{
+			{Path: "log/2021-01-04.md", Title: "January 4, 2021", Terms: []string{"2021", "4", "daili"}},
+		})
+		test(6, core.RelatedNotesOpts{}, []core.RelatedNote{
+			{Path: "ref/test/b.md", Title: "A nested note", Terms: []string{"nest"}},
+		})
+		test(4, core.RelatedNotesOpts{}, []core.RelatedNote{})
+
+		// The trashed notes are ignored.
+		_, err := tx.Exec(`UPDATE notes SET metadata = '{"status":"trash"}' WHERE id = 2`)
+		assert.Nil(t, err)
+		test(7, core.RelatedNotesOpts{}, []core.RelatedNote{
+			{Path: "log/2021-01-03.md", Title: "Daily note", Terms: []string{"daili"}},
+		})
+	})
+}
+
+func testRelatedDAO(t *testing.T, callback func(tx Transaction, dao *RelatedDAO)) {
+	testTransaction(t, func(tx Transaction) {
+		callback(tx, NewRelatedDAO(tx))
+	})
+}
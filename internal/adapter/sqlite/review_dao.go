@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// ReviewDAO persists the spaced repetition schedule of the notes in the
+// review queue in the SQLite database.
+type ReviewDAO struct {
+	tx Transaction
+
+	// Prepared SQL statements
+	setStmt *LazyStmt
+}
+
+// NewReviewDAO creates a new instance of a DAO working on the given database
+// transaction.
+func NewReviewDAO(tx Transaction) *ReviewDAO {
+	return &ReviewDAO{
+		tx: tx,
+
+		// Set the review schedule of a note.
+		setStmt: tx.PrepareLazy(`
+			INSERT OR REPLACE INTO reviews (note_id, reviewed, due, interval, ease, repetitions)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`),
+	}
+}
+
+// Set saves the review schedule of the given note.
+func (d *ReviewDAO) Set(noteID core.NoteID, review core.Review) error {
+	if review.Reviewed == nil || review.Due == nil {
+		return errors.New("failed to save the review: missing review dates")
+	}
+	_, err := d.setStmt.Exec(
+		int64(noteID), review.Reviewed.UTC(), review.Due.UTC(),
+		review.Interval, review.Ease, review.Repetitions,
+	)
+	return errors.Wrap(err, "failed to save the review")
+}
+
+// Find returns the notes of the review queue matching the given criteria,
+// ordered by due date, then by path. The notes never reviewed come last.
+func (d *ReviewDAO) Find(opts core.ReviewFindOpts) ([]core.NoteReview, error) {
+	wrap := errors.Wrapper("failed to find the notes to review")
+
+	whereExprs := []string{"in_review(n.metadata)", "NOT is_trashed(n.metadata)"}
+	args := []interface{}{}
+	if opts.DueBefore != nil {
+		whereExprs = append(whereExprs, "(r.due IS NULL OR r.due < ?)")
+		args = append(args, opts.DueBefore.UTC())
+	}
+	if len(opts.Paths) > 0 {
+		whereExprs = append(whereExprs, fmt.Sprintf("n.path IN (%s)", strings.TrimSuffix(strings.Repeat("?, ", len(opts.Paths)), ", ")))
+		for _, path := range opts.Paths {
+			args = append(args, path)
+		}
+	}
+
+	query := `
+		SELECT n.path, n.title, r.reviewed, r.due, r.interval, r.ease, r.repetitions
+		  FROM notes n
+		  LEFT JOIN reviews r ON r.note_id = n.id
+		 WHERE ` + strings.Join(whereExprs, " AND ") + `
+		 ORDER BY r.due IS NULL, r.due, n.sortable_path
+	`
+
+	rows, err := d.tx.Query(query, args...)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	defer rows.Close()
+
+	reviews := []core.NoteReview{}
+	for rows.Next() {
+		var (
+			review        core.NoteReview
+			reviewed, due sql.NullTime
+			interval      sql.NullInt64
+			ease          sql.NullFloat64
+			repetitions   sql.NullInt64
+		)
+		err := rows.Scan(&review.Path, &review.Title, &reviewed, &due, &interval, &ease, &repetitions)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		if reviewed.Valid && due.Valid {
+			reviewedDate := reviewed.Time.Local()
+			dueDate := due.Time.Local()
+			review.Reviewed = &reviewedDate
+			review.Due = &dueDate
+			review.Interval = int(interval.Int64)
+			review.Ease = ease.Float64
+			review.Repetitions = int(repetitions.Int64)
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, wrap(rows.Err())
+}
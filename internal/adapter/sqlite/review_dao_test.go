@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestReviewDAOFind(t *testing.T) {
+	testReviewDAO(t, func(tx Transaction, dao *ReviewDAO) {
+		reviews, err := dao.Find(core.ReviewFindOpts{})
+		assert.Nil(t, err)
+		assert.Equal(t, reviews, []core.NoteReview{})
+
+		_, err = tx.Exec(`UPDATE notes SET metadata = '{"review":true}' WHERE id IN (1, 3, 4)`)
+		assert.Nil(t, err)
+
+		reviewed := time.Date(2021, 10, 1, 9, 30, 0, 0, time.Local)
+		due1 := time.Date(2021, 10, 12, 0, 0, 0, 0, time.Local)
+		due2 := time.Date(2021, 10, 20, 0, 0, 0, 0, time.Local)
+		err = dao.Set(core.NoteID(3), core.Review{Reviewed: &reviewed, Due: &due2, Interval: 19, Ease: 2.6, Repetitions: 3})
+		assert.Nil(t, err)
+		err = dao.Set(core.NoteID(1), core.Review{Reviewed: &reviewed, Due: &due1, Interval: 11, Ease: 2.5, Repetitions: 2})
+		assert.Nil(t, err)
+
+		test := func(opts core.ReviewFindOpts, expected []core.NoteReview) {
+			t.Helper()
+			actual, err := dao.Find(opts)
+			assert.Nil(t, err)
+			assert.Equal(t, actual, expected)
+		}
+
+		daily := core.NoteReview{Path: "log/2021-01-03.md", Title: "Daily note", Review: core.Review{Reviewed: &reviewed, Due: &due1, Interval: 11, Ease: 2.5, Repetitions: 2}}
+		index := core.NoteReview{Path: "index.md", Title: "Index", Review: core.Review{Reviewed: &reviewed, Due: &due2, Interval: 19, Ease: 2.6, Repetitions: 3}}
+		unreviewed := core.NoteReview{Path: "f39c8.md", Title: "An interesting note"}
+
+		// Ordered by due date, the notes never reviewed come last.
+		test(core.ReviewFindOpts{}, []core.NoteReview{daily, index, unreviewed})
+		dueBefore := time.Date(2021, 10, 15, 0, 0, 0, 0, time.Local)
+		test(core.ReviewFindOpts{DueBefore: &dueBefore}, []core.NoteReview{daily, unreviewed})
+		test(core.ReviewFindOpts{Paths: []string{"index.md", "unknown.md"}}, []core.NoteReview{index})
+
+		// Replaces the existing schedule.
+		due3 := time.Date(2021, 10, 2, 0, 0, 0, 0, time.Local)
+		err = dao.Set(core.NoteID(3), core.Review{Reviewed: &reviewed, Due: &due3, Interval: 1, Ease: 2.3})
+		assert.Nil(t, err)
+		index.Review = core.Review{Reviewed: &reviewed, Due: &due3, Interval: 1, Ease: 2.3}
+		test(core.ReviewFindOpts{}, []core.NoteReview{index, daily, unreviewed})
+
+		// The notes leaving the review queue are ignored.
+		_, err = tx.Exec(`UPDATE notes SET metadata = '{"review":false}' WHERE id = 3`)
+		assert.Nil(t, err)
+		test(core.ReviewFindOpts{}, []core.NoteReview{daily, unreviewed})
+	})
+}
+
+func testReviewDAO(t *testing.T, callback func(tx Transaction, dao *ReviewDAO)) {
+	testTransaction(t, func(tx Transaction) {
+		callback(tx, NewReviewDAO(tx))
+	})
+}
@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// TaskDAO persists the checkbox items of the notes in the SQLite database.
+type TaskDAO struct {
+	tx Transaction
+
+	// Prepared SQL statements
+	addStmt    *LazyStmt
+	removeStmt *LazyStmt
+}
+
+// NewTaskDAO creates a new instance of a DAO working on the given database
+// transaction.
+func NewTaskDAO(tx Transaction) *TaskDAO {
+	return &TaskDAO{
+		tx: tx,
+
+		// Add a task to a note.
+		addStmt: tx.PrepareLazy(`
+			INSERT INTO tasks (note_id, line, text, done, due)
+			VALUES (?, ?, ?, ?, ?)
+		`),
+
+		// Remove all the tasks of a note.
+		removeStmt: tx.PrepareLazy(`
+			DELETE FROM tasks
+			 WHERE note_id = ?
+		`),
+	}
+}
+
+// Set replaces the tasks of the given note.
+func (d *TaskDAO) Set(noteID core.NoteID, tasks []core.Task) error {
+	wrap := errors.Wrapper("failed to index the tasks")
+
+	_, err := d.removeStmt.Exec(int64(noteID))
+	if err != nil {
+		return wrap(err)
+	}
+	for _, task := range tasks {
+		var due sql.NullTime
+		if task.Due != nil {
+			due = sql.NullTime{Time: task.Due.UTC(), Valid: true}
+		}
+		_, err := d.addStmt.Exec(int64(noteID), task.Line, task.Text, task.Done, due)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+	return nil
+}
+
+// Find returns the tasks matching the given criteria, ordered by due date,
+// then by note path and line. The tasks without due date come last.
+func (d *TaskDAO) Find(opts core.TaskFindOpts) ([]core.NoteTask, error) {
+	wrap := errors.Wrapper("failed to find the tasks")
+
+	whereExprs := []string{}
+	args := []interface{}{}
+	if opts.Open {
+		whereExprs = append(whereExprs, "t.done = 0")
+	}
+	if opts.DueBefore != nil {
+		whereExprs = append(whereExprs, "t.due < ?")
+		args = append(args, opts.DueBefore.UTC())
+	}
+	if len(opts.Paths) > 0 {
+		whereExprs = append(whereExprs, fmt.Sprintf("n.path IN (%s)", strings.TrimSuffix(strings.Repeat("?, ", len(opts.Paths)), ", ")))
+		for _, path := range opts.Paths {
+			args = append(args, path)
+		}
+	}
+
+	query := `
+		SELECT n.path, n.title, t.line, t.text, t.done, t.due
+		  FROM tasks t
+		  JOIN notes n ON n.id = t.note_id
+	`
+	if len(whereExprs) > 0 {
+		query += " WHERE " + strings.Join(whereExprs, " AND ")
+	}
+	query += " ORDER BY t.due IS NULL, t.due, n.sortable_path, t.line"
+
+	rows, err := d.tx.Query(query, args...)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	defer rows.Close()
+
+	tasks := []core.NoteTask{}
+	for rows.Next() {
+		var task core.NoteTask
+		var due sql.NullTime
+		err := rows.Scan(&task.Path, &task.Title, &task.Line, &task.Text, &task.Done, &due)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		if due.Valid {
+			date := due.Time.Local()
+			task.Due = &date
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, wrap(rows.Err())
+}
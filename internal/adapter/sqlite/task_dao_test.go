@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestTaskDAOFind(t *testing.T) {
+	testTaskDAO(t, func(tx Transaction, dao *TaskDAO) {
+		tasks, err := dao.Find(core.TaskFindOpts{})
+		assert.Nil(t, err)
+		assert.Equal(t, tasks, []core.NoteTask{})
+
+		due1 := time.Date(2021, 10, 12, 0, 0, 0, 0, time.Local)
+		due2 := time.Date(2021, 10, 20, 0, 0, 0, 0, time.Local)
+		err = dao.Set(core.NoteID(3), []core.Task{
+			{Line: 3, Text: "Write the index"},
+			{Line: 5, Text: "Review due:2021-10-20", Due: &due2},
+		})
+		assert.Nil(t, err)
+		err = dao.Set(core.NoteID(1), []core.Task{
+			{Line: 8, Text: "Call Bob due:2021-10-12", Due: &due1, Done: true},
+		})
+		assert.Nil(t, err)
+
+		test := func(opts core.TaskFindOpts, expected []core.NoteTask) {
+			t.Helper()
+			actual, err := dao.Find(opts)
+			assert.Nil(t, err)
+			assert.Equal(t, actual, expected)
+		}
+
+		bob := core.NoteTask{Path: "log/2021-01-03.md", Title: "Daily note", Task: core.Task{Line: 8, Text: "Call Bob due:2021-10-12", Due: &due1, Done: true}}
+		review := core.NoteTask{Path: "index.md", Title: "Index", Task: core.Task{Line: 5, Text: "Review due:2021-10-20", Due: &due2}}
+		write := core.NoteTask{Path: "index.md", Title: "Index", Task: core.Task{Line: 3, Text: "Write the index"}}
+
+		// Ordered by due date, then by path and line.
+		test(core.TaskFindOpts{}, []core.NoteTask{bob, review, write})
+		test(core.TaskFindOpts{Open: true}, []core.NoteTask{review, write})
+		dueBefore := time.Date(2021, 10, 15, 0, 0, 0, 0, time.Local)
+		test(core.TaskFindOpts{DueBefore: &dueBefore}, []core.NoteTask{bob})
+		test(core.TaskFindOpts{Paths: []string{"index.md", "unknown.md"}}, []core.NoteTask{review, write})
+
+		// Replaces the existing tasks.
+		err = dao.Set(core.NoteID(3), []core.Task{})
+		assert.Nil(t, err)
+		test(core.TaskFindOpts{}, []core.NoteTask{bob})
+	})
+}
+
+func testTaskDAO(t *testing.T, callback func(tx Transaction, dao *TaskDAO)) {
+	testTransaction(t, func(tx Transaction) {
+		callback(tx, NewTaskDAO(tx))
+	})
+}
@@ -1,6 +1,11 @@
 package sqlite
 
-import "database/sql"
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+)
 
 // Inspired by https://pseudomuto.com/2018/01/clean-sql-transactions-in-golang/
 
@@ -45,10 +50,30 @@ type TxFn func(tx Transaction) error
 
 // WithTransaction creates a new transaction and handles rollback/commit based
 // on the error object returned by the TxFn closure.
-func (db *DB) WithTransaction(fn TxFn) error {
-	tx, err := db.db.Begin()
+//
+// Returns core.ErrIndexLocked if the database is locked by another process.
+func (db *DB) WithTransaction(fn TxFn) (err error) {
+	return db.withTransaction(db.db, fn)
+}
+
+// WithWriteTransaction is similar to WithTransaction, but it locks the
+// database for writing before calling fn. The lock is requested again
+// LockRetries times if the database is still busy.
+//
+// Use it for the transactions modifying the database, to prevent failing
+// after reading from it when another process is writing.
+func (db *DB) WithWriteTransaction(fn TxFn) (err error) {
+	return db.withTransaction(db.writer, fn)
+}
+
+func (db *DB) withTransaction(native *sql.DB, fn TxFn) (err error) {
+	tx, err := native.Begin()
+	for i := 0; i < db.lockRetries && isLockedErr(err); i++ {
+		time.Sleep(lockRetryDelay)
+		tx, err = native.Begin()
+	}
 	if err != nil {
-		return err
+		return translateErr(err)
 	}
 
 	defer func() {
@@ -61,8 +86,17 @@ func (db *DB) WithTransaction(fn TxFn) error {
 		} else {
 			err = tx.Commit()
 		}
+		err = translateErr(err)
 	}()
 
 	err = fn(&txWrapper{tx})
 	return err
 }
+
+// translateErr converts the SQLite errors into their core counterpart.
+func translateErr(err error) error {
+	if isLockedErr(err) {
+		return core.ErrIndexLocked{Err: err}
+	}
+	return err
+}
@@ -0,0 +1,194 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// URLDAO caches the metadata of external URLs in the SQLite database.
+type URLDAO struct {
+	tx Transaction
+
+	// Prepared SQL statements
+	findUnfetchedStmt  *LazyStmt
+	findUnarchivedStmt *LazyStmt
+	findDeadStmt       *LazyStmt
+	findStmt           *LazyStmt
+	setStmt            *LazyStmt
+	setArchiveStmt     *LazyStmt
+}
+
+// NewURLDAO creates a new instance of a DAO working on the given database
+// transaction.
+func NewURLDAO(tx Transaction) *URLDAO {
+	return &URLDAO{
+		tx: tx,
+
+		// Find the external web links which were never fetched, first, or
+		// whose metadata are outdated.
+		findUnfetchedStmt: tx.PrepareLazy(`
+			SELECT DISTINCT l.href FROM links l
+			  LEFT JOIN url_metadata u ON u.url = l.href
+			 WHERE l.external = 1
+			   AND (l.href LIKE 'http://%' OR l.href LIKE 'https://%')
+			   AND (u.url IS NULL OR u.fetched < ?)
+			 ORDER BY u.fetched
+			 LIMIT ?
+		`),
+
+		// Find the external web links which were reachable when last
+		// fetched, but never archived.
+		findUnarchivedStmt: tx.PrepareLazy(`
+			SELECT u.url FROM url_metadata u
+			 WHERE u.archive = ''
+			   AND u.status BETWEEN 1 AND 399
+			   AND u.url IN (SELECT href FROM links WHERE external = 1)
+			 ORDER BY u.fetched
+			 LIMIT ?
+		`),
+
+		// Find the external web links which were unreachable when last
+		// fetched.
+		findDeadStmt: tx.PrepareLazy(`
+			SELECT u.url, u.title, u.status, u.fetched, u.archive FROM url_metadata u
+			 WHERE (u.status = 0 OR u.status >= 400)
+			   AND u.url IN (SELECT href FROM links WHERE external = 1)
+			 ORDER BY u.url
+		`),
+
+		// Get the cached metadata of a URL.
+		findStmt: tx.PrepareLazy(`
+			SELECT title, status, fetched, archive FROM url_metadata
+			 WHERE url = ?
+		`),
+
+		// Add or replace the metadata of a URL, keeping its archived
+		// version unless a new one is given.
+		setStmt: tx.PrepareLazy(`
+			INSERT INTO url_metadata(url, title, status, fetched, archive)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(url) DO UPDATE SET
+				title = excluded.title,
+				status = excluded.status,
+				fetched = excluded.fetched,
+				archive = COALESCE(NULLIF(excluded.archive, ''), archive)
+		`),
+
+		// Set the archived version of a URL.
+		setArchiveStmt: tx.PrepareLazy(`
+			UPDATE url_metadata SET archive = ?
+			 WHERE url = ?
+		`),
+	}
+}
+
+// FindUnfetched returns at most limit external URLs linked in the notes,
+// which were never fetched or not since the given date.
+func (d *URLDAO) FindUnfetched(fetchedBefore time.Time, limit int) ([]string, error) {
+	wrap := errors.Wrapper("failed to find the unfetched URLs")
+
+	rows, err := d.findUnfetchedStmt.Query(fetchedBefore.UTC(), limit)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	defer rows.Close()
+
+	urls := []string{}
+	for rows.Next() {
+		var url string
+		err := rows.Scan(&url)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		urls = append(urls, url)
+	}
+
+	return urls, wrap(rows.Err())
+}
+
+// FindUnarchived returns at most limit external URLs linked in the notes,
+// which were reachable when last fetched but never archived.
+func (d *URLDAO) FindUnarchived(limit int) ([]string, error) {
+	wrap := errors.Wrapper("failed to find the unarchived URLs")
+
+	rows, err := d.findUnarchivedStmt.Query(limit)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	defer rows.Close()
+
+	urls := []string{}
+	for rows.Next() {
+		var url string
+		err := rows.Scan(&url)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		urls = append(urls, url)
+	}
+
+	return urls, wrap(rows.Err())
+}
+
+// FindDead returns the cached metadata of the external URLs linked in the
+// notes, which were unreachable when last fetched.
+func (d *URLDAO) FindDead() ([]core.URLMetadata, error) {
+	wrap := errors.Wrapper("failed to find the dead URLs")
+
+	rows, err := d.findDeadStmt.Query()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	defer rows.Close()
+
+	urls := []core.URLMetadata{}
+	for rows.Next() {
+		var metadata core.URLMetadata
+		err := rows.Scan(&metadata.URL, &metadata.Title, &metadata.Status, &metadata.Fetched, &metadata.Archive)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		urls = append(urls, metadata)
+	}
+
+	return urls, wrap(rows.Err())
+}
+
+// Find returns the cached metadata of the given URL, or nil if it was never
+// fetched.
+func (d *URLDAO) Find(url string) (*core.URLMetadata, error) {
+	wrap := errors.Wrapperf("%s: failed to find the URL metadata", url)
+
+	row, err := d.findStmt.QueryRow(url)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	metadata := core.URLMetadata{URL: url}
+	err = row.Scan(&metadata.Title, &metadata.Status, &metadata.Fetched, &metadata.Archive)
+
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, nil
+	case err != nil:
+		return nil, wrap(err)
+	default:
+		return &metadata, nil
+	}
+}
+
+// Set caches the given URL metadata.
+func (d *URLDAO) Set(metadata core.URLMetadata) error {
+	_, err := d.setStmt.Exec(metadata.URL, metadata.Title, metadata.Status, metadata.Fetched.UTC(), metadata.Archive)
+	return errors.Wrapf(err, "%s: failed to cache the URL metadata", metadata.URL)
+}
+
+// SetArchive records the archived version of the given URL, whose metadata
+// must be cached already.
+func (d *URLDAO) SetArchive(url string, archive string) error {
+	_, err := d.setArchiveStmt.Exec(archive, url)
+	return errors.Wrapf(err, "%s: failed to record the archived URL", url)
+}
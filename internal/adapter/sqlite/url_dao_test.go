@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestURLDAOFindUnfetched(t *testing.T) {
+	testURLDAO(t, func(tx Transaction, dao *URLDAO) {
+		urls, err := dao.FindUnfetched(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), 10)
+		assert.Nil(t, err)
+		assert.Equal(t, urls, []string{"https://domain.com"})
+
+		err = dao.Set(core.URLMetadata{
+			URL:     "https://domain.com",
+			Status:  200,
+			Fetched: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		})
+		assert.Nil(t, err)
+
+		// Fetched after the given date.
+		urls, err = dao.FindUnfetched(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), 10)
+		assert.Nil(t, err)
+		assert.Equal(t, urls, []string{})
+
+		// Outdated.
+		urls, err = dao.FindUnfetched(time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), 10)
+		assert.Nil(t, err)
+		assert.Equal(t, urls, []string{"https://domain.com"})
+	})
+}
+
+func TestURLDAOFindUnknown(t *testing.T) {
+	testURLDAO(t, func(tx Transaction, dao *URLDAO) {
+		metadata, err := dao.Find("https://unknown.com")
+		assert.Nil(t, err)
+		assert.Nil(t, metadata)
+	})
+}
+
+func TestURLDAOSet(t *testing.T) {
+	testURLDAO(t, func(tx Transaction, dao *URLDAO) {
+		err := dao.Set(core.URLMetadata{
+			URL:     "https://domain.com",
+			Status:  404,
+			Fetched: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+		assert.Nil(t, err)
+		// Replaces the existing metadata.
+		err = dao.Set(core.URLMetadata{
+			URL:     "https://domain.com",
+			Title:   "Domain",
+			Status:  200,
+			Fetched: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		})
+		assert.Nil(t, err)
+
+		metadata, err := dao.Find("https://domain.com")
+		assert.Nil(t, err)
+		assert.Equal(t, metadata, &core.URLMetadata{
+			URL:     "https://domain.com",
+			Title:   "Domain",
+			Status:  200,
+			Fetched: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		})
+	})
+}
+
+func TestURLDAOArchive(t *testing.T) {
+	testURLDAO(t, func(tx Transaction, dao *URLDAO) {
+		// Never fetched.
+		urls, err := dao.FindUnarchived(10)
+		assert.Nil(t, err)
+		assert.Equal(t, urls, []string{})
+
+		// Dead when fetched.
+		err = dao.Set(core.URLMetadata{URL: "https://domain.com", Status: 404})
+		assert.Nil(t, err)
+		urls, err = dao.FindUnarchived(10)
+		assert.Nil(t, err)
+		assert.Equal(t, urls, []string{})
+
+		err = dao.Set(core.URLMetadata{URL: "https://domain.com", Status: 200})
+		assert.Nil(t, err)
+		urls, err = dao.FindUnarchived(10)
+		assert.Nil(t, err)
+		assert.Equal(t, urls, []string{"https://domain.com"})
+
+		err = dao.SetArchive("https://domain.com", "https://web.archive.org/web/2021/https://domain.com")
+		assert.Nil(t, err)
+		urls, err = dao.FindUnarchived(10)
+		assert.Nil(t, err)
+		assert.Equal(t, urls, []string{})
+
+		// Fetching the metadata again keeps the archive.
+		err = dao.Set(core.URLMetadata{
+			URL:     "https://domain.com",
+			Status:  404,
+			Fetched: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+		})
+		assert.Nil(t, err)
+		metadata, err := dao.Find("https://domain.com")
+		assert.Nil(t, err)
+		assert.Equal(t, metadata, &core.URLMetadata{
+			URL:     "https://domain.com",
+			Status:  404,
+			Fetched: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+			Archive: "https://web.archive.org/web/2021/https://domain.com",
+		})
+	})
+}
+
+func TestURLDAOFindDead(t *testing.T) {
+	testURLDAO(t, func(tx Transaction, dao *URLDAO) {
+		urls, err := dao.FindDead()
+		assert.Nil(t, err)
+		assert.Equal(t, urls, []core.URLMetadata{})
+
+		fetched := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+		for _, metadata := range []core.URLMetadata{
+			{URL: "https://domain.com", Status: 404, Fetched: fetched, Archive: "https://web.archive.org/web/2021/https://domain.com"},
+			// Not linked in the notes.
+			{URL: "https://other.org", Status: 0, Fetched: fetched},
+		} {
+			assert.Nil(t, dao.Set(metadata))
+		}
+
+		urls, err = dao.FindDead()
+		assert.Nil(t, err)
+		assert.Equal(t, urls, []core.URLMetadata{
+			{URL: "https://domain.com", Status: 404, Fetched: fetched, Archive: "https://web.archive.org/web/2021/https://domain.com"},
+		})
+
+		assert.Nil(t, dao.Set(core.URLMetadata{URL: "https://domain.com", Status: 200, Fetched: fetched}))
+		urls, err = dao.FindDead()
+		assert.Nil(t, err)
+		assert.Equal(t, urls, []core.URLMetadata{})
+	})
+}
+
+func testURLDAO(t *testing.T, callback func(tx Transaction, dao *URLDAO)) {
+	testTransaction(t, func(tx Transaction) {
+		callback(tx, NewURLDAO(tx))
+	})
+}
@@ -12,3 +12,9 @@ func escapeLikeTerm(term string, escapeChar rune) string {
 	}
 	return escape(escape(escape(term, string(escapeChar)), "%"), "_")
 }
+
+// quoteSQLString returns the given string as a SQL string literal, for the
+// expressions which can't use arguments.
+func quoteSQLString(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", "''") + "'"
+}
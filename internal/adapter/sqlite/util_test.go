@@ -15,3 +15,8 @@ func TestEscapeLikeTerm(t *testing.T) {
 	test("foo%bar_with@", '@', "foo@%bar@_with@@")
 	test(`foo%bar_with\`, '\\', `foo\%bar\_with\\`)
 }
+
+func TestQuoteSQLString(t *testing.T) {
+	assert.Equal(t, quoteSQLString("status"), "'status'")
+	assert.Equal(t, quoteSQLString("it's"), "'it''s'")
+}
@@ -49,3 +49,36 @@ func (t *Terminal) Confirm(msg string, defaultAnswer bool) (confirmed, skipped b
 	survey.AskOne(prompt, &confirmed)
 	return confirmed, false
 }
+
+// Select prompts the user to pick one of the given options, and returns its
+// index. ok is false when the prompt was skipped or interrupted.
+func (t *Terminal) Select(msg string, options []string, defaultIndex int) (index int, ok bool) {
+	if !t.IsInteractive() {
+		return defaultIndex, false
+	}
+
+	prompt := &survey.Select{
+		Message: msg,
+		Options: options,
+		Default: options[defaultIndex],
+	}
+	err := survey.AskOne(prompt, &index)
+	return index, err == nil
+}
+
+// Input prompts the user for a line of text, checked with the given validate
+// function. ok is false when the prompt was skipped or interrupted.
+func (t *Terminal) Input(msg string, defaultValue string, validate func(value string) error) (value string, ok bool) {
+	if !t.IsInteractive() {
+		return defaultValue, false
+	}
+
+	prompt := &survey.Input{
+		Message: msg,
+		Default: defaultValue,
+	}
+	err := survey.AskOne(prompt, &value, survey.WithValidator(func(answer interface{}) error {
+		return validate(answer.(string))
+	}))
+	return value, err == nil
+}
@@ -0,0 +1,451 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// ErrCancelled is returned when the user quits the browser without selecting
+// a note.
+var ErrCancelled = errors.New("cancelled")
+
+// BrowserOpts holds the configuration of the notes browser.
+type BrowserOpts struct {
+	// Criteria used to find the notes, refined by the search query.
+	FindOpts core.NoteFindOpts
+	// Initial search query.
+	Query string
+}
+
+// Browser is a terminal UI to search the notes of a notebook incrementally,
+// with a preview of the selected note and its backlinks.
+type Browser struct {
+	notebook *core.Notebook
+	opts     BrowserOpts
+	screen   tcell.Screen
+
+	query string
+	notes []core.ContextualNote
+	// Index of the selected note in notes.
+	selected  int
+	listTop   int
+	backlinks []core.MinimalNote
+	// Index of the selected backlink.
+	selectedBacklink int
+	// Whether the keyboard moves the selection in the backlinks pane instead
+	// of the search results.
+	focusBacklinks bool
+	previewTop     int
+	// Error of the last search, e.g. because of an invalid query syntax.
+	err error
+}
+
+// NewBrowser creates a new notes browser for the given notebook.
+func NewBrowser(notebook *core.Notebook, opts BrowserOpts) *Browser {
+	return &Browser{
+		notebook: notebook,
+		opts:     opts,
+		query:    opts.Query,
+	}
+}
+
+// Run shows the browser until the user selects a note, and returns the path
+// of the selected note relative to the notebook root.
+//
+// ErrCancelled is returned when the user quits without selecting a note.
+func (b *Browser) Run() (string, error) {
+	wrap := errors.Wrapper("browser")
+
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return "", wrap(err)
+	}
+	err = screen.Init()
+	if err != nil {
+		return "", wrap(err)
+	}
+	defer screen.Fini()
+
+	return b.run(screen)
+}
+
+func (b *Browser) run(screen tcell.Screen) (string, error) {
+	b.screen = screen
+	b.search()
+
+	for {
+		b.draw()
+
+		switch event := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			path, done := b.handleKey(event)
+			if done {
+				if path == "" {
+					return "", ErrCancelled
+				}
+				return path, nil
+			}
+		}
+	}
+}
+
+// handleKey updates the state of the browser after a key press. It returns
+// done when the browser must be closed, with the path of the selected note if
+// any.
+func (b *Browser) handleKey(event *tcell.EventKey) (path string, done bool) {
+	switch event.Key() {
+	case tcell.KeyEscape, tcell.KeyCtrlC:
+		return "", true
+
+	case tcell.KeyEnter:
+		if b.focusBacklinks {
+			if b.selectedBacklink < len(b.backlinks) {
+				return b.backlinks[b.selectedBacklink].Path, true
+			}
+		} else if b.selected < len(b.notes) {
+			return b.notes[b.selected].Path, true
+		}
+
+	case tcell.KeyTab, tcell.KeyBacktab:
+		b.focusBacklinks = !b.focusBacklinks && len(b.backlinks) > 0
+
+	case tcell.KeyUp, tcell.KeyCtrlP, tcell.KeyCtrlK:
+		b.moveSelection(-1)
+	case tcell.KeyDown, tcell.KeyCtrlN, tcell.KeyCtrlJ:
+		b.moveSelection(1)
+
+	case tcell.KeyPgUp:
+		b.scrollPreview(-b.previewHeight() / 2)
+	case tcell.KeyPgDn:
+		b.scrollPreview(b.previewHeight() / 2)
+
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if b.query != "" {
+			runes := []rune(b.query)
+			b.setQuery(string(runes[:len(runes)-1]))
+		}
+	case tcell.KeyCtrlW:
+		query := strings.TrimRight(b.query, " ")
+		b.setQuery(query[:strings.LastIndex(query, " ")+1])
+	case tcell.KeyCtrlU:
+		b.setQuery("")
+
+	case tcell.KeyRune:
+		b.setQuery(b.query + string(event.Rune()))
+	}
+
+	return "", false
+}
+
+func (b *Browser) setQuery(query string) {
+	if query == b.query {
+		return
+	}
+	b.query = query
+	b.search()
+}
+
+// search finds the notes matching the current query, and resets the
+// selection.
+func (b *Browser) search() {
+	findOpts := b.opts.FindOpts
+	match, tags := parseQuery(b.query)
+	if match != "" {
+		findOpts.Match = opt.NewNotEmptyString(strings.TrimSpace(findOpts.Match.String() + " " + match))
+	}
+	findOpts.Tags = append(append([]string{}, findOpts.Tags...), tags...)
+	if findOpts.Limit == 0 {
+		// Keeps the browser responsive with large notebooks.
+		findOpts.Limit = 1000
+	}
+
+	notes, err := b.notebook.FindNotes(findOpts)
+	b.err = err
+	if err != nil {
+		// Keeps the previous results while the query is being typed.
+		return
+	}
+
+	b.notes = notes
+	b.selected = 0
+	b.listTop = 0
+	b.selectionChanged()
+}
+
+func (b *Browser) moveSelection(delta int) {
+	if b.focusBacklinks {
+		b.selectedBacklink = clamp(b.selectedBacklink+delta, 0, len(b.backlinks)-1)
+		return
+	}
+
+	selected := clamp(b.selected+delta, 0, len(b.notes)-1)
+	if selected != b.selected {
+		b.selected = selected
+		b.selectionChanged()
+	}
+}
+
+// selectionChanged loads the backlinks of the newly selected note.
+func (b *Browser) selectionChanged() {
+	b.previewTop = 0
+	b.backlinks = []core.MinimalNote{}
+	b.selectedBacklink = 0
+	b.focusBacklinks = false
+
+	if b.selected >= len(b.notes) {
+		return
+	}
+	backlinks, err := b.notebook.FindMinimalNotes(core.NoteFindOpts{
+		LinkTo:  &core.LinkFilter{Paths: []string{b.notes[b.selected].Path}},
+		Trash:   core.TrashFilterExclude,
+		Sorters: []core.NoteSorter{{Field: core.NoteSortTitle, Ascending: true}},
+	})
+	if err != nil {
+		b.err = err
+		return
+	}
+	b.backlinks = backlinks
+}
+
+func (b *Browser) scrollPreview(delta int) {
+	b.previewTop = clamp(b.previewTop+delta, 0, len(b.previewLines(b.previewWidth()))-1)
+}
+
+var (
+	styleDefault  = tcell.StyleDefault
+	styleDim      = styleDefault.Dim(true)
+	styleBold     = styleDefault.Bold(true)
+	styleSelected = styleDefault.Reverse(true)
+	styleFocused  = styleDefault.Reverse(true).Bold(true)
+	styleTag      = styleDefault.Foreground(tcell.ColorTeal)
+	styleError    = styleDefault.Foreground(tcell.ColorRed)
+)
+
+// maxBacklinksHeight is the maximum number of backlinks displayed at once.
+const maxBacklinksHeight = 8
+
+// Layout of the screen:
+//
+//	> query                          count
+//	notes list     │ preview
+//	               │ ── Backlinks ──
+//	               │ backlinks
+//	key bindings or error
+func (b *Browser) draw() {
+	b.screen.Clear()
+	width, height := b.screen.Size()
+	listWidth := b.listWidth()
+
+	// Search prompt.
+	x := drawText(b.screen, 0, 0, width, styleBold, "> ")
+	x = drawText(b.screen, x, 0, width-x, styleDefault, b.query)
+	b.screen.ShowCursor(x, 0)
+	count := fmt.Sprintf("%d %s", len(b.notes), strutil.Pluralize("note", len(b.notes)))
+	drawText(b.screen, width-runewidth.StringWidth(count), 0, width, styleDim, count)
+
+	// Search results.
+	listHeight := height - 2
+	if b.selected < b.listTop {
+		b.listTop = b.selected
+	} else if b.selected >= b.listTop+listHeight {
+		b.listTop = b.selected - listHeight + 1
+	}
+	for row := 0; row < listHeight && b.listTop+row < len(b.notes); row++ {
+		i := b.listTop + row
+		style := styleDefault
+		if i == b.selected {
+			style = styleSelected
+			if !b.focusBacklinks {
+				style = styleFocused
+			}
+			fill(b.screen, 0, row+1, listWidth, style)
+		}
+		drawText(b.screen, 0, row+1, listWidth, style, noteLabel(b.notes[i].Title, b.notes[i].Path))
+	}
+	for row := 1; row < height-1; row++ {
+		b.screen.SetContent(listWidth, row, '│', nil, styleDim)
+	}
+
+	// Preview.
+	previewX := listWidth + 2
+	previewWidth := b.previewWidth()
+	lines := b.previewLines(previewWidth)
+	for row := 0; row < b.previewHeight() && b.previewTop+row < len(lines); row++ {
+		line := lines[b.previewTop+row]
+		drawText(b.screen, previewX, row+1, previewWidth, line.style, line.text)
+	}
+
+	// Backlinks.
+	y := b.previewHeight() + 1
+	title := fmt.Sprintf("── Backlinks (%d) ", len(b.backlinks))
+	x = drawText(b.screen, previewX, y, previewWidth, styleDim, title)
+	drawText(b.screen, x, y, previewWidth-(x-previewX), styleDim, strings.Repeat("─", width))
+	for i, backlink := range b.backlinks {
+		if i >= maxBacklinksHeight {
+			break
+		}
+		style := styleDefault
+		if b.focusBacklinks && i == b.selectedBacklink {
+			style = styleFocused
+			fill(b.screen, previewX, y+i+1, previewWidth, style)
+		}
+		drawText(b.screen, previewX, y+i+1, previewWidth, style, noteLabel(backlink.Title, backlink.Path))
+	}
+
+	// Status line.
+	if b.err != nil {
+		drawText(b.screen, 0, height-1, width, styleError, b.err.Error())
+	} else {
+		drawText(b.screen, 0, height-1, width, styleDim, "↑↓ select · tab backlinks · pgup/pgdn scroll · #tag filter · enter open · esc quit")
+	}
+
+	b.screen.Show()
+}
+
+func (b *Browser) listWidth() int {
+	width, _ := b.screen.Size()
+	return width * 2 / 5
+}
+
+func (b *Browser) previewWidth() int {
+	width, _ := b.screen.Size()
+	return width - b.listWidth() - 2
+}
+
+// previewHeight returns the number of rows available to the preview, above
+// the backlinks pane.
+func (b *Browser) previewHeight() int {
+	_, height := b.screen.Size()
+	backlinksHeight := len(b.backlinks)
+	if backlinksHeight > maxBacklinksHeight {
+		backlinksHeight = maxBacklinksHeight
+	}
+	// Keeps a row for the prompt, the status line and the backlinks title.
+	return height - 3 - backlinksHeight
+}
+
+type styledLine struct {
+	text  string
+	style tcell.Style
+}
+
+// previewLines renders the selected note, wrapped to the given width.
+func (b *Browser) previewLines(width int) []styledLine {
+	if b.selected >= len(b.notes) {
+		return []styledLine{}
+	}
+	note := b.notes[b.selected]
+
+	lines := []styledLine{}
+	for _, line := range wrapText(noteLabel(note.Title, note.Path), width) {
+		lines = append(lines, styledLine{line, styleBold})
+	}
+	lines = append(lines, styledLine{note.Path, styleDim})
+	if len(note.Tags) > 0 {
+		lines = append(lines, styledLine{"#" + strings.Join(note.Tags, " #"), styleTag})
+	}
+	lines = append(lines, styledLine{"", styleDefault})
+	for _, line := range wrapText(strings.TrimSpace(note.Body), width) {
+		lines = append(lines, styledLine{line, styleDefault})
+	}
+	return lines
+}
+
+// parseQuery splits the search query between the terms matched in the notes
+// and the tags, which start with #.
+func parseQuery(query string) (match string, tags []string) {
+	terms := []string{}
+	tags = []string{}
+	for _, term := range strings.Fields(query) {
+		if len(term) > 1 && strings.HasPrefix(term, "#") {
+			tags = append(tags, strings.TrimPrefix(term, "#"))
+		} else {
+			terms = append(terms, term)
+		}
+	}
+	return strings.Join(terms, " "), tags
+}
+
+// wrapText splits the given text into lines fitting in width columns,
+// breaking them between words when possible.
+func wrapText(text string, width int) []string {
+	lines := []string{}
+	if width <= 0 {
+		return lines
+	}
+
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\t", "    "), "\n") {
+		paragraph = strings.TrimRight(paragraph, " \r")
+		wrapped := false
+		for runewidth.StringWidth(paragraph) > width {
+			wrapped = true
+			cut := runewidth.Truncate(paragraph, width, "")
+			if space := strings.LastIndex(cut, " "); space > 0 {
+				cut = cut[:space+1]
+			} else if cut == "" {
+				// The first character is wider than the line.
+				_, size := utf8.DecodeRuneInString(paragraph)
+				cut = paragraph[:size]
+			}
+			lines = append(lines, strings.TrimRight(cut, " "))
+			paragraph = paragraph[len(cut):]
+		}
+		if paragraph != "" || !wrapped {
+			lines = append(lines, paragraph)
+		}
+	}
+	return lines
+}
+
+// noteLabel returns the text representing a note in the lists.
+func noteLabel(title string, path string) string {
+	if title == "" {
+		return path
+	}
+	return title
+}
+
+// drawText prints text on a single row, truncated to maxWidth columns. It
+// returns the column following the text.
+func drawText(screen tcell.Screen, x int, y int, maxWidth int, style tcell.Style, text string) int {
+	end := x + maxWidth
+	for _, r := range text {
+		w := runewidth.RuneWidth(r)
+		if w == 0 {
+			continue
+		}
+		if x+w > end {
+			break
+		}
+		screen.SetContent(x, y, r, nil, style)
+		x += w
+	}
+	return x
+}
+
+// fill paints the background of a row segment with the given style.
+func fill(screen tcell.Screen, x int, y int, width int, style tcell.Style) {
+	for i := 0; i < width; i++ {
+		screen.SetContent(x+i, y, ' ', nil, style)
+	}
+}
+
+func clamp(value int, min int, max int) int {
+	if value > max {
+		value = max
+	}
+	if value < min {
+		value = min
+	}
+	return value
+}
@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	test := func(query string, expectedMatch string, expectedTags []string) {
+		match, tags := parseQuery(query)
+		assert.Equal(t, match, expectedMatch)
+		assert.Equal(t, tags, expectedTags)
+	}
+
+	test("", "", []string{})
+	test("  zettel  kasten ", "zettel kasten", []string{})
+	test("#book zettel #to-read", "zettel", []string{"book", "to-read"})
+	test("# heading", "# heading", []string{})
+}
+
+func TestWrapText(t *testing.T) {
+	test := func(text string, width int, expected []string) {
+		assert.Equal(t, wrapText(text, width), expected)
+	}
+
+	test("", 10, []string{""})
+	test("Short line", 0, []string{})
+	test("Short line", 10, []string{"Short line"})
+	test("A longer line to wrap", 10, []string{"A longer", "line to", "wrap"})
+	test("Unbreakablewordhere", 8, []string{"Unbreaka", "blewordh", "ere"})
+	test("First\n\n\tIndented", 20, []string{"First", "", "    Indented"})
+	test("日本語のテキスト", 5, []string{"日本", "語の", "テキ", "スト"})
+	test("日本", 1, []string{"日", "本"})
+}
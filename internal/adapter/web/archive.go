@@ -0,0 +1,52 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+)
+
+// waybackURL is the base URL of the Wayback Machine of the Internet Archive.
+const waybackURL = "https://web.archive.org"
+
+// NewWaybackArchiver creates a core.URLArchiver saving the URLs to the
+// Wayback Machine, with the given timeout.
+func NewWaybackArchiver(timeout time.Duration) core.URLArchiver {
+	return newWaybackArchiver(waybackURL, timeout)
+}
+
+func newWaybackArchiver(baseURL string, timeout time.Duration) core.URLArchiver {
+	client := &http.Client{Timeout: timeout}
+
+	return func(rawURL string) (string, error) {
+		req, err := http.NewRequest("GET", baseURL+"/save/"+rawURL, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("User-Agent", "zk")
+
+		res, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode >= 400 {
+			return "", fmt.Errorf("the Wayback Machine failed to save the URL: %s", res.Status)
+		}
+
+		// The save request redirects to the snapshot, but older versions of
+		// the API only give its location in a header.
+		if snapshot := res.Request.URL.RequestURI(); strings.HasPrefix(snapshot, "/web/") {
+			return waybackURL + snapshot, nil
+		}
+		if snapshot := res.Header.Get("Content-Location"); strings.HasPrefix(snapshot, "/web/") {
+			return waybackURL + snapshot, nil
+		}
+
+		return "", fmt.Errorf("the Wayback Machine didn't return the location of the snapshot")
+	}
+}
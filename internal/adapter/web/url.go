@@ -0,0 +1,95 @@
+package web
+
+import (
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+)
+
+// maxBodySize is the maximum number of bytes read from a web page to find its
+// title, which is usually located at the top of the document.
+const maxBodySize = 1024 * 1024
+
+var titleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// NewURLMetadataFetcher creates a core.URLMetadataFetcher performing HTTP
+// requests with the given timeout.
+func NewURLMetadataFetcher(timeout time.Duration) core.URLMetadataFetcher {
+	client := &http.Client{Timeout: timeout}
+
+	return func(url string) (core.URLMetadata, error) {
+		metadata := core.URLMetadata{URL: url}
+
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return metadata, err
+		}
+		req.Header.Set("User-Agent", "zk")
+
+		res, err := client.Do(req)
+		if err != nil {
+			return metadata, err
+		}
+		defer res.Body.Close()
+
+		metadata.Status = res.StatusCode
+		if res.StatusCode >= 400 || !strings.Contains(res.Header.Get("Content-Type"), "html") {
+			return metadata, nil
+		}
+
+		body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxBodySize))
+		if err != nil {
+			return metadata, err
+		}
+		metadata.Title = parseTitle(string(body))
+
+		return metadata, nil
+	}
+}
+
+// NewURLChecker creates a core.URLChecker performing HTTP HEAD requests with
+// the given timeout.
+//
+// Some servers don't support HEAD requests, in which case the URL is checked
+// with a GET request instead.
+func NewURLChecker(timeout time.Duration) core.URLChecker {
+	client := &http.Client{Timeout: timeout}
+
+	request := func(method string, url string) (int, error) {
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("User-Agent", "zk")
+
+		res, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		res.Body.Close()
+		return res.StatusCode, nil
+	}
+
+	return func(url string) (int, error) {
+		status, err := request("HEAD", url)
+		if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+			status, err = request("GET", url)
+		}
+		return status, err
+	}
+}
+
+// parseTitle extracts the content of the <title> tag of an HTML document.
+func parseTitle(body string) string {
+	matches := titleRegex.FindStringSubmatch(body)
+	if matches == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(matches[1])), " ")
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestParseTitle(t *testing.T) {
+	assert.Equal(t, parseTitle(""), "")
+	assert.Equal(t, parseTitle("<html><body>No title</body></html>"), "")
+	assert.Equal(t, parseTitle("<head><title>A title</title></head>"), "A title")
+	assert.Equal(t, parseTitle("<TITLE lang=\"en\">\n  Multi\n  line &amp; escaped\n</TITLE>"), "Multi line & escaped")
+}
+
+func TestFetchURLMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><head><title>Page title</title></head></html>"))
+		case "/file":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("<title>Not a page</title>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetch := NewURLMetadataFetcher(time.Second)
+	test := func(path string, expected core.URLMetadata) {
+		actual, err := fetch(server.URL + path)
+		assert.Nil(t, err)
+		expected.URL = server.URL + path
+		assert.Equal(t, actual, expected)
+	}
+
+	test("/page", core.URLMetadata{Title: "Page title", Status: 200})
+	test("/file", core.URLMetadata{Status: 200})
+	test("/missing", core.URLMetadata{Status: 404})
+}
+
+func TestCheckURL(t *testing.T) {
+	methods := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		switch r.URL.Path {
+		case "/page":
+			w.WriteHeader(http.StatusOK)
+		case "/get-only":
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	check := NewURLChecker(time.Second)
+	test := func(path string, expectedStatus int, expectedMethods []string) {
+		methods = []string{}
+		status, err := check(server.URL + path)
+		assert.Nil(t, err)
+		assert.Equal(t, status, expectedStatus)
+		assert.Equal(t, methods, expectedMethods)
+	}
+
+	test("/page", 200, []string{"HEAD"})
+	test("/get-only", 200, []string{"HEAD", "GET"})
+	test("/gone", 410, []string{"HEAD"})
+	test("/missing", 404, []string{"HEAD"})
+
+	_, err := check("http://127.0.0.1:1/unreachable")
+	assert.NotNil(t, err)
+}
+
+func TestWaybackArchiver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/save/https://example.com/page":
+			w.Header().Set("Location", "/web/20211020142300/https://example.com/page?q=1")
+			w.WriteHeader(http.StatusFound)
+		case "/save/https://example.com/header":
+			w.Header().Set("Content-Location", "/web/20211020142300/https://example.com/header")
+		case "/save/https://example.com/nowhere":
+			w.WriteHeader(http.StatusOK)
+		case "/web/20211020142300/https://example.com/page":
+			w.Write([]byte("snapshot"))
+		default:
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	archive := newWaybackArchiver(server.URL, time.Second)
+
+	actual, err := archive("https://example.com/page")
+	assert.Nil(t, err)
+	assert.Equal(t, actual, "https://web.archive.org/web/20211020142300/https://example.com/page?q=1")
+
+	actual, err = archive("https://example.com/header")
+	assert.Nil(t, err)
+	assert.Equal(t, actual, "https://web.archive.org/web/20211020142300/https://example.com/header")
+
+	_, err = archive("https://example.com/nowhere")
+	assert.Err(t, err, "the Wayback Machine didn't return the location of the snapshot")
+
+	_, err = archive("https://example.com/limited")
+	assert.Err(t, err, "the Wayback Machine failed to save the URL: 429 Too Many Requests")
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Archive moves notes to the archive directory, updating the links to them.
+type Archive struct {
+	Paths  []string `arg help:"Paths to the notes to archive."`
+	DryRun bool     `short:n help:"Print the links which would be updated, without modifying any file."`
+}
+
+func (cmd *Archive) Help() string {
+	return "The archive directory is set with `archive-dir` in the [trash] config section. The archived notes keep their path below it and are still indexed, so that the links to them resolve, but they are hidden from the searches unless --include-archived is used. Move a note out of the archive with `zk mv` to unarchive it."
+}
+
+func (cmd *Archive) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	verb := "Archived"
+	if cmd.DryRun {
+		verb = "Would archive"
+	}
+
+	for _, path := range cmd.Paths {
+		move, err := notebook.ArchiveNote(container.Origin, path, cmd.DryRun)
+		if err != nil {
+			return err
+		}
+
+		for _, link := range move.RewrittenLinks {
+			fmt.Println(link)
+		}
+
+		linkCount := len(move.RewrittenLinks)
+		noteCount := len(move.NotePaths())
+		fmt.Fprintf(os.Stderr, "%s %s to %s, updating %d %s in %d %s\n",
+			verb, move.SourcePath, move.TargetPath,
+			linkCount, strings.Pluralize("link", linkCount),
+			noteCount, strings.Pluralize("note", noteCount),
+		)
+	}
+
+	return nil
+}
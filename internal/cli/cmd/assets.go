@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Assets lists the files of the notebook which are not notes, e.g. images.
+type Assets struct {
+	Unused  bool   `group:filter short:u help:"List only the assets which are not linked from any note."`
+	Format  string `group:format short:f placeholder:FORMAT help:"Format of the assets, among: path, jsonl."`
+	NoPager bool   `group:format short:P help:"Do not pipe output into a pager."`
+	Quiet   bool   `group:format short:q help:"Do not print the total number of assets found."`
+}
+
+func (cmd *Assets) Run(container *cli.Container) error {
+	if cmd.Format != "" && cmd.Format != "path" && cmd.Format != "jsonl" {
+		return fmt.Errorf("%s: unknown assets format, try path or jsonl", cmd.Format)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	assets, err := notebook.FindAssets()
+	if err != nil {
+		return err
+	}
+
+	if cmd.Unused {
+		unused := []core.Asset{}
+		for _, asset := range assets {
+			if len(asset.LinkedBy) == 0 {
+				unused = append(unused, asset)
+			}
+		}
+		assets = unused
+	}
+
+	count := len(assets)
+	if count > 0 {
+		err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
+			for _, asset := range assets {
+				if cmd.Format == "jsonl" {
+					line, err := json.Marshal(asset)
+					if err != nil {
+						return errors.Wrap(err, "failed to serialize asset")
+					}
+					fmt.Fprintln(out, string(line))
+				} else {
+					fmt.Fprintln(out, asset.Path)
+				}
+			}
+			return nil
+		})
+	}
+
+	if err == nil && !cmd.Quiet {
+		fmt.Fprintf(os.Stderr, "\nFound %d %s\n", count, strings.Pluralize("asset", count))
+	}
+
+	return err
+}
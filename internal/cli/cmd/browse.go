@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/mickael-menu/zk/internal/adapter/tui"
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+)
+
+// Browse searches the notes interactively in a terminal UI, with a preview
+// and the backlinks of the selected note.
+type Browse struct {
+	Print bool `short:p help:"Print the path of the selected note instead of opening it in the editor."`
+	cli.Filtering
+}
+
+func (cmd *Browse) Help() string {
+	return "Type to search the notes, and add #tag to the query to filter by tags. The filtering options narrow down the notes found by the search."
+}
+
+func (cmd *Browse) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	findOpts, err := cmd.Filtering.NewNoteFindOpts(notebook)
+	if err != nil {
+		return errors.Wrapf(err, "incorrect criteria")
+	}
+	// The --match terms are editable in the browser.
+	findOpts.Match = opt.NullString
+
+	path, err := tui.NewBrowser(notebook, tui.BrowserOpts{
+		FindOpts: findOpts,
+		Query:    cmd.Match,
+	}).Run()
+	if err != nil {
+		if err == tui.ErrCancelled {
+			return nil
+		}
+		return err
+	}
+
+	absPath := filepath.Join(notebook.Path, path)
+	if cmd.Print {
+		fmt.Println(absPath)
+		return nil
+	}
+
+	editor, err := container.NewNoteEditor(notebook)
+	if err != nil {
+		return err
+	}
+	return notebook.EditNotes([]string{absPath}, editor.Open)
+}
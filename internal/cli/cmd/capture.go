@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/adapter/email"
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/os"
+)
+
+// Capture creates a new note from the standard input, e.g. from a mail filter.
+type Capture struct {
+	Directory      string            `arg optional default:"." help:"Directory in which to create the note."`
+	Mime           bool              `short:m help:"Parse the standard input as an email (RFC 822), to use its subject as title and save its attachments."`
+	Title          string            `short:t placeholder:TITLE help:"Title of the new note, when not parsed from the input."`
+	Group          string            `short:g placeholder:NAME  help:"Name of the config group this note belongs to, e.g. an inbox."`
+	Extra          map[string]string `help:"Extra variables passed to the templates." mapsep:","`
+	Template       string            `placeholder:PATH help:"Custom template used to render the note."`
+	AttachmentsDir string            `default:"attachments" placeholder:PATH help:"Directory where the attachments are saved, relative to the note directory."`
+}
+
+func (cmd *Capture) Help() string {
+	return "The email body is available as {{content}} in the note template, followed by links to the saved attachments. The sender and original date are available as {{extra.from}} and {{extra.date}}."
+}
+
+func (cmd *Capture) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	input, err := os.ReadStdin()
+	if err != nil {
+		return err
+	}
+
+	extra := map[string]string{}
+	for k, v := range cmd.Extra {
+		extra[k] = v
+	}
+	opts := core.CaptureNoteOpts{
+		NewNoteOpts: core.NewNoteOpts{
+			Title:     opt.NewNotEmptyString(cmd.Title),
+			Content:   input,
+			Directory: opt.NewNotEmptyString(cmd.Directory),
+			Group:     opt.NewNotEmptyString(cmd.Group),
+			Template:  opt.NewNotEmptyString(cmd.Template),
+			Extra:     extra,
+			Date:      time.Now(),
+			Origin:    container.Origin,
+		},
+		AttachmentsDir: cmd.AttachmentsDir,
+	}
+
+	if cmd.Mime {
+		msg, err := email.Parse(strings.NewReader(input))
+		if err != nil {
+			return err
+		}
+		opts.Title = opts.Title.Or(opt.NewNotEmptyString(msg.Subject))
+		opts.Content = msg.Body
+		opts.Attachments = msg.Attachments
+		extra["from"] = msg.From
+		if !msg.Date.IsZero() {
+			extra["date"] = msg.Date.Format(time.RFC3339)
+		}
+	}
+
+	note, err := notebook.CaptureNote(opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(filepath.Join(notebook.Path, note.Path))
+	return nil
+}
@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/mickael-menu/zk/internal/adapter/fzf"
 	"github.com/mickael-menu/zk/internal/cli"
@@ -14,9 +16,15 @@ import (
 // Edit opens notes matching a set of criteria with the user editor.
 type Edit struct {
 	Force bool `short:f help:"Do not confirm before editing many notes at the same time."`
+	Split bool `help:"Open the note side by side with the list of its backlinks."`
+	Last  int  `placeholder:COUNT help:"Open the given number of notes recently created or opened."`
 	cli.Filtering
 }
 
+func (cmd *Edit) Help() string {
+	return "With --split, the backlinks of the note are written in .zk/backlinks.md, which is opened side by side with the note in Vim, Neovim and Helix. Set the editor arguments to open files side by side with the `editor-split` key of the [tool] config section."
+}
+
 func (cmd *Edit) Run(container *cli.Container) error {
 	notebook, err := container.CurrentNotebook()
 	if err != nil {
@@ -27,6 +35,10 @@ func (cmd *Edit) Run(container *cli.Container) error {
 	if err != nil {
 		return errors.Wrapf(err, "incorrect criteria")
 	}
+	if cmd.Last > 0 {
+		findOpts.RecentlyOpened = true
+		findOpts.Limit = cmd.Last
+	}
 
 	notes, err := notebook.FindNotes(findOpts)
 	if err != nil {
@@ -49,6 +61,9 @@ func (cmd *Edit) Run(container *cli.Container) error {
 	}
 
 	count := len(notes)
+	if cmd.Split && count > 1 {
+		return fmt.Errorf("--split opens a single note, but %d notes were found", count)
+	}
 
 	if count > 0 {
 		if !cmd.Force && count > 5 {
@@ -69,11 +84,20 @@ func (cmd *Edit) Run(container *cli.Container) error {
 		if err != nil {
 			return err
 		}
-		return editor.Open(paths...)
+		if cmd.Split {
+			backlinksPath, err := writeBacklinksFile(notebook, notes[0])
+			if err != nil {
+				return err
+			}
+			return notebook.EditNotes(paths, func(paths ...string) error {
+				return editor.OpenSplit(append(paths, backlinksPath)...)
+			})
+		}
+		return notebook.EditNotes(paths, editor.Open)
 
 	} else {
 		fmt.Fprintln(os.Stderr, "Found 0 note")
-		return nil
+		return cli.ErrNoResults
 	}
 }
 
@@ -95,3 +119,53 @@ func (cmd *Edit) newNoteDir(notebook *core.Notebook) *core.Dir {
 		return nil
 	}
 }
+
+// writeBacklinksFile lists the notes linking to the given one in
+// .zk/backlinks.md, with the context of their links. Returns the path of the
+// file.
+func writeBacklinksFile(notebook *core.Notebook, note core.ContextualNote) (string, error) {
+	path := filepath.Join(notebook.Path, ".zk", "backlinks.md")
+	wrap := errors.Wrapperf("%s: failed to write the backlinks", path)
+
+	backlinks, err := notebook.FindNotes(core.NoteFindOpts{
+		LinkTo:  &core.LinkFilter{Paths: []string{note.Path}},
+		Sorters: []core.NoteSorter{{Field: core.NoteSortTitle, Ascending: true}},
+	})
+	if err != nil {
+		return "", wrap(err)
+	}
+	formatter, err := notebook.NewLinkFormatter()
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	title := note.Title
+	if title == "" {
+		title = note.Path
+	}
+	var out strings.Builder
+	fmt.Fprintf(&out, "# Backlinks of %s\n\n", title)
+	if len(backlinks) == 0 {
+		out.WriteString("No note links to this note.\n")
+	}
+	for _, backlink := range backlinks {
+		context, err := core.NewLinkFormatterContext(backlink.AsMinimalNote(), notebook.Path, filepath.Dir(path))
+		if err != nil {
+			return "", wrap(err)
+		}
+		if context.Title == "" {
+			context.Title = backlink.Path
+		}
+		link, err := formatter(context)
+		if err != nil {
+			return "", wrap(err)
+		}
+		fmt.Fprintf(&out, "* %s\n", link)
+		for _, snippet := range backlink.Snippets {
+			snippet = strings.NewReplacer("<zk:match>", "", "</zk:match>", "", "\n", " ").Replace(snippet)
+			fmt.Fprintf(&out, "  > %s\n", strings.TrimSpace(snippet))
+		}
+	}
+
+	return path, wrap(ioutil.WriteFile(path, []byte(out.String()), 0644))
+}
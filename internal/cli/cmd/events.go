@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// Events prints the changes of the notebook index as JSON lines.
+type Events struct {
+	Follow   bool          `short:f help:"Keep reindexing the notebook and print the new events as they happen."`
+	Interval time.Duration `default:"2s" placeholder:DURATION help:"Delay between two reindexings when following the events."`
+	Limit    int           `short:n placeholder:COUNT help:"Print only the given number of most recent events."`
+	Listen   string        `placeholder:ADDRESS help:"Stream the new events as Server-Sent Events over HTTP, at /events on the given address, e.g. localhost:9878."`
+}
+
+func (cmd *Events) Help() string {
+	return "The notes added, changed or removed and the links broken are recorded in `.zk/events.log` every time the notebook is indexed, by any zk command or the LSP server."
+}
+
+func (cmd *Events) Run(container *cli.Container) error {
+	if cmd.Interval <= 0 {
+		return fmt.Errorf("%v: the interval must be positive", cmd.Interval)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	events, position, err := notebook.IndexEvents(0)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Listen != "" {
+		go cmd.reindex(notebook, container.Origin)
+		return cmd.serve(notebook, position)
+	}
+
+	if cmd.Limit > 0 && len(events) > cmd.Limit {
+		events = events[len(events)-cmd.Limit:]
+	}
+	err = writeIndexEvents(os.Stdout, events)
+	if err != nil || !cmd.Follow {
+		return err
+	}
+
+	go cmd.reindex(notebook, container.Origin)
+	for {
+		time.Sleep(cmd.Interval)
+		events, position, err = notebook.IndexEvents(position)
+		if err == nil {
+			err = writeIndexEvents(os.Stdout, events)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// reindex indexes the notebook periodically, to record the changes made
+// outside of zk.
+func (cmd *Events) reindex(notebook *core.Notebook, origin core.AuditOrigin) {
+	for {
+		time.Sleep(cmd.Interval)
+		_, err := notebook.Index(origin, false)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// serve streams the events recorded after the given position to the
+// clients connecting to /events, until the server fails.
+//
+// The position of each event is sent as its ID, so that the clients resume
+// the stream with the Last-Event-ID header after reconnecting.
+func (cmd *Events) serve(notebook *core.Notebook, position int64) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		from := position
+		if id, err := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64); err == nil {
+			from = id
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			events, next, err := notebook.IndexEvents(from)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return
+			}
+			if len(events) > 0 {
+				if err := writeServerSentEvents(w, events, next); err != nil {
+					// The client disconnected.
+					return
+				}
+				flusher.Flush()
+			}
+			from = next
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(cmd.Interval):
+			}
+		}
+	})
+
+	return errors.Wrap(http.ListenAndServe(cmd.Listen, mux), "events server")
+}
+
+// writeIndexEvents prints the given events as JSON lines.
+func writeIndexEvents(out io.Writer, events []core.IndexEvent) error {
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return errors.Wrap(err, "failed to serialize index event")
+		}
+		fmt.Fprintln(out, string(line))
+	}
+	return nil
+}
+
+// writeServerSentEvents writes the given events in the text/event-stream
+// format. The last one has the ID next, which is the position of the
+// following events in the log.
+func writeServerSentEvents(out io.Writer, events []core.IndexEvent, next int64) error {
+	for i, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return errors.Wrap(err, "failed to serialize index event")
+		}
+		if i == len(events)-1 {
+			fmt.Fprintf(out, "id: %d\n", next)
+		}
+		_, err = fmt.Fprintf(out, "event: %s\ndata: %s\n\n", event.Kind, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
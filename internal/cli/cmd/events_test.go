@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestWriteServerSentEvents(t *testing.T) {
+	date := time.Date(2021, 10, 12, 15, 0, 0, 0, time.UTC)
+	out := &strings.Builder{}
+	err := writeServerSentEvents(out, []core.IndexEvent{
+		{Time: date, Kind: core.IndexEventNoteRemoved, Path: "old.md"},
+		{Time: date, Kind: core.IndexEventLinkBroken, Path: "index.md", Href: "old"},
+	}, 142)
+	assert.Nil(t, err)
+	assert.Equal(t, out.String(), `event: note-removed
+data: {"time":"2021-10-12T15:00:00Z","event":"note-removed","path":"old.md"}
+
+id: 142
+event: link-broken
+data: {"time":"2021-10-12T15:00:00Z","event":"link-broken","path":"index.md","href":"old"}
+
+`)
+}
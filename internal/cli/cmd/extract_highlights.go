@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/opt"
+)
+
+// ExtractHighlights creates a permanent note for each passage quoted or
+// highlighted in a literature note.
+type ExtractHighlights struct {
+	Note      string            `arg help:"Path to the literature note holding the highlights."`
+	Directory string            `arg optional default:"." help:"Directory in which to create the notes."`
+	Group     string            `short:g placeholder:NAME help:"Name of the config group the notes belong to."`
+	Template  string            `placeholder:PATH help:"Custom template used to render the notes."`
+	Extra     map[string]string `help:"Extra variables passed to the templates." mapsep:","`
+	DryRun    bool              `short:n help:"Print the highlights found, without creating the notes."`
+}
+
+func (cmd *ExtractHighlights) Help() string {
+	return "The blockquotes and ==highlighted== passages are extracted. In the note template, the passage is available as {{extra.highlight}} and a link to the literature note as {{extra.source}}, while {{content}} holds both."
+}
+
+func (cmd *ExtractHighlights) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	if cmd.DryRun {
+		source, highlights, err := notebook.FindHighlights(cmd.Note)
+		if err != nil {
+			return err
+		}
+		for _, highlight := range highlights {
+			fmt.Printf("%s:%d: %s\n", source.Path, highlight.Line, strings.ReplaceAll(highlight.Text, "\n", " "))
+		}
+		return nil
+	}
+
+	notes, err := notebook.ExtractHighlights(core.ExtractHighlightsOpts{
+		Path:      cmd.Note,
+		Directory: opt.NewNotEmptyString(cmd.Directory),
+		Group:     opt.NewNotEmptyString(cmd.Group),
+		Template:  opt.NewNotEmptyString(cmd.Template),
+		Extra:     cmd.Extra,
+		Date:      time.Now(),
+		Origin:    container.Origin,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, note := range notes {
+		fmt.Println(filepath.Join(notebook.Path, note.Path))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// FixLinks fixes all the dead links of the notebook at once, by retargeting
+// them to a similar note or creating stub notes.
+type FixLinks struct {
+	Retarget    bool    `help:"Replace the destination of the dead links with the most similar note, when it is similar enough."`
+	Threshold   float64 `placeholder:SIMILARITY help:"Minimum similarity of the notes replacing the dead links, from 0 to 1 (default: 0.6)."`
+	CreateStubs bool    `help:"Create a note at the destination of the dead links which are not retargeted."`
+	Template    string  `placeholder:PATH help:"Custom template used to render the stub notes."`
+	DryRun      bool    `short:n help:"Print the changes, without modifying any file."`
+	Quiet       bool    `short:q help:"Do not print the fixed links."`
+}
+
+func (cmd *FixLinks) Help() string {
+	return "Without --retarget nor --create-stubs, the dead links are only reported. When both are given, a dead link is retargeted if a note is similar enough to its destination, or resolved with a stub note otherwise. A single stub is created for the dead links sharing the same destination.\n\n" +
+		"Use zk verify-links --fix to choose the replacements interactively instead. Exits with an error status if any dead link remains."
+}
+
+func (cmd *FixLinks) Run(container *cli.Container) error {
+	if cmd.Threshold < 0 || cmd.Threshold > 1 {
+		return fmt.Errorf("%v: the similarity threshold must be between 0 and 1", cmd.Threshold)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	plan, err := notebook.PlanDeadLinkFixes(core.DeadLinksFixOpts{
+		Retarget:          cmd.Retarget,
+		RetargetThreshold: cmd.Threshold,
+		CreateStubs:       cmd.CreateStubs,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !cmd.DryRun {
+		_, err = notebook.CreateDeadLinkStubs(container.Origin, plan.Stubs, opt.NewNotEmptyString(cmd.Template))
+		if err != nil {
+			return err
+		}
+	}
+	retargeted, err := notebook.FixDeadLinks(container.Origin, plan.Retargets, cmd.DryRun)
+	if err != nil {
+		return err
+	}
+
+	if !cmd.Quiet {
+		for _, link := range retargeted {
+			fmt.Println(link)
+		}
+		for _, stub := range plan.Stubs {
+			sources := []string{}
+			for _, link := range stub.Links {
+				sources = append(sources, fmt.Sprintf("%s:%d", link.Path, link.Line))
+			}
+			fmt.Printf("%s: new stub for %s\n", stub.Path, strings.Join(sources, ", "))
+		}
+	}
+	for _, link := range plan.Unfixed {
+		fmt.Printf("%s:%d: dead link to %s\n", link.Path, link.Line, link.Href)
+	}
+
+	if cmd.Retarget || cmd.CreateStubs {
+		retargetVerb, stubVerb := "Retargeted", "created"
+		if cmd.DryRun {
+			retargetVerb, stubVerb = "Would retarget", "create"
+		}
+		fmt.Fprintf(os.Stderr, "\n%s %d %s and %s %d %s\n",
+			retargetVerb, len(retargeted), strutil.Pluralize("link", len(retargeted)),
+			stubVerb, len(plan.Stubs), strutil.Pluralize("stub", len(plan.Stubs)),
+		)
+	}
+
+	return reportDeadLinks(len(plan.Unfixed))
+}
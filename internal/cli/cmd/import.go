@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/adapter/importer"
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Import converts the notes of another app into the current notebook.
+type Import struct {
+	Source     string `arg type:"path" help:"Obsidian vault, or Notion Markdown export (directory or ZIP archive)."`
+	Directory  string `arg optional default:"." help:"Directory in which to import the notes."`
+	From       string `enum:"auto,obsidian,notion" default:"auto" placeholder:"APP" help:"App the notes are exported from: auto, obsidian or notion."`
+	Duplicates string `enum:"skip,merge,keep" default:"skip" placeholder:"POLICY" help:"What to do with notes identical or similar to existing ones: skip, merge or keep."`
+}
+
+func (cmd *Import) Help() string {
+	return "The notes are created with the notebook templates and filenames, with their original title and content. Their links are rewritten with the configured link format. Notes duplicating existing notes are skipped by default, so importing the same export twice is safe. With --duplicates merge, the new paragraphs of similar notes are appended to the existing ones. The frontmatter values of an Obsidian note are available as {{extra.<key>}} in the note template."
+}
+
+func (cmd *Import) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	from := cmd.From
+	if from == "auto" {
+		from, err = detectImportSource(cmd.Source)
+		if err != nil {
+			return err
+		}
+	}
+
+	var source *core.ImportSource
+	switch from {
+	case "obsidian":
+		source, err = importer.ReadObsidianVault(cmd.Source)
+	case "notion":
+		source, err = importer.ReadNotionExport(cmd.Source)
+	}
+	if err != nil {
+		return err
+	}
+
+	report, err := notebook.Import(*source, core.ImportOpts{
+		Directory:  opt.NewNotEmptyString(cmd.Directory),
+		Duplicates: core.ImportDuplicates(cmd.Duplicates),
+		Origin:     container.Origin,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, note := range report.Notes {
+		fmt.Println(filepath.Join(notebook.Path, note.Path))
+	}
+	for _, link := range report.UnresolvedLinks {
+		fmt.Fprintf(os.Stderr, "%s: unresolved link to %s\n", link.SourcePath, link.Href)
+	}
+	for _, duplicate := range report.Duplicates {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", duplicate.SourcePath, formatImportDuplicate(duplicate))
+	}
+	fmt.Fprintf(os.Stderr, "\nImported %d %s and %d %s",
+		len(report.Notes), strutil.Pluralize("note", len(report.Notes)),
+		len(report.Assets), strutil.Pluralize("asset", len(report.Assets)),
+	)
+	if count := len(report.Duplicates); count > 0 {
+		fmt.Fprintf(os.Stderr, ", found %d %s", count, strutil.Pluralize("duplicate", count))
+	}
+	fmt.Fprintln(os.Stderr)
+	return nil
+}
+
+// formatImportDuplicate describes what was done with a duplicate note.
+func formatImportDuplicate(duplicate core.ImportDuplicate) string {
+	var action string
+	switch duplicate.Action {
+	case core.ImportDuplicatesSkip:
+		action = "skipped, duplicate of"
+	case core.ImportDuplicatesMerge:
+		action = "merged into"
+	default:
+		action = "imported, duplicate of"
+	}
+	res := action + " " + duplicate.Path
+	if duplicate.Similarity < 1 {
+		res += fmt.Sprintf(" (%d%% similar)", int(duplicate.Similarity*100))
+	}
+	return res
+}
+
+// detectImportSource guesses from which app the notes at path were exported.
+func detectImportSource(path string) (string, error) {
+	if strings.ToLower(filepath.Ext(path)) == ".zip" {
+		return "notion", nil
+	}
+	info, err := os.Stat(filepath.Join(path, ".obsidian"))
+	if err == nil && info.IsDir() {
+		return "obsidian", nil
+	}
+	return "", errors.New(path + ": can't detect the kind of export, use --from")
+}
@@ -2,18 +2,23 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/strings"
 )
 
 // Index indexes the content of all the notes in the notebook.
 type Index struct {
-	Force bool `short:"f" help:"Force indexing all the notes."`
-	Quiet bool `short:"q" help:"Do not print statistics nor progress."`
+	Force  bool `short:"f" help:"Force indexing all the notes."`
+	Relink bool `help:"Update the links to the notes renamed outside of zk without confirmation."`
+	Quiet  bool `short:"q" help:"Do not print statistics nor progress."`
 }
 
 func (cmd *Index) Help() string {
-	return "You usually do not need to run `zk index` manually, as notes are indexed automatically when needed."
+	return "You usually do not need to run `zk index` manually, as notes are indexed automatically when needed.\n\n" +
+		"When a note was renamed or moved outside of zk, you are offered to update the links to it. Set `relink-renamed = true` in the [index] config section to update them automatically."
 }
 
 func (cmd *Index) Run(container *cli.Container) error {
@@ -22,7 +27,12 @@ func (cmd *Index) Run(container *cli.Container) error {
 		return err
 	}
 
-	stats, err := notebook.Index(cmd.Force)
+	var stats core.NoteIndexingStats
+	if cmd.Quiet {
+		stats, err = notebook.Index(container.Origin, cmd.Force)
+	} else {
+		stats, err = container.IndexNotebook(notebook, cmd.Force)
+	}
 	if err != nil {
 		return err
 	}
@@ -31,5 +41,78 @@ func (cmd *Index) Run(container *cli.Container) error {
 		fmt.Println(stats)
 	}
 
+	if len(stats.Renamed) == 0 {
+		return nil
+	}
+	if notebook.Config.Index.RelinkRenamed || cmd.Relink {
+		moves := stats.Renamed
+		if !notebook.Config.Index.RelinkRenamed {
+			moves, err = notebook.RelinkRenamedNotes(container.Origin, stats.Renamed, false)
+			if err != nil {
+				return err
+			}
+		}
+		if !cmd.Quiet {
+			printRelinkedNotes(moves, "Updated")
+		}
+		return nil
+	}
+	if cmd.Quiet {
+		return nil
+	}
+
+	moves, err := notebook.RelinkRenamedNotes(container.Origin, stats.Renamed, true)
+	if err != nil {
+		return err
+	}
+	linkCount, noteCount := printRelinkedNotes(moves, "Would update")
+	if linkCount == 0 {
+		return nil
+	}
+
+	confirmed, skipped := container.Terminal.Confirm(
+		fmt.Sprintf("Update the %d %s to the renamed notes?", linkCount, strings.Pluralize("link", linkCount)),
+		false,
+	)
+	if skipped {
+		fmt.Fprintln(os.Stderr, "The links were not updated, use --relink or set `relink-renamed = true` in the [index] config section to update them when the renames are detected.")
+		return nil
+	} else if !confirmed {
+		return nil
+	}
+
+	_, err = notebook.RelinkRenamedNotes(container.Origin, stats.Renamed, false)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Updated %d %s in %d %s\n",
+		linkCount, strings.Pluralize("link", linkCount),
+		noteCount, strings.Pluralize("note", noteCount),
+	)
 	return nil
 }
+
+// printRelinkedNotes prints the notes renamed outside of zk with the links
+// rewritten to follow them, and returns their count.
+func printRelinkedNotes(moves []core.NoteMove, verb string) (linkCount int, noteCount int) {
+	for _, move := range moves {
+		linkCount += len(move.RewrittenLinks)
+		noteCount += len(move.NotePaths())
+	}
+
+	fmt.Fprintln(os.Stderr)
+	for _, move := range moves {
+		fmt.Fprintf(os.Stderr, "%s was renamed to %s outside of zk\n", move.SourcePath, move.TargetPath)
+		for _, link := range move.RewrittenLinks {
+			fmt.Fprintf(os.Stderr, "  %s\n", link)
+		}
+	}
+	if linkCount > 0 {
+		fmt.Fprintf(os.Stderr, "\n%s %d %s in %d %s\n",
+			verb,
+			linkCount, strings.Pluralize("link", linkCount),
+			noteCount, strings.Pluralize("note", noteCount),
+		)
+	}
+	return
+}
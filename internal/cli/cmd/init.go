@@ -33,7 +33,7 @@ func (cmd *Init) Run(container *cli.Container) error {
 	}
 
 	force := false
-	_, err = notebook.Index(force)
+	_, err = container.IndexNotebook(notebook, force)
 	if err != nil {
 		return err
 	}
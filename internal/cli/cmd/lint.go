@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/adapter/web"
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Lint checks the whole notebook for problems.
+type Lint struct {
+	Format    string `group:format short:f placeholder:FORMAT help:"Format of the reported problems, among: human, json, sarif."`
+	NoPager   bool   `group:format short:P help:"Do not pipe output into a pager."`
+	Quiet     bool   `group:format short:q help:"Do not print the total number of problems found."`
+	Fix       bool   `help:"Replace the dead external links with their archived version, before linting."`
+	CheckURLs bool   `name:check-urls help:"Check whether the external links are still reachable with HTTP requests, before linting."`
+}
+
+func (cmd *Lint) Help() string {
+	return "Reports dead links and URLs, duplicate titles and IDs, missing titles, malformed frontmatters and orphan assets. Exits with an error status if any error-level problem is found, which is useful in CI."
+}
+
+func (cmd *Lint) Run(container *cli.Container) error {
+	if cmd.Format != "" && cmd.Format != "human" && cmd.Format != "json" && cmd.Format != "sarif" {
+		return fmt.Errorf("%s: unknown lint format, try human, json or sarif", cmd.Format)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	if cmd.CheckURLs {
+		checked, err := notebook.CheckURLs(web.NewURLChecker(10 * time.Second))
+		if err != nil {
+			return err
+		}
+		if !cmd.Quiet {
+			fmt.Fprintf(os.Stderr, "Checked %d external %s\n", len(checked), strings.Pluralize("link", len(checked)))
+		}
+	}
+
+	if cmd.Fix {
+		replacements, err := notebook.ReplaceDeadURLs(container.Origin, false)
+		if err != nil {
+			return err
+		}
+		for _, replacement := range replacements {
+			if !cmd.Quiet {
+				printReplacementDiff(os.Stderr, container.Terminal, replacement)
+			}
+		}
+		if len(replacements) > 0 {
+			fmt.Fprintf(os.Stderr, "Replaced the dead URLs in %d %s\n",
+				len(replacements), strings.Pluralize("note", len(replacements)),
+			)
+		}
+	}
+
+	issues, err := notebook.Lint()
+	if err != nil {
+		return err
+	}
+
+	switch cmd.Format {
+	case "json":
+		err = printJSON(issues)
+	case "sarif":
+		err = printJSON(newSarifLog(issues))
+	default:
+		if len(issues) > 0 {
+			err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
+				for _, issue := range issues {
+					fmt.Fprintln(out, issue)
+				}
+				return nil
+			})
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	count := len(issues)
+	if !cmd.Quiet {
+		fmt.Fprintf(os.Stderr, "\nFound %d %s\n", count, strings.Pluralize("problem", count))
+	}
+
+	for _, issue := range issues {
+		if issue.Severity == core.LintSeverityError {
+			return errors.New("the notebook has errors")
+		}
+	}
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize the problems")
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// sarifLog is a minimal Static Analysis Results Interchange Format (SARIF)
+// report, to integrate with code scanning tools.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+func newSarifLog(issues []core.LintIssue) sarifLog {
+	rules := []sarifRule{}
+	for _, rule := range core.LintRules {
+		rules = append(rules, sarifRule{
+			ID:               string(rule),
+			ShortDescription: sarifMessage{Text: rule.Description()},
+		})
+	}
+
+	results := []sarifResult{}
+	for _, issue := range issues {
+		location := sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: issue.Path},
+		}
+		if issue.Line > 0 {
+			location.Region = &sarifRegion{StartLine: issue.Line}
+		}
+
+		results = append(results, sarifResult{
+			RuleID:    string(issue.Rule),
+			Level:     string(issue.Severity),
+			Message:   sarifMessage{Text: issue.Message},
+			Locations: []sarifLocation{{PhysicalLocation: location}},
+		})
+	}
+
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{
+				Driver: sarifDriver{
+					Name:           "zk",
+					InformationURI: "https://github.com/mickael-menu/zk",
+					Rules:          rules,
+				},
+			},
+			Results: results,
+		}},
+	}
+}
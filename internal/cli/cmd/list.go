@@ -1,25 +1,33 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	gostrings "strings"
 
+	"github.com/fatih/color"
 	"github.com/mickael-menu/zk/internal/adapter/fzf"
 	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
 	"github.com/mickael-menu/zk/internal/util/errors"
 	"github.com/mickael-menu/zk/internal/util/strings"
 )
 
 // List displays notes matching a set of criteria.
 type List struct {
-	Format     string `group:format short:f placeholder:TEMPLATE   help:"Pretty print the list using a custom template or one of the predefined formats: oneline, short, medium, long, full, json, jsonl."`
-	Header     string `group:format                                help:"Arbitrary text printed at the start of the list."`
-	Footer     string `group:format default:\n                     help:"Arbitrary text printed at the end of the list."`
-	Delimiter  string "group:format short:d default:\n             help:\"Print notes delimited by the given separator.\""
-	Delimiter0 bool   "group:format short:0 name:delimiter0        help:\"Print notes delimited by ASCII NUL characters. This is useful when used in conjunction with `xargs -0`.\""
-	NoPager    bool   `group:format short:P help:"Do not pipe output into a pager."`
-	Quiet      bool   `group:format short:q help:"Do not print the total number of notes found."`
+	Format     string   `group:format short:f placeholder:TEMPLATE   help:"Pretty print the list using a custom template or one of the predefined formats: oneline, short, medium, long, full, tree, json, jsonl, csv, alfred-json, rofi, feed."`
+	Fields     []string `group:format placeholder:FIELDS help:"Print only the given fields with the csv, json and jsonl formats, e.g. title,path,tags,created,word-count."`
+	Header     string   `group:format                                help:"Arbitrary text printed at the start of the list."`
+	Footer     string   `group:format default:\n                     help:"Arbitrary text printed at the end of the list."`
+	Delimiter  string   "group:format short:d default:\n             help:\"Print notes delimited by the given separator.\""
+	Delimiter0 bool     "group:format short:0 name:delimiter0        help:\"Print notes delimited by ASCII NUL characters. This is useful when used in conjunction with `xargs -0`.\""
+	NoPager    bool     `group:format short:P help:"Do not pipe output into a pager."`
+	Quiet      bool     `group:format short:q help:"Do not print the total number of notes found."`
+	Output     string   `group:format placeholder:PATH help:"Write the list into the given file. If it contains a region delimited by <!-- zk:begin --> and <!-- zk:end -->, only this region is replaced."`
+	Append     bool     `group:format help:"Append the list at the end of the --output file, if it doesn't contain a managed region."`
 	cli.Filtering
 }
 
@@ -43,7 +51,7 @@ func (cmd *List) Run(container *cli.Container) error {
 		cmd.Footer = "\x00"
 	}
 
-	if cmd.Format == "json" || cmd.Format == "jsonl" {
+	if cmd.Format == "json" || cmd.Format == "jsonl" || cmd.Format == "alfred-json" {
 		if cmd.Header != "" {
 			return errors.New("--header can't be used with JSON format")
 		}
@@ -66,7 +74,40 @@ func (cmd *List) Run(container *cli.Container) error {
 			// > present.
 			// > https://jsonlines.org/
 			cmd.Footer = "\n"
+
+		case "alfred-json":
+			// > https://www.alfredapp.com/help/workflows/inputs/script-filter/json/
+			cmd.Delimiter = ","
+			cmd.Header = `{"items":[`
+			cmd.Footer = "]}\n"
+		}
+	}
+
+	if (cmd.Format == "feed" || cmd.Format == "csv") && (cmd.Header != "" || cmd.Footer != "\n" || cmd.Delimiter != "\n") {
+		return fmt.Errorf("--header, --footer and --delimiter can't be used with the %s format", cmd.Format)
+	}
+
+	if len(cmd.Fields) > 0 {
+		if cmd.Format != "csv" && cmd.Format != "json" && cmd.Format != "jsonl" {
+			return errors.New("--fields can only be used with the csv, json and jsonl formats")
+		}
+		if err := validateNoteFields(cmd.Fields); err != nil {
+			return err
+		}
+	}
+	if cmd.Format == "csv" {
+		cmd.Header = gostrings.Join(cmd.csvFields(), ",") + "\n"
+	}
+
+	if cmd.Append && cmd.Output == "" {
+		return errors.New("--append requires an --output file")
+	}
+	if cmd.Output != "" {
+		if cmd.Interactive {
+			return errors.New("--interactive can't be used with --output")
 		}
+		// Terminal styles would garble the output file.
+		color.NoColor = true
 	}
 
 	notebook, err := container.CurrentNotebook()
@@ -103,12 +144,36 @@ func (cmd *List) Run(container *cli.Container) error {
 		return err
 	}
 
-	count := len(notes)
-	if count > 0 {
-		err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
-			if cmd.Header != "" {
-				fmt.Fprint(out, cmd.Header)
+	var trees []core.NoteTree
+	if cmd.Format == "tree" {
+		hierarchy, err := notebook.NoteHierarchy()
+		if err != nil {
+			return err
+		}
+		paths := make([]string, 0, len(notes))
+		for _, note := range notes {
+			paths = append(paths, note.Path)
+		}
+		trees = hierarchy.Tree(paths)
+	}
+
+	write := func(out io.Writer) error {
+		if cmd.Header != "" {
+			fmt.Fprint(out, cmd.Header)
+		}
+		if trees != nil {
+			notesByPath := map[string]core.ContextualNote{}
+			for _, note := range notes {
+				notesByPath[note.Path] = note
+			}
+			lines, err := noteTreeLines(trees, func(path string) (string, error) {
+				return format(notesByPath[path])
+			})
+			if err != nil {
+				return err
 			}
+			fmt.Fprint(out, gostrings.Join(lines, cmd.Delimiter))
+		} else {
 			for i, note := range notes {
 				if i > 0 {
 					fmt.Fprint(out, cmd.Delimiter)
@@ -120,27 +185,174 @@ func (cmd *List) Run(container *cli.Container) error {
 				}
 				fmt.Fprint(out, ft)
 			}
-			if cmd.Footer != "" {
-				fmt.Fprint(out, cmd.Footer)
-			}
+		}
+		if cmd.Footer != "" {
+			fmt.Fprint(out, cmd.Footer)
+		}
 
-			return nil
+		return nil
+	}
+
+	if cmd.Format == "feed" {
+		feed, err := notebook.NoteFeed(notes, core.NoteFeedOpts{
+			BaseURL: notebook.Config.LSP.Links.PublishedURL.String(),
 		})
+		if err != nil {
+			return err
+		}
+		write = func(out io.Writer) error {
+			_, err := out.Write(feed)
+			return err
+		}
+	}
+
+	count := len(notes)
+	// A feed is valid even without any entry.
+	hasOutput := count > 0 || cmd.Format == "feed"
+	if cmd.Output != "" {
+		var out gostrings.Builder
+		if hasOutput {
+			err = write(&out)
+		}
+		if err == nil {
+			err = cmd.writeOutput(notebook, out.String())
+		}
+	} else if hasOutput {
+		err = container.Paginate(cmd.NoPager, write)
 	}
 
 	if err == nil && !cmd.Quiet {
 		fmt.Fprintf(os.Stderr, "\nFound %d %s\n", count, strings.Pluralize("note", count))
 	}
+	// Writing an empty list into a file is not a failure.
+	if err == nil && !hasOutput && cmd.Output == "" {
+		err = cli.ErrNoResults
+	}
 
 	return err
 }
 
+// noteTreeLines formats the notes as a tree, one line per note below its
+// parent.
+func noteTreeLines(trees []core.NoteTree, format func(path string) (string, error)) ([]string, error) {
+	var nodes func(trees []core.NoteTree) ([]treeNode, error)
+	nodes = func(trees []core.NoteTree) ([]treeNode, error) {
+		res := []treeNode{}
+		for _, tree := range trees {
+			label, err := format(tree.Path)
+			if err != nil {
+				return nil, err
+			}
+			children, err := nodes(tree.Children)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, treeNode{label: label, children: children})
+		}
+		return res, nil
+	}
+
+	roots, err := nodes(trees)
+	if err != nil {
+		return nil, err
+	}
+	return treeLines(roots), nil
+}
+
+// treeNode is a formatted node of a tree printed with treeLines.
+type treeNode struct {
+	label    string
+	children []treeNode
+}
+
+// treeLines draws the given trees with box-drawing characters, one line per
+// node below its parent.
+func treeLines(roots []treeNode) []string {
+	lines := []string{}
+
+	var visit func(nodes []treeNode, indent string)
+	visit = func(nodes []treeNode, indent string) {
+		for i, node := range nodes {
+			branch, childIndent := "├── ", "│   "
+			if i == len(nodes)-1 {
+				branch, childIndent = "└── ", "    "
+			}
+			lines = append(lines, indent+branch+node.label)
+			visit(node.children, indent+childIndent)
+		}
+	}
+
+	// Roots are not prefixed with a branch.
+	for _, root := range roots {
+		lines = append(lines, root.label)
+		visit(root.children, "")
+	}
+
+	return lines
+}
+
+// writeOutput saves the formatted list into the --output file. The file is
+// written through the notebook, to be encrypted when it is a note of an
+// encrypted group.
+func (cmd *List) writeOutput(notebook *core.Notebook, list string) error {
+	wrap := errors.Wrapperf("%s: failed to write the list", cmd.Output)
+
+	path, err := filepath.Abs(cmd.Output)
+	if err != nil {
+		return wrap(err)
+	}
+	content, err := notebook.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return wrap(err)
+	}
+
+	if replaced, ok := replaceManagedRegion(string(content), list); ok {
+		list = replaced
+	} else if cmd.Append {
+		list = string(content) + list
+	}
+
+	return wrap(notebook.WriteFile(path, []byte(list)))
+}
+
+const (
+	managedRegionBegin = "<!-- zk:begin -->"
+	managedRegionEnd   = "<!-- zk:end -->"
+)
+
+// replaceManagedRegion replaces the text between the zk:begin and zk:end
+// markers found in content. Returns false if there is no such region.
+func replaceManagedRegion(content string, text string) (string, bool) {
+	start := gostrings.Index(content, managedRegionBegin)
+	if start < 0 {
+		return content, false
+	}
+	start += len(managedRegionBegin)
+	end := gostrings.Index(content[start:], managedRegionEnd)
+	if end < 0 {
+		return content, false
+	}
+	end += start
+
+	if !gostrings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return content[:start] + "\n" + text + content[end:], true
+}
+
 func (cmd *List) noteTemplate() string {
 	format := cmd.Format
 	if format == "" {
 		format = "short"
 	}
 
+	switch {
+	case format == "csv":
+		return fieldsNoteTemplate(format, cmd.csvFields())
+	case len(cmd.Fields) > 0 && (format == "json" || format == "jsonl"):
+		return fieldsNoteTemplate(format, cmd.Fields)
+	}
+
 	templ, ok := defaultNoteFormats[format]
 	if !ok {
 		templ = strings.ExpandWhitespaceLiterals(format)
@@ -149,14 +361,81 @@ func (cmd *List) noteTemplate() string {
 	return templ
 }
 
+// csvFields returns the fields printed with the csv format.
+func (cmd *List) csvFields() []string {
+	if len(cmd.Fields) > 0 {
+		return cmd.Fields
+	}
+	return []string{"path", "title", "tags", "created", "modified", "word-count"}
+}
+
+// noteFields are the fields which can be selected with --fields, in addition
+// to the metadata.<key> ones.
+var noteFields = []string{
+	"filename", "filename-stem", "path", "abs-path", "title", "link", "lead",
+	"body", "snippets", "score", "distance", "raw-content", "word-count",
+	"reading-time", "tags", "metadata", "created", "modified", "checksum",
+	"git-sha",
+}
+
+func validateNoteFields(fields []string) error {
+	for _, field := range fields {
+		if !strings.InList(noteFields, field) && !(gostrings.HasPrefix(field, "metadata.") && len(field) > len("metadata.")) {
+			return fmt.Errorf("%s: unknown field, try one of: %s or metadata.<key>", field, gostrings.Join(noteFields, ", "))
+		}
+	}
+	return nil
+}
+
+// fieldsNoteTemplate generates the template printing only the given fields
+// of a note, with the csv, json or jsonl format.
+//
+// The JSON keys are the camel case version of the fields, like with the
+// default JSON formats.
+func fieldsNoteTemplate(format string, fields []string) string {
+	values := []string{}
+	for _, field := range fields {
+		if format == "csv" {
+			values = append(values, "{{csv "+field+"}}")
+			continue
+		}
+		key := field
+		if !gostrings.HasPrefix(field, "metadata.") {
+			parts := gostrings.Split(field, "-")
+			for i := 1; i < len(parts); i++ {
+				parts[i] = gostrings.Title(parts[i])
+			}
+			key = gostrings.Join(parts, "")
+		}
+		jsonKey, _ := json.Marshal(key)
+		values = append(values, string(jsonKey)+":{{json "+field+"}}")
+	}
+
+	if format == "csv" {
+		return gostrings.Join(values, ",")
+	}
+	// The space prevents a closing }}} from being parsed as a triple-stash.
+	return "{" + gostrings.Join(values, ",") + " }"
+}
+
 var defaultNoteFormats = map[string]string{
 	"json":  `{{json .}}`,
 	"jsonl": `{{json .}}`,
 	"path":  `{{path}}`,
 	"link":  `{{link}}`,
 
+	// Item of an Alfred Script Filter, opening the note file when selected.
+	"alfred-json": `{"uid":{{json abs-path}},"type":"file","title":{{#if title}}{{json title}}{{else}}{{json filename}}{{/if}},"subtitle":{{json path}},"arg":{{json abs-path}},"icon":{"type":"fileicon","path":{{json abs-path}} }}`,
+
+	// Row of a rofi script or dmenu, with the absolute path in ROFI_INFO and
+	// the path and tags searchable as hidden metadata.
+	"rofi": "{{#if title}}{{title}}{{else}}{{filename}}{{/if}}\x00info\x1f{{abs-path}}\x1fmeta\x1f{{path}} {{join tags \" \"}}\x1ficon\x1ftext-markdown",
+
 	"oneline": `{{style "title" title}} {{style "path" path}} ({{date created "elapsed"}})`,
 
+	// Line of a note in the tree of the Folgezettel or `parent` hierarchy.
+	"tree": `{{style "title" title}} {{style "path" path}}`,
+
 	"short": `{{style "title" title}} {{style "path" path}} ({{date created "elapsed"}})
 
 {{list snippets}}`,
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/mickael-menu/zk/internal/core"
+
 	"github.com/mickael-menu/zk/internal/util/test/assert"
 )
 
@@ -26,6 +29,7 @@ func TestListFormatPredefined(t *testing.T) {
 	test("link", `{{link}}`)
 
 	test("oneline", `{{style "title" title}} {{style "path" path}} ({{date created "elapsed"}})`)
+	test("tree", `{{style "title" title}} {{style "path" path}}`)
 
 	test("short", `{{style "title" title}} {{style "path" path}} ({{date created "elapsed"}})
 
@@ -65,3 +69,66 @@ func TestListFormatCustom(t *testing.T) {
 	// \n and \t in custom formats are expanded.
 	test(`{{title}}\t{{path}}\n{{snippet}}`, "{{title}}\t{{path}}\n{{snippet}}")
 }
+
+func TestListReplaceManagedRegion(t *testing.T) {
+	test := func(content, text, expected string, expectedOK bool) {
+		actual, ok := replaceManagedRegion(content, text)
+		assert.Equal(t, ok, expectedOK)
+		assert.Equal(t, actual, expected)
+	}
+
+	// No region
+	test("", "list", "", false)
+	test("# Index\n", "list", "# Index\n", false)
+	// Unclosed region
+	test("# Index\n<!-- zk:begin -->\nold\n", "list", "# Index\n<!-- zk:begin -->\nold\n", false)
+	// Region is replaced
+	test("# Index\n<!-- zk:begin -->\nold\n<!-- zk:end -->\nFooter\n", "list\n", "# Index\n<!-- zk:begin -->\nlist\n<!-- zk:end -->\nFooter\n", true)
+	test("<!-- zk:begin --><!-- zk:end -->", "list", "<!-- zk:begin -->\nlist\n<!-- zk:end -->", true)
+}
+
+func TestNoteTreeLines(t *testing.T) {
+	lines, err := noteTreeLines([]core.NoteTree{
+		{Path: "1.md", Children: []core.NoteTree{
+			{Path: "1a.md", Children: []core.NoteTree{
+				{Path: "1a1.md"},
+				{Path: "1a2.md"},
+			}},
+			{Path: "1b.md", Children: []core.NoteTree{
+				{Path: "1b1.md"},
+			}},
+		}},
+		{Path: "2.md"},
+	}, func(path string) (string, error) {
+		return strings.TrimSuffix(path, ".md"), nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, lines, []string{
+		"1",
+		"├── 1a",
+		"│   ├── 1a1",
+		"│   └── 1a2",
+		"└── 1b",
+		"    └── 1b1",
+		"2",
+	})
+}
+
+func TestListFormatFields(t *testing.T) {
+	test := func(format string, fields []string, expectedTemplate string) {
+		cmd := List{Format: format, Fields: fields}
+		assert.Equal(t, cmd.noteTemplate(), expectedTemplate)
+	}
+
+	test("csv", nil, `{{csv path}},{{csv title}},{{csv tags}},{{csv created}},{{csv modified}},{{csv word-count}}`)
+	test("csv", []string{"title", "metadata.author"}, `{{csv title}},{{csv metadata.author}}`)
+	test("json", []string{"title", "word-count", "git-sha", "metadata.due-date"}, `{"title":{{json title}},"wordCount":{{json word-count}},"gitSha":{{json git-sha}},"metadata.due-date":{{json metadata.due-date}} }`)
+	test("jsonl", []string{"path"}, `{"path":{{json path}} }`)
+}
+
+func TestListValidateFields(t *testing.T) {
+	assert.Nil(t, validateNoteFields([]string{"title", "abs-path", "metadata.author"}))
+	assert.Err(t, validateNoteFields([]string{"title", "unknown"}), "unknown: unknown field")
+	assert.Err(t, validateNoteFields([]string{"metadata."}), "metadata.: unknown field")
+}
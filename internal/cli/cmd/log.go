@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Log prints the mutating operations recorded in the notebook audit log.
+type Log struct {
+	Format    string   `group:format short:f placeholder:FORMAT help:"Format of the entries, among: oneline, jsonl."`
+	Limit     int      `group:filter short:n placeholder:COUNT  help:"Show only the given number of most recent entries."`
+	Operation []string `group:filter short:o placeholder:OP     help:"Show only the given operations, among: create, move, delete, rename-tag."`
+	Path      string   `group:filter arg optional placeholder:PATH help:"Show only the entries affecting the given path."`
+	NoPager   bool     `group:format short:P help:"Do not pipe output into a pager."`
+	Quiet     bool     `group:format short:q help:"Do not print the total number of operations found."`
+}
+
+func (cmd *Log) Help() string {
+	return "Operations performed by zk on the notebook are recorded in `.zk/audit.log`, with the interface and command which initiated them."
+}
+
+func (cmd *Log) Run(container *cli.Container) error {
+	if cmd.Format != "" && cmd.Format != "oneline" && cmd.Format != "jsonl" {
+		return fmt.Errorf("%s: unknown log format, try oneline or jsonl", cmd.Format)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	path := ""
+	if cmd.Path != "" {
+		path, err = notebook.RelPath(cmd.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	entries, err := notebook.AuditEntries()
+	if err != nil {
+		return err
+	}
+
+	entries = cmd.filter(entries, path)
+
+	count := len(entries)
+	if count > 0 {
+		err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
+			// Most recent entries first.
+			for i := count - 1; i >= 0; i-- {
+				entry := entries[i]
+				if cmd.Format == "jsonl" {
+					line, err := json.Marshal(entry)
+					if err != nil {
+						return errors.Wrap(err, "failed to serialize audit entry")
+					}
+					fmt.Fprintln(out, string(line))
+				} else {
+					fmt.Fprintln(out, entry)
+				}
+			}
+			return nil
+		})
+	}
+
+	if err == nil && !cmd.Quiet {
+		fmt.Fprintf(os.Stderr, "\nFound %d %s\n", count, strings.Pluralize("operation", count))
+	}
+
+	return err
+}
+
+// filter keeps only the entries matching the command flags.
+func (cmd *Log) filter(entries []core.AuditEntry, path string) []core.AuditEntry {
+	res := []core.AuditEntry{}
+	for _, entry := range entries {
+		if len(cmd.Operation) > 0 && !strings.InList(cmd.Operation, string(entry.Operation)) {
+			continue
+		}
+		if path != "" && !strings.InList(entry.Paths, path) {
+			continue
+		}
+		res = append(res, entry)
+	}
+
+	if cmd.Limit > 0 && len(res) > cmd.Limit {
+		res = res[len(res)-cmd.Limit:]
+	}
+	return res
+}
@@ -1,26 +1,38 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/mickael-menu/zk/internal/adapter/lsp"
+	"github.com/mickael-menu/zk/internal/adapter/web"
 	"github.com/mickael-menu/zk/internal/cli"
 	"github.com/mickael-menu/zk/internal/util/opt"
 )
 
 // LSP starts a server implementing the Language Server Protocol.
 type LSP struct {
-	Log string `hidden type:path placeholder:PATH help:"Absolute path to the log file"`
+	Log      string `hidden type:path placeholder:PATH help:"Absolute path to the log file"`
+	Listen   string `placeholder:ADDRESS help:"Serve several editors from a single process, on the given address: tcp:PORT, tcp:HOST:PORT or unix:PATH"`
+	Metrics  string `placeholder:ADDRESS help:"Serve the metrics of the server in the Prometheus text format over HTTP, at /metrics on the given address, e.g. localhost:9878."`
+	ReadOnly bool   `help:"Prevent the editors from modifying the notebooks, e.g. to serve a shared checkout."`
 }
 
 func (cmd *LSP) Run(container *cli.Container) error {
-	server := lsp.NewServer(lsp.ServerOpts{
-		Name:           "zk",
-		Version:        container.Version,
-		Logger:         container.Logger,
-		LogFile:        opt.NewNotEmptyString(cmd.Log),
-		Notebooks:      container.Notebooks,
-		TemplateLoader: container.TemplateLoader,
-		FS:             container.FS,
-	})
+	opts := lsp.ServerOpts{
+		Name:               "zk",
+		Version:            container.Version,
+		Logger:             container.Logger,
+		LogFile:            opt.NewNotEmptyString(cmd.Log),
+		Notebooks:          container.Notebooks,
+		TemplateLoader:     container.TemplateLoader,
+		FS:                 container.FS,
+		URLMetadataFetcher: web.NewURLMetadataFetcher(10 * time.Second),
+		MetricsAddress:     cmd.Metrics,
+		ReadOnly:           cmd.ReadOnly,
+	}
 
-	return server.Run()
+	if cmd.Listen != "" {
+		return lsp.Listen(cmd.Listen, opts)
+	}
+	return lsp.NewServer(opts).Run()
 }
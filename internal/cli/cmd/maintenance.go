@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/adapter/web"
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// Maintenance performs the periodic housekeeping tasks of the notebook, such
+// as purging the trash.
+type Maintenance struct {
+	Task   []string `short:t placeholder:TASK help:"Housekeeping task to perform instead of the configured ones, among: index, trash, urls, archive, manifest, vacuum."`
+	DryRun bool     `short:n help:"Report what would be changed, without modifying the notebook."`
+	Format string   `group:format short:f placeholder:FORMAT help:"Format of the report, among: human, json."`
+}
+
+func (cmd *Maintenance) Help() string {
+	return "The tasks listed in the [maintenance] config section are performed, by default: index, trash and vacuum. Schedule this command, e.g. with cron, to keep the notebook tidy. It exits with an error if any task failed."
+}
+
+func (cmd *Maintenance) Run(container *cli.Container) error {
+	if cmd.Format != "" && cmd.Format != "human" && cmd.Format != "json" {
+		return fmt.Errorf("%s: unknown maintenance report format, try human or json", cmd.Format)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	tasks := notebook.Config.Maintenance.Tasks
+	if len(cmd.Task) > 0 {
+		tasks = []core.MaintenanceTask{}
+		for _, name := range cmd.Task {
+			task, err := core.MaintenanceTaskFromString(name)
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, task)
+		}
+	}
+
+	report := notebook.Maintain(core.MaintenanceOpts{
+		Tasks:      tasks,
+		DryRun:     cmd.DryRun,
+		Now:        time.Now(),
+		FetchURL:   web.NewURLMetadataFetcher(10 * time.Second),
+		ArchiveURL: web.NewWaybackArchiver(time.Minute),
+		Origin:     container.Origin,
+	})
+
+	if cmd.Format == "json" {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, task := range report.Tasks {
+			fmt.Println(task)
+			for _, path := range task.Purged {
+				fmt.Printf("  - %s\n", path)
+			}
+			for _, diff := range task.ManifestDiffs {
+				fmt.Printf("  %s\n", diff)
+			}
+		}
+	}
+
+	if report.Failed() {
+		return errors.New("some maintenance tasks failed")
+	}
+	return nil
+}
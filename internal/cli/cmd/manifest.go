@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Manifest manages the checksum manifest of the notes.
+type Manifest struct {
+	Write  ManifestWrite  `cmd group:"cmd" help:"Save the checksums of all the notes."`
+	Verify ManifestVerify `cmd group:"cmd" help:"Report the notes which changed since the manifest was saved."`
+}
+
+func (cmd *Manifest) Help() string {
+	return "The manifest is saved in the sha256sum format, by default in .zk/manifest.sha256. Verify it after a sync or a restore to make sure no note was corrupted or silently modified."
+}
+
+// ManifestWrite saves the checksums of all the notes.
+type ManifestWrite struct {
+	File string `short:F type:path placeholder:PATH help:"Path to the manifest, instead of .zk/manifest.sha256."`
+}
+
+func (cmd *ManifestWrite) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	path := manifestPath(notebook, cmd.File)
+	manifest, err := notebook.WriteManifest(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote the checksums of %d %s to %s\n",
+		len(manifest), strings.Pluralize("note", len(manifest)), path,
+	)
+	return nil
+}
+
+// ManifestVerify compares the manifest with the notes on disk.
+type ManifestVerify struct {
+	File   string `short:F type:path placeholder:PATH help:"Path to the manifest, instead of .zk/manifest.sha256."`
+	Format string `group:format short:f placeholder:FORMAT help:"Format of the reported differences, among: human, json."`
+	Quiet  bool   `group:format short:q help:"Do not print the total number of changed notes."`
+}
+
+func (cmd *ManifestVerify) Help() string {
+	return "Exits with an error status if any note was added, modified or removed since the manifest was saved."
+}
+
+func (cmd *ManifestVerify) Run(container *cli.Container) error {
+	if cmd.Format != "" && cmd.Format != "human" && cmd.Format != "json" {
+		return fmt.Errorf("%s: unknown manifest format, try human or json", cmd.Format)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	diffs, err := notebook.VerifyManifest(manifestPath(notebook, cmd.File))
+	if err != nil {
+		return err
+	}
+
+	if cmd.Format == "json" {
+		err = printJSON(diffs)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, diff := range diffs {
+			fmt.Println(diff)
+		}
+	}
+
+	count := len(diffs)
+	if !cmd.Quiet {
+		fmt.Fprintf(os.Stderr, "\nFound %d changed %s\n", count, strings.Pluralize("note", count))
+	}
+	if count > 0 {
+		return errors.New("the notes don't match the manifest")
+	}
+	return nil
+}
+
+// manifestPath returns the absolute path to the manifest, defaulting to the
+// one saved in the notebook.
+func manifestPath(notebook *core.Notebook, path string) string {
+	if path == "" {
+		return filepath.Join(notebook.Path, core.DefaultManifestPath)
+	}
+	return path
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Merge combines several notes into one.
+type Merge struct {
+	Paths    []string `arg help:"Paths to the notes to merge."`
+	Into     string   `required placeholder:PATH help:"Note receiving the merged content, created if it doesn't exist."`
+	Template string   `placeholder:PATH help:"Custom template combining the notes, instead of concatenating them."`
+	DryRun   bool     `short:n help:"Print the merged note and the links which would be updated, without modifying any file."`
+}
+
+func (cmd *Merge) Help() string {
+	return "The notes are concatenated after the content of the target note, if it exists. Their frontmatters are merged, keeping the first value of each key and the union of the tags. The links to the merged notes are updated to target the merged note, then the merged notes are archived, or moved to the trash when no archive directory is set.\n\nThe templates combining the notes receive the `path` of the target note and its `notes`, each with a `path`, `title`, `body`, `content`, `tags` and `metadata`. The merged frontmatter is prepended to the rendered template."
+}
+
+func (cmd *Merge) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	merge, err := notebook.MergeNotes(core.MergeNotesOpts{
+		Paths:    cmd.Paths,
+		Into:     cmd.Into,
+		Template: opt.NewNotEmptyString(cmd.Template),
+		DryRun:   cmd.DryRun,
+		Origin:   container.Origin,
+	})
+	if err != nil {
+		return err
+	}
+
+	if cmd.DryRun {
+		fmt.Println(merge.Content)
+	}
+	for _, link := range merge.RewrittenLinks {
+		fmt.Println(link)
+	}
+
+	verb := "Merged"
+	if cmd.DryRun {
+		verb = "Would merge"
+	}
+	action := "archiving"
+	if len(merge.Sources) > 0 && merge.Sources[0].ArchivePath == "" {
+		action = "trashing"
+	}
+	sourceCount := len(merge.Sources)
+	linkCount := len(merge.RewrittenLinks)
+	noteCount := len(merge.NotePaths())
+	fmt.Fprintf(os.Stderr, "\n%s %d %s into %s, %s them and updating %d %s in %d %s\n",
+		verb,
+		sourceCount, strings.Pluralize("note", sourceCount), merge.TargetPath,
+		action,
+		linkCount, strings.Pluralize("link", linkCount),
+		noteCount, strings.Pluralize("note", noteCount),
+	)
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// MigrateLinks converts the internal links of the notebook to another syntax
+// or path style.
+type MigrateLinks struct {
+	To     string `placeholder:SYNTAX help:"Syntax of the links, among: wiki, markdown. By default, the syntax of each link is kept."`
+	Path   string `placeholder:STYLE help:"Style of the link paths, among: relative, root, id. Defaults to relative for Markdown links and root for wiki-links."`
+	DryRun bool   `short:n help:"Print the changes as a diff, without modifying any file."`
+	Quiet  bool   `short:q help:"Do not print the changes."`
+}
+
+func (cmd *MigrateLinks) Help() string {
+	return "The links which can't be resolved to a note are left untouched. When the syntax changes, the link-format setting of the notebook config is updated to create the new links with the same style."
+}
+
+func (cmd *MigrateLinks) Run(container *cli.Container) error {
+	opts := core.MigrateLinksOpts{
+		DryRun: cmd.DryRun,
+		Origin: container.Origin,
+	}
+	switch cmd.To {
+	case "":
+	case "wiki", "markdown":
+		opts.Syntax = core.LinkSyntax(cmd.To)
+	default:
+		return fmt.Errorf("%s: unknown link syntax, try wiki or markdown", cmd.To)
+	}
+	switch cmd.Path {
+	case "":
+	case "relative", "root", "id":
+		opts.PathStyle = core.LinkPathStyle(cmd.Path)
+	default:
+		return fmt.Errorf("%s: unknown link path style, try relative, root or id", cmd.Path)
+	}
+	if opts.Syntax == "" && opts.PathStyle == "" {
+		return errors.New("nothing to migrate, give a link syntax with --to or a path style with --path")
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	migration, err := notebook.MigrateLinks(opts)
+	if err != nil {
+		return err
+	}
+
+	changeCount := 0
+	noteCount := 0
+	skippedCount := 0
+	for _, replacement := range migration.Notes {
+		if len(replacement.Changes) > 0 {
+			noteCount++
+		}
+		changeCount += len(replacement.Changes)
+		skippedCount += replacement.Skipped
+		if !cmd.Quiet {
+			printReplacementDiff(os.Stdout, container.Terminal, replacement)
+		}
+	}
+
+	verb := "Migrated"
+	if cmd.DryRun {
+		verb = "Would migrate"
+	}
+	fmt.Fprintf(os.Stderr, "%s the links of %d %s in %d %s\n",
+		verb,
+		changeCount, strutil.Pluralize("line", changeCount),
+		noteCount, strutil.Pluralize("note", noteCount),
+	)
+	if skippedCount > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d unresolved %s, check them with zk lint\n",
+			skippedCount, strutil.Pluralize("link", skippedCount),
+		)
+	}
+	if migration.LinkFormat != "" {
+		verb := "Set"
+		if cmd.DryRun {
+			verb = "Would set"
+		}
+		fmt.Fprintf(os.Stderr, "%s link-format = %q in the [format.markdown] config section\n", verb, migration.LinkFormat)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Mv moves or renames a note, updating the links to it across the notebook.
+type Mv struct {
+	Path        string `arg help:"Path to the note to move."`
+	Destination string `arg help:"New path of the note, or directory where to move it."`
+	DryRun      bool   `short:n help:"Print the links which would be updated, without modifying any file."`
+}
+
+func (cmd *Mv) Help() string {
+	return "Both wiki-links and Markdown relative links are updated, as well as the relative links of the moved note itself. The note extension can be omitted from the destination."
+}
+
+func (cmd *Mv) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	move, err := notebook.MoveNote(core.MoveNoteOpts{
+		Path:        cmd.Path,
+		Destination: cmd.Destination,
+		DryRun:      cmd.DryRun,
+		Origin:      container.Origin,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, link := range move.RewrittenLinks {
+		fmt.Println(link)
+	}
+
+	verb := "Moved"
+	if cmd.DryRun {
+		verb = "Would move"
+	}
+	linkCount := len(move.RewrittenLinks)
+	noteCount := len(move.NotePaths())
+	fmt.Fprintf(os.Stderr, "\n%s %s to %s, updating %d %s in %d %s\n",
+		verb, move.SourcePath, move.TargetPath,
+		linkCount, strings.Pluralize("link", linkCount),
+		noteCount, strings.Pluralize("note", noteCount),
+	)
+
+	return nil
+}
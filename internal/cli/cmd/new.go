@@ -1,25 +1,40 @@
 package cmd
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
+	"io"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/mickael-menu/zk/internal/adapter/term"
 	"github.com/mickael-menu/zk/internal/cli"
 	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
 	"github.com/mickael-menu/zk/internal/util/opt"
 	"github.com/mickael-menu/zk/internal/util/os"
+	"github.com/mickael-menu/zk/internal/util/yaml"
+	yamlv2 "gopkg.in/yaml.v2"
 )
 
 // New adds a new note to the notebook.
 type New struct {
-	Directory string            `arg optional default:"." help:"Directory in which to create the note."`
-	Title     string            `short:t   placeholder:TITLE help:"Title of the new note."`
-	Group     string            `short:g   placeholder:NAME  help:"Name of the config group this note belongs to. Takes precedence over the config of the directory."`
-	Extra     map[string]string `                            help:"Extra variables passed to the templates." mapsep:","`
-	Template  string            `          placeholder:PATH  help:"Custom template used to render the note."`
-	PrintPath bool              `short:p                     help:"Print the path of the created note instead of editing it."`
+	Directory   string            `arg optional default:"." help:"Directory in which to create the note."`
+	Title       string            `short:t   placeholder:TITLE help:"Title of the new note."`
+	Group       string            `short:g   placeholder:NAME  help:"Name of the config group this note belongs to. Takes precedence over the config of the directory."`
+	Extra       map[string]string `                            help:"Extra variables passed to the templates." mapsep:","`
+	Template    string            `          placeholder:PATH  help:"Custom template used to render the note."`
+	Parent      string            `          placeholder:NOTE  help:"Parent of a new Folgezettel note, either its path or its ID. Requires the folgezettel ID strategy."`
+	LinkFrom    string            `          placeholder:NOTE[:HEADING] help:"Insert a link to the new note in an existing note, at the end of the given heading section or of the note."`
+	PrintPath   bool              `short:p                     help:"Print the path of the created note instead of editing it."`
+	Interactive bool              `short:i                     help:"Prompt for the variables declared by the template which are not given with --extra."`
+	Batch       bool              `                            help:"Create several notes described by a JSON or YAML stream from the standard input, and print their paths as JSON."`
+}
+
+func (cmd *New) Help() string {
+	return "With --batch, each note of the stream can set its title, dir (relative to the notebook root), group, template, content and extra variables. The other flags are used as defaults for all the notes. Either all or none of the notes are created."
 }
 
 func (cmd *New) Run(container *cli.Container) error {
@@ -28,12 +43,32 @@ func (cmd *New) Run(container *cli.Container) error {
 		return err
 	}
 
+	if cmd.Batch {
+		if cmd.Interactive {
+			return errors.New("--interactive can't be used with --batch")
+		}
+		input, err := os.ReadStdin()
+		if err != nil {
+			return err
+		}
+		return cmd.runBatch(notebook, input, container.Origin)
+	}
+
 	content, err := os.ReadStdinPipe()
 	if err != nil {
 		return err
 	}
 
-	note, err := notebook.NewNote(core.NewNoteOpts{
+	var linkFrom *core.NoteLinkLocation
+	if cmd.LinkFrom != "" {
+		parts := strings.SplitN(cmd.LinkFrom, ":", 2)
+		linkFrom = &core.NoteLinkLocation{Path: parts[0]}
+		if len(parts) == 2 {
+			linkFrom.Heading = parts[1]
+		}
+	}
+
+	opts := core.NewNoteOpts{
 		Title:     opt.NewNotEmptyString(cmd.Title),
 		Content:   content.Unwrap(),
 		Directory: opt.NewNotEmptyString(cmd.Directory),
@@ -41,7 +76,19 @@ func (cmd *New) Run(container *cli.Container) error {
 		Template:  opt.NewNotEmptyString(cmd.Template),
 		Extra:     cmd.Extra,
 		Date:      time.Now(),
-	})
+		Parent:    opt.NewNotEmptyString(cmd.Parent),
+		LinkFrom:  linkFrom,
+		Origin:    container.Origin,
+	}
+	if cmd.Interactive && container.Terminal.IsInteractive() {
+		var ok bool
+		opts.Extra, ok, err = promptTemplateVars(container.Terminal, notebook, opts)
+		if !ok || err != nil {
+			return err
+		}
+	}
+
+	note, err := notebook.NewNote(opts)
 	var path string
 	if err == nil {
 		path = filepath.Join(notebook.Path, note.Path)
@@ -70,6 +117,164 @@ func (cmd *New) Run(container *cli.Container) error {
 		if err != nil {
 			return err
 		}
-		return editor.Open(path)
+		return notebook.EditNotes([]string{path}, editor.Open)
+	}
+}
+
+// promptTemplateVars prompts the user for the variables declared by the
+// template of the new note, and returns them with the given extra variables.
+// ok is false when the user interrupted a prompt.
+func promptTemplateVars(terminal *term.Terminal, notebook *core.Notebook, opts core.NewNoteOpts) (extra map[string]string, ok bool, err error) {
+	vars, err := notebook.MissingTemplateVars(opts)
+	if err != nil {
+		return nil, false, err
+	}
+
+	extra = map[string]string{}
+	for k, v := range opts.Extra {
+		extra[k] = v
+	}
+	for _, v := range vars {
+		var value string
+		switch {
+		case v.Type == core.TemplateVarBoolean:
+			defaultValue, _ := v.Parse(v.Default)
+			confirmed, skipped := terminal.Confirm(v.Prompt, defaultValue != "")
+			value, ok = strconv.FormatBool(confirmed), !skipped
+
+		case len(v.Choices) > 0:
+			defaultIndex := 0
+			for i, choice := range v.Choices {
+				if choice == v.Default {
+					defaultIndex = i
+				}
+			}
+			var index int
+			index, ok = terminal.Select(v.Prompt, v.Choices, defaultIndex)
+			value = v.Choices[index]
+
+		default:
+			value, ok = terminal.Input(v.Prompt, v.Default, func(value string) error {
+				_, err := v.Parse(value)
+				return err
+			})
+		}
+		if !ok {
+			return nil, false, nil
+		}
+		extra[v.Name] = value
+	}
+	return extra, true, nil
+}
+
+// batchNote describes a note created with `zk new --batch`.
+type batchNote struct {
+	Title    string            `json:"title" yaml:"title"`
+	Dir      string            `json:"dir" yaml:"dir"`
+	Group    string            `json:"group" yaml:"group"`
+	Template string            `json:"template" yaml:"template"`
+	Content  string            `json:"content" yaml:"content"`
+	Extra    map[string]string `json:"extra" yaml:"extra"`
+}
+
+func (cmd *New) runBatch(notebook *core.Notebook, input string, origin core.AuditOrigin) error {
+	batch, err := parseBatchNotes(input)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse the batch of notes")
 	}
+
+	now := time.Now()
+	opts := []core.NewNoteOpts{}
+	for _, note := range batch {
+		dir := cmd.Directory
+		if note.Dir != "" {
+			dir = note.Dir
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(notebook.Path, dir)
+			}
+		}
+		extra := map[string]string{}
+		for k, v := range cmd.Extra {
+			extra[k] = v
+		}
+		for k, v := range note.Extra {
+			extra[k] = v
+		}
+
+		opts = append(opts, core.NewNoteOpts{
+			Title:     opt.NewNotEmptyString(note.Title).Or(opt.NewNotEmptyString(cmd.Title)),
+			Content:   note.Content,
+			Directory: opt.NewNotEmptyString(dir),
+			Group:     opt.NewNotEmptyString(note.Group).Or(opt.NewNotEmptyString(cmd.Group)),
+			Template:  opt.NewNotEmptyString(note.Template).Or(opt.NewNotEmptyString(cmd.Template)),
+			Extra:     extra,
+			Date:      now,
+			Origin:    origin,
+		})
+	}
+
+	notes, err := notebook.NewNotes(opts)
+	if err != nil {
+		return err
+	}
+
+	paths := []string{}
+	for _, note := range notes {
+		paths = append(paths, filepath.Join(notebook.Path, note.Path))
+	}
+	output, err := json.Marshal(paths)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(output))
+	return nil
+}
+
+// parseBatchNotes reads the notes described in a stream of JSON values (e.g.
+// JSON lines) or YAML documents. Each value can be a single note or a list of
+// notes.
+func parseBatchNotes(input string) ([]batchNote, error) {
+	notes := []batchNote{}
+	add := func(value interface{}) error {
+		// Round-trip through JSON to support both a single note and a list.
+		data, err := json.Marshal(yaml.ConvertToJSONCompatible(value))
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(string(data), "[") {
+			var list []batchNote
+			err = json.Unmarshal(data, &list)
+			notes = append(notes, list...)
+		} else if string(data) != "null" {
+			var note batchNote
+			err = json.Unmarshal(data, &note)
+			notes = append(notes, note)
+		}
+		return err
+	}
+
+	var decoder interface {
+		Decode(value interface{}) error
+	}
+	input = strings.TrimSpace(input)
+	if strings.HasPrefix(input, "{") || strings.HasPrefix(input, "[") {
+		decoder = json.NewDecoder(strings.NewReader(input))
+	} else {
+		decoder = yamlv2.NewDecoder(strings.NewReader(input))
+	}
+
+	for {
+		var value interface{}
+		err := decoder.Decode(&value)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if err := add(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return notes, nil
 }
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestParseBatchNotes(t *testing.T) {
+	test := func(input string, expected []batchNote) {
+		actual, err := parseBatchNotes(input)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("", []batchNote{})
+
+	// JSON array
+	test(`[{"title": "First", "dir": "journal"}, {"title": "Second", "extra": {"key": "value"}}]`, []batchNote{
+		{Title: "First", Dir: "journal"},
+		{Title: "Second", Extra: map[string]string{"key": "value"}},
+	})
+
+	// JSON lines
+	test(`{"title": "First", "group": "daily"}
+{"title": "Second", "template": "task.md", "content": "Body"}
+`, []batchNote{
+		{Title: "First", Group: "daily"},
+		{Title: "Second", Template: "task.md", Content: "Body"},
+	})
+
+	// YAML documents
+	test(`title: First
+dir: journal
+---
+- title: Second
+  extra:
+    key: value
+- title: Third
+`, []batchNote{
+		{Title: "First", Dir: "journal"},
+		{Title: "Second", Extra: map[string]string{"key": "value"}},
+		{Title: "Third"},
+	})
+}
+
+func TestParseBatchNotesInvalid(t *testing.T) {
+	_, err := parseBatchNotes(`{"title": 42}`)
+	assert.NotNil(t, err)
+}
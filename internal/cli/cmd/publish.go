@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/adapter/fzf"
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Publish generates a static website from the notes matching a set of
+// criteria.
+type Publish struct {
+	Output     string `short:o type:path placeholder:DIR help:"Directory where the website is written, instead of .zk/site."`
+	Title      string `placeholder:TITLE help:"Title of the website, by default the name of the notebook directory."`
+	BaseURL    string `placeholder:URL help:"Base URL of the website, required for the sitemap and the RSS feed. Defaults to the published-url of the [lsp.links] config section."`
+	FeedLength int    `placeholder:COUNT default:"20" help:"Maximum number of recent notes in the RSS feed."`
+	Quiet      bool   `short:q help:"Do not print a summary of the website."`
+	cli.Filtering
+}
+
+func (cmd *Publish) Help() string {
+	return "The website has a page for each note with its backlinks, index pages per tag and directory, a graph of the links in graph.json, a sitemap and an RSS feed of the recent notes. The links to the notes which are not published are removed.\n\n" +
+		"Customize the pages with the publish/note.html and publish/list.html handlebars templates, in the .zk/templates directory. The files of publish/static are copied to the root of the website."
+}
+
+func (cmd *Publish) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	findOpts, err := cmd.Filtering.NewNoteFindOpts(notebook)
+	if err != nil {
+		return errors.Wrapf(err, "incorrect criteria")
+	}
+	notes, err := notebook.FindNotes(findOpts)
+	if err != nil {
+		return err
+	}
+	notes, err = container.NewNoteFilter(fzf.NoteFilterOpts{
+		Interactive: cmd.Interactive,
+		NotebookDir: notebook.Path,
+	}).Apply(notes)
+	if err != nil {
+		if err == fzf.ErrCancelled {
+			return nil
+		}
+		return err
+	}
+
+	output := cmd.Output
+	if output == "" {
+		output = filepath.Join(notebook.Path, ".zk/site")
+	}
+	baseURL := cmd.BaseURL
+	if baseURL == "" {
+		baseURL = notebook.Config.LSP.Links.PublishedURL.String()
+	}
+
+	report, err := notebook.Publish(core.PublishOpts{
+		Notes:      notes,
+		OutputDir:  output,
+		Title:      cmd.Title,
+		BaseURL:    baseURL,
+		FeedLength: cmd.FeedLength,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !cmd.Quiet {
+		details := []string{
+			fmt.Sprintf("%d index %s", report.IndexPages, strutil.Pluralize("page", report.IndexPages)),
+			fmt.Sprintf("%d %s", report.Assets, strutil.Pluralize("asset", report.Assets)),
+		}
+		if !report.Feed {
+			details = append(details, "no sitemap and RSS feed without --base-url")
+		}
+		fmt.Fprintf(os.Stderr, "Published %d %s to %s (%s)\n",
+			report.Notes, strutil.Pluralize("note", report.Notes), output, strings.Join(details, ", "),
+		)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+)
+
+// Query runs a read-only SQL query on the index of the notebook.
+type Query struct {
+	Query  string `arg placeholder:SQL help:"SQL query to run, e.g. \"SELECT path FROM zk_notes\"."`
+	Format string `group:format short:f placeholder:FORMAT help:"Format of the results, among: table, json."`
+}
+
+func (cmd *Query) Help() string {
+	return "The query runs with a read-only connection, so it can't modify the index. The views zk_notes, zk_links and zk_tags are stable across zk versions, unlike the underlying tables. See docs/index-queries.md for their columns."
+}
+
+func (cmd *Query) Run(container *cli.Container) error {
+	if cmd.Format != "" && cmd.Format != "table" && cmd.Format != "json" {
+		return fmt.Errorf("%s: unknown query format, try table or json", cmd.Format)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	res, err := notebook.QueryIndex(cmd.Query)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Format == "json" {
+		out, err := formatJSONQueryResult(res)
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+		return nil
+	}
+
+	fmt.Print(formatTableQueryResult(res))
+	return nil
+}
+
+// formatJSONQueryResult renders the rows as an array of JSON objects, with
+// the keys in the order of the columns.
+func formatJSONQueryResult(res core.IndexQueryResult) (string, error) {
+	out := &strings.Builder{}
+	out.WriteString("[")
+	for i, row := range res.Rows {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n  {")
+		for j, value := range row {
+			if j > 0 {
+				out.WriteString(", ")
+			}
+			key, err := json.Marshal(res.Columns[j])
+			if err != nil {
+				return "", err
+			}
+			val, err := json.Marshal(value)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(out, "%s: %s", key, val)
+		}
+		out.WriteString("}")
+	}
+	if len(res.Rows) > 0 {
+		out.WriteString("\n")
+	}
+	out.WriteString("]")
+	return out.String(), nil
+}
+
+var queryCellReplacer = strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`)
+
+// formatTableQueryResult renders the rows as a table with aligned columns.
+// NULL values are empty.
+func formatTableQueryResult(res core.IndexQueryResult) string {
+	if len(res.Columns) == 0 {
+		return ""
+	}
+
+	cells := [][]string{res.Columns}
+	for _, row := range res.Rows {
+		line := []string{}
+		for _, value := range row {
+			line = append(line, formatQueryValue(value))
+		}
+		cells = append(cells, line)
+	}
+
+	widths := make([]int, len(res.Columns))
+	for _, line := range cells {
+		for i, cell := range line {
+			if width := utf8.RuneCountInString(cell); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	out := &strings.Builder{}
+	for _, line := range cells {
+		row := &strings.Builder{}
+		for i, cell := range line {
+			row.WriteString(cell)
+			row.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2))
+		}
+		out.WriteString(strings.TrimRight(row.String(), " "))
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+func formatQueryValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case string:
+		return queryCellReplacer.Replace(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
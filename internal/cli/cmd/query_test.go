@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+var queryResultFixture = core.IndexQueryResult{
+	Columns: []string{"path", "words", "created", "target"},
+	Rows: [][]interface{}{
+		{"a.md", int64(12), time.Date(2021, 10, 12, 8, 0, 0, 0, time.UTC), "b.md"},
+		{"é\nb.md", int64(1234), time.Date(2021, 10, 14, 8, 0, 0, 0, time.UTC), nil},
+	},
+}
+
+func TestFormatTableQueryResult(t *testing.T) {
+	assert.Equal(t, formatTableQueryResult(core.IndexQueryResult{}), "")
+	assert.Equal(t, formatTableQueryResult(queryResultFixture), `path     words  created               target
+a.md     12     2021-10-12T08:00:00Z  b.md
+é\nb.md  1234   2021-10-14T08:00:00Z
+`)
+}
+
+func TestFormatJSONQueryResult(t *testing.T) {
+	out, err := formatJSONQueryResult(core.IndexQueryResult{Columns: []string{"path"}, Rows: [][]interface{}{}})
+	assert.Nil(t, err)
+	assert.Equal(t, out, "[]")
+
+	out, err = formatJSONQueryResult(queryResultFixture)
+	assert.Nil(t, err)
+	assert.Equal(t, out, `[
+  {"path": "a.md", "words": 12, "created": "2021-10-12T08:00:00Z", "target": "b.md"},
+  {"path": "é\nb.md", "words": 1234, "created": "2021-10-14T08:00:00Z", "target": null}
+]`)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Related lists the notes sharing rare terms or tags with a note, which are
+// not linked to or from it yet.
+type Related struct {
+	Path    string `arg help:"Path to the note."`
+	Limit   int    `short:n default:"10" placeholder:COUNT help:"Maximum number of related notes."`
+	Format  string `group:format short:f placeholder:FORMAT help:"Format of the list, among: text, json, jsonl."`
+	NoPager bool   `group:format short:P help:"Do not pipe output into a pager."`
+	Quiet   bool   `group:format short:q help:"Do not print the total number of notes found."`
+}
+
+func (cmd *Related) Help() string {
+	return "The notes are ranked by the rare terms and tags they share with the given note, to discover the missing connections between your notes. The shared terms are printed stemmed, as indexed for the full-text search."
+}
+
+func (cmd *Related) Run(container *cli.Container) error {
+	switch cmd.Format {
+	case "", "text", "json", "jsonl":
+	default:
+		return fmt.Errorf("%s: unknown format, expected one of: text, json, jsonl", cmd.Format)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	notes, err := notebook.FindRelatedNotes(cmd.Path, core.RelatedNotesOpts{Limit: cmd.Limit})
+	if err != nil {
+		return err
+	}
+
+	count := len(notes)
+	if count > 0 {
+		err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
+			return writeRelatedNotes(out, notes, cmd.Format)
+		})
+	}
+
+	if err == nil && !cmd.Quiet {
+		fmt.Fprintf(os.Stderr, "\nFound %d related %s\n", count, strutil.Pluralize("note", count))
+	}
+
+	return err
+}
+
+// writeRelatedNotes prints the related notes in the given format, among text,
+// json and jsonl.
+func writeRelatedNotes(out io.Writer, notes []core.RelatedNote, format string) error {
+	switch format {
+	case "json":
+		data, err := json.Marshal(notes)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(data))
+
+	case "jsonl":
+		for _, note := range notes {
+			data, err := json.Marshal(note)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, string(data))
+		}
+
+	default:
+		for _, note := range notes {
+			title := note.Title
+			if title == "" {
+				title = note.Path
+			}
+			fmt.Fprintf(out, "%s: %s (%s)\n", note.Path, title, strings.Join(note.Terms, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Relink retargets the links to a note to another note.
+type Relink struct {
+	Old    string `arg help:"Path to the note whose links are retargeted, or a regular expression with --regex."`
+	New    string `arg help:"Path to the note targeted by the links instead."`
+	Regex  bool   `help:"Retarget the links whose destination matches the regular expression OLD."`
+	DryRun bool   `short:n help:"Print the links which would be updated, without modifying any file."`
+	Diff   bool   `short:d help:"Print the modified lines as a diff instead of the updated links."`
+}
+
+func (cmd *Relink) Help() string {
+	return "Both wiki-links and Markdown links are updated, keeping their anchor and style, e.g. without extension. The old note doesn't need to exist anymore, which is convenient to fix dead links. The links found in the new note itself are left untouched.\n\nFor example, merge a duplicate note with `zk relink duplicate.md original.md` before deleting it."
+}
+
+func (cmd *Relink) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	opts := core.RelinkOpts{
+		Target: cmd.New,
+		DryRun: cmd.DryRun,
+		Origin: container.Origin,
+	}
+	if cmd.Regex {
+		opts.Pattern, err = regexp.Compile(cmd.Old)
+		if err != nil {
+			return errors.Wrapf(err, "invalid pattern")
+		}
+	} else {
+		opts.Source = cmd.Old
+	}
+
+	relink, err := notebook.Relink(opts)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Diff {
+		for _, replacement := range relink.Changes {
+			printReplacementDiff(os.Stdout, container.Terminal, replacement)
+		}
+	} else {
+		for _, link := range relink.RewrittenLinks {
+			fmt.Println(link)
+		}
+	}
+
+	verb := "Updated"
+	if cmd.DryRun {
+		verb = "Would update"
+	}
+	linkCount := len(relink.RewrittenLinks)
+	noteCount := len(relink.NotePaths())
+	fmt.Fprintf(os.Stderr, "\n%s %d %s in %d %s to target %s\n",
+		verb,
+		linkCount, strings.Pluralize("link", linkCount),
+		noteCount, strings.Pluralize("note", noteCount),
+		relink.TargetPath,
+	)
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/adapter/fzf"
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Replace finds and replaces text in the notes matching a set of criteria.
+type Replace struct {
+	Pattern            string `arg help:"Text to find, or a regular expression with --regex."`
+	Replacement        string `arg help:"Replacement text. With --regex, it can refer to submatches, e.g. $1."`
+	Regex              bool   `help:"Interpret the pattern as a regular expression."`
+	IncludeLinks       bool   `help:"Replace text in the link destinations as well, which might break them."`
+	IncludeFrontmatter bool   `help:"Replace text in the YAML frontmatter as well."`
+	IncludeTags        bool   `help:"Replace text in the inline tags as well, e.g. #project/alpha."`
+	DryRun             bool   `help:"Print the changes as a diff, without modifying any file."`
+	Quiet              bool   `short:q help:"Do not print the changes."`
+	cli.Filtering
+}
+
+func (cmd *Replace) Help() string {
+	return "Link destinations, inline tags and frontmatter are left untouched by default, to prevent corrupting the notebook. Use the filtering options to restrict the notes where the text is replaced."
+}
+
+func (cmd *Replace) Run(container *cli.Container) error {
+	pattern := cmd.Pattern
+	replacement := cmd.Replacement
+	if !cmd.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+		replacement = strings.ReplaceAll(replacement, "$", "$$")
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return errors.Wrapf(err, "invalid pattern")
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	findOpts, err := cmd.Filtering.NewNoteFindOpts(notebook)
+	if err != nil {
+		return errors.Wrapf(err, "incorrect criteria")
+	}
+	notes, err := notebook.FindNotes(findOpts)
+	if err != nil {
+		return err
+	}
+	notes, err = container.NewNoteFilter(fzf.NoteFilterOpts{
+		Interactive: cmd.Interactive,
+		NotebookDir: notebook.Path,
+	}).Apply(notes)
+	if err != nil {
+		if err == fzf.ErrCancelled {
+			return nil
+		}
+		return err
+	}
+
+	paths := []string{}
+	for _, note := range notes {
+		paths = append(paths, note.Path)
+	}
+
+	replacements, err := notebook.Replace(core.ReplaceOpts{
+		Pattern:            regex,
+		Replacement:        replacement,
+		Paths:              paths,
+		IncludeLinks:       cmd.IncludeLinks,
+		IncludeFrontmatter: cmd.IncludeFrontmatter,
+		IncludeTags:        cmd.IncludeTags,
+		DryRun:             cmd.DryRun,
+		Origin:             container.Origin,
+	})
+	if err != nil {
+		return err
+	}
+
+	changeCount := 0
+	noteCount := 0
+	skippedCount := 0
+	for _, replacement := range replacements {
+		if len(replacement.Changes) > 0 {
+			noteCount++
+		}
+		changeCount += len(replacement.Changes)
+		skippedCount += replacement.Skipped
+		if !cmd.Quiet {
+			printReplacementDiff(os.Stdout, container.Terminal, replacement)
+		}
+	}
+
+	verb := "Replaced"
+	if cmd.DryRun {
+		verb = "Would replace"
+	}
+	fmt.Fprintf(os.Stderr, "%s %d %s in %d %s\n",
+		verb,
+		changeCount, strutil.Pluralize("line", changeCount),
+		noteCount, strutil.Pluralize("note", noteCount),
+	)
+	if skippedCount > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d %s in link destinations, tags or frontmatter, use --include-links, --include-tags or --include-frontmatter to replace them\n",
+			skippedCount, strutil.Pluralize("occurrence", skippedCount),
+		)
+	}
+
+	return nil
+}
+
+// printReplacementDiff prints the lines modified in a note, as a diff.
+func printReplacementDiff(out io.Writer, styler core.Styler, replacement core.NoteReplacement) {
+	style := func(text string, rules ...core.Style) string {
+		styled, err := styler.Style(text, rules...)
+		if err != nil {
+			return text
+		}
+		return styled
+	}
+
+	for _, change := range replacement.Changes {
+		fmt.Fprintln(out, style(fmt.Sprintf("%s:%d", replacement.Path, change.Line), core.StylePath))
+		for _, line := range strings.Split(change.Old, "\n") {
+			fmt.Fprintln(out, style("- "+line, core.StyleRed))
+		}
+		for _, line := range strings.Split(change.New, "\n") {
+			fmt.Fprintln(out, style("+ "+line, core.StyleGreen))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+)
+
+// Resolve prints the note targeted by a link href, an ID or a title, resolved
+// like the wiki-links.
+type Resolve struct {
+	Href   string `arg help:"Href of a wiki-link, ID or title of the note."`
+	Format string `short:f placeholder:TEMPLATE default:"{{path}}" help:"Template used to print the note, e.g. {{abs-path}}."`
+}
+
+func (cmd *Resolve) Help() string {
+	return "The href is matched against the note paths, aliases and titles like the wiki-links in the editor, including the notes of other notebooks with a prefix declared in the [notebooks] config section. The command fails when no note matches."
+}
+
+func (cmd *Resolve) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	href := cmd.Href
+	if notebookPath, noteHref, ok := notebook.FederatedHref(href); ok {
+		notebook, err = container.Notebooks.Open(notebookPath)
+		if err != nil {
+			return err
+		}
+		href = noteHref
+	}
+
+	note, _, err := notebook.ResolveHref(href)
+	if err != nil {
+		return err
+	}
+	if note == nil {
+		return core.ErrNoteNotFound(cmd.Href)
+	}
+
+	notes, err := notebook.FindNotes(core.NoteFindOpts{ExactPaths: []string{note.Path}})
+	if err != nil {
+		return err
+	}
+	if len(notes) == 0 {
+		return core.ErrNoteNotFound(note.Path)
+	}
+
+	format, err := notebook.NewNoteFormatter(cmd.Format)
+	if err != nil {
+		return err
+	}
+	line, err := format(notes[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println(line)
+	return nil
+}
+
+// ID prints the ID of a note, which resolves back to the note with zk
+// resolve and in wiki-links.
+type ID struct {
+	Paths []string `arg help:"Paths to the notes."`
+}
+
+func (cmd *ID) Help() string {
+	return "The ID of a note is the stem of its filename, e.g. 4ufx for 4ufx.md."
+}
+
+func (cmd *ID) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range cmd.Paths {
+		id, err := notebook.NoteID(path)
+		if err != nil {
+			return err
+		}
+		fmt.Println(id)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mickael-menu/zk/internal/cli"
+)
+
+// Restore takes a note out of the trash.
+type Restore struct {
+	Path string `arg help:"Path to the trashed note to restore."`
+}
+
+func (cmd *Restore) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	note, err := notebook.RestoreNote(container.Origin, cmd.Path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Restored %s from the trash\n", note.Path)
+	return nil
+}
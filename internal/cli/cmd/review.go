@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/date"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Review manages the spaced repetition of the notes in the review queue.
+type Review struct {
+	List   ReviewList   `cmd group:"cmd" default:"withargs" help:"List the notes due for review."`
+	Record ReviewRecord `cmd group:"cmd" help:"Record the result of the review of a note."`
+}
+
+// ReviewList lists the notes of the review queue which are due.
+type ReviewList struct {
+	All     bool   `short:a help:"Include the notes which are not due yet."`
+	Date    string `placeholder:DATE help:"List the notes due at the given date instead of today, e.g. tomorrow or 2021-10-12."`
+	Format  string `group:format short:f placeholder:FORMAT help:"Format of the list, among: text, json, jsonl."`
+	NoPager bool   `group:format short:P help:"Do not pipe output into a pager."`
+	Quiet   bool   `group:format short:q help:"Do not print the total number of notes found."`
+}
+
+func (cmd *ReviewList) Help() string {
+	return "Add a note to the review queue with `review: true` in its frontmatter. After reviewing it, run `zk review record <note> <grade>` to schedule the next review."
+}
+
+func (cmd *ReviewList) Run(container *cli.Container) error {
+	switch cmd.Format {
+	case "", "text", "json", "jsonl":
+	default:
+		return fmt.Errorf("%s: unknown format, expected one of: text, json, jsonl", cmd.Format)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	opts := core.ReviewFindOpts{}
+	if !cmd.All {
+		day := time.Now()
+		if cmd.Date != "" {
+			day, err = date.TimeFromNatural(cmd.Date)
+			if err != nil {
+				return errors.Wrapf(err, "%s: invalid date", cmd.Date)
+			}
+		}
+		// Due until the end of the day.
+		dueBefore := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, time.Local)
+		opts.DueBefore = &dueBefore
+	}
+
+	reviews, err := notebook.FindReviews(opts)
+	if err != nil {
+		return err
+	}
+
+	count := len(reviews)
+	if count > 0 {
+		err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
+			return writeReviews(out, reviews, cmd.Format)
+		})
+	}
+
+	if err == nil && !cmd.Quiet {
+		fmt.Fprintf(os.Stderr, "\nFound %d %s\n", count, strings.Pluralize("note", count))
+	}
+
+	return err
+}
+
+// writeReviews prints the review schedule of the notes in the given format,
+// among text, json and jsonl.
+func writeReviews(out io.Writer, reviews []core.NoteReview, format string) error {
+	switch format {
+	case "json":
+		data, err := json.Marshal(reviews)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(data))
+
+	case "jsonl":
+		for _, review := range reviews {
+			data, err := json.Marshal(review)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, string(data))
+		}
+
+	default:
+		for _, review := range reviews {
+			due := "new"
+			if review.Due != nil {
+				due = "due " + review.Due.Format("2006-01-02")
+			}
+			title := review.Title
+			if title == "" {
+				title = review.Path
+			}
+			fmt.Fprintf(out, "%s: %s (%s)\n", review.Path, title, due)
+		}
+	}
+	return nil
+}
+
+// ReviewRecord schedules the next review of a note.
+type ReviewRecord struct {
+	Path  string `arg help:"Path to the reviewed note."`
+	Grade string `arg help:"How well the note was recalled, among: again, hard, good, easy."`
+}
+
+func (cmd *ReviewRecord) Run(container *cli.Container) error {
+	grade, err := core.ReviewGradeFromString(cmd.Grade)
+	if err != nil {
+		return err
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	review, err := notebook.RecordReview(container.Origin, cmd.Path, grade, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Next review of %s on %s\n", review.Path, review.Due.Format("2006-01-02"))
+	return nil
+}
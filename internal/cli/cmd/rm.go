@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Rm deletes a note, after reporting the links to it.
+type Rm struct {
+	Path       string `arg help:"Path to the note to delete."`
+	RedirectTo string `placeholder:PATH help:"Redirect the links to the deleted note to another note."`
+	Unlink     bool   `short:u help:"Replace the links to the deleted note with their plain text."`
+	DryRun     bool   `short:n help:"Print the links to the note, without deleting it."`
+	Force      bool   `short:f help:"Do not ask for confirmation before deleting a note which is still linked."`
+	Trash      bool   `short:t help:"Move the note to the trash instead, until it is restored or purged."`
+}
+
+func (cmd *Rm) Help() string {
+	return "The notes linking to the deleted note are listed first. Unless --unlink or --redirect-to is used, these links will be dead after deleting the note.\n\nWith --trash, the note is only marked with `status: trash` and hidden from the queries. Restore it with `zk restore`, or let `zk maintenance` purge it after the retention period."
+}
+
+func (cmd *Rm) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	if cmd.Trash {
+		return cmd.trash(notebook, container.Origin)
+	}
+
+	opts := core.RemoveNoteOpts{
+		Path:       cmd.Path,
+		RedirectTo: opt.NewNotEmptyString(cmd.RedirectTo),
+		Unlink:     cmd.Unlink,
+		DryRun:     true,
+		Origin:     container.Origin,
+	}
+
+	removal, err := notebook.RemoveNote(opts)
+	if err != nil {
+		return err
+	}
+
+	for _, link := range removal.Backlinks {
+		fmt.Println(link)
+	}
+	linkCount := len(removal.Backlinks)
+	noteCount := len(removal.NotePaths())
+
+	if cmd.DryRun {
+		fmt.Fprintf(os.Stderr, "\n%s is linked %d %s from %d %s\n",
+			removal.Path,
+			linkCount, strings.Pluralize("time", linkCount),
+			noteCount, strings.Pluralize("note", noteCount),
+		)
+		return nil
+	}
+
+	if linkCount > 0 && !cmd.Force && !cmd.Unlink && opts.RedirectTo.IsNull() {
+		confirmed, skipped := container.Terminal.Confirm(
+			fmt.Sprintf("%s is still linked from %d %s, delete it anyway?", removal.Path, noteCount, strings.Pluralize("note", noteCount)),
+			false,
+		)
+		if skipped {
+			return fmt.Errorf("%s: the note is still linked, use --force to delete it anyway", removal.Path)
+		} else if !confirmed {
+			return nil
+		}
+	}
+
+	opts.DryRun = false
+	removal, err = notebook.RemoveNote(opts)
+	if err != nil {
+		return err
+	}
+
+	verb := "updating"
+	if !cmd.Unlink && opts.RedirectTo.IsNull() {
+		verb = "leaving dead"
+	}
+	fmt.Fprintf(os.Stderr, "\nDeleted %s, %s %d %s in %d %s\n",
+		removal.Path, verb,
+		linkCount, strings.Pluralize("link", linkCount),
+		noteCount, strings.Pluralize("note", noteCount),
+	)
+
+	return nil
+}
+
+func (cmd *Rm) trash(notebook *core.Notebook, origin core.AuditOrigin) error {
+	if cmd.Unlink || cmd.RedirectTo != "" {
+		return errors.New("--trash can't be used with --unlink or --redirect-to, the links to a trashed note are kept")
+	}
+	if cmd.DryRun {
+		return errors.New("--trash can't be used with --dry-run")
+	}
+
+	note, err := notebook.TrashNote(origin, cmd.Path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Moved %s to the trash\n", note.Path)
+	return nil
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+)
+
+// Serve runs local HTTP endpoints to interact with the notebook, e.g. to
+// capture web pages from a browser bookmarklet.
+type Serve struct {
+	Listen      string `default:"localhost:9879" placeholder:ADDRESS help:"Address of the HTTP server."`
+	Capture     bool   `help:"Create notes from the web pages posted at /capture, e.g. by the bookmarklet."`
+	Bookmarklet bool   `help:"Print the bookmarklet posting the current page and selection to the capture endpoint, then exit."`
+	Directory   string `placeholder:PATH help:"Directory in which to create the captured notes."`
+	Group       string `short:g placeholder:NAME help:"Name of the config group the captured notes belong to."`
+	Template    string `placeholder:PATH help:"Custom template used to render the captured notes."`
+}
+
+func (cmd *Serve) Help() string {
+	return "The captured notes contain the selection followed by a link to the page. In the note template, they are available as {{extra.selection}} and {{extra.url}}. The requests are authenticated with a token saved in .zk/capture-token, which is embedded in the bookmarklet."
+}
+
+func (cmd *Serve) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+	if !cmd.Capture {
+		return errors.New("nothing to serve, use --capture to enable the capture endpoint")
+	}
+
+	token, err := captureToken(notebook)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Bookmarklet {
+		fmt.Println(captureBookmarklet("http://"+cmd.Listen+"/capture", token))
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/capture", &captureHandler{
+		notebook: notebook,
+		token:    token,
+		opts: core.NewNoteOpts{
+			Directory: opt.NewNotEmptyString(cmd.Directory),
+			Group:     opt.NewNotEmptyString(cmd.Group),
+			Template:  opt.NewNotEmptyString(cmd.Template),
+			Origin:    container.Origin,
+		},
+	})
+
+	fmt.Fprintf(os.Stderr, "Capturing web pages at http://%s/capture\n", cmd.Listen)
+	return errors.Wrap(http.ListenAndServe(cmd.Listen, mux), "capture server")
+}
+
+// captureToken returns the secret authenticating the capture requests, which
+// is generated the first time. It prevents any web page from creating notes
+// through the local endpoint.
+func captureToken(notebook *core.Notebook) (string, error) {
+	path := filepath.Join(notebook.Path, ".zk", "capture-token")
+	wrap := errors.Wrapperf("%s: failed to read the capture token", path)
+
+	content, err := ioutil.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(content)); token != "" {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", wrap(err)
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", wrap(err)
+	}
+	token := hex.EncodeToString(buf)
+	return token, wrap(ioutil.WriteFile(path, []byte(token+"\n"), 0600))
+}
+
+// captureBookmarklet returns a bookmarklet opening the capture endpoint with
+// the URL, title and selection of the current page.
+func captureBookmarklet(endpoint string, token string) string {
+	script := fmt.Sprintf(`(function(){var p=new URLSearchParams({token:%q,url:location.href,title:document.title,selection:String(getSelection())});window.open(%q+'?'+p,'_blank','width=400,height=200');})()`, token, endpoint)
+	return "javascript:" + url.PathEscape(script)
+}
+
+// captureHandler creates a note from each web page posted by the bookmarklet.
+type captureHandler struct {
+	notebook *core.Notebook
+	token    string
+	opts     core.NewNoteOpts
+	// Creating the notes concurrently could pick the same filename.
+	mutex sync.Mutex
+}
+
+func (h *captureHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.FormValue("token")), []byte(h.token)) != 1 {
+		http.Error(w, "invalid capture token", http.StatusForbidden)
+		return
+	}
+
+	opts := webClipNoteOpts(h.opts, r.FormValue("url"), r.FormValue("title"), r.FormValue("selection"))
+	opts.Date = time.Now()
+
+	h.mutex.Lock()
+	note, err := h.notebook.CaptureNote(core.CaptureNoteOpts{NewNoteOpts: opts})
+	h.mutex.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!DOCTYPE html><title>zk</title><p>Captured %s</p><script>setTimeout(function(){window.close()},1500)</script>\n", html.EscapeString(note.Path))
+}
+
+// webClipNoteOpts returns the options to create a note from a web page and
+// the text selected in it.
+func webClipNoteOpts(opts core.NewNoteOpts, pageURL string, title string, selection string) core.NewNoteOpts {
+	title = strings.TrimSpace(title)
+	selection = strings.TrimSpace(strings.ReplaceAll(selection, "\r\n", "\n"))
+
+	content := selection
+	if pageURL != "" {
+		if content != "" {
+			content += "\n\n"
+		}
+		label := title
+		if label == "" {
+			label = pageURL
+		}
+		content += fmt.Sprintf("[%s](%s)\n", label, pageURL)
+	}
+
+	opts.Title = opts.Title.Or(opt.NewNotEmptyString(title))
+	opts.Content = content
+	opts.Extra = map[string]string{
+		"url":       pageURL,
+		"selection": selection,
+	}
+	return opts
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestWebClipNoteOpts(t *testing.T) {
+	test := func(pageURL, title, selection string, expectedTitle opt.String, expectedContent string, expectedSelection string) {
+		t.Helper()
+		opts := webClipNoteOpts(core.NewNoteOpts{Group: opt.NewString("inbox")}, pageURL, title, selection)
+		assert.Equal(t, opts.Group, opt.NewString("inbox"))
+		assert.Equal(t, opts.Title, expectedTitle)
+		assert.Equal(t, opts.Content, expectedContent)
+		assert.Equal(t, opts.Extra, map[string]string{"url": pageURL, "selection": expectedSelection})
+	}
+
+	test("https://example.com", " A page ", "  Some\r\ntext ", opt.NewString("A page"), "Some\ntext\n\n[A page](https://example.com)\n", "Some\ntext")
+	test("https://example.com", "", "", opt.NullString, "[https://example.com](https://example.com)\n", "")
+	test("", "", "Some text", opt.NullString, "Some text", "Some text")
+}
+
+func TestCaptureHandlerRequiresToken(t *testing.T) {
+	handler := &captureHandler{token: "secret"}
+	test := func(method string, query string, expectedStatus int) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/capture?"+query, nil))
+		assert.Equal(t, rec.Code, expectedStatus)
+	}
+
+	test(http.MethodGet, "url=https://example.com", http.StatusForbidden)
+	test(http.MethodGet, "token=wrong&url=https://example.com", http.StatusForbidden)
+	test(http.MethodDelete, "token=secret", http.StatusMethodNotAllowed)
+}
+
+func TestCaptureBookmarklet(t *testing.T) {
+	bookmarklet := captureBookmarklet("http://localhost:9879/capture", "secret")
+	assert.True(t, strings.HasPrefix(bookmarklet, "javascript:"))
+	script, err := url.PathUnescape(strings.TrimPrefix(bookmarklet, "javascript:"))
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(script, `token:"secret"`))
+	assert.True(t, strings.Contains(script, `window.open("http://localhost:9879/capture"+'?'+p`))
+}
@@ -0,0 +1,410 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	dateutil "github.com/mickael-menu/zk/internal/util/date"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/prometheus"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Stats prints metrics about the health of the notebook, e.g. to monitor it.
+type Stats struct {
+	Format string `group:format short:f placeholder:FORMAT help:"Format of the metrics, among: human, json, prometheus."`
+	Listen string `placeholder:ADDRESS help:"Serve the metrics in the Prometheus text format over HTTP, at /metrics on the given address, e.g. localhost:9877."`
+
+	Activity bool   `help:"Print the writing activity instead: the notes and words created over time, the tag trends, the note length and the link density."`
+	By       string `placeholder:UNIT help:"Granularity of the activity, among: day, week, month (default)."`
+	Since    string `placeholder:DATE help:"Start of the activity period, e.g. 'last month'. Defaults to one year ago."`
+	Until    string `placeholder:DATE help:"End of the activity period, now by default."`
+	Heatmap  bool   `help:"Print the activity as a calendar heat map of the notes created each day."`
+}
+
+func (cmd *Stats) Help() string {
+	return "The age of the index is useful to be alerted when the notebook is not indexed anymore, e.g. because a scheduled zk maintenance fails.\n\n" +
+		"The size reports list the largest notes and directories, and the ones which grew the most during the last 30 days, to keep an eye on bloated sections of the notebook.\n\n" +
+		"The maturity of a note, from 0 to 100, grows with its age, edits, backlinks and length. The least mature notes older than 30 days are likely half-baked ideas worth revisiting.\n\n" +
+		"With --activity or --heatmap, the statistics cover the notes created during the activity period instead."
+}
+
+func (cmd *Stats) Run(container *cli.Container) error {
+	switch cmd.Format {
+	case "", "human", "json", "prometheus":
+	default:
+		return fmt.Errorf("%s: unknown stats format, try human, json or prometheus", cmd.Format)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	if cmd.Listen != "" {
+		return serveMetrics(cmd.Listen, notebook)
+	}
+	if cmd.Activity || cmd.Heatmap || cmd.By != "" || cmd.Since != "" || cmd.Until != "" {
+		return cmd.runActivity(notebook)
+	}
+
+	stats, err := notebook.Stats()
+	if err != nil {
+		return err
+	}
+
+	switch cmd.Format {
+	case "json":
+		out, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	case "prometheus":
+		fmt.Print(formatPrometheusMetrics(notebook.Path, stats, time.Now()))
+	default:
+		fmt.Print(formatHumanStats(stats, time.Now()))
+	}
+	return nil
+}
+
+// statsActivityTags is the number of tags whose trend is reported in the
+// activity stats.
+const statsActivityTags = 10
+
+func (cmd *Stats) runActivity(notebook *core.Notebook) error {
+	if cmd.Format == "prometheus" {
+		return errors.New("the activity stats can't be printed in the prometheus format")
+	}
+
+	opts := core.ActivityStatsOpts{
+		Unit:     core.ActivityMonth,
+		TagCount: statsActivityTags,
+	}
+	var err error
+	if cmd.By != "" {
+		opts.Unit, err = core.ActivityUnitFromString(cmd.By)
+		if err != nil {
+			return err
+		}
+	}
+	opts.End = time.Now()
+	if cmd.Until != "" {
+		opts.End, err = dateutil.TimeFromNatural(cmd.Until)
+		if err != nil {
+			return errors.Wrapf(err, "%s: invalid date", cmd.Until)
+		}
+	}
+	opts.Start = opts.End.AddDate(-1, 0, 0)
+	if cmd.Since != "" {
+		opts.Start, err = dateutil.TimeFromNatural(cmd.Since)
+		if err != nil {
+			return errors.Wrapf(err, "%s: invalid date", cmd.Since)
+		}
+	}
+	if !opts.Start.Before(opts.End) {
+		return errors.New("the start of the activity period must be before its end")
+	}
+
+	stats, err := notebook.ActivityStats(opts)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case cmd.Format == "json":
+		out, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	case cmd.Heatmap:
+		fmt.Print(formatActivityHeatmap(stats))
+	default:
+		fmt.Print(formatHumanActivity(stats))
+	}
+	return nil
+}
+
+// serveMetrics exposes the notebook metrics to Prometheus over HTTP, until
+// the server fails.
+func serveMetrics(address string, notebook *core.Notebook) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		stats, err := notebook.Stats()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprint(w, formatPrometheusMetrics(notebook.Path, stats, time.Now()))
+	})
+
+	return errors.Wrap(http.ListenAndServe(address, mux), "metrics server")
+}
+
+func formatHumanStats(stats core.NotebookStats, now time.Time) string {
+	lastIndexed := "never"
+	if !stats.LastIndexed.IsZero() {
+		lastIndexed = fmt.Sprintf("%s ago, in %v",
+			stats.IndexAge(now).Round(time.Second),
+			stats.LastIndexingDuration.Round(time.Millisecond),
+		)
+	}
+
+	out := &strings.Builder{}
+	fmt.Fprintf(out, `Notes:         %d (%s)
+Trashed notes: %d
+Assets:        %d (%s)
+Dead links:    %d
+Maturity:      %.1f / 100
+Last indexed:  %s
+`, stats.Notes, formatByteSize(stats.NotesSize), stats.TrashedNotes,
+		stats.Assets, formatByteSize(stats.AssetsSize), stats.DeadLinks, stats.Maturity, lastIndexed)
+
+	if len(stats.LargestNotes) > 0 {
+		fmt.Fprintf(out, "\nLargest notes:\n")
+		for _, note := range stats.LargestNotes {
+			fmt.Fprintf(out, "  %9s  %s\n", formatByteSize(note.Size), note.Path)
+		}
+	}
+	if len(stats.LeastMatureNotes) > 0 {
+		fmt.Fprintf(out, "\nLeast mature notes, older than %d days:\n", int(core.StatsGrowthPeriod.Hours()/24))
+		for _, note := range stats.LeastMatureNotes {
+			fmt.Fprintf(out, "  %9.1f  %s\n", note.Maturity, note.Path)
+		}
+	}
+	formatHumanAreas(out, "directories", stats.Dirs)
+	formatHumanAreas(out, "groups", stats.Groups)
+
+	return out.String()
+}
+
+// formatHumanAreas prints the largest areas of the notebook, followed by the
+// ones which grew during the last growth period, from the fastest-growing one.
+func formatHumanAreas(out *strings.Builder, kind string, areas []core.AreaStats) {
+	if len(areas) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\nLargest %s:\n", kind)
+	for _, area := range areas {
+		fmt.Fprintf(out, "  %9s  %s (%d %s, maturity %.1f)\n", formatByteSize(area.Size), area.Name,
+			area.Notes, strutil.Pluralize("note", area.Notes), area.Maturity)
+	}
+
+	growing := []core.AreaStats{}
+	for _, area := range areas {
+		if area.AddedNotes > 0 {
+			growing = append(growing, area)
+		}
+	}
+	if len(growing) == 0 {
+		return
+	}
+	sort.SliceStable(growing, func(i, j int) bool {
+		return growing[i].AddedSize > growing[j].AddedSize
+	})
+	fmt.Fprintf(out, "\nFastest-growing %s, in the last %d days:\n", kind, int(core.StatsGrowthPeriod.Hours()/24))
+	for _, area := range growing {
+		fmt.Fprintf(out, "  %9s  %s (+%d %s)\n", "+"+formatByteSize(area.AddedSize), area.Name,
+			area.AddedNotes, strutil.Pluralize("note", area.AddedNotes))
+	}
+}
+
+// activityDateLayouts are the layouts used to print the start of the
+// activity periods.
+var activityDateLayouts = map[core.ActivityUnit]string{
+	core.ActivityDay:   "2006-01-02",
+	core.ActivityWeek:  "2006-01-02",
+	core.ActivityMonth: "2006-01",
+}
+
+func formatHumanActivity(stats core.ActivityStats) string {
+	out := &strings.Builder{}
+	fmt.Fprintf(out, `Activity from %s to %s:
+Notes: %d
+Words: %d (%.1f per note)
+Links: %d (%.2f per note)
+`, stats.Start.Format("2006-01-02"), stats.End.Format("2006-01-02"),
+		stats.Notes, stats.Words, stats.AverageWords, stats.Links, stats.LinkDensity)
+
+	header := strings.Title(string(stats.Unit))
+	if stats.Unit == core.ActivityWeek {
+		header = "Week of"
+	}
+	fmt.Fprintf(out, "\n%-10s  %6s  %8s  %6s\n", header, "Notes", "Words", "Links")
+	for _, period := range stats.Periods {
+		fmt.Fprintf(out, "%-10s  %6d  %8d  %6d\n",
+			period.Start.Format(activityDateLayouts[stats.Unit]), period.Notes, period.Words, period.Links)
+	}
+
+	if len(stats.Tags) > 0 {
+		width := 0
+		for _, tag := range stats.Tags {
+			if len(tag.Name) > width {
+				width = len(tag.Name)
+			}
+		}
+		fmt.Fprintf(out, "\nTag trends, by %s:\n", stats.Unit)
+		for _, tag := range stats.Tags {
+			fmt.Fprintf(out, "  %-*s  %5d  %s\n", width, tag.Name, tag.Notes, formatSparkline(tag.Periods))
+		}
+	}
+
+	return out.String()
+}
+
+var sparklineRunes = []rune("▁▂▃▄▅▆▇█")
+
+// formatSparkline renders the given counts as a line of bars, scaled to the
+// largest one.
+func formatSparkline(counts []int) string {
+	max := 0
+	for _, count := range counts {
+		if count > max {
+			max = count
+		}
+	}
+	line := []rune{}
+	for _, count := range counts {
+		level := 0
+		if max > 0 {
+			level = count * (len(sparklineRunes) - 1) / max
+		}
+		line = append(line, sparklineRunes[level])
+	}
+	return string(line)
+}
+
+var heatmapRunes = []rune("·░▒▓█")
+
+// formatActivityHeatmap renders the notes created each day of the activity
+// period as a calendar, with a column per week and a row per day of the week.
+func formatActivityHeatmap(stats core.ActivityStats) string {
+	if len(stats.Days) == 0 {
+		return ""
+	}
+	max := 0
+	for _, day := range stats.Days {
+		if day.Notes > max {
+			max = day.Notes
+		}
+	}
+	level := func(notes int) rune {
+		if notes == 0 {
+			return heatmapRunes[0]
+		}
+		return heatmapRunes[(notes*(len(heatmapRunes)-1)+max-1)/max]
+	}
+
+	// The cells of each day of the week, by week.
+	firstWeek := core.ActivityPeriodStart(stats.Days[0].Start, core.ActivityWeek)
+	weeks := int(stats.Days[len(stats.Days)-1].Start.Sub(firstWeek).Hours()/24)/7 + 1
+	rows := make([][]rune, 7)
+	for i := range rows {
+		rows[i] = []rune(strings.Repeat(" ", weeks))
+	}
+	type label struct {
+		week int
+		text string
+	}
+	labels := []label{}
+	for _, day := range stats.Days {
+		// Rounded to ignore the daylight saving time changes.
+		days := int(math.Round(day.Start.Sub(firstWeek).Hours() / 24))
+		week, weekday := days/7, days%7
+		rows[weekday][week] = level(day.Notes)
+
+		// The months are labeled above their first week.
+		if week == 0 && len(labels) == 0 || weekday == 0 && day.Start.Day() <= 7 {
+			labels = append(labels, label{week, day.Start.Format("Jan")})
+		}
+	}
+	months := []rune(strings.Repeat(" ", weeks+3))
+	for i, label := range labels {
+		// Skips the label of a partial first month overlapping the next one.
+		if i+1 < len(labels) && labels[i+1].week-label.week <= len(label.text) {
+			continue
+		}
+		copy(months[label.week:], []rune(label.text))
+	}
+
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "     %s\n", strings.TrimRight(string(months), " "))
+	for i, weekday := range []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"} {
+		fmt.Fprintf(out, "%s  %s\n", weekday, strings.TrimRight(string(rows[i]), " "))
+	}
+	fmt.Fprintf(out, "\n%d %s created from %s to %s, at most %d a day\n",
+		stats.Notes, strutil.Pluralize("note", stats.Notes),
+		stats.Start.Format("2006-01-02"), stats.End.Format("2006-01-02"), max)
+	fmt.Fprintf(out, "Less %s More\n", strings.Join(strings.Split(string(heatmapRunes), ""), " "))
+	return out.String()
+}
+
+// formatByteSize renders a size in bytes with a decimal unit, e.g. 1.2 MB.
+func formatByteSize(size int64) string {
+	if size < 1000 {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size)
+	unit := 0
+	units := []string{"kB", "MB", "GB", "TB"}
+	for value /= 1000; value >= 1000 && unit < len(units)-1; value /= 1000 {
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// formatPrometheusMetrics renders the stats in the Prometheus text exposition
+// format. The metrics are labeled with the path of the notebook, to monitor
+// several notebooks from the same Prometheus server.
+func formatPrometheusMetrics(notebookPath string, stats core.NotebookStats, now time.Time) string {
+	out := prometheus.Writer{}
+	notebookLabel := prometheus.Label{Name: "notebook", Value: notebookPath}
+
+	metric := func(name string, help string, value float64) {
+		out.Header(name, help, "gauge")
+		out.Sample(name, []prometheus.Label{notebookLabel}, value)
+	}
+	// areaMetric reports a metric for each area, labeled with its name.
+	areaMetric := func(name string, help string, label string, areas []core.AreaStats, value func(core.AreaStats) float64) {
+		if len(areas) == 0 {
+			return
+		}
+		out.Header(name, help, "gauge")
+		for _, area := range areas {
+			out.Sample(name, []prometheus.Label{notebookLabel, {Name: label, Value: area.Name}}, value(area))
+		}
+	}
+	areaNotes := func(area core.AreaStats) float64 { return float64(area.Notes) }
+	areaSize := func(area core.AreaStats) float64 { return float64(area.Size) }
+	areaMaturity := func(area core.AreaStats) float64 { return area.Maturity }
+
+	metric("zk_notes", "Number of notes, excluding the trash.", float64(stats.Notes))
+	metric("zk_trashed_notes", "Number of notes in the trash.", float64(stats.TrashedNotes))
+	metric("zk_dead_links", "Number of internal links targeting neither a note nor a file.", float64(stats.DeadLinks))
+	metric("zk_notes_size_bytes", "Total size of the notes, excluding the trash.", float64(stats.NotesSize))
+	metric("zk_assets", "Number of assets, e.g. images or attachments.", float64(stats.Assets))
+	metric("zk_assets_size_bytes", "Total size of the assets.", float64(stats.AssetsSize))
+	metric("zk_notes_maturity", "Average maturity of the notes from 0 to 100, excluding the trash.", stats.Maturity)
+	areaMetric("zk_dir_notes", "Number of notes in a top-level directory.", "dir", stats.Dirs, areaNotes)
+	areaMetric("zk_dir_size_bytes", "Total size of the notes in a top-level directory.", "dir", stats.Dirs, areaSize)
+	areaMetric("zk_dir_maturity", "Average maturity of the notes in a top-level directory.", "dir", stats.Dirs, areaMaturity)
+	areaMetric("zk_group_notes", "Number of notes in a config group.", "group", stats.Groups, areaNotes)
+	areaMetric("zk_group_size_bytes", "Total size of the notes in a config group.", "group", stats.Groups, areaSize)
+	areaMetric("zk_group_maturity", "Average maturity of the notes in a config group.", "group", stats.Groups, areaMaturity)
+	if !stats.LastIndexed.IsZero() {
+		metric("zk_index_age_seconds", "Time elapsed since the last indexing.", stats.IndexAge(now).Seconds())
+		metric("zk_last_index_timestamp_seconds", "Start date of the last indexing, as a Unix timestamp.", float64(stats.LastIndexed.UnixNano())/1e9)
+		metric("zk_last_index_duration_seconds", "Duration of the last indexing.", stats.LastIndexingDuration.Seconds())
+	}
+
+	return out.String()
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestFormatPrometheusMetrics(t *testing.T) {
+	now := time.Date(2021, 10, 12, 15, 0, 0, 0, time.UTC)
+	stats := core.NotebookStats{
+		Notes:                42,
+		TrashedNotes:         3,
+		DeadLinks:            2,
+		LastIndexed:          time.Date(2021, 10, 12, 14, 0, 0, 0, time.UTC),
+		LastIndexingDuration: 1500 * time.Millisecond,
+		NotesSize:            12000,
+		Assets:               4,
+		AssetsSize:           2500000,
+		Maturity:             37.5,
+		Dirs: []core.AreaStats{
+			{Name: "journal", Notes: 30, Size: 9000, Maturity: 30.2},
+			{Name: ".", Notes: 12, Size: 3000, Maturity: 55.6},
+		},
+	}
+
+	assert.Equal(t, formatPrometheusMetrics(`/home/"me"/notes`, stats, now), `# HELP zk_notes Number of notes, excluding the trash.
+# TYPE zk_notes gauge
+zk_notes{notebook="/home/\"me\"/notes"} 42
+# HELP zk_trashed_notes Number of notes in the trash.
+# TYPE zk_trashed_notes gauge
+zk_trashed_notes{notebook="/home/\"me\"/notes"} 3
+# HELP zk_dead_links Number of internal links targeting neither a note nor a file.
+# TYPE zk_dead_links gauge
+zk_dead_links{notebook="/home/\"me\"/notes"} 2
+# HELP zk_notes_size_bytes Total size of the notes, excluding the trash.
+# TYPE zk_notes_size_bytes gauge
+zk_notes_size_bytes{notebook="/home/\"me\"/notes"} 12000
+# HELP zk_assets Number of assets, e.g. images or attachments.
+# TYPE zk_assets gauge
+zk_assets{notebook="/home/\"me\"/notes"} 4
+# HELP zk_assets_size_bytes Total size of the assets.
+# TYPE zk_assets_size_bytes gauge
+zk_assets_size_bytes{notebook="/home/\"me\"/notes"} 2500000
+# HELP zk_notes_maturity Average maturity of the notes from 0 to 100, excluding the trash.
+# TYPE zk_notes_maturity gauge
+zk_notes_maturity{notebook="/home/\"me\"/notes"} 37.5
+# HELP zk_dir_notes Number of notes in a top-level directory.
+# TYPE zk_dir_notes gauge
+zk_dir_notes{notebook="/home/\"me\"/notes",dir="journal"} 30
+zk_dir_notes{notebook="/home/\"me\"/notes",dir="."} 12
+# HELP zk_dir_size_bytes Total size of the notes in a top-level directory.
+# TYPE zk_dir_size_bytes gauge
+zk_dir_size_bytes{notebook="/home/\"me\"/notes",dir="journal"} 9000
+zk_dir_size_bytes{notebook="/home/\"me\"/notes",dir="."} 3000
+# HELP zk_dir_maturity Average maturity of the notes in a top-level directory.
+# TYPE zk_dir_maturity gauge
+zk_dir_maturity{notebook="/home/\"me\"/notes",dir="journal"} 30.2
+zk_dir_maturity{notebook="/home/\"me\"/notes",dir="."} 55.6
+# HELP zk_index_age_seconds Time elapsed since the last indexing.
+# TYPE zk_index_age_seconds gauge
+zk_index_age_seconds{notebook="/home/\"me\"/notes"} 3600
+# HELP zk_last_index_timestamp_seconds Start date of the last indexing, as a Unix timestamp.
+# TYPE zk_last_index_timestamp_seconds gauge
+zk_last_index_timestamp_seconds{notebook="/home/\"me\"/notes"} 1634047200
+# HELP zk_last_index_duration_seconds Duration of the last indexing.
+# TYPE zk_last_index_duration_seconds gauge
+zk_last_index_duration_seconds{notebook="/home/\"me\"/notes"} 1.5
+`)
+}
+
+func TestFormatPrometheusMetricsNeverIndexed(t *testing.T) {
+	assert.Equal(t, formatPrometheusMetrics("/notes", core.NotebookStats{Notes: 1}, time.Now()), `# HELP zk_notes Number of notes, excluding the trash.
+# TYPE zk_notes gauge
+zk_notes{notebook="/notes"} 1
+# HELP zk_trashed_notes Number of notes in the trash.
+# TYPE zk_trashed_notes gauge
+zk_trashed_notes{notebook="/notes"} 0
+# HELP zk_dead_links Number of internal links targeting neither a note nor a file.
+# TYPE zk_dead_links gauge
+zk_dead_links{notebook="/notes"} 0
+# HELP zk_notes_size_bytes Total size of the notes, excluding the trash.
+# TYPE zk_notes_size_bytes gauge
+zk_notes_size_bytes{notebook="/notes"} 0
+# HELP zk_assets Number of assets, e.g. images or attachments.
+# TYPE zk_assets gauge
+zk_assets{notebook="/notes"} 0
+# HELP zk_assets_size_bytes Total size of the assets.
+# TYPE zk_assets_size_bytes gauge
+zk_assets_size_bytes{notebook="/notes"} 0
+# HELP zk_notes_maturity Average maturity of the notes from 0 to 100, excluding the trash.
+# TYPE zk_notes_maturity gauge
+zk_notes_maturity{notebook="/notes"} 0
+`)
+}
+
+func TestFormatHumanStats(t *testing.T) {
+	now := time.Date(2021, 10, 12, 15, 0, 0, 0, time.UTC)
+	stats := core.NotebookStats{
+		Notes:                3,
+		DeadLinks:            1,
+		LastIndexed:          time.Date(2021, 10, 12, 14, 0, 0, 0, time.UTC),
+		LastIndexingDuration: 1500 * time.Millisecond,
+		NotesSize:            15300,
+		Assets:               2,
+		AssetsSize:           1200000,
+		LargestNotes: []core.NoteSize{
+			{Path: "journal/2021-10-01.md", Size: 12000},
+			{Path: "index.md", Size: 3000},
+			{Path: "journal/2021-10-11.md", Size: 300},
+		},
+		Maturity: 41.27,
+		LeastMatureNotes: []core.NoteMaturityScore{
+			{Path: "index.md", Maturity: 62},
+		},
+		Dirs: []core.AreaStats{
+			{Name: "journal", Notes: 2, Size: 12300, AddedNotes: 1, AddedSize: 300, Maturity: 30.4},
+			{Name: ".", Notes: 1, Size: 3000, AddedNotes: 1, AddedSize: 3000, Maturity: 62},
+		},
+	}
+
+	assert.Equal(t, formatHumanStats(stats, now), `Notes:         3 (15.3 kB)
+Trashed notes: 0
+Assets:        2 (1.2 MB)
+Dead links:    1
+Maturity:      41.3 / 100
+Last indexed:  1h0m0s ago, in 1.5s
+
+Largest notes:
+    12.0 kB  journal/2021-10-01.md
+     3.0 kB  index.md
+      300 B  journal/2021-10-11.md
+
+Least mature notes, older than 30 days:
+       62.0  index.md
+
+Largest directories:
+    12.3 kB  journal (2 notes, maturity 30.4)
+     3.0 kB  . (1 note, maturity 62.0)
+
+Fastest-growing directories, in the last 30 days:
+    +3.0 kB  . (+1 note)
+     +300 B  journal (+1 note)
+`)
+}
+
+func TestFormatByteSize(t *testing.T) {
+	test := func(size int64, expected string) {
+		assert.Equal(t, formatByteSize(size), expected)
+	}
+	test(0, "0 B")
+	test(999, "999 B")
+	test(1000, "1.0 kB")
+	test(1234567, "1.2 MB")
+	test(5000000000000000, "5000.0 TB")
+}
+
+func TestFormatSparkline(t *testing.T) {
+	assert.Equal(t, formatSparkline([]int{}), "")
+	assert.Equal(t, formatSparkline([]int{0, 0}), "▁▁")
+	assert.Equal(t, formatSparkline([]int{0, 1, 3, 7, 14}), "▁▁▂▄█")
+}
+
+func TestFormatHumanActivity(t *testing.T) {
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2021, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	stats := core.ActivityStats{
+		Start:        day(9, 27),
+		End:          day(10, 11),
+		Unit:         core.ActivityWeek,
+		Notes:        3,
+		Words:        250,
+		Links:        4,
+		AverageWords: 83.3,
+		LinkDensity:  1.33,
+		Periods: []core.ActivityPeriod{
+			{Start: day(9, 27), Notes: 1, Words: 50, Links: 1},
+			{Start: day(10, 4), Notes: 2, Words: 200, Links: 3},
+		},
+		Tags: []core.TagTrend{
+			{Name: "food", Notes: 3, Periods: []int{1, 2}},
+			{Name: "italy", Notes: 1, Periods: []int{0, 1}},
+		},
+	}
+
+	assert.Equal(t, formatHumanActivity(stats), `Activity from 2021-09-27 to 2021-10-11:
+Notes: 3
+Words: 250 (83.3 per note)
+Links: 4 (1.33 per note)
+
+Week of      Notes     Words   Links
+2021-09-27       1        50       1
+2021-10-04       2       200       3
+
+Tag trends, by week:
+  food       3  ▄█
+  italy      1  ▁█
+`)
+}
+
+func TestFormatActivityHeatmap(t *testing.T) {
+	stats := core.ActivityStats{
+		Start: time.Date(2021, 9, 29, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2021, 10, 19, 0, 0, 0, 0, time.UTC),
+		Notes: 7,
+	}
+	for date := stats.Start; date.Before(stats.End); date = date.AddDate(0, 0, 1) {
+		stats.Days = append(stats.Days, core.ActivityPeriod{Start: date})
+	}
+	stats.Days[0].Notes = 1
+	stats.Days[5].Notes = 4
+	stats.Days[13].Notes = 2
+
+	assert.Equal(t, formatActivityHeatmap(stats), `      Oct
+Mon   █··
+Tue   ·▒
+Wed  ░··
+Thu  ···
+Fri  ···
+Sat  ···
+Sun  ···
+
+7 notes created from 2021-09-29 to 2021-10-19, at most 4 a day
+Less · ░ ▒ ▓ █ More
+`)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// SuggestMocs reports the clusters of linked notes which would benefit from
+// a map of content.
+type SuggestMocs struct {
+	MinSize int    `default:"5" placeholder:COUNT help:"Minimum number of notes in a cluster."`
+	Limit   int    `short:n placeholder:COUNT help:"Report only the given number of largest clusters."`
+	Format  string `group:format short:f placeholder:FORMAT help:"Format of the report, among: human, json."`
+}
+
+func (cmd *SuggestMocs) Help() string {
+	return "Analyzes the links between the notes to find dense clusters which are not organized by a hub note yet, e.g. a map of content (MOC) linking to all of them.\n\n" +
+		"Each cluster is reported with its notes, from the most connected one, and proposed titles derived from their tags and titles. This helps structure emerge in a large and flat notebook."
+}
+
+func (cmd *SuggestMocs) Run(container *cli.Container) error {
+	if cmd.Format != "" && cmd.Format != "human" && cmd.Format != "json" {
+		return fmt.Errorf("%s: unknown suggest-mocs format, try human or json", cmd.Format)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	suggestions, err := notebook.SuggestMOCs(core.SuggestMOCsOpts{MinSize: cmd.MinSize})
+	if err != nil {
+		return err
+	}
+	if cmd.Limit > 0 && len(suggestions) > cmd.Limit {
+		suggestions = suggestions[:cmd.Limit]
+	}
+
+	if cmd.Format == "json" {
+		out, err := json.MarshalIndent(suggestions, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	fmt.Print(formatHumanMOCSuggestions(suggestions))
+	return nil
+}
+
+func formatHumanMOCSuggestions(suggestions []core.MOCSuggestion) string {
+	if len(suggestions) == 0 {
+		return "No cluster lacking a hub note found\n"
+	}
+
+	out := &strings.Builder{}
+	for i, suggestion := range suggestions {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "Cluster of %d notes, %d%% of the possible links\n",
+			len(suggestion.Notes), int(math.Round(suggestion.Density*100)))
+		fmt.Fprintf(out, "  Proposed titles: %s\n", strings.Join(suggestion.Titles, ", "))
+		for _, note := range suggestion.Notes {
+			title := note.Title
+			if title == "" {
+				title = note.Path
+			}
+			fmt.Fprintf(out, "  %s  %s (%d %s)\n", note.Path, title,
+				note.Links, strutil.Pluralize("link", note.Links))
+		}
+	}
+	return out.String()
+}
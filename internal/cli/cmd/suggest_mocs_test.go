@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestFormatHumanMOCSuggestions(t *testing.T) {
+	assert.Equal(t, formatHumanMOCSuggestions([]core.MOCSuggestion{}), "No cluster lacking a hub note found\n")
+
+	assert.Equal(t, formatHumanMOCSuggestions([]core.MOCSuggestion{
+		{
+			Notes: []core.MOCMember{
+				{Path: "a1.md", Title: "Compost basics", Links: 3},
+				{Path: "a2.md", Title: "Compost bins", Links: 1},
+			},
+			Density: 14.0 / 30.0,
+			Titles:  []string{"Gardening", "Compost"},
+		},
+		{
+			Notes:   []core.MOCMember{{Path: "c1.md", Links: 2}},
+			Density: 1,
+			Titles:  []string{"C1"},
+		},
+	}), `Cluster of 2 notes, 47% of the possible links
+  Proposed titles: Gardening, Compost
+  a1.md  Compost basics (3 links)
+  a2.md  Compost bins (1 link)
+
+Cluster of 1 notes, 100% of the possible links
+  Proposed titles: C1
+  c1.md  c1.md (2 links)
+`)
+}
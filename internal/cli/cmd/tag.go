@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	gostrings "strings"
 
 	"github.com/mickael-menu/zk/internal/cli"
 	"github.com/mickael-menu/zk/internal/core"
@@ -13,12 +14,13 @@ import (
 
 // Tag manages the note tags in the notebook.
 type Tag struct {
-	List TagList `cmd group:"cmd" default:"withargs" help:"List all the note tags."`
+	List      TagList      `cmd group:"cmd" default:"withargs" help:"List all the note tags."`
+	Normalize TagNormalize `cmd group:"cmd" help:"Rewrite the aliased tags in the notes with their canonical name."`
 }
 
 // TagList lists all the note tags.
 type TagList struct {
-	Format     string   `group:format short:f placeholder:TEMPLATE   help:"Pretty print the list using a custom template or one of the predefined formats: name, full, json, jsonl."`
+	Format     string   `group:format short:f placeholder:TEMPLATE   help:"Pretty print the list using a custom template or one of the predefined formats: name, full, tree, json, jsonl."`
 	Header     string   `group:format                                help:"Arbitrary text printed at the start of the list."`
 	Footer     string   `group:format default:\n                     help:"Arbitrary text printed at the end of the list."`
 	Delimiter  string   "group:format short:d default:\n             help:\"Print tags delimited by the given separator.\""
@@ -100,16 +102,24 @@ func (cmd *TagList) Run(container *cli.Container) error {
 			if cmd.Header != "" {
 				fmt.Fprint(out, cmd.Header)
 			}
-			for i, tag := range tags {
-				if i > 0 {
-					fmt.Fprint(out, cmd.Delimiter)
-				}
-
-				ft, err := format(tag)
+			if cmd.Format == "tree" {
+				lines, err := tagTreeLines(core.NewTagTrees(tags), format, notebook.Config)
 				if err != nil {
 					return err
 				}
-				fmt.Fprint(out, ft)
+				fmt.Fprint(out, gostrings.Join(lines, cmd.Delimiter))
+			} else {
+				for i, tag := range tags {
+					if i > 0 {
+						fmt.Fprint(out, cmd.Delimiter)
+					}
+
+					ft, err := format(tag)
+					if err != nil {
+						return err
+					}
+					fmt.Fprint(out, ft)
+				}
 			}
 			if cmd.Footer != "" {
 				fmt.Fprint(out, cmd.Footer)
@@ -145,4 +155,105 @@ var defaultTagFormats = map[string]string{
 	"jsonl": `{{json .}}`,
 	"name":  `{{name}}`,
 	"full":  `{{name}} ({{note-count}})`,
+	// Line of a tag in the hierarchy, with the last segment of its name.
+	"tree": `{{name}}{{#if note-count}} ({{note-count}}){{/if}}`,
+}
+
+// tagTreeLines formats the tags as a tree, one line per tag below its parent.
+// The name of a tag is the last segment of its full name.
+func tagTreeLines(trees []core.TagTree, format core.CollectionFormatter, config core.Config) ([]string, error) {
+	var nodes func(trees []core.TagTree) ([]treeNode, error)
+	nodes = func(trees []core.TagTree) ([]treeNode, error) {
+		res := []treeNode{}
+		for _, tree := range trees {
+			style := config.TagStyle(tree.Path)
+			label, err := format(core.Collection{
+				Kind:      core.CollectionKindTag,
+				Name:      tree.Name,
+				NoteCount: tree.NoteCount,
+				Color:     style.Color,
+				Icon:      style.Icon,
+			})
+			if err != nil {
+				return nil, err
+			}
+			children, err := nodes(tree.Children)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, treeNode{label: label, children: children})
+		}
+		return res, nil
+	}
+
+	roots, err := nodes(trees)
+	if err != nil {
+		return nil, err
+	}
+	return treeLines(roots), nil
+}
+
+// TagNormalize rewrites the aliased tags in the notes.
+type TagNormalize struct {
+	Write bool `help:"Modify the notes, instead of only printing the changes."`
+	Quiet bool `short:q help:"Do not print the changes."`
+	cli.Filtering
+}
+
+func (cmd *TagNormalize) Help() string {
+	return "The tag aliases are declared in the [tag-alias] section of the config, e.g. golang = \"go\". They are already merged in the index, this command rewrites them in the notes themselves."
+}
+
+func (cmd *TagNormalize) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+	if len(notebook.Config.TagAliases) == 0 {
+		return errors.New("no tag aliases declared in the [tag-alias] config section")
+	}
+
+	findOpts, err := cmd.Filtering.NewNoteFindOpts(notebook)
+	if err != nil {
+		return errors.Wrapf(err, "incorrect criteria")
+	}
+	notes, err := notebook.FindMinimalNotes(findOpts)
+	if err != nil {
+		return err
+	}
+	paths := []string{}
+	for _, note := range notes {
+		paths = append(paths, note.Path)
+	}
+
+	replacements, err := notebook.NormalizeTags(core.NormalizeTagsOpts{
+		Paths:  paths,
+		DryRun: !cmd.Write,
+		Origin: container.Origin,
+	})
+	if err != nil {
+		return err
+	}
+
+	changeCount := 0
+	for _, replacement := range replacements {
+		changeCount += len(replacement.Changes)
+		if !cmd.Quiet {
+			printReplacementDiff(os.Stdout, container.Terminal, replacement)
+		}
+	}
+
+	noteCount := len(replacements)
+	if cmd.Write {
+		fmt.Fprintf(os.Stderr, "Normalized the tags of %d %s in %d %s\n",
+			changeCount, strings.Pluralize("line", changeCount),
+			noteCount, strings.Pluralize("note", noteCount),
+		)
+	} else {
+		fmt.Fprintf(os.Stderr, "Would normalize the tags of %d %s in %d %s, use --write to modify the notes\n",
+			changeCount, strings.Pluralize("line", changeCount),
+			noteCount, strings.Pluralize("note", noteCount),
+		)
+	}
+	return nil
 }
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestTagTreeLines(t *testing.T) {
+	lines, err := tagTreeLines(core.NewTagTrees([]core.Collection{
+		{Name: "inbox", NoteCount: 4},
+		{Name: "project/alpha", NoteCount: 3},
+		{Name: "project/beta/v2", NoteCount: 1},
+	}), func(tag core.Collection) (string, error) {
+		return fmt.Sprintf("%s (%d)", tag.Name, tag.NoteCount), nil
+	}, core.NewDefaultConfig())
+
+	assert.Nil(t, err)
+	assert.Equal(t, lines, []string{
+		"inbox (4)",
+		"project (0)",
+		"├── alpha (3)",
+		"└── beta (0)",
+		"    └── v2 (1)",
+	})
+}
+
+func TestTagTreeLinesWithStyles(t *testing.T) {
+	config := core.NewDefaultConfig()
+	config.TagStyles = map[string]core.TagStyle{
+		"project":      {Icon: "📁"},
+		"project/beta": {Icon: "🅱"},
+	}
+
+	lines, err := tagTreeLines(core.NewTagTrees([]core.Collection{
+		{Name: "inbox", NoteCount: 4},
+		{Name: "project/alpha", NoteCount: 3},
+		{Name: "project/beta/v2", NoteCount: 1},
+	}), func(tag core.Collection) (string, error) {
+		return fmt.Sprintf("%s%s", tag.Icon, tag.Name), nil
+	}, config)
+
+	assert.Nil(t, err)
+	assert.Equal(t, lines, []string{
+		"inbox",
+		"📁project",
+		"├── 📁alpha",
+		"└── 🅱beta",
+		"    └── 🅱v2",
+	})
+}
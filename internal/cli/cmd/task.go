@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/date"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// Task manages the checkbox items of the notes.
+type Task struct {
+	List TaskList `cmd group:"cmd" default:"withargs" help:"List the tasks found in the notes."`
+}
+
+// TaskList lists the checkbox items of the notes.
+type TaskList struct {
+	Open      bool     `short:o help:"Only the tasks which are not done."`
+	DueBefore string   `placeholder:DATE help:"Only the tasks due before the given date, e.g. tomorrow or 2021-10-12."`
+	Note      []string `short:n placeholder:PATH help:"Only the tasks of the given notes."`
+	Format    string   `group:format short:f placeholder:FORMAT help:"Format of the list, among: text, json, jsonl."`
+	NoPager   bool     `group:format short:P help:"Do not pipe output into a pager."`
+	Quiet     bool     `group:format short:q help:"Do not print the total number of tasks found."`
+}
+
+func (cmd *TaskList) Help() string {
+	return "Tasks are the list items starting with a checkbox, e.g. `- [ ] Call Bob`. Their due date is given with an annotation such as `due:2021-10-12`, `@due(2021-10-12)` or `📅 2021-10-12`."
+}
+
+func (cmd *TaskList) Run(container *cli.Container) error {
+	switch cmd.Format {
+	case "", "text", "json", "jsonl":
+	default:
+		return fmt.Errorf("%s: unknown format, expected one of: text, json, jsonl", cmd.Format)
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	opts := core.TaskFindOpts{Open: cmd.Open}
+	if cmd.DueBefore != "" {
+		dueBefore, err := date.TimeFromNatural(cmd.DueBefore)
+		if err != nil {
+			return errors.Wrapf(err, "%s: invalid date", cmd.DueBefore)
+		}
+		// The due dates are days, without time.
+		dueBefore = time.Date(dueBefore.Year(), dueBefore.Month(), dueBefore.Day(), 0, 0, 0, 0, time.Local)
+		opts.DueBefore = &dueBefore
+	}
+	for _, path := range cmd.Note {
+		path, err := notebook.RelPath(path)
+		if err != nil {
+			return err
+		}
+		opts.Paths = append(opts.Paths, path)
+	}
+
+	tasks, err := notebook.FindTasks(opts)
+	if err != nil {
+		return err
+	}
+
+	count := len(tasks)
+	if count > 0 {
+		err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
+			return writeTasks(out, tasks, cmd.Format)
+		})
+	}
+
+	if err == nil && !cmd.Quiet {
+		fmt.Fprintf(os.Stderr, "\nFound %d %s\n", count, strings.Pluralize("task", count))
+	}
+
+	return err
+}
+
+// writeTasks prints the tasks in the given format, among text, json and
+// jsonl.
+func writeTasks(out io.Writer, tasks []core.NoteTask, format string) error {
+	switch format {
+	case "json":
+		data, err := json.Marshal(tasks)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(data))
+
+	case "jsonl":
+		for _, task := range tasks {
+			data, err := json.Marshal(task)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, string(data))
+		}
+
+	default:
+		for _, task := range tasks {
+			check := " "
+			if task.Done {
+				check = "x"
+			}
+			fmt.Fprintf(out, "%s:%d: [%s] %s\n", task.Path, task.Line, check, task.Text)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mickael-menu/zk/internal/cli"
+)
+
+// TOC prints or updates the table of contents of a note.
+type TOC struct {
+	Path  string `arg help:"Path to the note."`
+	Write bool   `short:w help:"Write the table of contents in the note, between the <!-- zk:toc --> and <!-- zk:toc:end --> markers."`
+	Depth int    `short:d placeholder:LEVELS help:"Maximum number of heading levels listed."`
+}
+
+func (cmd *TOC) Help() string {
+	return "Without --write, the table of contents is printed. The markers are inserted after the title of the note when missing."
+}
+
+func (cmd *TOC) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	if !cmd.Write {
+		toc, err := notebook.NoteTableOfContents(cmd.Path, cmd.Depth)
+		if err != nil {
+			return err
+		}
+		fmt.Print(toc)
+		return nil
+	}
+
+	changed, err := notebook.WriteTableOfContents(cmd.Path, cmd.Depth)
+	if err != nil {
+		return err
+	}
+	if changed {
+		fmt.Fprintf(os.Stderr, "Updated the table of contents of %s\n", cmd.Path)
+	}
+	return nil
+}
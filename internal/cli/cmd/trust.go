@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+)
+
+// Trust allows a notebook to run the shell commands defined in its config
+// and templates, e.g. aliases or {{sh}} helpers.
+type Trust struct {
+	Path   string `arg optional type:path placeholder:PATH help:"Path to the notebook, defaults to the current one."`
+	Revoke bool   `help:"Forbid the notebook to run shell commands."`
+}
+
+func (cmd *Trust) Help() string {
+	return "A notebook cloned from someone else could run arbitrary commands on your computer. zk asks before running the shell commands of a notebook for the first time, and ignores them when the notebook is not trusted."
+}
+
+func (cmd *Trust) Run(container *cli.Container) error {
+	var (
+		notebook *core.Notebook
+		err      error
+	)
+	if cmd.Path != "" {
+		notebook, err = container.Notebooks.Open(cmd.Path)
+	} else {
+		notebook, err = container.CurrentNotebook()
+	}
+	if err != nil {
+		return err
+	}
+
+	err = container.TrustStore.SetTrusted(notebook.Path, !cmd.Revoke)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Revoke {
+		fmt.Fprintf(os.Stderr, "The notebook at %s is not trusted anymore\n", notebook.Path)
+	} else {
+		fmt.Fprintf(os.Stderr, "The notebook at %s is now trusted\n", notebook.Path)
+	}
+	return nil
+}
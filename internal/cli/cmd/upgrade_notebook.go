@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// UpgradeNotebook rewrites the settings and template constructs of the
+// notebook deprecated by previous versions of zk.
+type UpgradeNotebook struct {
+	DryRun bool `short:n help:"Print the changes as a diff, without modifying any file."`
+	Quiet  bool `short:q help:"Do not print the changes."`
+}
+
+func (cmd *UpgradeNotebook) Help() string {
+	return "The notebook config in .zk/config.toml and the templates in .zk/templates are rewritten in place, keeping their comments and formatting. The global config is left untouched."
+}
+
+func (cmd *UpgradeNotebook) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	upgrade, err := notebook.Upgrade(core.UpgradeNotebookOpts{DryRun: cmd.DryRun})
+	if err != nil {
+		return err
+	}
+
+	if len(upgrade.Files) == 0 {
+		fmt.Fprintln(os.Stderr, "The notebook is up to date")
+		return nil
+	}
+
+	changeCount := 0
+	for _, file := range upgrade.Files {
+		changeCount += len(file.Changes)
+		if !cmd.Quiet {
+			printReplacementDiff(os.Stdout, container.Terminal, file)
+		}
+	}
+
+	for _, applied := range upgrade.Applied {
+		fmt.Fprintf(os.Stderr, "zk %s %s (%d %s)\n",
+			applied.Version, applied.Description,
+			applied.Count, strutil.Pluralize("occurrence", applied.Count),
+		)
+	}
+
+	verb := "Upgraded"
+	if cmd.DryRun {
+		verb = "Would upgrade"
+	}
+	fileCount := len(upgrade.Files)
+	fmt.Fprintf(os.Stderr, "%s %d %s in %d %s\n",
+		verb,
+		changeCount, strutil.Pluralize("line", changeCount),
+		fileCount, strutil.Pluralize("file", fileCount),
+	)
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/cli"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// VerifyLinks reports the dead links of the notebook, and fixes them
+// interactively.
+type VerifyLinks struct {
+	Fix    bool `help:"Walk through the dead links to replace them with one of the suggested notes."`
+	DryRun bool `short:n help:"Print the links which would be fixed, without modifying any file."`
+	Quiet  bool `short:q help:"Do not print the suggested notes nor the fixed links."`
+}
+
+func (cmd *VerifyLinks) Help() string {
+	return "The notes whose title or path are similar to the target of a dead link are suggested as replacements. With --fix, you are prompted to pick one of them for each dead link, and all the chosen replacements are written at the end. A dead link repeated in several notes is only prompted once.\n\n" +
+		"Exits with an error status if any dead link remains."
+}
+
+func (cmd *VerifyLinks) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	links, err := notebook.FindDeadLinks()
+	if err != nil {
+		return err
+	}
+
+	if !cmd.Fix {
+		for _, link := range links {
+			fmt.Printf("%s:%d: dead link to %s\n", link.Path, link.Line, link.Href)
+			if !cmd.Quiet && len(link.Candidates) > 0 {
+				fmt.Printf("  did you mean %s?\n", formatCandidates(link.Candidates))
+			}
+		}
+		return reportDeadLinks(len(links))
+	}
+
+	if len(links) > 0 && !container.Terminal.IsInteractive() {
+		return errors.New("zk verify-links --fix needs an interactive terminal to choose the replacements")
+	}
+
+	fixes := []core.DeadLinkFix{}
+	groups := groupDeadLinks(links)
+	for i, group := range groups {
+		link := group[0]
+		if len(link.Candidates) == 0 {
+			fmt.Fprintf(os.Stderr, "%s:%d: no note similar to %s, skipped\n", link.Path, link.Line, link.Href)
+			continue
+		}
+
+		msg := fmt.Sprintf("[%d/%d] %s:%d: dead link to %s", i+1, len(groups), link.Path, link.Line, link.Href)
+		if len(group) > 1 {
+			msg += fmt.Sprintf(" (%d occurrences)", len(group))
+		}
+		options := []string{}
+		for _, candidate := range link.Candidates {
+			options = append(options, formatCandidate(candidate))
+		}
+		skipIndex := len(options)
+		stopIndex := skipIndex + 1
+		options = append(options, "Skip this link", "Stop and apply the chosen fixes")
+
+		choice, ok := container.Terminal.Select(msg, options, 0)
+		if !ok {
+			// Interrupted, nothing is written.
+			return errors.New("the dead links were not fixed")
+		}
+		if choice == stopIndex {
+			break
+		}
+		if choice == skipIndex {
+			continue
+		}
+		for _, link := range group {
+			fixes = append(fixes, core.DeadLinkFix{
+				Link:       link,
+				TargetPath: link.Candidates[choice].Path,
+			})
+		}
+	}
+
+	rewritten, err := notebook.FixDeadLinks(container.Origin, fixes, cmd.DryRun)
+	if err != nil {
+		return err
+	}
+	if !cmd.Quiet {
+		for _, link := range rewritten {
+			fmt.Println(link)
+		}
+	}
+
+	noteCount := 0
+	for i, link := range rewritten {
+		if i == 0 || rewritten[i-1].Path != link.Path {
+			noteCount++
+		}
+	}
+	verb := "Fixed"
+	if cmd.DryRun {
+		verb = "Would fix"
+	}
+	fmt.Fprintf(os.Stderr, "\n%s %d %s in %d %s\n",
+		verb,
+		len(rewritten), strutil.Pluralize("link", len(rewritten)),
+		noteCount, strutil.Pluralize("note", noteCount),
+	)
+
+	if cmd.DryRun {
+		return reportDeadLinks(len(links))
+	}
+	remaining, err := notebook.FindDeadLinks()
+	if err != nil {
+		return err
+	}
+	return reportDeadLinks(len(remaining))
+}
+
+// groupDeadLinks groups the dead links with the same target, to fix them
+// together. The order of the links is kept.
+func groupDeadLinks(links []core.DeadLink) [][]core.DeadLink {
+	groups := [][]core.DeadLink{}
+	indexes := map[string]int{}
+	for _, link := range links {
+		if i, ok := indexes[link.Target]; ok {
+			groups[i] = append(groups[i], link)
+			continue
+		}
+		indexes[link.Target] = len(groups)
+		groups = append(groups, []core.DeadLink{link})
+	}
+	return groups
+}
+
+func formatCandidate(note core.MinimalNote) string {
+	if note.Title == "" {
+		return note.Path
+	}
+	return fmt.Sprintf("%s (%s)", note.Title, note.Path)
+}
+
+func formatCandidates(notes []core.MinimalNote) string {
+	formatted := []string{}
+	for _, note := range notes {
+		formatted = append(formatted, formatCandidate(note))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// reportDeadLinks prints the number of dead links, and returns an error if
+// there are any.
+func reportDeadLinks(count int) error {
+	fmt.Fprintf(os.Stderr, "Found %d dead %s\n", count, strutil.Pluralize("link", count))
+	if count > 0 {
+		return errors.New("the notebook has dead links")
+	}
+	return nil
+}
@@ -4,10 +4,14 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/mickael-menu/zk/internal/adapter/audit"
+	"github.com/mickael-menu/zk/internal/adapter/bibliography"
 	"github.com/mickael-menu/zk/internal/adapter/editor"
 	"github.com/mickael-menu/zk/internal/adapter/fs"
 	"github.com/mickael-menu/zk/internal/adapter/fzf"
+	"github.com/mickael-menu/zk/internal/adapter/git"
 	"github.com/mickael-menu/zk/internal/adapter/handlebars"
 	hbhelpers "github.com/mickael-menu/zk/internal/adapter/handlebars/helpers"
 	"github.com/mickael-menu/zk/internal/adapter/markdown"
@@ -28,19 +32,25 @@ type Dirs struct {
 }
 
 type Container struct {
-	Version            string
-	Config             core.Config
-	Logger             *util.ProxyLogger
-	Terminal           *term.Terminal
-	FS                 *fs.FileStorage
-	TemplateLoader     core.TemplateLoader
-	WorkingDir         string
-	Notebooks          *core.NotebookStore
+	Version        string
+	Config         core.Config
+	Logger         *util.ProxyLogger
+	Terminal       *term.Terminal
+	FS             *fs.FileStorage
+	TemplateLoader core.TemplateLoader
+	WorkingDir     string
+	Notebooks      *core.NotebookStore
+	TrustStore     *TrustStore
+	// Origin identifies the command being run, for the audit log and the
+	// hooks. It is set once the command line is parsed.
+	Origin             core.AuditOrigin
 	currentNotebook    *core.Notebook
 	currentNotebookErr error
 }
 
-func NewContainer(version string) (*Container, error) {
+// NewContainer creates the dependency graph of the CLI, using the settings of
+// the given config profile if not empty.
+func NewContainer(version string, profile string) (*Container, error) {
 	wrap := errors.Wrapper("initialization")
 
 	term := term.New()
@@ -69,68 +79,134 @@ func NewContainer(version string) (*Container, error) {
 			return nil, wrap(err)
 		}
 	}
+	config, err = config.WithProfile(profile)
+	if err != nil {
+		return nil, wrap(err)
+	}
 
-	return &Container{
+	container := &Container{
 		Version:        version,
 		Config:         config,
 		Logger:         logger,
 		Terminal:       term,
 		FS:             fs,
 		TemplateLoader: templateLoader,
-		Notebooks: core.NewNotebookStore(config, core.NotebookStorePorts{
-			FS:             fs,
-			TemplateLoader: templateLoader,
-			NotebookFactory: func(path string, config core.Config) (*core.Notebook, error) {
-				dbPath := filepath.Join(path, ".zk/notebook.db")
-				db, err := sqlite.Open(dbPath)
-				if err != nil {
-					return nil, err
-				}
-
-				notebook := core.NewNotebook(path, config, core.NotebookPorts{
-					NoteIndex: sqlite.NewNoteIndex(db, logger),
-					NoteContentParser: markdown.NewParser(
-						markdown.ParserOpts{
-							HashtagEnabled:      config.Format.Markdown.Hashtags,
-							MultiWordTagEnabled: config.Format.Markdown.MultiwordTags,
-							ColontagEnabled:     config.Format.Markdown.ColonTags,
-						},
-						logger,
-					),
-					TemplateLoaderFactory: func(language string) (core.TemplateLoader, error) {
-						loader := handlebars.NewLoader(handlebars.LoaderOpts{
-							LookupPaths: []string{
-								filepath.Join(globalConfigDir(), "templates"),
-								filepath.Join(path, ".zk/templates"),
-							},
-							Styler: styler,
-						})
+		TrustStore:     NewTrustStore(filepath.Join(globalConfigDir(), "trusted-notebooks.json")),
+	}
+
+	container.Notebooks = core.NewNotebookStore(config, core.NotebookStorePorts{
+		FS:             fs,
+		TemplateLoader: templateLoader,
+		TrustNotebook:  container.trustNotebook,
+		NotebookFactory: func(path string, config core.Config) (*core.Notebook, error) {
+			dbPath := filepath.Join(path, ".zk/notebook.db")
+			db, err := sqlite.Open(dbPath, sqlite.OpenOpts{
+				WAL:         config.Index.WAL,
+				BusyTimeout: config.Index.BusyTimeout,
+				LockRetries: config.Index.LockRetries,
+				Tokenizer:   config.Search.Tokenizer,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			index := sqlite.NewNoteIndex(db, logger)
+			templateDirs := []string{
+				filepath.Join(globalConfigDir(), "templates"),
+				filepath.Join(path, ".zk/templates"),
+			}
+			parser := markdown.NewParser(
+				markdown.ParserOpts{
+					HashtagEnabled:      config.Format.Markdown.Hashtags,
+					MultiWordTagEnabled: config.Format.Markdown.MultiwordTags,
+					ColontagEnabled:     config.Format.Markdown.ColonTags,
+					WikiLinkAliasFirst:  config.Format.Markdown.WikiLinkAliasFirst,
+				},
+				logger,
+			)
+			notebook := core.NewNotebook(path, config, core.NotebookPorts{
+				NoteIndex:           index,
+				NoteContentParser:   parser,
+				NoteContentRenderer: parser,
+				TemplateLoaderFactory: func(language string) (core.TemplateLoader, error) {
+					loader := handlebars.NewLoader(handlebars.LoaderOpts{
+						LookupPaths: templateDirs,
+						Styler:      styler,
+					})
 
-						loader.RegisterHelper("style", hbhelpers.NewStyleHelper(styler, logger))
-						loader.RegisterHelper("slug", hbhelpers.NewSlugHelper(language, logger))
+					loader.RegisterHelper("style", hbhelpers.NewStyleHelper(styler, logger))
+					loader.RegisterHelper("slug", hbhelpers.NewSlugHelper(language, logger))
 
-						linkFormatter, err := core.NewLinkFormatter(config.Format.Markdown, loader)
-						if err != nil {
+					linkFormatter, err := core.NewLinkFormatter(config.Format.Markdown, loader)
+					if err != nil {
+						return nil, err
+					}
+					loader.RegisterHelper("format-link", hbhelpers.NewLinkHelper(linkFormatter, logger))
+					loader.RegisterHelper("url-title", hbhelpers.NewURLTitleHelper(index.FindURLMetadata, logger))
+					loader.RegisterHelper("slug-id", hbhelpers.NewSlugIDHelper(language, logger))
+					loader.RegisterHelper("rel-path", hbhelpers.NewRelPathHelper(path, logger))
+					loader.RegisterHelper("link-count", hbhelpers.NewLinkCountHelper(func(notePath string) (int, error) {
+						if filepath.IsAbs(notePath) {
+							relPath, err := filepath.Rel(path, notePath)
+							if err != nil {
+								return 0, err
+							}
+							notePath = relPath
+						}
+						notes, err := index.FindMinimal(core.NoteFindOpts{
+							LinkTo: &core.LinkFilter{Paths: []string{notePath}},
+						})
+						return len(notes), err
+					}, logger))
+					loader.RegisterHelper("review-due", hbhelpers.NewReviewDueHelper(func(notePath string) (*time.Time, error) {
+						if filepath.IsAbs(notePath) {
+							relPath, err := filepath.Rel(path, notePath)
+							if err != nil {
+								return nil, err
+							}
+							notePath = relPath
+						}
+						reviews, err := index.FindReviews(core.ReviewFindOpts{Paths: []string{notePath}})
+						if err != nil || len(reviews) == 0 {
 							return nil, err
 						}
-						loader.RegisterHelper("format-link", hbhelpers.NewLinkHelper(linkFormatter, logger))
-
-						return loader, nil
-					},
-					IDGeneratorFactory: func(opts core.IDOptions) func() string {
-						return rand.NewIDGenerator(opts)
-					},
-					FS:     fs,
-					Logger: logger,
-					OSEnv: func() map[string]string {
-						return osutil.Env()
-					},
-				})
-
-				return notebook, nil
-			},
-		}),
-	}, nil
+						if due := reviews[0].Due; due != nil {
+							return due, nil
+						}
+						// Never reviewed, so due right away.
+						now := time.Now()
+						return &now, nil
+					}, logger))
+
+					for name, helper := range config.Helpers {
+						loader.RegisterHelper(name, hbhelpers.NewCustomHelper(name, helper, loader, logger))
+					}
+					if config.ShellDisabled {
+						loader.RegisterHelper("sh", hbhelpers.NewUntrustedShellHelper(logger))
+					}
+
+					return loader, nil
+				},
+				IDGeneratorFactory: func(opts core.IDOptions) func() string {
+					return rand.NewIDGenerator(opts)
+				},
+				FS:     fs,
+				Logger: logger,
+				OSEnv: func() map[string]string {
+					return osutil.Env()
+				},
+				AuditLog:           audit.NewFileLog(filepath.Join(path, ".zk/audit.log"), logger),
+				IndexEventLog:      audit.NewIndexEventFileLog(filepath.Join(path, ".zk/events.log"), logger),
+				VersionControl:     git.NewRepo(path),
+				BibliographyParser: bibliography.Parse,
+				TemplateDirs:       templateDirs,
+			})
+
+			return notebook, nil
+		},
+	})
+
+	return container, nil
 }
 
 // locateGlobalConfig looks for the global zk config file following the
@@ -208,7 +284,7 @@ func (c *Container) NewNoteFilter(opts fzf.NoteFilterOpts) *fzf.NoteFilter {
 }
 
 func (c *Container) NewNoteEditor(notebook *core.Notebook) (*editor.Editor, error) {
-	return editor.NewEditor(notebook.Config.Tool.Editor)
+	return editor.NewEditor(notebook.Config.Tool.Editor, notebook.Config.Tool.EditorSplit)
 }
 
 // Paginate creates an auto-closing io.Writer which will be automatically
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"encoding/json"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// Exit statuses of zk, to let scripts branch on the kind of failure.
+const (
+	// Any other failure.
+	ExitFailure = 1
+	// The filtering options didn't match any note.
+	ExitNoResults = 2
+	// No notebook was found in the working directory or its parents.
+	ExitNotebookNotFound = 3
+	// The given note doesn't exist in the notebook.
+	ExitNoteNotFound = 4
+	// The notebook index is locked by another process.
+	ExitIndexLocked = 5
+	// A template could not be loaded or rendered.
+	ExitTemplateError = 6
+	// The notes could not be indexed.
+	ExitIndexError = 7
+)
+
+// ErrNoResults is returned by the commands which found no note matching the
+// filtering options. It is not reported as an error, only with the exit
+// status.
+var ErrNoResults = errors.New("no notes found")
+
+// exitKinds are stable identifiers of the exit statuses, printed with
+// --error-format json.
+var exitKinds = map[int]string{
+	ExitFailure:          "failure",
+	ExitNoResults:        "no-results",
+	ExitNotebookNotFound: "notebook-not-found",
+	ExitNoteNotFound:     "note-not-found",
+	ExitIndexLocked:      "index-locked",
+	ExitTemplateError:    "template-error",
+	ExitIndexError:       "index-error",
+}
+
+// ExitCode returns the exit status matching the kind of the given error.
+func ExitCode(err error) int {
+	var (
+		notebookNotFound core.ErrNotebookNotFound
+		noteNotFound     core.ErrNoteNotFound
+		indexLocked      core.ErrIndexLocked
+		templateErr      core.ErrTemplate
+		indexingErr      core.ErrIndexing
+	)
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrNoResults):
+		return ExitNoResults
+	case errors.As(err, &notebookNotFound):
+		return ExitNotebookNotFound
+	case errors.As(err, &noteNotFound):
+		return ExitNoteNotFound
+	case errors.As(err, &indexLocked):
+		return ExitIndexLocked
+	case errors.As(err, &templateErr):
+		return ExitTemplateError
+	case errors.As(err, &indexingErr):
+		return ExitIndexError
+	default:
+		return ExitFailure
+	}
+}
+
+// ErrorJSON renders the given error as a JSON object, to be parsed by
+// scripts and editor plugins. For example:
+// {"status":3,"kind":"notebook-not-found","message":"failed to open notebook: ..."}
+func ErrorJSON(err error) string {
+	status := ExitCode(err)
+	// Marshalling strings and integers can't fail.
+	out, _ := json.Marshal(struct {
+		Status  int    `json:"status"`
+		Kind    string `json:"kind"`
+		Message string `json:"message"`
+	}{
+		Status:  status,
+		Kind:    exitKinds[status],
+		Message: err.Error(),
+	})
+	return string(out)
+}
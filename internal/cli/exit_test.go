@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	test := func(err error, expected int) {
+		assert.Equal(t, ExitCode(err), expected)
+		// The kind of the error is preserved when wrapped.
+		if err != nil {
+			assert.Equal(t, ExitCode(errors.Wrap(err, "wrapped")), expected)
+		}
+	}
+
+	test(nil, 0)
+	test(fmt.Errorf("unknown"), ExitFailure)
+	test(ErrNoResults, ExitNoResults)
+	test(core.ErrNotebookNotFound("/notebook"), ExitNotebookNotFound)
+	test(core.ErrNoteNotFound("note.md"), ExitNoteNotFound)
+	test(core.ErrIndexLocked{Err: fmt.Errorf("database is locked")}, ExitIndexLocked)
+	test(core.ErrTemplate{Template: "{{", Err: fmt.Errorf("parse error")}, ExitTemplateError)
+	test(core.ErrIndexing{Err: fmt.Errorf("disk full")}, ExitIndexError)
+	// The most specific kind wins.
+	test(core.ErrIndexing{Err: core.ErrIndexLocked{Err: fmt.Errorf("database is locked")}}, ExitIndexLocked)
+}
+
+func TestErrorJSON(t *testing.T) {
+	assert.Equal(t,
+		ErrorJSON(errors.Wrap(core.ErrNotebookNotFound("/notebook"), "failed to open notebook")),
+		`{"status":3,"kind":"notebook-not-found","message":"failed to open notebook: no notebook found in /notebook or a parent directory"}`,
+	)
+	assert.Equal(t,
+		ErrorJSON(fmt.Errorf(`unexpected "quote"`)),
+		`{"status":1,"kind":"failure","message":"unexpected \"quote\""}`,
+	)
+	assert.Equal(t, ErrorJSON(ErrNoResults), `{"status":2,"kind":"no-results","message":"no notes found"}`)
+}
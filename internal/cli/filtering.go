@@ -2,6 +2,8 @@ package cli
 
 import (
 	"fmt"
+	"regexp"
+	gostrings "strings"
 	"time"
 
 	"github.com/alecthomas/kong"
@@ -17,28 +19,36 @@ import (
 type Filtering struct {
 	Path []string `group:filter arg optional placeholder:PATH help:"Find notes matching the given path, including its descendants."`
 
-	Interactive    bool     `group:filter short:i                     help:"Select notes interactively with fzf."`
-	Limit          int      `group:filter short:n   placeholder:COUNT help:"Limit the number of notes found."`
-	Match          string   `group:filter short:m   placeholder:QUERY help:"Terms to search for in the notes."`
-	ExactMatch     bool     `group:filter short:e                     help:"Search for exact occurrences of the --match argument (case insensitive)."`
-	Exclude        []string `group:filter short:x   placeholder:PATH  help:"Ignore notes matching the given path, including its descendants."`
-	Tag            []string `group:filter short:t                     help:"Find notes tagged with the given tags."`
-	Mention        []string `group:filter           placeholder:PATH  help:"Find notes mentioning the title of the given ones."`
-	MentionedBy    []string `group:filter           placeholder:PATH  help:"Find notes whose title is mentioned in the given ones."`
-	LinkTo         []string `group:filter short:l   placeholder:PATH  help:"Find notes which are linking to the given ones."`
-	NoLinkTo       []string `group:filter           placeholder:PATH  help:"Find notes which are not linking to the given notes."`
-	LinkedBy       []string `group:filter short:L   placeholder:PATH  help:"Find notes which are linked by the given ones."`
-	NoLinkedBy     []string `group:filter           placeholder:PATH  help:"Find notes which are not linked by the given ones."`
-	Orphan         bool     `group:filter                             help:"Find notes which are not linked by any other note."`
-	Related        []string `group:filter           placeholder:PATH  help:"Find notes which might be related to the given ones."`
-	MaxDistance    int      `group:filter           placeholder:COUNT help:"Maximum distance between two linked notes."`
-	Recursive      bool     `group:filter short:r                     help:"Follow links recursively."`
-	Created        string   `group:filter           placeholder:DATE  help:"Find notes created on the given date."`
-	CreatedBefore  string   `group:filter           placeholder:DATE  help:"Find notes created before the given date."`
-	CreatedAfter   string   `group:filter           placeholder:DATE  help:"Find notes created after the given date."`
-	Modified       string   `group:filter           placeholder:DATE  help:"Find notes modified on the given date."`
-	ModifiedBefore string   `group:filter           placeholder:DATE  help:"Find notes modified before the given date."`
-	ModifiedAfter  string   `group:filter           placeholder:DATE  help:"Find notes modified after the given date."`
+	Interactive     bool     `group:filter short:i                     help:"Select notes interactively with fzf."`
+	Limit           int      `group:filter short:n   placeholder:COUNT help:"Limit the number of notes found."`
+	Match           string   `group:filter short:m   placeholder:QUERY help:"Terms to search for in the notes."`
+	ExactMatch      bool     `group:filter short:e                     help:"Search for exact occurrences of the --match argument (case insensitive)."`
+	Exclude         []string `group:filter short:x   placeholder:PATH  help:"Ignore notes matching the given path, including its descendants."`
+	Tag             []string `group:filter short:t                     help:"Find notes tagged with the given tags."`
+	Meta            []string `group:filter           placeholder:KEY=VALUE help:"Find notes by the value of a frontmatter key, e.g. status=draft or priority>=2." sep:none`
+	Mention         []string `group:filter           placeholder:PATH  help:"Find notes mentioning the title of the given ones."`
+	MentionedBy     []string `group:filter           placeholder:PATH  help:"Find notes whose title is mentioned in the given ones."`
+	LinkTo          []string `group:filter short:l   placeholder:PATH  help:"Find notes which are linking to the given ones."`
+	NoLinkTo        []string `group:filter           placeholder:PATH  help:"Find notes which are not linking to the given notes."`
+	LinkedBy        []string `group:filter short:L   placeholder:PATH  help:"Find notes which are linked by the given ones."`
+	NoLinkedBy      []string `group:filter           placeholder:PATH  help:"Find notes which are not linked by the given ones."`
+	LinkPath        []string `group:filter           placeholder:PATH  help:"Find the notes on the shortest path of links between two notes, e.g. --link-path a.md,b.md."`
+	Orphan          bool     `group:filter                             help:"Find notes which are not linked by any other note."`
+	RecentOpened    bool     `group:filter                             help:"Find the notes recently created or opened, the most recent first."`
+	Trashed         bool     `group:filter                             help:"Find only the notes in the trash, which are hidden otherwise."`
+	IncludeArchived bool     `group:filter                             help:"Include the notes of the archive directory, which are hidden otherwise."`
+	Related         []string `group:filter           placeholder:PATH  help:"Find notes which might be related to the given ones."`
+	ChildrenOf      []string `group:filter           placeholder:NOTE  help:"Find the children of the given notes, from their Folgezettel ID or parent frontmatter key. Use --recursive to find all the descendants."`
+	AncestorsOf     []string `group:filter           placeholder:NOTE  help:"Find the ancestors of the given notes, from their Folgezettel ID or parent frontmatter key."`
+	MaxDistance     int      `group:filter           placeholder:COUNT help:"Maximum distance between two linked notes, following links recursively."`
+	Recursive       bool     `group:filter short:r                     help:"Follow links or the note hierarchy recursively."`
+	Created         string   `group:filter           placeholder:DATE  help:"Find notes created on the given date."`
+	CreatedBefore   string   `group:filter           placeholder:DATE  help:"Find notes created before the given date."`
+	CreatedAfter    string   `group:filter           placeholder:DATE  help:"Find notes created after the given date."`
+	Modified        string   `group:filter           placeholder:DATE  help:"Find notes modified on the given date."`
+	ModifiedBefore  string   `group:filter           placeholder:DATE  help:"Find notes modified before the given date."`
+	ModifiedAfter   string   `group:filter           placeholder:DATE  help:"Find notes modified after the given date."`
+	ModifiedSince   string   `group:filter           placeholder:REV   help:"Find notes modified since the given git revision, e.g. HEAD~3."`
 
 	Sort []string `group:sort short:s placeholder:TERM help:"Order the notes by the given criterion."`
 }
@@ -75,6 +85,7 @@ func (f Filtering) ExpandNamedFilters(filters map[string]string, expandedFilters
 			actualPaths = append(actualPaths, parsedFilter.Path...)
 			f.Exclude = append(f.Exclude, parsedFilter.Exclude...)
 			f.Tag = append(f.Tag, parsedFilter.Tag...)
+			f.Meta = append(f.Meta, parsedFilter.Meta...)
 			f.Mention = append(f.Mention, parsedFilter.Mention...)
 			f.MentionedBy = append(f.MentionedBy, parsedFilter.MentionedBy...)
 			f.LinkTo = append(f.LinkTo, parsedFilter.LinkTo...)
@@ -82,11 +93,16 @@ func (f Filtering) ExpandNamedFilters(filters map[string]string, expandedFilters
 			f.LinkedBy = append(f.LinkedBy, parsedFilter.LinkedBy...)
 			f.NoLinkedBy = append(f.NoLinkedBy, parsedFilter.NoLinkedBy...)
 			f.Related = append(f.Related, parsedFilter.Related...)
+			f.ChildrenOf = append(f.ChildrenOf, parsedFilter.ChildrenOf...)
+			f.AncestorsOf = append(f.AncestorsOf, parsedFilter.AncestorsOf...)
 			f.Sort = append(f.Sort, parsedFilter.Sort...)
 
 			f.ExactMatch = f.ExactMatch || parsedFilter.ExactMatch
 			f.Interactive = f.Interactive || parsedFilter.Interactive
 			f.Orphan = f.Orphan || parsedFilter.Orphan
+			f.RecentOpened = f.RecentOpened || parsedFilter.RecentOpened
+			f.Trashed = f.Trashed || parsedFilter.Trashed
+			f.IncludeArchived = f.IncludeArchived || parsedFilter.IncludeArchived
 			f.Recursive = f.Recursive || parsedFilter.Recursive
 
 			if f.Limit == 0 {
@@ -95,6 +111,9 @@ func (f Filtering) ExpandNamedFilters(filters map[string]string, expandedFilters
 			if f.MaxDistance == 0 {
 				f.MaxDistance = parsedFilter.MaxDistance
 			}
+			if len(f.LinkPath) == 0 {
+				f.LinkPath = parsedFilter.LinkPath
+			}
 			if f.Created == "" {
 				f.Created = parsedFilter.Created
 			}
@@ -113,6 +132,9 @@ func (f Filtering) ExpandNamedFilters(filters map[string]string, expandedFilters
 			if f.ModifiedAfter == "" {
 				f.ModifiedAfter = parsedFilter.ModifiedAfter
 			}
+			if f.ModifiedSince == "" {
+				f.ModifiedSince = parsedFilter.ModifiedSince
+			}
 
 			if f.Match == "" {
 				f.Match = parsedFilter.Match
@@ -150,7 +172,18 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 	}
 
 	if len(f.Tag) > 0 {
-		opts.Tags = f.Tag
+		opts.Tags = []string{}
+		for _, tags := range f.Tag {
+			opts.Tags = append(opts.Tags, canonicalTagFilter(tags, notebook.Config))
+		}
+	}
+
+	for _, meta := range f.Meta {
+		filter, err := core.MetadataFilterFromString(meta)
+		if err != nil {
+			return opts, err
+		}
+		opts.Metadata = append(opts.Metadata, filter)
 	}
 
 	if len(f.Mention) > 0 {
@@ -161,11 +194,15 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 		opts.MentionedBy = f.MentionedBy
 	}
 
+	// A maximum distance is meaningful only when following links
+	// recursively.
+	recursive := f.Recursive || f.MaxDistance > 0
+
 	if paths, ok := relPaths(notebook, f.LinkedBy); ok {
 		opts.LinkedBy = &core.LinkFilter{
 			Paths:       paths,
 			Negate:      false,
-			Recursive:   f.Recursive,
+			Recursive:   recursive,
 			MaxDistance: f.MaxDistance,
 		}
 	} else if paths, ok := relPaths(notebook, f.NoLinkedBy); ok {
@@ -179,7 +216,7 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 		opts.LinkTo = &core.LinkFilter{
 			Paths:       paths,
 			Negate:      false,
-			Recursive:   f.Recursive,
+			Recursive:   recursive,
 			MaxDistance: f.MaxDistance,
 		}
 	} else if paths, ok := relPaths(notebook, f.NoLinkTo); ok {
@@ -189,11 +226,42 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 		}
 	}
 
+	if len(f.LinkPath) > 0 {
+		paths, _ := relPaths(notebook, f.LinkPath)
+		if len(paths) != 2 {
+			return opts, errors.New("--link-path expects two notes, e.g. --link-path a.md,b.md")
+		}
+		opts.LinkPath = &core.LinkPathFilter{
+			From:        paths[0],
+			To:          paths[1],
+			MaxDistance: f.MaxDistance,
+		}
+	}
+
 	if paths, ok := relPaths(notebook, f.Related); ok {
 		opts.Related = paths
 	}
 
+	if paths, ok := relPaths(notebook, f.ChildrenOf); ok {
+		opts.ChildrenOf = &core.HierarchyFilter{
+			Paths:     paths,
+			Recursive: f.Recursive,
+		}
+	}
+
+	if paths, ok := relPaths(notebook, f.AncestorsOf); ok {
+		opts.AncestorsOf = paths
+	}
+
 	opts.Orphan = f.Orphan
+	opts.RecentlyOpened = f.RecentOpened
+
+	if f.Trashed {
+		opts.Trash = core.TrashFilterOnly
+	} else {
+		opts.Trash = core.TrashFilterExclude
+	}
+	opts.ExcludeArchived = !f.IncludeArchived
 
 	if f.Created != "" {
 		start, end, err := parseDayRange(f.Created)
@@ -243,6 +311,8 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 		}
 	}
 
+	opts.ModifiedSince = opt.NewNotEmptyString(f.ModifiedSince)
+
 	sorters, err := core.NoteSortersFromStrings(f.Sort)
 	if err != nil {
 		return opts, err
@@ -280,3 +350,31 @@ func startOfDay(t time.Time) time.Time {
 	year, month, day := t.Date()
 	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
 }
+
+var tagFilterSeparatorRegex = regexp.MustCompile(`(\ OR\ )|\|`)
+
+// canonicalTagFilter replaces the aliased tags of a --tag filter with their
+// canonical name, as they are indexed.
+func canonicalTagFilter(filter string, config core.Config) string {
+	if len(config.TagAliases) == 0 {
+		return filter
+	}
+
+	terms := tagFilterSeparatorRegex.Split(filter, -1)
+	for i, term := range terms {
+		tag := gostrings.TrimSpace(term)
+		for _, prefix := range []string{"-", "NOT"} {
+			if gostrings.HasPrefix(tag, prefix) {
+				tag = gostrings.TrimSpace(gostrings.TrimPrefix(tag, prefix))
+				break
+			}
+		}
+		if tag == "" {
+			continue
+		}
+		if j := gostrings.LastIndex(term, tag); j >= 0 {
+			terms[i] = term[:j] + config.CanonicalTag(tag) + term[j+len(tag):]
+		}
+	}
+	return gostrings.Join(terms, "|")
+}
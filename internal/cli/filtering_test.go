@@ -3,6 +3,7 @@ package cli
 import (
 	"testing"
 
+	"github.com/mickael-menu/zk/internal/core"
 	"github.com/mickael-menu/zk/internal/util/test/assert"
 )
 
@@ -56,13 +57,15 @@ func TestExpandNamedFiltersJoinLists(t *testing.T) {
 		LinkedBy:    []string{"linked1", "linked2"},
 		NoLinkedBy:  []string{"linked3", "linked4"},
 		Related:     []string{"related1", "related2"},
+		ChildrenOf:  []string{"parent1"},
+		AncestorsOf: []string{"child1"},
 		Sort:        []string{"title", "created"},
 	}
 
 	res, err := f.ExpandNamedFilters(
 		map[string]string{
 			"f1": "path2 --exclude excl-path3 -x excl-path4 --tag tag3 -t tag4 --mention mention3,mention4 --mentioned-by note3",
-			"f2": "--link-to link5 --no-link-to link6 --linked-by linked5 --no-linked-by linked6 --related related3 --related related4 --sort random-",
+			"f2": "--link-to link5 --no-link-to link6 --linked-by linked5 --no-linked-by linked6 --related related3 --related related4 --children-of parent2 --ancestors-of child2 --sort random-",
 		},
 		[]string{},
 	)
@@ -78,6 +81,8 @@ func TestExpandNamedFiltersJoinLists(t *testing.T) {
 	assert.Equal(t, res.LinkedBy, []string{"linked1", "linked2", "linked5"})
 	assert.Equal(t, res.NoLinkedBy, []string{"linked3", "linked4", "linked6"})
 	assert.Equal(t, res.Related, []string{"related1", "related2", "related3", "related4"})
+	assert.Equal(t, res.ChildrenOf, []string{"parent1", "parent2"})
+	assert.Equal(t, res.AncestorsOf, []string{"child1", "child2"})
 	assert.Equal(t, res.Sort, []string{"title", "created", "random-"})
 }
 
@@ -89,7 +94,7 @@ func TestExpandNamedFiltersJoinBools(t *testing.T) {
 
 	res, err := f.ExpandNamedFilters(
 		map[string]string{
-			"f1": "--exact-match --interactive --orphan",
+			"f1": "--exact-match --interactive --orphan --trashed",
 			"f2": "--recursive",
 		},
 		[]string{},
@@ -99,6 +104,7 @@ func TestExpandNamedFiltersJoinBools(t *testing.T) {
 	assert.True(t, res.ExactMatch)
 	assert.True(t, res.Interactive)
 	assert.True(t, res.Orphan)
+	assert.True(t, res.Trashed)
 	assert.True(t, res.Recursive)
 }
 
@@ -108,35 +114,39 @@ func TestExpandNamedFiltersJoinLitterals(t *testing.T) {
 	res1, err := f1.ExpandNamedFilters(
 		map[string]string{
 			"f1": "--limit 42 --created 'yesterday' --created-before '2 days ago' --created-after '3 days ago'",
-			"f2": "--max-distance 24 --modified 'tomorrow' --modified-before '2 days' --modified-after '3 days'",
+			"f2": "--max-distance 24 --link-path a.md,b.md --modified 'tomorrow' --modified-before '2 days' --modified-after '3 days' --modified-since v1",
 		},
 		[]string{},
 	)
 	assert.Nil(t, err)
 	assert.Equal(t, res1.Limit, 42)
 	assert.Equal(t, res1.MaxDistance, 24)
+	assert.Equal(t, res1.LinkPath, []string{"a.md", "b.md"})
 	assert.Equal(t, res1.Created, "yesterday")
 	assert.Equal(t, res1.CreatedBefore, "2 days ago")
 	assert.Equal(t, res1.CreatedAfter, "3 days ago")
 	assert.Equal(t, res1.Modified, "tomorrow")
 	assert.Equal(t, res1.ModifiedBefore, "2 days")
 	assert.Equal(t, res1.ModifiedAfter, "3 days")
+	assert.Equal(t, res1.ModifiedSince, "v1")
 
 	f2 := Filtering{
 		Path:           []string{"f1", "f2"},
 		Limit:          10,
 		MaxDistance:    20,
+		LinkPath:       []string{"c.md", "d.md"},
 		Created:        "last week",
 		CreatedBefore:  "two weeks ago",
 		CreatedAfter:   "three weeks ago",
 		Modified:       "next week",
 		ModifiedBefore: "two weeks",
 		ModifiedAfter:  "three weeks",
+		ModifiedSince:  "HEAD~2",
 	}
 	res2, err := f2.ExpandNamedFilters(
 		map[string]string{
 			"f1": "--limit 42 --created 'yesterday' --created-before '2 days ago' --created-after '3 days ago'",
-			"f2": "--max-distance 24 --modified 'tomorrow' --modified-before '2 days' --modified-after '3 days'",
+			"f2": "--max-distance 24 --link-path a.md,b.md --modified 'tomorrow' --modified-before '2 days' --modified-after '3 days' --modified-since v1",
 		},
 		[]string{},
 	)
@@ -144,12 +154,14 @@ func TestExpandNamedFiltersJoinLitterals(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, res2.Limit, 10)
 	assert.Equal(t, res2.MaxDistance, 20)
+	assert.Equal(t, res2.LinkPath, []string{"c.md", "d.md"})
 	assert.Equal(t, res2.Created, "last week")
 	assert.Equal(t, res2.CreatedBefore, "two weeks ago")
 	assert.Equal(t, res2.CreatedAfter, "three weeks ago")
 	assert.Equal(t, res2.Modified, "next week")
 	assert.Equal(t, res2.ModifiedBefore, "two weeks")
 	assert.Equal(t, res2.ModifiedAfter, "three weeks")
+	assert.Equal(t, res2.ModifiedSince, "HEAD~2")
 }
 
 // ExpandNamedFilters: Match option predicates are cumulated with AND.
@@ -203,3 +215,16 @@ func TestExpandNamedFiltersReportsParsingError(t *testing.T) {
 
 	assert.Err(t, err, "failed to expand named filter `f1`: unknown flag --test")
 }
+
+func TestCanonicalTagFilter(t *testing.T) {
+	config := core.NewDefaultConfig()
+	assert.Equal(t, canonicalTagFilter("golang OR rust", config), "golang OR rust")
+
+	config.TagAliases = map[string]string{"golang": "go", "js": "javascript"}
+	assert.Equal(t, canonicalTagFilter("golang", config), "go")
+	assert.Equal(t, canonicalTagFilter("golang/generics", config), "go/generics")
+	assert.Equal(t, canonicalTagFilter("golang OR rust|js", config), "go|rust|javascript")
+	assert.Equal(t, canonicalTagFilter("NOT golang", config), "NOT go")
+	assert.Equal(t, canonicalTagFilter(" -golang", config), " -go")
+	assert.Equal(t, canonicalTagFilter("golang*", config), "golang*")
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/schollz/progressbar/v3"
+)
+
+// IndexNotebook indexes the content of the notebook, displaying the progress
+// of the run on the standard error, when it is a terminal.
+func (c *Container) IndexNotebook(notebook *core.Notebook, force bool) (core.NoteIndexingStats, error) {
+	if !isatty.IsTerminal(os.Stderr.Fd()) {
+		return notebook.Index(c.Origin, force)
+	}
+
+	progress := indexProgressBar{}
+	defer progress.clear()
+	return notebook.IndexWithProgress(c.Origin, force, progress.report)
+}
+
+// indexProgressBar displays a spinner while the notes are scanned, then a
+// progress bar with the estimated remaining time while the changes are
+// indexed.
+type indexProgressBar struct {
+	bar   *progressbar.ProgressBar
+	phase core.IndexPhase
+}
+
+func (p *indexProgressBar) report(progress core.IndexProgress) {
+	if p.bar == nil || p.phase != progress.Phase {
+		p.clear()
+		p.phase = progress.Phase
+		max := -1
+		if progress.Phase == core.IndexPhaseIndexing && progress.Total > 0 {
+			max = progress.Total
+		}
+		p.bar = progressbar.NewOptions(max,
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionThrottle(100*time.Millisecond),
+			progressbar.OptionSpinnerType(14),
+			progressbar.OptionShowCount(),
+			progressbar.OptionUseANSICodes(true),
+		)
+	}
+
+	switch progress.Phase {
+	case core.IndexPhaseScanning:
+		p.bar.Describe(fmt.Sprintf("Scanning: %d found", progress.Scanned))
+		p.bar.Add(1)
+	case core.IndexPhaseIndexing:
+		description := fmt.Sprintf("Indexing: %d parsed, %d indexed", progress.Parsed, progress.Indexed)
+		if progress.Removed > 0 {
+			description += fmt.Sprintf(", %d removed", progress.Removed)
+		}
+		p.bar.Describe(description)
+		p.bar.Set(progress.Done)
+	case core.IndexPhaseAssets:
+		p.bar.Describe("Extracting the text of " + progress.Path)
+		p.bar.Add(1)
+	}
+}
+
+func (p *indexProgressBar) clear() {
+	if p.bar != nil {
+		p.bar.Clear()
+		p.bar = nil
+	}
+}
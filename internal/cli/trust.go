@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// TrustStore records the notebooks the user allowed, or refused, to run the
+// shell commands defined in their config and templates.
+//
+// The decisions are saved in a JSON file mapping the absolute path of each
+// notebook to whether it is trusted.
+type TrustStore struct {
+	path string
+}
+
+// NewTrustStore creates a TrustStore saving the decisions in the file at the
+// given path.
+func NewTrustStore(path string) *TrustStore {
+	return &TrustStore{path: path}
+}
+
+// IsTrusted returns whether the notebook at the given path is trusted. ok is
+// false when the user never decided.
+func (s *TrustStore) IsTrusted(notebookPath string) (trusted bool, ok bool, err error) {
+	notebooks, err := s.read()
+	if err != nil {
+		return false, false, err
+	}
+	trusted, ok = notebooks[notebookPath]
+	return trusted, ok, nil
+}
+
+// SetTrusted records whether the notebook at the given path is trusted.
+func (s *TrustStore) SetTrusted(notebookPath string, trusted bool) error {
+	wrap := errors.Wrapperf("%s: failed to save the notebook trust", s.path)
+
+	notebooks, err := s.read()
+	if err != nil {
+		return err
+	}
+	notebooks[notebookPath] = trusted
+
+	data, err := json.MarshalIndent(notebooks, "", "  ")
+	if err != nil {
+		return wrap(err)
+	}
+	err = os.MkdirAll(filepath.Dir(s.path), os.ModePerm)
+	if err != nil {
+		return wrap(err)
+	}
+	return wrap(ioutil.WriteFile(s.path, append(data, '\n'), 0600))
+}
+
+func (s *TrustStore) read() (map[string]bool, error) {
+	wrap := errors.Wrapperf("%s: failed to read the trusted notebooks", s.path)
+
+	notebooks := map[string]bool{}
+	exists, err := paths.Exists(s.path)
+	if err != nil || !exists {
+		return notebooks, wrap(err)
+	}
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return notebooks, wrap(err)
+	}
+	err = json.Unmarshal(data, &notebooks)
+	return notebooks, wrap(err)
+}
+
+// trustNotebook asks the user whether the notebook at the given path can run
+// the shell commands found at the given locations, unless they already
+// decided.
+func (c *Container) trustNotebook(path string, snippets []string) (bool, error) {
+	trusted, ok, err := c.TrustStore.IsTrusted(path)
+	if err != nil {
+		return false, err
+	}
+
+	if !ok && c.Terminal.IsInteractive() {
+		fmt.Fprintf(os.Stderr, "The notebook at %s runs shell commands from:\n", path)
+		for _, snippet := range snippets {
+			fmt.Fprintf(os.Stderr, "  - %s\n", snippet)
+		}
+		trusted, _ = c.Terminal.Confirm("Do you trust this notebook and allow it to run these commands?", false)
+		err = c.TrustStore.SetTrusted(path, trusted)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if !trusted {
+		c.Logger.Printf("warning: ignoring the shell commands of the untrusted notebook at %s (%s), run `zk trust` to allow them", path, strings.Join(snippets, ", "))
+	}
+	return trusted, nil
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/exec"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// Asset is a file of the notebook which is not a note, such as an image or
+// a PDF attachment.
+type Asset struct {
+	// Path relative to the root of the notebook.
+	Path string `json:"path"`
+	// Paths of the notes linking to this asset.
+	LinkedBy []string `json:"linkedBy"`
+}
+
+// UnresolvedLink is an internal link which doesn't target any indexed note.
+type UnresolvedLink struct {
+	// Path of the note containing the link.
+	SourcePath string
+	// Destination of the link, relative to the notebook root.
+	Href string
+}
+
+// FindAssets returns all the assets stored in the notebook, with the notes
+// linking to them.
+func (n *Notebook) FindAssets() ([]Asset, error) {
+	wrap := errors.Wrapper("failed to find assets")
+
+	assets := map[string]*Asset{}
+	files := paths.Walk(n.Path, n.logger, func(path string) (bool, error) {
+		return false, nil
+	})
+	for file := range files {
+		if !n.isAssetPath(file.Path) {
+			continue
+		}
+		assets[file.Path] = &Asset{
+			Path:     file.Path,
+			LinkedBy: []string{},
+		}
+	}
+
+	links, err := n.index.FindUnresolvedLinks()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	for _, link := range links {
+		asset, ok := assets[assetHrefPath(link.Href)]
+		if ok && (len(asset.LinkedBy) == 0 || asset.LinkedBy[len(asset.LinkedBy)-1] != link.SourcePath) {
+			asset.LinkedBy = append(asset.LinkedBy, link.SourcePath)
+		}
+	}
+
+	res := []Asset{}
+	for _, asset := range assets {
+		res = append(res, *asset)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return res, nil
+}
+
+// isAssetPath returns whether the file at the given path relative to the
+// notebook root is an asset, i.e. it doesn't have a note extension.
+func (n *Notebook) isAssetPath(path string) bool {
+	group, err := n.Config.GroupConfigForPath(path)
+	if err != nil {
+		n.logger.Err(err)
+		return false
+	}
+	return filepath.Ext(path) != "."+group.Note.Extension
+}
+
+// assetHrefPath converts a link href relative to the notebook root to an
+// asset path.
+func assetHrefPath(href string) string {
+	href = strings.SplitN(href, "#", 2)[0]
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return filepath.Clean(href)
+}
+
+// imageExtensions lists the file extensions of the images supported by the
+// OCR command.
+var imageExtensions = map[string]bool{
+	".bmp":  true,
+	".gif":  true,
+	".jpeg": true,
+	".jpg":  true,
+	".png":  true,
+	".tif":  true,
+	".tiff": true,
+	".webp": true,
+}
+
+// IsImagePath returns whether the file at the given path is an image,
+// according to its extension.
+func IsImagePath(path string) bool {
+	return imageExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// assetTextCommand returns the external command used to extract the text of
+// the asset at the given path, if any.
+func assetTextCommand(config ToolConfig, path string) opt.String {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case ext == ".pdf":
+		return config.PDFText
+	case imageExtensions[ext]:
+		return config.OCR
+	default:
+		return opt.NullString
+	}
+}
+
+// extractAssetText pipes the file at absPath to the given command and
+// returns its output.
+func extractAssetText(command string, absPath string) (string, error) {
+	file, err := os.Open(absPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	cmd := exec.CommandFromString(command)
+	cmd.Stdin = file
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
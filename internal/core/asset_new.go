@@ -0,0 +1,120 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// NewAssetOpts holds the options used to add a new asset to a Notebook.
+type NewAssetOpts struct {
+	// Content of the asset file.
+	Content []byte
+	// Original filename of the asset, giving its extension. When empty, the
+	// extension is guessed from the content.
+	Filename string
+	// Path of the note linking to the asset, relative to the notebook root.
+	NotePath string
+	// Date used to generate the filename of the asset.
+	Date time.Time
+	// Command adding the asset.
+	Origin AuditOrigin
+}
+
+// assetExtensions maps the content types detected by NewAsset to a file
+// extension.
+var assetExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"image/bmp":       ".bmp",
+	"image/gif":       ".gif",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+}
+
+// NewAsset saves the given content in the asset directory of the notebook,
+// with a filename generated from the asset filename template. Returns the
+// path of the asset, relative to the notebook root.
+func (n *Notebook) NewAsset(opts NewAssetOpts) (string, error) {
+	wrap := errors.Wrapper("new asset")
+
+	if len(opts.Content) == 0 {
+		return "", wrap(fmt.Errorf("the asset is empty"))
+	}
+
+	ext := strings.ToLower(filepath.Ext(opts.Filename))
+	if ext == "" {
+		contentType := strings.SplitN(http.DetectContentType(opts.Content), ";", 2)[0]
+		var ok bool
+		if ext, ok = assetExtensions[contentType]; !ok {
+			return "", wrap(fmt.Errorf("%s: unknown type of asset, give it a filename with an extension", contentType))
+		}
+	}
+
+	templates, err := n.templateLoaderFactory(n.Config.Note.Lang)
+	if err != nil {
+		return "", wrap(err)
+	}
+	template, err := templates.LoadTemplate(n.Config.Asset.FilenameTemplate)
+	if err != nil {
+		return "", wrap(err)
+	}
+	context := newAssetTemplateContext{
+		ID:  n.idGeneratorFactory(n.Config.Note.IDOptions)(),
+		Now: opts.Date,
+		Env: n.osEnv(),
+	}
+	if opts.Filename != "" {
+		context.Filename = filepath.Base(opts.Filename)
+		context.FilenameStem = paths.FilenameStem(opts.Filename)
+	}
+	if opts.NotePath != "" {
+		context.Note = paths.FilenameStem(opts.NotePath)
+	}
+	filename, err := template.Render(context)
+	if err != nil {
+		return "", wrap(err)
+	}
+	filename = strings.TrimSpace(filename)
+	if filename == "" {
+		return "", wrap(fmt.Errorf("the asset filename template generated an empty filename"))
+	}
+
+	absPath, err := n.freeAttachmentPath(filepath.Join(n.Path, n.Config.Asset.Dir), filename+ext)
+	if err == nil {
+		err = n.fs.Write(absPath, opts.Content)
+	}
+	if err != nil {
+		return "", wrap(err)
+	}
+	path, err := n.RelPath(absPath)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	details := ""
+	if opts.NotePath != "" {
+		details = "linked from " + opts.NotePath
+	}
+	n.audit(opts.Origin, AuditOperationCreate, details, path)
+	n.autoCommit(string(AuditOperationCreate), path)
+	return path, nil
+}
+
+// newAssetTemplateContext holds the placeholder values which will be
+// expanded in the asset filename template.
+type newAssetTemplateContext struct {
+	ID string `handlebars:"id"`
+	// Original filename of the asset, if any.
+	Filename     string
+	FilenameStem string `handlebars:"filename-stem"`
+	// Filename stem of the note linking to the asset.
+	Note string
+	Now  time.Time
+	Env  map[string]string
+}
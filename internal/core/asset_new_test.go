@@ -0,0 +1,85 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+// pngHeader is the signature of a PNG image, to detect its content type.
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+func newAssetTest(files map[string]string) (*newNoteTest, *templateSpy) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+		files:   files,
+	}
+	test.setup()
+	test.config.Asset = AssetConfig{
+		Dir:              "assets",
+		FilenameTemplate: "asset-filename",
+	}
+	spy := test.templateLoader.Spy("asset-filename", func(context interface{}) string {
+		c := context.(newAssetTemplateContext)
+		return c.Note + "-" + c.ID
+	})
+	return &test, spy
+}
+
+func TestNotebookNewAsset(t *testing.T) {
+	test, spy := newAssetTest(nil)
+
+	path, err := test.notebook().NewAsset(NewAssetOpts{
+		Content:  []byte("PDF"),
+		Filename: "/tmp/My Report.PDF",
+		NotePath: "inbox/meeting.md",
+		Date:     now,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, path, "assets/meeting-id.pdf")
+	assert.Equal(t, test.fs.files["/notebook/assets/meeting-id.pdf"], "PDF")
+	assert.Equal(t, spy.Contexts, []interface{}{
+		newAssetTemplateContext{
+			ID:           "id",
+			Filename:     "My Report.PDF",
+			FilenameStem: "My Report",
+			Note:         "meeting",
+			Now:          now,
+			Env:          map[string]string{"KEY1": "foo", "KEY2": "bar"},
+		},
+	})
+}
+
+func TestNotebookNewAssetDetectsExtension(t *testing.T) {
+	test, _ := newAssetTest(map[string]string{
+		"/notebook/assets/note-id.png": "existing",
+	})
+
+	path, err := test.notebook().NewAsset(NewAssetOpts{
+		Content:  []byte(pngHeader + "data"),
+		NotePath: "note.md",
+		Date:     now,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, path, "assets/note-id-2.png")
+	assert.Equal(t, test.fs.files["/notebook/assets/note-id-2.png"], pngHeader+"data")
+}
+
+func TestNotebookNewAssetUnknownType(t *testing.T) {
+	test, _ := newAssetTest(nil)
+
+	_, err := test.notebook().NewAsset(NewAssetOpts{
+		Content: []byte("plain text"),
+		Date:    now,
+	})
+	assert.Err(t, err, "new asset: text/plain: unknown type of asset, give it a filename with an extension")
+
+	_, err = test.notebook().NewAsset(NewAssetOpts{
+		Content:  []byte{},
+		Filename: "image.png",
+		Date:     now,
+	})
+	assert.Err(t, err, "new asset: the asset is empty")
+}
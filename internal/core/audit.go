@@ -0,0 +1,116 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// AuditLog records the mutating operations performed on a notebook, to
+// answer the question "what changed my files?".
+type AuditLog interface {
+	// Append records a new entry at the end of the log.
+	Append(entry AuditEntry) error
+	// Entries returns all the entries recorded in the log, from the oldest to
+	// the newest.
+	Entries() ([]AuditEntry, error)
+}
+
+// AuditEntry is a single mutating operation recorded in the AuditLog.
+type AuditEntry struct {
+	// Date when the operation was performed.
+	Time time.Time `json:"time"`
+	// Kind of operation.
+	Operation AuditOperation `json:"operation"`
+	// Interface (e.g. cli or lsp) which initiated the operation.
+	Interface string `json:"interface"`
+	// Name of the command which initiated the operation, e.g. `new` or `zk.new`.
+	Command string `json:"command,omitempty"`
+	// Paths relative to the notebook root affected by the operation.
+	Paths []string `json:"paths"`
+	// Additional free-form details about the operation.
+	Details string `json:"details,omitempty"`
+}
+
+// String implements Stringer.
+func (e AuditEntry) String() string {
+	origin := e.Interface
+	if e.Command != "" {
+		origin += ":" + e.Command
+	}
+	s := fmt.Sprintf("%s %s %s", e.Time.Local().Format("2006-01-02 15:04:05"), origin, e.Operation)
+	for _, path := range e.Paths {
+		s += " " + path
+	}
+	if e.Details != "" {
+		s += " (" + e.Details + ")"
+	}
+	return s
+}
+
+// AuditOperation is a kind of mutating operation recorded in the AuditLog.
+type AuditOperation string
+
+const (
+	// A note was created.
+	AuditOperationCreate AuditOperation = "create"
+	// A note was moved or renamed.
+	AuditOperationMove AuditOperation = "move"
+	// A note was deleted.
+	AuditOperationDelete AuditOperation = "delete"
+	// A tag was renamed.
+	AuditOperationRenameTag AuditOperation = "rename-tag"
+	// The links to a note were retargeted to another note.
+	AuditOperationRelink AuditOperation = "relink"
+	// Several notes were merged into one.
+	AuditOperationMerge AuditOperation = "merge"
+	// Text was replaced across several notes.
+	AuditOperationReplace AuditOperation = "replace"
+	// Notes were imported from another app.
+	AuditOperationImport AuditOperation = "import"
+	// A note was moved to the trash.
+	AuditOperationTrash AuditOperation = "trash"
+	// A note was restored from the trash.
+	AuditOperationRestore AuditOperation = "restore"
+	// The review of a note was recorded.
+	AuditOperationReview AuditOperation = "review"
+)
+
+// AuditOrigin identifies the interface and command initiating the operations
+// performed on a notebook.
+//
+// The options of the notebook operations take the origin of the operation,
+// which is recorded in the audit log and given to the hooks.
+type AuditOrigin struct {
+	// Interface is for example "cli" or "lsp".
+	Interface string
+	// Command is the name of the CLI or LSP command being run.
+	Command string
+}
+
+// audit records a new operation in the notebook's audit log, initiated by
+// the given origin.
+//
+// Failing to record an operation is not fatal, so errors are only logged.
+func (n *Notebook) audit(origin AuditOrigin, op AuditOperation, details string, paths ...string) {
+	if n.auditLog == nil {
+		return
+	}
+
+	err := n.auditLog.Append(AuditEntry{
+		Time:      time.Now().UTC(),
+		Operation: op,
+		Interface: origin.Interface,
+		Command:   origin.Command,
+		Paths:     paths,
+		Details:   details,
+	})
+	n.logger.Err(err)
+}
+
+// AuditEntries returns the operations recorded in the notebook's audit log.
+func (n *Notebook) AuditEntries() ([]AuditEntry, error) {
+	if n.auditLog == nil {
+		return []AuditEntry{}, nil
+	}
+	return n.auditLog.Entries()
+}
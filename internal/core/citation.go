@@ -0,0 +1,142 @@
+package core
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// Citation is a bibliographic reference cited in the notes with its key,
+// e.g. `[@knuth1984]`.
+type Citation struct {
+	// Key identifying the reference in the notes.
+	Key string `json:"key"`
+	// Kind of reference, e.g. article or book.
+	Type    string   `json:"type"`
+	Title   string   `json:"title"`
+	Authors []string `json:"authors"`
+	// Year of publication.
+	Year string `json:"year"`
+	// Journal, book or publisher of the reference.
+	Container string `json:"container"`
+	DOI       string `json:"doi,omitempty"`
+	URL       string `json:"url,omitempty"`
+	// Absolute path of the bibliography file declaring the reference.
+	Path string `json:"path"`
+	// Line of the reference in the bibliography file, starting from 1.
+	Line int `json:"line"`
+}
+
+// Reference formats the citation as a bibliographic reference, e.g.
+// `Knuth, Donald E. (1984). Literate Programming. The Computer Journal.`
+func (c Citation) Reference() string {
+	parts := []string{}
+	authors := strings.Join(c.Authors, "; ")
+	if c.Year != "" {
+		authors = strings.TrimSpace(authors + " (" + c.Year + ")")
+	}
+	for _, part := range []string{authors, c.Title, c.Container} {
+		part = strings.TrimRight(strings.TrimSpace(part), ".")
+		if part != "" {
+			parts = append(parts, part+".")
+		}
+	}
+	if c.DOI != "" {
+		parts = append(parts, "https://doi.org/"+c.DOI)
+	} else if c.URL != "" {
+		parts = append(parts, c.URL)
+	}
+	return strings.Join(parts, " ")
+}
+
+// BibliographyParser reads the references declared in a bibliography file,
+// e.g. in the BibTeX or CSL-JSON format.
+type BibliographyParser func(path string, content []byte) ([]Citation, error)
+
+// BibliographyConfig holds the configuration of the references cited in the
+// notes.
+type BibliographyConfig struct {
+	// Bibliography files, relative to the notebook root.
+	Files []string
+}
+
+// parsedBibliography is a bibliography file parsed previously, which is
+// parsed again only when its content changes.
+type parsedBibliography struct {
+	content   []byte
+	citations []Citation
+}
+
+// Citations returns the references declared in the bibliography files of
+// the notebook.
+//
+// When a key is declared several times, the first reference is used.
+func (n *Notebook) Citations() ([]Citation, error) {
+	wrap := errors.Wrapper("failed to read the bibliography")
+
+	citations := []Citation{}
+	if n.bibliographyParser == nil {
+		return citations, nil
+	}
+
+	keys := map[string]bool{}
+	for _, file := range n.Config.Bibliography.Files {
+		path := paths.ExpandHome(file)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(n.Path, path)
+		}
+		content, err := n.fs.Read(path)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		parsed, err := n.parseBibliography(path, content)
+		if err != nil {
+			return nil, wrap(errors.Wrap(err, file))
+		}
+		for _, citation := range parsed {
+			if !keys[citation.Key] {
+				keys[citation.Key] = true
+				citations = append(citations, citation)
+			}
+		}
+	}
+	return citations, nil
+}
+
+// FindCitation returns the reference with the given key, or nil if it is not
+// declared in the bibliography.
+func (n *Notebook) FindCitation(key string) (*Citation, error) {
+	citations, err := n.Citations()
+	if err != nil {
+		return nil, err
+	}
+	for _, citation := range citations {
+		if citation.Key == key {
+			return &citation, nil
+		}
+	}
+	return nil, nil
+}
+
+// parseBibliography parses the given bibliography file, unless its content
+// didn't change since the last time.
+func (n *Notebook) parseBibliography(path string, content []byte) ([]Citation, error) {
+	n.bibliographiesMutex.Lock()
+	defer n.bibliographiesMutex.Unlock()
+
+	if parsed, ok := n.bibliographies[path]; ok && bytes.Equal(parsed.content, content) {
+		return parsed.citations, nil
+	}
+	citations, err := n.bibliographyParser(path, content)
+	if err != nil {
+		return nil, err
+	}
+	if n.bibliographies == nil {
+		n.bibliographies = map[string]parsedBibliography{}
+	}
+	n.bibliographies[path] = parsedBibliography{content: content, citations: citations}
+	return citations, nil
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestCitationReference(t *testing.T) {
+	assert.Equal(t, Citation{Key: "empty"}.Reference(), "")
+	assert.Equal(t, Citation{Title: "Untitled draft."}.Reference(), "Untitled draft.")
+	assert.Equal(t,
+		Citation{
+			Authors:   []string{"Knuth, Donald E."},
+			Year:      "1984",
+			Title:     "Literate Programming",
+			Container: "The Computer Journal",
+			DOI:       "10.1093/comjnl/27.2.97",
+			URL:       "https://example.com",
+		}.Reference(),
+		"Knuth, Donald E. (1984). Literate Programming. The Computer Journal. https://doi.org/10.1093/comjnl/27.2.97",
+	)
+	assert.Equal(t,
+		Citation{
+			Authors: []string{"Ahrens, Sönke", "Luhmann, Niklas"},
+			Title:   "How to Take Smart Notes",
+			URL:     "https://example.com",
+		}.Reference(),
+		"Ahrens, Sönke; Luhmann, Niklas. How to Take Smart Notes. https://example.com",
+	)
+}
+
+func TestNotebookCitations(t *testing.T) {
+	fs := newFileStorageMock("/notebook", []string{"/notebook"})
+	fs.files = map[string]string{
+		"/notebook/refs.bib":  "knuth1984 ahrens2017",
+		"/notebook/more.json": "ahrens2017 luhmann1992",
+	}
+	parsed := 0
+	// Fake parser declaring one citation by word.
+	parse := func(path string, content []byte) ([]Citation, error) {
+		parsed++
+		citations := []Citation{}
+		for i, key := range strings.Fields(string(content)) {
+			citations = append(citations, Citation{Key: key, Path: path, Line: i + 1})
+		}
+		return citations, nil
+	}
+
+	config := NewDefaultConfig()
+	config.Bibliography.Files = []string{"refs.bib", "more.json"}
+	notebook := NewNotebook("/notebook", config, NotebookPorts{
+		FS:                 fs,
+		Logger:             &util.NullLogger,
+		BibliographyParser: parse,
+	})
+
+	citations, err := notebook.Citations()
+	assert.Nil(t, err)
+	assert.Equal(t, citations, []Citation{
+		{Key: "knuth1984", Path: "/notebook/refs.bib", Line: 1},
+		{Key: "ahrens2017", Path: "/notebook/refs.bib", Line: 2},
+		{Key: "luhmann1992", Path: "/notebook/more.json", Line: 2},
+	})
+	assert.Equal(t, parsed, 2)
+
+	// The unchanged files are not parsed again.
+	citation, err := notebook.FindCitation("luhmann1992")
+	assert.Nil(t, err)
+	assert.Equal(t, citation, &Citation{Key: "luhmann1992", Path: "/notebook/more.json", Line: 2})
+	assert.Equal(t, parsed, 2)
+
+	fs.files["/notebook/more.json"] = "luhmann1992"
+	citation, err = notebook.FindCitation("luhmann1992")
+	assert.Nil(t, err)
+	assert.Equal(t, citation, &Citation{Key: "luhmann1992", Path: "/notebook/more.json", Line: 1})
+	assert.Equal(t, parsed, 3)
+
+	citation, err = notebook.FindCitation("unknown")
+	assert.Nil(t, err)
+	assert.Nil(t, citation)
+}
+
+func TestNotebookCitationsWithoutParser(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Bibliography.Files = []string{"refs.bib"}
+	notebook := NewNotebook("/notebook", config, NotebookPorts{})
+
+	citations, err := notebook.Citations()
+	assert.Nil(t, err)
+	assert.Equal(t, citations, []Citation{})
+}
@@ -16,6 +16,9 @@ type Collection struct {
 	Name string
 	// Number of notes associated with this collection.
 	NoteCount int
+	// Color and icon of a tag, declared in the config.
+	Color string
+	Icon  string
 }
 
 // CollectionID represents the unique ID of a collection relative to a given
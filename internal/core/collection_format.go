@@ -14,6 +14,8 @@ func newCollectionFormatter(template Template) (CollectionFormatter, error) {
 			Kind:      collection.Kind,
 			Name:      collection.Name,
 			NoteCount: collection.NoteCount,
+			Color:     collection.Color,
+			Icon:      collection.Icon,
 		})
 	}, nil
 }
@@ -29,6 +31,10 @@ type collectionFormatRenderContext struct {
 	Name string `json:"name"`
 	// Number of notes associated with this collection.
 	NoteCount int `json:"noteCount" handlebars:"note-count"`
+	// Color of the tag, declared in the config.
+	Color string `json:"color,omitempty"`
+	// Icon of the tag, declared in the config.
+	Icon string `json:"icon,omitempty"`
 }
 
 func (c collectionFormatRenderContext) Equal(other collectionFormatRenderContext) bool {
@@ -3,7 +3,9 @@ package core
 import (
 	"fmt"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/mickael-menu/zk/internal/util/errors"
 	"github.com/mickael-menu/zk/internal/util/opt"
@@ -12,14 +14,37 @@ import (
 
 // Config holds the user configuration.
 type Config struct {
-	Note    NoteConfig
-	Groups  map[string]GroupConfig
-	Format  FormatConfig
-	Tool    ToolConfig
-	LSP     LSPConfig
-	Filters map[string]string
-	Aliases map[string]string
-	Extra   map[string]string
+	Note         NoteConfig
+	Groups       map[string]GroupConfig
+	Format       FormatConfig
+	Tool         ToolConfig
+	LSP          LSPConfig
+	Encryption   EncryptionConfig
+	Git          GitConfig
+	Search       SearchConfig
+	Trash        TrashConfig
+	Archive      ArchiveConfig
+	Asset        AssetConfig
+	Bibliography BibliographyConfig
+	Lint         LintConfig
+	Maintenance  MaintenanceConfig
+	Index        IndexConfig
+	Hooks        HooksConfig
+	Notebooks    map[string]string
+	Filters      map[string]string
+	Aliases      map[string]string
+	TagAliases   map[string]string
+	TagStyles    map[string]TagStyle
+	Renderers    map[string]string
+	Helpers      map[string]HelperConfig
+	Extra        map[string]string
+	// Machine-specific settings, by profile name.
+	Profiles map[string]ProfileConfig
+	// Name of the active profile, if any.
+	Profile string
+	// Prevents the templates from running shell commands with {{sh}}, in
+	// the notebooks not trusted by the user.
+	ShellDisabled bool
 }
 
 // NewDefaultConfig creates a new Config with the default settings.
@@ -32,9 +57,10 @@ func NewDefaultConfig() Config {
 			Lang:             "en",
 			DefaultTitle:     "Untitled",
 			IDOptions: IDOptions{
-				Charset: CharsetAlphanum,
-				Length:  4,
-				Case:    CaseLower,
+				Strategy: IDStrategyRandom,
+				Charset:  CharsetAlphanum,
+				Length:   4,
+				Case:     CaseLower,
 			},
 			Ignore: []string{},
 		},
@@ -56,15 +82,80 @@ func NewDefaultConfig() Config {
 					FilterText: opt.NullString,
 					Detail:     opt.NullString,
 				},
+				MaxItems:       100,
+				DateFormat:     "%Y-%m-%d",
+				TriggerStrings: []string{"[[", "](("},
 			},
 			Diagnostics: LSPDiagnosticConfig{
 				WikiTitle: LSPDiagnosticNone,
 				DeadLink:  LSPDiagnosticError,
+				FuzzyLink: LSPDiagnosticHint,
+				Footnote:  LSPDiagnosticWarning,
+				DeadURL:   LSPDiagnosticHint,
+				Citation:  LSPDiagnosticWarning,
+			},
+			CodeLens: LSPCodeLensConfig{
+				Backlinks: true,
+			},
+			Formatting: LSPFormattingConfig{
+				CloseWikiLinks: true,
+				ContinueLists:  true,
+			},
+		},
+		Encryption: EncryptionConfig{
+			Tool:       "gpg",
+			Recipients: []string{},
+			Identity:   opt.NullString,
+		},
+		Git: GitConfig{
+			AutoCommit:    false,
+			CommitMessage: `{{operation}}: {{join paths ", "}}`,
+		},
+		Search: SearchConfig{
+			Weights:            DefaultSearchWeights,
+			FuzzyLinkThreshold: 0.6,
+			Tokenizer:          DefaultSearchTokenizer,
+		},
+		Trash: TrashConfig{
+			RetentionDays: 30,
+		},
+		Asset: AssetConfig{
+			Dir:              "assets",
+			FilenameTemplate: "{{date now '%Y%m%d%H%M%S'}}",
+		},
+		Bibliography: BibliographyConfig{
+			Files: []string{},
+		},
+		Lint: LintConfig{
+			URLs: URLCheckConfig{
+				AllowHosts:  []string{},
+				DenyHosts:   []string{},
+				Concurrency: 4,
+				HostDelay:   time.Second,
+				MaxAge:      24 * time.Hour,
 			},
 		},
-		Filters: map[string]string{},
-		Aliases: map[string]string{},
-		Extra:   map[string]string{},
+		Maintenance: MaintenanceConfig{
+			Tasks: []MaintenanceTask{
+				MaintenanceTaskIndex,
+				MaintenanceTaskTrash,
+				MaintenanceTaskVacuum,
+			},
+		},
+		Index: IndexConfig{
+			WAL:         true,
+			BusyTimeout: 5 * time.Second,
+			LockRetries: 3,
+		},
+		Notebooks:  map[string]string{},
+		Filters:    map[string]string{},
+		Aliases:    map[string]string{},
+		TagAliases: map[string]string{},
+		TagStyles:  map[string]TagStyle{},
+		Renderers:  map[string]string{},
+		Helpers:    map[string]HelperConfig{},
+		Profiles:   map[string]ProfileConfig{},
+		Extra:      map[string]string{},
 	}
 }
 
@@ -101,6 +192,37 @@ func (c Config) GroupConfigNamed(name string) (GroupConfig, error) {
 	}
 }
 
+// ForPath returns the config applying to the note at the given path, relative
+// to the notebook, with the format and LSP settings overridden by its group.
+func (c Config) ForPath(path string) (Config, error) {
+	name, err := c.GroupNameForPath(path)
+	if err != nil {
+		return c, err
+	}
+	return c.ForGroup(name)
+}
+
+// ForGroup returns the config applying to the notes of the group with the
+// given name, with the format and LSP settings it overrides. An empty name
+// matches the root group.
+func (c Config) ForGroup(name string) (Config, error) {
+	if name == "" {
+		return c, nil
+	}
+	group, err := c.GroupConfigNamed(name)
+	if err != nil {
+		return c, err
+	}
+	for _, overrides := range group.Overrides {
+		c.Format.Markdown = c.Format.Markdown.merge(overrides.Markdown)
+		c.LSP, err = c.LSP.merge(overrides.LSP)
+		if err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
 // GroupNameForPath returns the name of the GroupConfig matching the given
 // path, relative to the notebook.
 func (c Config) GroupNameForPath(path string) (string, error) {
@@ -143,25 +265,439 @@ type MarkdownConfig struct {
 	LinkEncodePath bool
 	// Indicates whether a link's path file extension will be removed.
 	LinkDropExtension bool
+	// Indicates whether the alias of a wiki-link comes before its target,
+	// e.g. [[Displayed text|target]], instead of [[target|Displayed text]].
+	WikiLinkAliasFirst bool
+}
+
+// merge overrides the Markdown settings with the ones set in the TOML config.
+func (c MarkdownConfig) merge(markdown tomlMarkdownConfig) MarkdownConfig {
+	if markdown.Hashtags != nil {
+		c.Hashtags = *markdown.Hashtags
+	}
+	if markdown.ColonTags != nil {
+		c.ColonTags = *markdown.ColonTags
+	}
+	if markdown.MultiwordTags != nil {
+		c.MultiwordTags = *markdown.MultiwordTags
+	}
+	if markdown.LinkFormat != nil && *markdown.LinkFormat == "" {
+		*markdown.LinkFormat = "markdown"
+	}
+	if markdown.LinkFormat != nil {
+		c.LinkFormat = *markdown.LinkFormat
+	}
+	if markdown.LinkEncodePath != nil {
+		c.LinkEncodePath = *markdown.LinkEncodePath
+	} else if markdown.LinkFormat != nil {
+		c.LinkEncodePath = (*markdown.LinkFormat == "markdown")
+	}
+	if markdown.LinkDropExtension != nil {
+		c.LinkDropExtension = *markdown.LinkDropExtension
+	}
+	if markdown.WikiLinkAliasFirst != nil {
+		c.WikiLinkAliasFirst = *markdown.WikiLinkAliasFirst
+	}
+	return c
 }
 
 // ToolConfig holds the external tooling configuration.
 type ToolConfig struct {
-	Editor     opt.String
-	Pager      opt.String
-	FzfPreview opt.String
-	FzfLine    opt.String
+	Editor opt.String
+	// Arguments given to the editor to open files side by side, e.g. -O
+	// with Vim.
+	EditorSplit opt.String
+	Pager       opt.String
+	FzfPreview  opt.String
+	FzfLine     opt.String
+	// Command extracting the text of a PDF piped to its standard input.
+	PDFText opt.String
+	// Command extracting the text of an image piped to its standard input.
+	OCR opt.String
+}
+
+// merge overrides the tool settings with the ones set in the TOML config.
+func (c ToolConfig) merge(tool tomlToolConfig) ToolConfig {
+	if tool.Editor != nil {
+		c.Editor = opt.NewNotEmptyString(*tool.Editor)
+	}
+	if tool.EditorSplit != nil {
+		c.EditorSplit = opt.NewNotEmptyString(*tool.EditorSplit)
+	}
+	if tool.Pager != nil {
+		c.Pager = opt.NewStringWithPtr(tool.Pager)
+	}
+	if tool.FzfPreview != nil {
+		c.FzfPreview = opt.NewStringWithPtr(tool.FzfPreview)
+	}
+	if tool.FzfLine != nil {
+		c.FzfLine = opt.NewNotEmptyString(*tool.FzfLine)
+	}
+	if tool.PDFText != nil {
+		c.PDFText = opt.NewNotEmptyString(*tool.PDFText)
+	}
+	if tool.OCR != nil {
+		c.OCR = opt.NewNotEmptyString(*tool.OCR)
+	}
+	return c
+}
+
+// override replaces the tool settings with the ones which are not null in
+// other.
+func (c ToolConfig) override(other ToolConfig) ToolConfig {
+	c.Editor = other.Editor.Or(c.Editor)
+	c.EditorSplit = other.EditorSplit.Or(c.EditorSplit)
+	c.Pager = other.Pager.Or(c.Pager)
+	c.FzfPreview = other.FzfPreview.Or(c.FzfPreview)
+	c.FzfLine = other.FzfLine.Or(c.FzfLine)
+	c.PDFText = other.PDFText.Or(c.PDFText)
+	c.OCR = other.OCR.Or(c.OCR)
+	return c
+}
+
+// ProfileConfig holds the machine-specific settings of a profile, selected
+// with the --profile flag or the ZK_PROFILE environment variable.
+type ProfileConfig struct {
+	// Tool settings overriding the [tool] section. Null settings are
+	// inherited.
+	Tool ToolConfig
+	// Notebook used when none is found from the working directory.
+	NotebookDir opt.String
+	// Locations of the other notebooks, overriding the [notebooks] section.
+	Notebooks map[string]string
+}
+
+// SearchConfig holds the full-text search configuration.
+type SearchConfig struct {
+	// Weights of the note fields when ranking the notes matching a query.
+	Weights SearchWeights
+	// Minimum similarity, from 0 to 1, of the path or title of a note with
+	// the href of a link which doesn't resolve exactly. 0 disables the
+	// fuzzy resolution of the links.
+	FuzzyLinkThreshold float64
+	// Splits the notes into the terms of the full-text index.
+	Tokenizer SearchTokenizer
+}
+
+// TrashConfig holds the configuration of the notes moved to the trash.
+type TrashConfig struct {
+	// Number of days a trashed note is kept after its last modification,
+	// before being purged. 0 keeps the trashed notes forever.
+	RetentionDays int
+	// Directory where the notes are archived, relative to the notebook root.
+	// The archived notes are hidden from the searches and the completion by
+	// default. Empty when the archive is disabled.
+	ArchiveDir string
+}
+
+// ArchiveConfig holds the configuration of the web archive used to snapshot
+// the external URLs linked in the notes.
+type ArchiveConfig struct {
+	// Shell command submitting the URL given as first argument to a web
+	// archive, and printing the URL of the archived version. When empty,
+	// the Wayback Machine is used.
+	Command string
+}
+
+// AssetConfig holds the configuration of the assets added from the editor,
+// e.g. a pasted image.
+type AssetConfig struct {
+	// Directory where the assets are saved, relative to the notebook root.
+	Dir string
+	// Handlebars template used to generate the filename of an asset, without
+	// its extension.
+	FilenameTemplate string
+}
+
+// LintConfig holds the configuration of `zk lint`.
+type LintConfig struct {
+	// HTTP checks of the external URLs linked in the notes.
+	URLs URLCheckConfig
+}
+
+// URLCheckConfig holds the configuration of the external URLs checked with
+// `zk lint --check-urls`.
+type URLCheckConfig struct {
+	// Hosts whose URLs are checked, including their subdomains. All the
+	// hosts are checked when empty.
+	AllowHosts []string
+	// Hosts whose URLs are never checked nor reported, including their
+	// subdomains.
+	DenyHosts []string
+	// Maximum number of URLs checked at the same time.
+	Concurrency int
+	// Minimum delay between two requests to the same host.
+	HostDelay time.Duration
+	// Duration during which the status of a checked URL is reused.
+	MaxAge time.Duration
+}
+
+// MaintenanceConfig holds the configuration of `zk maintenance`.
+type MaintenanceConfig struct {
+	// Housekeeping tasks performed by default.
+	Tasks []MaintenanceTask
+}
+
+// IndexConfig holds the configuration of the notebook index database.
+type IndexConfig struct {
+	// Enables the write-ahead log of the database, to let the editors, the
+	// LSP server and the CLI commands use the index concurrently.
+	WAL bool
+	// Duration to wait for the index to be unlocked by another process.
+	BusyTimeout time.Duration
+	// Number of times the index is locked again after the busy timeout,
+	// before giving up.
+	LockRetries int
+	// Rewrites the links to the notes renamed or moved outside of zk,
+	// when they are detected while indexing.
+	RelinkRenamed bool
+}
+
+// HooksConfig holds the shell commands run when the notes are modified,
+// which receive a JSON payload of the affected notes on their standard input.
+type HooksConfig struct {
+	// Command run after creating new notes.
+	PostNew string
+	// Command run before indexing the notebook. Indexing is aborted when it
+	// fails.
+	PreIndex string
+	// Command run after indexing the notebook, when some notes changed.
+	PostIndex string
+}
+
+// HelperConfig holds the definition of a custom template helper.
+//
+// A helper is either a Handlebars snippet rendered with the named parameters
+// as context, or a shell command receiving them as positional arguments.
+type HelperConfig struct {
+	// Names of the positional parameters expected by the helper.
+	Params []string
+	// Handlebars snippet rendered by the helper.
+	Template string
+	// Shell command run by the helper, its output is rendered.
+	Command string
+}
+
+// SearchWeights are the BM25 weights of the note fields used to rank the
+// notes matching a full-text search. A higher weight boosts the notes
+// matching the query in this field.
+type SearchWeights struct {
+	Path  float64
+	Title float64
+	Body  float64
+	Tags  float64
+}
+
+// DefaultSearchWeights favors the matches in the path and title of the notes
+// over their body.
+var DefaultSearchWeights = SearchWeights{
+	Path:  1000.0,
+	Title: 500.0,
+	Body:  1.0,
+	Tags:  100.0,
+}
+
+// TokenizerKind is a tokenizer of the full-text index, see SearchTokenizer.
+type TokenizerKind string
+
+const (
+	// TokenizerPorter splits the words like TokenizerUnicode61, and reduces
+	// them to their English stem, e.g. "running" matches "run".
+	TokenizerPorter TokenizerKind = "porter"
+	// TokenizerUnicode61 splits the words of any language written with
+	// spaces between them, without stemming.
+	TokenizerUnicode61 TokenizerKind = "unicode61"
+	// TokenizerTrigram indexes each sequence of three characters, to match
+	// any substring of at least three characters. It suits the languages
+	// written without spaces, such as Chinese or Japanese.
+	TokenizerTrigram TokenizerKind = "trigram"
+)
+
+// SearchTokenizer configures how the notes are split into the terms of the
+// full-text index. The index is rebuilt when it changes.
+type SearchTokenizer struct {
+	Kind TokenizerKind
+	// Removes the diacritics of the Latin letters, e.g. "é" matches "e":
+	// 0 keeps them, 1 removes them and 2 also removes the combining
+	// diacritics. Ignored by the trigram tokenizer.
+	RemoveDiacritics int
+	// Characters considered part of the words, in addition to the letters
+	// and digits. Ignored by the trigram tokenizer.
+	TokenChars string
+	// Characters separating the words, in addition to the whitespace and
+	// punctuation. Ignored by the trigram tokenizer.
+	Separators string
+}
+
+// DefaultSearchTokenizer stems the English words, and keeps the apostrophes,
+// ampersands and slashes in the words.
+var DefaultSearchTokenizer = SearchTokenizer{
+	Kind:             TokenizerPorter,
+	RemoveDiacritics: 1,
+	TokenChars:       "'&/",
 }
 
 // LSPConfig holds the Language Server Protocol configuration.
 type LSPConfig struct {
 	Completion  LSPCompletionConfig
 	Diagnostics LSPDiagnosticConfig
+	Links       LSPLinksConfig
+	CodeLens    LSPCodeLensConfig
+	Formatting  LSPFormattingConfig
+	// ReadOnly prevents the LSP server from modifying the notebook, e.g.
+	// for a shared checkout only edited with pull requests.
+	ReadOnly bool
+}
+
+// merge overrides the LSP settings with the ones set in the TOML config.
+func (c LSPConfig) merge(tomlConf tomlLSPConfig) (LSPConfig, error) {
+	var err error
+
+	// Completion
+	lspCompl := tomlConf.Completion
+	if lspCompl.NoteLabel != nil {
+		c.Completion.Note.Label = opt.NewNotEmptyString(*lspCompl.NoteLabel)
+	}
+	if lspCompl.NoteFilterText != nil {
+		c.Completion.Note.FilterText = opt.NewNotEmptyString(*lspCompl.NoteFilterText)
+	}
+	if lspCompl.NoteDetail != nil {
+		c.Completion.Note.Detail = opt.NewNotEmptyString(*lspCompl.NoteDetail)
+	}
+	if lspCompl.MarkdownLinks != nil {
+		c.Completion.MarkdownLinks = *lspCompl.MarkdownLinks
+	}
+	if lspCompl.MaxItems != nil {
+		if *lspCompl.MaxItems < 0 {
+			return c, fmt.Errorf("%d: the maximum number of completion items can't be negative", *lspCompl.MaxItems)
+		}
+		c.Completion.MaxItems = *lspCompl.MaxItems
+	}
+	if lspCompl.DateTrigger != nil {
+		if strings.ContainsAny(*lspCompl.DateTrigger, " \t\n") {
+			return c, fmt.Errorf("%s: the date completion trigger can't contain whitespace", *lspCompl.DateTrigger)
+		}
+		c.Completion.DateTrigger = *lspCompl.DateTrigger
+	}
+	if lspCompl.DateFormat != nil && *lspCompl.DateFormat != "" {
+		c.Completion.DateFormat = *lspCompl.DateFormat
+	}
+	if lspCompl.IncludeArchived != nil {
+		c.Completion.IncludeArchived = *lspCompl.IncludeArchived
+	}
+	if lspCompl.WikiTitle != nil {
+		c.Completion.WikiTitle = *lspCompl.WikiTitle
+	}
+	if lspCompl.UseAdditionalTextEdits != nil {
+		c.Completion.UseAdditionalTextEdits = lspCompl.UseAdditionalTextEdits
+	}
+	if lspCompl.TriggerStrings != nil {
+		for _, trigger := range lspCompl.TriggerStrings {
+			if trigger == "" || strings.ContainsAny(trigger, " \t\n") {
+				return c, fmt.Errorf("%q: the link completion triggers can't be empty or contain whitespace", trigger)
+			}
+		}
+		c.Completion.TriggerStrings = lspCompl.TriggerStrings
+	}
+
+	// Diagnostics
+	lspDiags := tomlConf.Diagnostics
+	if lspDiags.WikiTitle != nil {
+		c.Diagnostics.WikiTitle, err = lspDiagnosticSeverityFromString(*lspDiags.WikiTitle)
+		if err != nil {
+			return c, err
+		}
+	}
+	if lspDiags.DeadLink != nil {
+		c.Diagnostics.DeadLink, err = lspDiagnosticSeverityFromString(*lspDiags.DeadLink)
+		if err != nil {
+			return c, err
+		}
+	}
+	if lspDiags.FuzzyLink != nil {
+		c.Diagnostics.FuzzyLink, err = lspDiagnosticSeverityFromString(*lspDiags.FuzzyLink)
+		if err != nil {
+			return c, err
+		}
+	}
+	if lspDiags.Footnote != nil {
+		c.Diagnostics.Footnote, err = lspDiagnosticSeverityFromString(*lspDiags.Footnote)
+		if err != nil {
+			return c, err
+		}
+	}
+	if lspDiags.DeadURL != nil {
+		c.Diagnostics.DeadURL, err = lspDiagnosticSeverityFromString(*lspDiags.DeadURL)
+		if err != nil {
+			return c, err
+		}
+	}
+	if lspDiags.Citation != nil {
+		c.Diagnostics.Citation, err = lspDiagnosticSeverityFromString(*lspDiags.Citation)
+		if err != nil {
+			return c, err
+		}
+	}
+
+	// Links
+	if tomlConf.Links.PublishedURL != nil {
+		c.Links.PublishedURL = opt.NewNotEmptyString(*tomlConf.Links.PublishedURL)
+	}
+	if tomlConf.Links.FetchURLMetadata != nil {
+		c.Links.FetchURLMetadata = *tomlConf.Links.FetchURLMetadata
+	}
+
+	// Code lenses
+	if tomlConf.CodeLens.Backlinks != nil {
+		c.CodeLens.Backlinks = *tomlConf.CodeLens.Backlinks
+	}
+	if tomlConf.CodeLens.SuggestLinks != nil {
+		c.CodeLens.SuggestLinks = *tomlConf.CodeLens.SuggestLinks
+	}
+
+	if tomlConf.ReadOnly != nil {
+		c.ReadOnly = *tomlConf.ReadOnly
+	}
+
+	// Formatting
+	if tomlConf.Formatting.CloseWikiLinks != nil {
+		c.Formatting.CloseWikiLinks = *tomlConf.Formatting.CloseWikiLinks
+	}
+	if tomlConf.Formatting.ContinueLists != nil {
+		c.Formatting.ContinueLists = *tomlConf.Formatting.ContinueLists
+	}
+
+	return c, nil
 }
 
 // LSPCompletionConfig holds the LSP auto-completion configuration.
 type LSPCompletionConfig struct {
 	Note LSPCompletionTemplates
+	// MarkdownLinks enables the completion of regular Markdown links after
+	// typing `[text](`, using the link text to search the note titles.
+	MarkdownLinks bool
+	// MaxItems is the maximum number of notes offered when completing a
+	// link, 0 for no limit.
+	MaxItems int
+	// DateTrigger is the string starting the completion of natural language
+	// dates, e.g. `@` for `@next monday`. Disabled when empty.
+	DateTrigger string
+	// DateFormat is the format of the completed dates, either a named format
+	// or a strftime pattern.
+	DateFormat string
+	// IncludeArchived offers the notes of the archive directory when
+	// completing a link.
+	IncludeArchived bool
+	// WikiTitle inserts the completed wiki-links with the note title as
+	// alias, e.g. [[id|Title]].
+	WikiTitle bool
+	// UseAdditionalTextEdits deletes the typed trigger with an additional
+	// text edit, for the clients which can't replace it with the inserted
+	// link. Nil to detect it from the client capabilities.
+	UseAdditionalTextEdits *bool
+	// TriggerStrings are the strings starting the completion of a link to a
+	// note, e.g. `[[`.
+	TriggerStrings []string
 }
 
 // LSPCompletionConfig holds the LSP completion templates for a particular
@@ -176,6 +712,45 @@ type LSPCompletionTemplates struct {
 type LSPDiagnosticConfig struct {
 	WikiTitle LSPDiagnosticSeverity
 	DeadLink  LSPDiagnosticSeverity
+	// Links resolved with a fuzzy match, with a low confidence.
+	FuzzyLink LSPDiagnosticSeverity
+	// Undefined or unused footnotes.
+	Footnote LSPDiagnosticSeverity
+	// External URLs which were unreachable when last fetched or checked.
+	DeadURL LSPDiagnosticSeverity
+	// Citation keys not declared in the bibliography.
+	Citation LSPDiagnosticSeverity
+}
+
+// LSPLinksConfig holds the LSP document links configuration.
+type LSPLinksConfig struct {
+	// PublishedURL is the base URL where the notebook is published. When
+	// set, document links resolve to the published URL of the notes instead
+	// of their file.
+	PublishedURL opt.String
+	// FetchURLMetadata enables fetching the titles and status of the
+	// external URLs linked in the notes, in the background.
+	FetchURLMetadata bool
+}
+
+// LSPCodeLensConfig holds the LSP code lenses configuration.
+type LSPCodeLensConfig struct {
+	// Backlinks shows the number of backlinks above the title of a note.
+	Backlinks bool
+	// SuggestLinks lists the related notes which are not linked yet above
+	// the title of a note.
+	SuggestLinks bool
+}
+
+// LSPFormattingConfig holds the LSP on-type formatting configuration.
+type LSPFormattingConfig struct {
+	// CloseWikiLinks inserts the closing ]] after typing [[, and skips over
+	// it when typed.
+	CloseWikiLinks bool
+	// ContinueLists inserts the bullet, checkbox or number of the previous
+	// list item after a new line, and renumbers the following items of an
+	// ordered list.
+	ContinueLists bool
 }
 
 type LSPDiagnosticSeverity int
@@ -204,6 +779,12 @@ type NoteConfig struct {
 	IDOptions IDOptions
 	// Path globs to ignore when indexing notes.
 	Ignore []string
+	// Carry over the unchecked tasks of the previous note of the directory
+	// when creating a new note, e.g. for a daily journal.
+	CarryOverTasks bool
+	// Headings of the sections whose tasks are carried over. The tasks of
+	// the whole note are carried over when empty.
+	CarryOverSections []string
 }
 
 // GroupConfig holds the user configuration for a given group of notes.
@@ -211,6 +792,38 @@ type GroupConfig struct {
 	Paths []string
 	Note  NoteConfig
 	Extra map[string]string
+	// Store the notes of this group encrypted on disk.
+	Encrypt bool
+
+	// Format and LSP settings of the group, in the order of the config
+	// files. They are applied on top of the notebook settings by
+	// Config.ForPath, so that the group still inherits the notebook settings
+	// declared afterwards.
+	Overrides []groupOverrides
+}
+
+// groupOverrides holds the settings of a group overriding the ones of the
+// notebook, as declared in a config file.
+type groupOverrides struct {
+	Markdown tomlMarkdownConfig
+	LSP      tomlLSPConfig
+}
+
+// validateGroupOverrides checks the format and LSP settings of a group, which
+// can't change the settings applying to the whole notebook.
+func validateGroupOverrides(group tomlGroupConfig) error {
+	markdown := group.Format.Markdown
+	if markdown.Hashtags != nil || markdown.ColonTags != nil || markdown.MultiwordTags != nil {
+		return errors.New("the tag syntaxes can't be overridden in a group, they apply to the whole notebook")
+	}
+	if markdown.WikiLinkAliasFirst != nil {
+		return errors.New("the wiki-link-alias-first setting can't be overridden in a group, it applies to the whole notebook")
+	}
+	if group.LSP.Links.PublishedURL != nil || group.LSP.Links.FetchURLMetadata != nil {
+		return errors.New("the [lsp.links] settings can't be overridden in a group, they apply to the whole notebook")
+	}
+	_, err := LSPConfig{}.merge(group.LSP)
+	return err
 }
 
 // IgnoreGlobs returns all the Note.Ignore path globs for the group paths,
@@ -240,6 +853,10 @@ func (c GroupConfig) Clone() GroupConfig {
 	for k, v := range c.Extra {
 		clone.Extra[k] = v
 	}
+
+	if c.Overrides != nil {
+		clone.Overrides = append([]groupOverrides{}, c.Overrides...)
+	}
 	return clone
 }
 
@@ -268,7 +885,9 @@ func OpenConfig(path string, parentConfig Config, fs FileStorage) (Config, error
 func ParseConfig(content []byte, path string, parentConfig Config) (Config, error) {
 	wrap := errors.Wrapperf("failed to read config")
 
-	config := parentConfig
+	// The maps are copied to not leak the settings of a notebook into the
+	// parent config, shared by the other notebooks.
+	config := parentConfig.copyMaps()
 
 	var tomlConf tomlConfig
 	err := toml.Unmarshal(content, &tomlConf)
@@ -287,6 +906,12 @@ func ParseConfig(content []byte, path string, parentConfig Config) (Config, erro
 	if note.Template != "" {
 		config.Note.BodyTemplatePath = opt.NewNotEmptyString(note.Template)
 	}
+	if note.IDStrategy != "" {
+		config.Note.IDOptions.Strategy, err = IDStrategyFromString(note.IDStrategy)
+		if err != nil {
+			return config, wrap(err)
+		}
+	}
 	if note.IDLength != 0 {
 		config.Note.IDOptions.Length = note.IDLength
 	}
@@ -305,6 +930,12 @@ func ParseConfig(content []byte, path string, parentConfig Config) (Config, erro
 	for _, v := range note.Ignore {
 		config.Note.Ignore = append(config.Note.Ignore, v)
 	}
+	if note.CarryOverTasks != nil {
+		config.Note.CarryOverTasks = *note.CarryOverTasks
+	}
+	if note.CarryOverSections != nil {
+		config.Note.CarryOverSections = note.CarryOverSections
+	}
 	if tomlConf.Extra != nil {
 		for k, v := range tomlConf.Extra {
 			config.Extra[k] = v
@@ -318,75 +949,207 @@ func ParseConfig(content []byte, path string, parentConfig Config) (Config, erro
 			parent = config.RootGroupConfig()
 		}
 
+		if dirTOML.Note.IDStrategy != "" {
+			if _, err := IDStrategyFromString(dirTOML.Note.IDStrategy); err != nil {
+				return config, wrap(err)
+			}
+		}
+		if err := validateGroupOverrides(dirTOML); err != nil {
+			return config, wrap(errors.Wrapf(err, "[group.%s]", name))
+		}
+
 		config.Groups[name] = parent.merge(dirTOML, name)
 	}
 
 	// Format
-	markdown := tomlConf.Format.Markdown
-	if markdown.Hashtags != nil {
-		config.Format.Markdown.Hashtags = *markdown.Hashtags
+	config.Format.Markdown = config.Format.Markdown.merge(tomlConf.Format.Markdown)
+
+	// Tool
+	config.Tool = config.Tool.merge(tomlConf.Tool)
+
+	// LSP
+	config.LSP, err = config.LSP.merge(tomlConf.LSP)
+	if err != nil {
+		return config, wrap(err)
 	}
-	if markdown.ColonTags != nil {
-		config.Format.Markdown.ColonTags = *markdown.ColonTags
+
+	// Encryption
+	encryption := tomlConf.Encryption
+	if encryption.Tool != nil {
+		switch *encryption.Tool {
+		case "gpg", "age":
+			config.Encryption.Tool = *encryption.Tool
+		default:
+			return config, wrap(fmt.Errorf("%s: unknown encryption tool, expected gpg or age", *encryption.Tool))
+		}
 	}
-	if markdown.MultiwordTags != nil {
-		config.Format.Markdown.MultiwordTags = *markdown.MultiwordTags
+	if encryption.Recipients != nil {
+		config.Encryption.Recipients = encryption.Recipients
 	}
-	if markdown.LinkFormat != nil && *markdown.LinkFormat == "" {
-		*markdown.LinkFormat = "markdown"
+	if encryption.Identity != nil {
+		config.Encryption.Identity = opt.NewNotEmptyString(*encryption.Identity)
 	}
-	if markdown.LinkFormat != nil {
-		config.Format.Markdown.LinkFormat = *markdown.LinkFormat
+
+	// Git
+	git := tomlConf.Git
+	if git.AutoCommit != nil {
+		config.Git.AutoCommit = *git.AutoCommit
 	}
-	if markdown.LinkEncodePath != nil {
-		config.Format.Markdown.LinkEncodePath = *markdown.LinkEncodePath
-	} else if markdown.LinkFormat != nil {
-		config.Format.Markdown.LinkEncodePath = (*markdown.LinkFormat == "markdown")
+	if git.CommitMessage != nil {
+		config.Git.CommitMessage = *git.CommitMessage
 	}
-	if markdown.LinkDropExtension != nil {
-		config.Format.Markdown.LinkDropExtension = *markdown.LinkDropExtension
+
+	// Search
+	search := tomlConf.Search
+	for _, weight := range []struct {
+		value  interface{}
+		target *float64
+	}{
+		{search.PathWeight, &config.Search.Weights.Path},
+		{search.TitleWeight, &config.Search.Weights.Title},
+		{search.BodyWeight, &config.Search.Weights.Body},
+		{search.TagsWeight, &config.Search.Weights.Tags},
+	} {
+		if weight.value == nil {
+			continue
+		}
+		value, err := tomlFloat(weight.value)
+		if err != nil {
+			return config, wrap(errors.Wrap(err, "invalid search weight"))
+		}
+		if value < 0 {
+			return config, wrap(fmt.Errorf("%v: search weights can't be negative", value))
+		}
+		*weight.target = value
+	}
+	if search.FuzzyLinkThreshold != nil {
+		value, err := tomlFloat(search.FuzzyLinkThreshold)
+		if err != nil {
+			return config, wrap(errors.Wrap(err, "invalid fuzzy link threshold"))
+		}
+		if value < 0 || value > 1 {
+			return config, wrap(fmt.Errorf("%v: the fuzzy link threshold must be between 0 and 1", value))
+		}
+		config.Search.FuzzyLinkThreshold = value
+	}
+	if search.Tokenizer != nil {
+		kind := TokenizerKind(*search.Tokenizer)
+		switch kind {
+		case TokenizerPorter, TokenizerUnicode61, TokenizerTrigram:
+			config.Search.Tokenizer.Kind = kind
+		default:
+			return config, wrap(fmt.Errorf("%s: unknown search tokenizer, expected porter, unicode61 or trigram", kind))
+		}
+	}
+	if search.RemoveDiacritics != nil {
+		if *search.RemoveDiacritics < 0 || *search.RemoveDiacritics > 2 {
+			return config, wrap(fmt.Errorf("%d: remove-diacritics must be 0, 1 or 2", *search.RemoveDiacritics))
+		}
+		config.Search.Tokenizer.RemoveDiacritics = *search.RemoveDiacritics
+	}
+	if search.TokenChars != nil {
+		config.Search.Tokenizer.TokenChars = *search.TokenChars
+	}
+	if search.Separators != nil {
+		config.Search.Tokenizer.Separators = *search.Separators
 	}
 
-	// Tool
-	tool := tomlConf.Tool
-	if tool.Editor != nil {
-		config.Tool.Editor = opt.NewNotEmptyString(*tool.Editor)
+	// Trash
+	if tomlConf.Trash.RetentionDays != nil {
+		if *tomlConf.Trash.RetentionDays < 0 {
+			return config, wrap(fmt.Errorf("%d: the trash retention period can't be negative", *tomlConf.Trash.RetentionDays))
+		}
+		config.Trash.RetentionDays = *tomlConf.Trash.RetentionDays
 	}
-	if tool.Pager != nil {
-		config.Tool.Pager = opt.NewStringWithPtr(tool.Pager)
+	if dir := tomlConf.Trash.ArchiveDir; dir != "" {
+		dir = filepath.Clean(dir)
+		if filepath.IsAbs(dir) || dir == "." || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+			return config, wrap(fmt.Errorf("%s: the archive directory must be relative to the notebook root", tomlConf.Trash.ArchiveDir))
+		}
+		config.Trash.ArchiveDir = dir
 	}
-	if tool.FzfPreview != nil {
-		config.Tool.FzfPreview = opt.NewStringWithPtr(tool.FzfPreview)
+
+	// Archive
+	if tomlConf.Archive.Command != "" {
+		config.Archive.Command = tomlConf.Archive.Command
 	}
-	if tool.FzfLine != nil {
-		config.Tool.FzfLine = opt.NewNotEmptyString(*tool.FzfLine)
+
+	// Asset
+	if dir := tomlConf.Asset.Dir; dir != "" {
+		dir = filepath.Clean(dir)
+		if filepath.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+			return config, wrap(fmt.Errorf("%s: the asset directory must be relative to the notebook root", tomlConf.Asset.Dir))
+		}
+		config.Asset.Dir = dir
+	}
+	if tomlConf.Asset.Filename != "" {
+		config.Asset.FilenameTemplate = tomlConf.Asset.Filename
 	}
 
-	// LSP completion
-	lspCompl := tomlConf.LSP.Completion
-	if lspCompl.NoteLabel != nil {
-		config.LSP.Completion.Note.Label = opt.NewNotEmptyString(*lspCompl.NoteLabel)
+	// Bibliography
+	if tomlConf.Bibliography.Files != nil {
+		config.Bibliography.Files = tomlConf.Bibliography.Files
 	}
-	if lspCompl.NoteFilterText != nil {
-		config.LSP.Completion.Note.FilterText = opt.NewNotEmptyString(*lspCompl.NoteFilterText)
+
+	// Lint
+	urls := tomlConf.Lint.URLs
+	if urls.AllowHosts != nil {
+		config.Lint.URLs.AllowHosts = urls.AllowHosts
 	}
-	if lspCompl.NoteDetail != nil {
-		config.LSP.Completion.Note.Detail = opt.NewNotEmptyString(*lspCompl.NoteDetail)
+	if urls.DenyHosts != nil {
+		config.Lint.URLs.DenyHosts = urls.DenyHosts
+	}
+	if urls.Concurrency != nil {
+		if *urls.Concurrency < 1 {
+			return config, wrap(fmt.Errorf("%d: at least one URL must be checked at a time", *urls.Concurrency))
+		}
+		config.Lint.URLs.Concurrency = *urls.Concurrency
+	}
+	if urls.HostDelay != nil {
+		if *urls.HostDelay < 0 {
+			return config, wrap(fmt.Errorf("%d: the delay between the requests to a host can't be negative", *urls.HostDelay))
+		}
+		config.Lint.URLs.HostDelay = time.Duration(*urls.HostDelay) * time.Millisecond
+	}
+	if urls.MaxAge != nil {
+		if *urls.MaxAge < 0 {
+			return config, wrap(fmt.Errorf("%d: the maximum age of the checked URLs can't be negative", *urls.MaxAge))
+		}
+		config.Lint.URLs.MaxAge = time.Duration(*urls.MaxAge) * time.Hour
 	}
 
-	// LSP diagnostics
-	lspDiags := tomlConf.LSP.Diagnostics
-	if lspDiags.WikiTitle != nil {
-		config.LSP.Diagnostics.WikiTitle, err = lspDiagnosticSeverityFromString(*lspDiags.WikiTitle)
-		if err != nil {
-			return config, wrap(err)
+	// Index
+	index := tomlConf.Index
+	if index.WAL != nil {
+		config.Index.WAL = *index.WAL
+	}
+	if index.BusyTimeout != nil {
+		if *index.BusyTimeout < 0 {
+			return config, wrap(fmt.Errorf("%d: the index busy timeout can't be negative", *index.BusyTimeout))
 		}
+		config.Index.BusyTimeout = time.Duration(*index.BusyTimeout) * time.Millisecond
 	}
-	if lspDiags.DeadLink != nil {
-		config.LSP.Diagnostics.DeadLink, err = lspDiagnosticSeverityFromString(*lspDiags.DeadLink)
-		if err != nil {
-			return config, wrap(err)
+	if index.LockRetries != nil {
+		if *index.LockRetries < 0 {
+			return config, wrap(fmt.Errorf("%d: the number of index lock retries can't be negative", *index.LockRetries))
 		}
+		config.Index.LockRetries = *index.LockRetries
+	}
+	if index.RelinkRenamed != nil {
+		config.Index.RelinkRenamed = *index.RelinkRenamed
+	}
+
+	// Maintenance
+	if tomlConf.Maintenance.Tasks != nil {
+		tasks := []MaintenanceTask{}
+		for _, name := range tomlConf.Maintenance.Tasks {
+			task, err := MaintenanceTaskFromString(name)
+			if err != nil {
+				return config, wrap(err)
+			}
+			tasks = append(tasks, task)
+		}
+		config.Maintenance.Tasks = tasks
 	}
 
 	// Filters
@@ -396,6 +1159,25 @@ func ParseConfig(content []byte, path string, parentConfig Config) (Config, erro
 		}
 	}
 
+	// Hooks
+	if tomlConf.Hooks.PostNew != "" {
+		config.Hooks.PostNew = tomlConf.Hooks.PostNew
+	}
+	if tomlConf.Hooks.PreIndex != "" {
+		config.Hooks.PreIndex = tomlConf.Hooks.PreIndex
+	}
+	if tomlConf.Hooks.PostIndex != "" {
+		config.Hooks.PostIndex = tomlConf.Hooks.PostIndex
+	}
+
+	// Notebooks
+	for prefix, path := range tomlConf.Notebooks {
+		if err := validateNotebookPrefix(prefix); err != nil {
+			return config, wrap(err)
+		}
+		config.Notebooks[prefix] = path
+	}
+
 	// Aliases
 	if tomlConf.Aliases != nil {
 		for k, v := range tomlConf.Aliases {
@@ -403,9 +1185,177 @@ func ParseConfig(content []byte, path string, parentConfig Config) (Config, erro
 		}
 	}
 
+	// Tag aliases
+	for alias, tag := range tomlConf.TagAliases {
+		alias = strings.TrimPrefix(alias, "#")
+		tag = strings.TrimPrefix(tag, "#")
+		if alias == "" || tag == "" {
+			return config, wrap(fmt.Errorf("%s = %s: invalid tag alias, the tags can't be empty", alias, tag))
+		}
+		config.TagAliases[alias] = tag
+	}
+
+	// Tag styles
+	for tag, style := range tomlConf.TagStyles {
+		tag = strings.TrimPrefix(tag, "#")
+		if tag == "" {
+			return config, wrap(errors.New("invalid tag style, the tag can't be empty"))
+		}
+		if err := validateTagColor(style.Color); err != nil {
+			return config, wrap(errors.Wrapf(err, "[tag-style.%s]", tag))
+		}
+		config.TagStyles[tag] = TagStyle{Color: style.Color, Icon: style.Icon}
+	}
+
+	// Renderers
+	for lang, command := range tomlConf.Renderers {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" || command == "" {
+			return config, wrap(fmt.Errorf("%s = %s: invalid renderer, the language and command can't be empty", lang, command))
+		}
+		config.Renderers[lang] = command
+	}
+
+	// Helpers
+	for name, helper := range tomlConf.Helpers {
+		if name == "" || strings.ContainsAny(name, " \t.") {
+			return config, wrap(fmt.Errorf("%s: invalid template helper name, it can't contain spaces or dots", name))
+		}
+		if (helper.Template == "") == (helper.Command == "") {
+			return config, wrap(fmt.Errorf("%s: a template helper requires either a template or a command", name))
+		}
+		config.Helpers[name] = HelperConfig{
+			Params:   helper.Params,
+			Template: helper.Template,
+			Command:  helper.Command,
+		}
+	}
+
+	// Profiles
+	for name, profileTOML := range tomlConf.Profiles {
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return config, wrap(fmt.Errorf("%s: invalid profile name, it can't contain spaces", name))
+		}
+		profile, ok := config.Profiles[name]
+		if !ok {
+			profile = ProfileConfig{Notebooks: map[string]string{}}
+		}
+		profile.Tool = profile.Tool.merge(profileTOML.Tool)
+		if profileTOML.NotebookDir != nil {
+			profile.NotebookDir = opt.NewNotEmptyString(*profileTOML.NotebookDir)
+		}
+		for prefix, path := range profileTOML.Notebooks {
+			if err := validateNotebookPrefix(prefix); err != nil {
+				return config, wrap(err)
+			}
+			profile.Notebooks[prefix] = path
+		}
+		config.Profiles[name] = profile
+	}
+
+	// The active profile is applied again, for its settings to take
+	// precedence over the ones of the notebook config.
+	config, err = config.WithProfile(config.Profile)
+	if err != nil {
+		return config, wrap(err)
+	}
+
 	return config, nil
 }
 
+func validateNotebookPrefix(prefix string) error {
+	if prefix == "" || strings.ContainsAny(prefix, ": \t") {
+		return fmt.Errorf("%s: invalid notebook prefix, it can't contain spaces or colons", prefix)
+	}
+	return nil
+}
+
+// copyMaps returns a copy of the config which doesn't share its maps with the
+// receiver.
+func (c Config) copyMaps() Config {
+	copyStrings := func(values map[string]string) map[string]string {
+		if values == nil {
+			return nil
+		}
+		copy := map[string]string{}
+		for k, v := range values {
+			copy[k] = v
+		}
+		return copy
+	}
+
+	c.Extra = copyStrings(c.Extra)
+	c.Filters = copyStrings(c.Filters)
+	c.Notebooks = copyStrings(c.Notebooks)
+	c.Aliases = copyStrings(c.Aliases)
+	c.TagAliases = copyStrings(c.TagAliases)
+	c.Renderers = copyStrings(c.Renderers)
+
+	if c.Groups != nil {
+		groups := map[string]GroupConfig{}
+		for name, group := range c.Groups {
+			groups[name] = group
+		}
+		c.Groups = groups
+	}
+	if c.TagStyles != nil {
+		styles := map[string]TagStyle{}
+		for tag, style := range c.TagStyles {
+			styles[tag] = style
+		}
+		c.TagStyles = styles
+	}
+	if c.Helpers != nil {
+		helpers := map[string]HelperConfig{}
+		for name, helper := range c.Helpers {
+			helpers[name] = helper
+		}
+		c.Helpers = helpers
+	}
+	if c.Profiles != nil {
+		profiles := map[string]ProfileConfig{}
+		for name, profile := range c.Profiles {
+			profile.Notebooks = copyStrings(profile.Notebooks)
+			profiles[name] = profile
+		}
+		c.Profiles = profiles
+	}
+	return c
+}
+
+// WithProfile returns a copy of the config with the settings of the given
+// profile applied. The config is unchanged if the name is empty.
+func (c Config) WithProfile(name string) (Config, error) {
+	if name == "" {
+		return c, nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return c, fmt.Errorf("%s: profile not found in the config", name)
+	}
+
+	c.Profile = name
+	c.Tool = c.Tool.override(profile.Tool)
+	notebooks := map[string]string{}
+	for prefix, path := range c.Notebooks {
+		notebooks[prefix] = path
+	}
+	for prefix, path := range profile.Notebooks {
+		notebooks[prefix] = path
+	}
+	c.Notebooks = notebooks
+	return c, nil
+}
+
+// NotebookDir returns the notebook directory set by the active profile, to be
+// used when no notebook is found from the working directory.
+func (c Config) NotebookDir() opt.String {
+	if profile, ok := c.Profiles[c.Profile]; ok {
+		return profile.NotebookDir
+	}
+	return opt.NullString
+}
+
 func (c GroupConfig) merge(tomlConf tomlGroupConfig, name string) GroupConfig {
 	res := c.Clone()
 
@@ -429,6 +1379,9 @@ func (c GroupConfig) merge(tomlConf tomlGroupConfig, name string) GroupConfig {
 	if note.Template != "" {
 		res.Note.BodyTemplatePath = opt.NewNotEmptyString(note.Template)
 	}
+	if note.IDStrategy != "" {
+		res.Note.IDOptions.Strategy = IDStrategy(note.IDStrategy)
+	}
 	if note.IDLength != 0 {
 		res.Note.IDOptions.Length = note.IDLength
 	}
@@ -447,43 +1400,98 @@ func (c GroupConfig) merge(tomlConf tomlGroupConfig, name string) GroupConfig {
 	for _, v := range note.Ignore {
 		res.Note.Ignore = append(res.Note.Ignore, v)
 	}
+	if note.CarryOverTasks != nil {
+		res.Note.CarryOverTasks = *note.CarryOverTasks
+	}
+	if note.CarryOverSections != nil {
+		res.Note.CarryOverSections = note.CarryOverSections
+	}
 	if tomlConf.Extra != nil {
 		for k, v := range tomlConf.Extra {
 			res.Extra[k] = v
 		}
 	}
+	if tomlConf.Encrypt != nil {
+		res.Encrypt = *tomlConf.Encrypt
+	}
+	if tomlConf.Format.Markdown != (tomlMarkdownConfig{}) || !reflect.DeepEqual(tomlConf.LSP, tomlLSPConfig{}) {
+		res.Overrides = append(res.Overrides, groupOverrides{
+			Markdown: tomlConf.Format.Markdown,
+			LSP:      tomlConf.LSP,
+		})
+	}
 
 	return res
 }
 
 // tomlConfig holds the TOML representation of Config
 type tomlConfig struct {
-	Note    tomlNoteConfig
-	Groups  map[string]tomlGroupConfig `toml:"group"`
-	Format  tomlFormatConfig
-	Tool    tomlToolConfig
-	LSP     tomlLSPConfig
-	Extra   map[string]string
-	Filters map[string]string `toml:"filter"`
-	Aliases map[string]string `toml:"alias"`
+	Note         tomlNoteConfig
+	Groups       map[string]tomlGroupConfig `toml:"group"`
+	Format       tomlFormatConfig
+	Tool         tomlToolConfig
+	LSP          tomlLSPConfig
+	Encryption   tomlEncryptionConfig
+	Git          tomlGitConfig
+	Search       tomlSearchConfig
+	Trash        tomlTrashConfig
+	Archive      tomlArchiveConfig
+	Asset        tomlAssetConfig
+	Bibliography tomlBibliographyConfig
+	Lint         tomlLintConfig
+	Maintenance  tomlMaintenanceConfig
+	Index        tomlIndexConfig
+	Hooks        tomlHooksConfig
+	Notebooks    map[string]string
+	Extra        map[string]string
+	Filters      map[string]string            `toml:"filter"`
+	Aliases      map[string]string            `toml:"alias"`
+	TagAliases   map[string]string            `toml:"tag-alias"`
+	TagStyles    map[string]tomlTagStyle      `toml:"tag-style"`
+	Renderers    map[string]string            `toml:"renderer"`
+	Helpers      map[string]tomlHelperConfig  `toml:"helper"`
+	Profiles     map[string]tomlProfileConfig `toml:"profile"`
+}
+
+type tomlProfileConfig struct {
+	Tool        tomlToolConfig
+	NotebookDir *string `toml:"notebook-dir"`
+	Notebooks   map[string]string
 }
 
 type tomlNoteConfig struct {
-	Filename     string
-	Extension    string
-	Template     string
-	Lang         string   `toml:"language"`
-	DefaultTitle string   `toml:"default-title"`
-	IDCharset    string   `toml:"id-charset"`
-	IDLength     int      `toml:"id-length"`
-	IDCase       string   `toml:"id-case"`
-	Ignore       []string `toml:"ignore"`
+	Filename          string
+	Extension         string
+	Template          string
+	Lang              string   `toml:"language"`
+	DefaultTitle      string   `toml:"default-title"`
+	IDStrategy        string   `toml:"id-strategy"`
+	IDCharset         string   `toml:"id-charset"`
+	IDLength          int      `toml:"id-length"`
+	IDCase            string   `toml:"id-case"`
+	Ignore            []string `toml:"ignore"`
+	CarryOverTasks    *bool    `toml:"carry-over-tasks"`
+	CarryOverSections []string `toml:"carry-over-sections"`
+}
+
+type tomlTagStyle struct {
+	Color string
+	Icon  string
+}
+
+type tomlHelperConfig struct {
+	Params   []string
+	Template string
+	Command  string
 }
 
 type tomlGroupConfig struct {
-	Paths []string
-	Note  tomlNoteConfig
-	Extra map[string]string
+	Paths   []string
+	Note    tomlNoteConfig
+	Extra   map[string]string
+	Encrypt *bool
+	Format  tomlFormatConfig
+	LSP     tomlLSPConfig
 }
 
 type tomlFormatConfig struct {
@@ -491,31 +1499,132 @@ type tomlFormatConfig struct {
 }
 
 type tomlMarkdownConfig struct {
-	Hashtags          *bool   `toml:"hashtags"`
-	ColonTags         *bool   `toml:"colon-tags"`
-	MultiwordTags     *bool   `toml:"multiword-tags"`
-	LinkFormat        *string `toml:"link-format"`
-	LinkEncodePath    *bool   `toml:"link-encode-path"`
-	LinkDropExtension *bool   `toml:"link-drop-extension"`
+	Hashtags           *bool   `toml:"hashtags"`
+	ColonTags          *bool   `toml:"colon-tags"`
+	MultiwordTags      *bool   `toml:"multiword-tags"`
+	LinkFormat         *string `toml:"link-format"`
+	LinkEncodePath     *bool   `toml:"link-encode-path"`
+	LinkDropExtension  *bool   `toml:"link-drop-extension"`
+	WikiLinkAliasFirst *bool   `toml:"wiki-link-alias-first"`
 }
 
 type tomlToolConfig struct {
-	Editor     *string
-	Pager      *string
-	FzfPreview *string `toml:"fzf-preview"`
-	FzfLine    *string `toml:"fzf-line"`
+	Editor      *string
+	EditorSplit *string `toml:"editor-split"`
+	Pager       *string
+	FzfPreview  *string `toml:"fzf-preview"`
+	FzfLine     *string `toml:"fzf-line"`
+	PDFText     *string `toml:"pdf-text"`
+	OCR         *string `toml:"ocr"`
 }
 
 type tomlLSPConfig struct {
+	ReadOnly   *bool `toml:"readonly"`
 	Completion struct {
-		NoteLabel      *string `toml:"note-label"`
-		NoteFilterText *string `toml:"note-filter-text"`
-		NoteDetail     *string `toml:"note-detail"`
+		NoteLabel              *string  `toml:"note-label"`
+		NoteFilterText         *string  `toml:"note-filter-text"`
+		NoteDetail             *string  `toml:"note-detail"`
+		MarkdownLinks          *bool    `toml:"markdown-links"`
+		MaxItems               *int     `toml:"max-items"`
+		DateTrigger            *string  `toml:"date-trigger"`
+		DateFormat             *string  `toml:"date-format"`
+		IncludeArchived        *bool    `toml:"include-archived"`
+		WikiTitle              *bool    `toml:"wiki-title"`
+		UseAdditionalTextEdits *bool    `toml:"use-additional-text-edits"`
+		TriggerStrings         []string `toml:"trigger-strings"`
 	}
 	Diagnostics struct {
 		WikiTitle *string `toml:"wiki-title"`
 		DeadLink  *string `toml:"dead-link"`
+		FuzzyLink *string `toml:"fuzzy-link"`
+		Footnote  *string `toml:"footnote"`
+		DeadURL   *string `toml:"dead-url"`
+		Citation  *string `toml:"citation"`
 	}
+	Links struct {
+		PublishedURL     *string `toml:"published-url"`
+		FetchURLMetadata *bool   `toml:"fetch-url-metadata"`
+	}
+	CodeLens struct {
+		Backlinks    *bool
+		SuggestLinks *bool `toml:"suggest-links"`
+	} `toml:"code-lens"`
+	Formatting struct {
+		CloseWikiLinks *bool `toml:"close-wiki-links"`
+		ContinueLists  *bool `toml:"continue-lists"`
+	}
+}
+
+type tomlEncryptionConfig struct {
+	Tool       *string
+	Recipients []string
+	Identity   *string
+}
+
+type tomlSearchConfig struct {
+	PathWeight  interface{} `toml:"path-weight"`
+	TitleWeight interface{} `toml:"title-weight"`
+	BodyWeight  interface{} `toml:"body-weight"`
+	TagsWeight  interface{} `toml:"tags-weight"`
+	// Fuzzy resolution of the links.
+	FuzzyLinkThreshold interface{} `toml:"fuzzy-link-threshold"`
+	// Tokenizer of the full-text index.
+	Tokenizer        *string `toml:"tokenizer"`
+	RemoveDiacritics *int    `toml:"remove-diacritics"`
+	TokenChars       *string `toml:"token-chars"`
+	Separators       *string `toml:"separators"`
+}
+
+type tomlTrashConfig struct {
+	RetentionDays *int   `toml:"retention-days"`
+	ArchiveDir    string `toml:"archive-dir"`
+}
+
+type tomlArchiveConfig struct {
+	Command string
+}
+
+type tomlAssetConfig struct {
+	Dir      string
+	Filename string
+}
+
+type tomlBibliographyConfig struct {
+	Files []string
+}
+
+type tomlLintConfig struct {
+	URLs tomlURLCheckConfig `toml:"urls"`
+}
+
+type tomlURLCheckConfig struct {
+	AllowHosts  []string `toml:"allow-hosts"`
+	DenyHosts   []string `toml:"deny-hosts"`
+	Concurrency *int     `toml:"concurrency"`
+	HostDelay   *int     `toml:"host-delay"`
+	MaxAge      *int     `toml:"max-age"`
+}
+
+type tomlIndexConfig struct {
+	WAL           *bool `toml:"wal"`
+	BusyTimeout   *int  `toml:"busy-timeout"`
+	LockRetries   *int  `toml:"lock-retries"`
+	RelinkRenamed *bool `toml:"relink-renamed"`
+}
+
+type tomlHooksConfig struct {
+	PostNew   string `toml:"post-new"`
+	PreIndex  string `toml:"pre-index"`
+	PostIndex string `toml:"post-index"`
+}
+
+type tomlMaintenanceConfig struct {
+	Tasks []string
+}
+
+type tomlGitConfig struct {
+	AutoCommit    *bool   `toml:"auto-commit"`
+	CommitMessage *string `toml:"commit-message"`
 }
 
 func charsetFromString(charset string) Charset {
@@ -546,6 +1655,19 @@ func caseFromString(c string) Case {
 	}
 }
 
+// tomlFloat converts a TOML number into a float, as integers are decoded as
+// int64, e.g. `title-weight = 10`.
+func tomlFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("%v: not a number", v)
+	}
+}
+
 func lspDiagnosticSeverityFromString(s string) (LSPDiagnosticSeverity, error) {
 	switch s {
 	case "", "none":
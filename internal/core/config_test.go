@@ -3,6 +3,7 @@ package core
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/mickael-menu/zk/internal/util/opt"
@@ -19,9 +20,10 @@ func TestParseDefaultConfig(t *testing.T) {
 			Extension:        "md",
 			BodyTemplatePath: opt.NullString,
 			IDOptions: IDOptions{
-				Length:  4,
-				Charset: CharsetAlphanum,
-				Case:    CaseLower,
+				Strategy: IDStrategyRandom,
+				Length:   4,
+				Charset:  CharsetAlphanum,
+				Case:     CaseLower,
 			},
 			DefaultTitle: "Untitled",
 			Lang:         "en",
@@ -45,14 +47,77 @@ func TestParseDefaultConfig(t *testing.T) {
 			FzfLine:    opt.NullString,
 		},
 		LSP: LSPConfig{
+			Completion: LSPCompletionConfig{
+				MaxItems:       100,
+				DateFormat:     "%Y-%m-%d",
+				TriggerStrings: []string{"[[", "](("},
+			},
 			Diagnostics: LSPDiagnosticConfig{
 				WikiTitle: LSPDiagnosticNone,
 				DeadLink:  LSPDiagnosticError,
+				FuzzyLink: LSPDiagnosticHint,
+				Footnote:  LSPDiagnosticWarning,
+				DeadURL:   LSPDiagnosticHint,
+				Citation:  LSPDiagnosticWarning,
+			},
+			CodeLens: LSPCodeLensConfig{
+				Backlinks: true,
+			},
+			Formatting: LSPFormattingConfig{
+				CloseWikiLinks: true,
+				ContinueLists:  true,
+			},
+		},
+		Encryption: EncryptionConfig{
+			Tool:       "gpg",
+			Recipients: []string{},
+			Identity:   opt.NullString,
+		},
+		Git: GitConfig{
+			AutoCommit:    false,
+			CommitMessage: `{{operation}}: {{join paths ", "}}`,
+		},
+		Search: SearchConfig{
+			Weights:            DefaultSearchWeights,
+			FuzzyLinkThreshold: 0.6,
+			Tokenizer:          DefaultSearchTokenizer,
+		},
+		Trash: TrashConfig{
+			RetentionDays: 30,
+		},
+		Asset: AssetConfig{
+			Dir:              "assets",
+			FilenameTemplate: "{{date now '%Y%m%d%H%M%S'}}",
+		},
+		Bibliography: BibliographyConfig{
+			Files: []string{},
+		},
+		Lint: LintConfig{
+			URLs: URLCheckConfig{
+				AllowHosts:  []string{},
+				DenyHosts:   []string{},
+				Concurrency: 4,
+				HostDelay:   time.Second,
+				MaxAge:      24 * time.Hour,
 			},
 		},
-		Filters: make(map[string]string),
-		Aliases: make(map[string]string),
-		Extra:   make(map[string]string),
+		Maintenance: MaintenanceConfig{
+			Tasks: []MaintenanceTask{"index", "trash", "vacuum"},
+		},
+		Index: IndexConfig{
+			WAL:         true,
+			BusyTimeout: 5 * time.Second,
+			LockRetries: 3,
+		},
+		Notebooks:  make(map[string]string),
+		Filters:    make(map[string]string),
+		Aliases:    make(map[string]string),
+		TagAliases: make(map[string]string),
+		TagStyles:  make(map[string]TagStyle),
+		Renderers:  make(map[string]string),
+		Helpers:    make(map[string]HelperConfig),
+		Profiles:   make(map[string]ProfileConfig),
+		Extra:      make(map[string]string),
 	})
 }
 
@@ -83,17 +148,76 @@ func TestParseComplete(t *testing.T) {
 		link-format = "custom"
 		link-encode-path = true
 		link-drop-extension = false
+		wiki-link-alias-first = true
 
 		[tool]
 		editor = "vim"
+		editor-split = "-O"
 		pager = "less"
 		fzf-preview = "bat {1}"
 		fzf-line = "{{title}}"
+		pdf-text = "pdftotext - -"
+		ocr = "tesseract stdin stdout"
 
 		[extra]
 		hello = "world"
 		salut = "le monde"
 
+		[encryption]
+		tool = "age"
+		recipients = ["age1abc", "age1def"]
+		identity = "~/.age/key.txt"
+
+		[git]
+		auto-commit = true
+		commit-message = "Update {{join paths ' '}}"
+
+		[search]
+		title-weight = 10
+		body-weight = 2.5
+		fuzzy-link-threshold = 0.8
+		tokenizer = "unicode61"
+		remove-diacritics = 2
+		token-chars = "-_"
+		separators = "."
+
+		[trash]
+		retention-days = 7
+
+		[archive]
+		command = "archive-url $1"
+
+		[asset]
+		dir = "media/pasted/"
+		filename = "{{note}}-{{id}}"
+
+		[bibliography]
+		files = ["references.bib", "~/zotero.json"]
+
+		[lint.urls]
+		allow-hosts = ["example.com"]
+		deny-hosts = ["private.example.com"]
+		concurrency = 2
+		host-delay = 500
+		max-age = 48
+
+		[maintenance]
+		tasks = ["index", "urls"]
+
+		[notebooks]
+		work = "~/work-notes"
+
+		[index]
+		wal = false
+		busy-timeout = 10000
+		lock-retries = 0
+		relink-renamed = true
+
+		[hooks]
+		post-new = "notify-send 'New note'"
+		pre-index = "git pull -q"
+		post-index = "./publish.sh"
+
 		[filter]
 		recents = "--created-after '2 weeks ago'"
 		journal = "journal --sort created"
@@ -102,8 +226,27 @@ func TestParseComplete(t *testing.T) {
 		ls = "zk list $@"
 		ed = "zk edit $@"
 
+		[tag-alias]
+		golang = "go"
+		"#js" = "#javascript"
+
+		[tag-style.work]
+		color = "#e06c75"
+		icon = "💼"
+
+		[tag-style."#project/alpha"]
+		color = "teal"
+
+		[renderer]
+		Mermaid = "mmdc --input - --output -"
+
+		[helper.initials]
+		params = ["name"]
+		command = "echo \"$1\" | cut -c1"
+
 		[group.log]
 		paths = ["journal/daily", "journal/weekly"]
+		encrypt = true
 
 		[group.log.note]
 		filename = "{{date}}.md"
@@ -111,10 +254,13 @@ func TestParseComplete(t *testing.T) {
 		template = "log.md"
 		language = "de"
 		default-title = "Ohne Titel"
+		id-strategy = "folgezettel"
 		id-charset = "letters"
 		id-length = 8
 		id-case = "mixed"
 		ignore = ["new-ignored"]
+		carry-over-tasks = true
+		carry-over-sections = ["Tasks"]
 		
 		[group.log.extra]
 		log-ext = "value"
@@ -125,26 +271,54 @@ func TestParseComplete(t *testing.T) {
 		[group."without path"]
 		paths = []
 
+		[lsp]
+		readonly = true
+
 		[lsp.completion]
 		note-label = "notelabel"
 		note-filter-text = "notefiltertext"
 		note-detail = "notedetail"
+		markdown-links = true
+		max-items = 20
+		date-trigger = "@"
+		date-format = "medium"
+		wiki-title = true
+		use-additional-text-edits = false
+		trigger-strings = ["[[", "@@"]
 		
 		[lsp.diagnostics]
 		wiki-title = "hint"
 		dead-link = "none"
+		fuzzy-link = "warning"
+		footnote = "none"
+		dead-url = "info"
+		citation = "error"
+
+		[lsp.links]
+		published-url = "https://notes.example.com"
+		fetch-url-metadata = true
+
+		[lsp.code-lens]
+		backlinks = false
+		suggest-links = true
+
+		[lsp.formatting]
+		close-wiki-links = false
 	`), ".zk/config.toml", NewDefaultConfig())
 
 	assert.Nil(t, err)
+
+	useAdditionalTextEdits := false
 	assert.Equal(t, conf, Config{
 		Note: NoteConfig{
 			FilenameTemplate: "{{id}}.note",
 			Extension:        "txt",
 			BodyTemplatePath: opt.NewString("default.note"),
 			IDOptions: IDOptions{
-				Length:  4,
-				Charset: CharsetAlphanum,
-				Case:    CaseLower,
+				Strategy: IDStrategyRandom,
+				Length:   4,
+				Charset:  CharsetAlphanum,
+				Case:     CaseLower,
 			},
 			Lang:         "fr",
 			DefaultTitle: "Sans titre",
@@ -158,19 +332,23 @@ func TestParseComplete(t *testing.T) {
 					Extension:        "note",
 					BodyTemplatePath: opt.NewString("log.md"),
 					IDOptions: IDOptions{
-						Length:  8,
-						Charset: CharsetLetters,
-						Case:    CaseMixed,
+						Strategy: IDStrategyFolgezettel,
+						Length:   8,
+						Charset:  CharsetLetters,
+						Case:     CaseMixed,
 					},
-					Lang:         "de",
-					DefaultTitle: "Ohne Titel",
-					Ignore:       []string{"ignored", ".git", "new-ignored"},
+					Lang:              "de",
+					DefaultTitle:      "Ohne Titel",
+					Ignore:            []string{"ignored", ".git", "new-ignored"},
+					CarryOverTasks:    true,
+					CarryOverSections: []string{"Tasks"},
 				},
 				Extra: map[string]string{
 					"hello":   "world",
 					"salut":   "le monde",
 					"log-ext": "value",
 				},
+				Encrypt: true,
 			},
 			"ref": {
 				Paths: []string{"ref"},
@@ -179,9 +357,10 @@ func TestParseComplete(t *testing.T) {
 					Extension:        "txt",
 					BodyTemplatePath: opt.NewString("default.note"),
 					IDOptions: IDOptions{
-						Length:  4,
-						Charset: CharsetAlphanum,
-						Case:    CaseLower,
+						Strategy: IDStrategyRandom,
+						Length:   4,
+						Charset:  CharsetAlphanum,
+						Case:     CaseLower,
 					},
 					Lang:         "fr",
 					DefaultTitle: "Sans titre",
@@ -199,9 +378,10 @@ func TestParseComplete(t *testing.T) {
 					Extension:        "txt",
 					BodyTemplatePath: opt.NewString("default.note"),
 					IDOptions: IDOptions{
-						Length:  4,
-						Charset: CharsetAlphanum,
-						Case:    CaseLower,
+						Strategy: IDStrategyRandom,
+						Length:   4,
+						Charset:  CharsetAlphanum,
+						Case:     CaseLower,
 					},
 					Lang:         "fr",
 					DefaultTitle: "Sans titre",
@@ -215,19 +395,23 @@ func TestParseComplete(t *testing.T) {
 		},
 		Format: FormatConfig{
 			Markdown: MarkdownConfig{
-				Hashtags:          false,
-				ColonTags:         true,
-				MultiwordTags:     true,
-				LinkFormat:        "custom",
-				LinkEncodePath:    true,
-				LinkDropExtension: false,
+				Hashtags:           false,
+				ColonTags:          true,
+				MultiwordTags:      true,
+				LinkFormat:         "custom",
+				LinkEncodePath:     true,
+				LinkDropExtension:  false,
+				WikiLinkAliasFirst: true,
 			},
 		},
 		Tool: ToolConfig{
-			Editor:     opt.NewString("vim"),
-			Pager:      opt.NewString("less"),
-			FzfPreview: opt.NewString("bat {1}"),
-			FzfLine:    opt.NewString("{{title}}"),
+			Editor:      opt.NewString("vim"),
+			EditorSplit: opt.NewString("-O"),
+			Pager:       opt.NewString("less"),
+			FzfPreview:  opt.NewString("bat {1}"),
+			FzfLine:     opt.NewString("{{title}}"),
+			PDFText:     opt.NewString("pdftotext - -"),
+			OCR:         opt.NewString("tesseract stdin stdout"),
 		},
 		LSP: LSPConfig{
 			Completion: LSPCompletionConfig{
@@ -236,11 +420,98 @@ func TestParseComplete(t *testing.T) {
 					FilterText: opt.NewString("notefiltertext"),
 					Detail:     opt.NewString("notedetail"),
 				},
+				MarkdownLinks:          true,
+				MaxItems:               20,
+				DateTrigger:            "@",
+				DateFormat:             "medium",
+				WikiTitle:              true,
+				UseAdditionalTextEdits: &useAdditionalTextEdits,
+				TriggerStrings:         []string{"[[", "@@"},
 			},
 			Diagnostics: LSPDiagnosticConfig{
 				WikiTitle: LSPDiagnosticHint,
 				DeadLink:  LSPDiagnosticNone,
+				FuzzyLink: LSPDiagnosticWarning,
+				Footnote:  LSPDiagnosticNone,
+				DeadURL:   LSPDiagnosticInfo,
+				Citation:  LSPDiagnosticError,
+			},
+			Links: LSPLinksConfig{
+				PublishedURL:     opt.NewString("https://notes.example.com"),
+				FetchURLMetadata: true,
+			},
+			CodeLens: LSPCodeLensConfig{
+				Backlinks:    false,
+				SuggestLinks: true,
+			},
+			Formatting: LSPFormattingConfig{
+				CloseWikiLinks: false,
+				ContinueLists:  true,
 			},
+			ReadOnly: true,
+		},
+		Encryption: EncryptionConfig{
+			Tool:       "age",
+			Recipients: []string{"age1abc", "age1def"},
+			Identity:   opt.NewString("~/.age/key.txt"),
+		},
+		Git: GitConfig{
+			AutoCommit:    true,
+			CommitMessage: "Update {{join paths ' '}}",
+		},
+		Search: SearchConfig{
+			Weights: SearchWeights{
+				Path:  1000,
+				Title: 10,
+				Body:  2.5,
+				Tags:  100,
+			},
+			FuzzyLinkThreshold: 0.8,
+			Tokenizer: SearchTokenizer{
+				Kind:             TokenizerUnicode61,
+				RemoveDiacritics: 2,
+				TokenChars:       "-_",
+				Separators:       ".",
+			},
+		},
+		Trash: TrashConfig{
+			RetentionDays: 7,
+		},
+		Archive: ArchiveConfig{
+			Command: "archive-url $1",
+		},
+		Asset: AssetConfig{
+			Dir:              "media/pasted",
+			FilenameTemplate: "{{note}}-{{id}}",
+		},
+		Bibliography: BibliographyConfig{
+			Files: []string{"references.bib", "~/zotero.json"},
+		},
+		Lint: LintConfig{
+			URLs: URLCheckConfig{
+				AllowHosts:  []string{"example.com"},
+				DenyHosts:   []string{"private.example.com"},
+				Concurrency: 2,
+				HostDelay:   500 * time.Millisecond,
+				MaxAge:      48 * time.Hour,
+			},
+		},
+		Maintenance: MaintenanceConfig{
+			Tasks: []MaintenanceTask{"index", "urls"},
+		},
+		Index: IndexConfig{
+			WAL:           false,
+			BusyTimeout:   10 * time.Second,
+			LockRetries:   0,
+			RelinkRenamed: true,
+		},
+		Hooks: HooksConfig{
+			PostNew:   "notify-send 'New note'",
+			PreIndex:  "git pull -q",
+			PostIndex: "./publish.sh",
+		},
+		Notebooks: map[string]string{
+			"work": "~/work-notes",
 		},
 		Filters: map[string]string{
 			"recents": "--created-after '2 weeks ago'",
@@ -250,6 +521,24 @@ func TestParseComplete(t *testing.T) {
 			"ls": "zk list $@",
 			"ed": "zk edit $@",
 		},
+		TagAliases: map[string]string{
+			"golang": "go",
+			"js":     "javascript",
+		},
+		TagStyles: map[string]TagStyle{
+			"work":          {Color: "#e06c75", Icon: "💼"},
+			"project/alpha": {Color: "teal"},
+		},
+		Renderers: map[string]string{
+			"mermaid": "mmdc --input - --output -",
+		},
+		Helpers: map[string]HelperConfig{
+			"initials": {
+				Params:  []string{"name"},
+				Command: "echo \"$1\" | cut -c1",
+			},
+		},
+		Profiles: make(map[string]ProfileConfig),
 		Extra: map[string]string{
 			"hello": "world",
 			"salut": "le monde",
@@ -295,9 +584,10 @@ func TestParseMergesGroupConfig(t *testing.T) {
 			Extension:        "txt",
 			BodyTemplatePath: opt.NewString("root-template"),
 			IDOptions: IDOptions{
-				Length:  42,
-				Charset: CharsetLetters,
-				Case:    CaseUpper,
+				Strategy: IDStrategyRandom,
+				Length:   42,
+				Charset:  CharsetLetters,
+				Case:     CaseUpper,
 			},
 			Lang:         "fr",
 			DefaultTitle: "Sans titre",
@@ -311,9 +601,10 @@ func TestParseMergesGroupConfig(t *testing.T) {
 					Extension:        "txt",
 					BodyTemplatePath: opt.NewString("log-template"),
 					IDOptions: IDOptions{
-						Length:  8,
-						Charset: CharsetNumbers,
-						Case:    CaseMixed,
+						Strategy: IDStrategyRandom,
+						Length:   8,
+						Charset:  CharsetNumbers,
+						Case:     CaseMixed,
 					},
 					Lang:         "fr",
 					DefaultTitle: "Sans titre",
@@ -332,9 +623,10 @@ func TestParseMergesGroupConfig(t *testing.T) {
 					Extension:        "txt",
 					BodyTemplatePath: opt.NewString("root-template"),
 					IDOptions: IDOptions{
-						Length:  42,
-						Charset: CharsetLetters,
-						Case:    CaseUpper,
+						Strategy: IDStrategyRandom,
+						Length:   42,
+						Charset:  CharsetLetters,
+						Case:     CaseUpper,
 					},
 					Lang:         "fr",
 					DefaultTitle: "Sans titre",
@@ -363,14 +655,75 @@ func TestParseMergesGroupConfig(t *testing.T) {
 					FilterText: opt.NullString,
 					Detail:     opt.NullString,
 				},
+				MaxItems:       100,
+				DateFormat:     "%Y-%m-%d",
+				TriggerStrings: []string{"[[", "](("},
 			},
 			Diagnostics: LSPDiagnosticConfig{
 				WikiTitle: LSPDiagnosticNone,
 				DeadLink:  LSPDiagnosticError,
+				FuzzyLink: LSPDiagnosticHint,
+				Footnote:  LSPDiagnosticWarning,
+				DeadURL:   LSPDiagnosticHint,
+				Citation:  LSPDiagnosticWarning,
 			},
+			CodeLens: LSPCodeLensConfig{
+				Backlinks: true,
+			},
+			Formatting: LSPFormattingConfig{
+				CloseWikiLinks: true,
+				ContinueLists:  true,
+			},
+		},
+		Encryption: EncryptionConfig{
+			Tool:       "gpg",
+			Recipients: []string{},
+			Identity:   opt.NullString,
 		},
-		Filters: make(map[string]string),
-		Aliases: make(map[string]string),
+		Git: GitConfig{
+			AutoCommit:    false,
+			CommitMessage: `{{operation}}: {{join paths ", "}}`,
+		},
+		Search: SearchConfig{
+			Weights:            DefaultSearchWeights,
+			FuzzyLinkThreshold: 0.6,
+			Tokenizer:          DefaultSearchTokenizer,
+		},
+		Trash: TrashConfig{
+			RetentionDays: 30,
+		},
+		Asset: AssetConfig{
+			Dir:              "assets",
+			FilenameTemplate: "{{date now '%Y%m%d%H%M%S'}}",
+		},
+		Bibliography: BibliographyConfig{
+			Files: []string{},
+		},
+		Lint: LintConfig{
+			URLs: URLCheckConfig{
+				AllowHosts:  []string{},
+				DenyHosts:   []string{},
+				Concurrency: 4,
+				HostDelay:   time.Second,
+				MaxAge:      24 * time.Hour,
+			},
+		},
+		Maintenance: MaintenanceConfig{
+			Tasks: []MaintenanceTask{"index", "trash", "vacuum"},
+		},
+		Index: IndexConfig{
+			WAL:         true,
+			BusyTimeout: 5 * time.Second,
+			LockRetries: 3,
+		},
+		Notebooks:  make(map[string]string),
+		Filters:    make(map[string]string),
+		Aliases:    make(map[string]string),
+		TagAliases: make(map[string]string),
+		TagStyles:  make(map[string]TagStyle),
+		Renderers:  make(map[string]string),
+		Helpers:    make(map[string]HelperConfig),
+		Profiles:   make(map[string]ProfileConfig),
 		Extra: map[string]string{
 			"hello": "world",
 			"salut": "le monde",
@@ -436,6 +789,33 @@ func TestParseIDCase(t *testing.T) {
 
 // If link-encode-path is not set explicitly, it defaults to true for
 // "markdown" format and false for anything else.
+func TestParseIDStrategy(t *testing.T) {
+	test := func(strategy string, expected IDStrategy) {
+		toml := fmt.Sprintf(`
+			[note]
+			id-strategy = "%v"
+			[group.test.note]
+			id-strategy = "%v"
+		`, strategy, strategy)
+		conf, err := ParseConfig([]byte(toml), ".zk/config.toml", NewDefaultConfig())
+		assert.Nil(t, err)
+		assert.Equal(t, conf.Note.IDOptions.Strategy, expected)
+		assert.Equal(t, conf.Groups["test"].Note.IDOptions.Strategy, expected)
+	}
+
+	test("random", IDStrategyRandom)
+	test("ulid", IDStrategyULID)
+	test("ksuid", IDStrategyKSUID)
+	test("folgezettel", IDStrategyFolgezettel)
+	test("hash", IDStrategyHash)
+
+	_, err := ParseConfig([]byte(`
+		[group.test.note]
+		id-strategy = "uuid"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "uuid: unknown ID strategy, try random, ulid, ksuid, folgezettel or hash")
+}
+
 func TestParseMarkdownLinkEncodePath(t *testing.T) {
 	test := func(format string, expected bool) {
 		toml := fmt.Sprintf(`
@@ -459,11 +839,19 @@ func TestParseLSPDiagnosticsSeverity(t *testing.T) {
 			[lsp.diagnostics]
 			wiki-title = "%s"
 			dead-link = "%s"
-		`, value, value)
+			fuzzy-link = "%s"
+			footnote = "%s"
+			dead-url = "%s"
+			citation = "%s"
+		`, value, value, value, value, value, value)
 		conf, err := ParseConfig([]byte(toml), ".zk/config.toml", NewDefaultConfig())
 		assert.Nil(t, err)
 		assert.Equal(t, conf.LSP.Diagnostics.WikiTitle, expected)
 		assert.Equal(t, conf.LSP.Diagnostics.DeadLink, expected)
+		assert.Equal(t, conf.LSP.Diagnostics.FuzzyLink, expected)
+		assert.Equal(t, conf.LSP.Diagnostics.Footnote, expected)
+		assert.Equal(t, conf.LSP.Diagnostics.DeadURL, expected)
+		assert.Equal(t, conf.LSP.Diagnostics.Citation, expected)
 	}
 
 	test("", LSPDiagnosticNone)
@@ -481,6 +869,391 @@ func TestParseLSPDiagnosticsSeverity(t *testing.T) {
 	assert.Err(t, err, "foobar: unknown LSP diagnostic severity - may be none, hint, info, warning or error")
 }
 
+func TestParseEncryptionTool(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[encryption]
+		tool = "pgp"
+	`), ".zk/config.toml", NewDefaultConfig())
+
+	assert.Err(t, err, "pgp: unknown encryption tool, expected gpg or age")
+}
+
+func TestParseSearchWeights(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[search]
+		tags-weight = -1
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "-1: search weights can't be negative")
+
+	_, err = ParseConfig([]byte(`
+		[search]
+		fuzzy-link-threshold = 1.5
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "1.5: the fuzzy link threshold must be between 0 and 1")
+
+	conf, err := ParseConfig([]byte(`
+		[search]
+		fuzzy-link-threshold = 0
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, conf.Search.FuzzyLinkThreshold, 0.0)
+}
+
+func TestParseSearchTokenizer(t *testing.T) {
+	conf, err := ParseConfig([]byte(`
+		[search]
+		tokenizer = "trigram"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, conf.Search.Tokenizer, SearchTokenizer{
+		Kind:             TokenizerTrigram,
+		RemoveDiacritics: 1,
+		TokenChars:       "'&/",
+	})
+
+	_, err = ParseConfig([]byte(`
+		[search]
+		tokenizer = "icu"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "icu: unknown search tokenizer, expected porter, unicode61 or trigram")
+
+	_, err = ParseConfig([]byte(`
+		[search]
+		remove-diacritics = 3
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "3: remove-diacritics must be 0, 1 or 2")
+}
+
+func TestParseTrashRetention(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[trash]
+		retention-days = -1
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "-1: the trash retention period can't be negative")
+}
+
+func TestParseArchiveDir(t *testing.T) {
+	conf, err := ParseConfig([]byte(`
+		[trash]
+		archive-dir = "./old/archive/"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, conf.Trash.ArchiveDir, "old/archive")
+
+	test := func(dir string) {
+		_, err := ParseConfig([]byte(`
+			[trash]
+			archive-dir = "`+dir+`"
+		`), ".zk/config.toml", NewDefaultConfig())
+		assert.Err(t, err, dir+": the archive directory must be relative to the notebook root")
+	}
+	test("/archive")
+	test("../archive")
+	test(".")
+}
+
+func TestParseAssetDir(t *testing.T) {
+	conf, err := ParseConfig([]byte(`
+		[asset]
+		dir = "."
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, conf.Asset.Dir, ".")
+
+	test := func(dir string) {
+		_, err := ParseConfig([]byte(`
+			[asset]
+			dir = "`+dir+`"
+		`), ".zk/config.toml", NewDefaultConfig())
+		assert.Err(t, err, dir+": the asset directory must be relative to the notebook root")
+	}
+	test("/assets")
+	test("../assets")
+}
+
+func TestParseIndexLocking(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[index]
+		busy-timeout = -1
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "-1: the index busy timeout can't be negative")
+
+	_, err = ParseConfig([]byte(`
+		[index]
+		lock-retries = -2
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "-2: the number of index lock retries can't be negative")
+}
+
+func TestParseLintURLs(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[lint.urls]
+		concurrency = 0
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "0: at least one URL must be checked at a time")
+
+	_, err = ParseConfig([]byte(`
+		[lint.urls]
+		host-delay = -1
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "-1: the delay between the requests to a host can't be negative")
+
+	_, err = ParseConfig([]byte(`
+		[lint.urls]
+		max-age = -2
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "-2: the maximum age of the checked URLs can't be negative")
+}
+
+func TestParseNotebooks(t *testing.T) {
+	conf, err := ParseConfig([]byte(`
+		[notebooks]
+		work = "~/work-notes"
+		ref = "../references"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, conf.Notebooks, map[string]string{
+		"work": "~/work-notes",
+		"ref":  "../references",
+	})
+
+	_, err = ParseConfig([]byte(`
+		[notebooks]
+		"my work" = "~/work-notes"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "my work: invalid notebook prefix, it can't contain spaces or colons")
+}
+
+func TestParseProfiles(t *testing.T) {
+	conf, err := ParseConfig([]byte(`
+		[tool]
+		editor = "vim"
+		pager = "less"
+
+		[notebooks]
+		work = "~/work-notes"
+
+		[profile.laptop]
+		notebook-dir = "~/notes"
+
+		[profile.laptop.tool]
+		editor = "nvim"
+
+		[profile.laptop.notebooks]
+		work = "~/sync/work-notes"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, conf.Profiles["laptop"].Tool.Editor, opt.NewString("nvim"))
+	assert.Equal(t, conf.Profiles["laptop"].Tool.Pager, opt.NullString)
+	assert.Equal(t, conf.NotebookDir(), opt.NullString)
+
+	laptop, err := conf.WithProfile("laptop")
+	assert.Nil(t, err)
+	assert.Equal(t, laptop.Profile, "laptop")
+	assert.Equal(t, laptop.Tool.Editor, opt.NewString("nvim"))
+	assert.Equal(t, laptop.Tool.Pager, opt.NewString("less"))
+	assert.Equal(t, laptop.NotebookDir(), opt.NewString("~/notes"))
+	assert.Equal(t, laptop.Notebooks, map[string]string{"work": "~/sync/work-notes"})
+	assert.Equal(t, conf.Notebooks, map[string]string{"work": "~/work-notes"})
+
+	_, err = conf.WithProfile("unknown")
+	assert.Err(t, err, "unknown: profile not found in the config")
+}
+
+func TestParseProfileOverridesNotebookConfig(t *testing.T) {
+	global, err := ParseConfig([]byte(`
+		[profile.work.tool]
+		editor = "code --wait"
+	`), "config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+	global, err = global.WithProfile("work")
+	assert.Nil(t, err)
+
+	conf, err := ParseConfig([]byte(`
+		[tool]
+		editor = "vim"
+		fzf-preview = "cat {-1}"
+	`), ".zk/config.toml", global)
+	assert.Nil(t, err)
+	assert.Equal(t, conf.Tool.Editor, opt.NewString("code --wait"))
+	assert.Equal(t, conf.Tool.FzfPreview, opt.NewString("cat {-1}"))
+}
+
+func TestParseHelpers(t *testing.T) {
+	conf, err := ParseConfig([]byte(`
+		[helper.greet]
+		params = ["name", "greeting"]
+		template = "{{greeting}}, {{name}}!"
+
+		[helper.today]
+		command = "date +%F"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, conf.Helpers, map[string]HelperConfig{
+		"greet": {
+			Params:   []string{"name", "greeting"},
+			Template: "{{greeting}}, {{name}}!",
+		},
+		"today": {
+			Command: "date +%F",
+		},
+	})
+
+	_, err = ParseConfig([]byte(`
+		[helper.greet]
+		params = ["name"]
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "greet: a template helper requires either a template or a command")
+
+	_, err = ParseConfig([]byte(`
+		[helper.greet]
+		template = "Hello"
+		command = "echo Hello"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "greet: a template helper requires either a template or a command")
+
+	_, err = ParseConfig([]byte(`
+		[helper."say hello"]
+		template = "Hello"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "say hello: invalid template helper name, it can't contain spaces or dots")
+}
+
+func TestParseInvalidRenderer(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[renderer]
+		mermaid = ""
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "mermaid = : invalid renderer, the language and command can't be empty")
+}
+
+func TestParseInvalidTagStyle(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[tag-style.work]
+		color = "rgb(1, 2, 3)"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "[tag-style.work]: rgb(1, 2, 3): invalid tag color, expected a hex code such as #e06c75 or a color name")
+}
+
+func TestParseMaintenanceTasks(t *testing.T) {
+	conf, err := ParseConfig([]byte(`
+		[maintenance]
+		tasks = ["vacuum", "manifest"]
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, conf.Maintenance.Tasks, []MaintenanceTask{"vacuum", "manifest"})
+
+	_, err = ParseConfig([]byte(`
+		[maintenance]
+		tasks = ["index", "moc"]
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "moc: unknown maintenance task")
+}
+
+func TestParseLSPCompletionMaxItems(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[lsp.completion]
+		max-items = -1
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "-1: the maximum number of completion items can't be negative")
+
+	conf, err := ParseConfig([]byte(`
+		[lsp.completion]
+		max-items = 0
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, conf.LSP.Completion.MaxItems, 0)
+}
+
+func TestParseLSPCompletionInvalidDateTrigger(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[lsp.completion]
+		date-trigger = "@ "
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "@ : the date completion trigger can't contain whitespace")
+}
+
+func TestParseLSPCompletionInvalidTriggerStrings(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[lsp.completion]
+		trigger-strings = ["[[", "[ ["]
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "\"[ [\": the link completion triggers can't be empty or contain whitespace")
+}
+
+func TestParseGroupOverrides(t *testing.T) {
+	global, err := ParseConfig([]byte(`
+		[group.journal]
+		paths = ["journal"]
+
+		[group.journal.format.markdown]
+		link-format = "wiki"
+
+		[group.journal.lsp.completion]
+		note-label = "{{title}}"
+		date-trigger = "@"
+
+		[group.journal.lsp.diagnostics]
+		dead-link = "none"
+	`), "config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+
+	// The groups inherit the notebook settings declared afterwards.
+	conf, err := ParseConfig([]byte(`
+		[format.markdown]
+		link-format = "markdown"
+
+		[lsp.diagnostics]
+		dead-link = "error"
+		wiki-title = "hint"
+
+		[group.ref]
+		[group.ref.lsp.completion]
+		max-items = 10
+	`), ".zk/config.toml", global)
+	assert.Nil(t, err)
+
+	journal, err := conf.ForPath("journal/2021-01-01.md")
+	assert.Nil(t, err)
+	assert.Equal(t, journal.Format.Markdown.LinkFormat, "wiki")
+	assert.Equal(t, journal.Format.Markdown.LinkEncodePath, false)
+	assert.Equal(t, journal.LSP.Completion.Note.Label, opt.NewString("{{title}}"))
+	assert.Equal(t, journal.LSP.Completion.DateTrigger, "@")
+	assert.Equal(t, journal.LSP.Diagnostics.DeadLink, LSPDiagnosticNone)
+	assert.Equal(t, journal.LSP.Diagnostics.WikiTitle, LSPDiagnosticHint)
+
+	ref, err := conf.ForPath("ref/paper.md")
+	assert.Nil(t, err)
+	assert.Equal(t, ref.Format.Markdown.LinkFormat, "markdown")
+	assert.Equal(t, ref.LSP.Completion.MaxItems, 10)
+	assert.Equal(t, ref.LSP.Diagnostics.DeadLink, LSPDiagnosticError)
+
+	root, err := conf.ForPath("inbox.md")
+	assert.Nil(t, err)
+	assert.Equal(t, root.LSP, conf.LSP)
+	assert.Equal(t, root.LSP.Completion.DateTrigger, "")
+}
+
+func TestParseInvalidGroupOverrides(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[group.journal.format.markdown]
+		hashtags = false
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "[group.journal]: the tag syntaxes can't be overridden in a group, they apply to the whole notebook")
+
+	_, err = ParseConfig([]byte(`
+		[group.journal.format.markdown]
+		wiki-link-alias-first = true
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "[group.journal]: the wiki-link-alias-first setting can't be overridden in a group, it applies to the whole notebook")
+
+	_, err = ParseConfig([]byte(`
+		[group.journal.lsp.diagnostics]
+		dead-link = "loud"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Err(t, err, "[group.journal]: loud: unknown LSP diagnostic severity")
+}
+
 func TestGroupConfigIgnoreGlobs(t *testing.T) {
 	// empty globs
 	config := GroupConfig{
@@ -516,9 +1289,10 @@ func TestGroupConfigClone(t *testing.T) {
 			Extension:        "md",
 			BodyTemplatePath: opt.NewString("default.note"),
 			IDOptions: IDOptions{
-				Length:  4,
-				Charset: CharsetAlphanum,
-				Case:    CaseLower,
+				Strategy: IDStrategyRandom,
+				Length:   4,
+				Charset:  CharsetAlphanum,
+				Case:     CaseLower,
 			},
 			Lang:         "fr",
 			DefaultTitle: "Sans titre",
@@ -553,9 +1327,10 @@ func TestGroupConfigClone(t *testing.T) {
 			Extension:        "md",
 			BodyTemplatePath: opt.NewString("default.note"),
 			IDOptions: IDOptions{
-				Length:  4,
-				Charset: CharsetAlphanum,
-				Case:    CaseLower,
+				Strategy: IDStrategyRandom,
+				Length:   4,
+				Charset:  CharsetAlphanum,
+				Case:     CaseLower,
 			},
 			Lang:         "fr",
 			DefaultTitle: "Sans titre",
@@ -566,3 +1341,23 @@ func TestGroupConfigClone(t *testing.T) {
 		},
 	})
 }
+
+func TestParseConfigDoesNotModifyParent(t *testing.T) {
+	parent, err := ParseConfig([]byte(`
+		[alias]
+		ls = "zk list"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+
+	conf, err := ParseConfig([]byte(`
+		[alias]
+		edlast = "zk edit --limit 1"
+		[helper.today]
+		command = "date +%F"
+	`), ".zk/config.toml", parent)
+	assert.Nil(t, err)
+
+	assert.Equal(t, conf.Aliases, map[string]string{"ls": "zk list", "edlast": "zk edit --limit 1"})
+	assert.Equal(t, parent.Aliases, map[string]string{"ls": "zk list"})
+	assert.Equal(t, parent.Helpers, map[string]HelperConfig{})
+}
@@ -0,0 +1,283 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/exec"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// EncryptionConfig holds the configuration used to encrypt the notes of the
+// groups with `encrypt = true`.
+type EncryptionConfig struct {
+	// Tool used to encrypt and decrypt the notes: gpg or age.
+	Tool string
+	// Keys the notes are encrypted for, e.g. GPG user IDs or age public keys.
+	Recipients []string
+	// Path to the age identity file used to decrypt the notes. GPG uses
+	// its agent instead.
+	Identity opt.String
+}
+
+// Armor headers of the encrypted notes, used to detect them.
+var encryptionHeaders = map[string][]byte{
+	"gpg": []byte("-----BEGIN PGP MESSAGE-----"),
+	"age": []byte("-----BEGIN AGE ENCRYPTED FILE-----"),
+}
+
+// encryptCommand returns the shell command encrypting its standard input.
+func (c EncryptionConfig) encryptCommand() (string, error) {
+	args := []string{}
+	switch c.Tool {
+	case "gpg":
+		args = append(args, "gpg", "--batch", "--yes", "--quiet", "--armor", "--encrypt")
+		if len(c.Recipients) == 0 {
+			args = append(args, "--default-recipient-self")
+		}
+		for _, r := range c.Recipients {
+			args = append(args, "--recipient", r)
+		}
+	case "age":
+		if len(c.Recipients) == 0 {
+			return "", fmt.Errorf("no encryption recipients set in config")
+		}
+		args = append(args, "age", "--armor")
+		for _, r := range c.Recipients {
+			args = append(args, "--recipient", r)
+		}
+	default:
+		return "", fmt.Errorf("unknown encryption tool: %s", c.Tool)
+	}
+	return shellquote.Join(args...), nil
+}
+
+// decryptCommand returns the shell command decrypting its standard input.
+func (c EncryptionConfig) decryptCommand() (string, error) {
+	switch c.Tool {
+	case "gpg":
+		return "gpg --batch --quiet --decrypt", nil
+	case "age":
+		if c.Identity.IsNull() {
+			return "", fmt.Errorf("no encryption identity set in config")
+		}
+		return shellquote.Join("age", "--decrypt", "--identity", paths.ExpandHome(c.Identity.Unwrap())), nil
+	default:
+		return "", fmt.Errorf("unknown encryption tool: %s", c.Tool)
+	}
+}
+
+// isEncrypted returns whether the given content was encrypted with the
+// configured tool.
+func (c EncryptionConfig) isEncrypted(content []byte) bool {
+	header, ok := encryptionHeaders[c.Tool]
+	return ok && bytes.HasPrefix(bytes.TrimLeft(content, " \t\r\n"), header)
+}
+
+// pipeCommand runs the given shell command with content as standard input,
+// and returns its output.
+func pipeCommand(command string, content []byte) ([]byte, error) {
+	cmd := exec.CommandFromString(command)
+	cmd.Stdin = bytes.NewReader(content)
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.New(msg)
+		}
+		return nil, errors.Wrap(err, command)
+	}
+	return output, nil
+}
+
+// encryptedFileStorage is a FileStorage storing the notes of the encrypted
+// groups on disk as armored messages, while reading and writing them in
+// plain text.
+type encryptedFileStorage struct {
+	FileStorage
+	notebookPath string
+	config       Config
+	encrypt      func(content []byte) ([]byte, error)
+	decrypt      func(content []byte) ([]byte, error)
+}
+
+func newEncryptedFileStorage(fs FileStorage, notebookPath string, config Config) *encryptedFileStorage {
+	encryption := config.Encryption
+	return &encryptedFileStorage{
+		FileStorage:  fs,
+		notebookPath: notebookPath,
+		config:       config,
+		encrypt: func(content []byte) ([]byte, error) {
+			command, err := encryption.encryptCommand()
+			if err != nil {
+				return nil, err
+			}
+			return pipeCommand(command, content)
+		},
+		decrypt: func(content []byte) ([]byte, error) {
+			command, err := encryption.decryptCommand()
+			if err != nil {
+				return nil, err
+			}
+			return pipeCommand(command, content)
+		},
+	}
+}
+
+// Read decrypts the file at path, when it is encrypted. Notes which were
+// written before their group was encrypted are read as is.
+func (fs *encryptedFileStorage) Read(path string) ([]byte, error) {
+	content, err := fs.FileStorage.Read(path)
+	if err != nil || !fs.config.Encryption.isEncrypted(content) {
+		return content, err
+	}
+	content, err = fs.decrypt(content)
+	return content, errors.Wrapf(err, "%s: failed to decrypt", path)
+}
+
+// Write encrypts the given content, if path is a note of an encrypted group.
+func (fs *encryptedFileStorage) Write(path string, content []byte) error {
+	encrypted, err := fs.isEncryptedNote(path)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		content, err = fs.encrypt(content)
+		if err != nil {
+			return errors.Wrapf(err, "%s: failed to encrypt", path)
+		}
+	}
+	return fs.FileStorage.Write(path, content)
+}
+
+// isEncryptedNote returns whether the file at the given absolute path is a
+// note belonging to an encrypted group. The assets are not encrypted, to be
+// readable by other apps.
+func (fs *encryptedFileStorage) isEncryptedNote(path string) (bool, error) {
+	path, err := filepath.Rel(fs.notebookPath, path)
+	if err != nil || strings.HasPrefix(path, "..") {
+		return false, err
+	}
+	group, err := fs.config.GroupConfigForPath(path)
+	if err != nil {
+		return false, err
+	}
+	return group.Encrypt && filepath.Ext(path) == "."+group.Note.Extension, nil
+}
+
+// readStored returns the content of the file at the given absolute path, as
+// stored on disk without decrypting it.
+func (n *Notebook) readStored(path string) ([]byte, error) {
+	if efs, ok := n.fs.(*encryptedFileStorage); ok {
+		return efs.FileStorage.Read(path)
+	}
+	return n.fs.Read(path)
+}
+
+// ReadFile returns the content of the file at the given absolute path,
+// decrypting it if needed.
+func (n *Notebook) ReadFile(path string) ([]byte, error) {
+	return n.fs.Read(path)
+}
+
+// WriteFile writes the content of the file at the given absolute path,
+// encrypting it if it is a note of an encrypted group.
+func (n *Notebook) WriteFile(path string, content []byte) error {
+	return n.fs.Write(path, content)
+}
+
+// EditNotes runs edit with the paths of the given notes, e.g. to open them
+// with an editor, then commits them if auto-commit is enabled. The notes are
+// recorded in the history of the recently opened notes.
+//
+// The encrypted notes are decrypted in a private temporary directory during
+// the edition, then encrypted back in the notebook if they were modified.
+func (n *Notebook) EditNotes(paths []string, edit func(paths ...string) error) error {
+	n.logger.Err(n.RecordHistory(NoteHistoryOpened, paths...))
+	err := n.editNotes(paths, edit)
+	if err == nil {
+		n.CommitEdits(paths...)
+	}
+	return err
+}
+
+func (n *Notebook) editNotes(paths []string, edit func(paths ...string) error) error {
+	wrap := errors.Wrapper("failed to edit the encrypted notes")
+
+	type decryptedNote struct {
+		path    string
+		content []byte
+	}
+	decrypted := map[string]decryptedNote{}
+
+	efs, ok := n.fs.(*encryptedFileStorage)
+	if !ok {
+		return edit(paths...)
+	}
+
+	var tempDir string
+	editedPaths := []string{}
+	for _, path := range paths {
+		// Notes of an encrypted group which are still in plain text are
+		// edited as well in a temporary file, to be encrypted on save.
+		shouldEncrypt, err := efs.isEncryptedNote(path)
+		if err != nil {
+			return wrap(err)
+		}
+		raw, err := efs.FileStorage.Read(path)
+		isEncrypted := err == nil && efs.config.Encryption.isEncrypted(raw)
+		if !isEncrypted && !shouldEncrypt {
+			editedPaths = append(editedPaths, path)
+			continue
+		}
+		content, err := efs.Read(path)
+		if err != nil {
+			return wrap(err)
+		}
+
+		if tempDir == "" {
+			tempDir, err = ioutil.TempDir("", "zk-")
+			if err != nil {
+				return wrap(err)
+			}
+			defer os.RemoveAll(tempDir)
+		}
+		// Keep the filename to preserve the syntax highlighting.
+		tempPath := filepath.Join(tempDir, fmt.Sprintf("%d", len(decrypted)), filepath.Base(path))
+		err = os.MkdirAll(filepath.Dir(tempPath), 0700)
+		if err == nil {
+			err = ioutil.WriteFile(tempPath, content, 0600)
+		}
+		if err != nil {
+			return wrap(err)
+		}
+		decrypted[tempPath] = decryptedNote{path: path, content: content}
+		editedPaths = append(editedPaths, tempPath)
+	}
+
+	editErr := edit(editedPaths...)
+
+	for tempPath, note := range decrypted {
+		content, err := ioutil.ReadFile(tempPath)
+		if err != nil {
+			return wrap(err)
+		}
+		if bytes.Equal(content, note.content) {
+			continue
+		}
+		err = n.fs.Write(note.path, content)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+
+	return editErr
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestEncryptionConfigCommands(t *testing.T) {
+	test := func(config EncryptionConfig, encrypt string, decrypt string) {
+		command, err := config.encryptCommand()
+		assert.Nil(t, err)
+		assert.Equal(t, command, encrypt)
+		command, err = config.decryptCommand()
+		assert.Nil(t, err)
+		assert.Equal(t, command, decrypt)
+	}
+
+	test(
+		EncryptionConfig{Tool: "gpg"},
+		"gpg --batch --yes --quiet --armor --encrypt --default-recipient-self",
+		"gpg --batch --quiet --decrypt",
+	)
+	test(
+		EncryptionConfig{Tool: "gpg", Recipients: []string{"me@example.com", "Other One"}},
+		"gpg --batch --yes --quiet --armor --encrypt --recipient me@example.com --recipient 'Other One'",
+		"gpg --batch --quiet --decrypt",
+	)
+	test(
+		EncryptionConfig{Tool: "age", Recipients: []string{"age1abc"}, Identity: opt.NewString("/keys/my key.txt")},
+		"age --armor --recipient age1abc",
+		"age --decrypt --identity '/keys/my key.txt'",
+	)
+}
+
+func TestEncryptionConfigCommandsRequireAgeKeys(t *testing.T) {
+	config := EncryptionConfig{Tool: "age", Identity: opt.NullString}
+	_, err := config.encryptCommand()
+	assert.Err(t, err, "no encryption recipients set in config")
+	_, err = config.decryptCommand()
+	assert.Err(t, err, "no encryption identity set in config")
+}
+
+func TestEncryptionConfigIsEncrypted(t *testing.T) {
+	gpg := EncryptionConfig{Tool: "gpg"}
+	assert.True(t, gpg.isEncrypted([]byte("\n-----BEGIN PGP MESSAGE-----\n\nhQIMA\n")))
+	assert.False(t, gpg.isEncrypted([]byte("# -----BEGIN PGP MESSAGE-----")))
+	assert.False(t, gpg.isEncrypted([]byte("-----BEGIN AGE ENCRYPTED FILE-----\n")))
+
+	age := EncryptionConfig{Tool: "age"}
+	assert.True(t, age.isEncrypted([]byte("-----BEGIN AGE ENCRYPTED FILE-----\n")))
+}
+
+func newEncryptionTest(rootDir string, files map[string]string) (*Notebook, *fileStorageMock) {
+	fs := newFileStorageMock(rootDir, []string{rootDir})
+	fs.files = files
+
+	config := NewDefaultConfig()
+	config.Groups["journal"] = GroupConfig{
+		Paths:   []string{"journal"},
+		Note:    config.Note,
+		Encrypt: true,
+	}
+	notebook := NewNotebook(rootDir, config, NotebookPorts{
+		NoteIndex: &noteIndexAddMock{},
+		FS:        fs,
+		Logger:    &util.NullLogger,
+	})
+
+	// Fake encryption, to not depend on GPG in the tests.
+	header := []byte("-----BEGIN PGP MESSAGE-----\n")
+	efs := notebook.fs.(*encryptedFileStorage)
+	efs.encrypt = func(content []byte) ([]byte, error) {
+		return append(append([]byte{}, header...), content...), nil
+	}
+	efs.decrypt = func(content []byte) ([]byte, error) {
+		return bytes.TrimPrefix(content, header), nil
+	}
+	return notebook, fs
+}
+
+func TestEncryptedFileStorage(t *testing.T) {
+	notebook, fs := newEncryptionTest("/notebook", map[string]string{
+		"/notebook/journal/plain.md": "Not yet encrypted",
+	})
+
+	assert.Nil(t, notebook.WriteFile("/notebook/journal/entry.md", []byte("Dear diary")))
+	assert.Nil(t, notebook.fs.Write("/notebook/journal/image.png", []byte("PNG")))
+	assert.Nil(t, notebook.fs.Write("/notebook/work.md", []byte("Work")))
+
+	assert.Equal(t, fs.files["/notebook/journal/entry.md"], "-----BEGIN PGP MESSAGE-----\nDear diary")
+	assert.Equal(t, fs.files["/notebook/journal/image.png"], "PNG")
+	assert.Equal(t, fs.files["/notebook/work.md"], "Work")
+
+	for path, expected := range map[string]string{
+		"/notebook/journal/entry.md": "Dear diary",
+		"/notebook/journal/plain.md": "Not yet encrypted",
+		"/notebook/work.md":          "Work",
+	} {
+		content, err := notebook.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, string(content), expected)
+	}
+}
+
+func TestNotebookEditNotesDecryptsTemporarily(t *testing.T) {
+	notebook, fs := newEncryptionTest("/notebook", map[string]string{
+		"/notebook/journal/entry.md": "-----BEGIN PGP MESSAGE-----\nDear diary",
+		"/notebook/journal/plain.md": "Not yet encrypted",
+		"/notebook/work.md":          "Work",
+	})
+
+	var editedPaths []string
+	err := notebook.EditNotes([]string{"/notebook/journal/entry.md", "/notebook/journal/plain.md", "/notebook/work.md"}, func(paths ...string) error {
+		editedPaths = paths
+		content, err := ioutil.ReadFile(paths[0])
+		assert.Nil(t, err)
+		assert.Equal(t, string(content), "Dear diary")
+		assert.Nil(t, ioutil.WriteFile(paths[1], []byte("Encrypted now"), 0600))
+		return ioutil.WriteFile(paths[0], []byte("Dear diary, edited"), 0600)
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, len(editedPaths), 3)
+	assert.Equal(t, filepath.Base(editedPaths[0]), "entry.md")
+	assert.Equal(t, filepath.Base(editedPaths[1]), "plain.md")
+	assert.Equal(t, editedPaths[2], "/notebook/work.md")
+	assert.Equal(t, fs.files["/notebook/journal/entry.md"], "-----BEGIN PGP MESSAGE-----\nDear diary, edited")
+	assert.Equal(t, fs.files["/notebook/journal/plain.md"], "-----BEGIN PGP MESSAGE-----\nEncrypted now")
+
+	// The decrypted copies are removed.
+	_, err = ioutil.ReadFile(editedPaths[0])
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// FederatedHref splits an href targeting a note of another notebook, prefixed
+// with the name given to this notebook in the `[notebooks]` config section,
+// e.g. `work:project-x`.
+//
+// Returns the absolute path to the other notebook and the href of the note in
+// it, or false if the href doesn't start with a registered prefix.
+func (n *Notebook) FederatedHref(href string) (notebookPath string, noteHref string, ok bool) {
+	i := strings.Index(href, ":")
+	if i <= 0 || i == len(href)-1 {
+		return "", "", false
+	}
+	path, ok := n.Config.Notebooks[href[:i]]
+	if !ok {
+		return "", "", false
+	}
+
+	path = paths.ExpandHome(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(n.Path, path)
+	}
+	return filepath.Clean(path), href[i+1:], true
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestFederatedHref(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Notebooks = map[string]string{
+		"work":    "/notes/work",
+		"sibling": "../sibling",
+		"home":    "~/notes",
+	}
+	notebook := NewNotebook("/notes/personal", config, NotebookPorts{})
+
+	test := func(href string, expectedPath string, expectedHref string, expectedOK bool) {
+		path, noteHref, ok := notebook.FederatedHref(href)
+		assert.Equal(t, ok, expectedOK)
+		assert.Equal(t, path, expectedPath)
+		assert.Equal(t, noteHref, expectedHref)
+	}
+
+	home, err := os.UserHomeDir()
+	assert.Nil(t, err)
+
+	test("work:project-x", "/notes/work", "project-x", true)
+	test("work:dir/project-x.md", "/notes/work", "dir/project-x.md", true)
+	test("sibling:idea", "/notes/sibling", "idea", true)
+	test("home:idea", filepath.Join(home, "notes"), "idea", true)
+	// Not registered prefixes.
+	test("project-x", "", "", false)
+	test("other:project-x", "", "", false)
+	test("work:", "", "", false)
+	test(":project-x", "", "", false)
+}
@@ -33,4 +33,15 @@ type FileStorage interface {
 	// Write creates or overwrite the content at the given file path, creating
 	// any intermediate directories if needed.
 	Write(path string, content []byte) error
+
+	// Rename moves the file at the source path to the target path, creating
+	// any intermediate directories if needed.
+	Rename(source string, target string) error
+
+	// Remove deletes the file at the given path.
+	Remove(path string) error
+
+	// CreateDir creates a directory at the given path, with any intermediate
+	// directories if needed.
+	CreateDir(path string) error
 }
@@ -79,3 +79,31 @@ func (fs *fileStorageMock) Write(path string, content []byte) error {
 	fs.files[path] = string(content)
 	return nil
 }
+
+func (fs *fileStorageMock) Rename(source string, target string) error {
+	content, ok := fs.files[source]
+	if !ok {
+		return os.ErrNotExist
+	}
+	delete(fs.files, source)
+	fs.files[target] = content
+	return nil
+}
+
+func (fs *fileStorageMock) Remove(path string) error {
+	if _, ok := fs.files[path]; !ok {
+		return os.ErrNotExist
+	}
+	delete(fs.files, path)
+	return nil
+}
+
+func (fs *fileStorageMock) CreateDir(path string) error {
+	for _, dir := range fs.dirs {
+		if dir == path {
+			return nil
+		}
+	}
+	fs.dirs = append(fs.dirs, path)
+	return nil
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// VersionControl records the history of the notebook files, e.g. with git.
+//
+// All the paths are relative to the notebook root.
+type VersionControl interface {
+	// Commit records the current state of the given files, if they changed.
+	Commit(message string, paths ...string) error
+	// ChangedSince returns the files modified since the given revision,
+	// including the uncommitted changes.
+	ChangedSince(revision string) ([]string, error)
+	// LastCommit returns the hash of the last commit modifying the given
+	// file, or an empty string if it was never committed.
+	LastCommit(path string) (string, error)
+	// Sync commits all the changes of the notebook with message, then pulls
+	// and pushes the remote changes.
+	Sync(message string) error
+}
+
+// GitConfig holds the configuration of the git integration.
+type GitConfig struct {
+	// Commit automatically the notes created with zk or saved from the
+	// editor.
+	AutoCommit bool
+	// Template used to render the message of the automatic commits.
+	CommitMessage string
+}
+
+// commitMessageRenderContext holds the variables available to the commit
+// message template.
+type commitMessageRenderContext struct {
+	// Operation which modified the notes, e.g. create, edit or sync.
+	Operation string
+	// Paths of the committed notes, relative to the notebook root.
+	Paths []string
+}
+
+// autoCommit commits the given notes if auto-commit is enabled.
+//
+// Like the audit log, failing to commit is not fatal so errors are only
+// logged.
+func (n *Notebook) autoCommit(operation string, paths ...string) {
+	if n.vcs == nil || !n.Config.Git.AutoCommit || len(paths) == 0 {
+		return
+	}
+
+	message, err := n.renderCommitMessage(operation, paths)
+	if err == nil {
+		err = n.vcs.Commit(message, paths...)
+	}
+	n.logger.Err(errors.Wrap(err, "auto-commit failed"))
+}
+
+// CommitEdits commits the notes at the given absolute paths after they were
+// saved from the editor, if auto-commit is enabled.
+func (n *Notebook) CommitEdits(paths ...string) {
+	relPaths := []string{}
+	for _, path := range paths {
+		relPath, err := n.RelPath(path)
+		if err != nil {
+			n.logger.Err(err)
+			continue
+		}
+		relPaths = append(relPaths, relPath)
+	}
+	n.autoCommit("edit", relPaths...)
+}
+
+// Sync commits all the changes of the notebook, then pulls and pushes the
+// remote changes with git.
+func (n *Notebook) Sync() error {
+	wrap := errors.Wrapper("sync failed")
+
+	if n.vcs == nil {
+		return wrap(errors.New("the notebook is not versioned with git"))
+	}
+
+	// The repository might not have any commit yet.
+	paths, _ := n.vcs.ChangedSince("HEAD")
+	message, err := n.renderCommitMessage("sync", paths)
+	if err != nil {
+		return wrap(err)
+	}
+	return wrap(n.vcs.Sync(message))
+}
+
+func (n *Notebook) renderCommitMessage(operation string, paths []string) (string, error) {
+	templates, err := n.templateLoaderFactory(n.Config.Note.Lang)
+	if err != nil {
+		return "", err
+	}
+	template, err := templates.LoadTemplate(n.Config.Git.CommitMessage)
+	if err != nil {
+		return "", err
+	}
+	message, err := template.Render(commitMessageRenderContext{
+		Operation: operation,
+		Paths:     paths,
+	})
+	if err != nil {
+		return "", err
+	}
+	message = strings.TrimSpace(message)
+	if message == "" {
+		message = operation
+	}
+	return message, nil
+}
+
+// resolveModifiedSince converts the git revision of opts.ModifiedSince into
+// the list of notes modified since then.
+func (n *Notebook) resolveModifiedSince(opts NoteFindOpts) (NoteFindOpts, error) {
+	if opts.ModifiedSince.IsNull() {
+		return opts, nil
+	}
+	if n.vcs == nil {
+		return opts, errors.New("the notebook is not versioned with git")
+	}
+
+	paths, err := n.vcs.ChangedSince(opts.ModifiedSince.Unwrap())
+	if err != nil {
+		return opts, errors.Wrapf(err, "%s: failed to find the notes modified since this revision", opts.ModifiedSince)
+	}
+	opts.ExactPaths = make([]string, 0, len(paths))
+	for _, path := range paths {
+		opts.ExactPaths = append(opts.ExactPaths, filepath.ToSlash(path))
+	}
+	return opts, nil
+}
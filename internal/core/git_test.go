@@ -0,0 +1,99 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+type versionControlMock struct {
+	commits []string
+	changed []string
+	synced  []string
+}
+
+func (m *versionControlMock) Commit(message string, paths ...string) error {
+	m.commits = append(m.commits, fmt.Sprintf("%s %v", message, paths))
+	return nil
+}
+
+func (m *versionControlMock) ChangedSince(revision string) ([]string, error) {
+	return m.changed, nil
+}
+
+func (m *versionControlMock) LastCommit(path string) (string, error) {
+	return "sha-" + path, nil
+}
+
+func (m *versionControlMock) Sync(message string) error {
+	m.synced = append(m.synced, message)
+	return nil
+}
+
+func newGitTest(autoCommit bool) (*Notebook, *versionControlMock) {
+	config := NewDefaultConfig()
+	config.Git.AutoCommit = autoCommit
+
+	templates := newTemplateLoaderMock()
+	templates.Spy(config.Git.CommitMessage, func(context interface{}) string {
+		c := context.(commitMessageRenderContext)
+		return fmt.Sprintf("%s: %v", c.Operation, c.Paths)
+	})
+
+	vcs := &versionControlMock{}
+	notebook := NewNotebook("/notebook", config, NotebookPorts{
+		TemplateLoaderFactory: func(language string) (TemplateLoader, error) {
+			return templates, nil
+		},
+		FS:             newFileStorageMock("/notebook", []string{"/notebook"}),
+		Logger:         &util.NullLogger,
+		VersionControl: vcs,
+	})
+	return notebook, vcs
+}
+
+func TestNotebookCommitEdits(t *testing.T) {
+	notebook, vcs := newGitTest(true)
+	notebook.CommitEdits("/notebook/a.md", "/notebook/dir/b.md")
+	assert.Equal(t, vcs.commits, []string{"edit: [a.md dir/b.md] [a.md dir/b.md]"})
+}
+
+func TestNotebookCommitEditsDisabled(t *testing.T) {
+	notebook, vcs := newGitTest(false)
+	notebook.CommitEdits("/notebook/a.md")
+	assert.Equal(t, len(vcs.commits), 0)
+}
+
+func TestNotebookSync(t *testing.T) {
+	notebook, vcs := newGitTest(false)
+	vcs.changed = []string{"a.md"}
+	assert.Nil(t, notebook.Sync())
+	assert.Equal(t, vcs.synced, []string{"sync: [a.md]"})
+}
+
+func TestNotebookResolveModifiedSince(t *testing.T) {
+	notebook, vcs := newGitTest(false)
+	vcs.changed = []string{"a.md", "dir/b.md"}
+
+	opts, err := notebook.resolveModifiedSince(NoteFindOpts{})
+	assert.Nil(t, err)
+	assert.True(t, opts.ExactPaths == nil)
+
+	opts, err = notebook.resolveModifiedSince(NoteFindOpts{ModifiedSince: opt.NewString("HEAD~2")})
+	assert.Nil(t, err)
+	assert.Equal(t, opts.ExactPaths, []string{"a.md", "dir/b.md"})
+}
+
+func TestNotebookWithoutGit(t *testing.T) {
+	notebook := NewNotebook("/notebook", NewDefaultConfig(), NotebookPorts{
+		FS:     newFileStorageMock("/notebook", []string{"/notebook"}),
+		Logger: &util.NullLogger,
+	})
+
+	_, err := notebook.resolveModifiedSince(NoteFindOpts{ModifiedSince: opt.NewString("HEAD")})
+	assert.Err(t, err, "the notebook is not versioned with git")
+	assert.Err(t, notebook.Sync(), "sync failed: the notebook is not versioned with git")
+}
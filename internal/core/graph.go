@@ -0,0 +1,211 @@
+package core
+
+import (
+	"sort"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// LinkGraph is a directed graph of notes, identified by their paths. The
+// edges are either the links between the notes, or the parent-child
+// relationships of the NoteHierarchy.
+//
+// The graph features walk it with Traverse, to share the same semantics for
+// the start notes, the direction, the depth and the filters.
+type LinkGraph struct {
+	outgoing map[string][]string
+	incoming map[string][]string
+}
+
+// GraphDirection is the direction in which the edges of a LinkGraph are
+// followed.
+type GraphDirection int
+
+const (
+	// GraphForward follows the edges from their source to their target,
+	// e.g. the notes linked by the start notes.
+	GraphForward GraphDirection = iota + 1
+	// GraphBackward follows the edges from their target to their source,
+	// e.g. the notes linking to the start notes.
+	GraphBackward
+	// GraphBoth follows the edges in both directions.
+	GraphBoth
+)
+
+// TraverseOpts holds the options used to walk a LinkGraph.
+type TraverseOpts struct {
+	// Paths of the notes where the traversal starts.
+	Start []string
+	// Direction of the followed edges, GraphForward by default.
+	Direction GraphDirection
+	// Maximum number of edges followed from the start notes, 0 for no limit.
+	MaxDepth int
+	// Walks the graph depth first instead of breadth first, e.g. to list a
+	// sequence of notes in reading order.
+	DepthFirst bool
+	// Includes the start notes in the result, at depth 0.
+	IncludeStart bool
+	// Returns whether the edge from source to target is followed, as walked
+	// in the traversal direction. All the edges are followed when nil.
+	FollowEdge func(source, target string) bool
+	// Returns whether the note is visited. A note which is not visited is
+	// not walked through either. All the notes are visited when nil.
+	VisitNote func(path string) bool
+}
+
+// TraversedNote is a note reached when walking a LinkGraph.
+type TraversedNote struct {
+	Path string
+	// Number of edges followed from a start note to reach this one. With a
+	// breadth first traversal, it is the distance to the closest start note.
+	Depth int
+	// Path of the note from which this one was reached, empty for a start
+	// note.
+	Via string
+}
+
+// NewLinkGraph creates a LinkGraph from the given edges. The neighbors of a
+// note are walked in the order of the edges.
+func NewLinkGraph(edges []NoteLink) *LinkGraph {
+	g := &LinkGraph{
+		outgoing: map[string][]string{},
+		incoming: map[string][]string{},
+	}
+	for _, edge := range edges {
+		g.AddEdge(edge.SourcePath, edge.TargetPath)
+	}
+	return g
+}
+
+// AddEdge adds an edge from source to target, unless it already exists.
+// Self-references are ignored.
+func (g *LinkGraph) AddEdge(source, target string) {
+	if source == "" || target == "" || source == target {
+		return
+	}
+	if strutil.InList(g.outgoing[source], target) {
+		return
+	}
+	g.outgoing[source] = append(g.outgoing[source], target)
+	g.incoming[target] = append(g.incoming[target], source)
+}
+
+// Neighbors returns the paths of the notes directly reached from the given
+// one, in the given direction.
+func (g *LinkGraph) Neighbors(path string, direction GraphDirection) []string {
+	switch direction {
+	case GraphBackward:
+		return g.incoming[path]
+	case GraphBoth:
+		neighbors := append([]string{}, g.outgoing[path]...)
+		for _, source := range g.incoming[path] {
+			if !strutil.InList(g.outgoing[path], source) {
+				neighbors = append(neighbors, source)
+			}
+		}
+		return neighbors
+	default:
+		return g.outgoing[path]
+	}
+}
+
+// Traverse walks the graph from the start notes, and returns the notes
+// reached in the order they were visited. Each note is visited once, even
+// when the graph has cycles or several start notes reach it.
+func (g *LinkGraph) Traverse(opts TraverseOpts) []TraversedNote {
+	if opts.Direction == 0 {
+		opts.Direction = GraphForward
+	}
+	visitNote := func(path string) bool {
+		return opts.VisitNote == nil || opts.VisitNote(path)
+	}
+	followEdge := func(source, target string) bool {
+		return opts.FollowEdge == nil || opts.FollowEdge(source, target)
+	}
+
+	result := []TraversedNote{}
+	visited := map[string]bool{}
+	start := []TraversedNote{}
+	for _, path := range opts.Start {
+		if visited[path] || !visitNote(path) {
+			continue
+		}
+		visited[path] = true
+		note := TraversedNote{Path: path}
+		start = append(start, note)
+		if opts.IncludeStart {
+			result = append(result, note)
+		}
+	}
+
+	// next returns the notes reached from the given one, which were not
+	// visited yet.
+	next := func(note TraversedNote) []TraversedNote {
+		if opts.MaxDepth > 0 && note.Depth >= opts.MaxDepth {
+			return nil
+		}
+		notes := []TraversedNote{}
+		for _, path := range g.Neighbors(note.Path, opts.Direction) {
+			if visited[path] || !followEdge(note.Path, path) || !visitNote(path) {
+				continue
+			}
+			visited[path] = true
+			notes = append(notes, TraversedNote{Path: path, Depth: note.Depth + 1, Via: note.Path})
+		}
+		return notes
+	}
+
+	if opts.DepthFirst {
+		var walk func(note TraversedNote)
+		walk = func(note TraversedNote) {
+			for _, child := range next(note) {
+				result = append(result, child)
+				walk(child)
+			}
+		}
+		for _, note := range start {
+			walk(note)
+		}
+		return result
+	}
+
+	queue := start
+	for len(queue) > 0 {
+		note := queue[0]
+		queue = queue[1:]
+		reached := next(note)
+		result = append(result, reached...)
+		queue = append(queue, reached...)
+	}
+	return result
+}
+
+// LinkGraph builds the graph of the links between the indexed notes. The
+// neighbors of a note are sorted by path, to walk them in the same order on
+// each run.
+func (n *Notebook) LinkGraph() (*LinkGraph, error) {
+	links, err := n.index.FindNoteLinks()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build the link graph")
+	}
+
+	links = append([]NoteLink{}, links...)
+	sort.SliceStable(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if a.SourcePath != b.SourcePath {
+			return a.SourcePath < b.SourcePath
+		}
+		return a.TargetPath < b.TargetPath
+	})
+	return NewLinkGraph(links), nil
+}
+
+// TraversedPaths returns the paths of the traversed notes.
+func TraversedPaths(notes []TraversedNote) []string {
+	paths := []string{}
+	for _, note := range notes {
+		paths = append(paths, note.Path)
+	}
+	return paths
+}
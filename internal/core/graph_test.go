@@ -0,0 +1,59 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestLinkGraphTraverse(t *testing.T) {
+	g := NewLinkGraph([]NoteLink{
+		{SourcePath: "a", TargetPath: "b"},
+		{SourcePath: "a", TargetPath: "c"},
+		{SourcePath: "b", TargetPath: "d"},
+		{SourcePath: "c", TargetPath: "d"},
+		{SourcePath: "d", TargetPath: "a"},
+		{SourcePath: "e", TargetPath: "c"},
+		{SourcePath: "a", TargetPath: "b"},
+		{SourcePath: "a", TargetPath: "a"},
+	})
+
+	test := func(opts TraverseOpts, expected []string) {
+		t.Helper()
+		assert.Equal(t, TraversedPaths(g.Traverse(opts)), expected)
+	}
+
+	test(TraverseOpts{Start: []string{"a"}}, []string{"b", "c", "d"})
+	test(TraverseOpts{Start: []string{"a"}, IncludeStart: true}, []string{"a", "b", "c", "d"})
+	test(TraverseOpts{Start: []string{"a"}, MaxDepth: 1}, []string{"b", "c"})
+	test(TraverseOpts{Start: []string{"a"}, DepthFirst: true}, []string{"b", "d", "c"})
+	test(TraverseOpts{Start: []string{"c"}, Direction: GraphBackward}, []string{"a", "e", "d", "b"})
+	test(TraverseOpts{Start: []string{"e"}, Direction: GraphBoth, MaxDepth: 2}, []string{"c", "d", "a"})
+	test(TraverseOpts{Start: []string{"unknown"}}, []string{})
+
+	// Several start notes reaching the same notes.
+	test(TraverseOpts{Start: []string{"e", "b", "e"}, IncludeStart: true}, []string{"e", "b", "c", "d", "a"})
+
+	// Filtered notes are not walked through.
+	test(TraverseOpts{
+		Start:     []string{"a"},
+		VisitNote: func(path string) bool { return path != "b" },
+	}, []string{"c", "d"})
+	test(TraverseOpts{
+		Start:      []string{"a"},
+		FollowEdge: func(source, target string) bool { return source != "a" || target != "c" },
+	}, []string{"b", "d"})
+}
+
+func TestLinkGraphTraverseDepth(t *testing.T) {
+	g := NewLinkGraph([]NoteLink{
+		{SourcePath: "a", TargetPath: "b"},
+		{SourcePath: "b", TargetPath: "c"},
+		{SourcePath: "x", TargetPath: "c"},
+	})
+
+	assert.Equal(t, g.Traverse(TraverseOpts{Start: []string{"a", "x"}}), []TraversedNote{
+		{Path: "b", Depth: 1, Via: "a"},
+		{Path: "c", Depth: 1, Via: "x"},
+	})
+}
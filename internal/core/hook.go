@@ -0,0 +1,120 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/exec"
+)
+
+// Hook is an event of the notebook triggering a user command, see
+// HooksConfig.
+type Hook string
+
+const (
+	HookPostNew   Hook = "post-new"
+	HookPreIndex  Hook = "pre-index"
+	HookPostIndex Hook = "post-index"
+)
+
+// HookPayload is the JSON document given to a hook command on its standard
+// input.
+type HookPayload struct {
+	Hook Hook `json:"hook"`
+	// Absolute path of the notebook root.
+	Notebook string `json:"notebook"`
+	// Interface (e.g. cli or lsp) and command which triggered the hook.
+	Interface string `json:"interface"`
+	Command   string `json:"command,omitempty"`
+	// Notes affected by the operation.
+	Notes []HookNote `json:"notes"`
+	// Whether all the notes are reindexed, with the index hooks.
+	Force bool `json:"force,omitempty"`
+}
+
+// HookNote is a note affected by the operation triggering a hook.
+type HookNote struct {
+	// Path relative to the notebook root.
+	Path    string `json:"path"`
+	AbsPath string `json:"absPath"`
+	Title   string `json:"title,omitempty"`
+	// Change of the note with the post-index hook: added, modified or
+	// removed.
+	Status string `json:"status,omitempty"`
+}
+
+// hookCommand returns the command configured for the given hook.
+func (c HooksConfig) hookCommand(hook Hook) string {
+	switch hook {
+	case HookPostNew:
+		return c.PostNew
+	case HookPreIndex:
+		return c.PreIndex
+	case HookPostIndex:
+		return c.PostIndex
+	default:
+		return ""
+	}
+}
+
+// runHook runs the command of the given hook, if any, with a JSON payload of
+// the affected notes.
+//
+// The command runs from the notebook root. Its output is discarded, as the
+// standard output of the LSP server is used by the protocol.
+func (n *Notebook) runHook(origin AuditOrigin, hook Hook, notes []HookNote, force bool) error {
+	command := n.Config.Hooks.hookCommand(hook)
+	if command == "" {
+		return nil
+	}
+	wrap := errors.Wrapperf("%s hook failed", hook)
+
+	if notes == nil {
+		notes = []HookNote{}
+	}
+	payload, err := json.Marshal(HookPayload{
+		Hook:      hook,
+		Notebook:  n.Path,
+		Interface: origin.Interface,
+		Command:   origin.Command,
+		Notes:     notes,
+		Force:     force,
+	})
+	if err != nil {
+		return wrap(err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandFromString(command)
+	cmd.Dir = n.Path
+	cmd.Env = append(os.Environ(), "ZK_HOOK="+string(hook), "ZK_NOTEBOOK_DIR="+n.Path)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return wrap(err)
+	}
+	return nil
+}
+
+// newNotesHookPayload returns the payload of the post-new hook for the given
+// notes.
+func (n *Notebook) newNotesHookPayload(notes ...*Note) []HookNote {
+	payload := []HookNote{}
+	for _, note := range notes {
+		payload = append(payload, HookNote{
+			Path:    note.Path,
+			AbsPath: filepath.Join(n.Path, note.Path),
+			Title:   note.Title,
+		})
+	}
+	return payload
+}
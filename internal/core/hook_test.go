@@ -0,0 +1,94 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func newHookTestNotebook(t *testing.T, hooks HooksConfig) (*Notebook, string) {
+	dir, err := ioutil.TempDir("", "zk-hook")
+	assert.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	config := NewDefaultConfig()
+	config.Hooks = hooks
+	notebook := NewNotebook(dir, config, NotebookPorts{
+		FS:     newFileStorageMock(dir, []string{dir}),
+		Logger: &util.NullLogger,
+	})
+	return notebook, dir
+}
+
+func TestNotebookRunHook(t *testing.T) {
+	notebook, dir := newHookTestNotebook(t, HooksConfig{
+		PostIndex: `echo "$ZK_HOOK" > hook.txt; cat >> hook.txt`,
+	})
+
+	err := notebook.runHook(AuditOrigin{Interface: "lsp", Command: "zk.new"}, HookPostIndex, []HookNote{
+		{Path: "a.md", AbsPath: filepath.Join(dir, "a.md"), Title: "A", Status: "added"},
+	}, true)
+	assert.Nil(t, err)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "hook.txt"))
+	assert.Nil(t, err)
+	expected, err := json.Marshal(HookPayload{
+		Hook:      HookPostIndex,
+		Notebook:  dir,
+		Interface: "lsp",
+		Command:   "zk.new",
+		Notes: []HookNote{
+			{Path: "a.md", AbsPath: filepath.Join(dir, "a.md"), Title: "A", Status: "added"},
+		},
+		Force: true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, string(content), "post-index\n"+string(expected))
+}
+
+func TestNotebookRunHookWithoutCommand(t *testing.T) {
+	notebook, _ := newHookTestNotebook(t, HooksConfig{PostNew: "exit 1"})
+	assert.Nil(t, notebook.runHook(AuditOrigin{}, HookPreIndex, nil, false))
+}
+
+func TestNotebookRunHookFailure(t *testing.T) {
+	notebook, _ := newHookTestNotebook(t, HooksConfig{
+		PreIndex: "echo 'not ready' >&2; exit 1",
+	})
+	err := notebook.runHook(AuditOrigin{}, HookPreIndex, nil, false)
+	assert.Err(t, err, "pre-index hook failed: exit status 1: not ready")
+
+	_, err = notebook.Index(AuditOrigin{}, false)
+	assert.Err(t, err, "pre-index hook failed: exit status 1: not ready")
+}
+
+func TestNotebookNewNoteRunsPostNewHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zk-hook")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	test := newNoteTest{rootDir: dir}
+	test.setup()
+	test.config.Hooks.PostNew = "cat > payload.json"
+	_, err = test.run(NewNoteOpts{
+		Date:   now,
+		Origin: AuditOrigin{Interface: "cli", Command: "new"},
+	})
+	assert.Nil(t, err)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "payload.json"))
+	assert.Nil(t, err)
+	var payload HookPayload
+	assert.Nil(t, json.Unmarshal(content, &payload))
+	assert.Equal(t, payload.Hook, HookPostNew)
+	assert.Equal(t, payload.Interface, "cli")
+	assert.Equal(t, payload.Command, "new")
+	assert.Equal(t, payload.Notes, []HookNote{
+		{Path: "filename.ext", AbsPath: filepath.Join(dir, "filename.ext")},
+	})
+}
@@ -1,10 +1,53 @@
 package core
 
+import "fmt"
+
 // IDOptions holds the options used to generate an ID.
 type IDOptions struct {
-	Length  int
-	Charset Charset
-	Case    Case
+	Strategy IDStrategy
+	Length   int
+	Charset  Charset
+	Case     Case
+}
+
+// IDStrategy is a method used to generate the ID of new notes.
+type IDStrategy string
+
+const (
+	// IDStrategyRandom generates random IDs with the given length, charset
+	// and case.
+	IDStrategyRandom IDStrategy = "random"
+	// IDStrategyULID generates timestamp-sortable ULIDs, e.g.
+	// 01FJ2TQ3C5V8YPTAXCJ8R6W5Z3.
+	IDStrategyULID IDStrategy = "ulid"
+	// IDStrategyKSUID generates timestamp-sortable KSUIDs, e.g.
+	// 1zK8xBH5QO6wEjBpVq3SvNqGhnB.
+	IDStrategyKSUID IDStrategy = "ksuid"
+	// IDStrategyFolgezettel generates Luhmann-style hierarchical IDs derived
+	// from the ID of a parent note, e.g. 1a2 follows 1a1 under 1a.
+	IDStrategyFolgezettel IDStrategy = "folgezettel"
+	// IDStrategyHash derives the ID from the title and content of the note,
+	// to create the same note only once when importing it several times.
+	IDStrategyHash IDStrategy = "hash"
+)
+
+// IDStrategies lists the available ID strategies.
+var IDStrategies = []IDStrategy{
+	IDStrategyRandom,
+	IDStrategyULID,
+	IDStrategyKSUID,
+	IDStrategyFolgezettel,
+	IDStrategyHash,
+}
+
+// IDStrategyFromString returns the ID strategy matching the given name.
+func IDStrategyFromString(name string) (IDStrategy, error) {
+	for _, strategy := range IDStrategies {
+		if string(strategy) == name {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("%s: unknown ID strategy, try random, ulid, ksuid, folgezettel or hash", name)
 }
 
 // Charset is a set of characters.
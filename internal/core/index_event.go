@@ -0,0 +1,160 @@
+package core
+
+import (
+	"time"
+)
+
+// IndexEventLog records the changes of the notebook index in an append-only
+// stream, for external tools reacting to them.
+type IndexEventLog interface {
+	// Append records new events at the end of the log.
+	Append(events []IndexEvent) error
+	// Events returns the events recorded from the given position, and the
+	// position following the last one to read the next events later. The
+	// position 0 is the start of the log.
+	Events(from int64) (events []IndexEvent, next int64, err error)
+}
+
+// IndexEvent is a single change of the notebook index.
+type IndexEvent struct {
+	// Date when the change was indexed.
+	Time time.Time `json:"time"`
+	// Kind of change.
+	Kind IndexEventKind `json:"event"`
+	// Path of the note relative to the notebook root. For a broken link,
+	// this is the note containing the link.
+	Path string `json:"path"`
+	// Destination of a broken link, relative to the notebook root.
+	Href string `json:"href,omitempty"`
+	// Previous path of a renamed note, relative to the notebook root.
+	From string `json:"from,omitempty"`
+}
+
+// IndexEventKind is a kind of change recorded in the IndexEventLog.
+type IndexEventKind string
+
+const (
+	// A new note was indexed.
+	IndexEventNoteAdded IndexEventKind = "note-added"
+	// An indexed note was modified.
+	IndexEventNoteChanged IndexEventKind = "note-changed"
+	// A note was removed from the index.
+	IndexEventNoteRemoved IndexEventKind = "note-removed"
+	// A note was renamed or moved outside of zk.
+	IndexEventNoteRenamed IndexEventKind = "note-renamed"
+	// An internal link doesn't target an existing note or file anymore.
+	IndexEventLinkBroken IndexEventKind = "link-broken"
+)
+
+// IndexEvents returns the changes of the index recorded from the given
+// position, and the position of the next events.
+func (n *Notebook) IndexEvents(from int64) ([]IndexEvent, int64, error) {
+	if n.eventLog == nil {
+		return []IndexEvent{}, from, nil
+	}
+	return n.eventLog.Events(from)
+}
+
+// indexEventRecorder is a NoteIndex recording the changes made to the notes
+// during a transaction.
+type indexEventRecorder struct {
+	NoteIndex
+	notebook *Notebook
+	events   []IndexEvent
+	// Dead links before the first change, to report only the newly broken
+	// ones. Nil until a note is changed.
+	deadLinks map[UnresolvedLink]bool
+}
+
+func newIndexEventRecorder(index NoteIndex, notebook *Notebook) *indexEventRecorder {
+	return &indexEventRecorder{
+		NoteIndex: index,
+		notebook:  notebook,
+		events:    []IndexEvent{},
+	}
+}
+
+// Add implements NoteIndex.
+func (r *indexEventRecorder) Add(note Note) (NoteID, error) {
+	r.snapshotDeadLinks()
+	id, err := r.NoteIndex.Add(note)
+	if err == nil {
+		r.record(IndexEventNoteAdded, note.Path, "")
+	}
+	return id, err
+}
+
+// Update implements NoteIndex.
+func (r *indexEventRecorder) Update(note Note) error {
+	r.snapshotDeadLinks()
+	err := r.NoteIndex.Update(note)
+	if err == nil {
+		r.record(IndexEventNoteChanged, note.Path, "")
+	}
+	return err
+}
+
+// Remove implements NoteIndex.
+func (r *indexEventRecorder) Remove(path string) error {
+	r.snapshotDeadLinks()
+	err := r.NoteIndex.Remove(path)
+	if err == nil {
+		r.record(IndexEventNoteRemoved, path, "")
+	}
+	return err
+}
+
+// Commit implements NoteIndex.
+func (r *indexEventRecorder) Commit(transaction func(idx NoteIndex) error) error {
+	return r.NoteIndex.Commit(func(idx NoteIndex) error {
+		return transaction(r)
+	})
+}
+
+func (r *indexEventRecorder) record(kind IndexEventKind, path string, href string) {
+	r.events = append(r.events, IndexEvent{
+		Time: time.Now().UTC(),
+		Kind: kind,
+		Path: path,
+		Href: href,
+	})
+}
+
+func (r *indexEventRecorder) snapshotDeadLinks() {
+	if r.deadLinks != nil {
+		return
+	}
+	r.deadLinks = map[UnresolvedLink]bool{}
+	links, err := r.notebook.findDeadLinks(r.NoteIndex)
+	if err != nil {
+		r.notebook.logger.Err(err)
+		return
+	}
+	for _, link := range links {
+		r.deadLinks[link] = true
+	}
+}
+
+// finish returns the recorded events, with the links broken by the changes.
+//
+// Failing to find the broken links is not fatal, so errors are only logged.
+func (r *indexEventRecorder) finish() []IndexEvent {
+	if len(r.events) == 0 {
+		return r.events
+	}
+
+	links, err := r.notebook.findDeadLinks(r.NoteIndex)
+	if err != nil {
+		r.notebook.logger.Err(err)
+		return r.events
+	}
+	for _, link := range links {
+		if r.deadLinks[link] {
+			continue
+		}
+		// Prevents reporting twice the same link in a note.
+		r.deadLinks[link] = true
+		r.record(IndexEventLinkBroken, link.SourcePath, link.Href)
+	}
+	return r.events
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/paths"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+// linkIndexMock is a NoteIndex breaking the links to the removed notes.
+type linkIndexMock struct {
+	NoteIndex
+	links []UnresolvedLink
+}
+
+func (m *linkIndexMock) FindUnresolvedLinks() ([]UnresolvedLink, error) {
+	return m.links, nil
+}
+
+func (m *linkIndexMock) Add(note Note) (NoteID, error) {
+	return NoteID(1), nil
+}
+
+func (m *linkIndexMock) Update(note Note) error {
+	return nil
+}
+
+func (m *linkIndexMock) Remove(path string) error {
+	m.links = append(m.links, UnresolvedLink{SourcePath: "index.md", Href: paths.DropExt(path)})
+	return nil
+}
+
+func (m *linkIndexMock) Commit(transaction func(idx NoteIndex) error) error {
+	return transaction(m)
+}
+
+func TestIndexEventRecorder(t *testing.T) {
+	fs := newFileStorageMock("/notebook", []string{"/notebook"})
+	fs.files = map[string]string{"/notebook/image.png": ""}
+	notebook := NewNotebook("/notebook", NewDefaultConfig(), NotebookPorts{
+		FS:     fs,
+		Logger: &util.NullLogger,
+	})
+	index := &linkIndexMock{links: []UnresolvedLink{
+		// Already dead before the changes.
+		{SourcePath: "index.md", Href: "missing"},
+		// Links to a file.
+		{SourcePath: "index.md", Href: "image.png"},
+	}}
+
+	recorder := newIndexEventRecorder(index, notebook)
+	assert.Equal(t, recorder.finish(), []IndexEvent{})
+
+	err := recorder.Commit(func(idx NoteIndex) error {
+		_, err := idx.Add(Note{Path: "new.md"})
+		assert.Nil(t, err)
+		assert.Nil(t, idx.Update(Note{Path: "index.md"}))
+		assert.Nil(t, idx.Remove("old.md"))
+		return nil
+	})
+	assert.Nil(t, err)
+
+	events := recorder.finish()
+	kinds := []string{}
+	for _, event := range events {
+		kinds = append(kinds, string(event.Kind)+" "+event.Path+" "+event.Href)
+		assert.False(t, event.Time.IsZero())
+	}
+	assert.Equal(t, kinds, []string{
+		"note-added new.md ",
+		"note-changed index.md ",
+		"note-removed old.md ",
+		"link-broken index.md old",
+	})
+}
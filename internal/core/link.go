@@ -18,6 +18,14 @@ type Link struct {
 	SnippetEnd int
 }
 
+// NoteLink is a link between two indexed notes.
+type NoteLink struct {
+	// Path of the note containing the link.
+	SourcePath string
+	// Path of the note targeted by the link.
+	TargetPath string
+}
+
 // LinkRelation defines the relationship between a link's source and target.
 type LinkRelation string
 
@@ -0,0 +1,410 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/paths"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// DeadLink is an internal link targeting a note or file which doesn't exist.
+type DeadLink struct {
+	// Path of the note containing the link, relative to the notebook root.
+	Path string
+	// Line number of the link in the note, starting at 1.
+	Line int
+	// Destination of the link.
+	Href string
+	// Label of the link.
+	Title string
+	// Path relative to the notebook root targeted by the link.
+	Target string
+	// Notes which might be the intended target of the link, the most
+	// similar first.
+	Candidates []MinimalNote
+}
+
+// DeadLinkFix replaces the destination of a dead link with an existing note.
+type DeadLinkFix struct {
+	Link DeadLink
+	// Path of the new target note, relative to the notebook root.
+	TargetPath string
+}
+
+const (
+	// Maximum number of candidate targets suggested for a dead link.
+	deadLinkCandidatesLimit = 5
+	// Minimum similarity of the candidate targets suggested for a dead link.
+	deadLinkCandidatesThreshold = 0.25
+)
+
+// FindDeadLinks returns the internal links of the notebook targeting a note
+// or file which doesn't exist, with the notes they might have targeted,
+// matched by title or path.
+func (n *Notebook) FindDeadLinks() ([]DeadLink, error) {
+	wrap := errors.Wrapper("failed to find the dead links")
+
+	assets := map[string]bool{}
+	foundAssets, err := n.FindAssets()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	for _, asset := range foundAssets {
+		assets[asset.Path] = true
+	}
+
+	candidates, err := n.FindMinimalNotes(NoteFindOpts{})
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	// The malformed notes are reported by Notebook.Lint.
+	notes, _ := n.readLintNotes()
+	links := []DeadLink{}
+	for _, note := range notes {
+		for _, link := range note.links {
+			dead, err := n.isDeadLink(note.path, link.Href, assets)
+			if err != nil {
+				return nil, wrap(err)
+			}
+			if !dead {
+				continue
+			}
+
+			line := 0
+			if link.SnippetStart <= len(note.content) {
+				line = strings.Count(note.content[:link.SnippetStart], "\n") + 1
+			}
+			target, _ := lintLinkTarget(note.path, link.Href)
+			links = append(links, DeadLink{
+				Path:       note.path,
+				Line:       line,
+				Href:       link.Href,
+				Title:      link.Title,
+				Target:     target,
+				Candidates: rankLinkTargets(link, note.path, candidates, deadLinkCandidatesThreshold, deadLinkCandidatesLimit),
+			})
+		}
+	}
+
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].Path != links[j].Path {
+			return links[i].Path < links[j].Path
+		}
+		return links[i].Line < links[j].Line
+	})
+	return links, nil
+}
+
+// rankLinkTargets returns up to limit notes whose path or title is similar
+// to the destination or label of the given link, the most similar first.
+// The note containing the link at notePath is never suggested.
+func rankLinkTargets(link Link, notePath string, notes []MinimalNote, threshold float64, limit int) []MinimalNote {
+	href := strings.SplitN(link.Href, "#", 2)[0]
+	title := strings.TrimSpace(link.Title)
+
+	type match struct {
+		note  MinimalNote
+		score float64
+	}
+	matches := []match{}
+	for _, note := range notes {
+		if note.Path == notePath {
+			continue
+		}
+		score := 0.0
+		if href != "" {
+			score = fuzzyHrefSimilarity(href, note)
+		}
+		if title != "" && title != link.Href {
+			score = math.Max(score, strutil.TrigramSimilarity(title, note.Title))
+		}
+		if score >= threshold {
+			matches = append(matches, match{note, score})
+		}
+	}
+
+	// On a tie, the shortest path is the best match, like FindByHref.
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].note.Path) < len(matches[j].note.Path)
+	})
+
+	res := []MinimalNote{}
+	for i, match := range matches {
+		if i == limit {
+			break
+		}
+		res = append(res, match.note)
+	}
+	return res
+}
+
+// FixDeadLinks replaces the destination of the given dead links with their
+// new target notes. Every occurrence of a dead link in its note is fixed.
+//
+// When dryRun is true, the rewritten links are returned without modifying
+// any file.
+func (n *Notebook) FixDeadLinks(origin AuditOrigin, fixes []DeadLinkFix, dryRun bool) ([]RewrittenLink, error) {
+	wrap := errors.Wrapper("failed to fix the dead links")
+
+	fixesByNote := map[string][]DeadLinkFix{}
+	notePaths := []string{}
+	for _, fix := range fixes {
+		path := fix.Link.Path
+		if _, ok := fixesByNote[path]; !ok {
+			notePaths = append(notePaths, path)
+		}
+		fixesByNote[path] = append(fixesByNote[path], fix)
+	}
+	sort.Strings(notePaths)
+
+	contents := map[string]string{}
+	links := []RewrittenLink{}
+	for _, path := range notePaths {
+		content, err := n.fs.Read(filepath.Join(n.Path, path))
+		if err != nil {
+			return nil, wrap(err)
+		}
+		newContent, rewritten := FixDeadLinksInNote(string(content), path, fixesByNote[path])
+		if len(rewritten) > 0 {
+			contents[path] = newContent
+			links = append(links, rewritten...)
+		}
+	}
+
+	if dryRun || len(contents) == 0 {
+		return links, nil
+	}
+
+	paths, err := n.writeReplacedNotes(contents)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	n.audit(origin, AuditOperationReplace,
+		fmt.Sprintf("fixed %d dead %s", len(links), strutil.Pluralize("link", len(links))),
+		paths...,
+	)
+	return links, nil
+}
+
+// FixDeadLinksInNote rewrites the dead links found in the content of the note
+// at notePath with the given fixes, e.g. to fix an unsaved buffer.
+func FixDeadLinksInNote(content string, notePath string, fixes []DeadLinkFix) (string, []RewrittenLink) {
+	return rewriteLinks(content, notePath, func(href string, isWikiLink bool) (string, bool) {
+		// The wiki-link destinations are matched without their anchor.
+		path := strings.SplitN(href, "#", 2)[0]
+		for _, fix := range fixes {
+			if path == strings.SplitN(fix.Link.Href, "#", 2)[0] {
+				return fixedLinkHref(href, isWikiLink, notePath, fix.TargetPath), true
+			}
+		}
+		return "", false
+	})
+}
+
+// fixedLinkHref returns the href replacing a dead link found in the note at
+// notePath, to target the note at targetPath instead. The anchor and the
+// style of the dead link are kept.
+func fixedLinkHref(href string, isWikiLink bool, notePath string, targetPath string) string {
+	path, anchor, escaped := splitHref(href)
+
+	newPath := targetPath
+	if !strings.EqualFold(filepath.Ext(path), filepath.Ext(targetPath)) {
+		newPath = paths.DropExt(newPath)
+	}
+	// Wiki-links are relative to the notebook root.
+	if !isWikiLink {
+		if rel, err := filepath.Rel(filepath.Dir(notePath), newPath); err == nil {
+			newPath = rel
+		}
+		// Markdown link destinations can't contain spaces.
+		escaped = escaped || strings.ContainsAny(newPath, " \t")
+	}
+	return joinHref(newPath, anchor, escaped)
+}
+
+// DefaultRetargetThreshold is the minimum similarity of the note replacing
+// the destination of a dead link, when retargeted without confirmation.
+const DefaultRetargetThreshold = 0.6
+
+// DeadLinksFixOpts holds the options used to fix all the dead links of a
+// notebook at once. The dead links neither retargeted nor resolved by a
+// stub are left untouched.
+type DeadLinksFixOpts struct {
+	// Replaces the destination of the dead links with their most similar
+	// note, if any is similar enough.
+	Retarget bool
+	// Minimum similarity of the retargeted notes, from 0 to 1. Defaults to
+	// DefaultRetargetThreshold.
+	RetargetThreshold float64
+	// Creates a stub note at the destination of the dead links which are not
+	// retargeted.
+	CreateStubs bool
+}
+
+// DeadLinkStub is a new note resolving dead links.
+type DeadLinkStub struct {
+	// Path of the note, relative to the notebook root.
+	Path string
+	// Title of the note, from the label of the first dead link.
+	Title string
+	// Name of the config group of the note.
+	Group string
+	// Dead links targeting the note.
+	Links []DeadLink
+}
+
+// DeadLinksFixPlan lists how the dead links of a notebook are fixed.
+type DeadLinksFixPlan struct {
+	Retargets []DeadLinkFix
+	Stubs     []DeadLinkStub
+	// Dead links which are left untouched.
+	Unfixed []DeadLink
+}
+
+// PlanDeadLinkFixes finds the dead links of the notebook, and decides how to
+// fix each of them with the given options. Nothing is modified, use
+// CreateDeadLinkStubs and FixDeadLinks to apply the plan.
+func (n *Notebook) PlanDeadLinkFixes(opts DeadLinksFixOpts) (DeadLinksFixPlan, error) {
+	wrap := errors.Wrapper("failed to plan the dead links fixes")
+
+	links, err := n.FindDeadLinks()
+	if err != nil {
+		return DeadLinksFixPlan{}, wrap(err)
+	}
+	candidates, err := n.FindMinimalNotes(NoteFindOpts{})
+	if err != nil {
+		return DeadLinksFixPlan{}, wrap(err)
+	}
+
+	plan, err := planDeadLinkFixes(links, candidates, opts, n.deadLinkStub)
+	return plan, wrap(err)
+}
+
+// planDeadLinkFixes retargets the dead links to the most similar candidate
+// above the threshold, or resolves them with the stubs returned by stubFor.
+// A single stub is created for the dead links sharing the same target.
+func planDeadLinkFixes(
+	links []DeadLink,
+	candidates []MinimalNote,
+	opts DeadLinksFixOpts,
+	stubFor func(link DeadLink) (DeadLinkStub, bool, error),
+) (DeadLinksFixPlan, error) {
+	plan := DeadLinksFixPlan{
+		Retargets: []DeadLinkFix{},
+		Stubs:     []DeadLinkStub{},
+		Unfixed:   []DeadLink{},
+	}
+
+	threshold := opts.RetargetThreshold
+	if threshold <= 0 {
+		threshold = DefaultRetargetThreshold
+	}
+	stubIndexes := map[string]int{}
+
+	for _, link := range links {
+		if opts.Retarget {
+			matches := rankLinkTargets(Link{Href: link.Href, Title: link.Title}, link.Path, candidates, threshold, 1)
+			if len(matches) > 0 {
+				plan.Retargets = append(plan.Retargets, DeadLinkFix{
+					Link:       link,
+					TargetPath: matches[0].Path,
+				})
+				continue
+			}
+		}
+
+		if opts.CreateStubs {
+			stub, ok, err := stubFor(link)
+			if err != nil {
+				return plan, err
+			}
+			if ok {
+				if i, found := stubIndexes[stub.Path]; found {
+					plan.Stubs[i].Links = append(plan.Stubs[i].Links, link)
+				} else {
+					stubIndexes[stub.Path] = len(plan.Stubs)
+					stub.Links = []DeadLink{link}
+					plan.Stubs = append(plan.Stubs, stub)
+				}
+				continue
+			}
+		}
+
+		plan.Unfixed = append(plan.Unfixed, link)
+	}
+
+	return plan, nil
+}
+
+// deadLinkStub returns the stub note created at the destination of the given
+// dead link. Returns false when the destination is out of the notebook, or
+// when a file which is not a note already exists there.
+func (n *Notebook) deadLinkStub(link DeadLink) (DeadLinkStub, bool, error) {
+	path := link.Target
+	if path == "" || filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, "../") {
+		return DeadLinkStub{}, false, nil
+	}
+
+	group, err := n.Config.GroupNameForPath(filepath.Dir(path))
+	if err != nil {
+		return DeadLinkStub{}, false, err
+	}
+	if filepath.Ext(path) == "" {
+		config, err := n.Config.GroupConfigNamed(group)
+		if err != nil {
+			return DeadLinkStub{}, false, err
+		}
+		path += "." + config.Note.Extension
+	}
+
+	exists, err := n.fs.FileExists(filepath.Join(n.Path, path))
+	if err != nil || exists {
+		return DeadLinkStub{}, false, err
+	}
+
+	title := strings.TrimSpace(link.Title)
+	if title == "" || title == link.Href {
+		title = paths.FilenameStem(path)
+	}
+
+	return DeadLinkStub{
+		Path:  path,
+		Title: title,
+		Group: group,
+	}, true, nil
+}
+
+// CreateDeadLinkStubs creates the given stub notes, rendered with the body
+// template of their group, or the custom template if provided.
+func (n *Notebook) CreateDeadLinkStubs(origin AuditOrigin, stubs []DeadLinkStub, template opt.String) ([]*Note, error) {
+	if len(stubs) == 0 {
+		return []*Note{}, nil
+	}
+
+	now := time.Now()
+	opts := []NewNoteOpts{}
+	for _, stub := range stubs {
+		opts = append(opts, NewNoteOpts{
+			Title:    opt.NewNotEmptyString(stub.Title),
+			Filename: opt.NewString(stub.Path),
+			Group:    opt.NewNotEmptyString(stub.Group),
+			Template: template,
+			Date:     now,
+			Origin:   origin,
+		})
+	}
+
+	notes, err := n.NewNotes(opts)
+	return notes, errors.Wrap(err, "failed to create the dead links stubs")
+}
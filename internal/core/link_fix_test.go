@@ -0,0 +1,175 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/opt"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestRankLinkTargets(t *testing.T) {
+	notes := []MinimalNote{
+		{Path: "index.md", Title: "Index"},
+		{Path: "dir/gardening.md", Title: "Gardening tips"},
+		{Path: "garden.md", Title: "My garden"},
+		{Path: "cooking.md", Title: "Cooking"},
+		{Path: "recipes/pasta.md", Title: "Fresh pasta"},
+	}
+
+	test := func(link Link, limit int, expected []string) {
+		actual := []string{}
+		for _, note := range rankLinkTargets(link, "index.md", notes, 0.3, limit) {
+			actual = append(actual, note.Path)
+		}
+		assert.Equal(t, actual, expected)
+	}
+
+	// Matched by path.
+	test(Link{Href: "garden"}, 5, []string{"garden.md", "dir/gardening.md"})
+	test(Link{Href: "garden#plants"}, 1, []string{"garden.md"})
+	test(Link{Href: "recipes/past.md"}, 5, []string{"recipes/pasta.md"})
+	// Matched by the label of the link.
+	test(Link{Href: "202101011200", Title: "Fresh pasta"}, 5, []string{"recipes/pasta.md"})
+	// The note containing the link is not suggested.
+	test(Link{Href: "index"}, 5, []string{})
+	test(Link{Href: "unrelated"}, 5, []string{})
+}
+
+func TestFixDeadLinksInNote(t *testing.T) {
+	test := func(notePath string, content string, fixes []DeadLinkFix, expectedContent string, expectedLinks []RewrittenLink) {
+		actualContent, actualLinks := FixDeadLinksInNote(content, notePath, fixes)
+		assert.Equal(t, actualContent, expectedContent)
+		assert.Equal(t, actualLinks, expectedLinks)
+	}
+
+	fixes := []DeadLinkFix{
+		{Link: DeadLink{Href: "garden.md#plants"}, TargetPath: "dir/my garden.md"},
+		{Link: DeadLink{Href: "pasta"}, TargetPath: "recipes/pasta.md"},
+	}
+
+	test("index.md", "No links", fixes, "No links", []RewrittenLink{})
+
+	// Markdown links are relative to the note, and keep their extension and
+	// anchor.
+	test("notes/index.md",
+		"A [link](garden.md#plants), [another](garden.md#plants) and [unrelated](garden).",
+		fixes,
+		"A [link](../dir/my%20garden.md#plants), [another](../dir/my%20garden.md#plants) and [unrelated](garden).",
+		[]RewrittenLink{
+			{Path: "notes/index.md", OldHref: "garden.md#plants", NewHref: "../dir/my%20garden.md#plants"},
+			{Path: "notes/index.md", OldHref: "garden.md#plants", NewHref: "../dir/my%20garden.md#plants"},
+		},
+	)
+
+	// Wiki-links are relative to the notebook root.
+	test("notes/index.md",
+		"A [[pasta]] wiki-link and [[pasta#sauce | a titled]] one.",
+		fixes,
+		"A [[recipes/pasta]] wiki-link and [[recipes/pasta#sauce | a titled]] one.",
+		[]RewrittenLink{
+			{Path: "notes/index.md", OldHref: "pasta", NewHref: "recipes/pasta"},
+			{Path: "notes/index.md", OldHref: "pasta", NewHref: "recipes/pasta"},
+		},
+	)
+}
+
+func TestPlanDeadLinkFixes(t *testing.T) {
+	candidates := []MinimalNote{
+		{Path: "garden.md", Title: "My garden"},
+		{Path: "recipes/pasta.md", Title: "Fresh pasta"},
+	}
+	links := []DeadLink{
+		{Path: "index.md", Line: 1, Href: "gardens", Target: "gardens"},
+		{Path: "index.md", Line: 2, Href: "ideas/cooking", Title: "Cooking ideas", Target: "ideas/cooking"},
+		{Path: "log.md", Line: 3, Href: "ideas/cooking#pies", Target: "ideas/cooking"},
+		{Path: "log.md", Line: 4, Href: "../outside", Target: "../outside"},
+	}
+	stubFor := func(link DeadLink) (DeadLinkStub, bool, error) {
+		if strings.HasPrefix(link.Target, "..") {
+			return DeadLinkStub{}, false, nil
+		}
+		return DeadLinkStub{Path: link.Target + ".md", Title: link.Title}, true, nil
+	}
+
+	test := func(opts DeadLinksFixOpts, expected DeadLinksFixPlan) {
+		t.Helper()
+		actual, err := planDeadLinkFixes(links, candidates, opts, stubFor)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	// Only reported.
+	test(DeadLinksFixOpts{}, DeadLinksFixPlan{
+		Retargets: []DeadLinkFix{},
+		Stubs:     []DeadLinkStub{},
+		Unfixed:   links,
+	})
+
+	test(DeadLinksFixOpts{Retarget: true}, DeadLinksFixPlan{
+		Retargets: []DeadLinkFix{{Link: links[0], TargetPath: "garden.md"}},
+		Stubs:     []DeadLinkStub{},
+		Unfixed:   links[1:],
+	})
+
+	// A higher threshold doesn't retarget the approximate link.
+	test(DeadLinksFixOpts{Retarget: true, RetargetThreshold: 0.9}, DeadLinksFixPlan{
+		Retargets: []DeadLinkFix{},
+		Stubs:     []DeadLinkStub{},
+		Unfixed:   links,
+	})
+
+	// The links to the same target share a stub, the retargeted links don't
+	// get one.
+	test(DeadLinksFixOpts{Retarget: true, CreateStubs: true}, DeadLinksFixPlan{
+		Retargets: []DeadLinkFix{{Link: links[0], TargetPath: "garden.md"}},
+		Stubs: []DeadLinkStub{
+			{Path: "ideas/cooking.md", Title: "Cooking ideas", Links: []DeadLink{links[1], links[2]}},
+		},
+		Unfixed: []DeadLink{links[3]},
+	})
+
+	test(DeadLinksFixOpts{CreateStubs: true}, DeadLinksFixPlan{
+		Retargets: []DeadLinkFix{},
+		Stubs: []DeadLinkStub{
+			{Path: "gardens.md", Links: []DeadLink{links[0]}},
+			{Path: "ideas/cooking.md", Title: "Cooking ideas", Links: []DeadLink{links[1], links[2]}},
+		},
+		Unfixed: []DeadLink{links[3]},
+	})
+}
+
+func TestFindDeadLinksResolvesWikiLinksToTitles(t *testing.T) {
+	notebook, _ := newResolveTestNotebook(t, map[string]*NoteContent{
+		"a.md": {Title: opt.NewString("Alpha note")},
+		"gamma.md": {
+			Title: opt.NewString("Gamma"),
+			Links: []Link{{Href: "Alpha note"}, {Href: "Alpha"}, {Href: "Missing"}},
+		},
+	})
+
+	links, err := notebook.FindDeadLinks()
+	assert.Nil(t, err)
+	hrefs := []string{}
+	for _, link := range links {
+		hrefs = append(hrefs, link.Href)
+	}
+	assert.Equal(t, hrefs, []string{"Missing"})
+}
+
+func TestPlanDeadLinkFixesKeepsResolvedLinks(t *testing.T) {
+	notebook, _ := newResolveTestNotebook(t, map[string]*NoteContent{
+		"a.md": {Title: opt.NewString("Alpha note")},
+		"gamma.md": {
+			Title: opt.NewString("Gamma"),
+			Links: []Link{{Href: "Alpha note"}, {Href: "Alpha notes"}},
+		},
+	})
+
+	plan, err := notebook.PlanDeadLinkFixes(DeadLinksFixOpts{Retarget: true, RetargetThreshold: 0.5})
+	assert.Nil(t, err)
+	assert.Equal(t, len(plan.Retargets), 1)
+	assert.Equal(t, plan.Retargets[0].Link.Href, "Alpha notes")
+	assert.Equal(t, plan.Retargets[0].TargetPath, "a.md")
+}
@@ -87,6 +87,28 @@ func NewWikiLinkFormatter(config MarkdownConfig) (LinkFormatter, error) {
 	}, nil
 }
 
+// NewTitledWikiLinkFormatter generates wiki-links labeled with the title of
+// the note, e.g. [[path|Title]], or [[Title|path]] when the alias comes
+// first.
+func NewTitledWikiLinkFormatter(config MarkdownConfig) (LinkFormatter, error) {
+	formatter, err := NewWikiLinkFormatter(config)
+	if err != nil {
+		return nil, err
+	}
+	return func(context LinkFormatterContext) (string, error) {
+		link, err := formatter(context)
+		if err != nil || context.Title == "" {
+			return link, err
+		}
+		title := strings.ReplaceAll(context.Title, "]]", `\]]`)
+		target := strings.TrimSuffix(strings.TrimPrefix(link, "[["), "]]")
+		if config.WikiLinkAliasFirst {
+			return "[[" + title + "|" + target + "]]", nil
+		}
+		return "[[" + target + "|" + title + "]]", nil
+	}, nil
+}
+
 func NewCustomLinkFormatter(config MarkdownConfig, templateLoader TemplateLoader) (LinkFormatter, error) {
 	wrap := errors.Wrapperf("failed to render custom link with format: %s", config.LinkFormat)
 	template, err := templateLoader.LoadTemplate(config.LinkFormat)
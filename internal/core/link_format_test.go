@@ -116,6 +116,35 @@ func TestWikiLinkFormatter(t *testing.T) {
 	test("path/to note.md", "title", "[[path/to%20note]]")
 }
 
+func TestTitledWikiLinkFormatter(t *testing.T) {
+	formatter, err := NewTitledWikiLinkFormatter(MarkdownConfig{LinkDropExtension: true})
+	assert.Nil(t, err)
+
+	test := func(path, title, expected string) {
+		actual, err := formatter(LinkFormatterContext{Path: path, Title: title})
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("path/to note.md", "An interesting title", "[[path/to note|An interesting title]]")
+	test("path/to note.md", "", "[[path/to note]]")
+	test("note.md", "Nested [[brackets]]", `[[note|Nested [[brackets\]]]]`)
+}
+
+func TestTitledWikiLinkFormatterWithAliasFirst(t *testing.T) {
+	formatter, err := NewTitledWikiLinkFormatter(MarkdownConfig{LinkDropExtension: true, WikiLinkAliasFirst: true})
+	assert.Nil(t, err)
+
+	test := func(path, title, expected string) {
+		actual, err := formatter(LinkFormatterContext{Path: path, Title: title})
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("path/to note.md", "An interesting title", "[[An interesting title|path/to note]]")
+	test("path/to note.md", "", "[[path/to note]]")
+}
+
 func TestCustomLinkFormatter(t *testing.T) {
 	newTester := func(encodePath, dropExtension bool) func(path, title string, expected LinkFormatterContext) {
 		return func(path, title string, expected LinkFormatterContext) {
@@ -0,0 +1,401 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// LinkSyntax is the syntax of an internal link.
+type LinkSyntax string
+
+const (
+	// Wiki-links, e.g. [[path|label]].
+	LinkSyntaxWiki LinkSyntax = "wiki"
+	// Markdown links, e.g. [label](path).
+	LinkSyntaxMarkdown LinkSyntax = "markdown"
+)
+
+// LinkPathStyle is the way an internal link refers to the target note.
+type LinkPathStyle string
+
+const (
+	// Path relative to the directory of the note containing the link.
+	LinkPathRelative LinkPathStyle = "relative"
+	// Path relative to the notebook root.
+	LinkPathRoot LinkPathStyle = "root"
+	// Only the filename stem of the target note, e.g. its ID.
+	LinkPathID LinkPathStyle = "id"
+)
+
+// MigrateLinksOpts holds the options used to change the style of the
+// internal links across the notebook.
+type MigrateLinksOpts struct {
+	// Syntax of the migrated links. An empty syntax keeps the syntax of each
+	// link.
+	Syntax LinkSyntax
+	// Style of the link paths. When empty, it is relative to the note for
+	// Markdown links and to the notebook root for wiki-links.
+	PathStyle LinkPathStyle
+	// Lists the links to migrate without rewriting the notes.
+	DryRun bool
+	// Command migrating the links.
+	Origin AuditOrigin
+}
+
+// LinkMigration reports the changes made when migrating the links.
+type LinkMigration struct {
+	// Notes containing migrated links. Their skipped occurrences are the
+	// links which could not be resolved to a note.
+	Notes []NoteReplacement `json:"notes"`
+	// New link format saved in the notebook config, if any.
+	LinkFormat string `json:"linkFormat,omitempty"`
+}
+
+// MigrateLinks rewrites the internal links of every note with the given
+// syntax and path style, then saves the matching link format in the
+// notebook config, so that the new links follow the same convention.
+func (n *Notebook) MigrateLinks(opts MigrateLinksOpts) (*LinkMigration, error) {
+	wrap := errors.Wrapper("failed to migrate the links")
+
+	notes, err := n.FindMinimalNotes(NoteFindOpts{
+		Sorters: []NoteSorter{{Field: NoteSortPath, Ascending: true}},
+	})
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	resolved := map[string]*MinimalNote{}
+	var resolveErr error
+	resolve := func(notePath string, path string, isWikiLink bool) *MinimalNote {
+		key := fmt.Sprintf("%s\x00%s\x00%t", filepath.Dir(notePath), path, isWikiLink)
+		if note, ok := resolved[key]; ok {
+			return note
+		}
+		note, _, err := n.ResolveLink(notePath, path, ResolveLinkOpts{IsWikiLink: isWikiLink})
+		if err != nil && resolveErr == nil {
+			resolveErr = err
+		}
+		resolved[key] = note
+		return note
+	}
+
+	migration := LinkMigration{Notes: []NoteReplacement{}}
+	contents := map[string]string{}
+	for _, note := range notes {
+		content, err := n.fs.Read(filepath.Join(n.Path, note.Path))
+		if err != nil {
+			return nil, wrap(err)
+		}
+		newContent, replacement := migrateLinksInNote(string(content), note.Path, opts, n.Config, func(path string, isWikiLink bool) *MinimalNote {
+			if _, _, federated := n.FederatedHref(path); federated {
+				return nil
+			}
+			return resolve(note.Path, path, isWikiLink)
+		})
+		if resolveErr != nil {
+			return nil, wrap(resolveErr)
+		}
+		if len(replacement.Changes) == 0 && replacement.Skipped == 0 {
+			continue
+		}
+		replacement.Path = note.Path
+		migration.Notes = append(migration.Notes, replacement)
+		if len(replacement.Changes) > 0 {
+			contents[note.Path] = newContent
+		}
+	}
+
+	migration.LinkFormat = opts.linkFormat()
+	if opts.DryRun {
+		return &migration, nil
+	}
+
+	if len(contents) > 0 {
+		paths, err := n.writeReplacedNotes(contents)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		n.audit(opts.Origin, AuditOperationReplace,
+			fmt.Sprintf("migrated the links of %d %s", len(paths), strutil.Pluralize("note", len(paths))),
+			paths...,
+		)
+	}
+
+	if migration.LinkFormat != "" {
+		err = n.setConfigValue("format.markdown", "link-format", migration.LinkFormat)
+		if err != nil {
+			return nil, wrap(err)
+		}
+	}
+
+	return &migration, nil
+}
+
+// linkFormat returns the link-format setting generating links with the
+// style of the migration, or an empty string when the syntax is kept.
+func (opts MigrateLinksOpts) linkFormat() string {
+	switch opts.Syntax {
+	case LinkSyntaxWiki:
+		switch opts.pathStyle(LinkSyntaxWiki) {
+		case LinkPathRelative:
+			return "[[{{rel-path}}]]"
+		case LinkPathID:
+			return "[[{{filename}}]]"
+		default:
+			return "wiki"
+		}
+	case LinkSyntaxMarkdown:
+		switch opts.pathStyle(LinkSyntaxMarkdown) {
+		case LinkPathRoot:
+			return "[{{title}}]({{path}})"
+		case LinkPathID:
+			return "[{{title}}]({{filename}})"
+		default:
+			return "markdown"
+		}
+	default:
+		return ""
+	}
+}
+
+// pathStyle returns the path style of the links with the given syntax,
+// defaulting to the convention of the syntax.
+func (opts MigrateLinksOpts) pathStyle(syntax LinkSyntax) LinkPathStyle {
+	switch {
+	case opts.PathStyle != "":
+		return opts.PathStyle
+	case syntax == LinkSyntaxWiki:
+		return LinkPathRoot
+	default:
+		return LinkPathRelative
+	}
+}
+
+// migrateLinksInNote rewrites the internal links of the note at notePath,
+// resolving their target note with the resolve callback.
+//
+// The links to external URLs, assets and other notebooks are left untouched,
+// as well as the unresolved links, which are reported as skipped.
+func migrateLinksInNote(content string, notePath string, opts MigrateLinksOpts, config Config, resolve func(path string, isWikiLink bool) *MinimalNote) (string, NoteReplacement) {
+	type span struct{ start, end int }
+	protected := []span{}
+	if loc := frontmatterRegex.FindStringIndex(content); loc != nil {
+		protected = append(protected, span{loc[0], loc[1]})
+	}
+	for _, regex := range codeRegexes {
+		for _, loc := range regex.FindAllStringIndex(content, -1) {
+			protected = append(protected, span{loc[0], loc[1]})
+		}
+	}
+	isProtected := func(offset int) bool {
+		for _, s := range protected {
+			if offset >= s.start && offset < s.end {
+				return true
+			}
+		}
+		return false
+	}
+
+	// link is an internal link found in the note.
+	type link struct {
+		start, end int
+		syntax     LinkSyntax
+		// Destination of the link, without its anchor.
+		path   string
+		anchor string
+		label  string
+		// Range of the destination in the content, to rewrite only the path
+		// when the syntax is kept.
+		pathStart, pathEnd int
+	}
+	links := []link{}
+	for _, match := range markdownLinkRegex.FindAllStringSubmatchIndex(content, -1) {
+		if match[3] > match[2] {
+			// Images are not links to notes.
+			continue
+		}
+		start, end := match[6], match[7]
+		if content[start] == '<' {
+			start, end = start+1, end-1
+		}
+		path, anchor, _ := splitHref(content[start:end])
+		links = append(links, link{
+			start: match[0], end: match[1],
+			syntax:    LinkSyntaxMarkdown,
+			path:      path,
+			anchor:    anchor,
+			label:     content[match[4]:match[5]],
+			pathStart: start, pathEnd: end,
+		})
+	}
+	for _, match := range wikiLinkRegex.FindAllStringSubmatchIndex(content, -1) {
+		l := link{
+			start: match[0], end: match[1],
+			syntax:    LinkSyntaxWiki,
+			path:      content[match[2]:match[3]],
+			pathStart: match[2], pathEnd: match[3],
+		}
+		if match[4] >= 0 {
+			l.label = content[match[4]:match[5]]
+		}
+		if rest := strings.TrimSpace(content[match[3]:match[1]]); strings.HasPrefix(rest, "#") {
+			l.anchor = strings.TrimRight(strings.SplitN(rest, "|", 2)[0], "] \t")
+		}
+		links = append(links, l)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].start < links[j].start
+	})
+
+	result := NoteReplacement{}
+	edits := []textEdit{}
+	last := 0
+	for _, l := range links {
+		if l.start < last || isProtected(l.start) {
+			continue
+		}
+		last = l.end
+		if l.path == "" || strutil.IsURL(l.path) {
+			continue
+		}
+		target := resolve(l.path, l.syntax == LinkSyntaxWiki)
+		if target == nil {
+			// Links to assets, e.g. images, are not reported.
+			if ext := filepath.Ext(l.path); ext == "" || ext == "."+config.Note.Extension {
+				result.Skipped++
+			}
+			continue
+		}
+
+		syntax := opts.Syntax
+		if syntax == "" {
+			syntax = l.syntax
+		}
+		path := migratedLinkPath(target.Path, notePath, opts.pathStyle(syntax), config.Format.Markdown)
+
+		if syntax == l.syntax {
+			// Keep the label and anchor as written.
+			if syntax == LinkSyntaxMarkdown {
+				path = joinHref(path, l.anchor, true)
+			}
+			if content[l.pathStart:l.pathEnd] == path {
+				continue
+			}
+			edits = append(edits, textEdit{l.pathStart, l.pathEnd, path})
+			continue
+		}
+
+		var text string
+		switch syntax {
+		case LinkSyntaxWiki:
+			text = "[[" + path + l.anchor
+			if label := strings.TrimSpace(l.label); label != "" && label != target.Title && label != l.path {
+				text += "|" + strings.ReplaceAll(label, "]]", `\]]`)
+			}
+			text += "]]"
+		case LinkSyntaxMarkdown:
+			label := l.label
+			if label == "" {
+				label = target.Title
+			}
+			if label == "" {
+				label = l.path
+			}
+			label = strings.ReplaceAll(label, `]`, `\]`)
+			text = "[" + label + "](" + joinHref(path, l.anchor, true) + ")"
+		}
+		edits = append(edits, textEdit{l.start, l.end, text})
+	}
+
+	newContent, changes := applyTextEdits(content, edits)
+	result.Changes = changes
+	return newContent, result
+}
+
+// migratedLinkPath returns the path of a link to the note at targetPath,
+// found in the note at notePath.
+func migratedLinkPath(targetPath string, notePath string, style LinkPathStyle, config MarkdownConfig) string {
+	path := targetPath
+	switch style {
+	case LinkPathID:
+		return paths.FilenameStem(targetPath)
+	case LinkPathRelative:
+		if rel, err := filepath.Rel(filepath.Dir(notePath), targetPath); err == nil {
+			path = rel
+		}
+	}
+	if config.LinkDropExtension {
+		path = paths.DropExt(path)
+	}
+	return filepath.ToSlash(path)
+}
+
+// setConfigValue sets a string setting in the given table of the notebook
+// config file, keeping the rest of the file as written.
+func (n *Notebook) setConfigValue(table string, key string, value string) error {
+	path := filepath.Join(n.Path, ".zk/config.toml")
+	content := ""
+	if exists, err := n.fs.FileExists(path); err != nil {
+		return err
+	} else if exists {
+		bytes, err := n.fs.Read(path)
+		if err != nil {
+			return err
+		}
+		content = string(bytes)
+	}
+	return n.fs.Write(path, []byte(setTOMLValue(content, table, key, value)))
+}
+
+var tomlTableRegex = regexp.MustCompile(`^\s*\[\s*([^\]\s]+)\s*\]`)
+
+// setTOMLValue sets the string value of the key in the given table of a TOML
+// document, adding the table if needed.
+func setTOMLValue(content string, table string, key string, value string) string {
+	line := key + " = " + strconv.Quote(value)
+	keyRegex := regexp.MustCompile(`^(\s*)` + regexp.QuoteMeta(key) + `\s*=`)
+
+	lines := strings.Split(content, "\n")
+	inTable := false
+	tableEnd := -1
+	for i, l := range lines {
+		if match := tomlTableRegex.FindStringSubmatch(l); match != nil {
+			if inTable {
+				break
+			}
+			inTable = match[1] == table
+			if inTable {
+				tableEnd = i + 1
+			}
+			continue
+		}
+		if !inTable {
+			continue
+		}
+		if match := keyRegex.FindStringSubmatch(l); match != nil {
+			lines[i] = match[1] + line
+			return strings.Join(lines, "\n")
+		}
+		if strings.TrimSpace(l) != "" {
+			tableEnd = i + 1
+		}
+	}
+
+	if tableEnd < 0 {
+		content = strings.TrimRight(content, "\n")
+		if content != "" {
+			content += "\n\n"
+		}
+		return content + "[" + table + "]\n" + line + "\n"
+	}
+	lines = append(lines[:tableEnd], append([]string{line}, lines[tableEnd:]...)...)
+	return strings.Join(lines, "\n")
+}
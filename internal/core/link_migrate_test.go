@@ -0,0 +1,116 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestMigrateLinksInNote(t *testing.T) {
+	notes := map[string]MinimalNote{
+		"ref/abc1.md": {Path: "ref/abc1.md", Title: "Alpha note"},
+		"def2.md":     {Path: "def2.md", Title: "Beta"},
+	}
+	resolve := func(path string, isWikiLink bool) *MinimalNote {
+		for _, candidate := range []string{path, path + ".md", "ref/" + path + ".md", "ref/" + path} {
+			if note, ok := notes[candidate]; ok {
+				return &note
+			}
+		}
+		return nil
+	}
+	config := NewDefaultConfig()
+
+	test := func(content string, opts MigrateLinksOpts, expectedContent string, expectedSkipped int) {
+		t.Helper()
+		actualContent, actual := migrateLinksInNote(content, "def2.md", opts, config, resolve)
+		assert.Equal(t, actualContent, expectedContent)
+		assert.Equal(t, actual.Skipped, expectedSkipped)
+	}
+
+	content := "See [[abc1]], [[ref/abc1#Intro|the alpha]] and [Beta](def2.md).\n" +
+		"Not [[unknown]], ![image](ref/abc1.md), [asset](file.pdf), [web](https://example.com) nor `[[abc1]]`."
+
+	test(content, MigrateLinksOpts{Syntax: LinkSyntaxMarkdown},
+		"See [Alpha note](ref/abc1), [the alpha](ref/abc1#Intro) and [Beta](def2).\n"+
+			"Not [[unknown]], ![image](ref/abc1.md), [asset](file.pdf), [web](https://example.com) nor `[[abc1]]`.",
+		1,
+	)
+
+	test(content, MigrateLinksOpts{Syntax: LinkSyntaxWiki},
+		"See [[ref/abc1]], [[ref/abc1#Intro|the alpha]] and [[def2]].\n"+
+			"Not [[unknown]], ![image](ref/abc1.md), [asset](file.pdf), [web](https://example.com) nor `[[abc1]]`.",
+		1,
+	)
+
+	// The syntax of each link is kept when only the path style changes.
+	test(content, MigrateLinksOpts{PathStyle: LinkPathID},
+		"See [[abc1]], [[abc1#Intro|the alpha]] and [Beta](def2).\n"+
+			"Not [[unknown]], ![image](ref/abc1.md), [asset](file.pdf), [web](https://example.com) nor `[[abc1]]`.",
+		1,
+	)
+
+	// Labels different from the note title are kept in wiki-links.
+	test("[Custom label](ref/abc1.md \"title\")", MigrateLinksOpts{Syntax: LinkSyntaxWiki, PathStyle: LinkPathID},
+		"[[abc1|Custom label]]", 0,
+	)
+
+	// Paths are encoded in Markdown links.
+	notes["my note.md"] = MinimalNote{Path: "my note.md", Title: "Mine"}
+	test("[[my note]]", MigrateLinksOpts{Syntax: LinkSyntaxMarkdown},
+		"[Mine](my%20note)", 0,
+	)
+}
+
+func TestMigrateLinksOptsLinkFormat(t *testing.T) {
+	test := func(syntax LinkSyntax, style LinkPathStyle, expected string) {
+		assert.Equal(t, MigrateLinksOpts{Syntax: syntax, PathStyle: style}.linkFormat(), expected)
+	}
+
+	test("", LinkPathID, "")
+	test(LinkSyntaxWiki, "", "wiki")
+	test(LinkSyntaxWiki, LinkPathRoot, "wiki")
+	test(LinkSyntaxWiki, LinkPathRelative, "[[{{rel-path}}]]")
+	test(LinkSyntaxWiki, LinkPathID, "[[{{filename}}]]")
+	test(LinkSyntaxMarkdown, "", "markdown")
+	test(LinkSyntaxMarkdown, LinkPathRoot, "[{{title}}]({{path}})")
+	test(LinkSyntaxMarkdown, LinkPathID, "[{{title}}]({{filename}})")
+}
+
+func TestSetTOMLValue(t *testing.T) {
+	test := func(content string, expected string) {
+		assert.Equal(t, setTOMLValue(content, "format.markdown", "link-format", "wiki"), expected)
+	}
+
+	test("", "[format.markdown]\nlink-format = \"wiki\"\n")
+	test("[note]\nlanguage = \"fr\"\n", "[note]\nlanguage = \"fr\"\n\n[format.markdown]\nlink-format = \"wiki\"\n")
+	test("[format.markdown]\n# Comment\nlink-format = \"markdown\"\nhashtags = true\n",
+		"[format.markdown]\n# Comment\nlink-format = \"wiki\"\nhashtags = true\n")
+	test("[format.markdown]\nhashtags = true\n\n[tool]\neditor = \"vim\"\n",
+		"[format.markdown]\nhashtags = true\nlink-format = \"wiki\"\n\n[tool]\neditor = \"vim\"\n")
+	test("[format.markdown]\n\n[tool]\nlink-format = \"markdown\"\n",
+		"[format.markdown]\nlink-format = \"wiki\"\n\n[tool]\nlink-format = \"markdown\"\n")
+}
+
+func TestNotebookMigrateLinksResolvesTitles(t *testing.T) {
+	notebook, fs := newResolveTestNotebook(t, map[string]*NoteContent{
+		"a.md":     {Title: opt.NewString("Alpha")},
+		"dir/c.md": {Title: opt.NewString("Gamma note")},
+	})
+	fs.files[filepath.Join(notebook.Path, "a.md")] = "See [[Gamma note]].\n"
+	fs.files[filepath.Join(notebook.Path, "dir/c.md")] = "# Gamma note\n"
+
+	migration, err := notebook.MigrateLinks(MigrateLinksOpts{
+		Syntax: LinkSyntaxMarkdown,
+		DryRun: true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, migration.Notes, []NoteReplacement{{
+		Path: "a.md",
+		Changes: []TextChange{
+			{Line: 1, Old: "See [[Gamma note]].", New: "See [Gamma note](dir/c)."},
+		},
+	}})
+}
@@ -0,0 +1,350 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// LintRule identifies a kind of problem reported by Notebook.Lint.
+type LintRule string
+
+const (
+	// A link targets a note or file which doesn't exist.
+	LintRuleDeadLink LintRule = "dead-link"
+	// A link resolves only approximately to a note, e.g. after a rename.
+	LintRuleFuzzyLink LintRule = "fuzzy-link"
+	// Several notes share the same title.
+	LintRuleDuplicateTitle LintRule = "duplicate-title"
+	// Several notes share the same ID, i.e. the same filename stem.
+	LintRuleDuplicateID LintRule = "duplicate-id"
+	// A note doesn't have any title.
+	LintRuleMissingTitle LintRule = "missing-title"
+	// The YAML frontmatter of a note can't be parsed.
+	LintRuleMalformedFrontmatter LintRule = "malformed-frontmatter"
+	// A file which is not a note is not linked from any note.
+	LintRuleOrphanAsset LintRule = "orphan-asset"
+	// An external URL was unreachable when last fetched or checked.
+	LintRuleDeadURL LintRule = "dead-url"
+)
+
+// LintRules lists all the rules checked by Notebook.Lint.
+var LintRules = []LintRule{
+	LintRuleDeadLink,
+	LintRuleFuzzyLink,
+	LintRuleDuplicateTitle,
+	LintRuleDuplicateID,
+	LintRuleMissingTitle,
+	LintRuleMalformedFrontmatter,
+	LintRuleOrphanAsset,
+	LintRuleDeadURL,
+}
+
+// Severity returns how serious a problem reported for this rule is.
+func (r LintRule) Severity() LintSeverity {
+	switch r {
+	case LintRuleDeadLink, LintRuleDuplicateID, LintRuleMalformedFrontmatter:
+		return LintSeverityError
+	default:
+		return LintSeverityWarning
+	}
+}
+
+// Description returns a short description of the rule.
+func (r LintRule) Description() string {
+	switch r {
+	case LintRuleDeadLink:
+		return "Links must target an existing note or file"
+	case LintRuleFuzzyLink:
+		return "Links should match the path or title of their target exactly"
+	case LintRuleDuplicateTitle:
+		return "Note titles should be unique"
+	case LintRuleDuplicateID:
+		return "Note IDs must be unique"
+	case LintRuleMissingTitle:
+		return "Notes should have a title"
+	case LintRuleMalformedFrontmatter:
+		return "YAML frontmatters must be valid"
+	case LintRuleOrphanAsset:
+		return "Assets should be linked from a note"
+	case LintRuleDeadURL:
+		return "External links should be reachable"
+	default:
+		return string(r)
+	}
+}
+
+// LintSeverity indicates how serious a LintIssue is.
+type LintSeverity string
+
+const (
+	LintSeverityError   LintSeverity = "error"
+	LintSeverityWarning LintSeverity = "warning"
+)
+
+// LintIssue is a problem found in a notebook by Notebook.Lint.
+type LintIssue struct {
+	Rule     LintRule     `json:"rule"`
+	Severity LintSeverity `json:"severity"`
+	// Path relative to the notebook root of the file with the issue.
+	Path string `json:"path"`
+	// Line number of the issue in the file, starting at 1. 0 if the issue is
+	// about the whole file.
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// String implements Stringer.
+func (i LintIssue) String() string {
+	location := i.Path
+	if i.Line > 0 {
+		location += fmt.Sprintf(":%d", i.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", location, i.Severity, i.Message, i.Rule)
+}
+
+// lintNote holds the content of a note checked by Notebook.Lint.
+type lintNote struct {
+	path    string
+	title   string
+	content string
+	links   []Link
+}
+
+// Lint checks the whole notebook for problems, such as dead links or
+// duplicate titles.
+func (n *Notebook) Lint() ([]LintIssue, error) {
+	wrap := errors.Wrapper("lint failed")
+
+	issues := []LintIssue{}
+
+	assets := map[string]bool{}
+	foundAssets, err := n.FindAssets()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	for _, asset := range foundAssets {
+		assets[asset.Path] = true
+		if len(asset.LinkedBy) == 0 {
+			issues = append(issues, newLintIssue(LintRuleOrphanAsset, asset.Path, 0, "not linked from any note"))
+		}
+	}
+
+	notes, malformed := n.readLintNotes()
+	issues = append(issues, malformed...)
+
+	// The external URLs are not fetched while linting, only the cached
+	// metadata are checked. The hosts excluded by the configuration are
+	// never reported.
+	deadURLs := map[string]URLMetadata{}
+	foundDeadURLs, err := n.index.FindDeadURLs()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	for _, metadata := range foundDeadURLs {
+		if n.Config.Lint.URLs.ChecksURL(metadata.URL) {
+			deadURLs[metadata.URL] = metadata
+		}
+	}
+
+	// Candidates for the fuzzy matching of links, loaded lazily.
+	var fuzzyCandidates []MinimalNote
+
+	for _, note := range notes {
+		for _, link := range note.links {
+			line := 0
+			if link.SnippetStart <= len(note.content) {
+				line = strings.Count(note.content[:link.SnippetStart], "\n") + 1
+			}
+
+			if metadata, ok := deadURLs[link.Href]; ok {
+				msg := fmt.Sprintf("dead URL %s (%s)", link.Href, metadata.StatusText())
+				if metadata.Archive != "" {
+					msg += ", archived at " + metadata.Archive
+				}
+				issues = append(issues, newLintIssue(LintRuleDeadURL, note.path, line, msg))
+				continue
+			}
+
+			dead, err := n.isDeadLink(note.path, link.Href, assets)
+			if err != nil {
+				return nil, wrap(err)
+			}
+			if !dead {
+				continue
+			}
+
+			if threshold := n.Config.Search.FuzzyLinkThreshold; threshold > 0 {
+				if fuzzyCandidates == nil {
+					fuzzyCandidates, err = n.FindMinimalNotes(NoteFindOpts{})
+					if err != nil {
+						return nil, wrap(err)
+					}
+				}
+				found, confidence := fuzzyMatchHref(link.Href, fuzzyCandidates, threshold)
+				if found != nil && confidence >= 1 {
+					// An exact path or title match which the index could
+					// not look up, e.g. with punctuation in the title.
+					continue
+				}
+				if found != nil {
+					msg := fmt.Sprintf("link to %s resolved approximately to %s (%d%%)", link.Href, found.Path, int(confidence*100))
+					issues = append(issues, newLintIssue(LintRuleFuzzyLink, note.path, line, msg))
+					continue
+				}
+			}
+
+			issues = append(issues, newLintIssue(LintRuleDeadLink, note.path, line, "dead link to "+link.Href))
+		}
+	}
+
+	issues = append(issues, lintNotes(notes)...)
+
+	sortLintIssues(issues)
+	return issues, nil
+}
+
+// readLintNotes reads and parses all the notes of the notebook. The notes
+// which can't be parsed are reported as issues.
+func (n *Notebook) readLintNotes() ([]lintNote, []LintIssue) {
+	notes := []lintNote{}
+	issues := []LintIssue{}
+
+	files := paths.Walk(n.Path, n.logger, func(path string) (bool, error) {
+		return false, nil
+	})
+	for file := range files {
+		if n.isAssetPath(file.Path) {
+			continue
+		}
+		isNote, err := isNotePath(n.Config, file.Path)
+		if err != nil {
+			n.logger.Err(err)
+			continue
+		}
+		if !isNote {
+			// Ignored note.
+			continue
+		}
+
+		content, err := n.fs.Read(filepath.Join(n.Path, file.Path))
+		if err != nil {
+			n.logger.Err(err)
+			continue
+		}
+		parsed, err := n.parser.ParseNoteContent(string(content))
+		if err != nil {
+			issues = append(issues, newLintIssue(LintRuleMalformedFrontmatter, file.Path, 0, err.Error()))
+			continue
+		}
+		notes = append(notes, lintNote{
+			path:    file.Path,
+			title:   parsed.Title.String(),
+			content: string(content),
+			links:   parsed.Links,
+		})
+	}
+
+	return notes, issues
+}
+
+// isDeadLink returns whether the internal link with the given href, found in
+// the note at notePath, targets a note or asset which doesn't exist. The
+// external links and the links to other notebooks are never dead.
+func (n *Notebook) isDeadLink(notePath string, href string, assets map[string]bool) (bool, error) {
+	target, ok := lintLinkTarget(notePath, href)
+	if !ok {
+		return false, nil
+	}
+	if _, _, federated := n.FederatedHref(href); federated {
+		// The notes of the other notebooks are not checked.
+		return false, nil
+	}
+	if assets[target] {
+		return false, nil
+	}
+
+	// The parsed links don't tell whether they are wiki-links, so they are
+	// resolved leniently: a link followed by the editor is never dead.
+	found, _, err := n.ResolveLink(notePath, href, ResolveLinkOpts{IsWikiLink: true})
+	return found == nil && err == nil, err
+}
+
+// sortLintIssues orders the issues by path and line.
+func sortLintIssues(issues []LintIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Path != issues[j].Path {
+			return issues[i].Path < issues[j].Path
+		}
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Rule < issues[j].Rule
+	})
+}
+
+// lintNotes checks the titles and IDs of the given notes.
+func lintNotes(notes []lintNote) []LintIssue {
+	issues := []LintIssue{}
+
+	titles := map[string][]string{}
+	ids := map[string][]string{}
+	for _, note := range notes {
+		if note.title == "" {
+			issues = append(issues, newLintIssue(LintRuleMissingTitle, note.path, 0, "missing title"))
+		} else {
+			titles[note.title] = append(titles[note.title], note.path)
+		}
+		id := paths.FilenameStem(note.path)
+		ids[id] = append(ids[id], note.path)
+	}
+
+	reportDuplicates := func(rule LintRule, kind string, values map[string][]string) {
+		for value, notePaths := range values {
+			if len(notePaths) < 2 {
+				continue
+			}
+			for _, path := range notePaths {
+				others := []string{}
+				for _, other := range notePaths {
+					if other != path {
+						others = append(others, other)
+					}
+				}
+				msg := fmt.Sprintf("%s `%s` shared with %s", kind, value, strings.Join(others, ", "))
+				issues = append(issues, newLintIssue(rule, path, 0, msg))
+			}
+		}
+	}
+	reportDuplicates(LintRuleDuplicateTitle, "title", titles)
+	reportDuplicates(LintRuleDuplicateID, "ID", ids)
+
+	return issues
+}
+
+// lintLinkTarget returns the path relative to the notebook root targeted by
+// an internal link. Returns false for external links and anchors.
+func lintLinkTarget(notePath string, href string) (string, bool) {
+	if strutil.IsURL(href) {
+		return "", false
+	}
+	if strings.SplitN(href, "#", 2)[0] == "" {
+		return "", false
+	}
+	return assetHrefPath(filepath.Join(filepath.Dir(notePath), href)), true
+}
+
+func newLintIssue(rule LintRule, path string, line int, message string) LintIssue {
+	return LintIssue{
+		Rule:     rule,
+		Severity: rule.Severity(),
+		Path:     path,
+		Line:     line,
+		Message:  message,
+	}
+}
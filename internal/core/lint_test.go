@@ -0,0 +1,87 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestLintNotes(t *testing.T) {
+	issues := lintNotes([]lintNote{
+		{path: "a.md", title: "Title"},
+		{path: "b.md", title: "Other"},
+		{path: "dir/a.md", title: "Title"},
+		{path: "c.md", title: ""},
+	})
+
+	sortLintIssues(issues)
+	assert.Equal(t, issues, []LintIssue{
+		{Rule: LintRuleDuplicateID, Severity: LintSeverityError, Path: "a.md", Message: "ID `a` shared with dir/a.md"},
+		{Rule: LintRuleDuplicateTitle, Severity: LintSeverityWarning, Path: "a.md", Message: "title `Title` shared with dir/a.md"},
+		{Rule: LintRuleMissingTitle, Severity: LintSeverityWarning, Path: "c.md", Message: "missing title"},
+		{Rule: LintRuleDuplicateID, Severity: LintSeverityError, Path: "dir/a.md", Message: "ID `a` shared with a.md"},
+		{Rule: LintRuleDuplicateTitle, Severity: LintSeverityWarning, Path: "dir/a.md", Message: "title `Title` shared with a.md"},
+	})
+}
+
+func TestLintLinkTarget(t *testing.T) {
+	test := func(notePath, href, expected string, expectedOK bool) {
+		actual, ok := lintLinkTarget(notePath, href)
+		assert.Equal(t, ok, expectedOK)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("note.md", "https://github.com", "", false)
+	test("note.md", "#section", "", false)
+	test("note.md", "other", "other", true)
+	test("note.md", "other.md#section", "other.md", true)
+	test("dir/note.md", "../image%20one.png", "image one.png", true)
+	test("dir/note.md", "sub/other", "dir/sub/other", true)
+}
+
+func TestLintIssueString(t *testing.T) {
+	assert.Equal(t,
+		LintIssue{Rule: LintRuleDeadLink, Severity: LintSeverityError, Path: "a.md", Line: 4, Message: "dead link to b"}.String(),
+		"a.md:4: error: dead link to b (dead-link)",
+	)
+	assert.Equal(t,
+		LintIssue{Rule: LintRuleOrphanAsset, Severity: LintSeverityWarning, Path: "img.png", Message: "not linked from any note"}.String(),
+		"img.png: warning: not linked from any note (orphan-asset)",
+	)
+}
+
+func TestLintResolvesWikiLinksToTitles(t *testing.T) {
+	notebook, _ := newResolveTestNotebook(t, map[string]*NoteContent{
+		"a.md": {Title: opt.NewString("Alpha note")},
+		"gamma.md": {
+			Title: opt.NewString("Gamma"),
+			Links: []Link{{Href: "Alpha note"}, {Href: "Missing"}},
+		},
+	})
+
+	issues, err := notebook.Lint()
+	assert.Nil(t, err)
+	assert.Equal(t, issues, []LintIssue{
+		{Rule: LintRuleDeadLink, Severity: LintSeverityError, Path: "gamma.md", Line: 1, Message: "dead link to Missing"},
+	})
+}
+
+func TestLintReportsOnlyApproximateFuzzyLinks(t *testing.T) {
+	notebook, _ := newResolveTestNotebook(t, map[string]*NoteContent{
+		"a.md":         {Title: opt.NewString("Alpha note")},
+		"cpp-notes.md": {Title: opt.NewString("C++")},
+		"gamma.md": {
+			Title: opt.NewString("Gamma"),
+			Links: []Link{{Href: "C++"}, {Href: "Alpha notes"}},
+		},
+	})
+	notebook.Config.Search.FuzzyLinkThreshold = 0.7
+
+	issues, err := notebook.Lint()
+	assert.Nil(t, err)
+	assert.Equal(t, len(issues), 1)
+	assert.Equal(t, issues[0].Rule, LintRuleFuzzyLink)
+	assert.True(t, strings.HasPrefix(issues[0].Message, "link to Alpha notes resolved approximately to a.md"))
+}
@@ -0,0 +1,250 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// MaintenanceTask is a housekeeping task performed periodically on a
+// notebook.
+type MaintenanceTask string
+
+const (
+	// Indexes the modified notes and prunes the removed ones from the index.
+	MaintenanceTaskIndex MaintenanceTask = "index"
+	// Purges the notes kept in the trash for longer than the retention
+	// period.
+	MaintenanceTaskTrash MaintenanceTask = "trash"
+	// Refreshes the cached metadata of the external URLs.
+	MaintenanceTaskURLs MaintenanceTask = "urls"
+	// Submits the reachable external URLs to a web archive.
+	MaintenanceTaskArchive MaintenanceTask = "archive"
+	// Verifies the checksums of the notes against the notebook manifest.
+	MaintenanceTaskManifest MaintenanceTask = "manifest"
+	// Reclaims the unused space of the index database.
+	MaintenanceTaskVacuum MaintenanceTask = "vacuum"
+)
+
+// MaintenanceTasks lists the available maintenance tasks, in the order they
+// are performed.
+var MaintenanceTasks = []MaintenanceTask{
+	MaintenanceTaskIndex,
+	MaintenanceTaskTrash,
+	MaintenanceTaskURLs,
+	MaintenanceTaskArchive,
+	MaintenanceTaskManifest,
+	MaintenanceTaskVacuum,
+}
+
+// MaintenanceTaskFromString returns the maintenance task matching the given
+// name.
+func MaintenanceTaskFromString(name string) (MaintenanceTask, error) {
+	for _, task := range MaintenanceTasks {
+		if string(task) == name {
+			return task, nil
+		}
+	}
+	return "", fmt.Errorf("%s: unknown maintenance task", name)
+}
+
+// MaintenanceStatus is the outcome of a maintenance task.
+type MaintenanceStatus string
+
+const (
+	MaintenanceStatusOK      MaintenanceStatus = "ok"
+	MaintenanceStatusSkipped MaintenanceStatus = "skipped"
+	MaintenanceStatusFailed  MaintenanceStatus = "failed"
+)
+
+// MaintenanceOpts holds the options used to perform the maintenance of a
+// Notebook.
+type MaintenanceOpts struct {
+	// Tasks to perform, in any order.
+	Tasks []MaintenanceTask
+	// Only reports what would be changed, without modifying the notebook.
+	DryRun bool
+	// Current date, used to find the notes to purge from the trash.
+	Now time.Time
+	// Fetcher used to refresh the metadata of the external URLs.
+	FetchURL URLMetadataFetcher
+	// Web archive used to snapshot the external URLs, unless an archive
+	// command is configured.
+	ArchiveURL URLArchiver
+	// Command running the maintenance, passed on to each task.
+	Origin AuditOrigin
+}
+
+// MaintenanceReport holds the outcome of each maintenance task.
+type MaintenanceReport struct {
+	Tasks []MaintenanceTaskReport `json:"tasks"`
+}
+
+// Failed returns whether at least one of the tasks failed.
+func (r MaintenanceReport) Failed() bool {
+	for _, task := range r.Tasks {
+		if task.Status == MaintenanceStatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// MaintenanceTaskReport holds the outcome of a single maintenance task.
+type MaintenanceTaskReport struct {
+	Task   MaintenanceTask   `json:"task"`
+	Status MaintenanceStatus `json:"status"`
+	// Human readable summary of the outcome.
+	Message string `json:"message"`
+	// Statistics of the index task.
+	Indexing *NoteIndexingStats `json:"indexing,omitempty"`
+	// Paths of the notes purged from the trash.
+	Purged []string `json:"purged,omitempty"`
+	// Number of external URLs refreshed.
+	FetchedURLs int `json:"fetchedUrls,omitempty"`
+	// Number of external URLs submitted to the web archive.
+	ArchivedURLs int `json:"archivedUrls,omitempty"`
+	// Notes which don't match the manifest.
+	ManifestDiffs []ManifestDiff `json:"manifestDiffs,omitempty"`
+	// Duration of the task.
+	Duration time.Duration `json:"duration"`
+}
+
+func (r MaintenanceTaskReport) String() string {
+	return fmt.Sprintf("%s: %s", r.Task, r.Message)
+}
+
+// Maintain performs the given housekeeping tasks on the notebook.
+//
+// A failing task doesn't prevent the next ones from running, the failures
+// are reported in the returned MaintenanceReport.
+func (n *Notebook) Maintain(opts MaintenanceOpts) MaintenanceReport {
+	report := MaintenanceReport{Tasks: []MaintenanceTaskReport{}}
+
+	for _, task := range MaintenanceTasks {
+		if !containsMaintenanceTask(opts.Tasks, task) {
+			continue
+		}
+
+		taskReport := MaintenanceTaskReport{Task: task}
+		startTime := time.Now()
+		err := n.maintain(task, opts, &taskReport)
+		taskReport.Duration = time.Since(startTime)
+		if err != nil {
+			taskReport.Status = MaintenanceStatusFailed
+			taskReport.Message = err.Error()
+		} else if taskReport.Status == "" {
+			taskReport.Status = MaintenanceStatusOK
+		}
+
+		report.Tasks = append(report.Tasks, taskReport)
+	}
+
+	return report
+}
+
+func (n *Notebook) maintain(task MaintenanceTask, opts MaintenanceOpts, report *MaintenanceTaskReport) error {
+	skip := func(reason string) error {
+		report.Status = MaintenanceStatusSkipped
+		report.Message = reason
+		return nil
+	}
+
+	switch task {
+	case MaintenanceTaskIndex:
+		if opts.DryRun {
+			return skip("dry run")
+		}
+		stats, err := n.Index(opts.Origin, false)
+		if err != nil {
+			return err
+		}
+		report.Indexing = &stats
+		report.Message = fmt.Sprintf("%d added, %d modified, %d removed",
+			stats.AddedCount, stats.ModifiedCount, stats.RemovedCount,
+		)
+
+	case MaintenanceTaskTrash:
+		purged, err := n.PurgeTrash(PurgeTrashOpts{DryRun: opts.DryRun, Now: opts.Now, Origin: opts.Origin})
+		report.Purged = purged
+		if err != nil {
+			return err
+		}
+		verb := "purged"
+		if opts.DryRun {
+			verb = "would purge"
+		}
+		report.Message = fmt.Sprintf("%s %d %s", verb, len(purged), strutil.Pluralize("note", len(purged)))
+
+	case MaintenanceTaskURLs:
+		if opts.DryRun {
+			return skip("dry run")
+		}
+		if opts.FetchURL == nil {
+			return skip("no URL fetcher")
+		}
+		count, err := n.FetchURLMetadata(opts.FetchURL, -1)
+		if err != nil {
+			return err
+		}
+		report.FetchedURLs = count
+		report.Message = fmt.Sprintf("refreshed %d %s", count, strutil.Pluralize("URL", count))
+
+	case MaintenanceTaskArchive:
+		if opts.DryRun {
+			return skip("dry run")
+		}
+		if opts.ArchiveURL == nil && n.Config.Archive.Command == "" {
+			return skip("no web archive")
+		}
+		count, err := n.ArchiveURLs(opts.ArchiveURL, -1)
+		report.ArchivedURLs = count
+		if err != nil {
+			return err
+		}
+		report.Message = fmt.Sprintf("archived %d %s", count, strutil.Pluralize("URL", count))
+
+	case MaintenanceTaskManifest:
+		path := filepath.Join(n.Path, DefaultManifestPath)
+		exists, err := n.fs.FileExists(path)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return skip("no manifest at " + DefaultManifestPath)
+		}
+		diffs, err := n.VerifyManifest(path)
+		if err != nil {
+			return err
+		}
+		report.ManifestDiffs = diffs
+		if len(diffs) > 0 {
+			return fmt.Errorf("%d %s changed since the manifest was written", len(diffs), strutil.Pluralize("note", len(diffs)))
+		}
+		report.Message = "all the notes match the manifest"
+
+	case MaintenanceTaskVacuum:
+		if opts.DryRun {
+			return skip("dry run")
+		}
+		err := n.index.Vacuum()
+		if err != nil {
+			return errors.Wrap(err, "failed to vacuum the index")
+		}
+		report.Message = "reclaimed the unused space of the index"
+	}
+
+	return nil
+}
+
+func containsMaintenanceTask(tasks []MaintenanceTask, task MaintenanceTask) bool {
+	for _, t := range tasks {
+		if t == task {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+// maintenanceIndexMock is a NoteIndex finding the notes in the trash, whose
+// vacuum fails with the given error.
+type maintenanceIndexMock struct {
+	*resolveIndexMock
+	trashed   []MinimalNote
+	vacuumErr error
+	vacuumed  bool
+}
+
+func (m *maintenanceIndexMock) FindMinimal(opts NoteFindOpts) ([]MinimalNote, error) {
+	if opts.Trash == TrashFilterOnly {
+		return m.trashed, nil
+	}
+	return m.resolveIndexMock.FindMinimal(opts)
+}
+
+func (m *maintenanceIndexMock) Vacuum() error {
+	m.vacuumed = true
+	return m.vacuumErr
+}
+
+func newMaintenanceTestNotebook(t *testing.T) (*Notebook, *maintenanceIndexMock) {
+	notebook, _ := newResolveTestNotebook(t, map[string]*NoteContent{
+		"a.md":   {Title: opt.NewString("Alpha")},
+		"old.md": {Title: opt.NewString("Old")},
+	})
+	index := &maintenanceIndexMock{
+		resolveIndexMock: notebook.index.(*resolveIndexMock),
+		trashed:          []MinimalNote{{Path: "old.md"}},
+	}
+	notebook.index = index
+	return notebook, index
+}
+
+func TestMaintenanceTaskFromString(t *testing.T) {
+	for _, task := range MaintenanceTasks {
+		actual, err := MaintenanceTaskFromString(string(task))
+		assert.Nil(t, err)
+		assert.Equal(t, actual, task)
+	}
+
+	_, err := MaintenanceTaskFromString("moc")
+	assert.Err(t, err, "moc: unknown maintenance task")
+}
+
+func TestMaintenanceReportFailed(t *testing.T) {
+	assert.False(t, MaintenanceReport{}.Failed())
+	assert.False(t, MaintenanceReport{Tasks: []MaintenanceTaskReport{
+		{Task: MaintenanceTaskIndex, Status: MaintenanceStatusOK},
+		{Task: MaintenanceTaskURLs, Status: MaintenanceStatusSkipped},
+	}}.Failed())
+	assert.True(t, MaintenanceReport{Tasks: []MaintenanceTaskReport{
+		{Task: MaintenanceTaskIndex, Status: MaintenanceStatusOK},
+		{Task: MaintenanceTaskVacuum, Status: MaintenanceStatusFailed},
+	}}.Failed())
+}
+
+func TestNotebookMaintainDryRun(t *testing.T) {
+	notebook, index := newMaintenanceTestNotebook(t)
+
+	report := notebook.Maintain(MaintenanceOpts{
+		Tasks:  MaintenanceTasks,
+		DryRun: true,
+		Now:    time.Now(),
+	})
+
+	statuses := map[MaintenanceTask]MaintenanceStatus{}
+	tasks := []MaintenanceTask{}
+	for _, task := range report.Tasks {
+		tasks = append(tasks, task.Task)
+		statuses[task.Task] = task.Status
+	}
+	assert.Equal(t, tasks, MaintenanceTasks)
+	assert.Equal(t, statuses, map[MaintenanceTask]MaintenanceStatus{
+		MaintenanceTaskIndex:    MaintenanceStatusSkipped,
+		MaintenanceTaskTrash:    MaintenanceStatusOK,
+		MaintenanceTaskURLs:     MaintenanceStatusSkipped,
+		MaintenanceTaskArchive:  MaintenanceStatusSkipped,
+		MaintenanceTaskManifest: MaintenanceStatusSkipped,
+		MaintenanceTaskVacuum:   MaintenanceStatusSkipped,
+	})
+	assert.False(t, report.Failed())
+
+	assert.Equal(t, report.Tasks[1].Message, "would purge 1 note")
+	assert.Equal(t, report.Tasks[1].Purged, []string{"old.md"})
+	assert.Equal(t, report.Tasks[4].Message, "no manifest at "+DefaultManifestPath)
+	assert.False(t, index.vacuumed)
+
+	// The dry run doesn't purge the trash.
+	exists, err := notebook.fs.FileExists(filepath.Join(notebook.Path, "old.md"))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+}
+
+func TestNotebookMaintainReportsFailures(t *testing.T) {
+	notebook, index := newMaintenanceTestNotebook(t)
+	index.vacuumErr = errors.New("database is locked")
+	notebook.Config.Trash.RetentionDays = 0
+
+	// The tasks are performed in their own order, and a failure doesn't
+	// prevent the next tasks from running.
+	report := notebook.Maintain(MaintenanceOpts{
+		Tasks: []MaintenanceTask{MaintenanceTaskTrash, MaintenanceTaskVacuum, MaintenanceTaskURLs},
+		Now:   time.Now(),
+	})
+
+	assert.True(t, index.vacuumed)
+	assert.True(t, report.Failed())
+	assert.Equal(t, len(report.Tasks), 3)
+	assert.Equal(t, report.Tasks[0].String(), "trash: purged 0 note")
+	assert.Equal(t, report.Tasks[1].String(), "urls: no URL fetcher")
+	assert.Equal(t, report.Tasks[1].Status, MaintenanceStatusSkipped)
+	assert.Equal(t, report.Tasks[2].String(), "vacuum: failed to vacuum the index: database is locked")
+	assert.Equal(t, report.Tasks[2].Status, MaintenanceStatusFailed)
+}
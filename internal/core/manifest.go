@@ -0,0 +1,168 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// Manifest maps the path of each note, relative to the notebook root, to the
+// SHA-256 checksum of the file stored on disk.
+//
+// It is serialized in the format of the sha256sum tool, to be checked
+// without zk as well.
+type Manifest map[string]string
+
+// ManifestDiffKind is the kind of difference between a manifest and the
+// notes on disk.
+type ManifestDiffKind string
+
+const (
+	// The note is not listed in the manifest.
+	ManifestDiffAdded ManifestDiffKind = "added"
+	// The content of the note doesn't match its checksum.
+	ManifestDiffModified ManifestDiffKind = "modified"
+	// The note listed in the manifest doesn't exist anymore.
+	ManifestDiffMissing ManifestDiffKind = "missing"
+)
+
+// ManifestDiff is a note which doesn't match its manifest entry.
+type ManifestDiff struct {
+	Path string           `json:"path"`
+	Kind ManifestDiffKind `json:"kind"`
+}
+
+func (d ManifestDiff) String() string {
+	return fmt.Sprintf("%s: %s", d.Path, d.Kind)
+}
+
+// DefaultManifestPath is the location of the manifest, relative to the
+// notebook root.
+const DefaultManifestPath = ".zk/manifest.sha256"
+
+// Manifest computes the checksums of all the notes in the notebook.
+//
+// The checksums are computed from the files as stored, e.g. encrypted.
+func (n *Notebook) Manifest() (Manifest, error) {
+	manifest := Manifest{}
+
+	files := paths.Walk(n.Path, n.logger, func(path string) (bool, error) {
+		isNote, err := isNotePath(n.Config, path)
+		return !isNote, err
+	})
+	for file := range files {
+		content, err := n.readStored(filepath.Join(n.Path, file.Path))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to compute the notebook manifest")
+		}
+		manifest[filepath.ToSlash(file.Path)] = fmt.Sprintf("%x", sha256.Sum256(content))
+	}
+
+	return manifest, nil
+}
+
+// WriteManifest saves the checksums of all the notes at the given path.
+func (n *Notebook) WriteManifest(path string) (Manifest, error) {
+	wrap := errors.Wrapperf("%s: failed to write the manifest", path)
+
+	manifest, err := n.Manifest()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	err = n.fs.Write(path, manifest.Bytes())
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	return manifest, nil
+}
+
+// VerifyManifest compares the manifest saved at the given path with the
+// notes on disk, and returns the notes which were added, modified or removed
+// since it was written.
+func (n *Notebook) VerifyManifest(path string) ([]ManifestDiff, error) {
+	wrap := errors.Wrapperf("%s: failed to verify the manifest", path)
+
+	content, err := n.fs.Read(path)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	expected, err := ParseManifest(content)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	actual, err := n.Manifest()
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	return expected.Diff(actual), nil
+}
+
+// ParseManifest reads a manifest in the sha256sum format.
+func ParseManifest(content []byte) (Manifest, error) {
+	manifest := Manifest{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for i := 1; scanner.Scan(); i++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		// The binary mode marker `*` is used by sha256sum -b.
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 || len(parts[0]) != 64 || len(parts[1]) < 2 {
+			return nil, fmt.Errorf("line %d: invalid checksum entry", i)
+		}
+		manifest[parts[1][1:]] = strings.ToLower(parts[0])
+	}
+
+	return manifest, scanner.Err()
+}
+
+// Bytes serializes the manifest in the sha256sum format, sorted by path.
+func (m Manifest) Bytes() []byte {
+	notePaths := make([]string, 0, len(m))
+	for path := range m {
+		notePaths = append(notePaths, path)
+	}
+	sort.Strings(notePaths)
+
+	var out bytes.Buffer
+	for _, path := range notePaths {
+		fmt.Fprintf(&out, "%s  %s\n", m[path], path)
+	}
+	return out.Bytes()
+}
+
+// Diff returns the differences between this manifest and the actual one,
+// sorted by path.
+func (m Manifest) Diff(actual Manifest) []ManifestDiff {
+	diffs := []ManifestDiff{}
+
+	for path, checksum := range m {
+		actualChecksum, ok := actual[path]
+		if !ok {
+			diffs = append(diffs, ManifestDiff{Path: path, Kind: ManifestDiffMissing})
+		} else if actualChecksum != checksum {
+			diffs = append(diffs, ManifestDiff{Path: path, Kind: ManifestDiffModified})
+		}
+	}
+	for path := range actual {
+		if _, ok := m[path]; !ok {
+			diffs = append(diffs, ManifestDiff{Path: path, Kind: ManifestDiffAdded})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs
+}
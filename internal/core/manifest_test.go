@@ -0,0 +1,59 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+var (
+	checksumA = strings.Repeat("a", 64)
+	checksumB = strings.Repeat("b", 64)
+	checksumC = strings.Repeat("c", 64)
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	manifest := Manifest{
+		"dir/note with spaces.md": checksumB,
+		"a.md":                    checksumA,
+	}
+
+	content := manifest.Bytes()
+	assert.Equal(t, string(content), checksumA+"  a.md\n"+checksumB+"  dir/note with spaces.md\n")
+
+	parsed, err := ParseManifest(content)
+	assert.Nil(t, err)
+	assert.Equal(t, parsed, manifest)
+}
+
+func TestParseManifestBinaryMode(t *testing.T) {
+	parsed, err := ParseManifest([]byte(strings.ToUpper(checksumA) + " *a.md\n\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, parsed, Manifest{"a.md": checksumA})
+}
+
+func TestParseManifestInvalid(t *testing.T) {
+	_, err := ParseManifest([]byte(checksumA + "  a.md\nnot a checksum\n"))
+	assert.Err(t, err, "line 2: invalid checksum entry")
+}
+
+func TestManifestDiff(t *testing.T) {
+	expected := Manifest{
+		"unchanged.md": checksumA,
+		"modified.md":  checksumA,
+		"missing.md":   checksumA,
+	}
+	actual := Manifest{
+		"unchanged.md": checksumA,
+		"modified.md":  checksumB,
+		"added.md":     checksumC,
+	}
+
+	assert.Equal(t, expected.Diff(actual), []ManifestDiff{
+		{Path: "added.md", Kind: ManifestDiffAdded},
+		{Path: "missing.md", Kind: ManifestDiffMissing},
+		{Path: "modified.md", Kind: ManifestDiffModified},
+	})
+	assert.Equal(t, expected.Diff(expected), []ManifestDiff{})
+}
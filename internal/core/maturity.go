@@ -0,0 +1,43 @@
+package core
+
+import (
+	"math"
+	"time"
+)
+
+// Half-saturation values of the maturity factors: a factor contributes half
+// of its weight to the maturity of a note when it reaches this value, and
+// the additional units matter less and less afterwards.
+const (
+	maturityHalfAge          = 30 * 24 * time.Hour
+	maturityHalfEdits        = 5
+	maturityHalfInboundLinks = 3
+	maturityHalfWords        = 250
+)
+
+// NoteMaturity estimates how developed a note is, between 0 for a note just
+// drafted and 100 for a well-established one, from:
+//   - its age,
+//   - the number of times its content changed since it was indexed,
+//   - the number of other notes linking to it,
+//   - its number of words.
+//
+// Each factor weighs a quarter of the score. A note which is old but still
+// short, rarely edited and seldom linked is likely to be half-baked.
+func NoteMaturity(age time.Duration, editCount int, inboundLinks int, wordCount int) float64 {
+	score := saturate(age.Hours(), maturityHalfAge.Hours()) +
+		saturate(float64(editCount), maturityHalfEdits) +
+		saturate(float64(inboundLinks), maturityHalfInboundLinks) +
+		saturate(float64(wordCount), maturityHalfWords)
+
+	return math.Round(score/4*1000) / 10
+}
+
+// saturate maps a positive value to [0, 1[, reaching 0.5 for the given half
+// value.
+func saturate(value float64, half float64) float64 {
+	if value <= 0 {
+		return 0
+	}
+	return value / (value + half)
+}
@@ -0,0 +1,21 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestNoteMaturity(t *testing.T) {
+	day := 24 * time.Hour
+
+	assert.Equal(t, NoteMaturity(0, 0, 0, 0), 0.0)
+	assert.Equal(t, NoteMaturity(-day, -1, 0, 0), 0.0)
+	assert.Equal(t, NoteMaturity(30*day, 5, 3, 250), 50.0)
+	assert.Equal(t, NoteMaturity(30*day, 0, 0, 0), 12.5)
+	assert.Equal(t, NoteMaturity(0, 0, 0, 250), 12.5)
+	assert.Equal(t, NoteMaturity(270*day, 45, 27, 2250), 90.0)
+	// Old notes left untouched are not mature.
+	assert.Equal(t, NoteMaturity(3650*day, 0, 0, 20), 26.6)
+}
@@ -0,0 +1,338 @@
+package core
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// SuggestMOCsOpts holds the options used to find the clusters of notes
+// which would benefit from a map of content.
+type SuggestMOCsOpts struct {
+	// Minimum number of notes in a cluster, by default 5.
+	MinSize int
+}
+
+// MOCSuggestion is a cluster of densely linked notes which is not organized
+// by a hub note yet, e.g. a map of content (MOC) linking to all of them.
+type MOCSuggestion struct {
+	// Notes of the cluster, from the most connected one.
+	Notes []MOCMember `json:"notes"`
+	// Ratio of the existing links between the notes of the cluster, among
+	// all the possible ones.
+	Density float64 `json:"density"`
+	// Proposed titles for the map of content, from the most relevant one.
+	Titles []string `json:"titles"`
+}
+
+// MOCMember is a note of a MOCSuggestion.
+type MOCMember struct {
+	Path  string `json:"path"`
+	Title string `json:"title"`
+	// Number of other notes of the cluster linked to or from this note.
+	Links int `json:"links"`
+}
+
+const (
+	// mocDefaultMinSize is the default minimum number of notes in a
+	// suggested cluster.
+	mocDefaultMinSize = 5
+	// mocHubRatio is the ratio of the other notes of a cluster a note must
+	// be linked with to be considered as the hub of the cluster.
+	mocHubRatio = 0.5
+	// mocHubFactor is how many times more links than the average of the
+	// other notes a hub has.
+	mocHubFactor = 2
+	// mocMaxIterations bounds the label propagation, which usually converges
+	// in a few iterations.
+	mocMaxIterations = 20
+	// mocMaxTitles is the maximum number of proposed titles per cluster.
+	mocMaxTitles = 3
+)
+
+// SuggestMOCs analyzes the link graph of the notebook to find the dense
+// clusters of notes lacking a hub note, from the largest one.
+//
+// The links are considered in both directions and the trashed notes are
+// ignored.
+func (n *Notebook) SuggestMOCs(opts SuggestMOCsOpts) ([]MOCSuggestion, error) {
+	wrap := errors.Wrapper("failed to suggest maps of content")
+
+	notes, err := n.FindNotes(NoteFindOpts{})
+	if err != nil {
+		return nil, wrap(err)
+	}
+	links, err := n.index.FindNoteLinks()
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	members := []Note{}
+	for _, note := range notes {
+		members = append(members, note.Note)
+	}
+	return suggestMOCs(members, links, opts), nil
+}
+
+// suggestMOCs finds the clusters of the link graph between the given notes
+// with label propagation, and keeps the ones without a hub.
+func suggestMOCs(notes []Note, links []NoteLink, opts SuggestMOCsOpts) []MOCSuggestion {
+	if opts.MinSize <= 0 {
+		opts.MinSize = mocDefaultMinSize
+	}
+
+	graph := newNoteGraph(notes, links)
+	suggestions := []MOCSuggestion{}
+	for _, cluster := range graph.clusters() {
+		if len(cluster) < opts.MinSize || graph.hasHub(cluster) {
+			continue
+		}
+		suggestions = append(suggestions, graph.suggestion(cluster))
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if len(a.Notes) != len(b.Notes) {
+			return len(a.Notes) > len(b.Notes)
+		}
+		if a.Density != b.Density {
+			return a.Density > b.Density
+		}
+		return a.Notes[0].Path < b.Notes[0].Path
+	})
+	return suggestions
+}
+
+// noteGraph is the undirected graph of the links between notes. The nodes
+// are sorted by path, to find the same clusters on each run.
+type noteGraph struct {
+	notes     []Note
+	neighbors []map[int]bool
+}
+
+func newNoteGraph(notes []Note, links []NoteLink) *noteGraph {
+	notes = append([]Note{}, notes...)
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].Path < notes[j].Path
+	})
+
+	graph := &noteGraph{notes: notes}
+	nodes := map[string]int{}
+	for i, note := range notes {
+		nodes[note.Path] = i
+		graph.neighbors = append(graph.neighbors, map[int]bool{})
+	}
+	for _, link := range links {
+		source, ok := nodes[link.SourcePath]
+		if !ok {
+			continue
+		}
+		target, ok := nodes[link.TargetPath]
+		if !ok || source == target {
+			continue
+		}
+		graph.neighbors[source][target] = true
+		graph.neighbors[target][source] = true
+	}
+	return graph
+}
+
+// clusters groups the connected notes with label propagation: each note
+// takes the label most common among its neighbors, until the labels are
+// stable. Ties are broken with the smallest label.
+func (g *noteGraph) clusters() [][]int {
+	labels := make([]int, len(g.notes))
+	for i := range labels {
+		labels[i] = i
+	}
+
+	for iteration := 0; iteration < mocMaxIterations; iteration++ {
+		changed := false
+		for node, neighbors := range g.neighbors {
+			if len(neighbors) == 0 {
+				continue
+			}
+			counts := map[int]int{}
+			for neighbor := range neighbors {
+				counts[labels[neighbor]]++
+			}
+			best, bestCount := labels[node], counts[labels[node]]
+			for label, count := range counts {
+				if count > bestCount || (count == bestCount && label < best) {
+					best, bestCount = label, count
+				}
+			}
+			if best != labels[node] {
+				labels[node] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	byLabel := map[int][]int{}
+	order := []int{}
+	for node, label := range labels {
+		if _, ok := byLabel[label]; !ok {
+			order = append(order, label)
+		}
+		byLabel[label] = append(byLabel[label], node)
+	}
+	clusters := [][]int{}
+	for _, label := range order {
+		clusters = append(clusters, byLabel[label])
+	}
+	return clusters
+}
+
+// degree returns the number of notes of the cluster linked with node.
+func (g *noteGraph) degree(node int, cluster []int) int {
+	degree := 0
+	for _, other := range cluster {
+		if g.neighbors[node][other] {
+			degree++
+		}
+	}
+	return degree
+}
+
+// hasHub returns whether a note of the cluster is linked with most of the
+// other notes, and much more than they are on average, which makes it a map
+// of content already.
+func (g *noteGraph) hasHub(cluster []int) bool {
+	total := 0
+	degrees := []int{}
+	for _, node := range cluster {
+		degree := g.degree(node, cluster)
+		total += degree
+		degrees = append(degrees, degree)
+	}
+	for _, degree := range degrees {
+		others := float64(total-degree) / float64(len(cluster)-1)
+		if float64(degree) >= mocHubRatio*float64(len(cluster)-1) && float64(degree) >= mocHubFactor*others {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *noteGraph) suggestion(cluster []int) MOCSuggestion {
+	suggestion := MOCSuggestion{Notes: []MOCMember{}}
+	links := 0
+	notes := []Note{}
+	for _, node := range cluster {
+		note := g.notes[node]
+		degree := g.degree(node, cluster)
+		links += degree
+		notes = append(notes, note)
+		suggestion.Notes = append(suggestion.Notes, MOCMember{
+			Path:  note.Path,
+			Title: note.Title,
+			Links: degree,
+		})
+	}
+	sort.SliceStable(suggestion.Notes, func(i, j int) bool {
+		return suggestion.Notes[i].Links > suggestion.Notes[j].Links
+	})
+
+	// Each link is counted from both of its notes.
+	size := float64(len(cluster))
+	suggestion.Density = float64(links) / (size * (size - 1))
+	suggestion.Titles = proposeMOCTitles(notes, suggestion.Notes[0])
+	return suggestion
+}
+
+// proposeMOCTitles suggests titles for the map of content of the given
+// notes: their most common tag, the words frequent in their titles and the
+// title of their most connected note.
+func proposeMOCTitles(notes []Note, hub MOCMember) []string {
+	titles := []string{}
+	add := func(title string) {
+		title = strings.TrimSpace(title)
+		if title == "" || len(titles) >= mocMaxTitles {
+			return
+		}
+		for _, other := range titles {
+			if strings.EqualFold(other, title) {
+				return
+			}
+		}
+		titles = append(titles, capitalize(title))
+	}
+
+	tagCounts := map[string]int{}
+	for _, note := range notes {
+		for _, tag := range note.Tags {
+			tagCounts[tag]++
+		}
+	}
+	if tag, count := mostCommon(tagCounts); count*2 >= len(notes) {
+		add(tag)
+	}
+
+	wordCounts := map[string]int{}
+	for _, note := range notes {
+		seen := map[string]bool{}
+		for _, word := range strings.FieldsFunc(strings.ToLower(note.Title), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		}) {
+			if seen[word] || utf8.RuneCountInString(word) < 3 || mocStopWords[word] {
+				continue
+			}
+			seen[word] = true
+			wordCounts[word]++
+		}
+	}
+	words := []string{}
+	for len(words) < 2 {
+		word, count := mostCommon(wordCounts)
+		if count < 2 {
+			break
+		}
+		words = append(words, word)
+		delete(wordCounts, word)
+	}
+	add(strings.Join(words, " and "))
+
+	hubTitle := hub.Title
+	if hubTitle == "" {
+		hubTitle = paths.DropExt(filepath.Base(hub.Path))
+	}
+	add(hubTitle)
+
+	return titles
+}
+
+// mostCommon returns the key with the highest count, the smallest one on
+// ties.
+func mostCommon(counts map[string]int) (string, int) {
+	best, bestCount := "", 0
+	for key, count := range counts {
+		if count > bestCount || (count == bestCount && key < best) {
+			best, bestCount = key, count
+		}
+	}
+	return best, bestCount
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// mocStopWords are the common English words ignored when looking for the
+// topic of a cluster.
+var mocStopWords = map[string]bool{
+	"about": true, "and": true, "are": true, "but": true, "for": true,
+	"from": true, "how": true, "into": true, "not": true, "notes": true,
+	"off": true, "our": true, "out": true, "the": true, "their": true,
+	"this": true, "what": true, "when": true, "why": true, "with": true,
+	"you": true, "your": true,
+}
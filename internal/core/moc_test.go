@@ -0,0 +1,78 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestSuggestMOCs(t *testing.T) {
+	notes := []Note{
+		{Path: "a1.md", Title: "Compost basics", Tags: []string{"gardening"}},
+		{Path: "a2.md", Title: "Compost bins", Tags: []string{"gardening"}},
+		{Path: "a3.md", Title: "Soil health", Tags: []string{"gardening"}},
+		{Path: "a4.md", Title: "Soil pH", Tags: []string{"gardening", "chemistry"}},
+		{Path: "a5.md", Title: "Seed starting"},
+		{Path: "a6.md", Title: "Watering tips"},
+		{Path: "b/1.md", Title: "Go"},
+		{Path: "b/2.md", Title: "Rust"},
+		{Path: "b/3.md", Title: "Zig"},
+		{Path: "b/4.md", Title: "C"},
+		{Path: "b/hub.md", Title: "Languages"},
+		{Path: "c1.md", Title: "Chess openings"},
+		{Path: "c2.md", Title: "Chess endgames"},
+		{Path: "c3.md"},
+		{Path: "orphan.md", Title: "Orphan"},
+	}
+	links := []NoteLink{
+		// A ring with a chord, without a hub.
+		{SourcePath: "a1.md", TargetPath: "a2.md"},
+		{SourcePath: "a2.md", TargetPath: "a1.md"},
+		{SourcePath: "a2.md", TargetPath: "a3.md"},
+		{SourcePath: "a3.md", TargetPath: "a4.md"},
+		{SourcePath: "a4.md", TargetPath: "a5.md"},
+		{SourcePath: "a5.md", TargetPath: "a6.md"},
+		{SourcePath: "a6.md", TargetPath: "a1.md"},
+		{SourcePath: "a1.md", TargetPath: "a4.md"},
+		{SourcePath: "a1.md", TargetPath: "a1.md"},
+		{SourcePath: "a1.md", TargetPath: "trashed.md"},
+		// A star organized by a hub note.
+		{SourcePath: "b/hub.md", TargetPath: "b/1.md"},
+		{SourcePath: "b/hub.md", TargetPath: "b/2.md"},
+		{SourcePath: "b/hub.md", TargetPath: "b/3.md"},
+		{SourcePath: "b/hub.md", TargetPath: "b/4.md"},
+		// A small cluster.
+		{SourcePath: "c1.md", TargetPath: "c2.md"},
+		{SourcePath: "c2.md", TargetPath: "c3.md"},
+		{SourcePath: "c3.md", TargetPath: "c1.md"},
+	}
+
+	assert.Equal(t, suggestMOCs(notes, links, SuggestMOCsOpts{}), []MOCSuggestion{
+		{
+			Notes: []MOCMember{
+				{Path: "a1.md", Title: "Compost basics", Links: 3},
+				{Path: "a4.md", Title: "Soil pH", Links: 3},
+				{Path: "a2.md", Title: "Compost bins", Links: 2},
+				{Path: "a3.md", Title: "Soil health", Links: 2},
+				{Path: "a5.md", Title: "Seed starting", Links: 2},
+				{Path: "a6.md", Title: "Watering tips", Links: 2},
+			},
+			Density: 14.0 / 30.0,
+			Titles:  []string{"Gardening", "Compost and soil", "Compost basics"},
+		},
+	})
+
+	assert.Equal(t, suggestMOCs(notes, links, SuggestMOCsOpts{MinSize: 3})[1], MOCSuggestion{
+		Notes: []MOCMember{
+			{Path: "c1.md", Title: "Chess openings", Links: 2},
+			{Path: "c2.md", Title: "Chess endgames", Links: 2},
+			{Path: "c3.md", Title: "", Links: 2},
+		},
+		Density: 1,
+		Titles:  []string{"Chess", "Chess openings"},
+	})
+}
+
+func TestSuggestMOCsEmpty(t *testing.T) {
+	assert.Equal(t, suggestMOCs([]Note{}, []NoteLink{}, SuggestMOCsOpts{}), []MOCSuggestion{})
+}
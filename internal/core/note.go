@@ -1,7 +1,9 @@
 package core
 
 import (
+	"fmt"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/mickael-menu/zk/internal/util/paths"
@@ -23,10 +25,75 @@ type MinimalNote struct {
 	Path string
 	// Title of the note.
 	Title string
+	// Short description of the note, see Note.Summary.
+	Summary string
+	// Number of words found in the content, see Note.WordCount.
+	WordCount int
 	// JSON dictionary of raw metadata extracted from the frontmatter.
 	Metadata map[string]interface{}
 }
 
+// PublishedURL returns the URL of the note once the notebook is published at
+// the given base URL.
+//
+// The path of the URL is read from the `permalink` frontmatter key, or
+// defaults to the note path without its file extension.
+func (n MinimalNote) PublishedURL(baseURL string) string {
+	permalink, ok := n.Metadata["permalink"].(string)
+	if !ok || permalink == "" {
+		permalink = strings.TrimSuffix(filepath.ToSlash(n.Path), filepath.Ext(n.Path))
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(permalink, "/")
+}
+
+// Aliases returns the alternative titles of the note, declared with the
+// `aliases` frontmatter key, like Obsidian:
+// https://publish.obsidian.md/help/How+to/Add+aliases+to+note
+//
+// The key holds either a list of aliases or a single one.
+func (n MinimalNote) Aliases() []string {
+	aliases := []string{}
+	appendAlias := func(alias string) {
+		alias = strings.TrimSpace(alias)
+		if alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	switch value := n.Metadata["aliases"].(type) {
+	case []interface{}:
+		for _, alias := range value {
+			appendAlias(fmt.Sprint(alias))
+		}
+	case string:
+		appendAlias(value)
+	}
+	return aliases
+}
+
+// maxSummaryLength is the number of characters after which a summary taken
+// from the lead of a note is truncated.
+const maxSummaryLength = 200
+
+// NoteSummary returns a short description of a note, read from the `summary`
+// or `description` frontmatter keys. It defaults to the first paragraph of the
+// note, truncated and folded into a single line.
+func NoteSummary(metadata map[string]interface{}, lead string) string {
+	for _, key := range []string{"summary", "description"} {
+		if summary, ok := metadata[key].(string); ok {
+			if summary = strings.TrimSpace(summary); summary != "" {
+				return summary
+			}
+		}
+	}
+
+	summary := []rune(strings.Join(strings.Fields(lead), " "))
+	if len(summary) > maxSummaryLength {
+		summary = append([]rune(strings.TrimSpace(string(summary[:maxSummaryLength-1]))), '…')
+	}
+	return string(summary)
+}
+
 // Note holds the metadata and content of a single note.
 type Note struct {
 	// Unique ID of this note in a NoteRepository.
@@ -37,6 +104,9 @@ type Note struct {
 	Title string
 	// First paragraph from the note body.
 	Lead string
+	// Short description of the note, from the `summary` or `description`
+	// frontmatter keys, or defaulting to the lead.
+	Summary string
 	// Content of the note, after any frontmatter and title heading.
 	Body string
 	// Whole raw content of the note.
@@ -47,6 +117,8 @@ type Note struct {
 	Links []Link
 	// List of tags found in the content.
 	Tags []string
+	// List of checkbox items found in the content.
+	Tasks []Task
 	// JSON dictionary of raw metadata extracted from the frontmatter.
 	Metadata map[string]interface{}
 	// Date of creation.
@@ -57,12 +129,24 @@ type Note struct {
 	Checksum string
 }
 
+// ReadingSpeed is the number of words read per minute, used to estimate the
+// reading time of the notes.
+const ReadingSpeed = 200
+
+// ReadingTime estimates the number of minutes needed to read a note with the
+// given number of words, rounded up.
+func ReadingTime(wordCount int) int {
+	return (wordCount + ReadingSpeed - 1) / ReadingSpeed
+}
+
 func (n Note) AsMinimalNote() MinimalNote {
 	return MinimalNote{
-		ID:       n.ID,
-		Path:     n.Path,
-		Title:    n.Title,
-		Metadata: n.Metadata,
+		ID:        n.ID,
+		Path:      n.Path,
+		Title:     n.Title,
+		Summary:   n.Summary,
+		WordCount: n.WordCount,
+		Metadata:  n.Metadata,
 	}
 }
 
@@ -86,4 +170,13 @@ type ContextualNote struct {
 	Note
 	// List of context-sensitive excerpts from the note.
 	Snippets []string
+	// Relevance of the note for the full-text search query, higher is
+	// better. Zero when the notes are not searched with a query.
+	Score float64
+	// Number of links between the note and the ones given to a link filter,
+	// or its position on a link path. Zero when the links are not filtered.
+	Distance int
+	// Estimate of how developed the note is, from 0 to 100. See
+	// NoteMaturity.
+	Maturity float64
 }
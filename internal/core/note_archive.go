@@ -0,0 +1,69 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// IsArchived returns whether the note at the given path, relative to the
+// notebook root, is in the archive directory set in the `[trash]` config
+// section.
+func (n *Notebook) IsArchived(path string) bool {
+	dir := n.Config.Trash.ArchiveDir
+	if dir == "" {
+		return false
+	}
+	path = filepath.Clean(path)
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// ArchiveNote moves a note to the archive directory set in the `[trash]`
+// config section, keeping its path relative to the notebook root. The links
+// to the note are updated like with MoveNote.
+//
+// The archived notes are kept in the index, but they are hidden from the
+// searches and the completion unless requested.
+func (n *Notebook) ArchiveNote(origin AuditOrigin, path string, dryRun bool) (*NoteMove, error) {
+	wrap := errors.Wrapperf("%s: failed to archive the note", path)
+
+	dir := n.Config.Trash.ArchiveDir
+	if dir == "" {
+		return nil, wrap(errors.New("no archive directory, set `archive-dir` in the [trash] config section"))
+	}
+	note, err := n.indexedNoteAt(path)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if n.IsArchived(note.Path) {
+		return nil, wrap(errors.New("the note is already archived"))
+	}
+
+	return n.MoveNote(MoveNoteOpts{
+		Path:        filepath.Join(n.Path, note.Path),
+		Destination: filepath.Join(n.Path, dir, note.Path),
+		DryRun:      dryRun,
+		Origin:      origin,
+	})
+}
+
+// resolveArchive hides the archived notes when opts.ExcludeArchived is set,
+// unless the notes are filtered by paths inside the archive directory.
+func (n *Notebook) resolveArchive(opts NoteFindOpts) NoteFindOpts {
+	dir := n.Config.Trash.ArchiveDir
+	if !opts.ExcludeArchived || dir == "" {
+		return opts
+	}
+	if !opts.EnablePathRegexes {
+		for _, path := range opts.IncludePaths {
+			if n.IsArchived(path) {
+				return opts
+			}
+		}
+	}
+
+	// Copied to not modify the caller's slice.
+	opts.ExcludeDirs = append(append([]string{}, opts.ExcludeDirs...), filepath.ToSlash(dir))
+	return opts
+}
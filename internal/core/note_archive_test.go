@@ -0,0 +1,76 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestNotebookIsArchived(t *testing.T) {
+	notebook := &Notebook{Config: NewDefaultConfig()}
+	assert.False(t, notebook.IsArchived("archive/a.md"))
+
+	notebook.Config.Trash.ArchiveDir = "archive"
+	assert.True(t, notebook.IsArchived("archive"))
+	assert.True(t, notebook.IsArchived("archive/a.md"))
+	assert.True(t, notebook.IsArchived("archive/dir/a.md"))
+	assert.True(t, notebook.IsArchived("dir/../archive/a.md"))
+	assert.False(t, notebook.IsArchived("archived/a.md"))
+	assert.False(t, notebook.IsArchived("dir/archive/a.md"))
+	assert.False(t, notebook.IsArchived("a.md"))
+}
+
+func TestNotebookResolveArchive(t *testing.T) {
+	notebook := &Notebook{Config: NewDefaultConfig()}
+	opts := NoteFindOpts{ExcludeArchived: true, ExcludeDirs: []string{"drafts"}}
+
+	// No archive directory.
+	assert.Equal(t, notebook.resolveArchive(opts), opts)
+
+	notebook.Config.Trash.ArchiveDir = filepath.Join("notes", "archive")
+	assert.Equal(t, notebook.resolveArchive(opts).ExcludeDirs, []string{"drafts", "notes/archive"})
+	assert.Equal(t, opts.ExcludeDirs, []string{"drafts"})
+
+	// The archived notes are requested.
+	opts.ExcludeArchived = false
+	assert.Equal(t, notebook.resolveArchive(opts), opts)
+
+	// Explicitly filtered by a path inside the archive.
+	opts = NoteFindOpts{ExcludeArchived: true, IncludePaths: []string{"notes/archive/a.md"}}
+	assert.Equal(t, notebook.resolveArchive(opts), opts)
+	opts.EnablePathRegexes = true
+	assert.Equal(t, notebook.resolveArchive(opts).ExcludeDirs, []string{"notes/archive"})
+}
+
+func TestNotebookArchiveNote(t *testing.T) {
+	notebook, _ := newResolveTestNotebook(t, map[string]*NoteContent{
+		"dir/a.md":     {Title: opt.NewString("Alpha")},
+		"archive/b.md": {Title: opt.NewString("Beta")},
+	})
+
+	test := func(path string, expected string, expectedErr string) {
+		t.Helper()
+		move, err := notebook.ArchiveNote(AuditOrigin{}, filepath.Join(notebook.Path, path), true)
+		if expectedErr != "" {
+			assert.Err(t, err, expectedErr)
+			return
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, move.SourcePath, path)
+		assert.Equal(t, move.TargetPath, expected)
+	}
+
+	test("dir/a.md", "", "failed to archive the note: no archive directory")
+
+	notebook.Config.Trash.ArchiveDir = "archive"
+	test("dir/a.md", "archive/dir/a.md", "")
+	test("archive/b.md", "", "failed to archive the note: the note is already archived")
+	test("missing.md", "", "missing.md: note not found")
+
+	// The dry run doesn't move the note.
+	exists, err := notebook.fs.FileExists(filepath.Join(notebook.Path, "archive/dir/a.md"))
+	assert.Nil(t, err)
+	assert.False(t, exists)
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// Attachment is a file captured along with a new note, e.g. from an email.
+type Attachment struct {
+	Filename string
+	Content  []byte
+}
+
+// CaptureNoteOpts holds the options used to capture a new note with
+// attachments in a Notebook.
+type CaptureNoteOpts struct {
+	NewNoteOpts
+	// Files saved as assets and linked at the end of the note content.
+	Attachments []Attachment
+	// Directory where the attachments are saved, relative to the note
+	// directory.
+	AttachmentsDir string
+}
+
+// CapturedNote is a note created with its attachments.
+type CapturedNote struct {
+	*Note
+	// Paths of the saved attachments, relative to the notebook root.
+	AttachmentPaths []string
+}
+
+// CaptureNote saves the given attachments as assets and creates a new note
+// linking to them.
+func (n *Notebook) CaptureNote(opts CaptureNoteOpts) (*CapturedNote, error) {
+	wrap := errors.Wrapper("capture note")
+
+	dir, err := n.RequireDirAt(opts.Directory.OrString(n.Path).Unwrap())
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	captured := CapturedNote{AttachmentPaths: []string{}}
+	removeAttachments := func() {
+		for _, path := range captured.AttachmentPaths {
+			n.logger.Err(n.fs.Remove(filepath.Join(n.Path, path)))
+		}
+	}
+
+	links := []string{}
+	for _, attachment := range opts.Attachments {
+		absPath, err := n.freeAttachmentPath(filepath.Join(dir.Path, opts.AttachmentsDir), attachment.Filename)
+		if err == nil {
+			err = n.fs.Write(absPath, attachment.Content)
+		}
+		if err != nil {
+			removeAttachments()
+			return nil, wrap(err)
+		}
+		path, err := n.RelPath(absPath)
+		if err != nil {
+			removeAttachments()
+			return nil, wrap(err)
+		}
+		captured.AttachmentPaths = append(captured.AttachmentPaths, path)
+
+		href, err := filepath.Rel(dir.Path, absPath)
+		if err != nil {
+			removeAttachments()
+			return nil, wrap(err)
+		}
+		link := fmt.Sprintf("[%s](%s)", filepath.Base(absPath), joinHref(href, "", true))
+		if IsImagePath(absPath) {
+			link = "!" + link
+		}
+		links = append(links, "* "+link)
+	}
+
+	if len(links) > 0 {
+		opts.Content = strings.TrimRight(opts.Content, "\n")
+		if opts.Content != "" {
+			opts.Content += "\n\n"
+		}
+		opts.Content += strings.Join(links, "\n") + "\n"
+	}
+
+	captured.Note, err = n.NewNote(opts.NewNoteOpts)
+	if err != nil {
+		removeAttachments()
+		return nil, wrap(err)
+	}
+
+	return &captured, nil
+}
+
+// freeAttachmentPath returns an absolute path in dir for a new attachment,
+// which doesn't overwrite an existing file.
+func (n *Notebook) freeAttachmentPath(dir string, filename string) (string, error) {
+	filename = filepath.Base(filepath.Clean("/" + filename))
+	if filename == "/" || filename == "." {
+		filename = "attachment"
+	}
+	ext := filepath.Ext(filename)
+	stem := paths.DropExt(filename)
+
+	for i := 1; i < 100; i++ {
+		name := filename
+		if i > 1 {
+			name = fmt.Sprintf("%s-%d%s", stem, i, ext)
+		}
+		path := filepath.Join(dir, name)
+		exists, err := n.fs.FileExists(path)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s: attachment already exists", filepath.Join(dir, filename))
+}
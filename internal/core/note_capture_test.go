@@ -0,0 +1,62 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestNotebookCaptureNote(t *testing.T) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+		dirs:    []string{"/notebook/inbox"},
+		files: map[string]string{
+			"/notebook/inbox/attachments/doc.pdf": "existing",
+		},
+	}
+	test.setup()
+
+	note, err := test.notebook().CaptureNote(CaptureNoteOpts{
+		NewNoteOpts: NewNoteOpts{
+			Content:   "Body\n",
+			Directory: opt.NewString("/notebook/inbox"),
+			Date:      now,
+		},
+		Attachments: []Attachment{
+			{Filename: "doc.pdf", Content: []byte("PDF")},
+			{Filename: "../my photo.png", Content: []byte("PNG")},
+		},
+		AttachmentsDir: "attachments",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, note.Path, "inbox/filename.ext")
+	assert.Equal(t, note.AttachmentPaths, []string{"inbox/attachments/doc-2.pdf", "inbox/attachments/my photo.png"})
+	assert.Equal(t, test.fs.files["/notebook/inbox/attachments/doc-2.pdf"], "PDF")
+	assert.Equal(t, test.fs.files["/notebook/inbox/attachments/my photo.png"], "PNG")
+	assert.Equal(t, test.bodyTemplate.Contexts[0].(newNoteTemplateContext).Content, `Body
+
+* [doc-2.pdf](attachments/doc-2.pdf)
+* ![my photo.png](attachments/my%20photo.png)
+`)
+}
+
+func TestNotebookCaptureNoteRemovesAttachmentsOnError(t *testing.T) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+	}
+	test.setup()
+
+	_, err := test.notebook().CaptureNote(CaptureNoteOpts{
+		NewNoteOpts: NewNoteOpts{
+			Group: opt.NewString("unknown"),
+			Date:  now,
+		},
+		Attachments:    []Attachment{{Filename: "doc.pdf", Content: []byte("PDF")}},
+		AttachmentsDir: "attachments",
+	})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, test.fs.files, map[string]string{})
+}
@@ -0,0 +1,226 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// carriedTasks are the unchecked tasks of a previous note, carried over to a
+// new note.
+type carriedTasks struct {
+	// Note the tasks come from.
+	source MinimalNote
+	// Content of the source note, with the carried tasks marked as migrated.
+	sourceContent string
+	// Tasks grouped by the heading of their section, an empty heading for
+	// the tasks of the whole note.
+	sections []carriedSection
+	count    int
+}
+
+type carriedSection struct {
+	heading string
+	tasks   []string
+}
+
+// findCarriedTasks collects the unchecked tasks of the most recent note
+// created in dir before the given date, to carry them over to a new note of
+// the directory. Returns nil when there are no tasks to carry over.
+func (n *Notebook) findCarriedTasks(index NoteIndex, dir Dir, config NoteConfig, date time.Time) (*carriedTasks, error) {
+	if !config.CarryOverTasks {
+		return nil, nil
+	}
+	wrap := errors.Wrapper("failed to carry over the unfinished tasks")
+
+	opts := NoteFindOpts{
+		CreatedEnd: &date,
+		Trash:      TrashFilterExclude,
+		Sorters:    []NoteSorter{{Field: NoteSortCreated, Ascending: false}},
+		Limit:      1,
+	}
+	if dir.Name != "" {
+		opts.IncludePaths = []string{dir.Name}
+	}
+	notes, err := index.Find(opts)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if len(notes) == 0 {
+		return nil, nil
+	}
+	source := notes[0].AsMinimalNote()
+
+	content, err := n.fs.Read(filepath.Join(n.Path, source.Path))
+	if err != nil {
+		return nil, wrap(err)
+	}
+	sourceContent, sections := extractUncheckedTasks(string(content), config.CarryOverSections)
+	carried := carriedTasks{
+		source:        source,
+		sourceContent: sourceContent,
+		sections:      sections,
+	}
+	for _, section := range sections {
+		carried.count += len(section.tasks)
+	}
+	if carried.count == 0 {
+		return nil, nil
+	}
+	return &carried, nil
+}
+
+// carryOverTasks inserts the carried tasks in the new note, then marks them
+// as migrated in their source note. Both notes are reindexed.
+func (n *Notebook) carryOverTasks(index NoteIndex, note *Note, carried carriedTasks) error {
+	wrap := errors.Wrapper("failed to carry over the unfinished tasks")
+
+	path := filepath.Join(n.Path, note.Path)
+	content, err := n.fs.Read(path)
+	if err != nil {
+		return wrap(err)
+	}
+	updated := string(content)
+	for _, section := range carried.sections {
+		for _, task := range section.tasks {
+			updated = insertUnderHeading(updated, section.heading, task)
+		}
+	}
+	err = n.fs.Write(path, []byte(updated))
+	if err != nil {
+		return wrap(err)
+	}
+	parsed, err := n.ParseNoteAt(path)
+	if parsed == nil || err != nil {
+		return wrap(err)
+	}
+	parsed.ID = note.ID
+	err = index.Update(*parsed)
+	if err != nil {
+		return wrap(err)
+	}
+	*note = *parsed
+
+	sourcePath := filepath.Join(n.Path, carried.source.Path)
+	err = n.fs.Write(sourcePath, []byte(carried.sourceContent))
+	if err != nil {
+		return wrap(err)
+	}
+	source, err := n.ParseNoteAt(sourcePath)
+	if source == nil || err != nil {
+		return wrap(err)
+	}
+	return wrap(index.Update(*source))
+}
+
+// details describes the carried tasks for the audit log.
+func (c carriedTasks) details() string {
+	return fmt.Sprintf("carried over %d %s from %s", c.count, strutil.Pluralize("task", c.count), c.source.Path)
+}
+
+var uncheckedTaskRegex = regexp.MustCompile(`^(\s*)[-*+][ \t]+\[( )\]`)
+
+// extractUncheckedTasks returns the unchecked task items of the content with
+// their nested lines, grouped by section, and the content with these tasks
+// marked as migrated, e.g. `- [>] task`.
+//
+// When sections is empty, the tasks of the whole content are extracted.
+// Otherwise, only the tasks found under the given headings.
+func extractUncheckedTasks(content string, sections []string) (string, []carriedSection) {
+	lines := strings.Split(content, "\n")
+	res := []carriedSection{}
+	add := func(heading string, task string) {
+		for i, s := range res {
+			if s.heading == heading {
+				res[i].tasks = append(res[i].tasks, task)
+				return
+			}
+		}
+		res = append(res, carriedSection{heading: heading, tasks: []string{task}})
+	}
+
+	// Heading of the current section, when it is carried over.
+	heading := ""
+	inSection := len(sections) == 0
+	level := 0
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if matches := codeFenceRegex.FindStringSubmatch(line); matches != nil {
+			if fence == "" {
+				fence = matches[1]
+			} else if fence == matches[1] {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		if matches := headingRegex.FindStringSubmatch(strings.TrimRight(line, "\r")); matches != nil && len(sections) > 0 {
+			title := strings.TrimSpace(matches[2])
+			if inSection && len(matches[1]) > level {
+				// Sub-sections are part of the carried section.
+				continue
+			}
+			inSection = false
+			for _, section := range sections {
+				if strings.EqualFold(title, strings.TrimSpace(section)) {
+					inSection, heading, level = true, section, len(matches[1])
+					break
+				}
+			}
+			continue
+		}
+		if !inSection {
+			continue
+		}
+
+		matches := uncheckedTaskRegex.FindStringSubmatchIndex(line)
+		if matches == nil {
+			continue
+		}
+		indent := line[matches[2]:matches[3]]
+
+		// Nested lines are carried over with the task.
+		end := i + 1
+		for end < len(lines) && isNestedLine(lines[end], indent) {
+			end++
+		}
+		// Blank lines ending the task are not part of it.
+		for end > i+1 && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+
+		task := []string{}
+		for j := i; j < end; j++ {
+			task = append(task, strings.TrimPrefix(lines[j], indent))
+			if loc := uncheckedTaskRegex.FindStringSubmatchIndex(lines[j]); loc != nil {
+				lines[j] = lines[j][:loc[4]] + ">" + lines[j][loc[5]:]
+			}
+		}
+		add(heading, strings.Join(task, "\n"))
+		i = end - 1
+	}
+
+	return strings.Join(lines, "\n"), res
+}
+
+// isNestedLine returns whether line belongs to a list item indented with
+// the given indentation.
+func isNestedLine(line string, indent string) bool {
+	if strings.TrimSpace(line) == "" {
+		return true
+	}
+	if !strings.HasPrefix(line, indent) {
+		return false
+	}
+	rest := line[len(indent):]
+	return strings.HasPrefix(rest, " ") || strings.HasPrefix(rest, "\t")
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestExtractUncheckedTasks(t *testing.T) {
+	test := func(content string, sections []string, expectedContent string, expectedSections []carriedSection) {
+		t.Helper()
+		actualContent, actualSections := extractUncheckedTasks(content, sections)
+		assert.Equal(t, actualContent, expectedContent)
+		assert.Equal(t, actualSections, expectedSections)
+	}
+
+	test("", []string{}, "", []carriedSection{})
+	test("# Today\n\n- [x] Done\n- Not a task\n", []string{}, "# Today\n\n- [x] Done\n- Not a task\n", []carriedSection{})
+
+	// All the tasks of the note, with their nested lines.
+	test(`# Today
+
+- [ ] Call Paul
+- [x] Write the report
+* [ ] Plan the trip
+    - [ ] Book the hotel
+    - Pack
+
+  Details about the trip.
+
+Some text.
+`, []string{}, `# Today
+
+- [>] Call Paul
+- [x] Write the report
+* [>] Plan the trip
+    - [>] Book the hotel
+    - Pack
+
+  Details about the trip.
+
+Some text.
+`, []carriedSection{
+		{heading: "", tasks: []string{
+			"- [ ] Call Paul",
+			"* [ ] Plan the trip\n    - [ ] Book the hotel\n    - Pack\n\n  Details about the trip.",
+		}},
+	})
+
+	// A nested task is carried over without its parent.
+	test("- Project\n  - [ ] Review\n", []string{}, "- Project\n  - [>] Review\n", []carriedSection{
+		{heading: "", tasks: []string{"- [ ] Review"}},
+	})
+
+	// Only the tasks of the given sections, including their sub-sections.
+	test(`# Journal
+
+- [ ] Outside
+
+## Tasks
+
+- [ ] First
+
+### Work
+
+- [ ] Second
+
+## Notes
+
+- [ ] Ignored
+
+## Errands
+- [ ] Third
+`, []string{"tasks", "Errands"}, `# Journal
+
+- [ ] Outside
+
+## Tasks
+
+- [>] First
+
+### Work
+
+- [>] Second
+
+## Notes
+
+- [ ] Ignored
+
+## Errands
+- [>] Third
+`, []carriedSection{
+		{heading: "tasks", tasks: []string{"- [ ] First", "- [ ] Second"}},
+		{heading: "Errands", tasks: []string{"- [ ] Third"}},
+	})
+
+	// Tasks in code blocks are ignored.
+	test("```\n- [ ] Code\n```\n- [ ] Task\n", []string{}, "```\n- [ ] Code\n```\n- [>] Task\n", []carriedSection{
+		{heading: "", tasks: []string{"- [ ] Task"}},
+	})
+}
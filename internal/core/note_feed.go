@@ -0,0 +1,129 @@
+package core
+
+import (
+	"encoding/xml"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// NoteFeedOpts holds the options used to generate a feed of notes.
+type NoteFeedOpts struct {
+	// Title of the feed, by default the name of the notebook directory.
+	Title string
+	// Base URL where the notebook is published, to link the entries to the
+	// published notes. The notes are linked with file:// URLs otherwise.
+	BaseURL string
+}
+
+// NoteFeed generates an Atom feed of the given notes, with their title,
+// dates and first paragraph as a summary.
+func (n *Notebook) NoteFeed(notes []ContextualNote, opts NoteFeedOpts) ([]byte, error) {
+	if opts.Title == "" {
+		opts.Title = filepath.Base(n.Path)
+	}
+
+	feed := atomFeed{
+		Title:   opts.Title,
+		ID:      n.feedURL(MinimalNote{}, opts.BaseURL),
+		Author:  atomPerson{Name: opts.Title},
+		Entries: []atomEntry{},
+	}
+	if opts.BaseURL != "" {
+		feed.Link = &atomLink{Href: feed.ID}
+	}
+
+	var updated time.Time
+	for _, note := range notes {
+		link := n.feedURL(note.AsMinimalNote(), opts.BaseURL)
+		title := note.Title
+		if title == "" {
+			title = filepath.Base(note.Path)
+		}
+		entry := atomEntry{
+			Title:     title,
+			ID:        link,
+			Link:      atomLink{Href: link},
+			Published: formatAtomDate(note.Created),
+			Updated:   formatAtomDate(note.Modified),
+			Summary:   note.Lead,
+		}
+		if author, ok := note.Metadata["author"].(string); ok && author != "" {
+			entry.Author = &atomPerson{Name: author}
+		}
+		for _, tag := range note.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+
+		if note.Modified.After(updated) {
+			updated = note.Modified
+		}
+	}
+	// The date of the most recent change keeps the feed unchanged when the
+	// notes are not modified.
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = formatAtomDate(updated)
+
+	content, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(xml.Header), content...), '\n'), nil
+}
+
+// feedURL returns the URL of a note in a feed, or of the notebook root for
+// an empty note.
+func (n *Notebook) feedURL(note MinimalNote, baseURL string) string {
+	if baseURL != "" {
+		if note.Path == "" {
+			return strings.TrimSuffix(baseURL, "/") + "/"
+		}
+		return note.PublishedURL(baseURL)
+	}
+	path := filepath.Join(n.Path, note.Path)
+	if note.Path == "" {
+		path += string(filepath.Separator)
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func formatAtomDate(date time.Time) string {
+	return date.UTC().Format(time.RFC3339)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Link    *atomLink   `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     *atomPerson    `xml:"author"`
+	Summary    string         `xml:"summary,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestNoteFeed(t *testing.T) {
+	notebook := NewNotebook("/home/user/notes", NewDefaultConfig(), NotebookPorts{})
+	notes := []ContextualNote{
+		{Note: Note{
+			Path:     "journal/2021-10-12.md",
+			Title:    "Fish & chips",
+			Lead:     "A <great> day.",
+			Created:  time.Date(2021, 10, 12, 8, 0, 0, 0, time.UTC),
+			Modified: time.Date(2021, 10, 14, 9, 30, 0, 0, time.UTC),
+			Tags:     []string{"food", "travel"},
+			Metadata: map[string]interface{}{"author": "Jane"},
+		}},
+		{Note: Note{
+			Path:     "untitled.md",
+			Created:  time.Date(2021, 10, 10, 8, 0, 0, 0, time.UTC),
+			Modified: time.Date(2021, 10, 11, 8, 0, 0, 0, time.UTC),
+			Metadata: map[string]interface{}{"permalink": "/misc/untitled"},
+		}},
+	}
+
+	feed, err := notebook.NoteFeed(notes, NoteFeedOpts{
+		Title:   "My notes",
+		BaseURL: "https://notes.example.com/",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, string(feed), `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>My notes</title>
+  <id>https://notes.example.com/</id>
+  <link href="https://notes.example.com/"></link>
+  <updated>2021-10-14T09:30:00Z</updated>
+  <author>
+    <name>My notes</name>
+  </author>
+  <entry>
+    <title>Fish &amp; chips</title>
+    <id>https://notes.example.com/journal/2021-10-12</id>
+    <link href="https://notes.example.com/journal/2021-10-12"></link>
+    <published>2021-10-12T08:00:00Z</published>
+    <updated>2021-10-14T09:30:00Z</updated>
+    <author>
+      <name>Jane</name>
+    </author>
+    <summary>A &lt;great&gt; day.</summary>
+    <category term="food"></category>
+    <category term="travel"></category>
+  </entry>
+  <entry>
+    <title>untitled.md</title>
+    <id>https://notes.example.com/misc/untitled</id>
+    <link href="https://notes.example.com/misc/untitled"></link>
+    <published>2021-10-10T08:00:00Z</published>
+    <updated>2021-10-11T08:00:00Z</updated>
+  </entry>
+</feed>
+`)
+}
+
+func TestNoteFeedLinksToFiles(t *testing.T) {
+	notebook := NewNotebook("/home/user/my notes", NewDefaultConfig(), NotebookPorts{})
+	assert.Equal(t, notebook.feedURL(MinimalNote{}, ""), "file:///home/user/my%20notes/")
+	assert.Equal(t, notebook.feedURL(MinimalNote{Path: "dir/note.md"}, ""), "file:///home/user/my%20notes/dir/note.md")
+}
@@ -21,10 +21,21 @@ type NoteFindOpts struct {
 	ExcludePaths []string
 	// Indicates whether IncludePaths and ExcludePaths are using regexes.
 	EnablePathRegexes bool
+	// Filter excluding the notes in the given directories, relative to the
+	// notebook root.
+	ExcludeDirs []string
+	// Filter excluding the notes in the archive directory. Ignored when
+	// IncludePaths targets the archive.
+	ExcludeArchived bool
 	// Filter excluding notes with the given IDs.
 	ExcludeIDs []NoteID
+	// Filter by aliases declared in the `aliases` frontmatter key of the
+	// notes, ignoring case.
+	Aliases []string
 	// Filter by tags found in the notes.
 	Tags []string
+	// Filter by the values of the frontmatter keys.
+	Metadata []MetadataFilter
 	// Filter the notes mentioning the given ones.
 	Mention []string
 	// Filter the notes mentioned by the given ones.
@@ -33,10 +44,23 @@ type NoteFindOpts struct {
 	LinkedBy *LinkFilter
 	// Filter to select notes linking to another one.
 	LinkTo *LinkFilter
+	// Filter to select the notes on the shortest link path between two notes.
+	LinkPath *LinkPathFilter
+	// Filter to select the children of the given notes, in the hierarchy of
+	// Folgezettel IDs and `parent` frontmatter keys.
+	ChildrenOf *HierarchyFilter
+	// Filter to select the ancestors of the given notes, in the hierarchy of
+	// Folgezettel IDs and `parent` frontmatter keys.
+	AncestorsOf []string
 	// Filter to select notes which could might be related to the given notes paths.
 	Related []string
 	// Filter to select notes having no other notes linking to them.
 	Orphan bool
+	// Filter to select the notes recently created or opened, sorted by the
+	// date of their last action unless other Sorters are given.
+	RecentlyOpened bool
+	// Filter the notes according to whether they are in the trash.
+	Trash TrashFilter
 	// Filter notes created after the given date.
 	CreatedStart *time.Time
 	// Filter notes created before the given date.
@@ -45,6 +69,14 @@ type NoteFindOpts struct {
 	ModifiedStart *time.Time
 	// Filter notes modified before the given date.
 	ModifiedEnd *time.Time
+	// Filter notes modified since the given git revision.
+	ModifiedSince opt.String
+	// Filter by exact note paths, e.g. the ones modified since a git
+	// revision.
+	ExactPaths []string
+	// Weights of the note fields used to rank the notes matching the Match
+	// query. The default ones are used when nil.
+	MatchWeights *SearchWeights
 	// Limits the number of results
 	Limit int
 	// Sorting criteria
@@ -70,10 +102,89 @@ type LinkFilter struct {
 	MaxDistance int
 }
 
+// LinkPathFilter is a note filter used to select the notes on the shortest
+// path of links from a note to another one.
+type LinkPathFilter struct {
+	From string
+	To   string
+	// Maximum number of links followed, 0 for no limit.
+	MaxDistance int
+}
+
+// MetadataFilter is a note filter used to select notes by the value of a
+// frontmatter key, e.g. `status=draft` or `priority>=2`.
+//
+// The nested keys are joined with dots, e.g. `project.name`, and a list
+// matches when any of its items matches.
+type MetadataFilter struct {
+	// Lowercase frontmatter key.
+	Key string
+	// Comparison with Value, or MetadataOpExists to only check the presence
+	// of the key.
+	Op    MetadataOp
+	Value string
+}
+
+// MetadataOp is a comparison operator of a MetadataFilter.
+//
+// The values are compared as numbers when the filter value is a number,
+// otherwise as text ignoring case.
+type MetadataOp string
+
+const (
+	MetadataOpExists         MetadataOp = ""
+	MetadataOpEqual          MetadataOp = "="
+	MetadataOpNotEqual       MetadataOp = "!="
+	MetadataOpLess           MetadataOp = "<"
+	MetadataOpLessOrEqual    MetadataOp = "<="
+	MetadataOpGreater        MetadataOp = ">"
+	MetadataOpGreaterOrEqual MetadataOp = ">="
+)
+
+// metadataOps lists the operators of a MetadataFilter, the longest first to
+// be parsed before their prefix.
+var metadataOps = []MetadataOp{
+	MetadataOpNotEqual,
+	MetadataOpLessOrEqual,
+	MetadataOpGreaterOrEqual,
+	MetadataOpEqual,
+	MetadataOpLess,
+	MetadataOpGreater,
+}
+
+// MetadataFilterFromString parses a MetadataFilter from its string
+// representation, e.g. `status=draft`, `priority>=2` or `due` to select the
+// notes having the key.
+func MetadataFilterFromString(str string) (MetadataFilter, error) {
+	filter := MetadataFilter{Op: MetadataOpExists}
+
+	index := -1
+	for _, op := range metadataOps {
+		i := strings.Index(str, string(op))
+		if i >= 0 && (index < 0 || i < index || (i == index && len(op) > len(filter.Op))) {
+			index = i
+			filter.Op = op
+		}
+	}
+
+	key := str
+	if index >= 0 {
+		key = str[:index]
+		filter.Value = strings.TrimSpace(str[index+len(filter.Op):])
+	}
+	filter.Key = strings.ToLower(strings.TrimSpace(key))
+	if filter.Key == "" {
+		return filter, fmt.Errorf("%s: missing frontmatter key, expected e.g. status=draft", str)
+	}
+	return filter, nil
+}
+
 // NoteSorter represents an order term used to sort a list of notes.
 type NoteSorter struct {
 	Field     NoteSortField
 	Ascending bool
+	// Lowercase frontmatter key used with NoteSortMetadata.
+	Key string
 }
 
 // NoteSortField represents a note field used to sort a list of notes.
@@ -92,6 +203,15 @@ const (
 	NoteSortTitle
 	// Sort by the number of words in the note bodies.
 	NoteSortWordCount
+	// Sort by the relevance of the notes matching the Match query.
+	NoteSortRelevance
+	// Sort by the maturity of the notes, see NoteMaturity.
+	NoteSortMaturity
+	// Sort by the value of a frontmatter key, given in NoteSorter.Key.
+	NoteSortMetadata
+	// Sort by the date the notes were last created or opened, see
+	// NoteHistoryEntry.
+	NoteSortOpened
 	// Sort by the length of the note path.
 	// This is not accessible to the user but used for technical reasons, to
 	// find the best match when searching a path prefix.
@@ -120,6 +240,8 @@ func NoteSortersFromStrings(strs []string) ([]NoteSorter, error) {
 // If the input str has for suffix `+`, then the order will be ascending, while
 // descending for `-`. If no suffix is given, then the default order for the
 // sorting field will be used.
+//
+// The `meta.<key>` terms sort by the value of a frontmatter key.
 func NoteSorterFromString(str string) (NoteSorter, error) {
 	orderSymbol, _ := utf8.DecodeLastRuneInString(str)
 	str = strings.TrimRight(str, "+-")
@@ -136,10 +258,21 @@ func NoteSorterFromString(str string) (NoteSorter, error) {
 		sorter = NoteSorter{Field: NoteSortTitle, Ascending: true}
 	case "random", "r":
 		sorter = NoteSorter{Field: NoteSortRandom, Ascending: true}
-	case "word-count", "wc":
+	case "word-count", "wc", "reading-time", "rt":
+		// The reading time is estimated from the word count.
 		sorter = NoteSorter{Field: NoteSortWordCount, Ascending: true}
+	case "relevance", "rel":
+		sorter = NoteSorter{Field: NoteSortRelevance, Ascending: false}
+	case "maturity", "mat":
+		sorter = NoteSorter{Field: NoteSortMaturity, Ascending: true}
+	case "opened", "o":
+		sorter = NoteSorter{Field: NoteSortOpened, Ascending: false}
 	default:
-		return sorter, fmt.Errorf("%s: unknown sorting term\ntry created, modified, path, title, random or word-count", str)
+		key := strings.TrimPrefix(str, "meta.")
+		if key == str || key == "" {
+			return sorter, fmt.Errorf("%s: unknown sorting term\ntry created, modified, path, title, random, word-count, reading-time, relevance, maturity, opened or meta.<key>", str)
+		}
+		sorter = NoteSorter{Field: NoteSortMetadata, Ascending: true, Key: strings.ToLower(key)}
 	}
 
 	switch orderSymbol {
@@ -38,9 +38,56 @@ func TestNoteSorterFromString(t *testing.T) {
 	test("wc", NoteSortWordCount, true)
 	test("word-count", NoteSortWordCount, true)
 	test("word-count-", NoteSortWordCount, false)
+	test("rt", NoteSortWordCount, true)
+	test("reading-time-", NoteSortWordCount, false)
 
-	_, err := NoteSorterFromString("foobar")
+	test("rel", NoteSortRelevance, false)
+	test("relevance", NoteSortRelevance, false)
+	test("relevance+", NoteSortRelevance, true)
+
+	test("mat", NoteSortMaturity, true)
+	test("maturity", NoteSortMaturity, true)
+	test("maturity-", NoteSortMaturity, false)
+
+	test("o", NoteSortOpened, false)
+	test("opened", NoteSortOpened, false)
+	test("opened+", NoteSortOpened, true)
+
+	sorter, err := NoteSorterFromString("meta.Priority-")
+	assert.Nil(t, err)
+	assert.Equal(t, sorter, NoteSorter{Field: NoteSortMetadata, Ascending: false, Key: "priority"})
+	sorter, err = NoteSorterFromString("meta.project.name")
+	assert.Nil(t, err)
+	assert.Equal(t, sorter, NoteSorter{Field: NoteSortMetadata, Ascending: true, Key: "project.name"})
+
+	_, err = NoteSorterFromString("foobar")
 	assert.Err(t, err, "foobar: unknown sorting term")
+	_, err = NoteSorterFromString("meta.")
+	assert.Err(t, err, "meta.: unknown sorting term")
+}
+
+func TestMetadataFilterFromString(t *testing.T) {
+	test := func(str string, expected MetadataFilter) {
+		t.Helper()
+		actual, err := MetadataFilterFromString(str)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("status", MetadataFilter{Key: "status", Op: MetadataOpExists})
+	test("Status=draft", MetadataFilter{Key: "status", Op: MetadataOpEqual, Value: "draft"})
+	test("status = In progress", MetadataFilter{Key: "status", Op: MetadataOpEqual, Value: "In progress"})
+	test("status!=draft", MetadataFilter{Key: "status", Op: MetadataOpNotEqual, Value: "draft"})
+	test("priority>=2", MetadataFilter{Key: "priority", Op: MetadataOpGreaterOrEqual, Value: "2"})
+	test("priority>2", MetadataFilter{Key: "priority", Op: MetadataOpGreater, Value: "2"})
+	test("priority<=2", MetadataFilter{Key: "priority", Op: MetadataOpLessOrEqual, Value: "2"})
+	test("priority<2", MetadataFilter{Key: "priority", Op: MetadataOpLess, Value: "2"})
+	test("project.name=a=b", MetadataFilter{Key: "project.name", Op: MetadataOpEqual, Value: "a=b"})
+	test("title=a>b", MetadataFilter{Key: "title", Op: MetadataOpEqual, Value: "a>b"})
+	test("empty=", MetadataFilter{Key: "empty", Op: MetadataOpEqual, Value: ""})
+
+	_, err := MetadataFilterFromString("=draft")
+	assert.Err(t, err, "=draft: missing frontmatter key")
 }
 
 func TestSortersFromStrings(t *testing.T) {
@@ -67,3 +114,34 @@ func TestSortersFromStrings(t *testing.T) {
 	_, err := NoteSortersFromStrings([]string{"c", "foobar"})
 	assert.Err(t, err, "foobar: unknown sorting term")
 }
+
+func TestFuzzyMatchHref(t *testing.T) {
+	notes := []MinimalNote{
+		{Path: "dir/meeting-notes.md", Title: "Weekly meeting"},
+		{Path: "meeting-notes.md", Title: "Meeting notes"},
+		{Path: "Recipes/Pasta carbonara.md", Title: "Pasta Carbonara"},
+		{Path: "ideas.md", Title: "Mr. Smith's ideas"},
+	}
+
+	test := func(href string, threshold float64, expectedPath string, expectedConfidence float64) {
+		note, confidence := fuzzyMatchHref(href, notes, threshold)
+		if expectedPath == "" {
+			assert.True(t, note == nil)
+		} else {
+			assert.Equal(t, note.Path, expectedPath)
+		}
+		assert.Equal(t, confidence, expectedConfidence)
+	}
+
+	// Case and punctuation changes.
+	test("Meeting_Notes", 0.6, "meeting-notes.md", 1)
+	test("pasta-carbonara#ingredients", 0.6, "Recipes/Pasta carbonara.md", 1)
+	test("Recipes/pasta-carbonara.md", 0.6, "Recipes/Pasta carbonara.md", 1)
+	// Matches the title.
+	test("Mr Smith's Ideas", 0.6, "ideas.md", 1)
+	// Minor renames.
+	test("meeting-note", 0.6, "meeting-notes.md", 0.8)
+	test("meeting-note", 0.9, "", 0)
+	test("unrelated", 0.6, "", 0)
+	test("#anchor", 0.6, "", 0)
+}
@@ -11,7 +11,7 @@ import (
 // NoteFormatter formats notes to be printed on the screen.
 type NoteFormatter func(note ContextualNote) (string, error)
 
-func newNoteFormatter(basePath string, template Template, linkFormatter LinkFormatter, env map[string]string, fs FileStorage) (NoteFormatter, error) {
+func newNoteFormatter(basePath string, template Template, linkFormatter LinkFormatter, env map[string]string, fs FileStorage, vcs VersionControl) (NoteFormatter, error) {
 	termRepl, err := template.Styler().Style("$1", StyleTerm)
 	if err != nil {
 		return nil, err
@@ -49,17 +49,31 @@ func newNoteFormatter(basePath string, template Template, linkFormatter LinkForm
 				})
 				return link
 			}),
-			Lead:       note.Lead,
-			Body:       note.Body,
-			Snippets:   snippets,
-			Tags:       note.Tags,
-			RawContent: note.RawContent,
-			WordCount:  note.WordCount,
-			Metadata:   note.Metadata,
-			Created:    note.Created,
-			Modified:   note.Modified,
-			Checksum:   note.Checksum,
-			Env:        env,
+			Lead:        note.Lead,
+			Summary:     note.Summary,
+			Body:        note.Body,
+			Snippets:    snippets,
+			Score:       note.Score,
+			Distance:    note.Distance,
+			Maturity:    note.Maturity,
+			Tags:        note.Tags,
+			RawContent:  note.RawContent,
+			WordCount:   note.WordCount,
+			ReadingTime: ReadingTime(note.WordCount),
+			Metadata:    note.Metadata,
+			Created:     note.Created,
+			Modified:    note.Modified,
+			Checksum:    note.Checksum,
+			// Computed only when used, as it runs a git command for each
+			// note.
+			GitSHA: newLazyStringer(func() string {
+				if vcs == nil {
+					return ""
+				}
+				sha, _ := vcs.LastCommit(note.Path)
+				return sha
+			}),
+			Env: env,
 		})
 	}, nil
 }
@@ -76,15 +90,21 @@ type noteFormatRenderContext struct {
 	Title        string                 `json:"title"`
 	Link         fmt.Stringer           `json:"link"`
 	Lead         string                 `json:"lead"`
+	Summary      string                 `json:"summary"`
 	Body         string                 `json:"body"`
 	Snippets     []string               `json:"snippets"`
+	Score        float64                `json:"score"`
+	Distance     int                    `json:"distance"`
+	Maturity     float64                `json:"maturity"`
 	RawContent   string                 `json:"rawContent" handlebars:"raw-content"`
 	WordCount    int                    `json:"wordCount" handlebars:"word-count"`
+	ReadingTime  int                    `json:"readingTime" handlebars:"reading-time"`
 	Tags         []string               `json:"tags"`
 	Metadata     map[string]interface{} `json:"metadata"`
 	Created      time.Time              `json:"created"`
 	Modified     time.Time              `json:"modified"`
 	Checksum     string                 `json:"checksum"`
+	GitSHA       fmt.Stringer           `json:"-" handlebars:"git-sha"`
 	Env          map[string]string      `json:"-"`
 }
 
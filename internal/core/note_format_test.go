@@ -32,6 +32,7 @@ func TestNewNoteFormatter(t *testing.T) {
 			Path:       "note1.md",
 			Title:      "Note 1",
 			Lead:       "Lead 1",
+			Summary:    "Summary 1",
 			Body:       "Body 1",
 			RawContent: "Content 1",
 			WordCount:  1,
@@ -55,6 +56,7 @@ func TestNewNoteFormatter(t *testing.T) {
 			Path:       "dir/note2.md",
 			Title:      "Note 2",
 			Lead:       "Lead 2",
+			Summary:    "Summary 2",
 			Body:       "Body 2",
 			RawContent: "Content 2",
 			WordCount:  2,
@@ -79,10 +81,12 @@ func TestNewNoteFormatter(t *testing.T) {
 			Title:        "Note 1",
 			Link:         opt.NewString("[Note 1](note1)"),
 			Lead:         "Lead 1",
+			Summary:      "Summary 1",
 			Body:         "Body 1",
 			Snippets:     []string{"snippet1", "snippet2"},
 			RawContent:   "Content 1",
 			WordCount:    1,
+			ReadingTime:  1,
 			Tags:         []string{"tag1", "tag2"},
 			Metadata: map[string]interface{}{
 				"metadata1": "val1",
@@ -100,10 +104,12 @@ func TestNewNoteFormatter(t *testing.T) {
 			Title:        "Note 2",
 			Link:         opt.NewString("[Note 2](dir/note2)"),
 			Lead:         "Lead 2",
+			Summary:      "Summary 2",
 			Body:         "Body 2",
 			Snippets:     []string{},
 			RawContent:   "Content 2",
 			WordCount:    2,
+			ReadingTime:  1,
 			Tags:         []string{},
 			Metadata:     map[string]interface{}{},
 			Created:      date3,
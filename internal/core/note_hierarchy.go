@@ -0,0 +1,311 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// HierarchyFilter is a note filter used to select the children of other
+// notes, in the NoteHierarchy.
+type HierarchyFilter struct {
+	Paths []string
+	// Selects all the descendants instead of the direct children only.
+	Recursive bool
+}
+
+// NoteHierarchy arranges the notes of a notebook in a tree.
+//
+// The parent of a note is given by its `parent` frontmatter key, as a path
+// or an ID. Otherwise, a note with a Folgezettel ID is the child of the note
+// with the parent ID in the same directory, e.g. 1a2 is the child of 1a.
+type NoteHierarchy struct {
+	notes    map[string]MinimalNote
+	parents  map[string]string
+	children map[string][]string
+	byStem   map[string][]string
+	// Edges from the parents to their children.
+	graph *LinkGraph
+}
+
+// NoteTree is a note with its descendants in the NoteHierarchy.
+type NoteTree struct {
+	Path     string     `json:"path"`
+	Title    string     `json:"title"`
+	Children []NoteTree `json:"children"`
+}
+
+// NoteHierarchy builds the hierarchy of all the notes in the notebook.
+func (n *Notebook) NoteHierarchy() (*NoteHierarchy, error) {
+	notes, err := n.index.FindMinimal(NoteFindOpts{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build the note hierarchy")
+	}
+	return newNoteHierarchy(notes), nil
+}
+
+func newNoteHierarchy(notes []MinimalNote) *NoteHierarchy {
+	h := &NoteHierarchy{
+		notes:    map[string]MinimalNote{},
+		parents:  map[string]string{},
+		children: map[string][]string{},
+		byStem:   map[string][]string{},
+	}
+
+	folgezettels := map[string]string{}
+	for _, note := range notes {
+		h.notes[note.Path] = note
+		stem := paths.FilenameStem(note.Path)
+		h.byStem[stem] = append(h.byStem[stem], note.Path)
+		if id := folgezettelPrefix(stem); id != "" {
+			folgezettels[filepath.Join(filepath.Dir(note.Path), id)] = note.Path
+		}
+	}
+
+	for _, note := range notes {
+		parent := ""
+		// Numeric IDs are parsed as numbers in the YAML frontmatter.
+		if ref := note.Metadata["parent"]; ref != nil && ref != "" {
+			parent = h.resolve(fmt.Sprint(ref), filepath.Dir(note.Path))
+		} else if id := folgezettelPrefix(paths.FilenameStem(note.Path)); id != "" {
+			if parentID := folgezettelParentOf(id); parentID != "" {
+				parent = folgezettels[filepath.Join(filepath.Dir(note.Path), parentID)]
+			}
+		}
+		if parent == "" || parent == note.Path {
+			continue
+		}
+		h.parents[note.Path] = parent
+		h.children[parent] = append(h.children[parent], note.Path)
+	}
+
+	h.graph = NewLinkGraph(nil)
+	for _, note := range notes {
+		children := h.children[note.Path]
+		sort.Slice(children, func(i, j int) bool {
+			return naturalLess(children[i], children[j])
+		})
+		for _, child := range children {
+			h.graph.AddEdge(note.Path, child)
+		}
+	}
+
+	return h
+}
+
+// Find returns the path of the note referenced by the given path or ID.
+func (h *NoteHierarchy) Find(ref string) (string, error) {
+	path := h.resolve(ref, "")
+	if path == "" {
+		return "", fmt.Errorf("%s: note not found", ref)
+	}
+	return path, nil
+}
+
+// resolve returns the path of the note referenced by a path relative to the
+// notebook root, with or without extension, or by an ID. When several notes
+// share the ID, the one in dir is preferred.
+func (h *NoteHierarchy) resolve(ref string, dir string) string {
+	ref = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(ref), "[["), "]]")
+	ref = filepath.Clean(ref)
+
+	if _, ok := h.notes[ref]; ok {
+		return ref
+	}
+	candidates := h.byStem[paths.FilenameStem(ref)]
+	for _, path := range candidates {
+		if paths.DropExt(path) == ref {
+			return path
+		}
+	}
+	if strings.Contains(ref, "/") || len(candidates) == 0 {
+		return ""
+	}
+	for _, path := range candidates {
+		if filepath.Dir(path) == dir {
+			return path
+		}
+	}
+	return candidates[0]
+}
+
+// Parent returns the path of the parent of the given note, or an empty
+// string for a root note.
+func (h *NoteHierarchy) Parent(path string) string {
+	return h.parents[path]
+}
+
+// Children returns the paths of the children of the given note. With
+// recursive, all its descendants are returned, depth first.
+func (h *NoteHierarchy) Children(path string, recursive bool) []string {
+	maxDepth := 1
+	if recursive {
+		maxDepth = 0
+	}
+	return TraversedPaths(h.graph.Traverse(TraverseOpts{
+		Start:      []string{path},
+		MaxDepth:   maxDepth,
+		DepthFirst: true,
+	}))
+}
+
+// Ancestors returns the paths of the ancestors of the given note, starting
+// from its parent.
+//
+// The `parent` frontmatter keys might create a cycle, each ancestor is
+// returned once.
+func (h *NoteHierarchy) Ancestors(path string) []string {
+	return TraversedPaths(h.graph.Traverse(TraverseOpts{
+		Start:     []string{path},
+		Direction: GraphBackward,
+	}))
+}
+
+// Graph returns the graph of the hierarchy, with edges from the parents to
+// their children, sorted in their natural order.
+func (h *NoteHierarchy) Graph() *LinkGraph {
+	return h.graph
+}
+
+// Tree arranges the given notes as a forest, preserving their order.
+//
+// Each note is attached to its closest ancestor among the given notes, or is
+// a root if there is none.
+func (h *NoteHierarchy) Tree(notePaths []string) []NoteTree {
+	included := map[string]bool{}
+	for _, path := range notePaths {
+		included[path] = true
+	}
+
+	roots := []string{}
+	children := map[string][]string{}
+	for _, path := range notePaths {
+		parent := ""
+		for _, ancestor := range h.Ancestors(path) {
+			if included[ancestor] {
+				parent = ancestor
+				break
+			}
+		}
+		if parent == "" {
+			roots = append(roots, path)
+		} else {
+			children[parent] = append(children[parent], path)
+		}
+	}
+
+	var build func(paths []string) []NoteTree
+	build = func(paths []string) []NoteTree {
+		trees := []NoteTree{}
+		for _, path := range paths {
+			trees = append(trees, NoteTree{
+				Path:     path,
+				Title:    h.notes[path].Title,
+				Children: build(children[path]),
+			})
+		}
+		return trees
+	}
+	return build(roots)
+}
+
+// resolveHierarchy converts the hierarchy filters into a list of exact
+// paths.
+func (n *Notebook) resolveHierarchy(opts NoteFindOpts) (NoteFindOpts, error) {
+	if opts.ChildrenOf == nil && len(opts.AncestorsOf) == 0 {
+		return opts, nil
+	}
+
+	hierarchy, err := n.NoteHierarchy()
+	if err != nil {
+		return opts, err
+	}
+
+	matches := map[string]bool{}
+	addMatches := func(refs []string, related func(path string) []string) error {
+		for _, ref := range refs {
+			path, err := hierarchy.Find(ref)
+			if err != nil {
+				return err
+			}
+			for _, match := range related(path) {
+				matches[match] = true
+			}
+		}
+		return nil
+	}
+
+	if opts.ChildrenOf != nil {
+		recursive := opts.ChildrenOf.Recursive
+		err = addMatches(opts.ChildrenOf.Paths, func(path string) []string {
+			return hierarchy.Children(path, recursive)
+		})
+		if err != nil {
+			return opts, err
+		}
+	}
+	err = addMatches(opts.AncestorsOf, hierarchy.Ancestors)
+	if err != nil {
+		return opts, err
+	}
+
+	exactPaths := []string{}
+	if opts.ExactPaths == nil {
+		for path := range matches {
+			exactPaths = append(exactPaths, path)
+		}
+	} else {
+		for _, path := range opts.ExactPaths {
+			if matches[path] {
+				exactPaths = append(exactPaths, path)
+			}
+		}
+	}
+	sort.Strings(exactPaths)
+	opts.ExactPaths = exactPaths
+
+	return opts, nil
+}
+
+// folgezettelParentOf returns the Folgezettel ID of the parent of the given
+// one, by dropping its last run of letters or numbers: 1a2 → 1a, 1 → "".
+func folgezettelParentOf(id string) string {
+	last := rune(id[len(id)-1])
+	isSameKind := unicode.IsLetter
+	if unicode.IsDigit(last) {
+		isSameKind = unicode.IsDigit
+	}
+	return strings.TrimRightFunc(id, isSameKind)
+}
+
+// naturalLess compares two strings, ordering the runs of digits by their
+// numerical value, so that 1a2 comes before 1a10.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		aDigits := leadingRun(a, unicode.IsDigit)
+		bDigits := leadingRun(b, unicode.IsDigit)
+		if aDigits != "" && bDigits != "" {
+			aNum, aErr := strconv.Atoi(aDigits)
+			bNum, bErr := strconv.Atoi(bDigits)
+			if aErr == nil && bErr == nil && aNum != bNum {
+				return aNum < bNum
+			}
+			if aDigits != bDigits {
+				return aDigits < bDigits
+			}
+			a, b = a[len(aDigits):], b[len(bDigits):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
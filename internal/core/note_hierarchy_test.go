@@ -0,0 +1,81 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestNoteHierarchy(t *testing.T) {
+	h := newNoteHierarchy([]MinimalNote{
+		{Path: "1.md", Title: "One"},
+		{Path: "1a.md", Title: "One A"},
+		{Path: "1a1 Details.md"},
+		{Path: "1a10.md"},
+		{Path: "1a2.md"},
+		{Path: "1b.md"},
+		{Path: "2.md"},
+		{Path: "other/1a.md"},
+		{Path: "ideas/draft.md", Metadata: map[string]interface{}{"parent": "1a2"}},
+		{Path: "ideas/more.md", Metadata: map[string]interface{}{"parent": "[[ideas/draft]]"}},
+		{Path: "numeric.md", Metadata: map[string]interface{}{"parent": 2.0}},
+		{Path: "cycle-a.md", Metadata: map[string]interface{}{"parent": "cycle-b.md"}},
+		{Path: "cycle-b.md", Metadata: map[string]interface{}{"parent": "cycle-a"}},
+	})
+
+	assert.Equal(t, h.Parent("1.md"), "")
+	assert.Equal(t, h.Parent("1a.md"), "1.md")
+	assert.Equal(t, h.Parent("1a1 Details.md"), "1a.md")
+	// The Folgezettel parent must be in the same directory.
+	assert.Equal(t, h.Parent("other/1a.md"), "")
+	assert.Equal(t, h.Parent("ideas/draft.md"), "1a2.md")
+	assert.Equal(t, h.Parent("numeric.md"), "2.md")
+
+	assert.Equal(t, h.Children("1.md", false), []string{"1a.md", "1b.md"})
+	assert.Equal(t, h.Children("1a.md", false), []string{"1a1 Details.md", "1a2.md", "1a10.md"})
+	assert.Equal(t, h.Children("1a.md", true), []string{
+		"1a1 Details.md", "1a2.md", "ideas/draft.md", "ideas/more.md", "1a10.md",
+	})
+	assert.Equal(t, h.Children("2.md", true), []string{"numeric.md"})
+
+	assert.Equal(t, h.Ancestors("ideas/more.md"), []string{"ideas/draft.md", "1a2.md", "1a.md", "1.md"})
+	assert.Equal(t, h.Ancestors("1.md"), []string{})
+	assert.Equal(t, h.Ancestors("cycle-a.md"), []string{"cycle-b.md"})
+
+	path, err := h.Find("1a2")
+	assert.Nil(t, err)
+	assert.Equal(t, path, "1a2.md")
+	path, err = h.Find("other/1a")
+	assert.Nil(t, err)
+	assert.Equal(t, path, "other/1a.md")
+	_, err = h.Find("3")
+	assert.Err(t, err, "3: note not found")
+
+	// Intermediate notes missing from the list are skipped.
+	assert.Equal(t, h.Tree([]string{"2.md", "1.md", "1a2.md", "1b.md", "ideas/more.md"}), []NoteTree{
+		{Path: "2.md", Children: []NoteTree{}},
+		{Path: "1.md", Title: "One", Children: []NoteTree{
+			{Path: "1a2.md", Children: []NoteTree{
+				{Path: "ideas/more.md", Children: []NoteTree{}},
+			}},
+			{Path: "1b.md", Children: []NoteTree{}},
+		}},
+	})
+}
+
+func TestFolgezettelParentOf(t *testing.T) {
+	assert.Equal(t, folgezettelParentOf("1"), "")
+	assert.Equal(t, folgezettelParentOf("12"), "")
+	assert.Equal(t, folgezettelParentOf("1a"), "1")
+	assert.Equal(t, folgezettelParentOf("1ab"), "1")
+	assert.Equal(t, folgezettelParentOf("1a21"), "1a")
+}
+
+func TestNaturalLess(t *testing.T) {
+	assert.True(t, naturalLess("1a2", "1a10"))
+	assert.False(t, naturalLess("1a10", "1a2"))
+	assert.True(t, naturalLess("1a", "1a1"))
+	assert.True(t, naturalLess("1a9z", "1b"))
+	assert.True(t, naturalLess("a", "b"))
+	assert.False(t, naturalLess("b", "b"))
+}
@@ -0,0 +1,201 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+)
+
+// Highlight is a passage quoted or highlighted in a literature note.
+type Highlight struct {
+	// Text of the passage, without the Markdown quote or highlight markers.
+	Text string
+	// Line number of the passage in the note, starting from 1.
+	Line int
+}
+
+// ExtractHighlightsOpts holds the options used to extract the highlights of
+// a literature note into new notes.
+type ExtractHighlightsOpts struct {
+	// Path to the literature note.
+	Path string
+	// Directory in which to create the notes, relative to the root of the
+	// notebook.
+	Directory opt.String
+	// Group the new notes belong to.
+	Group opt.String
+	// Path to a custom template used to render the notes.
+	Template opt.String
+	// Extra variables passed to the templates.
+	Extra map[string]string
+	// Creation date provided to the templates.
+	Date time.Time
+	// Command extracting the highlights.
+	Origin AuditOrigin
+}
+
+// FindHighlights returns the literature note at the given path, with the
+// passages quoted or highlighted in its content.
+func (n *Notebook) FindHighlights(path string) (*MinimalNote, []Highlight, error) {
+	wrap := errors.Wrapperf("%s: failed to find the highlights", path)
+
+	note, err := n.indexedNoteAt(path)
+	if err != nil {
+		return nil, nil, wrap(err)
+	}
+	content, err := n.fs.Read(filepath.Join(n.Path, note.Path))
+	if err != nil {
+		return nil, nil, wrap(err)
+	}
+	return note, parseHighlights(string(content)), nil
+}
+
+// ExtractHighlights creates a new note for each passage quoted or highlighted
+// in a literature note, linking back to it.
+//
+// The passage is available to the note template as {{extra.highlight}}, and
+// the link to the literature note as {{extra.source}}. {{content}} holds both
+// the quoted passage and the link.
+func (n *Notebook) ExtractHighlights(opts ExtractHighlightsOpts) ([]*Note, error) {
+	wrap := errors.Wrapperf("%s: failed to extract the highlights", opts.Path)
+
+	source, highlights, err := n.FindHighlights(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(highlights) == 0 {
+		return nil, wrap(errors.New("no quoted or highlighted passage found"))
+	}
+
+	dir, err := n.RequireDirAt(opts.Directory.OrString(n.Path).Unwrap())
+	if err != nil {
+		return nil, wrap(err)
+	}
+	linkFormatter, err := n.NewLinkFormatter()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	linkContext, err := NewLinkFormatterContext(*source, n.Path, dir.Path)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	link, err := linkFormatter(linkContext)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	notesOpts := []NewNoteOpts{}
+	for _, highlight := range highlights {
+		extra := map[string]string{}
+		for k, v := range opts.Extra {
+			extra[k] = v
+		}
+		extra["highlight"] = highlight.Text
+		extra["source"] = link
+		extra["source-path"] = source.Path
+		extra["source-title"] = source.Title
+
+		notesOpts = append(notesOpts, NewNoteOpts{
+			Title:     opt.NewNotEmptyString(highlightTitle(highlight.Text)),
+			Content:   fmt.Sprintf("> %s\n\n%s\n", strings.ReplaceAll(highlight.Text, "\n", "\n> "), link),
+			Directory: opt.NewString(dir.Path),
+			Group:     opts.Group,
+			Template:  opts.Template,
+			Extra:     extra,
+			Date:      opts.Date,
+			Origin:    opts.Origin,
+		})
+	}
+
+	notes, err := n.NewNotes(notesOpts)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	return notes, nil
+}
+
+var (
+	highlightMarkRegex = regexp.MustCompile(`==([^=\s](?:[^\n]*?[^=\s])?)==`)
+	quoteLineRegex     = regexp.MustCompile(`^ {0,3}>[ \t]?(.*)$`)
+	codeFenceRegex     = regexp.MustCompile("^ {0,3}(```|~~~)")
+)
+
+// parseHighlights finds the passages of a note content which are either in a
+// blockquote or marked with ==highlight==. Each blockquote is a single
+// passage. The frontmatter and code blocks are ignored.
+func parseHighlights(content string) []Highlight {
+	// Blanks the frontmatter while keeping the line numbers.
+	if loc := frontmatterRegex.FindStringIndex(content); loc != nil {
+		content = strings.Repeat("\n", strings.Count(content[:loc[1]], "\n")) + content[loc[1]:]
+	}
+
+	highlights := []Highlight{}
+	var quote []string
+	quoteLine := 0
+	endQuote := func() {
+		text := strings.TrimSpace(strings.Join(quote, "\n"))
+		if text != "" {
+			highlights = append(highlights, Highlight{Text: text, Line: quoteLine})
+		}
+		quote = nil
+	}
+
+	fence := ""
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if matches := codeFenceRegex.FindStringSubmatch(line); matches != nil {
+			if fence == "" {
+				fence = matches[1]
+			} else if fence == matches[1] {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		if matches := quoteLineRegex.FindStringSubmatch(line); matches != nil {
+			if quote == nil {
+				quoteLine = i + 1
+			}
+			quote = append(quote, matches[1])
+			continue
+		}
+		if quote != nil {
+			endQuote()
+		}
+
+		for _, matches := range highlightMarkRegex.FindAllStringSubmatch(line, -1) {
+			highlights = append(highlights, Highlight{Text: matches[1], Line: i + 1})
+		}
+	}
+	if quote != nil {
+		endQuote()
+	}
+
+	return highlights
+}
+
+// highlightTitle generates the title of the note created for a highlight,
+// from its first words.
+func highlightTitle(text string) string {
+	maxLength := 50
+	title := ""
+	for _, word := range strings.Fields(text) {
+		if title != "" && len(title)+1+len(word) > maxLength {
+			return title + "…"
+		}
+		if title != "" {
+			title += " "
+		}
+		title += word
+	}
+	return title
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestParseHighlights(t *testing.T) {
+	test := func(content string, expected []Highlight) {
+		assert.Equal(t, parseHighlights(content), expected)
+	}
+
+	test("", []Highlight{})
+	test("# Title\n\nNo highlight == here.", []Highlight{})
+
+	test(`---
+quote: > not a quote
+---
+# Literature note
+
+Some ==highlighted passage== and ==another one==.
+
+> A quoted passage
+> on two lines.
+
+>
+
+  > Another quote
+Paragraph
+`, []Highlight{
+		{Text: "highlighted passage", Line: 6},
+		{Text: "another one", Line: 6},
+		{Text: "A quoted passage\non two lines.", Line: 8},
+		{Text: "Another quote", Line: 13},
+	})
+
+	test("```\n> Quoted code\n==code==\n```\n~~~\n```\n> Still code\n~~~\n> Quote\n", []Highlight{
+		{Text: "Quote", Line: 9},
+	})
+
+	// Highlights in a quote are part of the quote.
+	test("> Quote with ==highlight==", []Highlight{
+		{Text: "Quote with ==highlight==", Line: 1},
+	})
+}
+
+func TestHighlightTitle(t *testing.T) {
+	test := func(text, expected string) {
+		assert.Equal(t, highlightTitle(text), expected)
+	}
+
+	test("", "")
+	test("A short passage", "A short passage")
+	test("A passage\non two lines", "A passage on two lines")
+	test("The quick brown fox jumps over the lazy dog, again and again", "The quick brown fox jumps over the lazy dog, again…")
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// NoteHistoryAction is an action recorded in the history of the recently
+// touched notes.
+type NoteHistoryAction string
+
+const (
+	// The note was created, e.g. with `zk new`.
+	NoteHistoryCreated NoteHistoryAction = "created"
+	// The note was opened in an editor, e.g. with `zk edit` or by the LSP
+	// client.
+	NoteHistoryOpened NoteHistoryAction = "opened"
+)
+
+// NoteHistoryEntry is the last action done on a note of the history.
+type NoteHistoryEntry struct {
+	// Path of the note, relative to the notebook root.
+	Path string `json:"path"`
+	// Title of the note.
+	Title string `json:"title"`
+	// Last action done on the note.
+	Action NoteHistoryAction `json:"action"`
+	// Date of the last action.
+	Date time.Time `json:"date"`
+}
+
+// RecordHistory adds the notes at the given paths to the history of the
+// recently touched notes. The paths are absolute or relative to the
+// notebook root, and the ones which are not indexed are ignored.
+func (n *Notebook) RecordHistory(action NoteHistoryAction, paths ...string) error {
+	wrap := errors.Wrapper("failed to record the history of the notes")
+
+	// The history is not part of the indexed notes, so it doesn't bump the
+	// index revision.
+	date := time.Now()
+	return wrap(n.index.Commit(func(index NoteIndex) error {
+		for _, path := range paths {
+			path, err := n.RelPath(path)
+			if err != nil {
+				return err
+			}
+			err = index.AddHistory(path, action, date)
+			var notFound ErrNoteNotFound
+			if err != nil && !errors.As(err, &notFound) {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// FindHistory returns the notes recently created or opened, the most recent
+// first. All of them are returned when limit is 0.
+func (n *Notebook) FindHistory(limit int) ([]NoteHistoryEntry, error) {
+	entries, err := n.index.FindHistory(limit)
+	return entries, errors.Wrap(err, "failed to find the history of the notes")
+}
@@ -0,0 +1,216 @@
+package core
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// noteIDGenerator returns the function generating the ID of a new note, and
+// whether the IDs need to be reserved against concurrent processes.
+//
+// The random and timestamp-based IDs are generated by the
+// IDGeneratorFactory port, while the ones derived from the notebook or the
+// note itself are generated here.
+func (n *Notebook) noteIDGenerator(index NoteIndex, dir Dir, options IDOptions, title string, opts NewNoteOpts) (IDGenerator, bool, error) {
+	parent := opts.Parent.Unwrap()
+	if parent != "" && options.Strategy != IDStrategyFolgezettel {
+		return nil, false, fmt.Errorf("%s: a parent note requires the folgezettel ID strategy", parent)
+	}
+
+	switch options.Strategy {
+	case IDStrategyFolgezettel:
+		parentID, err := n.folgezettelParentID(parent)
+		if err != nil {
+			return nil, false, err
+		}
+		taken, err := folgezettelIDsIn(index, dir)
+		if err != nil {
+			return nil, false, err
+		}
+		return newFolgezettelIDGenerator(parentID, taken), true, nil
+
+	case IDStrategyHash:
+		// Reserving the ID would prevent importing the same note again,
+		// instead of reporting that it already exists.
+		return newHashIDGenerator(options, title+"\n"+opts.Content), false, nil
+
+	default:
+		return n.idGeneratorFactory(options), true, nil
+	}
+}
+
+// folgezettelParentID returns the Folgezettel ID of the given parent note,
+// which is either a path to the note file or the ID itself.
+func (n *Notebook) folgezettelParentID(parent string) (string, error) {
+	if parent == "" {
+		return "", nil
+	}
+
+	path, err := n.fs.Abs(parent)
+	if err != nil {
+		return "", err
+	}
+	exists, err := n.fs.FileExists(path)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		id := folgezettelPrefix(paths.FilenameStem(path))
+		if id == "" {
+			return "", fmt.Errorf("%s: the parent note has no Folgezettel ID", parent)
+		}
+		return id, nil
+	}
+
+	if !isFolgezettelID(parent) {
+		return "", fmt.Errorf("%s: parent note not found", parent)
+	}
+	return parent, nil
+}
+
+// folgezettelIDsIn returns the Folgezettel IDs prefixing the filenames of
+// the notes indexed in the given directory.
+func folgezettelIDsIn(index NoteIndex, dir Dir) ([]string, error) {
+	metadata, err := index.IndexedPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	dirName := filepath.Clean(dir.Name)
+	for file := range metadata {
+		if filepath.Dir(file.Path) != dirName {
+			continue
+		}
+		if id := folgezettelPrefix(paths.FilenameStem(file.Path)); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// folgezettelRegex matches a Folgezettel ID, alternating numbers and
+// letters, e.g. 21a3b.
+var folgezettelRegex = regexp.MustCompile(`^[0-9]+(?:[a-z]+[0-9]+)*[a-z]*`)
+
+// folgezettelPrefix returns the Folgezettel ID prefixing a filename stem,
+// e.g. 1a2 for "1a2 A title".
+func folgezettelPrefix(stem string) string {
+	id := folgezettelRegex.FindString(stem)
+	if id == "" || len(id) == len(stem) {
+		return id
+	}
+	next := rune(stem[len(id)])
+	if unicode.IsLetter(next) || unicode.IsDigit(next) {
+		return ""
+	}
+	return id
+}
+
+func isFolgezettelID(id string) bool {
+	return id != "" && folgezettelRegex.FindString(id) == id
+}
+
+// newFolgezettelIDGenerator returns a function generating the IDs of the
+// next children of the parent ID, after the ones already taken.
+//
+// The children of a top-level or letter-ending ID are numbered (1a → 1a1),
+// while the ones of a number-ending ID are lettered (1 → 1a, 1z → 1aa).
+func newFolgezettelIDGenerator(parent string, taken []string) IDGenerator {
+	lettered := parent != "" && unicode.IsDigit(rune(parent[len(parent)-1]))
+
+	last := 0
+	for _, id := range taken {
+		if !strings.HasPrefix(id, parent) {
+			continue
+		}
+		var index int
+		if lettered {
+			index = lettersToIndex(leadingRun(id[len(parent):], unicode.IsLetter))
+		} else {
+			index, _ = strconv.Atoi(leadingRun(id[len(parent):], unicode.IsDigit))
+		}
+		if index > last {
+			last = index
+		}
+	}
+
+	return func() string {
+		last++
+		if lettered {
+			return parent + indexToLetters(last)
+		}
+		return parent + strconv.Itoa(last)
+	}
+}
+
+// leadingRun returns the prefix of s made of the characters matching the
+// given predicate.
+func leadingRun(s string, predicate func(rune) bool) string {
+	end := strings.IndexFunc(s, func(r rune) bool { return !predicate(r) })
+	if end == -1 {
+		return s
+	}
+	return s[:end]
+}
+
+// indexToLetters converts a 1-based index to a sequence of letters:
+// 1 → a, 26 → z, 27 → aa.
+func indexToLetters(index int) string {
+	letters := ""
+	for index > 0 {
+		index--
+		letters = string(rune('a'+index%26)) + letters
+		index /= 26
+	}
+	return letters
+}
+
+// lettersToIndex is the reverse of indexToLetters.
+func lettersToIndex(letters string) int {
+	index := 0
+	for _, letter := range letters {
+		index = index*26 + int(letter-'a') + 1
+	}
+	return index
+}
+
+// newHashIDGenerator returns a function generating an ID derived from the
+// hash of the given data, using the charset, case and length options.
+func newHashIDGenerator(options IDOptions, data string) IDGenerator {
+	var charset []rune
+	for _, char := range options.Charset {
+		switch options.Case {
+		case CaseUpper:
+			charset = append(charset, unicode.ToUpper(char))
+		case CaseMixed:
+			charset = append(charset, unicode.ToLower(char), unicode.ToUpper(char))
+		default:
+			charset = append(charset, unicode.ToLower(char))
+		}
+	}
+
+	sum := sha256.Sum256([]byte(data))
+	n := new(big.Int).SetBytes(sum[:])
+	base := big.NewInt(int64(len(charset)))
+	digit := new(big.Int)
+
+	buf := make([]rune, options.Length)
+	for i := range buf {
+		n.DivMod(n, base, digit)
+		buf[i] = charset[digit.Int64()]
+	}
+	id := string(buf)
+
+	return func() string {
+		return id
+	}
+}
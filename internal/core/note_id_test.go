@@ -0,0 +1,80 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestFolgezettelPrefix(t *testing.T) {
+	test := func(stem string, expected string) {
+		assert.Equal(t, folgezettelPrefix(stem), expected)
+	}
+
+	test("", "")
+	test("1", "1")
+	test("21a3b", "21a3b")
+	test("1a2 A title", "1a2")
+	test("1a2-a-title", "1a2")
+	test("a title", "")
+	test("4ufx", "4ufx")
+	test("1aB", "")
+	test("2021-10-12", "2021")
+}
+
+func TestFolgezettelIDGenerator(t *testing.T) {
+	test := func(parent string, taken []string, expected ...string) {
+		gen := newFolgezettelIDGenerator(parent, taken)
+		for _, id := range expected {
+			assert.Equal(t, gen(), id)
+		}
+	}
+
+	// Top-level notes
+	test("", []string{}, "1", "2")
+	test("", []string{"1", "3a", "2b4", "12c"}, "13", "14")
+	// Children of a number-ending ID
+	test("1", []string{}, "1a", "1b")
+	test("1", []string{"1", "1a", "1c2", "12", "2d"}, "1d")
+	test("1", []string{"1y"}, "1z", "1aa", "1ab")
+	test("1", []string{"1az3"}, "1ba")
+	// Children of a letter-ending ID
+	test("1a", []string{}, "1a1", "1a2")
+	test("1a", []string{"1a", "1a1", "1a9b", "1b3", "1aa4"}, "1a10")
+}
+
+func TestIndexToLetters(t *testing.T) {
+	test := func(index int, expected string) {
+		assert.Equal(t, indexToLetters(index), expected)
+		assert.Equal(t, lettersToIndex(expected), index)
+	}
+
+	test(1, "a")
+	test(26, "z")
+	test(27, "aa")
+	test(52, "az")
+	test(53, "ba")
+	test(702, "zz")
+	test(703, "aaa")
+}
+
+func TestHashIDGenerator(t *testing.T) {
+	options := IDOptions{
+		Strategy: IDStrategyHash,
+		Length:   8,
+		Charset:  CharsetHex,
+		Case:     CaseLower,
+	}
+	gen := newHashIDGenerator(options, "Title\nContent")
+	id := gen()
+	assert.Equal(t, len(id), 8)
+	// The ID is stable.
+	assert.Equal(t, gen(), id)
+	assert.Equal(t, newHashIDGenerator(options, "Title\nContent")(), id)
+	// And depends on the data.
+	assert.NotEqual(t, newHashIDGenerator(options, "Title\nOther content")(), id)
+
+	options.Case = CaseUpper
+	options.Charset = CharsetLetters
+	assert.Equal(t, newHashIDGenerator(options, "Title\nContent")(), "UCSWGJDB")
+}
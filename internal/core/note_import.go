@@ -0,0 +1,594 @@
+package core
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/paths"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// ImportSource holds the notes and assets read from another note-taking
+// app, e.g. an Obsidian vault.
+type ImportSource struct {
+	Notes  []ImportedNote
+	Assets []ImportedAsset
+}
+
+// ImportedNote is a note read from an ImportSource.
+type ImportedNote struct {
+	// Path of the note in the source, relative to its root. It is used to
+	// preserve the directory structure and resolve the links.
+	Path    string
+	Title   string
+	Content string
+	// Extra variables passed to the note templates, e.g. from the source
+	// frontmatter.
+	Extra map[string]string
+	// Creation date provided to the templates.
+	Created time.Time
+}
+
+// ImportedAsset is a file which is not a note, read from an ImportSource.
+type ImportedAsset struct {
+	// Path of the asset in the source, relative to its root.
+	Path    string
+	Content []byte
+}
+
+// ImportOpts holds the options used to import notes in a Notebook.
+type ImportOpts struct {
+	// Directory in which the notes and assets are imported.
+	Directory opt.String
+	// Policy applied to the imported notes which are identical or
+	// near-identical to existing notes. Defaults to ImportDuplicatesKeep.
+	Duplicates ImportDuplicates
+	// Command importing the notes.
+	Origin AuditOrigin
+}
+
+// ImportDuplicates is the policy applied to the imported notes duplicating
+// notes already in the notebook, e.g. when importing the same vault twice.
+type ImportDuplicates string
+
+const (
+	// ImportDuplicatesKeep imports the duplicate notes anyway.
+	ImportDuplicatesKeep ImportDuplicates = "keep"
+	// ImportDuplicatesSkip ignores the duplicate notes. The imported links to
+	// them target the existing notes instead.
+	ImportDuplicatesSkip ImportDuplicates = "skip"
+	// ImportDuplicatesMerge appends the paragraphs of a near-identical note
+	// which are missing from the existing note. Identical notes are skipped.
+	ImportDuplicatesMerge ImportDuplicates = "merge"
+)
+
+// importSimilarityThreshold is the minimum similarity between an imported
+// note and an existing one to consider them duplicates.
+const importSimilarityThreshold = 0.8
+
+// ImportReport lists the files created during an import.
+type ImportReport struct {
+	Notes  []ImportedFile
+	Assets []ImportedFile
+	// Links which could not be resolved to an imported note or asset.
+	UnresolvedLinks []UnresolvedLink
+	// Imported notes duplicating existing notes.
+	Duplicates []ImportDuplicate
+}
+
+// ImportDuplicate is an imported note identical or near-identical to a note
+// of the notebook.
+type ImportDuplicate struct {
+	SourcePath string
+	// Path of the existing note, relative to the notebook root.
+	Path string
+	// Similarity between the content of both notes, from 0 to 1 when they
+	// are identical.
+	Similarity float64
+	// Action taken on the imported note.
+	Action ImportDuplicates
+}
+
+// ImportedFile maps the path of a file in an ImportSource to its path in
+// the notebook.
+type ImportedFile struct {
+	SourcePath string
+	// Path relative to the notebook root.
+	Path string
+}
+
+// Import creates new notes from the ones found in the given source, using
+// the notebook config to generate their filenames and content. Links between
+// the imported notes are rewritten with the configured link format.
+//
+// Notes identical or near-identical to existing notes are handled according
+// to the ImportDuplicates policy.
+//
+// If any of the notes can't be created, the files already imported are
+// removed and the merged notes are restored.
+func (n *Notebook) Import(source ImportSource, opts ImportOpts) (*ImportReport, error) {
+	wrap := errors.Wrapper("import failed")
+
+	if opts.Duplicates == "" {
+		opts.Duplicates = ImportDuplicatesKeep
+	}
+
+	dir, err := n.RequireDirAt(opts.Directory.OrString(n.Path).Unwrap())
+	if err != nil {
+		return nil, wrap(err)
+	}
+	linkFormatter, err := n.NewLinkFormatter()
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	report := ImportReport{
+		Notes:           []ImportedFile{},
+		Assets:          []ImportedFile{},
+		UnresolvedLinks: []UnresolvedLink{},
+		Duplicates:      []ImportDuplicate{},
+	}
+	targets := newImportTargets()
+	createdPaths := []string{}
+	// Original content of the notes modified by a merge.
+	mergedContents := map[string][]byte{}
+
+	err = n.commitIndex(func(index NoteIndex) error {
+		existingNotes, err := index.Find(NoteFindOpts{})
+		if err != nil {
+			return err
+		}
+		duplicates := newDuplicateFinder(existingNotes)
+
+		for _, asset := range source.Assets {
+			sourcePath := filepath.FromSlash(asset.Path)
+			absPath, err := n.freeAttachmentPath(filepath.Join(dir.Path, filepath.Dir(sourcePath)), filepath.Base(sourcePath))
+			if err != nil {
+				return err
+			}
+			err = n.fs.Write(absPath, asset.Content)
+			if err != nil {
+				return err
+			}
+			createdPaths = append(createdPaths, absPath)
+
+			path, err := n.RelPath(absPath)
+			if err != nil {
+				return err
+			}
+			targets.addAsset(asset.Path, path)
+			report.Assets = append(report.Assets, ImportedFile{SourcePath: asset.Path, Path: path})
+		}
+
+		notes := []*Note{}
+		sourceNotes := []ImportedNote{}
+		merges := []importMerge{}
+		for _, imported := range source.Notes {
+			if existing, similarity := duplicates.find(imported); existing != nil {
+				action := opts.Duplicates
+				if action == ImportDuplicatesMerge && similarity == 1 {
+					action = ImportDuplicatesSkip
+				}
+				report.Duplicates = append(report.Duplicates, ImportDuplicate{
+					SourcePath: imported.Path,
+					Path:       existing.Path,
+					Similarity: similarity,
+					Action:     action,
+				})
+
+				if action != ImportDuplicatesKeep {
+					targets.addNote(imported.Path, existing.AsMinimalNote())
+					if action == ImportDuplicatesMerge {
+						merges = append(merges, importMerge{source: imported, note: existing.Note})
+					}
+					continue
+				}
+			}
+
+			noteDir := filepath.Join(dir.Path, filepath.Dir(filepath.FromSlash(imported.Path)))
+			err := n.fs.CreateDir(noteDir)
+			if err != nil {
+				return err
+			}
+			date := imported.Created
+			if date.IsZero() {
+				date = time.Now()
+			}
+
+			note, err := n.newNote(index, NewNoteOpts{
+				Title:     opt.NewNotEmptyString(imported.Title),
+				Content:   imported.Content,
+				Directory: opt.NewString(noteDir),
+				Extra:     imported.Extra,
+				Date:      date,
+			})
+			if err != nil {
+				return errors.Wrap(err, imported.Path)
+			}
+			createdPaths = append(createdPaths, filepath.Join(n.Path, note.Path))
+
+			notes = append(notes, note)
+			sourceNotes = append(sourceNotes, imported)
+			targets.addNote(imported.Path, note.AsMinimalNote())
+			report.Notes = append(report.Notes, ImportedFile{SourcePath: imported.Path, Path: note.Path})
+		}
+
+		// The links can be rewritten only once all the notes are created,
+		// to know their paths.
+		for i, note := range notes {
+			absPath := filepath.Join(n.Path, note.Path)
+			content, err := n.fs.Read(absPath)
+			if err != nil {
+				return err
+			}
+			newContent, unresolved, err := targets.rewriteLinks(string(content), sourceNotes[i].Path, absPath, n.Path, linkFormatter)
+			if err != nil {
+				return err
+			}
+			for _, href := range unresolved {
+				report.UnresolvedLinks = append(report.UnresolvedLinks, UnresolvedLink{SourcePath: note.Path, Href: href})
+			}
+			if newContent == string(content) {
+				continue
+			}
+
+			err = n.fs.Write(absPath, []byte(newContent))
+			if err != nil {
+				return err
+			}
+			parsedNote, err := n.ParseNoteAt(absPath)
+			if err != nil {
+				return err
+			}
+			err = index.Update(*parsedNote)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, merge := range merges {
+			paragraphs := missingParagraphs(merge.source.Content, merge.note.Body)
+			if len(paragraphs) == 0 {
+				continue
+			}
+
+			absPath := filepath.Join(n.Path, merge.note.Path)
+			content, err := n.fs.Read(absPath)
+			if err != nil {
+				return err
+			}
+			addition, unresolved, err := targets.rewriteLinks(strings.Join(paragraphs, "\n\n"), merge.source.Path, absPath, n.Path, linkFormatter)
+			if err != nil {
+				return err
+			}
+			for _, href := range unresolved {
+				report.UnresolvedLinks = append(report.UnresolvedLinks, UnresolvedLink{SourcePath: merge.note.Path, Href: href})
+			}
+
+			if _, ok := mergedContents[absPath]; !ok {
+				mergedContents[absPath] = content
+			}
+			newContent := strings.TrimRight(string(content), "\n") + "\n\n" + addition + "\n"
+			err = n.fs.Write(absPath, []byte(newContent))
+			if err != nil {
+				return err
+			}
+			parsedNote, err := n.ParseNoteAt(absPath)
+			if err != nil {
+				return err
+			}
+			err = index.Update(*parsedNote)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		for _, path := range createdPaths {
+			n.logger.Err(n.fs.Remove(path))
+		}
+		for path, content := range mergedContents {
+			n.logger.Err(n.fs.Write(path, content))
+		}
+		return nil, wrap(err)
+	}
+
+	n.audit(opts.Origin, AuditOperationImport,
+		fmt.Sprintf("%d %s and %d %s",
+			len(report.Notes), strutil.Pluralize("note", len(report.Notes)),
+			len(report.Assets), strutil.Pluralize("asset", len(report.Assets)),
+		),
+		dir.Name,
+	)
+	return &report, nil
+}
+
+// importTargets resolves the links found in imported notes to the files
+// created in the notebook.
+type importTargets struct {
+	// Imported notes indexed by their lowercase source path, with and
+	// without extension, and their filename stem.
+	notes map[string]MinimalNote
+	// Path of the imported assets, indexed by their lowercase source path
+	// and filename.
+	assets map[string]string
+}
+
+func newImportTargets() *importTargets {
+	return &importTargets{
+		notes:  map[string]MinimalNote{},
+		assets: map[string]string{},
+	}
+}
+
+func importKey(path string) string {
+	return strings.ToLower(filepath.ToSlash(filepath.Clean(path)))
+}
+
+func (t *importTargets) addNote(sourcePath string, note MinimalNote) {
+	t.notes[importKey(sourcePath)] = note
+	t.notes[importKey(paths.DropExt(sourcePath))] = note
+	// Obsidian links can target a note with only its filename, when it is
+	// unique in the vault.
+	stem := importKey(paths.FilenameStem(sourcePath))
+	if _, ok := t.notes[stem]; !ok {
+		t.notes[stem] = note
+	}
+}
+
+func (t *importTargets) addAsset(sourcePath string, path string) {
+	t.assets[importKey(sourcePath)] = path
+	filename := importKey(filepath.Base(sourcePath))
+	if _, ok := t.assets[filename]; !ok {
+		t.assets[filename] = path
+	}
+}
+
+// resolve finds the imported note or asset targeted by the given href, found
+// in the note at sourcePath.
+func (t *importTargets) resolve(href string, sourcePath string) (note *MinimalNote, assetPath string) {
+	candidates := []string{
+		filepath.Join(filepath.Dir(sourcePath), href),
+		href,
+	}
+	if !strings.Contains(href, "/") {
+		candidates = append(candidates, filepath.Base(href))
+	}
+	for _, candidate := range candidates {
+		key := importKey(candidate)
+		if note, ok := t.notes[key]; ok {
+			return &note, ""
+		}
+		if path, ok := t.assets[key]; ok {
+			return nil, path
+		}
+	}
+	return nil, ""
+}
+
+// importLinkRegex matches wiki-links (with optional anchors and labels) and
+// Markdown links, which are possibly embedded with a `!` prefix.
+var importLinkRegex = regexp.MustCompile(`(!?)(?:\[\[([^\]\[|#\n]*)(#[^\]|\n]*)?(?:\|([^\]\n]*))?\]\]|\[([^\]\n]*)\]\(\s*<?([^)>\s]*)>?\s*\))`)
+
+// rewriteLinks replaces the links to the imported notes and assets in the
+// content of the note created at absPath, from the note at sourcePath.
+// Returns the hrefs which could not be resolved.
+func (t *importTargets) rewriteLinks(content string, sourcePath string, absPath string, notebookDir string, formatter LinkFormatter) (string, []string, error) {
+	unresolved := []string{}
+	noteDir := filepath.Dir(absPath)
+
+	res := strings.Builder{}
+	last := 0
+	for _, match := range importLinkRegex.FindAllStringSubmatchIndex(content, -1) {
+		group := func(i int) string {
+			if match[2*i] < 0 {
+				return ""
+			}
+			return content[match[2*i]:match[2*i+1]]
+		}
+		isEmbed := group(1) == "!"
+		isWikiLink := match[4] >= 0
+		href := strings.TrimSpace(group(2))
+		label := strings.TrimSpace(group(4))
+		if !isWikiLink {
+			label = group(5)
+			href = strings.SplitN(group(6), "#", 2)[0]
+			if strutil.IsURL(href) || href == "" {
+				continue
+			}
+			if unescaped, err := url.PathUnescape(href); err == nil {
+				href = unescaped
+			}
+		}
+		if href == "" {
+			continue
+		}
+
+		var link string
+		note, assetPath := t.resolve(href, sourcePath)
+		switch {
+		case note != nil:
+			context, err := NewLinkFormatterContext(*note, notebookDir, noteDir)
+			if err != nil {
+				return "", nil, err
+			}
+			if label != "" {
+				context.Title = label
+			}
+			link, err = formatter(context)
+			if err != nil {
+				return "", nil, err
+			}
+
+		case assetPath != "":
+			relPath, err := filepath.Rel(noteDir, filepath.Join(notebookDir, assetPath))
+			if err != nil {
+				return "", nil, err
+			}
+			isImage := IsImagePath(assetPath)
+			if isWikiLink {
+				// Wiki-link labels of embedded images are their size in
+				// Obsidian.
+				if isEmbed && isImage {
+					label = ""
+				} else if label == "" {
+					label = filepath.Base(assetPath)
+				}
+			}
+			link = fmt.Sprintf("[%s](%s)", label, joinHref(relPath, "", true))
+			if isEmbed && (isImage || !isWikiLink) {
+				link = "!" + link
+			}
+
+		default:
+			unresolved = append(unresolved, href)
+			continue
+		}
+
+		res.WriteString(content[last:match[0]])
+		res.WriteString(link)
+		last = match[1]
+	}
+	res.WriteString(content[last:])
+
+	return res.String(), unresolved, nil
+}
+
+// importMerge is an imported note to merge into an existing note.
+type importMerge struct {
+	source ImportedNote
+	note   Note
+}
+
+// duplicateFinder finds the existing notes duplicated by imported notes.
+type duplicateFinder struct {
+	notes        []ContextualNote
+	fingerprints []noteFingerprint
+	byHash       map[string]int
+}
+
+func newDuplicateFinder(notes []ContextualNote) *duplicateFinder {
+	finder := &duplicateFinder{
+		notes:        notes,
+		fingerprints: []noteFingerprint{},
+		byHash:       map[string]int{},
+	}
+	for i, note := range notes {
+		fingerprint := newNoteFingerprint(note.Title + "\n" + note.Body)
+		finder.fingerprints = append(finder.fingerprints, fingerprint)
+		if _, ok := finder.byHash[fingerprint.hash]; !ok {
+			finder.byHash[fingerprint.hash] = i
+		}
+	}
+	return finder
+}
+
+// find returns the existing note most similar to the imported one, with
+// their similarity, or nil if there is no duplicate.
+func (f *duplicateFinder) find(imported ImportedNote) (*ContextualNote, float64) {
+	fingerprint := newNoteFingerprint(imported.Title + "\n" + imported.Content)
+	// Empty notes are not duplicates of each other.
+	if len(fingerprint.shingles) == 0 {
+		return nil, 0
+	}
+	if i, ok := f.byHash[fingerprint.hash]; ok {
+		return &f.notes[i], 1
+	}
+
+	best := -1
+	bestSimilarity := 0.0
+	for i, other := range f.fingerprints {
+		if similarity := fingerprint.similarity(other); similarity > bestSimilarity {
+			best = i
+			bestSimilarity = similarity
+		}
+	}
+	if best < 0 || bestSimilarity < importSimilarityThreshold {
+		return nil, 0
+	}
+	return &f.notes[best], bestSimilarity
+}
+
+// noteFingerprint summarizes the content of a note to compare it with
+// others, ignoring its formatting and links which are rewritten during an
+// import.
+type noteFingerprint struct {
+	hash string
+	// Sequences of three consecutive words found in the content.
+	shingles map[string]bool
+}
+
+func newNoteFingerprint(content string) noteFingerprint {
+	words := normalizedWords(content)
+	fingerprint := noteFingerprint{
+		hash:     fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(words, " ")))),
+		shingles: map[string]bool{},
+	}
+	if len(words) > 0 && len(words) < 3 {
+		fingerprint.shingles[strings.Join(words, " ")] = true
+	}
+	for i := 0; i+3 <= len(words); i++ {
+		fingerprint.shingles[strings.Join(words[i:i+3], " ")] = true
+	}
+	return fingerprint
+}
+
+// similarity returns the Jaccard index of the shingles of both fingerprints,
+// from 0 to 1 when they are identical.
+func (f noteFingerprint) similarity(other noteFingerprint) float64 {
+	if f.hash == other.hash {
+		return 1
+	}
+	shared := 0
+	for shingle := range f.shingles {
+		if other.shingles[shingle] {
+			shared++
+		}
+	}
+	total := len(f.shingles) + len(other.shingles) - shared
+	if total == 0 {
+		return 0
+	}
+	return float64(shared) / float64(total)
+}
+
+// normalizedWords returns the lowercase words of the given Markdown content,
+// without its links.
+func normalizedWords(content string) []string {
+	content = importLinkRegex.ReplaceAllString(content, " ")
+	return strings.FieldsFunc(strings.ToLower(content), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+var paragraphSeparatorRegex = regexp.MustCompile(`\n\s*\n`)
+
+// missingParagraphs returns the paragraphs of content which are not found in
+// the existing body, ignoring their formatting and links.
+func missingParagraphs(content string, body string) []string {
+	existing := map[string]bool{}
+	for _, paragraph := range paragraphSeparatorRegex.Split(body, -1) {
+		existing[strings.Join(normalizedWords(paragraph), " ")] = true
+	}
+
+	missing := []string{}
+	for _, paragraph := range paragraphSeparatorRegex.Split(content, -1) {
+		key := strings.Join(normalizedWords(paragraph), " ")
+		if key == "" || existing[key] {
+			continue
+		}
+		existing[key] = true
+		missing = append(missing, strings.TrimSpace(paragraph))
+	}
+	return missing
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func newImportTest() *newNoteTest {
+	test := &newNoteTest{
+		rootDir: "/notebook",
+		dirs:    []string{"/notebook/vault"},
+		filenameTemplateRender: func(context newNoteTemplateContext) string {
+			return strings.ToLower(strings.ReplaceAll(context.Title, " ", "-")) + ".ext"
+		},
+		idGeneratorFactory: incrementingID,
+	}
+	test.setup()
+	test.templateLoader.fileTemplates["default"] = newTemplateSpy(func(context interface{}) string {
+		return context.(newNoteTemplateContext).Content
+	})
+	return test
+}
+
+func TestNotebookImport(t *testing.T) {
+	test := newImportTest()
+
+	report, err := test.notebook().Import(ImportSource{
+		Notes: []ImportedNote{
+			{
+				Path:    "Index.md",
+				Title:   "Index",
+				Content: "See [[Daily/Meeting notes|the meeting]] and [[Unknown]].\n![[diagram.png|300]] ![[doc.pdf]]\n",
+				Created: now,
+			},
+			{
+				Path:    "Daily/Meeting notes.md",
+				Title:   "Meeting notes",
+				Content: "Back to [the index](../Index.md#top), [Google](https://google.com).\n![Diagram](../assets/diagram.png)\n",
+				Created: now,
+			},
+		},
+		Assets: []ImportedAsset{
+			{Path: "assets/diagram.png", Content: []byte("PNG")},
+			{Path: "doc.pdf", Content: []byte("PDF")},
+		},
+	}, ImportOpts{Directory: opt.NewString("/notebook/vault")})
+
+	assert.Nil(t, err)
+	assert.Equal(t, report, &ImportReport{
+		Notes: []ImportedFile{
+			{SourcePath: "Index.md", Path: "vault/index.ext"},
+			{SourcePath: "Daily/Meeting notes.md", Path: "vault/Daily/meeting-notes.ext"},
+		},
+		Assets: []ImportedFile{
+			{SourcePath: "assets/diagram.png", Path: "vault/assets/diagram.png"},
+			{SourcePath: "doc.pdf", Path: "vault/doc.pdf"},
+		},
+		UnresolvedLinks: []UnresolvedLink{
+			{SourcePath: "vault/index.ext", Href: "Unknown"},
+		},
+		Duplicates: []ImportDuplicate{},
+	})
+	assert.Equal(t, test.fs.files["/notebook/vault/assets/diagram.png"], "PNG")
+	assert.Equal(t, test.fs.files["/notebook/vault/index.ext"],
+		"See [the meeting](Daily/meeting-notes.ext) and [[Unknown]].\n![](assets/diagram.png) [doc.pdf](doc.pdf)\n",
+	)
+	assert.Equal(t, test.fs.files["/notebook/vault/Daily/meeting-notes.ext"],
+		"Back to [the index](../index.ext), [Google](https://google.com).\n![Diagram](../assets/diagram.png)\n",
+	)
+}
+
+func TestNotebookImportRemovesCreatedFilesOnError(t *testing.T) {
+	test := newImportTest()
+
+	_, err := test.notebook().Import(ImportSource{
+		Notes: []ImportedNote{
+			{Path: "a.md", Title: "A", Created: now},
+			{Path: "b.md", Title: "A", Created: now},
+		},
+		Assets: []ImportedAsset{
+			{Path: "image.png", Content: []byte("PNG")},
+		},
+	}, ImportOpts{Directory: opt.NewString("/notebook/vault")})
+
+	assert.Err(t, err, "import failed: b.md: /notebook/vault/a.ext: note already exists")
+	assert.Equal(t, test.fs.files, map[string]string{})
+}
+
+func TestNotebookImportDuplicates(t *testing.T) {
+	source := ImportSource{
+		Notes: []ImportedNote{
+			{Path: "Same.md", Title: "Same", Content: "The **same** content, linking to [[New]].\n", Created: now},
+			{Path: "Similar.md", Title: "Similar", Content: "A first paragraph long enough to look alike when only a short sentence is added to the note.\n\nA new paragraph about [[New|the new note]].\n", Created: now},
+			{Path: "New.md", Title: "New", Content: "Back to [[Same]].\n", Created: now},
+		},
+	}
+	existing := []ContextualNote{
+		{Note: Note{Path: "same.ext", Title: "Same", Body: "The same content, linking to [the new note](vault/new.ext)."}},
+		{Note: Note{Path: "similar.ext", Title: "Similar", Body: "A first paragraph long enough to look alike when only a short sentence is added to the note."}},
+	}
+
+	test := newImportTest()
+	test.index.Notes = existing
+	test.fs.files["/notebook/similar.ext"] = "# Similar\n\nA first paragraph long enough to look alike when only a short sentence is added to the note.\n"
+	report, err := test.notebook().Import(source, ImportOpts{
+		Directory:  opt.NewString("/notebook/vault"),
+		Duplicates: ImportDuplicatesMerge,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, report.Notes, []ImportedFile{
+		{SourcePath: "New.md", Path: "vault/new.ext"},
+	})
+	assert.Equal(t, len(report.Duplicates), 2)
+	assert.Equal(t, report.Duplicates[0], ImportDuplicate{
+		SourcePath: "Same.md", Path: "same.ext", Similarity: 1, Action: ImportDuplicatesSkip,
+	})
+	assert.Equal(t, report.Duplicates[1].Path, "similar.ext")
+	assert.Equal(t, report.Duplicates[1].Action, ImportDuplicatesMerge)
+	assert.True(t, report.Duplicates[1].Similarity < 1)
+	// The links to a duplicate target the existing note.
+	assert.Equal(t, test.fs.files["/notebook/vault/new.ext"], "Back to [Same](../same.ext).\n")
+	assert.Equal(t, test.fs.files["/notebook/similar.ext"],
+		"# Similar\n\nA first paragraph long enough to look alike when only a short sentence is added to the note.\n\nA new paragraph about [the new note](vault/new.ext).\n",
+	)
+
+	test = newImportTest()
+	test.index.Notes = existing
+	report, err = test.notebook().Import(source, ImportOpts{
+		Directory:  opt.NewString("/notebook/vault"),
+		Duplicates: ImportDuplicatesKeep,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, len(report.Notes), 3)
+	assert.Equal(t, report.Duplicates[1].Action, ImportDuplicatesKeep)
+}
+
+func TestMissingParagraphs(t *testing.T) {
+	assert.Equal(t,
+		missingParagraphs("One.\n\nTwo, with a [[link]].\n  \nThree\nlines.\n\nOne!", "one\n\nTwo with a [link](path).\n"),
+		[]string{"Three\nlines."},
+	)
+}
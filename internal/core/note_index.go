@@ -1,8 +1,12 @@
 package core
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"time"
 
 	"github.com/mickael-menu/zk/internal/util"
@@ -11,6 +15,34 @@ import (
 	strutil "github.com/mickael-menu/zk/internal/util/strings"
 )
 
+// ErrIndexLocked is an error returned when the notebook index is locked for
+// too long by another process, e.g. while it is being reindexed.
+type ErrIndexLocked struct {
+	Err error
+}
+
+func (e ErrIndexLocked) Error() string {
+	return "the notebook index is locked by another process, try again later"
+}
+
+func (e ErrIndexLocked) Unwrap() error {
+	return e.Err
+}
+
+// ErrIndexing is an error returned when the notes of the notebook can't be
+// indexed.
+type ErrIndexing struct {
+	Err error
+}
+
+func (e ErrIndexing) Error() string {
+	return "indexing: " + e.Err.Error()
+}
+
+func (e ErrIndexing) Unwrap() error {
+	return e.Err
+}
+
 // NoteIndex persists and grants access to indexed information about the notes.
 type NoteIndex interface {
 	// Find retrieves the notes matching the given filtering and sorting criteria.
@@ -22,14 +54,76 @@ type NoteIndex interface {
 	// FindCollections retrieves all the collections of the given kind.
 	FindCollections(kind CollectionKind, sorters []CollectionSorter) ([]Collection, error)
 
+	// FindUnresolvedLinks retrieves the internal links which don't target
+	// any indexed note, e.g. links to assets.
+	FindUnresolvedLinks() ([]UnresolvedLink, error)
+	// FindNoteLinks retrieves the links between two indexed notes, once per
+	// source and target notes.
+	FindNoteLinks() ([]NoteLink, error)
+
 	// Indexed returns the list of indexed note file metadata.
 	IndexedPaths() (<-chan paths.Metadata, error)
 	// Add indexes a new note.
 	Add(note Note) (NoteID, error)
 	// Update resets the metadata of an already indexed note.
 	Update(note Note) error
+	// Touch records the modification date of a note file whose content
+	// didn't change since it was indexed.
+	Touch(path string, modified time.Time) error
 	// Remove deletes a note from the index.
 	Remove(path string) error
+	// ReserveID marks the given note ID as taken, to prevent other processes
+	// from using it simultaneously. Returns false if the ID is already
+	// reserved.
+	ReserveID(id string) (bool, error)
+
+	// IndexedAssetPaths returns the list of assets with an indexed text.
+	IndexedAssetPaths() (<-chan paths.Metadata, error)
+	// SetAssetText indexes the text extracted from an asset, to find the
+	// notes linking to it when searching.
+	SetAssetText(asset paths.Metadata, text string) error
+	// RemoveAssetText deletes the text of an asset from the index.
+	RemoveAssetText(path string) error
+
+	// FindUnfetchedURLs retrieves at most limit external URLs linked in the
+	// notes, whose metadata were never fetched or not since the given date.
+	FindUnfetchedURLs(fetchedBefore time.Time, limit int) ([]string, error)
+	// FindURLMetadata retrieves the cached metadata of an external URL.
+	// Returns nil if the URL was never fetched.
+	FindURLMetadata(url string) (*URLMetadata, error)
+	// SetURLMetadata caches the metadata of an external URL.
+	SetURLMetadata(metadata URLMetadata) error
+	// FindUnarchivedURLs retrieves at most limit external URLs linked in the
+	// notes, which were reachable when last fetched but never archived.
+	FindUnarchivedURLs(limit int) ([]string, error)
+	// SetURLArchive records the URL of the archived version of an external
+	// URL.
+	SetURLArchive(url string, archive string) error
+	// FindDeadURLs retrieves the cached metadata of the external URLs linked
+	// in the notes, which were unreachable when last fetched.
+	FindDeadURLs() ([]URLMetadata, error)
+
+	// FindTasks retrieves the tasks of the indexed notes matching the given
+	// criteria.
+	FindTasks(opts TaskFindOpts) ([]NoteTask, error)
+
+	// FindReviews retrieves the notes of the review queue matching the given
+	// criteria, with their review schedule.
+	FindReviews(opts ReviewFindOpts) ([]NoteReview, error)
+
+	// SetReview saves the review schedule of the note at the given path.
+	SetReview(path string, review Review) error
+
+	// FindRelated retrieves the notes sharing rare terms or tags with the
+	// note at the given path, which are not linked to or from it.
+	FindRelated(path string, opts RelatedNotesOpts) ([]RelatedNote, error)
+
+	// FindHistory retrieves the notes recently created or opened, the most
+	// recent first. All of them are returned when limit is 0.
+	FindHistory(limit int) ([]NoteHistoryEntry, error)
+	// AddHistory records an action done on the note at the given path, in
+	// the history of the recently touched notes.
+	AddHistory(path string, action NoteHistoryAction, date time.Time) error
 
 	// Commit performs a set of operations atomically.
 	Commit(transaction func(idx NoteIndex) error) error
@@ -38,6 +132,28 @@ type NoteIndex interface {
 	NeedsReindexing() (bool, error)
 	// SetNeedsReindexing indicates whether all notes should be reindexed.
 	SetNeedsReindexing(needsReindexing bool) error
+
+	// LastIndexing returns the start date and duration of the last indexing
+	// of the notebook. The date is zero if it was never indexed.
+	LastIndexing() (date time.Time, duration time.Duration, err error)
+	// SetLastIndexing records the start date and duration of an indexing.
+	SetLastIndexing(date time.Time, duration time.Duration) error
+
+	// Vacuum reclaims the unused storage space of the index.
+	Vacuum() error
+
+	// Query runs a read-only SQL query on the index, typically on its
+	// documented views.
+	Query(query string) (IndexQueryResult, error)
+}
+
+// IndexQueryResult holds the rows returned by a query on the index.
+type IndexQueryResult struct {
+	// Names of the columns returned by the query.
+	Columns []string
+	// Values of each row, in the order of the columns. A value is nil, a
+	// string, an int64, a float64 or a time.Time.
+	Rows [][]interface{}
 }
 
 // NoteIndexingStats holds statistics about a notebook indexing process.
@@ -50,13 +166,16 @@ type NoteIndexingStats struct {
 	ModifiedCount int `json:"modifiedCount"`
 	// Number of notes removed since last indexing.
 	RemovedCount int `json:"removedCount"`
+	// Notes renamed or moved outside of zk since last indexing, which are
+	// counted as both added and removed.
+	Renamed []NoteMove `json:"renamed"`
 	// Duration of the indexing process.
 	Duration time.Duration `json:"duration"`
 }
 
 // String implements Stringer
 func (s NoteIndexingStats) String() string {
-	return fmt.Sprintf(`Indexed %d %v in %v
+	res := fmt.Sprintf(`Indexed %d %v in %v
   + %d added
   ~ %d modified
   - %d removed`,
@@ -65,6 +184,10 @@ func (s NoteIndexingStats) String() string {
 		s.Duration.Round(500*time.Millisecond),
 		s.AddedCount, s.ModifiedCount, s.RemovedCount,
 	)
+	if len(s.Renamed) > 0 {
+		res += fmt.Sprintf("\n  > %d renamed", len(s.Renamed))
+	}
+	return res
 }
 
 // indexTask indexes the notes in the given directory with the NoteIndex.
@@ -74,14 +197,55 @@ type indexTask struct {
 	force  bool
 	index  NoteIndex
 	parser NoteParser
+	fs     FileStorage
 	logger util.Logger
+	// Notes added, modified or removed by the task, given to the post-index
+	// hook.
+	changes []HookNote
 }
 
-func (t *indexTask) execute(callback func(change paths.DiffChange)) (NoteIndexingStats, error) {
+// IndexPhase is a step of an indexing run.
+type IndexPhase string
+
+const (
+	// Listing the note files and comparing them with the index.
+	IndexPhaseScanning IndexPhase = "scanning"
+	// Parsing and indexing the new and modified notes.
+	IndexPhaseIndexing IndexPhase = "indexing"
+	// Extracting the text of the attachments.
+	IndexPhaseAssets IndexPhase = "assets"
+)
+
+// IndexProgress reports the progress of an indexing run.
+type IndexProgress struct {
+	Phase IndexPhase
+	// Number of note files found in the notebook.
+	Scanned int
+	// Number of changes to index, known after scanning.
+	Total int
+	// Number of changes indexed, out of Total.
+	Done int
+	// Number of new or modified notes parsed.
+	Parsed int
+	// Number of parsed notes saved in the index.
+	Indexed int
+	// Number of notes removed from the index.
+	Removed int
+	// Path of the file being indexed, if any.
+	Path string
+}
+
+func (t *indexTask) execute(progress func(IndexProgress)) (NoteIndexingStats, error) {
 	wrap := errors.Wrapper("indexing failed")
 
 	stats := NoteIndexingStats{}
 	startTime := time.Now()
+	state := IndexProgress{Phase: IndexPhaseScanning}
+	report := func() {
+		if progress != nil {
+			progress(state)
+		}
+	}
 
 	needsReindexing, err := t.index.NeedsReindexing()
 	if err != nil {
@@ -91,71 +255,369 @@ func (t *indexTask) execute(callback func(change paths.DiffChange)) (NoteIndexin
 	force := t.force || needsReindexing
 
 	shouldIgnorePath := func(path string) (bool, error) {
-		group, err := t.config.GroupConfigForPath(path)
-		if err != nil {
-			return true, err
-		}
+		isNote, err := isNotePath(t.config, path)
+		return !isNote, err
+	}
 
-		if filepath.Ext(path) != "."+group.Note.Extension {
-			return true, nil
+	// The note files are listed first, to know how many changes need to be
+	// indexed.
+	files := []paths.Metadata{}
+	modified := map[string]time.Time{}
+	for file := range paths.Walk(t.path, t.logger, shouldIgnorePath) {
+		files = append(files, file)
+		modified[file.Path] = file.Modified
+		state.Scanned = len(files)
+		state.Path = file.Path
+		report()
+	}
+	source := make(chan paths.Metadata)
+	go func() {
+		defer close(source)
+		for _, file := range files {
+			source <- file
 		}
+	}()
 
-		for _, ignoreGlob := range group.IgnoreGlobs() {
-			matches, err := filepath.Match(ignoreGlob, path)
-			if err != nil {
-				return true, errors.Wrapf(err, "failed to match ignore glob %s to %s", ignoreGlob, path)
-			}
-			if matches {
-				return true, nil
+	indexed, err := t.index.IndexedPaths()
+	if err != nil {
+		return stats, wrap(err)
+	}
+	// The checksums of the indexed notes are used to skip the files whose
+	// modification date changed, but not their content.
+	checksums := map[string]string{}
+	target := make(chan paths.Metadata)
+	go func() {
+		defer close(target)
+		for file := range indexed {
+			if !force {
+				checksums[file.Path] = file.Checksum
 			}
+			target <- file
 		}
+	}()
 
-		return false, nil
-	}
-
-	source := paths.Walk(t.path, t.logger, shouldIgnorePath)
-
-	target, err := t.index.IndexedPaths()
+	// FIXME: Use the FS?
+	changes := []paths.DiffChange{}
+	count, err := paths.Diff(source, target, force, func(change paths.DiffChange) error {
+		changes = append(changes, change)
+		return nil
+	})
 	if err != nil {
 		return stats, wrap(err)
 	}
+	stats.SourceCount = count
 
-	// FIXME: Use the FS?
-	count, err := paths.Diff(source, target, force, func(change paths.DiffChange) error {
-		callback(change)
-		absPath := filepath.Join(t.path, change.Path)
+	// The removed notes are kept in the index until all the changes are
+	// known, to detect the notes renamed outside of zk.
+	added := []Note{}
+	removed := []string{}
+
+	state.Phase = IndexPhaseIndexing
+	state.Total = len(changes)
+	// The notes are parsed concurrently, but written sequentially to the
+	// index, in the order of the changes.
+	parsed := t.parseChanges(changes, checksums)
+	for i, change := range changes {
+		state.Path = change.Path
+		report()
 
 		switch change.Kind {
 		case paths.DiffAdded:
 			stats.AddedCount += 1
-			note, err := t.parser.ParseNoteAt(absPath)
+			note, err := parsed.result(i)
 			if note != nil {
+				state.Parsed++
 				_, err = t.index.Add(*note)
+				added = append(added, *note)
+				t.addChange(change.Path, note.Title, "added")
+			}
+			if note != nil && err == nil {
+				state.Indexed++
 			}
 			t.logger.Err(err)
 
 		case paths.DiffModified:
+			note, unchanged, err := parsed.resultOrUnchanged(i)
+			if unchanged {
+				t.logger.Err(t.index.Touch(change.Path, modified[change.Path]))
+				break
+			}
 			stats.ModifiedCount += 1
-			note, err := t.parser.ParseNoteAt(absPath)
 			if note != nil {
+				state.Parsed++
 				err = t.index.Update(*note)
+				t.addChange(change.Path, note.Title, "modified")
+			}
+			if note != nil && err == nil {
+				state.Indexed++
 			}
 			t.logger.Err(err)
 
 		case paths.DiffRemoved:
 			stats.RemovedCount += 1
-			err := t.index.Remove(change.Path)
-			t.logger.Err(err)
+			removed = append(removed, change.Path)
+			t.addChange(change.Path, "", "removed")
 		}
-		return nil
+		state.Done++
+	}
+
+	if len(removed) > 0 {
+		stats.Renamed, err = t.detectRenames(removed, added)
+		t.logger.Err(err)
+	}
+	for _, path := range removed {
+		err := t.index.Remove(path)
+		if err == nil {
+			state.Removed++
+		}
+		t.logger.Err(err)
+	}
+	state.Path = ""
+	report()
+
+	state.Phase = IndexPhaseAssets
+	err = t.indexAssetTexts(force, func(change paths.DiffChange) {
+		state.Path = change.Path
+		report()
 	})
+	if err != nil {
+		return stats, wrap(err)
+	}
 
-	stats.SourceCount = count
 	stats.Duration = time.Since(startTime)
 
+	err = t.index.SetLastIndexing(startTime, stats.Duration)
+	if err != nil {
+		return stats, wrap(err)
+	}
+
 	if needsReindexing {
 		err = t.index.SetNeedsReindexing(false)
 	}
 
 	return stats, wrap(err)
 }
+
+// addChange records a note changed by the task.
+func (t *indexTask) addChange(path string, title string, status string) {
+	t.changes = append(t.changes, HookNote{
+		Path:    path,
+		AbsPath: filepath.Join(t.path, path),
+		Title:   title,
+		Status:  status,
+	})
+}
+
+// parsedNotes holds the notes parsed concurrently by indexTask.parseChanges.
+type parsedNotes struct {
+	// Results of the parsing, by index of the change. nil for the removed
+	// notes.
+	results []chan parsedNote
+	// Bounds the number of parsed notes waiting to be indexed.
+	window chan struct{}
+}
+
+type parsedNote struct {
+	note *Note
+	// The content of the note didn't change since it was indexed, so it was
+	// not parsed.
+	unchanged bool
+	err       error
+}
+
+// result waits for the parsed note of the change at index i.
+func (p *parsedNotes) result(i int) (*Note, error) {
+	note, _, err := p.resultOrUnchanged(i)
+	return note, err
+}
+
+// resultOrUnchanged waits for the parsed note of the change at index i, or
+// returns true if its content didn't change.
+func (p *parsedNotes) resultOrUnchanged(i int) (*Note, bool, error) {
+	res := <-p.results[i]
+	<-p.window
+	return res.note, res.unchanged, res.err
+}
+
+// parseChanges parses the added and modified notes with a bounded pool of
+// workers. Every result must be consumed with parsedNotes.result.
+//
+// The modified notes whose content still matches the given indexed
+// checksums are not parsed.
+func (t *indexTask) parseChanges(changes []paths.DiffChange, checksums map[string]string) *parsedNotes {
+	workers := runtime.GOMAXPROCS(0)
+	parsed := &parsedNotes{
+		results: make([]chan parsedNote, len(changes)),
+		window:  make(chan struct{}, workers*4),
+	}
+	for i, change := range changes {
+		if change.Kind != paths.DiffRemoved {
+			parsed.results[i] = make(chan parsedNote, 1)
+		}
+	}
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i, result := range parsed.results {
+			if result != nil {
+				parsed.window <- struct{}{}
+				jobs <- i
+			}
+		}
+	}()
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				parsed.results[i] <- t.parseChange(changes[i], checksums[changes[i].Path])
+			}
+		}()
+	}
+
+	return parsed
+}
+
+// parseChange parses the note of an added or modified change, unless its
+// content matches the indexed checksum.
+func (t *indexTask) parseChange(change paths.DiffChange, checksum string) parsedNote {
+	absPath := filepath.Join(t.path, change.Path)
+	if change.Kind == paths.DiffModified && checksum != "" && t.fs != nil {
+		content, err := t.fs.Read(absPath)
+		if err == nil && fmt.Sprintf("%x", sha256.Sum256(content)) == checksum {
+			return parsedNote{unchanged: true}
+		}
+	}
+	note, err := t.parser.ParseNoteAt(absPath)
+	return parsedNote{note: note, err: err}
+}
+
+// detectRenames finds the notes at the removed paths which were renamed or
+// moved outside of zk, among the added notes.
+func (t *indexTask) detectRenames(removedPaths []string, added []Note) ([]NoteMove, error) {
+	if len(added) == 0 {
+		return []NoteMove{}, nil
+	}
+
+	found, err := t.index.Find(NoteFindOpts{ExactPaths: removedPaths})
+	if err != nil {
+		return []NoteMove{}, err
+	}
+	removed := []Note{}
+	for _, note := range found {
+		removed = append(removed, note.Note)
+	}
+	return detectRenames(removed, added), nil
+}
+
+// detectRenames pairs the removed notes with the added ones having the same
+// content, or else the same filename stem, e.g. their ID. Only unambiguous
+// pairs are considered renamed.
+func detectRenames(removed []Note, added []Note) []NoteMove {
+	renames := []NoteMove{}
+	pairedPaths := map[string]bool{}
+	pair := func(key func(note Note) string) {
+		group := func(notes []Note) map[string][]Note {
+			res := map[string][]Note{}
+			for _, note := range notes {
+				if !pairedPaths[note.Path] {
+					res[key(note)] = append(res[key(note)], note)
+				}
+			}
+			return res
+		}
+		addedByKey := group(added)
+		for k, sources := range group(removed) {
+			targets := addedByKey[k]
+			if k == "" || len(sources) != 1 || len(targets) != 1 {
+				continue
+			}
+			renames = append(renames, NoteMove{
+				SourcePath:     sources[0].Path,
+				TargetPath:     targets[0].Path,
+				RewrittenLinks: []RewrittenLink{},
+			})
+			pairedPaths[sources[0].Path] = true
+			pairedPaths[targets[0].Path] = true
+		}
+	}
+
+	pair(func(note Note) string { return note.Checksum })
+	pair(func(note Note) string { return paths.FilenameStem(note.Path) })
+
+	sort.Slice(renames, func(i, j int) bool {
+		return renames[i].SourcePath < renames[j].SourcePath
+	})
+	return renames
+}
+
+// indexAssetTexts extracts the text of the PDF and image assets using the
+// external commands set in the tool config.
+func (t *indexTask) indexAssetTexts(force bool, callback func(change paths.DiffChange)) error {
+	source := paths.Walk(t.path, t.logger, func(path string) (bool, error) {
+		return assetTextCommand(t.config.Tool, path).IsNull(), nil
+	})
+
+	target, err := t.index.IndexedAssetPaths()
+	if err != nil {
+		return err
+	}
+
+	_, err = paths.Diff(source, target, force, func(change paths.DiffChange) error {
+		callback(change)
+		absPath := filepath.Join(t.path, change.Path)
+
+		switch change.Kind {
+		case paths.DiffAdded, paths.DiffModified:
+			err := t.indexAssetText(change.Path, absPath)
+			t.logger.Err(err)
+
+		case paths.DiffRemoved:
+			err := t.index.RemoveAssetText(change.Path)
+			t.logger.Err(err)
+		}
+		return nil
+	})
+
+	return err
+}
+
+func (t *indexTask) indexAssetText(path string, absPath string) error {
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return err
+	}
+	command := assetTextCommand(t.config.Tool, path)
+	text, err := extractAssetText(command.String(), absPath)
+	if err != nil {
+		return errors.Wrapf(err, "%s: failed to extract text", path)
+	}
+	return t.index.SetAssetText(paths.Metadata{
+		Path:     path,
+		Modified: info.ModTime().UTC(),
+	}, text)
+}
+
+// isNotePath returns whether the given path relative to the notebook root is
+// a note, according to the extension and ignore globs of its group.
+func isNotePath(config Config, path string) (bool, error) {
+	group, err := config.GroupConfigForPath(path)
+	if err != nil {
+		return false, err
+	}
+
+	if filepath.Ext(path) != "."+group.Note.Extension {
+		return false, nil
+	}
+
+	for _, ignoreGlob := range group.IgnoreGlobs() {
+		matches, err := filepath.Match(ignoreGlob, path)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to match ignore glob %s to %s", ignoreGlob, path)
+		}
+		if matches {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
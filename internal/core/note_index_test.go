@@ -0,0 +1,51 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestDetectRenames(t *testing.T) {
+	move := func(source string, target string) NoteMove {
+		return NoteMove{SourcePath: source, TargetPath: target, RewrittenLinks: []RewrittenLink{}}
+	}
+
+	assert.Equal(t, detectRenames([]Note{}, []Note{}), []NoteMove{})
+
+	assert.Equal(t, detectRenames(
+		[]Note{
+			// Same content.
+			{Path: "draft.md", Checksum: "a"},
+			// Same filename stem, with a modified content.
+			{Path: "inbox/202101.md", Checksum: "b"},
+			// Removed.
+			{Path: "gone.md", Checksum: "c"},
+			// Ambiguous, several new notes with the same content.
+			{Path: "empty.md", Checksum: "d"},
+		},
+		[]Note{
+			{Path: "ideas/final.md", Checksum: "a"},
+			{Path: "archive/202101.md", Checksum: "b2"},
+			{Path: "new.md", Checksum: "e"},
+			{Path: "empty-1.md", Checksum: "d"},
+			{Path: "empty-2.md", Checksum: "d"},
+		},
+	), []NoteMove{
+		move("draft.md", "ideas/final.md"),
+		move("inbox/202101.md", "archive/202101.md"),
+	})
+
+	// A note paired by its content is not paired again by its stem.
+	assert.Equal(t, detectRenames(
+		[]Note{
+			{Path: "a/note.md", Checksum: "a"},
+			{Path: "b/note.md", Checksum: "b"},
+		},
+		[]Note{
+			{Path: "c/note.md", Checksum: "a"},
+		},
+	), []NoteMove{
+		move("a/note.md", "c/note.md"),
+	})
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// NoteLinkLocation is the place in an existing note where a link to a new
+// note is inserted.
+type NoteLinkLocation struct {
+	// Path to the note receiving the link.
+	Path string
+	// Heading under which the link is appended, case insensitive. The link is
+	// appended at the end of the note when empty.
+	Heading string
+}
+
+// linkFromTarget is a note resolved from a NoteLinkLocation.
+type linkFromTarget struct {
+	note    MinimalNote
+	heading string
+}
+
+// resolveLinkFrom finds the note receiving the link to a new note, before
+// creating it.
+func (n *Notebook) resolveLinkFrom(location *NoteLinkLocation) (*linkFromTarget, error) {
+	if location == nil {
+		return nil, nil
+	}
+	note, err := n.indexedNoteAt(location.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find the note to link from")
+	}
+	return &linkFromTarget{note: *note, heading: location.Heading}, nil
+}
+
+// insertLinkFrom appends a link to note in the target note, and reindexes it.
+func (n *Notebook) insertLinkFrom(index NoteIndex, note Note, target linkFromTarget) error {
+	wrap := errors.Wrapperf("%s: failed to insert the link", target.note.Path)
+
+	path := filepath.Join(n.Path, target.note.Path)
+	formatter, err := n.NewLinkFormatter()
+	if err != nil {
+		return wrap(err)
+	}
+	context, err := NewLinkFormatterContext(note.AsMinimalNote(), n.Path, filepath.Dir(path))
+	if err != nil {
+		return wrap(err)
+	}
+	link, err := formatter(context)
+	if err != nil {
+		return wrap(err)
+	}
+
+	content, err := n.fs.Read(path)
+	if err != nil {
+		return wrap(err)
+	}
+	updated := insertUnderHeading(string(content), target.heading, "- "+link)
+	err = n.fs.Write(path, []byte(updated))
+	if err != nil {
+		return wrap(err)
+	}
+
+	parsed, err := n.ParseNoteAt(path)
+	if parsed == nil || err != nil {
+		return wrap(err)
+	}
+	return wrap(index.Update(*parsed))
+}
+
+var (
+	headingRegex  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	listItemRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s`)
+)
+
+// insertUnderHeading appends a line at the end of the section of the given
+// heading, or at the end of the content when heading is empty. A missing
+// heading is added at the end of the content.
+//
+// The line is added to the list ending the section, if any.
+func insertUnderHeading(content string, heading string, line string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+
+	start, end := -1, len(lines)
+	if heading != "" {
+		level := 0
+		fence := ""
+		for i, l := range lines {
+			if matches := codeFenceRegex.FindStringSubmatch(l); matches != nil {
+				if fence == "" {
+					fence = matches[1]
+				} else if fence == matches[1] {
+					fence = ""
+				}
+				continue
+			}
+			if fence != "" {
+				continue
+			}
+			matches := headingRegex.FindStringSubmatch(strings.TrimRight(l, "\r"))
+			if matches == nil {
+				continue
+			}
+			if start == -1 {
+				if strings.EqualFold(strings.TrimSpace(matches[2]), strings.TrimSpace(heading)) {
+					start, level = i, len(matches[1])
+				}
+			} else if len(matches[1]) <= level {
+				end = i
+				break
+			}
+		}
+
+		if start == -1 {
+			lines = append(lines, "", "## "+strings.TrimSpace(heading))
+			start, end = len(lines)-1, len(lines)
+		}
+	}
+
+	// Skips the blank lines ending the section.
+	last := end - 1
+	for last > start && strings.TrimSpace(lines[last]) == "" {
+		last--
+	}
+
+	inserted := []string{line}
+	if last >= 0 && !listItemRegex.MatchString(lines[last]) {
+		// Separates the line from a paragraph or the heading.
+		inserted = []string{"", line}
+	}
+	if end < len(lines) {
+		inserted = append(inserted, "")
+	}
+
+	res := append([]string{}, lines[:last+1]...)
+	res = append(res, inserted...)
+	res = append(res, lines[end:]...)
+	return strings.Join(res, "\n") + "\n"
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestInsertUnderHeading(t *testing.T) {
+	test := func(content string, heading string, expected string) {
+		t.Helper()
+		assert.Equal(t, insertUnderHeading(content, heading, "- [[new]]"), expected)
+	}
+
+	// At the end of the note.
+	test("", "", "- [[new]]\n")
+	test("# Inbox\n", "", "# Inbox\n\n- [[new]]\n")
+	test("# Inbox\n\nSome text.\n\n\n", "", "# Inbox\n\nSome text.\n\n- [[new]]\n")
+	test("# Inbox\n\n- [[old]]\n", "", "# Inbox\n\n- [[old]]\n- [[new]]\n")
+
+	// At the end of a section, before the next heading of the same level.
+	content := `# Index
+
+## Ideas
+
+- [[idea]]
+
+### Sub-ideas
+
+Text
+
+## Projects
+`
+	test(content, "ideas", `# Index
+
+## Ideas
+
+- [[idea]]
+
+### Sub-ideas
+
+Text
+
+- [[new]]
+
+## Projects
+`)
+	test(content, "Sub-ideas", `# Index
+
+## Ideas
+
+- [[idea]]
+
+### Sub-ideas
+
+Text
+
+- [[new]]
+
+## Projects
+`)
+	test(content, "Projects", `# Index
+
+## Ideas
+
+- [[idea]]
+
+### Sub-ideas
+
+Text
+
+## Projects
+
+- [[new]]
+`)
+	test("## Ideas\n- [[idea]]\n## Projects\n", "Ideas", "## Ideas\n- [[idea]]\n- [[new]]\n\n## Projects\n")
+
+	// Headings in code blocks are ignored.
+	test("```\n# Ideas\n```\n", "Ideas", "```\n# Ideas\n```\n\n## Ideas\n\n- [[new]]\n")
+
+	// A missing heading is added.
+	test("# Index\n", "Ideas", "# Index\n\n## Ideas\n\n- [[new]]\n")
+}
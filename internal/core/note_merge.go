@@ -0,0 +1,514 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// MergeNotesOpts holds the options used to merge several notes into one.
+type MergeNotesOpts struct {
+	// Paths to the merged notes.
+	Paths []string
+	// Path to the note receiving the merged content. When it is an existing
+	// note, its content comes first. Otherwise, the note is created.
+	Into string
+	// Path to a template combining the notes, relative to the template
+	// directories. The notes are concatenated by default.
+	Template opt.String
+	// Computes the merged note without writing it nor removing the
+	// sources.
+	DryRun bool
+	// Command merging the notes.
+	Origin AuditOrigin
+}
+
+// NoteMerge reports the changes made when merging several notes into one.
+type NoteMerge struct {
+	// Path of the note receiving the merged content, relative to the
+	// notebook root.
+	TargetPath string `json:"targetPath"`
+	// Whether the target note was created by the merge.
+	Created bool `json:"created"`
+	// Content of the target note after the merge.
+	Content string `json:"content"`
+	// Notes merged into the target, which were archived or trashed.
+	Sources []MergedNote `json:"sources"`
+	// Links to the merged notes updated to target the merged note.
+	RewrittenLinks []RewrittenLink `json:"rewrittenLinks"`
+}
+
+// MergedNote is a note merged into another one.
+type MergedNote struct {
+	// Path of the note before the merge, relative to the notebook root.
+	Path string `json:"path"`
+	// Path of the note in the archive, or empty if the note was moved to
+	// the trash.
+	ArchivePath string `json:"archivePath,omitempty"`
+}
+
+// NotePaths returns the paths of the notes containing rewritten links.
+func (m NoteMerge) NotePaths() []string {
+	return NoteMove{RewrittenLinks: m.RewrittenLinks}.NotePaths()
+}
+
+// mergeTemplateContext is the template context used to combine the merged
+// notes.
+type mergeTemplateContext struct {
+	// Path of the target note, relative to the notebook root.
+	Path  string
+	Notes []mergedNoteContext
+	Now   time.Time
+}
+
+// mergedNoteContext is a note combined by a merge template. The existing
+// target note is the first one.
+type mergedNoteContext struct {
+	Path  string
+	Title string
+	// Content after the frontmatter and title heading.
+	Body string
+	// Content after the frontmatter.
+	Content  string
+	Tags     []string
+	Metadata map[string]interface{}
+}
+
+// MergeNotes combines several notes into a target note, then archives them.
+// Their frontmatters are merged, the first value of a key winning except for
+// the tags which are united, and the links to the merged notes are updated
+// to target the merged note. The sources are moved to the trash when no
+// archive directory is configured.
+//
+// All the notes are written and indexed in a single transaction.
+func (n *Notebook) MergeNotes(opts MergeNotesOpts) (*NoteMerge, error) {
+	wrap := errors.Wrapper("failed to merge the notes")
+
+	// Merged notes, with the target first when it already exists.
+	notes := []*Note{}
+	contains := func(path string) bool {
+		for _, note := range notes {
+			if note.Path == path {
+				return true
+			}
+		}
+		return false
+	}
+
+	target, err := n.indexedNoteAt(opts.Into)
+	if _, ok := err.(ErrNoteNotFound); ok {
+		target = nil
+	} else if err != nil {
+		return nil, wrap(err)
+	}
+	if target != nil {
+		note, err := n.ParseNoteAt(filepath.Join(n.Path, target.Path))
+		if err != nil {
+			return nil, wrap(err)
+		}
+		notes = append(notes, note)
+	}
+
+	sourcePaths := []string{}
+	for _, path := range opts.Paths {
+		source, err := n.indexedNoteAt(path)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		if contains(source.Path) {
+			continue
+		}
+		note, err := n.ParseNoteAt(filepath.Join(n.Path, source.Path))
+		if err != nil {
+			return nil, wrap(err)
+		}
+		notes = append(notes, note)
+		sourcePaths = append(sourcePaths, source.Path)
+	}
+	if len(sourcePaths) == 0 {
+		return nil, wrap(errors.New("no notes to merge into the target"))
+	}
+
+	result := NoteMerge{
+		Sources:        []MergedNote{},
+		RewrittenLinks: []RewrittenLink{},
+	}
+	if target != nil {
+		result.TargetPath = target.Path
+	} else {
+		result.Created = true
+		result.TargetPath, err = n.moveTargetPath(sourcePaths[0], opts.Into)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		exists, err := n.fs.FileExists(filepath.Join(n.Path, result.TargetPath))
+		if err != nil {
+			return nil, wrap(err)
+		}
+		if exists {
+			return nil, wrap(fmt.Errorf("%s: a file already exists at this location, but it is not an indexed note", result.TargetPath))
+		}
+	}
+	targetPath := result.TargetPath
+
+	// relinkSources updates the links to the merged notes found in the note
+	// at notePath.
+	relinkSources := func(content string, notePath string) (string, []RewrittenLink) {
+		links := []RewrittenLink{}
+		for _, sourcePath := range sourcePaths {
+			var rewritten []RewrittenLink
+			content, rewritten = rewriteLinks(content, notePath, func(href string, isWikiLink bool) (string, bool) {
+				return relinkHref(href, isWikiLink, notePath, sourcePath, targetPath)
+			})
+			links = append(links, rewritten...)
+		}
+		return content, links
+	}
+
+	// Combines the content of the notes.
+	mergedPaths := append([]string{targetPath}, sourcePaths...)
+	frontmatters := [][]frontmatterEntry{}
+	tags := []string{}
+	context := mergeTemplateContext{
+		Path:  targetPath,
+		Notes: []mergedNoteContext{},
+		Now:   time.Now(),
+	}
+	for _, note := range notes {
+		frontmatter, content := splitFrontmatter(note.RawContent)
+		frontmatters = append(frontmatters, frontmatter)
+		tags = append(tags, frontmatterTags(note.Metadata)...)
+
+		// The relative links of the notes need to be updated when changing
+		// directory.
+		body := note.Body
+		if note.Path != targetPath && filepath.Dir(note.Path) != filepath.Dir(targetPath) {
+			rebase := func(href string, isWikiLink bool) (string, bool) {
+				return rebaseHref(href, isWikiLink, note.Path, targetPath)
+			}
+			content, _ = rewriteLinks(content, targetPath, rebase)
+			body, _ = rewriteLinks(body, targetPath, rebase)
+		}
+		// The links between the merged notes would target the merged note
+		// itself.
+		content = unlinkLinksTo(content, targetPath, mergedPaths)
+		body = unlinkLinksTo(body, targetPath, mergedPaths)
+		context.Notes = append(context.Notes, mergedNoteContext{
+			Path:     note.Path,
+			Title:    note.Title,
+			Body:     body,
+			Content:  strings.TrimSpace(content),
+			Tags:     note.Tags,
+			Metadata: note.Metadata,
+		})
+	}
+
+	var content string
+	if templatePath := opts.Template.Unwrap(); templatePath != "" {
+		templates, err := n.templateLoaderFactory(n.Config.Note.Lang)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		template, err := templates.LoadTemplateAt(templatePath)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		content, err = template.Render(context)
+		if err != nil {
+			return nil, wrap(err)
+		}
+	} else {
+		contents := []string{}
+		for _, note := range context.Notes {
+			if note.Content != "" {
+				contents = append(contents, note.Content)
+			}
+		}
+		content = strings.Join(contents, "\n\n") + "\n"
+	}
+	content, _ = relinkSources(mergeFrontmatters(frontmatters, strutil.RemoveDuplicates(tags))+content, targetPath)
+	result.Content = content
+
+	// New content of the notes linking to the merged notes, indexed by their
+	// path.
+	contents := map[string]string{}
+	for _, sourcePath := range sourcePaths {
+		linkingPaths, err := n.findNotesLinkingToPath(sourcePath)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		backlinks, err := n.FindMinimalNotes(NoteFindOpts{
+			LinkTo: &LinkFilter{Paths: []string{sourcePath}},
+		})
+		if err != nil {
+			return nil, wrap(err)
+		}
+		for _, backlink := range backlinks {
+			linkingPaths = append(linkingPaths, backlink.Path)
+		}
+
+		for _, path := range linkingPaths {
+			if path == targetPath || strutil.InList(sourcePaths, path) {
+				continue
+			}
+			if _, ok := contents[path]; ok {
+				continue
+			}
+			content, err := n.fs.Read(filepath.Join(n.Path, path))
+			if err != nil {
+				return nil, wrap(err)
+			}
+			newContent, links := relinkSources(string(content), path)
+			if len(links) > 0 {
+				contents[path] = newContent
+				result.RewrittenLinks = append(result.RewrittenLinks, links...)
+			}
+		}
+	}
+	sort.SliceStable(result.RewrittenLinks, func(i, j int) bool {
+		return result.RewrittenLinks[i].Path < result.RewrittenLinks[j].Path
+	})
+
+	archiveDir := n.Config.Trash.ArchiveDir
+	for _, sourcePath := range sourcePaths {
+		source := MergedNote{Path: sourcePath}
+		if archiveDir != "" && !n.IsArchived(sourcePath) {
+			source.ArchivePath = filepath.Join(archiveDir, sourcePath)
+			exists, err := n.fs.FileExists(filepath.Join(n.Path, source.ArchivePath))
+			if err != nil {
+				return nil, wrap(err)
+			}
+			if exists {
+				return nil, wrap(fmt.Errorf("%s: a file already exists in the archive", source.ArchivePath))
+			}
+		}
+		result.Sources = append(result.Sources, source)
+	}
+
+	// New content of the merged notes, indexed by their path after being
+	// archived or trashed.
+	archived := map[string]string{}
+	for i, source := range result.Sources {
+		content := notes[len(notes)-len(sourcePaths)+i].RawContent
+		if source.ArchivePath == "" {
+			archived[source.Path] = setFrontmatterStatus(content, "trash")
+			continue
+		}
+		content, _ = rewriteLinks(content, source.ArchivePath, func(href string, isWikiLink bool) (string, bool) {
+			return rebaseHref(href, isWikiLink, source.Path, source.ArchivePath)
+		})
+		// The links between the merged notes target their archived version.
+		for _, other := range result.Sources {
+			if other.ArchivePath == "" || other.Path == source.Path {
+				continue
+			}
+			content, _ = rewriteLinks(content, source.ArchivePath, func(href string, isWikiLink bool) (string, bool) {
+				return relinkHref(href, isWikiLink, source.ArchivePath, other.Path, other.ArchivePath)
+			})
+		}
+		archived[source.ArchivePath] = content
+	}
+
+	if opts.DryRun {
+		return &result, nil
+	}
+
+	err = n.commitIndex(func(index NoteIndex) error {
+		// Parses and indexes the note written at the given path.
+		write := func(path string, content string, update func(note Note) error) error {
+			absPath := filepath.Join(n.Path, path)
+			err := n.fs.Write(absPath, []byte(content))
+			if err != nil {
+				return err
+			}
+			note, err := n.ParseNoteAt(absPath)
+			if err != nil {
+				return err
+			}
+			return update(*note)
+		}
+		add := func(note Note) error {
+			_, err := index.Add(note)
+			return err
+		}
+
+		if result.Created {
+			err = write(targetPath, result.Content, add)
+		} else {
+			err = write(targetPath, result.Content, index.Update)
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, source := range result.Sources {
+			if source.ArchivePath == "" {
+				err := write(source.Path, archived[source.Path], index.Update)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			err := n.fs.Remove(filepath.Join(n.Path, source.Path))
+			if err != nil {
+				return err
+			}
+			err = index.Remove(source.Path)
+			if err != nil {
+				return err
+			}
+			err = write(source.ArchivePath, archived[source.ArchivePath], add)
+			if err != nil {
+				return err
+			}
+		}
+
+		for path, content := range contents {
+			err := write(path, content, index.Update)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	n.audit(opts.Origin, AuditOperationMerge,
+		fmt.Sprintf("%d %s merged into %s, %d %s rewritten",
+			len(sourcePaths), strutil.Pluralize("note", len(sourcePaths)), targetPath,
+			len(result.RewrittenLinks), strutil.Pluralize("link", len(result.RewrittenLinks)),
+		),
+		append(sourcePaths, targetPath)...,
+	)
+	return &result, nil
+}
+
+// unlinkLinksTo replaces the links of the note at notePath targeting one of
+// the given notes with their plain text.
+func unlinkLinksTo(content string, notePath string, targetPaths []string) string {
+	content, _ = unlinkLinks(content, notePath, func(href string, isWikiLink bool) bool {
+		for _, targetPath := range targetPaths {
+			if _, ok := relinkHref(href, isWikiLink, notePath, targetPath, targetPath); ok {
+				return true
+			}
+		}
+		return false
+	})
+	return content
+}
+
+// frontmatterEntry is a top-level entry of a YAML frontmatter, with the raw
+// lines of its value.
+type frontmatterEntry struct {
+	key string
+	raw string
+}
+
+var frontmatterKeyRegex = regexp.MustCompile(`^([^\s#\-][^:]*?)[ \t]*:(?:\s|$)`)
+
+// frontmatterTagKeys are the frontmatter keys holding the tags of a note.
+var frontmatterTagKeys = []string{"tag", "tags", "keyword", "keywords"}
+
+// splitFrontmatter splits a note content between the top-level entries of
+// its YAML frontmatter and the content following it.
+func splitFrontmatter(content string) ([]frontmatterEntry, string) {
+	loc := frontmatterRegex.FindStringIndex(content)
+	if loc == nil {
+		return []frontmatterEntry{}, content
+	}
+	frontmatter := content[loc[0]:loc[1]]
+	start := strings.Index(frontmatter, "\n") + 1
+	end := strings.LastIndex(strings.TrimRight(frontmatter, "\r\n"), "\n") + 1
+	if end < start {
+		end = start
+	}
+
+	entries := []frontmatterEntry{}
+	for _, line := range strings.SplitAfter(frontmatter[start:end], "\n") {
+		if line == "" {
+			continue
+		}
+		if match := frontmatterKeyRegex.FindStringSubmatch(line); match != nil {
+			entries = append(entries, frontmatterEntry{key: match[1], raw: line})
+		} else if len(entries) > 0 {
+			entries[len(entries)-1].raw += line
+		} else {
+			entries = append(entries, frontmatterEntry{raw: line})
+		}
+	}
+	return entries, content[loc[1]:]
+}
+
+// mergeFrontmatters merges the entries of several frontmatters, the first
+// value of a key winning. The tag entries are replaced with a single `tags`
+// entry holding the given tags. Returns an empty string when there are no
+// entries.
+func mergeFrontmatters(frontmatters [][]frontmatterEntry, tags []string) string {
+	yaml := strings.Builder{}
+	keys := map[string]bool{}
+	hasTags := false
+	for _, entries := range frontmatters {
+		for _, entry := range entries {
+			if strutil.InList(frontmatterTagKeys, strings.ToLower(entry.key)) {
+				if !hasTags && len(tags) > 0 {
+					yaml.WriteString("tags: " + formatYAMLList(tags) + "\n")
+				}
+				hasTags = true
+				continue
+			}
+			if keys[entry.key] {
+				continue
+			}
+			keys[entry.key] = true
+			yaml.WriteString(entry.raw)
+			if !strings.HasSuffix(entry.raw, "\n") {
+				yaml.WriteString("\n")
+			}
+		}
+	}
+	if yaml.Len() == 0 {
+		return ""
+	}
+	return "---\n" + yaml.String() + "---\n\n"
+}
+
+// frontmatterTags returns the tags declared in the given frontmatter
+// metadata.
+func frontmatterTags(metadata map[string]interface{}) []string {
+	tags := []string{}
+	for _, key := range frontmatterTagKeys {
+		switch value := metadata[key].(type) {
+		case []interface{}:
+			for _, tag := range value {
+				tags = append(tags, strings.TrimPrefix(fmt.Sprint(tag), "#"))
+			}
+		case string:
+			for _, tag := range strings.Fields(value) {
+				tags = append(tags, strings.TrimPrefix(tag, "#"))
+			}
+		}
+	}
+	return tags
+}
+
+// formatYAMLList formats the given strings as a YAML flow sequence.
+func formatYAMLList(items []string) string {
+	formatted := []string{}
+	for _, item := range items {
+		if item == "" || strings.ContainsAny(item, " ,[]{}:#&*!|>'\"%@`\\") {
+			item = strconv.Quote(item)
+		}
+		formatted = append(formatted, item)
+	}
+	return "[" + strings.Join(formatted, ", ") + "]"
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestSplitFrontmatter(t *testing.T) {
+	test := func(content string, expectedEntries []frontmatterEntry, expectedContent string) {
+		t.Helper()
+		entries, content := splitFrontmatter(content)
+		assert.Equal(t, entries, expectedEntries)
+		assert.Equal(t, content, expectedContent)
+	}
+
+	test("# Title\n", []frontmatterEntry{}, "# Title\n")
+	test("---\ntitle: Pizza\ntags:\n  - food\n- italy\n# Comment\nauthor : me\n---\n\n# Pizza\n",
+		[]frontmatterEntry{
+			{key: "title", raw: "title: Pizza\n"},
+			{key: "tags", raw: "tags:\n  - food\n- italy\n# Comment\n"},
+			{key: "author", raw: "author : me\n"},
+		},
+		"\n# Pizza\n",
+	)
+	test("---\n# Leading comment\nurl: https://example.com\n---\n",
+		[]frontmatterEntry{
+			{raw: "# Leading comment\n"},
+			{key: "url", raw: "url: https://example.com\n"},
+		},
+		"",
+	)
+}
+
+func TestUnlinkLinksTo(t *testing.T) {
+	test := func(content string, notePath string, expected string) {
+		t.Helper()
+		assert.Equal(t, unlinkLinksTo(content, notePath, []string{notePath, "dir/b.md"}), expected)
+	}
+
+	test("See [Gamma](merged.md) and [Other](other.md).", "merged.md", "See Gamma and [Other](other.md).")
+	test("See [Gamma](../dir/merged) and [[dir/merged]].", "dir/merged.md", "See Gamma and dir/merged.")
+	test("See [[merged|Gamma]] and [[other]].", "dir/merged.md", "See Gamma and [[other]].")
+	test("See [Gamma](merged.md#intro) and [[merged#intro]].", "merged.md", "See Gamma and merged.")
+	test("See [Gamma](https://example.com/merged.md).", "merged.md", "See [Gamma](https://example.com/merged.md).")
+	test("See [Beta](dir/b.md), [[b]] and [[b|the beta]].", "merged.md", "See Beta, b and the beta.")
+}
+
+func TestNotebookMergeNotesUnlinksMergedNotes(t *testing.T) {
+	notebook, fs := newResolveTestNotebook(t, map[string]*NoteContent{
+		"a.md":     {Title: opt.NewString("Alpha")},
+		"b.md":     {Title: opt.NewString("Beta")},
+		"other.md": {Title: opt.NewString("Other")},
+	})
+	fs.files[filepath.Join(notebook.Path, "a.md")] = "# Alpha\n\nSee [[b]] and [Other](other.md).\n"
+	fs.files[filepath.Join(notebook.Path, "b.md")] = "# Beta\n\nBack to [the alpha](a.md).\n"
+
+	merge, err := notebook.MergeNotes(MergeNotesOpts{
+		Paths:  []string{"a.md", "b.md"},
+		Into:   filepath.Join(notebook.Path, "ab.md"),
+		DryRun: true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, merge.TargetPath, "ab.md")
+	assert.Equal(t, merge.Content, "# Alpha\n\nSee b and [Other](other.md).\n\n# Beta\n\nBack to the alpha.\n")
+}
+
+func TestMergeFrontmatters(t *testing.T) {
+	assert.Equal(t, mergeFrontmatters([][]frontmatterEntry{{}, {}}, []string{}), "")
+
+	assert.Equal(t,
+		mergeFrontmatters([][]frontmatterEntry{
+			{
+				{key: "title", raw: "title: Pizza\n"},
+				{key: "tags", raw: "tags: [food]\n"},
+			},
+			{},
+			{
+				{key: "title", raw: "title: Dough\n"},
+				{key: "keywords", raw: "keywords:\n  - recipe\n"},
+				{key: "source", raw: "source: book"},
+			},
+		}, []string{"food", "recipe", "two words"}),
+		"---\ntitle: Pizza\ntags: [food, recipe, \"two words\"]\nsource: book\n---\n\n",
+	)
+}
+
+func TestFrontmatterTags(t *testing.T) {
+	assert.Equal(t, frontmatterTags(map[string]interface{}{}), []string{})
+	assert.Equal(t,
+		frontmatterTags(map[string]interface{}{
+			"tags":     []interface{}{"food", "#italy", 2021},
+			"keywords": "recipe #dough",
+			"title":    "Pizza",
+		}),
+		[]string{"food", "italy", "2021", "recipe", "dough"},
+	)
+}
@@ -0,0 +1,510 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/paths"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// MoveNoteOpts holds the options used to move a note in a Notebook.
+type MoveNoteOpts struct {
+	// Path to the note to move.
+	Path string
+	// Destination file path or directory of the note.
+	Destination string
+	// Reports the move and the links to update, without touching any
+	// file.
+	DryRun bool
+	// Command moving the note, e.g. zk mv or zk archive.
+	Origin AuditOrigin
+}
+
+// NoteMove reports the changes made when moving a note.
+type NoteMove struct {
+	// Path of the note before the move, relative to the notebook root.
+	SourcePath string `json:"sourcePath"`
+	// Path of the note after the move, relative to the notebook root.
+	TargetPath string `json:"targetPath"`
+	// Links updated to follow the moved note.
+	RewrittenLinks []RewrittenLink `json:"rewrittenLinks"`
+}
+
+// RewrittenLink is a link updated after moving or removing a note.
+type RewrittenLink struct {
+	// Path of the note containing the link, relative to the notebook root.
+	Path    string `json:"path"`
+	OldHref string `json:"oldHref"`
+	// New destination of the link, or empty if it was replaced by its plain
+	// text.
+	NewHref string `json:"newHref"`
+}
+
+// String implements Stringer.
+func (l RewrittenLink) String() string {
+	switch l.NewHref {
+	case "":
+		return fmt.Sprintf("%s: %s (unlinked)", l.Path, l.OldHref)
+	case l.OldHref:
+		return fmt.Sprintf("%s: %s", l.Path, l.OldHref)
+	default:
+		return fmt.Sprintf("%s: %s -> %s", l.Path, l.OldHref, l.NewHref)
+	}
+}
+
+// NotePaths returns the paths of the notes containing rewritten links.
+func (m NoteMove) NotePaths() []string {
+	paths := []string{}
+	for _, link := range m.RewrittenLinks {
+		if len(paths) == 0 || paths[len(paths)-1] != link.Path {
+			paths = append(paths, link.Path)
+		}
+	}
+	return paths
+}
+
+// MoveNote moves or renames a note, and updates all the links to it across
+// the notebook.
+func (n *Notebook) MoveNote(opts MoveNoteOpts) (*NoteMove, error) {
+	wrap := errors.Wrapperf("%s: failed to move the note", opts.Path)
+
+	note, err := n.indexedNoteAt(opts.Path)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	sourcePath := note.Path
+
+	targetPath, err := n.moveTargetPath(sourcePath, opts.Destination)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if targetPath == sourcePath {
+		return nil, wrap(errors.New("the note is already at this location"))
+	}
+	absTargetPath := filepath.Join(n.Path, targetPath)
+	exists, err := n.fs.FileExists(absTargetPath)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if exists {
+		return nil, wrap(fmt.Errorf("%s: a file already exists at this location", targetPath))
+	}
+
+	move := NoteMove{
+		SourcePath:     sourcePath,
+		TargetPath:     targetPath,
+		RewrittenLinks: []RewrittenLink{},
+	}
+
+	linkingPaths, err := n.findNotesLinkingTo(*note)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	// New content of the notes with rewritten links, indexed by their path
+	// after the move.
+	contents := map[string]string{}
+	err = n.rewriteLinksToMovedNote(&move, linkingPaths, sourcePath, contents)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	if opts.DryRun {
+		return &move, nil
+	}
+
+	err = n.commitIndex(func(index NoteIndex) error {
+		err := n.fs.Rename(filepath.Join(n.Path, sourcePath), absTargetPath)
+		if err != nil {
+			return err
+		}
+		for path, content := range contents {
+			err := n.fs.Write(filepath.Join(n.Path, path), []byte(content))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = index.Remove(sourcePath)
+		if err != nil {
+			return err
+		}
+		movedNote, err := n.ParseNoteAt(absTargetPath)
+		if err != nil {
+			return err
+		}
+		_, err = index.Add(*movedNote)
+		if err != nil {
+			return err
+		}
+		for path := range contents {
+			if path == targetPath {
+				continue
+			}
+			note, err := n.ParseNoteAt(filepath.Join(n.Path, path))
+			if err != nil {
+				return err
+			}
+			err = index.Update(*note)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	n.audit(opts.Origin, AuditOperationMove, fmt.Sprintf("%d %s rewritten", len(move.RewrittenLinks), strutil.Pluralize("link", len(move.RewrittenLinks))), sourcePath, targetPath)
+	return &move, nil
+}
+
+// RelinkRenamedNotes rewrites the links to the notes renamed or moved outside
+// of zk, detected while indexing the notebook. The given moves are returned
+// with their rewritten links.
+func (n *Notebook) RelinkRenamedNotes(origin AuditOrigin, renames []NoteMove, dryRun bool) ([]NoteMove, error) {
+	wrap := errors.Wrapper("failed to update the links to the renamed notes")
+
+	// The same note might link to several renamed notes.
+	contents := map[string]string{}
+	moves := []NoteMove{}
+	for _, rename := range renames {
+		move := NoteMove{
+			SourcePath:     rename.SourcePath,
+			TargetPath:     rename.TargetPath,
+			RewrittenLinks: []RewrittenLink{},
+		}
+		linkingPaths, err := n.findNotesLinkingToPath(rename.SourcePath)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		err = n.rewriteLinksToMovedNote(&move, linkingPaths, rename.TargetPath, contents)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		moves = append(moves, move)
+	}
+
+	if dryRun || len(contents) == 0 {
+		return moves, nil
+	}
+
+	_, err := n.writeReplacedNotes(contents)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	for _, move := range moves {
+		if len(move.RewrittenLinks) == 0 {
+			continue
+		}
+		n.audit(origin, AuditOperationMove, fmt.Sprintf("renamed outside of zk, %d %s rewritten", len(move.RewrittenLinks), strutil.Pluralize("link", len(move.RewrittenLinks))), move.SourcePath, move.TargetPath)
+	}
+	return moves, nil
+}
+
+// rewriteLinksToMovedNote rewrites the links of the notes at linkingPaths to
+// follow the given move, as well as the relative links of the moved note
+// itself, currently at movedPath. The new contents are stored in contents, by
+// path after the move, which also holds the content of the notes already
+// rewritten.
+func (n *Notebook) rewriteLinksToMovedNote(move *NoteMove, linkingPaths []string, movedPath string, contents map[string]string) error {
+	sourcePath, targetPath := move.SourcePath, move.TargetPath
+	read := func(path string, currentPath string) (string, error) {
+		if content, ok := contents[path]; ok {
+			return content, nil
+		}
+		content, err := n.fs.Read(filepath.Join(n.Path, currentPath))
+		return string(content), err
+	}
+
+	for _, path := range linkingPaths {
+		if path == sourcePath || path == targetPath {
+			continue
+		}
+		content, err := read(path, path)
+		if err != nil {
+			return err
+		}
+		newContent, links := rewriteLinks(content, path, func(href string, isWikiLink bool) (string, bool) {
+			return relinkHref(href, isWikiLink, path, sourcePath, targetPath)
+		})
+		if len(links) > 0 {
+			contents[path] = newContent
+			move.RewrittenLinks = append(move.RewrittenLinks, links...)
+		}
+	}
+
+	// The relative links of the moved note itself need to be updated when
+	// changing directory.
+	if filepath.Dir(sourcePath) != filepath.Dir(targetPath) {
+		content, err := read(targetPath, movedPath)
+		if err != nil {
+			return err
+		}
+		newContent, links := rewriteLinks(content, targetPath, func(href string, isWikiLink bool) (string, bool) {
+			return rebaseHref(href, isWikiLink, sourcePath, targetPath)
+		})
+		if len(links) > 0 {
+			contents[targetPath] = newContent
+			move.RewrittenLinks = append(move.RewrittenLinks, links...)
+		}
+	}
+
+	sort.SliceStable(move.RewrittenLinks, func(i, j int) bool {
+		return move.RewrittenLinks[i].Path < move.RewrittenLinks[j].Path
+	})
+	return nil
+}
+
+// indexedNoteAt returns the indexed note at the given file path, which can be
+// relative to the working directory.
+func (n *Notebook) indexedNoteAt(path string) (*MinimalNote, error) {
+	relPath, err := n.RelPath(path)
+	if err != nil {
+		return nil, err
+	}
+	note, err := n.FindMinimalNote(NoteFindOpts{IncludePaths: []string{relPath}})
+	if err != nil {
+		return nil, err
+	}
+	if note == nil || note.Path != relPath {
+		return nil, ErrNoteNotFound(relPath)
+	}
+	return note, nil
+}
+
+// moveTargetPath returns the path relative to the notebook root where a note
+// will be moved, from the destination given by the user.
+func (n *Notebook) moveTargetPath(sourcePath string, destination string) (string, error) {
+	absDestination, err := n.fs.Abs(destination)
+	if err != nil {
+		return "", err
+	}
+	isDir, err := n.fs.DirExists(absDestination)
+	if err != nil {
+		return "", err
+	}
+	if isDir || strings.HasSuffix(destination, string(filepath.Separator)) {
+		absDestination = filepath.Join(absDestination, filepath.Base(sourcePath))
+	}
+
+	targetPath, err := n.RelPath(absDestination)
+	if err != nil {
+		return "", err
+	}
+
+	// The extension can be omitted, in which case we use the one configured
+	// for the destination group.
+	if filepath.Ext(targetPath) == "" {
+		group, err := n.Config.GroupConfigForPath(targetPath)
+		if err != nil {
+			return "", err
+		}
+		targetPath += "." + group.Note.Extension
+	}
+
+	return targetPath, nil
+}
+
+// findNotesLinkingTo returns the paths of the notes which might contain a
+// link to the given note.
+func (n *Notebook) findNotesLinkingTo(note MinimalNote) ([]string, error) {
+	found := map[string]bool{}
+
+	backlinks, err := n.FindMinimalNotes(NoteFindOpts{
+		LinkTo: &LinkFilter{Paths: []string{note.Path}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, backlink := range backlinks {
+		found[backlink.Path] = true
+	}
+
+	// Some links are not resolved by the index, for example wiki-links
+	// targeting only the note ID from a sub-directory.
+	mentions, err := n.findNotesLinkingToPath(note.Path)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range mentions {
+		found[path] = true
+	}
+
+	res := []string{}
+	for path := range found {
+		res = append(res, path)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// findNotesLinkingToPath returns the paths of the notes which might contain a
+// link to the given path, whether a note is indexed at this path or not.
+func (n *Notebook) findNotesLinkingToPath(path string) ([]string, error) {
+	mentions, err := n.FindMinimalNotes(NoteFindOpts{
+		Match:      opt.NewString(paths.FilenameStem(path)),
+		ExactMatch: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := []string{}
+	for _, mention := range mentions {
+		res = append(res, mention.Path)
+	}
+	return res, nil
+}
+
+var (
+	markdownLinkDestinationRegex = regexp.MustCompile(`\]\(\s*(<[^>\n]*>|[^)\s]+)`)
+	wikiLinkDestinationRegex     = regexp.MustCompile(`\[\[+\s*([^\]|#\n]*[^\]|#\s])`)
+)
+
+// rewriteLinks replaces the destination of the links found in the given
+// note content, using the relink callback.
+func rewriteLinks(content string, notePath string, relink func(href string, isWikiLink bool) (string, bool)) (string, []RewrittenLink) {
+	type destination struct {
+		start, end int
+		isWikiLink bool
+	}
+	destinations := []destination{}
+	for _, match := range markdownLinkDestinationRegex.FindAllStringSubmatchIndex(content, -1) {
+		destinations = append(destinations, destination{match[2], match[3], false})
+	}
+	for _, match := range wikiLinkDestinationRegex.FindAllStringSubmatchIndex(content, -1) {
+		destinations = append(destinations, destination{match[2], match[3], true})
+	}
+	// Replace from the end, to keep the offsets of the previous
+	// destinations valid.
+	sort.Slice(destinations, func(i, j int) bool {
+		return destinations[i].start > destinations[j].start
+	})
+
+	links := []RewrittenLink{}
+	for _, dest := range destinations {
+		start, end := dest.start, dest.end
+		href := content[start:end]
+		if strings.HasPrefix(href, "<") {
+			start, end = start+1, end-1
+			href = content[start:end]
+		}
+		newHref, ok := relink(href, dest.isWikiLink)
+		if !ok || newHref == href {
+			continue
+		}
+		content = content[:start] + newHref + content[end:]
+		// Prepend to report the links in the order of the document.
+		links = append([]RewrittenLink{{
+			Path:    notePath,
+			OldHref: href,
+			NewHref: newHref,
+		}}, links...)
+	}
+
+	return content, links
+}
+
+// splitHref splits a link href into its unescaped path and any anchor
+// suffix, e.g. `#heading`.
+func splitHref(href string) (path string, anchor string, escaped bool) {
+	parts := strings.SplitN(href, "#", 2)
+	path = parts[0]
+	if len(parts) > 1 {
+		anchor = "#" + parts[1]
+	}
+	if unescaped, err := url.PathUnescape(path); err == nil && unescaped != path {
+		path = unescaped
+		escaped = true
+	}
+	return
+}
+
+// joinHref builds a link href from the given path and anchor.
+func joinHref(path string, anchor string, escape bool) string {
+	path = filepath.ToSlash(path)
+	if escape {
+		path = strings.ReplaceAll(url.PathEscape(path), "%2F", "/")
+	}
+	return path + anchor
+}
+
+// relinkHref returns the new href for a link found in the note at notePath,
+// if it targets the note moved from sourcePath to targetPath.
+func relinkHref(href string, isWikiLink bool, notePath string, sourcePath string, targetPath string) (string, bool) {
+	if strutil.IsURL(href) {
+		return "", false
+	}
+	path, anchor, escaped := splitHref(href)
+	if path == "" {
+		return "", false
+	}
+
+	matches := func(path string) (ok bool, withExt bool) {
+		path = filepath.Clean(path)
+		switch path {
+		case sourcePath:
+			return true, true
+		case paths.DropExt(sourcePath):
+			return true, false
+		default:
+			return false, false
+		}
+	}
+	newPath := func(dir string, withExt bool) string {
+		path := targetPath
+		if !withExt {
+			path = paths.DropExt(path)
+		}
+		if dir != "" && dir != "." {
+			if rel, err := filepath.Rel(dir, path); err == nil {
+				path = rel
+			}
+		}
+		return path
+	}
+
+	noteDir := filepath.Dir(notePath)
+	if ok, withExt := matches(filepath.Join(noteDir, path)); ok {
+		return joinHref(newPath(noteDir, withExt), anchor, escaped), true
+	}
+
+	if isWikiLink {
+		// Wiki-links are usually relative to the notebook root, or contain
+		// only the ID of the target note.
+		if ok, withExt := matches(path); ok {
+			return joinHref(newPath("", withExt), anchor, escaped), true
+		}
+		if path == paths.FilenameStem(sourcePath) {
+			return joinHref(paths.FilenameStem(targetPath), anchor, escaped), true
+		}
+	}
+
+	return "", false
+}
+
+// rebaseHref returns the new href for a relative Markdown link found in a
+// note moved from sourcePath to targetPath.
+func rebaseHref(href string, isWikiLink bool, sourcePath string, targetPath string) (string, bool) {
+	if isWikiLink || strutil.IsURL(href) {
+		return "", false
+	}
+	path, anchor, escaped := splitHref(href)
+	if path == "" || filepath.IsAbs(path) {
+		return "", false
+	}
+
+	path = filepath.Join(filepath.Dir(sourcePath), path)
+	path, err := filepath.Rel(filepath.Dir(targetPath), path)
+	if err != nil {
+		return "", false
+	}
+	return joinHref(path, anchor, escaped), true
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestRewriteLinksToMovedNote(t *testing.T) {
+	test := func(notePath string, content string, expectedContent string, expectedLinks []RewrittenLink) {
+		actualContent, actualLinks := rewriteLinks(content, notePath, func(href string, isWikiLink bool) (string, bool) {
+			return relinkHref(href, isWikiLink, notePath, "dir/old name.md", "other/new.md")
+		})
+		assert.Equal(t, actualContent, expectedContent)
+		assert.Equal(t, actualLinks, expectedLinks)
+	}
+
+	test("index.md", "No links", "No links", []RewrittenLink{})
+
+	// Markdown links relative to the note.
+	test("index.md",
+		"A [link](dir/old%20name.md#heading), [another](dir/old%20name) and [unrelated](dir/other.md).",
+		"A [link](other/new.md#heading), [another](other/new) and [unrelated](dir/other.md).",
+		[]RewrittenLink{
+			{Path: "index.md", OldHref: "dir/old%20name.md#heading", NewHref: "other/new.md#heading"},
+			{Path: "index.md", OldHref: "dir/old%20name", NewHref: "other/new"},
+		},
+	)
+	test("dir/sibling.md",
+		"An ![image link](<old name.md>) and an [external](https://example.com/old%20name.md) one.",
+		"An ![image link](<../other/new.md>) and an [external](https://example.com/old%20name.md) one.",
+		[]RewrittenLink{
+			{Path: "dir/sibling.md", OldHref: "old name.md", NewHref: "../other/new.md"},
+		},
+	)
+
+	// Wiki-links relative to the notebook root or with the note ID.
+	test("dir/sub/note.md",
+		"A [[dir/old name]] wiki-link, a [[old name | titled]] one and [[other]].",
+		"A [[other/new]] wiki-link, a [[new | titled]] one and [[other]].",
+		[]RewrittenLink{
+			{Path: "dir/sub/note.md", OldHref: "dir/old name", NewHref: "other/new"},
+			{Path: "dir/sub/note.md", OldHref: "old name", NewHref: "new"},
+		},
+	)
+}
+
+func TestRewriteLinksOfMovedNote(t *testing.T) {
+	content, links := rewriteLinks(
+		"A [sibling](sibling.md), an [anchor](#heading), a [[wiki-link]] and a [[parent]](../parent.md).",
+		"other/sub/new.md",
+		func(href string, isWikiLink bool) (string, bool) {
+			return rebaseHref(href, isWikiLink, "dir/old.md", "other/sub/new.md")
+		},
+	)
+
+	assert.Equal(t, content, "A [sibling](../../dir/sibling.md), an [anchor](#heading), a [[wiki-link]] and a [[parent]](../../parent.md).")
+	assert.Equal(t, links, []RewrittenLink{
+		{Path: "other/sub/new.md", OldHref: "sibling.md", NewHref: "../../dir/sibling.md"},
+		{Path: "other/sub/new.md", OldHref: "../parent.md", NewHref: "../../parent.md"},
+	})
+}
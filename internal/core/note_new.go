@@ -16,10 +16,12 @@ type newNoteTask struct {
 	extra            map[string]string
 	env              map[string]string
 	fs               FileStorage
+	filename         string // Overrides filenameTemplate when not empty.
 	filenameTemplate string
 	bodyTemplatePath opt.String
 	templates        TemplateLoader
 	genID            IDGenerator
+	reserveID        func(id string) (bool, error)
 }
 
 func (t *newNoteTask) execute() (string, error) {
@@ -70,10 +72,23 @@ func (c *newNoteTask) generatePath(context newNoteTemplateContext, filenameTempl
 
 	for i := 0; i < 50; i++ {
 		context.ID = c.genID()
+		if context.ID != "" && c.reserveID != nil {
+			// Another process might be creating a note with the same ID.
+			reserved, err := c.reserveID(context.ID)
+			if err != nil {
+				return "", context, err
+			} else if !reserved {
+				continue
+			}
+		}
 
-		filename, err = filenameTemplate.Render(context)
-		if err != nil {
-			return "", context, err
+		if c.filename != "" {
+			filename = c.filename
+		} else {
+			filename, err = filenameTemplate.Render(context)
+			if err != nil {
+				return "", context, err
+			}
 		}
 
 		path = filepath.Join(c.dir.Path, filename)
@@ -84,6 +99,9 @@ func (c *newNoteTask) generatePath(context newNoteTemplateContext, filenameTempl
 			context.Filename = filepath.Base(path)
 			context.FilenameStem = paths.FilenameStem(path)
 			return path, context, nil
+		} else if c.filename != "" {
+			// Another ID won't help.
+			break
 		}
 	}
 
@@ -356,6 +356,26 @@ func TestNotebookNewNoteTriesUntilFreePath(t *testing.T) {
 	assert.Equal(t, test.fs.files["/notebook/filename4.ext"], "body")
 }
 
+func TestNotebookNewNoteSkipsReservedIDs(t *testing.T) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+		filenameTemplateRender: func(context newNoteTemplateContext) string {
+			return "filename" + context.ID + ".ext"
+		},
+		idGeneratorFactory: incrementingID,
+	}
+	test.setup()
+	test.index.ReservedIDs = []string{"1", "2"}
+
+	note, err := test.run(NewNoteOpts{
+		Date: now,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, note.Path, "filename3.ext")
+	assert.Equal(t, test.index.ReservedIDs, []string{"1", "2", "3"})
+}
+
 func TestNotebookNewNoteErrorWhenNoFreePath(t *testing.T) {
 	files := map[string]string{}
 	for i := 1; i < 51; i++ {
@@ -379,6 +399,54 @@ func TestNotebookNewNoteErrorWhenNoFreePath(t *testing.T) {
 	assert.Equal(t, test.fs.files, files)
 }
 
+func TestNotebookNewNotes(t *testing.T) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+		filenameTemplateRender: func(context newNoteTemplateContext) string {
+			return "filename" + context.ID + ".ext"
+		},
+		idGeneratorFactory: incrementingID,
+	}
+	test.setup()
+
+	notes, err := test.runBatch([]NewNoteOpts{
+		{Title: opt.NewString("First"), Extra: map[string]string{"first": "1"}, Date: now},
+		{Title: opt.NewString("Second"), Date: now},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, len(notes), 2)
+	assert.Equal(t, notes[0].Path, "filename1.ext")
+	assert.Equal(t, notes[1].Path, "filename2.ext")
+	assert.Equal(t, test.fs.files["/notebook/filename1.ext"], "body")
+	assert.Equal(t, test.fs.files["/notebook/filename2.ext"], "body")
+
+	// The extra variables of a note are not leaked to the next ones.
+	extras := []map[string]string{}
+	for _, context := range test.bodyTemplate.Contexts {
+		extras = append(extras, context.(newNoteTemplateContext).Extra)
+	}
+	assert.Equal(t, extras, []map[string]string{
+		{"first": "1", "conf-extra": "38srnw"},
+		{"conf-extra": "38srnw"},
+	})
+}
+
+func TestNotebookNewNotesRemovesCreatedNotesOnError(t *testing.T) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+	}
+	test.setup()
+
+	_, err := test.runBatch([]NewNoteOpts{
+		{Date: now},
+		{Directory: opt.NewString("/notebook/a-dir"), Date: now},
+	})
+
+	assert.Err(t, err, "new notes: /notebook/a-dir: directory not found")
+	assert.Equal(t, test.fs.files, map[string]string{})
+}
+
 var now = time.Date(2009, 11, 17, 20, 34, 58, 651387237, time.UTC)
 
 // newNoteTest builds and runs the SUT for new note test cases.
@@ -470,7 +538,15 @@ func (t *newNoteTest) parseContentAsNote(content string, note *NoteContent) {
 }
 
 func (t *newNoteTest) run(opts NewNoteOpts) (*Note, error) {
-	notebook := NewNotebook(t.rootDir, t.config, NotebookPorts{
+	return t.notebook().NewNote(opts)
+}
+
+func (t *newNoteTest) runBatch(opts []NewNoteOpts) ([]*Note, error) {
+	return t.notebook().NewNotes(opts)
+}
+
+func (t *newNoteTest) notebook() *Notebook {
+	return NewNotebook(t.rootDir, t.config, NotebookPorts{
 		TemplateLoaderFactory: func(language string) (TemplateLoader, error) {
 			t.receivedLang = language
 			return t.templateLoader, nil
@@ -485,8 +561,6 @@ func (t *newNoteTest) run(opts NewNoteOpts) (*Note, error) {
 		Logger:            &util.NullLogger,
 		OSEnv:             func() map[string]string { return t.osEnv },
 	})
-
-	return notebook.NewNote(opts)
 }
 
 // incrementingID returns a generator of incrementing string ID.
@@ -499,18 +573,100 @@ func incrementingID(opts IDOptions) func() string {
 }
 
 type noteIndexAddMock struct {
-	ReturnedID NoteID
+	ReturnedID  NoteID
+	ReservedIDs []string
+	Notes       []ContextualNote
 }
 
-func (m *noteIndexAddMock) Find(opts NoteFindOpts) ([]ContextualNote, error)     { return nil, nil }
+func (m *noteIndexAddMock) Find(opts NoteFindOpts) ([]ContextualNote, error)     { return m.Notes, nil }
 func (m *noteIndexAddMock) FindMinimal(opts NoteFindOpts) ([]MinimalNote, error) { return nil, nil }
 func (m *noteIndexAddMock) FindCollections(kind CollectionKind, sorters []CollectionSorter) ([]Collection, error) {
 	return nil, nil
 }
+func (m *noteIndexAddMock) FindUnresolvedLinks() ([]UnresolvedLink, error)     { return nil, nil }
+func (m *noteIndexAddMock) FindNoteLinks() ([]NoteLink, error)                 { return nil, nil }
 func (m *noteIndexAddMock) IndexedPaths() (<-chan paths.Metadata, error)       { return nil, nil }
 func (m *noteIndexAddMock) Add(note Note) (NoteID, error)                      { return m.ReturnedID, nil }
 func (m *noteIndexAddMock) Update(note Note) error                             { return nil }
+func (m *noteIndexAddMock) Touch(path string, modified time.Time) error        { return nil }
 func (m *noteIndexAddMock) Remove(path string) error                           { return nil }
-func (m *noteIndexAddMock) Commit(transaction func(idx NoteIndex) error) error { return nil }
+func (m *noteIndexAddMock) Commit(transaction func(idx NoteIndex) error) error { return transaction(m) }
 func (m *noteIndexAddMock) NeedsReindexing() (bool, error)                     { return false, nil }
 func (m *noteIndexAddMock) SetNeedsReindexing(needsReindexing bool) error      { return nil }
+func (m *noteIndexAddMock) Vacuum() error                                      { return nil }
+func (m *noteIndexAddMock) Query(query string) (IndexQueryResult, error) {
+	return IndexQueryResult{}, nil
+}
+func (m *noteIndexAddMock) LastIndexing() (time.Time, time.Duration, error) {
+	return time.Time{}, 0, nil
+}
+func (m *noteIndexAddMock) SetLastIndexing(date time.Time, duration time.Duration) error {
+	return nil
+}
+func (m *noteIndexAddMock) IndexedAssetPaths() (<-chan paths.Metadata, error) { return nil, nil }
+func (m *noteIndexAddMock) SetAssetText(asset paths.Metadata, text string) error {
+	return nil
+}
+func (m *noteIndexAddMock) RemoveAssetText(path string) error { return nil }
+func (m *noteIndexAddMock) FindUnfetchedURLs(fetchedBefore time.Time, limit int) ([]string, error) {
+	return nil, nil
+}
+func (m *noteIndexAddMock) FindURLMetadata(url string) (*URLMetadata, error) { return nil, nil }
+func (m *noteIndexAddMock) SetURLMetadata(metadata URLMetadata) error        { return nil }
+func (m *noteIndexAddMock) FindUnarchivedURLs(limit int) ([]string, error)   { return nil, nil }
+func (m *noteIndexAddMock) SetURLArchive(url string, archive string) error   { return nil }
+func (m *noteIndexAddMock) FindDeadURLs() ([]URLMetadata, error)             { return nil, nil }
+func (m *noteIndexAddMock) FindTasks(opts TaskFindOpts) ([]NoteTask, error)  { return nil, nil }
+func (m *noteIndexAddMock) FindReviews(opts ReviewFindOpts) ([]NoteReview, error) {
+	return nil, nil
+}
+func (m *noteIndexAddMock) SetReview(path string, review Review) error { return nil }
+func (m *noteIndexAddMock) FindRelated(path string, opts RelatedNotesOpts) ([]RelatedNote, error) {
+	return nil, nil
+}
+func (m *noteIndexAddMock) FindHistory(limit int) ([]NoteHistoryEntry, error) { return nil, nil }
+func (m *noteIndexAddMock) AddHistory(path string, action NoteHistoryAction, date time.Time) error {
+	return nil
+}
+func (m *noteIndexAddMock) ReserveID(id string) (bool, error) {
+	for _, reservedID := range m.ReservedIDs {
+		if reservedID == id {
+			return false, nil
+		}
+	}
+	m.ReservedIDs = append(m.ReservedIDs, id)
+	return true, nil
+}
+
+func TestNotebookNewNoteWithFilename(t *testing.T) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+	}
+	test.setup()
+
+	note, err := test.run(NewNoteOpts{
+		Filename: opt.NewString("ideas/garden.md"),
+		Date:     now,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, note.Path, "ideas/garden.md")
+	assert.Equal(t, test.fs.files["/notebook/ideas/garden.md"], "body")
+}
+
+func TestNotebookNewNoteWithExistingFilename(t *testing.T) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+		files: map[string]string{
+			"/notebook/garden.md": "existing",
+		},
+	}
+	test.setup()
+
+	_, err := test.run(NewNoteOpts{
+		Filename: opt.NewString("garden.md"),
+		Date:     now,
+	})
+
+	assert.Err(t, err, "/notebook/garden.md: note already exists")
+}
@@ -36,6 +36,8 @@ type NoteContent struct {
 	Tags []string
 	// Links is the list of outbound links found in the note.
 	Links []Link
+	// Tasks is the list of checkbox items found in the note.
+	Tasks []Task
 	// Additional metadata. For example, extracted from a YAML frontmatter.
 	Metadata map[string]interface{}
 }
@@ -67,10 +69,12 @@ func (n *Notebook) ParseNoteAt(absPath string) (*Note, error) {
 		RawContent: contentStr,
 		WordCount:  len(strings.Fields(contentStr)),
 		Links:      make([]Link, 0),
-		Tags:       contentParts.Tags,
+		Tags:       n.Config.CanonicalTags(contentParts.Tags),
+		Tasks:      contentParts.Tasks,
 		Metadata:   contentParts.Metadata,
 		Checksum:   fmt.Sprintf("%x", sha256.Sum256(content)),
 	}
+	note.Summary = NoteSummary(note.Metadata, note.Lead)
 
 	for _, link := range contentParts.Links {
 		if !strutil.IsURL(link.Href) {
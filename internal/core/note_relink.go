@@ -0,0 +1,198 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// RelinkOpts holds the options used to retarget the links to a note.
+type RelinkOpts struct {
+	// Path to the note whose links are retargeted. It doesn't need to be
+	// indexed, e.g. to fix the links to a deleted note.
+	Source string
+	// Pattern matching the destination of the retargeted links, without
+	// their anchor, used instead of Source.
+	Pattern *regexp.Regexp
+	// Path to the note targeted by the links after the change.
+	Target string
+	// Lists the links to retarget, leaving the notes untouched.
+	DryRun bool
+	// Command retargeting the links.
+	Origin AuditOrigin
+}
+
+// NoteRelink reports the changes made when retargeting the links to a note.
+type NoteRelink struct {
+	// Path of the note targeted by the links after the change, relative to
+	// the notebook root.
+	TargetPath string `json:"targetPath"`
+	// Links updated to target the note.
+	RewrittenLinks []RewrittenLink `json:"rewrittenLinks"`
+	// Lines modified in each note, for the notes containing rewritten links.
+	Changes []NoteReplacement `json:"changes"`
+}
+
+// NotePaths returns the paths of the notes containing rewritten links.
+func (r NoteRelink) NotePaths() []string {
+	return NoteMove{RewrittenLinks: r.RewrittenLinks}.NotePaths()
+}
+
+// Relink rewrites the wiki-links and Markdown links targeting a note, or
+// matching a pattern, to target another note instead, for example when
+// merging duplicate notes.
+//
+// The links found in the target note itself are left untouched, as they
+// would point to the note itself.
+func (n *Notebook) Relink(opts RelinkOpts) (*NoteRelink, error) {
+	wrap := errors.Wrapper("failed to relink the notes")
+
+	if (opts.Source == "") == (opts.Pattern == nil) {
+		return nil, wrap(errors.New("expected either a source note or a pattern"))
+	}
+
+	target, err := n.indexedNoteAt(opts.Target)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	targetPath := target.Path
+
+	var (
+		linkingPaths []string
+		relink       func(href string, isWikiLink bool, notePath string) (string, bool)
+	)
+	if opts.Pattern != nil {
+		notes, err := n.FindMinimalNotes(NoteFindOpts{})
+		if err != nil {
+			return nil, wrap(err)
+		}
+		for _, note := range notes {
+			linkingPaths = append(linkingPaths, note.Path)
+		}
+		relink = func(href string, isWikiLink bool, notePath string) (string, bool) {
+			if strutil.IsURL(href) {
+				return "", false
+			}
+			path, _, _ := splitHref(href)
+			if path == "" || !opts.Pattern.MatchString(path) {
+				return "", false
+			}
+			return retargetHref(href, isWikiLink, notePath, targetPath)
+		}
+	} else {
+		sourcePath, err := n.RelPath(opts.Source)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		if sourcePath == targetPath {
+			return nil, wrap(errors.New("can't relink a note to itself"))
+		}
+		source, err := n.indexedNoteAt(opts.Source)
+		if _, ok := err.(ErrNoteNotFound); ok {
+			// The links to a note which doesn't exist anymore can be fixed
+			// as well.
+			linkingPaths, err = n.findNotesLinkingToPath(sourcePath)
+		} else if err == nil {
+			linkingPaths, err = n.findNotesLinkingTo(*source)
+		}
+		if err != nil {
+			return nil, wrap(err)
+		}
+		relink = func(href string, isWikiLink bool, notePath string) (string, bool) {
+			return relinkHref(href, isWikiLink, notePath, sourcePath, targetPath)
+		}
+	}
+
+	result := NoteRelink{
+		TargetPath:     targetPath,
+		RewrittenLinks: []RewrittenLink{},
+		Changes:        []NoteReplacement{},
+	}
+	// New content of the notes with rewritten links, indexed by their path.
+	contents := map[string]string{}
+
+	sort.Strings(linkingPaths)
+	for _, path := range linkingPaths {
+		if path == targetPath {
+			continue
+		}
+		content, err := n.fs.Read(filepath.Join(n.Path, path))
+		if err != nil {
+			return nil, wrap(err)
+		}
+		newContent, links := rewriteLinks(string(content), path, func(href string, isWikiLink bool) (string, bool) {
+			return relink(href, isWikiLink, path)
+		})
+		if len(links) == 0 {
+			continue
+		}
+		contents[path] = newContent
+		result.RewrittenLinks = append(result.RewrittenLinks, links...)
+		result.Changes = append(result.Changes, NoteReplacement{
+			Path:    path,
+			Changes: lineChanges(string(content), newContent),
+		})
+	}
+
+	if opts.DryRun || len(contents) == 0 {
+		return &result, nil
+	}
+
+	notePaths, err := n.writeReplacedNotes(contents)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	n.audit(opts.Origin, AuditOperationRelink,
+		fmt.Sprintf("%d %s retargeted to %s", len(result.RewrittenLinks), strutil.Pluralize("link", len(result.RewrittenLinks)), targetPath),
+		notePaths...,
+	)
+
+	return &result, nil
+}
+
+// retargetHref returns a href for a link found in the note at notePath,
+// targeting the note at targetPath while keeping the style of the given
+// href: with or without extension, and relative to the note for Markdown
+// links, to the notebook root or with only the filename stem for
+// wiki-links.
+func retargetHref(href string, isWikiLink bool, notePath string, targetPath string) (string, bool) {
+	path, anchor, escaped := splitHref(href)
+	newPath := targetPath
+	if filepath.Ext(path) == "" {
+		newPath = paths.DropExt(newPath)
+	}
+
+	if isWikiLink {
+		if !strings.Contains(path, "/") {
+			newPath = filepath.Base(newPath)
+		}
+	} else if rel, err := filepath.Rel(filepath.Dir(notePath), newPath); err == nil {
+		newPath = rel
+	}
+
+	return joinHref(newPath, anchor, escaped), true
+}
+
+// lineChanges reports the lines which differ between two versions of a note
+// content having the same number of lines.
+func lineChanges(oldContent string, newContent string) []TextChange {
+	changes := []TextChange{}
+	oldLines := strings.Split(oldContent, "\n")
+	newLines := strings.Split(newContent, "\n")
+	for i, oldLine := range oldLines {
+		if i < len(newLines) && newLines[i] != oldLine {
+			changes = append(changes, TextChange{
+				Line: i + 1,
+				Old:  oldLine,
+				New:  newLines[i],
+			})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestRetargetLinksMatchingPattern(t *testing.T) {
+	pattern := regexp.MustCompile(`^(dir/)?old`)
+	test := func(notePath string, content string, expectedContent string, expectedLinks []RewrittenLink) {
+		t.Helper()
+		actualContent, actualLinks := rewriteLinks(content, notePath, func(href string, isWikiLink bool) (string, bool) {
+			path, _, _ := splitHref(href)
+			if !pattern.MatchString(path) {
+				return "", false
+			}
+			return retargetHref(href, isWikiLink, notePath, "other/new note.md")
+		})
+		assert.Equal(t, actualContent, expectedContent)
+		assert.Equal(t, actualLinks, expectedLinks)
+	}
+
+	// Markdown links keep their extension, anchor and escaping.
+	test("index.md",
+		"A [link](dir/old.md#heading), [another](dir/old-idea) and [unrelated](dir/other.md).",
+		"A [link](other/new note.md#heading), [another](other/new note) and [unrelated](dir/other.md).",
+		[]RewrittenLink{
+			{Path: "index.md", OldHref: "dir/old.md#heading", NewHref: "other/new note.md#heading"},
+			{Path: "index.md", OldHref: "dir/old-idea", NewHref: "other/new note"},
+		},
+	)
+	test("dir/sibling.md",
+		"A [relative](old%20name.md) link.",
+		"A [relative](../other/new%20note.md) link.",
+		[]RewrittenLink{
+			{Path: "dir/sibling.md", OldHref: "old%20name.md", NewHref: "../other/new%20note.md"},
+		},
+	)
+
+	// Wiki-links keep a path relative to the notebook root, or only the
+	// filename stem.
+	test("dir/sub/note.md",
+		"A [[dir/old]] wiki-link, a [[old | titled]] one and [[other]].",
+		"A [[other/new note]] wiki-link, a [[new note | titled]] one and [[other]].",
+		[]RewrittenLink{
+			{Path: "dir/sub/note.md", OldHref: "dir/old", NewHref: "other/new note"},
+			{Path: "dir/sub/note.md", OldHref: "old", NewHref: "new note"},
+		},
+	)
+}
+
+func TestLineChanges(t *testing.T) {
+	assert.Equal(t, lineChanges("a\nb\nc", "a\nb\nc"), []TextChange{})
+	assert.Equal(t,
+		lineChanges("# Title\n\nSee [[old]].\n\nAnd [[old]] again.\n", "# Title\n\nSee [[new]].\n\nAnd [[new]] again.\n"),
+		[]TextChange{
+			{Line: 3, Old: "See [[old]].", New: "See [[new]]."},
+			{Line: 5, Old: "And [[old]] again.", New: "And [[new]] again."},
+		},
+	)
+}
@@ -0,0 +1,229 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// RemoveNoteOpts holds the options used to remove a note from a Notebook.
+type RemoveNoteOpts struct {
+	// Path to the note to remove.
+	Path string
+	// Path to another note, where the links to the removed note are
+	// redirected.
+	RedirectTo opt.String
+	// Replaces the links to the removed note with their plain text.
+	Unlink bool
+	// Only reports the links to the note, without modifying any file.
+	DryRun bool
+	// Command removing the note, e.g. zk rm or the purge of the trash.
+	Origin AuditOrigin
+}
+
+// NoteRemoval reports the changes made when removing a note.
+type NoteRemoval struct {
+	// Path of the removed note, relative to the notebook root.
+	Path string `json:"path"`
+	// Links to the removed note found across the notebook. They are left
+	// untouched, unless redirected or unlinked.
+	Backlinks []RewrittenLink `json:"backlinks"`
+}
+
+// NotePaths returns the paths of the notes linking to the removed note.
+func (r NoteRemoval) NotePaths() []string {
+	return NoteMove{RewrittenLinks: r.Backlinks}.NotePaths()
+}
+
+// RemoveNote deletes a note from the notebook and its index, after
+// optionally rewriting the links to it.
+func (n *Notebook) RemoveNote(opts RemoveNoteOpts) (*NoteRemoval, error) {
+	wrap := errors.Wrapperf("%s: failed to remove the note", opts.Path)
+
+	if opts.Unlink && !opts.RedirectTo.IsNull() {
+		return nil, wrap(errors.New("the links can't be both unlinked and redirected"))
+	}
+
+	note, err := n.indexedNoteAt(opts.Path)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	path := note.Path
+
+	redirectPath := ""
+	if !opts.RedirectTo.IsNull() {
+		redirect, err := n.indexedNoteAt(opts.RedirectTo.String())
+		if err != nil {
+			return nil, wrap(err)
+		}
+		if redirect.Path == path {
+			return nil, wrap(errors.New("can't redirect the links to the removed note itself"))
+		}
+		redirectPath = redirect.Path
+	}
+
+	removal := NoteRemoval{
+		Path:      path,
+		Backlinks: []RewrittenLink{},
+	}
+
+	// New content of the notes with rewritten links, indexed by their path.
+	contents := map[string]string{}
+
+	linkingPaths, err := n.findNotesLinkingTo(*note)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	for _, linkingPath := range linkingPaths {
+		if linkingPath == path {
+			continue
+		}
+		content, err := n.fs.Read(filepath.Join(n.Path, linkingPath))
+		if err != nil {
+			return nil, wrap(err)
+		}
+
+		var (
+			newContent string
+			links      []RewrittenLink
+		)
+		if redirectPath != "" {
+			newContent, links = rewriteLinks(string(content), linkingPath, func(href string, isWikiLink bool) (string, bool) {
+				return relinkHref(href, isWikiLink, linkingPath, path, redirectPath)
+			})
+		} else {
+			newContent, links = unlinkLinks(string(content), linkingPath, func(href string, isWikiLink bool) bool {
+				_, ok := relinkHref(href, isWikiLink, linkingPath, path, path)
+				return ok
+			})
+			if !opts.Unlink {
+				// Only report the links, which will be dead.
+				newContent = string(content)
+				for i := range links {
+					links[i].NewHref = links[i].OldHref
+				}
+			}
+		}
+
+		if len(links) > 0 {
+			if newContent != string(content) {
+				contents[linkingPath] = newContent
+			}
+			removal.Backlinks = append(removal.Backlinks, links...)
+		}
+	}
+
+	sort.SliceStable(removal.Backlinks, func(i, j int) bool {
+		return removal.Backlinks[i].Path < removal.Backlinks[j].Path
+	})
+
+	if opts.DryRun {
+		return &removal, nil
+	}
+
+	err = n.commitIndex(func(index NoteIndex) error {
+		for linkingPath, content := range contents {
+			err := n.fs.Write(filepath.Join(n.Path, linkingPath), []byte(content))
+			if err != nil {
+				return err
+			}
+		}
+		err := n.fs.Remove(filepath.Join(n.Path, path))
+		if err != nil {
+			return err
+		}
+
+		err = index.Remove(path)
+		if err != nil {
+			return err
+		}
+		for linkingPath := range contents {
+			note, err := n.ParseNoteAt(filepath.Join(n.Path, linkingPath))
+			if err != nil {
+				return err
+			}
+			err = index.Update(*note)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	details := fmt.Sprintf("%d %s rewritten", len(contents), strutil.Pluralize("note", len(contents)))
+	if redirectPath != "" {
+		details += ", redirected to " + redirectPath
+	}
+	n.audit(opts.Origin, AuditOperationDelete, details, path)
+	return &removal, nil
+}
+
+var (
+	markdownLinkRegex = regexp.MustCompile(`(!?)\[([^\]\n]*)\]\(\s*(<[^>\n]*>|[^)\s]+)(?:\s+"[^"\n]*")?\s*\)`)
+	wikiLinkRegex     = regexp.MustCompile(`\[\[+\s*([^\]|#\n]*[^\]|#\s])\s*(?:#[^\]|\n]*)?(?:\|\s*([^\]\n]*[^\]\s]))?\s*\]\]+`)
+)
+
+// unlinkLinks replaces the links matched by the linksTo callback with their
+// plain text, in the given note content.
+func unlinkLinks(content string, notePath string, linksTo func(href string, isWikiLink bool) bool) (string, []RewrittenLink) {
+	type link struct {
+		start, end int
+		href, text string
+	}
+	found := []link{}
+
+	for _, match := range markdownLinkRegex.FindAllStringSubmatchIndex(content, -1) {
+		if match[3] > match[2] {
+			// Images are not links to notes.
+			continue
+		}
+		href := strings.TrimSuffix(strings.TrimPrefix(content[match[6]:match[7]], "<"), ">")
+		if linksTo(href, false) {
+			found = append(found, link{match[0], match[1], href, content[match[4]:match[5]]})
+		}
+	}
+	for _, match := range wikiLinkRegex.FindAllStringSubmatchIndex(content, -1) {
+		href := content[match[2]:match[3]]
+		if !linksTo(href, true) {
+			continue
+		}
+		text := href
+		if match[4] >= 0 {
+			text = content[match[4]:match[5]]
+		}
+		found = append(found, link{match[0], match[1], href, text})
+	}
+
+	// Replace from the end, to keep the offsets of the previous links
+	// valid.
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].start > found[j].start
+	})
+
+	links := []RewrittenLink{}
+	lastStart := len(content)
+	for _, l := range found {
+		if l.end > lastStart {
+			// Overlaps with an already replaced link, e.g. `[[a]](a.md)`.
+			continue
+		}
+		lastStart = l.start
+		content = content[:l.start] + l.text + content[l.end:]
+		// Prepend to report the links in the order of the document.
+		links = append([]RewrittenLink{{
+			Path:    notePath,
+			OldHref: l.href,
+		}}, links...)
+	}
+
+	return content, links
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestUnlinkLinksToRemovedNote(t *testing.T) {
+	test := func(notePath string, content string, expectedContent string, expectedLinks []RewrittenLink) {
+		actualContent, actualLinks := unlinkLinks(content, notePath, func(href string, isWikiLink bool) bool {
+			_, ok := relinkHref(href, isWikiLink, notePath, "dir/old name.md", "dir/old name.md")
+			return ok
+		})
+		assert.Equal(t, actualContent, expectedContent)
+		assert.Equal(t, actualLinks, expectedLinks)
+	}
+
+	test("index.md", "No links", "No links", []RewrittenLink{})
+
+	// Markdown links relative to the note.
+	test("index.md",
+		`A [link](dir/old%20name.md#heading "Title"), [another](<dir/old name>) and [unrelated](dir/other.md).`,
+		"A link, another and [unrelated](dir/other.md).",
+		[]RewrittenLink{
+			{Path: "index.md", OldHref: "dir/old%20name.md#heading"},
+			{Path: "index.md", OldHref: "dir/old name"},
+		},
+	)
+
+	// Images are left untouched.
+	test("dir/sibling.md",
+		"An ![image](old name.md).",
+		"An ![image](old name.md).",
+		[]RewrittenLink{},
+	)
+
+	// Wiki-links are replaced with their label, or their target.
+	test("dir/sub/note.md",
+		"A [[dir/old name]] wiki-link, a [[old name#heading | titled]] one and [[other]].",
+		"A dir/old name wiki-link, a titled one and [[other]].",
+		[]RewrittenLink{
+			{Path: "dir/sub/note.md", OldHref: "dir/old name"},
+			{Path: "dir/sub/note.md", OldHref: "old name"},
+		},
+	)
+}
+
+func TestNoteRemovalNotePaths(t *testing.T) {
+	removal := NoteRemoval{
+		Path: "old.md",
+		Backlinks: []RewrittenLink{
+			{Path: "a.md", OldHref: "old"},
+			{Path: "a.md", OldHref: "old.md"},
+			{Path: "b.md", OldHref: "old"},
+		},
+	}
+	assert.Equal(t, removal.NotePaths(), []string{"a.md", "b.md"})
+}
+
+func TestRewrittenLinkString(t *testing.T) {
+	assert.Equal(t, RewrittenLink{Path: "a.md", OldHref: "old", NewHref: "new"}.String(), "a.md: old -> new")
+	assert.Equal(t, RewrittenLink{Path: "a.md", OldHref: "old", NewHref: "old"}.String(), "a.md: old")
+	assert.Equal(t, RewrittenLink{Path: "a.md", OldHref: "old"}.String(), "a.md: old (unlinked)")
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/exec"
+)
+
+// NoteContentRenderer renders the Markdown content of notes as HTML.
+type NoteContentRenderer interface {
+	// RenderHTML renders the given content as sanitized HTML: the raw HTML
+	// is omitted and dangerous URLs, e.g. javascript:, are removed.
+	//
+	// The links and embeds are given to resolve, to rewrite their
+	// destination or expand the embedded notes. The fenced code blocks are
+	// given to renderBlock, to render them with external programs.
+	RenderHTML(content string, resolve LinkResolver, renderBlock BlockRenderer) (string, error)
+}
+
+// LinkResolver resolves a link found in a rendered note.
+type LinkResolver func(link RenderedLink) (*ResolvedLink, error)
+
+// BlockRenderer renders the content of a fenced code block written in the
+// given language, e.g. a mermaid diagram. When ok is false, the block is
+// rendered as regular code.
+type BlockRenderer func(language string, code string) (html string, ok bool)
+
+// RenderedLink is a link or an embed found in a rendered note.
+type RenderedLink struct {
+	// Destination of the link, as written in the note.
+	Href string
+	// IsWikiLink indicates whether this is a [[wiki link]].
+	IsWikiLink bool
+	// IsEmbed indicates whether the target is embedded in the note, e.g.
+	// ![[note]] or ![image](image.png).
+	IsEmbed bool
+}
+
+// ResolvedLink is the target of a link found in a rendered note.
+type ResolvedLink struct {
+	// URL replacing the destination of the link.
+	URL string
+	// HTML content of an embedded note, rendered in place of the embed.
+	HTML string
+	// LabelOnly indicates whether only the label of the link is rendered,
+	// e.g. when its target is not published.
+	LabelOnly bool
+}
+
+// MaxEmbedDepth is the maximum number of nested notes expanded when rendering
+// a note, including the rendered note itself. Deeper embeds and the ones
+// forming a cycle are rendered as regular links.
+const MaxEmbedDepth = 3
+
+// RenderHTML renders the given note content as sanitized HTML, using resolve
+// to rewrite the links. A nil resolve keeps the links untouched.
+func (n *Notebook) RenderHTML(content string, resolve LinkResolver) (string, error) {
+	if n.renderer == nil {
+		return "", errors.New("rendering notes is not supported")
+	}
+	if resolve == nil {
+		resolve = func(link RenderedLink) (*ResolvedLink, error) {
+			return nil, nil
+		}
+	}
+	html, err := n.renderer.RenderHTML(content, resolve, n.renderBlock)
+	return html, errors.Wrap(err, "failed to render the note")
+}
+
+// renderedBlocksMaxCount is the maximum number of rendered blocks kept in
+// memory, to avoid running the renderers again when refreshing a preview.
+const renderedBlocksMaxCount = 200
+
+// renderBlock renders a fenced code block with the renderer configured for
+// its language, if any. The block is rendered as regular code when the
+// renderer is not installed or fails.
+func (n *Notebook) renderBlock(language string, code string) (string, bool) {
+	language = strings.ToLower(language)
+	command, ok := n.Config.Renderers[language]
+	if !ok {
+		return "", false
+	}
+
+	key := language + "\x00" + code
+	n.renderedBlocksMutex.Lock()
+	html, ok := n.renderedBlocks[key]
+	n.renderedBlocksMutex.Unlock()
+	if ok {
+		return html, true
+	}
+
+	html, err := runBlockRenderer(command, language, code)
+	if err != nil {
+		n.logger.Err(errors.Wrapf(err, "%s: failed to render the block", language))
+		return "", false
+	}
+
+	n.renderedBlocksMutex.Lock()
+	if n.renderedBlocks == nil || len(n.renderedBlocks) >= renderedBlocksMaxCount {
+		n.renderedBlocks = map[string]string{}
+	}
+	n.renderedBlocks[key] = html
+	n.renderedBlocksMutex.Unlock()
+	return html, true
+}
+
+// runBlockRenderer pipes the code of a block to the given command and returns
+// its output. The language of the block is given as the first argument.
+func runBlockRenderer(command string, language string, code string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandFromString(command, language)
+	cmd.Stdin = strings.NewReader(code)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	html := strings.TrimSpace(string(output))
+	if html == "" {
+		return "", errors.New("the renderer returned an empty output")
+	}
+	return html, nil
+}
@@ -0,0 +1,19 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestRunBlockRenderer(t *testing.T) {
+	html, err := runBlockRenderer(`echo "<div class=\"$1\">$(cat)</div>"`, "mermaid", "graph TD")
+	assert.Nil(t, err)
+	assert.Equal(t, html, `<div class="mermaid">graph TD</div>`)
+
+	_, err = runBlockRenderer("echo 'syntax error' >&2; exit 1", "mermaid", "graph TD")
+	assert.Err(t, err, "exit status 1: syntax error")
+
+	_, err = runBlockRenderer("cat >/dev/null", "mermaid", "graph TD")
+	assert.Err(t, err, "the renderer returned an empty output")
+}
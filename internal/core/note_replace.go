@@ -0,0 +1,270 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// ReplaceOpts holds the options used to find and replace text across notes.
+type ReplaceOpts struct {
+	// Pattern matching the text to replace.
+	Pattern *regexp.Regexp
+	// Replacement text, which can refer to submatches of the pattern, e.g.
+	// $1.
+	Replacement string
+	// Paths of the notes in which to replace the text, relative to the
+	// notebook root.
+	Paths []string
+	// Allows replacing text in the link destinations.
+	IncludeLinks bool
+	// Allows replacing text in the YAML frontmatter.
+	IncludeFrontmatter bool
+	// Allows replacing text in the inline tags, e.g. #project/alpha.
+	IncludeTags bool
+	// Lists the replacements without writing the notes.
+	DryRun bool
+	// Command performing the replacement.
+	Origin AuditOrigin
+}
+
+// NoteReplacement reports the text replaced in a note.
+type NoteReplacement struct {
+	// Path of the note, relative to the notebook root.
+	Path string `json:"path"`
+	// Lines modified in the note.
+	Changes []TextChange `json:"changes"`
+	// Number of matches which were not replaced because they are part of a
+	// link destination, of an inline tag or of the frontmatter.
+	Skipped int `json:"skipped"`
+}
+
+// TextChange is a range of lines modified in a note.
+type TextChange struct {
+	// Line number of the first modified line, starting from 1.
+	Line int    `json:"line"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// Replace finds and replaces text in the given notes, without corrupting
+// their links, tags and frontmatter unless explicitly allowed.
+//
+// Only the notes with matches are returned.
+func (n *Notebook) Replace(opts ReplaceOpts) ([]NoteReplacement, error) {
+	wrap := errors.Wrapper("failed to replace text")
+
+	replacements := []NoteReplacement{}
+	contents := map[string]string{}
+
+	for _, path := range opts.Paths {
+		content, err := n.fs.Read(filepath.Join(n.Path, path))
+		if err != nil {
+			return nil, wrap(err)
+		}
+		parsed, err := n.parser.ParseNoteContent(string(content))
+		if err != nil {
+			return nil, wrap(err)
+		}
+		newContent, replacement := replaceInNote(string(content), parsed.Links, opts, n.Config.Format.Markdown)
+		if len(replacement.Changes) == 0 && replacement.Skipped == 0 {
+			continue
+		}
+		replacement.Path = path
+		replacements = append(replacements, replacement)
+		if len(replacement.Changes) > 0 {
+			contents[path] = newContent
+		}
+	}
+
+	if opts.DryRun || len(contents) == 0 {
+		return replacements, nil
+	}
+
+	paths, err := n.writeReplacedNotes(contents)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	n.audit(opts.Origin, AuditOperationReplace,
+		fmt.Sprintf("%s -> %s in %d %s", opts.Pattern, opts.Replacement, len(paths), strutil.Pluralize("note", len(paths))),
+		paths...,
+	)
+
+	return replacements, nil
+}
+
+// writeReplacedNotes writes the new content of the notes, indexed by their
+// path relative to the notebook root, and updates the index. Returns the
+// sorted paths of the modified notes.
+func (n *Notebook) writeReplacedNotes(contents map[string]string) ([]string, error) {
+	err := n.commitIndex(func(index NoteIndex) error {
+		for path, content := range contents {
+			absPath := filepath.Join(n.Path, path)
+			err := n.fs.Write(absPath, []byte(content))
+			if err != nil {
+				return err
+			}
+			note, err := n.ParseNoteAt(absPath)
+			if err != nil {
+				return err
+			}
+			err = index.Update(*note)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for path := range contents {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+var frontmatterRegex = regexp.MustCompile(`(?s)^---[ \t]*\r?\n.*?\r?\n---[ \t]*(?:\r?\n|$)`)
+
+// replaceInNote replaces the matches of the pattern in the given note
+// content, outside of the protected ranges. The links are the ones found by
+// the parser in the content, and the config tells which inline tags are
+// recognized.
+func replaceInNote(content string, links []Link, opts ReplaceOpts, config MarkdownConfig) (string, NoteReplacement) {
+	protected := []textSpan{}
+	body := 0
+	if loc := frontmatterRegex.FindStringIndex(content); loc != nil {
+		body = loc[1]
+		if !opts.IncludeFrontmatter {
+			protected = append(protected, textSpan{loc[0], loc[1]})
+		}
+	}
+	if !opts.IncludeLinks {
+		protected = append(protected, linkDestinationSpans(content, links)...)
+	}
+	if !opts.IncludeTags {
+		protected = append(protected, inlineTagSpans(content, body, config)...)
+	}
+	isProtected := func(start, end int) bool {
+		for _, s := range protected {
+			if start < s.end && end > s.start || start == end && start >= s.start && start < s.end {
+				return true
+			}
+		}
+		return false
+	}
+
+	edits := []textEdit{}
+	result := NoteReplacement{}
+	for _, match := range opts.Pattern.FindAllStringSubmatchIndex(content, -1) {
+		if isProtected(match[0], match[1]) {
+			result.Skipped++
+			continue
+		}
+		text := string(opts.Pattern.ExpandString(nil, opts.Replacement, content, match))
+		if text != content[match[0]:match[1]] {
+			edits = append(edits, textEdit{match[0], match[1], text})
+		}
+	}
+
+	newContent, changes := applyTextEdits(content, edits)
+	result.Changes = changes
+	return newContent, result
+}
+
+var linkReferenceDefinitionRegex = regexp.MustCompile(`(?m)^ {0,3}\[[^\]\n]+\]:[ \t]*(<[^>\n]*>|\S+)`)
+
+// linkDestinationSpans returns the ranges of the link destinations in the
+// given note content, including the reference definitions and the external
+// links found by the parser, e.g. autolinks and bare URLs.
+func linkDestinationSpans(content string, links []Link) []textSpan {
+	spans := []textSpan{}
+	for _, regex := range []*regexp.Regexp{markdownLinkDestinationRegex, wikiLinkDestinationRegex, linkReferenceDefinitionRegex} {
+		for _, match := range regex.FindAllStringSubmatchIndex(content, -1) {
+			spans = append(spans, textSpan{match[2], match[3]})
+		}
+	}
+
+	// The position of the destination is not known, so all the occurrences
+	// of an URL in its paragraph are protected.
+	for _, link := range links {
+		if !link.IsExternal || link.Href == "" || link.SnippetStart < 0 || link.SnippetEnd > len(content) || link.SnippetStart > link.SnippetEnd {
+			continue
+		}
+		snippet := content[link.SnippetStart:link.SnippetEnd]
+		for offset := 0; ; {
+			i := strings.Index(snippet[offset:], link.Href)
+			if i < 0 {
+				break
+			}
+			start := link.SnippetStart + offset + i
+			spans = append(spans, textSpan{start, start + len(link.Href)})
+			offset += i + len(link.Href)
+		}
+	}
+	return spans
+}
+
+// textEdit replaces a range of bytes in the content of a note.
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// applyTextEdits applies the given sorted and non-overlapping edits to the
+// content, and reports the modified lines as a diff.
+func applyTextEdits(content string, edits []textEdit) (string, []TextChange) {
+	changes := []TextChange{}
+
+	// Group the edits by the lines they modify.
+	newContent := strings.Builder{}
+	last := 0
+	for i := 0; i < len(edits); {
+		lineStart := strings.LastIndex(content[:edits[i].start], "\n") + 1
+		lineEnd := lineEndAfter(content, edits[i].end)
+		j := i + 1
+		for j < len(edits) && edits[j].start <= lineEnd {
+			lineEnd = lineEndAfter(content, edits[j].end)
+			j++
+		}
+
+		newLines := strings.Builder{}
+		pos := lineStart
+		for _, e := range edits[i:j] {
+			newLines.WriteString(content[pos:e.start])
+			newLines.WriteString(e.text)
+			pos = e.end
+		}
+		newLines.WriteString(content[pos:lineEnd])
+
+		changes = append(changes, TextChange{
+			Line: strings.Count(content[:lineStart], "\n") + 1,
+			Old:  content[lineStart:lineEnd],
+			New:  newLines.String(),
+		})
+		newContent.WriteString(content[last:lineStart])
+		newContent.WriteString(newLines.String())
+		last = lineEnd
+		i = j
+	}
+	newContent.WriteString(content[last:])
+
+	return newContent.String(), changes
+}
+
+// lineEndAfter returns the offset of the end of the line containing the given
+// offset, excluding the newline character.
+func lineEndAfter(content string, offset int) int {
+	if i := strings.Index(content[offset:], "\n"); i >= 0 {
+		return offset + i
+	}
+	return len(content)
+}
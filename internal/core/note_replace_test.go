@@ -0,0 +1,153 @@
+package core
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestReplaceInNote(t *testing.T) {
+	test := func(content string, opts ReplaceOpts, expectedContent string, expected NoteReplacement) {
+		actualContent, actual := replaceInNote(content, nil, opts, NewDefaultConfig().Format.Markdown)
+		assert.Equal(t, actualContent, expectedContent)
+		assert.Equal(t, actual, expected)
+	}
+
+	opts := func(pattern string, replacement string) ReplaceOpts {
+		return ReplaceOpts{
+			Pattern:     regexp.MustCompile(pattern),
+			Replacement: replacement,
+		}
+	}
+
+	test("No match", opts("foo", "bar"), "No match", NoteReplacement{Changes: []TextChange{}})
+
+	// Several matches on the same line are grouped.
+	test("# Title\nfoo and foo\nnothing\nfoo\n", opts("foo", "bar"),
+		"# Title\nbar and bar\nnothing\nbar\n",
+		NoteReplacement{Changes: []TextChange{
+			{Line: 2, Old: "foo and foo", New: "bar and bar"},
+			{Line: 4, Old: "foo", New: "bar"},
+		}},
+	)
+
+	// Submatches.
+	test("Meeting on 2021-10-19.", opts(`(\d{4})-(\d{2})-(\d{2})`, "$3/$2/$1"),
+		"Meeting on 19/10/2021.",
+		NoteReplacement{Changes: []TextChange{
+			{Line: 1, Old: "Meeting on 2021-10-19.", New: "Meeting on 19/10/2021."},
+		}},
+	)
+
+	// Matches of the line endings.
+	test("a foo\nb foo\n", opts(`foo\n`, "bar "),
+		"a bar b bar ",
+		NoteReplacement{Changes: []TextChange{
+			{Line: 1, Old: "a foo\nb foo\n", New: "a bar b bar "},
+		}},
+	)
+
+	// Matches spanning several lines.
+	test("a foo\nbar b\nc", opts(`foo\nbar`, "baz"),
+		"a baz b\nc",
+		NoteReplacement{Changes: []TextChange{
+			{Line: 1, Old: "a foo\nbar b", New: "a baz b"},
+		}},
+	)
+}
+
+func TestReplaceInNoteProtectsLinksAndFrontmatter(t *testing.T) {
+	content := `---
+title: project
+---
+A project with a [project link](project.md) and a [[project]].
+`
+	test := func(includeLinks bool, includeFrontmatter bool, expectedContent string, expected NoteReplacement) {
+		actualContent, actual := replaceInNote(content, nil, ReplaceOpts{
+			Pattern:            regexp.MustCompile("project"),
+			Replacement:        "plan",
+			IncludeLinks:       includeLinks,
+			IncludeFrontmatter: includeFrontmatter,
+		}, NewDefaultConfig().Format.Markdown)
+		assert.Equal(t, actualContent, expectedContent)
+		assert.Equal(t, actual, expected)
+	}
+
+	test(false, false, `---
+title: project
+---
+A plan with a [plan link](project.md) and a [[project]].
+`, NoteReplacement{
+		Changes: []TextChange{{
+			Line: 4,
+			Old:  "A project with a [project link](project.md) and a [[project]].",
+			New:  "A plan with a [plan link](project.md) and a [[project]].",
+		}},
+		Skipped: 3,
+	})
+
+	test(true, true, `---
+title: plan
+---
+A plan with a [plan link](plan.md) and a [[plan]].
+`, NoteReplacement{
+		Changes: []TextChange{
+			{Line: 2, Old: "title: project", New: "title: plan"},
+			{Line: 4, Old: "A project with a [project link](project.md) and a [[project]].", New: "A plan with a [plan link](plan.md) and a [[plan]]."},
+		},
+	})
+}
+
+func TestReplaceInNoteProtectsTags(t *testing.T) {
+	content := "An alpha note tagged #project/alpha and :alpha:beta:.\n"
+
+	test := func(includeTags bool, colonTags bool, expectedContent string, expectedSkipped int) {
+		config := NewDefaultConfig().Format.Markdown
+		config.ColonTags = colonTags
+		actualContent, actual := replaceInNote(content, nil, ReplaceOpts{
+			Pattern:     regexp.MustCompile("a"),
+			Replacement: "ZZ",
+			IncludeTags: includeTags,
+		}, config)
+		assert.Equal(t, actualContent, expectedContent)
+		assert.Equal(t, actual.Skipped, expectedSkipped)
+	}
+
+	test(false, false, "An ZZlphZZ note tZZgged #project/alpha ZZnd :ZZlphZZ:betZZ:.\n", 2)
+	test(false, true, "An ZZlphZZ note tZZgged #project/alpha ZZnd :alpha:beta:.\n", 5)
+	test(true, true, "An ZZlphZZ note tZZgged #project/ZZlphZZ ZZnd :ZZlphZZ:betZZ:.\n", 0)
+}
+
+func TestReplaceInNoteProtectsExternalLinks(t *testing.T) {
+	test := func(content string, links []Link, expectedContent string, expectedSkipped int) {
+		t.Helper()
+		actualContent, actual := replaceInNote(content, links, ReplaceOpts{
+			Pattern:     regexp.MustCompile("(?i)alpha"),
+			Replacement: "Omega",
+		}, NewDefaultConfig().Format.Markdown)
+		assert.Equal(t, actualContent, expectedContent)
+		assert.Equal(t, actual.Skipped, expectedSkipped)
+	}
+
+	// Bare URL, autolinked by the parser.
+	test("Alpha on https://alpha.example.com/alpha\n",
+		[]Link{{Href: "https://alpha.example.com/alpha", IsExternal: true, SnippetStart: 0, SnippetEnd: 40}},
+		"Omega on https://alpha.example.com/alpha\n", 2,
+	)
+	// The same URL text outside of the paragraph of the link is replaced.
+	test("See https://alpha.example.com\n\nalpha.example.com\n",
+		[]Link{{Href: "https://alpha.example.com", IsExternal: true, SnippetStart: 0, SnippetEnd: 29}},
+		"See https://alpha.example.com\n\nOmega.example.com\n", 1,
+	)
+	// Autolink.
+	test("Alpha on <https://alpha.example.com>\n",
+		[]Link{{Href: "https://alpha.example.com", IsExternal: true, SnippetStart: 0, SnippetEnd: 36}},
+		"Omega on <https://alpha.example.com>\n", 1,
+	)
+	// Reference definitions, which are not indexed links.
+	test("An [alpha link][alpha].\n\n[alpha]: https://alpha.example.com\n  [beta]: <alpha.md>\n",
+		[]Link{},
+		"An [Omega link][Omega].\n\n[Omega]: https://alpha.example.com\n  [beta]: <alpha.md>\n", 2,
+	)
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/paths"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// ResolveLinkOpts holds the options used to resolve the target of a link.
+type ResolveLinkOpts struct {
+	// IsWikiLink indicates whether the link is a [[wiki-link]], which can
+	// target a note by alias, by a portion of its path or by its title.
+	IsWikiLink bool
+	// Fuzzy falls back on the note whose path or title is the closest to
+	// the href, according to `search.fuzzy-link-threshold`.
+	Fuzzy bool
+}
+
+// ResolveLink retrieves the note targeted by a link with the given href,
+// found in the note at sourcePath, relative to the notebook root. The
+// confidence of the match, between 0 and 1, is returned along the note.
+//
+// Match by order of precedence:
+//  1. Prefix of the path, relative to the directory of the source note
+//  2. Alias declared in the frontmatter of a note (wiki-links only)
+//  3. Any occurrence of the href in a note path, e.g. a note ID (wiki-links
+//     only)
+//  4. The href as terms of the note titles (wiki-links only)
+//  5. The closest path or title, with opts.Fuzzy
+//
+// It is shared by the LSP server and the notebook-wide checks, so that a
+// link followed by the editor is never reported or rewritten as dead.
+//
+// Returns nil when no note matches.
+func (n *Notebook) ResolveLink(sourcePath string, href string, opts ResolveLinkOpts) (*MinimalNote, float64, error) {
+	href = strings.TrimSpace(href)
+	target := strings.SplitN(href, "#", 2)[0]
+	if target == "" || strutil.IsURL(href) {
+		return nil, 0, nil
+	}
+
+	note, err := n.FindByHref(assetHrefPath(filepath.Join(filepath.Dir(sourcePath), target)), false)
+	if note == nil && err == nil && opts.IsWikiLink {
+		note, err = n.FindByAlias(href)
+	}
+	if note == nil && err == nil && opts.IsWikiLink {
+		note, err = n.FindByHref(href, true)
+	}
+	if note == nil && err == nil && opts.IsWikiLink {
+		// The href is not always a valid search query, e.g. with
+		// punctuation, in which case it just doesn't match any title.
+		note, _ = n.FindMatching("title:(" + target + ")")
+	}
+	if note != nil || err != nil || !opts.Fuzzy {
+		return note, 1, err
+	}
+
+	return n.FindByHrefFuzzy(href)
+}
+
+// ResolveHref retrieves the note targeted by a wiki-link with the given href,
+// relative to the notebook root, see ResolveLink.
+func (n *Notebook) ResolveHref(href string) (*MinimalNote, float64, error) {
+	return n.ResolveLink("", href, ResolveLinkOpts{IsWikiLink: true, Fuzzy: true})
+}
+
+// NoteID returns the ID of the indexed note at the given path, which is the
+// stem of its filename. Wiki-links can target a note with its ID, which is
+// resolved back to the note by ResolveHref.
+func (n *Notebook) NoteID(path string) (string, error) {
+	note, err := n.indexedNoteAt(path)
+	if err != nil {
+		return "", err
+	}
+	return paths.FilenameStem(note.Path), nil
+}
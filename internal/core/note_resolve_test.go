@@ -0,0 +1,231 @@
+package core
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/mickael-menu/zk/internal/util"
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/paths"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+// resolveIndexMock is a NoteIndex finding the notes by path, alias or title
+// terms, like the SQLite index.
+type resolveIndexMock struct {
+	NoteIndex
+	notes []MinimalNote
+}
+
+func (m *resolveIndexMock) FindMinimal(opts NoteFindOpts) ([]MinimalNote, error) {
+	if terms, ok := titleTerms(opts.Match); ok {
+		for _, c := range terms {
+			if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != ' ' {
+				return nil, errors.New("fts5: syntax error")
+			}
+		}
+	}
+
+	notes := []MinimalNote{}
+	for _, note := range m.notes {
+		if m.matches(note, opts) {
+			notes = append(notes, note)
+		}
+	}
+	if opts.Limit > 0 && len(notes) > opts.Limit {
+		notes = notes[:opts.Limit]
+	}
+	return notes, nil
+}
+
+func (m *resolveIndexMock) matches(note MinimalNote, opts NoteFindOpts) bool {
+	for _, path := range opts.IncludePaths {
+		if opts.EnablePathRegexes {
+			if !regexp.MustCompile("^" + path + "$").MatchString(note.Path) {
+				return false
+			}
+		} else if path != note.Path && path != paths.DropExt(note.Path) {
+			return false
+		}
+	}
+	for _, alias := range opts.Aliases {
+		found := false
+		for _, noteAlias := range note.Aliases() {
+			found = found || strings.EqualFold(alias, noteAlias)
+		}
+		if !found {
+			return false
+		}
+	}
+	if terms, ok := titleTerms(opts.Match); ok {
+		for _, term := range strings.Fields(terms) {
+			if !strings.Contains(strings.ToLower(note.Title), strings.ToLower(term)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// titleTerms returns the terms of a `title:(terms)` full-text query.
+func titleTerms(match opt.String) (string, bool) {
+	query := match.String()
+	if !strings.HasPrefix(query, "title:(") {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(query, "title:("), ")"), true
+}
+
+func (m *resolveIndexMock) FindUnresolvedLinks() ([]UnresolvedLink, error) {
+	return []UnresolvedLink{}, nil
+}
+
+func (m *resolveIndexMock) FindDeadURLs() ([]URLMetadata, error) {
+	return []URLMetadata{}, nil
+}
+
+// newResolveTestNotebook creates a notebook on the disk with the given notes,
+// indexed by a resolveIndexMock. The content of each note is its path, which
+// is parsed to the given NoteContent.
+func newResolveTestNotebook(t *testing.T, notes map[string]*NoteContent) (*Notebook, *fileStorageMock) {
+	dir, err := ioutil.TempDir("", "zk-resolve")
+	assert.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	fs := newFileStorageMock(dir, []string{dir})
+	index := &resolveIndexMock{}
+	parsed := map[string]*NoteContent{}
+	for path, content := range notes {
+		absPath := filepath.Join(dir, path)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(absPath), 0755))
+		assert.Nil(t, ioutil.WriteFile(absPath, []byte(path), 0644))
+		fs.files[absPath] = path
+		parsed[path] = content
+		index.notes = append(index.notes, MinimalNote{
+			Path:     path,
+			Title:    content.Title.String(),
+			Metadata: content.Metadata,
+		})
+	}
+
+	notebook := NewNotebook(dir, NewDefaultConfig(), NotebookPorts{
+		NoteIndex:         index,
+		NoteContentParser: newNoteContentParserMock(parsed),
+		FS:                fs,
+		Logger:            &util.NullLogger,
+	})
+	return notebook, fs
+}
+
+func TestNotebookResolveLink(t *testing.T) {
+	notebook, _ := newResolveTestNotebook(t, map[string]*NoteContent{
+		"a.md": {
+			Title:    opt.NewString("Alpha note"),
+			Metadata: map[string]interface{}{"aliases": []interface{}{"First"}},
+		},
+		"dir/4fz2.md":  {Title: opt.NewString("Beta")},
+		"dir/other.md": {Title: opt.NewString("Other note")},
+		"cpp-notes.md": {Title: opt.NewString("C++")},
+		"dir/gamma.md": {},
+	})
+
+	test := func(href string, opts ResolveLinkOpts, expected string, expectedConfidence float64) {
+		t.Helper()
+		note, confidence, err := notebook.ResolveLink("dir/gamma.md", href, opts)
+		assert.Nil(t, err)
+		if expected == "" {
+			assert.Nil(t, note)
+			return
+		}
+		assert.NotNil(t, note)
+		assert.Equal(t, note.Path, expected)
+		assert.Equal(t, confidence, expectedConfidence)
+	}
+
+	wiki := ResolveLinkOpts{IsWikiLink: true}
+	markdown := ResolveLinkOpts{}
+
+	// Relative to the source note.
+	test("other", markdown, "dir/other.md", 1)
+	test("../a.md#section", markdown, "a.md", 1)
+	test("a", markdown, "", 0)
+	test("https://example.com", wiki, "", 0)
+	test("#section", wiki, "", 0)
+	// Alias, portion of the path and title, only for the wiki-links.
+	test("First", wiki, "a.md", 1)
+	test("First", markdown, "", 0)
+	test("4fz2", wiki, "dir/4fz2.md", 1)
+	test("Alpha note", wiki, "a.md", 1)
+	test("Alpha note#section", wiki, "a.md", 1)
+	test("Alpha note", markdown, "", 0)
+	// Not a valid search query.
+	test("C++", wiki, "", 0)
+	test("C++", ResolveLinkOpts{IsWikiLink: true, Fuzzy: true}, "cpp-notes.md", 1)
+	test("Unknown", ResolveLinkOpts{IsWikiLink: true, Fuzzy: true}, "", 0)
+
+	// Approximate match.
+	note, confidence, err := notebook.ResolveLink("dir/gamma.md", "Alpha notes", ResolveLinkOpts{Fuzzy: true})
+	assert.Nil(t, err)
+	assert.Equal(t, note.Path, "a.md")
+	assert.True(t, confidence > 0.7 && confidence < 1)
+}
+
+func TestNotebookResolveHref(t *testing.T) {
+	notebook, _ := newResolveTestNotebook(t, map[string]*NoteContent{
+		"a.md": {
+			Title:    opt.NewString("Alpha note"),
+			Metadata: map[string]interface{}{"aliases": []interface{}{"First"}},
+		},
+		"dir/4fz2.md": {Title: opt.NewString("Beta")},
+	})
+
+	test := func(href string, expected string) {
+		t.Helper()
+		note, _, err := notebook.ResolveHref(href)
+		assert.Nil(t, err)
+		if expected == "" {
+			assert.Nil(t, note)
+			return
+		}
+		assert.NotNil(t, note)
+		assert.Equal(t, note.Path, expected)
+	}
+
+	// Relative to the notebook root, like a wiki-link.
+	test("dir/4fz2", "dir/4fz2.md")
+	test("4fz2", "dir/4fz2.md")
+	test(" First ", "a.md")
+	test("Alpha note", "a.md")
+	test("", "")
+	test("Unknown", "")
+
+	// Falls back on the closest title.
+	note, confidence, err := notebook.ResolveHref("Alpha notes")
+	assert.Nil(t, err)
+	assert.Equal(t, note.Path, "a.md")
+	assert.True(t, confidence < 1)
+}
+
+func TestNotebookNoteID(t *testing.T) {
+	notebook, _ := newResolveTestNotebook(t, map[string]*NoteContent{
+		"dir/4fz2.md": {Title: opt.NewString("Beta")},
+	})
+
+	id, err := notebook.NoteID(filepath.Join(notebook.Path, "dir/4fz2.md"))
+	assert.Nil(t, err)
+	assert.Equal(t, id, "4fz2")
+
+	// The ID resolves back to the note.
+	note, _, err := notebook.ResolveHref(id)
+	assert.Nil(t, err)
+	assert.Equal(t, note.Path, "dir/4fz2.md")
+
+	_, err = notebook.NoteID(filepath.Join(notebook.Path, "dir/missing.md"))
+	assert.Err(t, err, "dir/missing.md: note not found")
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+)
+
+// NoteTemplate is a template file available to render the content of new
+// notes.
+type NoteTemplate struct {
+	// Path relative to the templates directory, as given to the `template`
+	// option when creating a note.
+	Name string `json:"name"`
+	// Absolute path to the template file.
+	AbsPath string `json:"absPath"`
+	// Summary read from the comment header of the template.
+	Description string `json:"description"`
+	// Note configuration groups using this template by default.
+	Groups []string `json:"groups"`
+	// Whether this template is used by default outside of any group.
+	Default bool `json:"default"`
+	// Variables declared in the comment header of the template.
+	Variables []TemplateVar `json:"variables"`
+}
+
+// NoteTemplates lists the templates found in the templates directories of
+// the notebook, sorted by name.
+//
+// When several directories hold a template with the same name, the one from
+// the first directory wins, as when the template is loaded.
+func (n *Notebook) NoteTemplates() ([]NoteTemplate, error) {
+	wrap := errors.Wrapper("failed to list the note templates")
+
+	templates := []NoteTemplate{}
+	found := map[string]bool{}
+	for _, dir := range n.templateDirs {
+		exists, err := n.fs.DirExists(dir)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		if !exists {
+			continue
+		}
+
+		files := paths.Walk(dir, n.logger, func(path string) (bool, error) {
+			return false, nil
+		})
+		for file := range files {
+			if found[file.Path] {
+				continue
+			}
+			found[file.Path] = true
+
+			absPath := filepath.Join(dir, file.Path)
+			content, err := n.fs.Read(absPath)
+			if err != nil {
+				return nil, wrap(err)
+			}
+
+			vars, err := parseTemplateVars(string(content))
+			if err != nil {
+				n.logger.Err(errors.Wrap(err, file.Path))
+				vars = []TemplateVar{}
+			}
+
+			templates = append(templates, NoteTemplate{
+				Name:        file.Path,
+				AbsPath:     absPath,
+				Description: templateDescription(string(content)),
+				Groups:      []string{},
+				Variables:   vars,
+			})
+		}
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+
+	for i, template := range templates {
+		templates[i].Default = template.matches(n.Config.Note.BodyTemplatePath.String())
+		for name, group := range n.Config.Groups {
+			if template.matches(group.Note.BodyTemplatePath.String()) {
+				templates[i].Groups = append(templates[i].Groups, name)
+			}
+		}
+		sort.Strings(templates[i].Groups)
+	}
+
+	return templates, nil
+}
+
+// matches returns whether the given template path, as written in the config,
+// refers to this template.
+func (t NoteTemplate) matches(path string) bool {
+	if path == "" {
+		return false
+	}
+	if filepath.IsAbs(path) {
+		return path == t.AbsPath
+	}
+	return filepath.Clean(path) == t.Name
+}
+
+// templateCommentRegex matches a Handlebars comment at the beginning of a
+// template, e.g. {{!-- Daily journal entry --}} or {{! Meeting minutes }}.
+var templateCommentRegex = regexp.MustCompile(`^\s*\{\{!(?:--((?s).*?)--\}\}|([^}]*)\}\})`)
+
+// templateHeader returns the content of the comment header of a template.
+// The comment is removed when rendering the template.
+func templateHeader(content string) string {
+	matches := templateCommentRegex.FindStringSubmatch(content)
+	if matches == nil {
+		return ""
+	}
+	return matches[1] + matches[2]
+}
+
+// templateDescription reads the description of a template from its comment
+// header, without the variable declarations.
+func templateDescription(content string) string {
+	lines := []string{}
+	for _, line := range strings.Split(templateHeader(content), "\n") {
+		if _, ok := templateVarDeclaration(line); !ok {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(lines, "\n")), " ")
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestTemplateDescription(t *testing.T) {
+	test := func(content, expected string) {
+		assert.Equal(t, templateDescription(content), expected)
+	}
+
+	test("", "")
+	test("# {{title}}\n\n{{content}}", "")
+	test("{{! Meeting minutes }}\n# {{title}}", "Meeting minutes")
+	test("\n  {{!-- Daily journal entry --}}\n# {{title}}", "Daily journal entry")
+	test("{{!--\n  Weekly review,\n  with the {{title}} of the week.\n--}}\n", "Weekly review, with the {{title}} of the week.")
+	test("# {{title}}\n{{! Not a header }}", "")
+	test("{{!--\n  Meeting minutes\n  @var project \"Project\" choices=alpha,beta\n--}}\n", "Meeting minutes")
+}
+
+func TestNoteTemplateMatches(t *testing.T) {
+	template := NoteTemplate{Name: "journal/daily.md", AbsPath: "/notebook/.zk/templates/journal/daily.md"}
+
+	assert.True(t, template.matches("journal/daily.md"))
+	assert.True(t, template.matches("./journal/daily.md"))
+	assert.True(t, template.matches("/notebook/.zk/templates/journal/daily.md"))
+	assert.False(t, template.matches(""))
+	assert.False(t, template.matches("daily.md"))
+	assert.False(t, template.matches("/other/journal/daily.md"))
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestMinimalNotePublishedURL(t *testing.T) {
+	test := func(path string, metadata map[string]interface{}, baseURL string, expected string) {
+		note := MinimalNote{Path: path, Metadata: metadata}
+		assert.Equal(t, note.PublishedURL(baseURL), expected)
+	}
+
+	test("note.md", nil, "https://example.com", "https://example.com/note")
+	test("dir/note.md", nil, "https://example.com/", "https://example.com/dir/note")
+	test("dir/note.md", map[string]interface{}{"permalink": "/custom/"}, "https://example.com", "https://example.com/custom/")
+	test("dir/note.md", map[string]interface{}{"permalink": ""}, "https://example.com", "https://example.com/dir/note")
+}
+
+func TestMinimalNoteAliases(t *testing.T) {
+	test := func(metadata map[string]interface{}, expected []string) {
+		note := MinimalNote{Path: "note.md", Metadata: metadata}
+		assert.Equal(t, note.Aliases(), expected)
+	}
+
+	test(nil, []string{})
+	test(map[string]interface{}{"aliases": "JS"}, []string{"JS"})
+	test(map[string]interface{}{"aliases": []interface{}{"JS", " ECMAScript ", "", 2015}}, []string{"JS", "ECMAScript", "2015"})
+	test(map[string]interface{}{"aliases": 42}, []string{})
+}
+
+func TestNoteSummary(t *testing.T) {
+	test := func(metadata map[string]interface{}, lead string, expected string) {
+		t.Helper()
+		assert.Equal(t, NoteSummary(metadata, lead), expected)
+	}
+
+	test(nil, "", "")
+	test(nil, "First paragraph\nof the note.", "First paragraph of the note.")
+	test(map[string]interface{}{"summary": " A summary "}, "Lead", "A summary")
+	test(map[string]interface{}{"description": "A description"}, "Lead", "A description")
+	test(map[string]interface{}{"summary": "A summary", "description": "A description"}, "Lead", "A summary")
+	test(map[string]interface{}{"summary": "", "description": 42}, "Lead", "Lead")
+
+	long := strings.Repeat("word ", 50)
+	test(nil, long, strings.Repeat("word ", 39)+"word…")
+}
+
+func TestReadingTime(t *testing.T) {
+	assert.Equal(t, ReadingTime(0), 0)
+	assert.Equal(t, ReadingTime(1), 1)
+	assert.Equal(t, ReadingTime(200), 1)
+	assert.Equal(t, ReadingTime(201), 2)
+	assert.Equal(t, ReadingTime(1000), 5)
+}
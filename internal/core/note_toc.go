@@ -0,0 +1,209 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+const (
+	tocBegin = "<!-- zk:toc -->"
+	tocEnd   = "<!-- zk:toc:end -->"
+)
+
+// TableOfContents returns a Markdown list linking to the headings of the
+// given note content, nested by level. The headings deeper than maxDepth are
+// skipped, unless it is 0.
+//
+// The title of the note, when it is the only level 1 heading, is not listed.
+func TableOfContents(content string, maxDepth int) string {
+	type heading struct {
+		level int
+		text  string
+	}
+	headings := []heading{}
+	titles := 0
+	fence := ""
+	inTOC := false
+	for _, line := range strings.Split(content[len(frontmatterRegex.FindString(content)):], "\n") {
+		line = strings.TrimRight(line, "\r")
+		if matches := codeFenceRegex.FindStringSubmatch(line); matches != nil {
+			if fence == "" {
+				fence = matches[1]
+			} else if fence == matches[1] {
+				fence = ""
+			}
+			continue
+		}
+		// The headings of a previous table of contents are not repeated.
+		switch strings.TrimSpace(line) {
+		case tocBegin:
+			inTOC = true
+		case tocEnd:
+			inTOC = false
+		}
+		if fence != "" || inTOC {
+			continue
+		}
+
+		matches := headingRegex.FindStringSubmatch(line)
+		if matches == nil || strings.TrimSpace(matches[2]) == "" {
+			continue
+		}
+		level := len(matches[1])
+		if level == 1 {
+			titles++
+		}
+		headings = append(headings, heading{level: level, text: strings.TrimSpace(matches[2])})
+	}
+
+	skipTitle := titles == 1
+
+	minLevel := 6
+	for _, h := range headings {
+		if h.level < minLevel && !(skipTitle && h.level == 1) {
+			minLevel = h.level
+		}
+	}
+
+	var toc strings.Builder
+	slugs := map[string]int{}
+	for _, h := range headings {
+		// The anchor is computed even for the skipped headings, to number
+		// the duplicates like the Markdown renderers.
+		anchor := headingAnchor(h.text)
+		if count := slugs[anchor]; count > 0 {
+			slugs[anchor]++
+			anchor = fmt.Sprintf("%s-%d", anchor, count)
+		} else {
+			slugs[anchor] = 1
+		}
+
+		if skipTitle && h.level == 1 {
+			continue
+		}
+		depth := h.level - minLevel + 1
+		if maxDepth > 0 && depth > maxDepth {
+			continue
+		}
+		fmt.Fprintf(&toc, "%s- [%s](#%s)\n", strings.Repeat("  ", depth-1), h.text, anchor)
+	}
+	return toc.String()
+}
+
+// headingAnchor returns the identifier of a heading, as generated by GitHub
+// and most Markdown renderers.
+func headingAnchor(text string) string {
+	var anchor strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-' || r == '_':
+			anchor.WriteRune(r)
+		case r == ' ':
+			anchor.WriteRune('-')
+		}
+	}
+	return anchor.String()
+}
+
+// UpdateTableOfContents writes the table of contents of the given note
+// content between the `<!-- zk:toc -->` and `<!-- zk:toc:end -->` markers.
+// When they are missing, the markers are inserted after the title of the
+// note, or at the beginning of its body.
+func UpdateTableOfContents(content string, maxDepth int) string {
+	toc := TableOfContents(content, maxDepth)
+
+	if start := strings.Index(content, tocBegin); start >= 0 {
+		start += len(tocBegin)
+		if end := strings.Index(content[start:], tocEnd); end >= 0 {
+			return content[:start] + "\n" + toc + content[start+end:]
+		}
+	}
+
+	region := tocBegin + "\n" + toc + tocEnd + "\n"
+
+	bodyStart := len(frontmatterRegex.FindString(content))
+	lines := strings.SplitAfter(content[bodyStart:], "\n")
+	// Skips the blank lines and the title of the note.
+	offset := bodyStart
+	i := 0
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		offset += len(lines[i])
+		i++
+	}
+	if i < len(lines) {
+		if matches := headingRegex.FindStringSubmatch(strings.TrimRight(lines[i], "\r\n")); matches != nil && len(matches[1]) == 1 {
+			offset += len(lines[i])
+			if !strings.HasSuffix(lines[i], "\n") {
+				region = "\n" + region
+			}
+			region = "\n" + region
+		}
+	}
+
+	rest := content[offset:]
+	if strings.TrimSpace(rest) != "" {
+		region += "\n"
+	}
+	return content[:offset] + region + strings.TrimLeft(rest, "\r\n")
+}
+
+// NoteTableOfContents returns the table of contents of the note at the given
+// path, as done by TableOfContents.
+func (n *Notebook) NoteTableOfContents(path string, maxDepth int) (string, error) {
+	_, content, err := n.readNoteForTOC(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "%s: failed to generate the table of contents", path)
+	}
+	return TableOfContents(content, maxDepth), nil
+}
+
+// WriteTableOfContents updates the table of contents of the note at the
+// given path, as done by UpdateTableOfContents. It returns whether the note
+// changed.
+func (n *Notebook) WriteTableOfContents(path string, maxDepth int) (bool, error) {
+	wrap := errors.Wrapperf("%s: failed to write the table of contents", path)
+
+	absPath, content, err := n.readNoteForTOC(path)
+	if err != nil {
+		return false, wrap(err)
+	}
+	updated := UpdateTableOfContents(content, maxDepth)
+	if updated == content {
+		return false, nil
+	}
+
+	err = n.commitIndex(func(index NoteIndex) error {
+		err := n.fs.Write(absPath, []byte(updated))
+		if err != nil {
+			return err
+		}
+		note, err := n.ParseNoteAt(absPath)
+		if note == nil || err != nil {
+			return err
+		}
+		return index.Update(*note)
+	})
+	if err != nil {
+		return false, wrap(err)
+	}
+	return true, nil
+}
+
+// readNoteForTOC returns the absolute path and the content of the note at
+// the given path, which must be in the notebook.
+func (n *Notebook) readNoteForTOC(path string) (string, string, error) {
+	relPath, err := n.RelPath(path)
+	if err != nil {
+		return "", "", err
+	}
+	absPath := filepath.Join(n.Path, relPath)
+	content, err := n.fs.Read(absPath)
+	if err != nil {
+		return "", "", err
+	}
+	return absPath, string(content), nil
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestTableOfContents(t *testing.T) {
+	test := func(content string, maxDepth int, expected string) {
+		t.Helper()
+		assert.Equal(t, TableOfContents(content, maxDepth), expected)
+	}
+
+	test("", 0, "")
+	test("Some text.\n", 0, "")
+
+	content := `---
+title: Note
+---
+
+# Note
+
+## Ideas
+
+### Sub-ideas, and more!
+
+` + "```" + `
+## Not a heading
+` + "```" + `
+
+## Ideas
+`
+	test(content, 0, `- [Ideas](#ideas)
+  - [Sub-ideas, and more!](#sub-ideas-and-more)
+- [Ideas](#ideas-1)
+`)
+	test(content, 1, `- [Ideas](#ideas)
+- [Ideas](#ideas-1)
+`)
+
+	// Several level 1 headings are listed.
+	test("# One\n## Sub\n# Two\n", 0, "- [One](#one)\n  - [Sub](#sub)\n- [Two](#two)\n")
+
+	// The title is counted to number the duplicates.
+	test("# Note\n## Note\n", 0, "- [Note](#note-1)\n")
+}
+
+func TestUpdateTableOfContents(t *testing.T) {
+	test := func(content string, expected string) {
+		t.Helper()
+		assert.Equal(t, UpdateTableOfContents(content, 0), expected)
+	}
+
+	// Inserted after the title.
+	test("# Note\n\nIntro\n\n## Section\n", "# Note\n\n<!-- zk:toc -->\n- [Section](#section)\n<!-- zk:toc:end -->\n\nIntro\n\n## Section\n")
+	test("# Note", "# Note\n\n<!-- zk:toc -->\n<!-- zk:toc:end -->\n")
+
+	// Inserted at the beginning of the body.
+	test("---\ntitle: Note\n---\n## Section\n", "---\ntitle: Note\n---\n<!-- zk:toc -->\n- [Section](#section)\n<!-- zk:toc:end -->\n\n## Section\n")
+
+	// Updated between the markers.
+	test(
+		"# Note\n\n<!-- zk:toc -->\n- [Old](#old)\n<!-- zk:toc:end -->\n\n## New\n",
+		"# Note\n\n<!-- zk:toc -->\n- [New](#new)\n<!-- zk:toc:end -->\n\n## New\n",
+	)
+	// An up-to-date table of contents is not changed.
+	content := "# Note\n\n<!-- zk:toc -->\n- [New](#new)\n<!-- zk:toc:end -->\n\n## New\n"
+	test(content, content)
+}
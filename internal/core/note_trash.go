@@ -0,0 +1,189 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// TrashFilter selects the notes according to whether they are in the trash.
+type TrashFilter int
+
+const (
+	// Find the trashed notes along the other ones.
+	TrashFilterInclude TrashFilter = iota
+	// Find only the notes which are not trashed.
+	TrashFilterExclude
+	// Find only the trashed notes.
+	TrashFilterOnly
+)
+
+// TrashStatuses are the values of the `status` frontmatter key marking a note
+// as deleted. Trashed notes are hidden from the queries until they are
+// restored or purged.
+var TrashStatuses = []string{"trash", "deleted"}
+
+// IsTrashed returns whether the given frontmatter metadata marks a note as
+// deleted.
+func IsTrashed(metadata map[string]interface{}) bool {
+	status, ok := metadata["status"].(string)
+	return ok && strutil.InList(TrashStatuses, strings.ToLower(strings.TrimSpace(status)))
+}
+
+// TrashNote moves a note to the trash, by setting its `status` to `trash`.
+// The note is kept in the notebook until it is restored or purged.
+func (n *Notebook) TrashNote(origin AuditOrigin, path string) (*MinimalNote, error) {
+	wrap := errors.Wrapperf("%s: failed to trash the note", path)
+
+	note, err := n.indexedNoteAt(path)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if IsTrashed(note.Metadata) {
+		return nil, wrap(errors.New("the note is already in the trash"))
+	}
+
+	err = n.setNoteStatus(note.Path, "trash")
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	n.audit(origin, AuditOperationTrash, "", note.Path)
+	n.autoCommit(string(AuditOperationTrash), note.Path)
+	return note, nil
+}
+
+// RestoreNote takes a note out of the trash, by removing its `status`.
+func (n *Notebook) RestoreNote(origin AuditOrigin, path string) (*MinimalNote, error) {
+	wrap := errors.Wrapperf("%s: failed to restore the note", path)
+
+	note, err := n.indexedNoteAt(path)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if !IsTrashed(note.Metadata) {
+		return nil, wrap(errors.New("the note is not in the trash"))
+	}
+
+	err = n.setNoteStatus(note.Path, "")
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	n.audit(origin, AuditOperationRestore, "", note.Path)
+	n.autoCommit(string(AuditOperationRestore), note.Path)
+	return note, nil
+}
+
+// PurgeTrashOpts holds the options used to purge the trash of a Notebook.
+type PurgeTrashOpts struct {
+	// Only reports the notes to purge, without deleting them.
+	DryRun bool
+	// Current date, used to find the notes trashed for longer than the
+	// retention period.
+	Now time.Time
+	// Command purging the trash, usually zk maintenance.
+	Origin AuditOrigin
+}
+
+// PurgeTrash deletes the trashed notes which were not modified during the
+// retention period set in the `[trash]` config section. It returns the paths
+// of the purged notes.
+func (n *Notebook) PurgeTrash(opts PurgeTrashOpts) ([]string, error) {
+	wrap := errors.Wrapper("failed to purge the trash")
+
+	purged := []string{}
+	retention := n.Config.Trash.RetentionDays
+	if retention <= 0 {
+		return purged, nil
+	}
+
+	modifiedEnd := opts.Now.AddDate(0, 0, -retention)
+	notes, err := n.FindMinimalNotes(NoteFindOpts{
+		Trash:       TrashFilterOnly,
+		ModifiedEnd: &modifiedEnd,
+		Sorters:     []NoteSorter{{Field: NoteSortPath, Ascending: true}},
+	})
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	for _, note := range notes {
+		if !opts.DryRun {
+			_, err := n.RemoveNote(RemoveNoteOpts{Path: filepath.Join(n.Path, note.Path), Origin: opts.Origin})
+			if err != nil {
+				return purged, wrap(err)
+			}
+		}
+		purged = append(purged, note.Path)
+	}
+
+	return purged, nil
+}
+
+// setNoteStatus changes the `status` frontmatter key of the note at the given
+// path, then indexes it.
+func (n *Notebook) setNoteStatus(path string, status string) error {
+	absPath := filepath.Join(n.Path, path)
+	content, err := n.fs.Read(absPath)
+	if err != nil {
+		return err
+	}
+
+	return n.commitIndex(func(index NoteIndex) error {
+		err := n.fs.Write(absPath, []byte(setFrontmatterStatus(string(content), status)))
+		if err != nil {
+			return err
+		}
+		note, err := n.ParseNoteAt(absPath)
+		if err != nil {
+			return err
+		}
+		return index.Update(*note)
+	})
+}
+
+var frontmatterStatusRegex = regexp.MustCompile(`(?im)^status[ \t]*:.*(?:\r?\n|$)`)
+
+// setFrontmatterStatus sets the `status` key of the YAML frontmatter in the
+// given note content, creating the frontmatter if needed. An empty status
+// removes the key, and the frontmatter if it is left empty.
+func setFrontmatterStatus(content string, status string) string {
+	line := ""
+	if status != "" {
+		line = fmt.Sprintf("status: %s\n", status)
+	}
+
+	loc := frontmatterRegex.FindStringIndex(content)
+	if loc == nil {
+		if line == "" {
+			return content
+		}
+		return "---\n" + line + "---\n\n" + content
+	}
+
+	frontmatter := content[loc[0]:loc[1]]
+	body := content[loc[1]:]
+
+	// Splits the frontmatter between the opening delimiter, its YAML content
+	// and the closing delimiter.
+	start := strings.Index(frontmatter, "\n") + 1
+	end := strings.LastIndex(strings.TrimRight(frontmatter, "\r\n"), "\n") + 1
+	yaml := frontmatter[start:end]
+
+	if frontmatterStatusRegex.MatchString(yaml) {
+		yaml = frontmatterStatusRegex.ReplaceAllLiteralString(yaml, line)
+	} else {
+		yaml += line
+	}
+
+	if strings.TrimSpace(yaml) == "" {
+		return strings.TrimPrefix(strings.TrimPrefix(body, "\r\n"), "\n")
+	}
+	return frontmatter[:start] + yaml + frontmatter[end:] + body
+}
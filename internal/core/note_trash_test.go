@@ -0,0 +1,35 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestIsTrashed(t *testing.T) {
+	assert.False(t, IsTrashed(map[string]interface{}{}))
+	assert.False(t, IsTrashed(map[string]interface{}{"status": "draft"}))
+	assert.False(t, IsTrashed(map[string]interface{}{"status": 42}))
+	assert.True(t, IsTrashed(map[string]interface{}{"status": "trash"}))
+	assert.True(t, IsTrashed(map[string]interface{}{"status": " Deleted "}))
+}
+
+func TestSetFrontmatterStatus(t *testing.T) {
+	test := func(content, status, expected string) {
+		assert.Equal(t, setFrontmatterStatus(content, status), expected)
+	}
+
+	// Creates the frontmatter.
+	test("# Title\n", "trash", "---\nstatus: trash\n---\n\n# Title\n")
+	test("# Title\n", "", "# Title\n")
+	// Adds the status to an existing frontmatter.
+	test("---\ntitle: A\n---\nBody", "trash", "---\ntitle: A\nstatus: trash\n---\nBody")
+	// Replaces an existing status.
+	test("---\nStatus: draft\ntitle: A\n---\nBody", "trash", "---\nstatus: trash\ntitle: A\n---\nBody")
+	// Removes the status.
+	test("---\ntitle: A\nstatus: trash\n---\nBody", "", "---\ntitle: A\n---\nBody")
+	// Removes the frontmatter left empty.
+	test("---\nstatus: trash\n---\n\n# Title\n", "", "# Title\n")
+	// Indented keys are not top-level statuses.
+	test("---\nproject:\n  status: done\n---\nBody", "trash", "---\nproject:\n  status: done\nstatus: trash\n---\nBody")
+}
@@ -2,9 +2,13 @@ package core
 
 import (
 	"fmt"
-	"os"
+	"math"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mickael-menu/zk/internal/util"
@@ -12,7 +16,7 @@ import (
 	"github.com/mickael-menu/zk/internal/util/icu"
 	"github.com/mickael-menu/zk/internal/util/opt"
 	"github.com/mickael-menu/zk/internal/util/paths"
-	"github.com/schollz/progressbar/v3"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
 )
 
 // Notebook handles queries and commands performed on an opened notebook.
@@ -22,11 +26,26 @@ type Notebook struct {
 
 	index                 NoteIndex
 	parser                NoteContentParser
+	renderer              NoteContentRenderer
 	templateLoaderFactory TemplateLoaderFactory
 	idGeneratorFactory    IDGeneratorFactory
 	fs                    FileStorage
 	logger                util.Logger
 	osEnv                 func() map[string]string
+	auditLog              AuditLog
+	eventLog              IndexEventLog
+	vcs                   VersionControl
+	bibliographyParser    BibliographyParser
+	templateDirs          []string
+	// Incremented after each write transaction in the index, accessed
+	// atomically.
+	indexRevision uint32
+	// HTML output of the block renderers, by language and block content.
+	renderedBlocks      map[string]string
+	renderedBlocksMutex sync.Mutex
+	// Parsed bibliography files, by absolute path.
+	bibliographies      map[string]parsedBibliography
+	bibliographiesMutex sync.Mutex
 }
 
 // NewNotebook creates a new Notebook instance.
@@ -40,58 +59,142 @@ func NewNotebook(
 		Config:                config,
 		index:                 ports.NoteIndex,
 		parser:                ports.NoteContentParser,
+		renderer:              ports.NoteContentRenderer,
 		templateLoaderFactory: ports.TemplateLoaderFactory,
 		idGeneratorFactory:    ports.IDGeneratorFactory,
-		fs:                    ports.FS,
+		fs:                    newEncryptedFileStorage(ports.FS, path, config),
 		logger:                ports.Logger,
 		osEnv:                 ports.OSEnv,
+		auditLog:              ports.AuditLog,
+		eventLog:              ports.IndexEventLog,
+		vcs:                   ports.VersionControl,
+		bibliographyParser:    ports.BibliographyParser,
+		templateDirs:          ports.TemplateDirs,
 	}
 }
 
 type NotebookPorts struct {
 	NoteIndex             NoteIndex
 	NoteContentParser     NoteContentParser
+	NoteContentRenderer   NoteContentRenderer
 	TemplateLoaderFactory TemplateLoaderFactory
 	IDGeneratorFactory    IDGeneratorFactory
 	FS                    FileStorage
 	Logger                util.Logger
 	OSEnv                 func() map[string]string
+	AuditLog              AuditLog
+	IndexEventLog         IndexEventLog
+	VersionControl        VersionControl
+	BibliographyParser    BibliographyParser
+	// Directories holding the note templates, by lookup order.
+	TemplateDirs []string
 }
 
 // NotebookFactory creates a new Notebook instance at the given root path.
 type NotebookFactory func(path string, config Config) (*Notebook, error)
 
 // Index indexes the content of the notebook to be searchable.
-// If force is true, existing notes will be reindexed.
-func (n *Notebook) Index(force bool) (stats NoteIndexingStats, err error) {
-	// FIXME: Move out of Core
-	bar := progressbar.NewOptions(-1,
-		progressbar.OptionSetWriter(os.Stderr),
-		progressbar.OptionThrottle(100*time.Millisecond),
-		progressbar.OptionSpinnerType(14),
-	)
-
-	err = n.index.Commit(func(index NoteIndex) error {
+// If force is true, existing notes will be reindexed. The origin is given to
+// the indexing hooks.
+func (n *Notebook) Index(origin AuditOrigin, force bool) (NoteIndexingStats, error) {
+	return n.IndexWithProgress(origin, force, nil)
+}
+
+// IndexWithProgress indexes the content of the notebook, reporting the
+// progress of the run to the given callback, which may be nil.
+func (n *Notebook) IndexWithProgress(origin AuditOrigin, force bool, progress func(IndexProgress)) (stats NoteIndexingStats, err error) {
+	err = n.runHook(origin, HookPreIndex, nil, force)
+	if err != nil {
+		return
+	}
+
+	var changes []HookNote
+	err = n.commitIndex(func(index NoteIndex) error {
 		task := indexTask{
 			path:   n.Path,
 			config: n.Config,
 			force:  force,
 			index:  index,
 			parser: n,
+			fs:     n.fs,
 			logger: n.logger,
 		}
-		stats, err = task.execute(func(change paths.DiffChange) {
-			bar.Add(1)
-			bar.Describe(change.String())
-		})
+		stats, err = task.execute(progress)
+		changes = task.changes
 		return err
 	})
 
-	bar.Clear()
-	err = errors.Wrap(err, "indexing")
+	if err != nil {
+		err = ErrIndexing{Err: err}
+		return
+	}
+
+	if len(stats.Renamed) > 0 {
+		n.recordRenames(stats.Renamed)
+		if n.Config.Index.RelinkRenamed {
+			var relinked []NoteMove
+			relinked, err = n.RelinkRenamedNotes(origin, stats.Renamed, false)
+			if err == nil {
+				stats.Renamed = relinked
+			}
+		}
+	}
+
+	if len(changes) > 0 {
+		n.logger.Err(n.runHook(origin, HookPostIndex, changes, force))
+	}
 	return
 }
 
+// recordRenames appends the notes renamed outside of zk to the index event
+// log.
+func (n *Notebook) recordRenames(renames []NoteMove) {
+	if n.eventLog == nil {
+		return
+	}
+	events := []IndexEvent{}
+	for _, rename := range renames {
+		events = append(events, IndexEvent{
+			Time: time.Now().UTC(),
+			Kind: IndexEventNoteRenamed,
+			Path: rename.TargetPath,
+			From: rename.SourcePath,
+		})
+	}
+	n.logger.Err(n.eventLog.Append(events))
+}
+
+// IndexRevision returns a number which changes every time the index of the
+// notebook is modified, to invalidate the caches built from its content.
+func (n *Notebook) IndexRevision() uint32 {
+	return atomic.LoadUint32(&n.indexRevision)
+}
+
+// commitIndex performs the given transaction in the index of the notebook.
+//
+// The changes made to the notes are recorded in the index event log.
+func (n *Notebook) commitIndex(transaction func(idx NoteIndex) error) error {
+	defer atomic.AddUint32(&n.indexRevision, 1)
+	if n.eventLog == nil {
+		return n.index.Commit(transaction)
+	}
+
+	var events []IndexEvent
+	err := n.index.Commit(func(idx NoteIndex) error {
+		recorder := newIndexEventRecorder(idx, n)
+		if err := transaction(recorder); err != nil {
+			return err
+		}
+		events = recorder.finish()
+		return nil
+	})
+	if err == nil && len(events) > 0 {
+		// Failing to record the events is not fatal.
+		n.logger.Err(n.eventLog.Append(events))
+	}
+	return err
+}
+
 // NewNoteOpts holds the options used to create a new note in a Notebook.
 type NewNoteOpts struct {
 	// Title of the new note.
@@ -100,6 +203,9 @@ type NewNoteOpts struct {
 	Content string
 	// Directory in which to create the note, relative to the root of the notebook.
 	Directory opt.String
+	// Path of the note relative to Directory, instead of the one generated
+	// from the filename template of the group.
+	Filename opt.String
 	// Group this note belongs to.
 	Group opt.String
 	// Path to a custom template used to render the note.
@@ -108,6 +214,13 @@ type NewNoteOpts struct {
 	Extra map[string]string
 	// Creation date provided to the templates.
 	Date time.Time
+	// Parent note of a new Folgezettel, either its path or its ID.
+	Parent opt.String
+	// Existing note in which a link to the new note is inserted.
+	LinkFrom *NoteLinkLocation
+	// Origin identifies who is creating the note, for the audit log and the
+	// hooks.
+	Origin AuditOrigin
 }
 
 // ErrNoteExists is an error returned when a note already exists with the
@@ -121,58 +234,193 @@ func (e ErrNoteExists) Error() string {
 	return fmt.Sprintf("%s: note already exists", e.Path)
 }
 
+// ErrNoteNotFound is an error returned when no note is found at the given
+// path.
+type ErrNoteNotFound string
+
+func (e ErrNoteNotFound) Error() string {
+	return fmt.Sprintf("%s: note not found in the notebook", string(e))
+}
+
 // NewNote generates a new note in the notebook, index and returns it.
 //
 // Returns ErrNoteExists if no free filename can be generated for this note.
 func (n *Notebook) NewNote(opts NewNoteOpts) (*Note, error) {
 	wrap := errors.Wrapper("new note")
 
-	dir, err := n.RequireDirAt(opts.Directory.OrString(n.Path).Unwrap())
+	linkFrom, err := n.resolveLinkFrom(opts.LinkFrom)
 	if err != nil {
 		return nil, wrap(err)
 	}
 
-	config, err := n.Config.GroupConfigNamed(opts.Group.OrString(dir.Group).Unwrap())
+	// The note file is written in the index transaction, to prevent another
+	// process from indexing it first.
+	var note *Note
+	var carried *carriedTasks
+	err = n.commitIndex(func(index NoteIndex) error {
+		dir, config, err := n.newNoteGroup(opts)
+		if err != nil {
+			return err
+		}
+		// Looked up before creating the note, which would be the most
+		// recent one.
+		carried, err = n.findCarriedTasks(index, dir, config.Note, opts.Date)
+		if err != nil {
+			return err
+		}
+
+		note, err = n.newNote(index, opts)
+		if err != nil {
+			return err
+		}
+		if carried != nil {
+			err = n.carryOverTasks(index, note, *carried)
+		}
+		if err == nil && linkFrom != nil {
+			err = n.insertLinkFrom(index, *note, *linkFrom)
+		}
+		if err != nil {
+			n.logger.Err(n.fs.Remove(filepath.Join(n.Path, note.Path)))
+		}
+		return err
+	})
 	if err != nil {
 		return nil, wrap(err)
 	}
 
-	extra := config.Extra
+	paths := []string{note.Path}
+	details := []string{}
+	if carried != nil {
+		paths = append(paths, carried.source.Path)
+		details = append(details, carried.details())
+	}
+	if linkFrom != nil {
+		paths = append(paths, linkFrom.note.Path)
+		details = append(details, "linked from "+linkFrom.note.Path)
+	}
+	n.audit(opts.Origin, AuditOperationCreate, strings.Join(details, ", "), note.Path)
+	n.autoCommit(string(AuditOperationCreate), paths...)
+	n.logger.Err(n.RecordHistory(NoteHistoryCreated, note.Path))
+	n.logger.Err(n.runHook(opts.Origin, HookPostNew, n.newNotesHookPayload(note), false))
+	return note, nil
+}
+
+// NewNotes generates several new notes in the notebook at once, with a single
+// index transaction.
+//
+// If any of the notes can't be created, the ones already generated are
+// removed and no note is indexed. The notes are expected to share the same
+// origin, the post-new hook runs once with the origin of the first one.
+func (n *Notebook) NewNotes(opts []NewNoteOpts) ([]*Note, error) {
+	wrap := errors.Wrapper("new notes")
+
+	notes := []*Note{}
+	err := n.commitIndex(func(index NoteIndex) error {
+		for _, noteOpts := range opts {
+			note, err := n.newNote(index, noteOpts)
+			if err != nil {
+				return err
+			}
+			notes = append(notes, note)
+		}
+		return nil
+	})
+	if err != nil {
+		for _, note := range notes {
+			n.logger.Err(n.fs.Remove(filepath.Join(n.Path, note.Path)))
+		}
+		return nil, wrap(err)
+	}
+
+	paths := []string{}
+	for i, note := range notes {
+		n.audit(opts[i].Origin, AuditOperationCreate, "batch", note.Path)
+		paths = append(paths, note.Path)
+	}
+	n.autoCommit(string(AuditOperationCreate), paths...)
+	if len(notes) > 0 {
+		n.logger.Err(n.runHook(opts[0].Origin, HookPostNew, n.newNotesHookPayload(notes...), false))
+	}
+	return notes, nil
+}
+
+// newNoteGroup returns the directory of a new note and the config of its
+// group.
+func (n *Notebook) newNoteGroup(opts NewNoteOpts) (Dir, GroupConfig, error) {
+	dir, err := n.RequireDirAt(opts.Directory.OrString(n.Path).Unwrap())
+	if err != nil {
+		return dir, GroupConfig{}, err
+	}
+
+	config, err := n.Config.GroupConfigNamed(opts.Group.OrString(dir.Group).Unwrap())
+	return dir, config, err
+}
+
+// newNote generates a new note and adds it to the given index.
+func (n *Notebook) newNote(index NoteIndex, opts NewNoteOpts) (*Note, error) {
+	dir, config, err := n.newNoteGroup(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the group variables, to not leak the ones of this note in the
+	// next ones.
+	extra := map[string]string{}
+	for k, v := range config.Extra {
+		extra[k] = v
+	}
 	for k, v := range opts.Extra {
 		extra[k] = v
 	}
+	bodyTemplatePath := opts.Template.Or(config.Note.BodyTemplatePath)
+	err = n.resolveTemplateVars(bodyTemplatePath.Unwrap(), extra)
+	if err != nil {
+		return nil, err
+	}
 
 	templates, err := n.templateLoaderFactory(config.Note.Lang)
 	if err != nil {
-		return nil, wrap(err)
+		return nil, err
+	}
+
+	title := opts.Title.OrString(config.Note.DefaultTitle).Unwrap()
+	genID, reserveIDs, err := n.noteIDGenerator(index, dir, config.Note.IDOptions, title, opts)
+	if err != nil {
+		return nil, err
+	}
+	var reserveID func(id string) (bool, error)
+	if reserveIDs {
+		reserveID = index.ReserveID
 	}
 
 	task := newNoteTask{
 		dir:              dir,
-		title:            opts.Title.OrString(config.Note.DefaultTitle).Unwrap(),
+		title:            title,
 		content:          opts.Content,
 		date:             opts.Date,
 		extra:            extra,
 		env:              n.osEnv(),
 		fs:               n.fs,
+		filename:         opts.Filename.Unwrap(),
 		filenameTemplate: config.Note.FilenameTemplate + "." + config.Note.Extension,
-		bodyTemplatePath: opts.Template.Or(config.Note.BodyTemplatePath),
+		bodyTemplatePath: bodyTemplatePath,
 		templates:        templates,
-		genID:            n.idGeneratorFactory(config.Note.IDOptions),
+		genID:            genID,
+		reserveID:        reserveID,
 	}
 	path, err := task.execute()
 	if err != nil {
-		return nil, wrap(err)
+		return nil, err
 	}
 
 	note, err := n.ParseNoteAt(path)
 	if note == nil || err != nil {
-		return nil, wrap(err)
+		return nil, err
 	}
 
-	id, err := n.index.Add(*note)
+	id, err := index.Add(*note)
 	if err != nil {
-		return nil, wrap(err)
+		return nil, err
 	}
 
 	note.ID = id
@@ -181,6 +429,18 @@ func (n *Notebook) NewNote(opts NewNoteOpts) (*Note, error) {
 
 // FindNotes retrieves the notes matching the given filtering options.
 func (n *Notebook) FindNotes(opts NoteFindOpts) ([]ContextualNote, error) {
+	opts, err := n.resolveModifiedSince(opts)
+	if err != nil {
+		return nil, err
+	}
+	opts, err = n.resolveHierarchy(opts)
+	if err != nil {
+		return nil, err
+	}
+	opts = n.resolveArchive(opts)
+	if opts.MatchWeights == nil {
+		opts.MatchWeights = &n.Config.Search.Weights
+	}
 	return n.index.Find(opts)
 }
 
@@ -201,6 +461,18 @@ func (n *Notebook) FindNote(opts NoteFindOpts) (*Note, error) {
 // FindMinimalNotes retrieves lightweight metadata for the notes matching
 // the given filtering options.
 func (n *Notebook) FindMinimalNotes(opts NoteFindOpts) ([]MinimalNote, error) {
+	opts, err := n.resolveModifiedSince(opts)
+	if err != nil {
+		return nil, err
+	}
+	opts, err = n.resolveHierarchy(opts)
+	if err != nil {
+		return nil, err
+	}
+	opts = n.resolveArchive(opts)
+	if opts.MatchWeights == nil {
+		opts.MatchWeights = &n.Config.Search.Weights
+	}
 	return n.index.FindMinimal(opts)
 }
 
@@ -239,6 +511,141 @@ func (n *Notebook) FindByHref(href string, allowPartialMatch bool) (*MinimalNote
 	})
 }
 
+// FindByAlias retrieves the first note declaring the given alias in its
+// frontmatter, ignoring case. Wiki-links can target a note by one of its
+// aliases, e.g. [[JS]] for a note about JavaScript.
+func (n *Notebook) FindByAlias(alias string) (*MinimalNote, error) {
+	alias = strings.TrimSpace(strings.SplitN(alias, "#", 2)[0])
+	if alias == "" {
+		return nil, nil
+	}
+
+	return n.FindMinimalNote(NoteFindOpts{
+		Aliases: []string{alias},
+		Sorters: []NoteSorter{{Field: NoteSortPathLength, Ascending: true}},
+	})
+}
+
+var idPlaceholderRegex = regexp.MustCompile(`\{\{[^}]*\bid\b`)
+
+// FindJournalNote retrieves the note created for the given date, e.g. a daily
+// journal entry. The filename template of each group is rendered with the
+// date, then looked up in the group directories.
+//
+// Returns nil when no note was created for this date.
+func (n *Notebook) FindJournalNote(date time.Time) (*MinimalNote, error) {
+	wrap := errors.Wrapperf("failed to find the journal note of %s", date.Format("2006-01-02"))
+
+	names := []string{}
+	for name := range n.Config.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	groups := []GroupConfig{}
+	for _, name := range names {
+		groups = append(groups, n.Config.Groups[name])
+	}
+	groups = append(groups, n.Config.RootGroupConfig())
+
+	for _, group := range groups {
+		if idPlaceholderRegex.MatchString(group.Note.FilenameTemplate) {
+			// Filenames with a random ID can't be derived from the date.
+			continue
+		}
+		templates, err := n.templateLoaderFactory(group.Note.Lang)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		template, err := templates.LoadTemplate(group.Note.FilenameTemplate + "." + group.Note.Extension)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		filename, err := template.Render(newNoteTemplateContext{Now: date})
+		if err != nil {
+			return nil, wrap(err)
+		}
+
+		dirs := group.Paths
+		if len(dirs) == 0 {
+			dirs = []string{""}
+		}
+		for _, dir := range dirs {
+			note, err := n.FindByHref(filepath.Join(dir, filename), false)
+			if err != nil {
+				return nil, wrap(err)
+			}
+			if note != nil {
+				return note, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// FindByHrefFuzzy retrieves the note whose path or title is the closest to
+// the given link href, for links which don't resolve to any note otherwise.
+// The confidence of the match, between 0 and 1, is returned along the note.
+//
+// Returns nil when no note is similar enough, according to the
+// `search.fuzzy-link-threshold` setting.
+func (n *Notebook) FindByHrefFuzzy(href string) (*MinimalNote, float64, error) {
+	threshold := n.Config.Search.FuzzyLinkThreshold
+	if threshold <= 0 {
+		return nil, 0, nil
+	}
+
+	notes, err := n.FindMinimalNotes(NoteFindOpts{})
+	if err != nil {
+		return nil, 0, err
+	}
+	note, confidence := fuzzyMatchHref(href, notes, threshold)
+	return note, confidence, nil
+}
+
+// fuzzyMatchHref returns the note from the given candidates which is the
+// most similar to href, if its similarity is at least threshold.
+func fuzzyMatchHref(href string, notes []MinimalNote, threshold float64) (*MinimalNote, float64) {
+	href = strings.SplitN(href, "#", 2)[0]
+	if href == "" {
+		return nil, 0
+	}
+
+	var best *MinimalNote
+	bestScore := 0.0
+	for i, note := range notes {
+		score := fuzzyHrefSimilarity(href, note)
+		if score < threshold || score < bestScore {
+			continue
+		}
+		// On a tie, the shortest path is the best match, like FindByHref.
+		if best != nil && score == bestScore && len(note.Path) >= len(best.Path) {
+			continue
+		}
+		best = &notes[i]
+		bestScore = score
+	}
+	return best, bestScore
+}
+
+// fuzzyHrefSimilarity returns how similar the path or title of the note is
+// to the given link href, without anchor, from 0 to 1.
+func fuzzyHrefSimilarity(href string, note MinimalNote) float64 {
+	stem := paths.FilenameStem(href)
+	// Wiki-links targeting a title might contain dots which are not a file
+	// extension.
+	base := filepath.Base(href)
+
+	score := strutil.TrigramSimilarity(stem, paths.FilenameStem(note.Path))
+	score = math.Max(score, strutil.TrigramSimilarity(stem, note.Title))
+	score = math.Max(score, strutil.TrigramSimilarity(base, note.Title))
+	if strings.Contains(href, "/") {
+		score = math.Max(score, strutil.TrigramSimilarity(paths.DropExt(href), paths.DropExt(note.Path)))
+	}
+	return score
+}
+
 // FindMatching retrieves the first note matching the given search terms.
 func (n *Notebook) FindMatching(terms string) (*MinimalNote, error) {
 	return n.FindMinimalNote(NoteFindOpts{
@@ -247,8 +654,24 @@ func (n *Notebook) FindMatching(terms string) (*MinimalNote, error) {
 }
 
 // FindCollections retrieves all the collections of the given kind.
+// The tags are decorated with their style declared in the config.
 func (n *Notebook) FindCollections(kind CollectionKind, sorters []CollectionSorter) ([]Collection, error) {
-	return n.index.FindCollections(kind, sorters)
+	collections, err := n.index.FindCollections(kind, sorters)
+	if err != nil || kind != CollectionKindTag || len(n.Config.TagStyles) == 0 {
+		return collections, err
+	}
+	for i, collection := range collections {
+		style := n.Config.TagStyle(collection.Name)
+		collections[i].Color = style.Color
+		collections[i].Icon = style.Icon
+	}
+	return collections, nil
+}
+
+// QueryIndex runs a read-only SQL query on the index of the notebook.
+func (n *Notebook) QueryIndex(query string) (IndexQueryResult, error) {
+	res, err := n.index.Query(query)
+	return res, errors.Wrap(err, "failed to query the index")
 }
 
 // RelPath returns the path relative to the notebook root to the given path.
@@ -349,7 +772,7 @@ func (n *Notebook) NewNoteFormatter(templateString string) (NoteFormatter, error
 		return nil, err
 	}
 
-	return newNoteFormatter(n.Path, template, linkFormatter, n.osEnv(), n.fs)
+	return newNoteFormatter(n.Path, template, linkFormatter, n.osEnv(), n.fs, n.vcs)
 }
 
 // NewCollectionFormatter returns a CollectionFormatter used to format notes with the given template.
@@ -368,10 +791,16 @@ func (n *Notebook) NewCollectionFormatter(templateString string) (CollectionForm
 
 // NewLinkFormatter returns a LinkFormatter used to generate internal links between notes.
 func (n *Notebook) NewLinkFormatter() (LinkFormatter, error) {
+	return n.NewLinkFormatterWithConfig(n.Config.Format.Markdown)
+}
+
+// NewLinkFormatterWithConfig returns a LinkFormatter generating the links
+// with the given Markdown settings, e.g. the ones of a note group.
+func (n *Notebook) NewLinkFormatterWithConfig(config MarkdownConfig) (LinkFormatter, error) {
 	templates, err := n.templateLoaderFactory(n.Config.Note.Lang)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewLinkFormatter(n.Config.Format.Markdown, templates)
+	return NewLinkFormatter(config, templates)
 }
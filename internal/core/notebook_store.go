@@ -3,6 +3,7 @@ package core
 import (
 	"fmt"
 	"path/filepath"
+	"sync"
 
 	"github.com/mickael-menu/zk/internal/util/errors"
 )
@@ -13,15 +14,22 @@ type NotebookStore struct {
 	notebookFactory NotebookFactory
 	templateLoader  TemplateLoader
 	fs              FileStorage
+	trustNotebook   func(path string, snippets []string) (bool, error)
 
-	// Cached opened notebooks.
+	// Cached opened notebooks, shared by concurrent callers such as the
+	// clients of an LSP server.
 	notebooks map[string]*Notebook
+	mutex     sync.Mutex
 }
 
 type NotebookStorePorts struct {
 	NotebookFactory NotebookFactory
 	TemplateLoader  TemplateLoader
 	FS              FileStorage
+	// Asks whether the notebook at the given path is trusted to run the
+	// shell commands found at the given locations of its config and
+	// templates. When nil, all the notebooks are trusted.
+	TrustNotebook func(path string, snippets []string) (bool, error)
 }
 
 // NewNotebookStore creates a new NotebookStore instance using the given
@@ -32,6 +40,7 @@ func NewNotebookStore(config Config, ports NotebookStorePorts) *NotebookStore {
 		notebookFactory: ports.NotebookFactory,
 		templateLoader:  ports.TemplateLoader,
 		fs:              ports.FS,
+		trustNotebook:   ports.TrustNotebook,
 		notebooks:       map[string]*Notebook{},
 	}
 }
@@ -48,6 +57,9 @@ func (e ErrNotebookNotFound) Error() string {
 func (ns *NotebookStore) Open(path string) (*Notebook, error) {
 	wrap := errors.Wrapper("failed to open notebook")
 
+	ns.mutex.Lock()
+	defer ns.mutex.Unlock()
+
 	path = ns.fs.Canonical(path)
 	nb := ns.cachedNotebookAt(path)
 	if nb != nil {
@@ -68,6 +80,10 @@ func (ns *NotebookStore) Open(path string) (*Notebook, error) {
 	if err != nil {
 		return nil, wrap(err)
 	}
+	config, err = ns.trustConfig(path, config)
+	if err != nil {
+		return nil, wrap(err)
+	}
 
 	nb, err = ns.notebookFactory(path, config)
 	if err != nil {
@@ -78,6 +94,26 @@ func (ns *NotebookStore) Open(path string) (*Notebook, error) {
 	return nb, nil
 }
 
+// trustConfig returns the config of the notebook at the given path, without
+// its shell commands if the user doesn't trust the notebook. This prevents a
+// cloned notebook from running arbitrary commands on behalf of the user.
+func (ns *NotebookStore) trustConfig(path string, config Config) (Config, error) {
+	if ns.trustNotebook == nil {
+		return config, nil
+	}
+
+	snippets := append(config.ShellSnippets(ns.config), shellTemplates(path)...)
+	if len(snippets) == 0 {
+		return config, nil
+	}
+
+	trusted, err := ns.trustNotebook(path, snippets)
+	if err != nil || trusted {
+		return config, err
+	}
+	return config.WithoutShellSnippets(ns.config), nil
+}
+
 // cachedNotebookAt returns any cached notebook containing the given path.
 func (ns *NotebookStore) cachedNotebookAt(path string) *Notebook {
 	path, err := ns.fs.Abs(path)
@@ -301,6 +337,12 @@ multiword-tags = false
 #https://github.com/sharkdp/bat
 #fzf-preview = "bat -p --color always {-1}"
 
+# Commands used to extract the text of PDF and image attachments, to find
+# the notes linking to them with --match. The file is piped to the
+# standard input and the text is read from the standard output.
+#pdf-text = "pdftotext - -"
+#ocr = "tesseract stdin stdout"
+
 
 # LSP
 #
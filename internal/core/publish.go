@@ -0,0 +1,680 @@
+package core
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/paths"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// PublishOpts holds the options used to publish notes as a static website.
+type PublishOpts struct {
+	// Notes to publish.
+	Notes []ContextualNote
+	// Directory where the website is written.
+	OutputDir string
+	// Title of the website.
+	Title string
+	// Base URL where the website is served, required to generate the
+	// sitemap and the RSS feed.
+	BaseURL string
+	// Maximum number of recent notes listed in the RSS feed.
+	FeedLength int
+}
+
+// PublishReport describes the website generated by Notebook.Publish.
+type PublishReport struct {
+	// Number of published notes.
+	Notes int
+	// Number of index pages, for the tags and directories.
+	IndexPages int
+	// Number of copied assets.
+	Assets int
+	// Indicates whether the sitemap and the RSS feed were generated.
+	Feed bool
+}
+
+// publishTemplateDir is the directory of the website templates, in the
+// template directories of the notebook.
+const publishTemplateDir = "publish"
+
+// Publish generates a static website from the given notes, with index pages
+// per tag and directory, backlinks, a graph of the links in graph.json, a
+// sitemap and an RSS feed of the recent notes.
+//
+// The pages are rendered with the templates publish/note.html and
+// publish/list.html found in the template directories, falling back on the
+// built-in ones. The files of publish/static are copied to the website root.
+func (n *Notebook) Publish(opts PublishOpts) (*PublishReport, error) {
+	wrap := errors.Wrapper("failed to publish the notebook")
+
+	site, err := n.newPublishedSite(opts)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if err := site.render(); err != nil {
+		return nil, wrap(err)
+	}
+	report, err := site.write()
+	return report, wrap(err)
+}
+
+// publishedSite is a website being generated from a set of notes.
+type publishedSite struct {
+	notebook *Notebook
+	opts     PublishOpts
+	// Published notes by path, in the order of the options.
+	notes  []*publishedNote
+	byPath map[string]*publishedNote
+	// Assets linked by the published notes, relative to the notebook root.
+	assets map[string]bool
+	// Links between the published notes, as source and target paths.
+	links map[[2]string]bool
+
+	noteTemplate Template
+	listTemplate Template
+}
+
+// publishedNote is a note rendered as a page of the website.
+type publishedNote struct {
+	note ContextualNote
+	// Path of the page, relative to the website root, e.g. dir/note/.
+	page string
+	html string
+}
+
+// title returns the title of the note, or its filename when it has none.
+func (n *publishedNote) title() string {
+	if n.note.Title == "" {
+		return paths.FilenameStem(n.note.Path)
+	}
+	return n.note.Title
+}
+
+func (n *Notebook) newPublishedSite(opts PublishOpts) (*publishedSite, error) {
+	if opts.Title == "" {
+		opts.Title = filepath.Base(n.Path)
+	}
+	if opts.FeedLength <= 0 {
+		opts.FeedLength = 20
+	}
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+
+	site := &publishedSite{
+		notebook: n,
+		opts:     opts,
+		notes:    []*publishedNote{},
+		byPath:   map[string]*publishedNote{},
+		assets:   map[string]bool{},
+		links:    map[[2]string]bool{},
+	}
+
+	for _, note := range opts.Notes {
+		if _, ok := site.byPath[note.Path]; ok {
+			continue
+		}
+		published := &publishedNote{
+			note: note,
+			page: publishedPage(note.AsMinimalNote()),
+		}
+		site.notes = append(site.notes, published)
+		site.byPath[note.Path] = published
+	}
+
+	var err error
+	site.noteTemplate, err = n.publishTemplate("note.html", defaultPublishNoteTemplate)
+	if err != nil {
+		return nil, err
+	}
+	site.listTemplate, err = n.publishTemplate("list.html", defaultPublishListTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return site, nil
+}
+
+// publishTemplate loads the website template with the given name from the
+// template directories, or the given default one.
+func (n *Notebook) publishTemplate(name string, defaultTemplate string) (Template, error) {
+	templates, err := n.templateLoaderFactory(n.Config.Note.Lang)
+	if err != nil {
+		return nil, err
+	}
+
+	content := defaultTemplate
+	// The notebook templates take precedence over the global ones.
+	for i := len(n.templateDirs) - 1; i >= 0; i-- {
+		path := filepath.Join(n.templateDirs[i], publishTemplateDir, name)
+		exists, err := n.fs.FileExists(path)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			bytes, err := n.fs.Read(path)
+			if err != nil {
+				return nil, err
+			}
+			content = string(bytes)
+			break
+		}
+	}
+	return templates.LoadHTMLTemplate(content)
+}
+
+// publishedPage returns the path of the page of a note, relative to the
+// website root. It matches the URL returned by MinimalNote.PublishedURL.
+func publishedPage(note MinimalNote) string {
+	page := strings.TrimPrefix(note.PublishedURL(""), "/")
+	if path.Ext(page) == "" {
+		page += "/"
+	}
+	return page
+}
+
+// render renders the content of the published notes, and collects their
+// links.
+func (s *publishedSite) render() error {
+	for _, note := range s.notes {
+		page := note.page
+		var err error
+		note.html, err = s.renderNote(note.note.Path, func(target string) string {
+			return relativeURL(page, target)
+		}, map[string]bool{note.note.Path: true})
+		if err != nil {
+			return errors.Wrapf(err, "%s", note.note.Path)
+		}
+	}
+	return nil
+}
+
+// renderNote renders the content of the note at the given path, using url to
+// get the URL of a page or asset relative to the website root. The embedded
+// notes are expanded recursively unless they are in embedding, to prevent
+// cycles.
+func (s *publishedSite) renderNote(notePath string, url func(target string) string, embedding map[string]bool) (string, error) {
+	note := s.byPath[notePath]
+
+	return s.notebook.RenderHTML(note.note.RawContent, func(link RenderedLink) (*ResolvedLink, error) {
+		href := strings.SplitN(link.Href, "#", 2)[0]
+		if href == "" || strutil.IsURL(link.Href) {
+			return nil, nil
+		}
+		anchor := strings.TrimPrefix(link.Href, href)
+		if _, _, federated := s.notebook.FederatedHref(link.Href); federated {
+			return &ResolvedLink{LabelOnly: true}, nil
+		}
+
+		target, _ := lintLinkTarget(notePath, link.Href)
+		if s.notebook.isAssetPath(target) {
+			exists, err := s.notebook.fs.FileExists(filepath.Join(s.notebook.Path, target))
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				s.assets[target] = true
+				return &ResolvedLink{URL: url(target) + anchor}, nil
+			}
+		}
+
+		found, _, err := s.notebook.ResolveLink(notePath, link.Href, ResolveLinkOpts{IsWikiLink: link.IsWikiLink})
+		if err != nil {
+			return nil, err
+		}
+		var published *publishedNote
+		if found != nil {
+			published = s.byPath[found.Path]
+		}
+		if published == nil {
+			// The links to the private notes are not published.
+			return &ResolvedLink{LabelOnly: true}, nil
+		}
+
+		if found.Path != notePath {
+			s.links[[2]string{notePath, found.Path}] = true
+		}
+		resolved := &ResolvedLink{URL: url(published.page) + anchor}
+		if link.IsEmbed && !embedding[found.Path] && len(embedding) < MaxEmbedDepth {
+			embedding[found.Path] = true
+			resolved.HTML, err = s.renderNote(found.Path, url, embedding)
+			delete(embedding, found.Path)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return resolved, nil
+	})
+}
+
+// relativeURL returns the URL of the target relative to the given page, both
+// relative to the website root.
+func relativeURL(page string, target string) string {
+	dir := path.Dir(page + "_")
+	rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(target))
+	if err != nil {
+		return "/" + target
+	}
+	rel = filepath.ToSlash(rel)
+	// The website root and the pages of the notes are directories.
+	if (target == "" || strings.HasSuffix(target, "/")) && !strings.HasSuffix(rel, "/") {
+		rel += "/"
+	}
+	return rel
+}
+
+// write writes the pages and other files of the website.
+func (s *publishedSite) write() (*PublishReport, error) {
+	report := &PublishReport{}
+
+	backlinks := map[string][]*publishedNote{}
+	for link := range s.links {
+		backlinks[link[1]] = append(backlinks[link[1]], s.byPath[link[0]])
+	}
+
+	tags := map[string][]*publishedNote{}
+	dirs := map[string][]*publishedNote{}
+	for _, note := range s.notes {
+		for _, tag := range note.note.Tags {
+			tags[tag] = append(tags[tag], note)
+		}
+		if dir := filepath.ToSlash(filepath.Dir(note.note.Path)); dir != "." {
+			dirs[dir] = append(dirs[dir], note)
+		}
+
+		sortPublishedNotes(backlinks[note.note.Path])
+		noteContext := s.noteContext(note, note.page)
+		context := s.pageContext(note.page)
+		context["title"] = noteContext["title"]
+		context["note"] = noteContext
+		context["content"] = note.html
+		context["backlinks"] = s.notesContext(backlinks[note.note.Path], note.page)
+		tagsContext := []map[string]string{}
+		for _, tag := range note.note.Tags {
+			style := s.notebook.Config.TagStyle(tag)
+			tagsContext = append(tagsContext, map[string]string{
+				"name":  tag,
+				"url":   relativeURL(note.page, publishedTagPage(tag)),
+				"color": style.Color,
+				"icon":  style.Icon,
+			})
+		}
+		context["tags"] = tagsContext
+
+		if err := s.writePage(note.page, s.noteTemplate, context); err != nil {
+			return nil, err
+		}
+		report.Notes++
+	}
+
+	for tag, notes := range tags {
+		page := publishedTagPage(tag)
+		if err := s.writeList(page, "#"+tag, notes); err != nil {
+			return nil, err
+		}
+		report.IndexPages++
+	}
+	for dir, notes := range dirs {
+		page := "dirs/" + dir + "/"
+		if err := s.writeList(page, dir, notes); err != nil {
+			return nil, err
+		}
+		report.IndexPages++
+	}
+	if err := s.writeHome(tags, dirs); err != nil {
+		return nil, err
+	}
+
+	if err := s.writeGraph(); err != nil {
+		return nil, err
+	}
+	if s.opts.BaseURL != "" {
+		if err := s.writeSitemap(tags, dirs); err != nil {
+			return nil, err
+		}
+		if err := s.writeFeed(); err != nil {
+			return nil, err
+		}
+		report.Feed = true
+	}
+
+	for asset := range s.assets {
+		if err := s.copyFile(filepath.Join(s.notebook.Path, asset), asset); err != nil {
+			return nil, err
+		}
+		report.Assets++
+	}
+	if err := s.copyStaticFiles(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// writeHome writes the home page of the website, listing all the notes with
+// the tags and directories.
+func (s *publishedSite) writeHome(tags map[string][]*publishedNote, dirs map[string][]*publishedNote) error {
+	notes := append([]*publishedNote{}, s.notes...)
+	sortPublishedNotes(notes)
+
+	context := s.pageContext("")
+	context["title"] = s.opts.Title
+	context["notes"] = s.notesContext(notes, "")
+	tagsContext := indexContext(tags, func(tag string) (string, string) {
+		return "#" + tag, publishedTagPage(tag)
+	})
+	for _, tag := range tagsContext {
+		style := s.notebook.Config.TagStyle(strings.TrimPrefix(tag["name"].(string), "#"))
+		tag["color"] = style.Color
+		tag["icon"] = style.Icon
+	}
+	context["tags"] = tagsContext
+	context["dirs"] = indexContext(dirs, func(dir string) (string, string) {
+		return dir, "dirs/" + dir + "/"
+	})
+	return s.writePage("", s.listTemplate, context)
+}
+
+// writeList writes an index page listing the given notes.
+func (s *publishedSite) writeList(page string, title string, notes []*publishedNote) error {
+	sortPublishedNotes(notes)
+	context := s.pageContext(page)
+	context["title"] = title
+	context["notes"] = s.notesContext(notes, page)
+	return s.writePage(page, s.listTemplate, context)
+}
+
+// pageContext returns the template context shared by all the pages.
+func (s *publishedSite) pageContext(page string) map[string]interface{} {
+	return map[string]interface{}{
+		"site": map[string]string{
+			"title":    s.opts.Title,
+			"base-url": s.opts.BaseURL,
+			"root":     relativeURL(page, ""),
+			"graph":    relativeURL(page, "graph.json"),
+		},
+	}
+}
+
+func (s *publishedSite) noteContext(note *publishedNote, page string) map[string]interface{} {
+	return map[string]interface{}{
+		"title":    note.title(),
+		"path":     note.note.Path,
+		"url":      relativeURL(page, note.page),
+		"lead":     note.note.Lead,
+		"created":  note.note.Created,
+		"modified": note.note.Modified,
+		"metadata": note.note.Metadata,
+	}
+}
+
+func (s *publishedSite) notesContext(notes []*publishedNote, page string) []map[string]interface{} {
+	context := []map[string]interface{}{}
+	for _, note := range notes {
+		context = append(context, s.noteContext(note, page))
+	}
+	return context
+}
+
+// indexContext returns the names and URLs of the given index pages, sorted
+// by name.
+func indexContext(pages map[string][]*publishedNote, nameAndPage func(key string) (string, string)) []map[string]interface{} {
+	context := []map[string]interface{}{}
+	for key, notes := range pages {
+		name, page := nameAndPage(key)
+		context = append(context, map[string]interface{}{
+			"name":       name,
+			"url":        page,
+			"note-count": len(notes),
+		})
+	}
+	sort.Slice(context, func(i, j int) bool {
+		return context[i]["name"].(string) < context[j]["name"].(string)
+	})
+	return context
+}
+
+// sortPublishedNotes sorts the notes by creation date, from the most recent
+// one.
+func sortPublishedNotes(notes []*publishedNote) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		if !notes[i].note.Created.Equal(notes[j].note.Created) {
+			return notes[i].note.Created.After(notes[j].note.Created)
+		}
+		return notes[i].note.Path < notes[j].note.Path
+	})
+}
+
+var publishedTagRegex = regexp.MustCompile(`[^\pL\pN_-]+`)
+
+// publishedTagPage returns the path of the index page of a tag.
+func publishedTagPage(tag string) string {
+	slug := strings.Trim(publishedTagRegex.ReplaceAllString(strings.ToLower(tag), "-"), "-")
+	return "tags/" + slug + "/"
+}
+
+// writePage renders a page of the website with the given template.
+func (s *publishedSite) writePage(page string, template Template, context map[string]interface{}) error {
+	html, err := template.Render(context)
+	if err != nil {
+		return err
+	}
+	file := page
+	if file == "" || strings.HasSuffix(file, "/") {
+		file += "index.html"
+	}
+	return s.writeFile(file, []byte(html))
+}
+
+// publishedGraph is the graph of the links between the published notes, for
+// a JavaScript visualization.
+type publishedGraph struct {
+	Nodes []publishedGraphNode `json:"nodes"`
+	Links []publishedGraphLink `json:"links"`
+	// Styles of the tags of the nodes, by tag name.
+	TagStyles map[string]publishedTagStyle `json:"tagStyles"`
+}
+
+type publishedTagStyle struct {
+	Color string `json:"color,omitempty"`
+	Icon  string `json:"icon,omitempty"`
+}
+
+type publishedGraphNode struct {
+	ID    string   `json:"id"`
+	Title string   `json:"title"`
+	URL   string   `json:"url"`
+	Tags  []string `json:"tags"`
+}
+
+type publishedGraphLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+func (s *publishedSite) writeGraph() error {
+	graph := publishedGraph{
+		Nodes:     []publishedGraphNode{},
+		Links:     []publishedGraphLink{},
+		TagStyles: map[string]publishedTagStyle{},
+	}
+	for _, note := range s.notes {
+		tags := note.note.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		for _, tag := range tags {
+			if style := s.notebook.Config.TagStyle(tag); style != (TagStyle{}) {
+				graph.TagStyles[tag] = publishedTagStyle{Color: style.Color, Icon: style.Icon}
+			}
+		}
+		graph.Nodes = append(graph.Nodes, publishedGraphNode{
+			ID:    note.note.Path,
+			Title: note.title(),
+			URL:   note.page,
+			Tags:  tags,
+		})
+	}
+	for link := range s.links {
+		graph.Links = append(graph.Links, publishedGraphLink{Source: link[0], Target: link[1]})
+	}
+	sort.Slice(graph.Links, func(i, j int) bool {
+		if graph.Links[i].Source != graph.Links[j].Source {
+			return graph.Links[i].Source < graph.Links[j].Source
+		}
+		return graph.Links[i].Target < graph.Links[j].Target
+	})
+
+	content, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.writeFile("graph.json", content)
+}
+
+type sitemap struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+func (s *publishedSite) writeSitemap(tags map[string][]*publishedNote, dirs map[string][]*publishedNote) error {
+	urls := []sitemapURL{{Loc: s.opts.BaseURL + "/"}}
+	for _, note := range s.notes {
+		url := sitemapURL{Loc: s.opts.BaseURL + "/" + note.page}
+		if !note.note.Modified.IsZero() {
+			url.LastMod = note.note.Modified.UTC().Format("2006-01-02")
+		}
+		urls = append(urls, url)
+	}
+	for tag := range tags {
+		urls = append(urls, sitemapURL{Loc: s.opts.BaseURL + "/" + publishedTagPage(tag)})
+	}
+	for dir := range dirs {
+		urls = append(urls, sitemapURL{Loc: s.opts.BaseURL + "/dirs/" + dir + "/"})
+	}
+	sort.Slice(urls[1:], func(i, j int) bool {
+		return urls[i+1].Loc < urls[j+1].Loc
+	})
+
+	return s.writeXML("sitemap.xml", sitemap{
+		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		URLs:  urls,
+	})
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title string    `xml:"title"`
+	Link  string    `xml:"link"`
+	Desc  string    `xml:"description"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title   string `xml:"title"`
+	Link    string `xml:"link"`
+	GUID    string `xml:"guid"`
+	PubDate string `xml:"pubDate,omitempty"`
+	Desc    string `xml:"description"`
+}
+
+// writeFeed writes an RSS feed of the most recently created notes.
+func (s *publishedSite) writeFeed() error {
+	notes := append([]*publishedNote{}, s.notes...)
+	sortPublishedNotes(notes)
+	if len(notes) > s.opts.FeedLength {
+		notes = notes[:s.opts.FeedLength]
+	}
+
+	channel := rssChannel{
+		Title: s.opts.Title,
+		Link:  s.opts.BaseURL + "/",
+		Desc:  "Recent notes of " + s.opts.Title,
+		Items: []rssItem{},
+	}
+	for _, note := range notes {
+		url := s.opts.BaseURL + "/" + note.page
+		// The links of the feed items must be absolute.
+		html, err := s.renderNote(note.note.Path, func(target string) string {
+			return s.opts.BaseURL + "/" + target
+		}, map[string]bool{note.note.Path: true})
+		if err != nil {
+			return err
+		}
+		item := rssItem{
+			Title: note.title(),
+			Link:  url,
+			GUID:  url,
+			Desc:  html,
+		}
+		if !note.note.Created.IsZero() {
+			item.PubDate = note.note.Created.UTC().Format(time.RFC1123Z)
+		}
+		channel.Items = append(channel.Items, item)
+	}
+
+	return s.writeXML("feed.xml", rssFeed{Version: "2.0", Channel: channel})
+}
+
+func (s *publishedSite) writeXML(file string, value interface{}) error {
+	content, err := xml.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.writeFile(file, append([]byte(xml.Header), content...))
+}
+
+// copyStaticFiles copies the files of the publish/static template
+// directories to the website root.
+func (s *publishedSite) copyStaticFiles() error {
+	for _, dir := range s.notebook.templateDirs {
+		dir = filepath.Join(dir, publishTemplateDir, "static")
+		exists, err := s.notebook.fs.DirExists(dir)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		for file := range paths.Walk(dir, s.notebook.logger, func(path string) (bool, error) {
+			return false, nil
+		}) {
+			if err := s.copyFile(filepath.Join(dir, file.Path), filepath.ToSlash(file.Path)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyFile copies the file at the given absolute path to the website.
+func (s *publishedSite) copyFile(source string, file string) error {
+	content, err := s.notebook.fs.Read(source)
+	if err != nil {
+		return err
+	}
+	return s.writeFile(file, content)
+}
+
+// writeFile writes a file of the website, at the given path relative to its
+// root.
+func (s *publishedSite) writeFile(file string, content []byte) error {
+	return s.notebook.fs.Write(filepath.Join(s.opts.OutputDir, filepath.FromSlash(file)), content)
+}
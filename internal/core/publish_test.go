@@ -0,0 +1,156 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestPublishedPage(t *testing.T) {
+	test := func(path string, metadata map[string]interface{}, expected string) {
+		assert.Equal(t, publishedPage(MinimalNote{Path: path, Metadata: metadata}), expected)
+	}
+
+	test("note.md", nil, "note/")
+	test("dir/sub/note.md", nil, "dir/sub/note/")
+	test("note.md", map[string]interface{}{"permalink": "/about"}, "about/")
+	test("note.md", map[string]interface{}{"permalink": "feed/index.html"}, "feed/index.html")
+}
+
+func TestPublishedTagPage(t *testing.T) {
+	assert.Equal(t, publishedTagPage("tag"), "tags/tag/")
+	assert.Equal(t, publishedTagPage("Big Tag"), "tags/big-tag/")
+	assert.Equal(t, publishedTagPage("project/zk"), "tags/project-zk/")
+	assert.Equal(t, publishedTagPage("été"), "tags/été/")
+}
+
+func TestRelativeURL(t *testing.T) {
+	test := func(page string, target string, expected string) {
+		assert.Equal(t, relativeURL(page, target), expected)
+	}
+
+	test("", "", "./")
+	test("", "note/", "note/")
+	test("", "img/pic.png", "img/pic.png")
+	test("note/", "", "../")
+	test("note/", "other/", "../other/")
+	test("dir/note/", "dir/other/", "../other/")
+	test("dir/note/", "note/", "../../note/")
+	test("dir/note/", "img/pic.png", "../../img/pic.png")
+	test("note/", "note/", "./")
+	test("about.html", "note/", "note/")
+	test("dir/about.html", "dir/note/", "note/")
+}
+
+func TestPublishResolvesLinks(t *testing.T) {
+	site := newPublishTestSite(t, map[string]string{
+		"a.md":     "# Alpha\nSee [[Gamma note]], [[c]], [[dir/c#intro]] and [[Unknown]].",
+		"dir/c.md": "# Gamma note",
+	})
+	assert.Nil(t, site.render())
+	assert.Equal(t, site.byPath["a.md"].html,
+		`# Alpha
+See <a href="../dir/c/">Gamma note</a>, <a href="../dir/c/">c</a>, <a href="../dir/c/#intro">dir/c#intro</a> and Unknown.`,
+	)
+	assert.Equal(t, site.links, map[[2]string]bool{{"a.md", "dir/c.md"}: true})
+}
+
+func TestPublishExpandsEmbeds(t *testing.T) {
+	site := newPublishTestSite(t, map[string]string{
+		// Cycles.
+		"a.md": "# A ![[b]]",
+		"b.md": "# B ![[c]]",
+		"c.md": "# C ![[a]]",
+		"x.md": "# X ![[y]]",
+		"y.md": "# Y ![[x]]",
+		// Chain deeper than MaxEmbedDepth.
+		"d.md": "# D ![[e]]",
+		"e.md": "# E ![[f]]",
+		"f.md": "# F ![[g]]",
+		"g.md": "# G ![[h]]",
+		"h.md": "# H",
+	})
+	assert.Nil(t, site.render())
+
+	test := func(path string, expected string) {
+		t.Helper()
+		assert.Equal(t, site.byPath[path].html, expected)
+	}
+
+	test("a.md", `# A <embed src="../b/"># B <embed src="../c/"># C <a href="./">a</a></embed></embed>`)
+	test("c.md", `# C <embed src="../a/"># A <embed src="../b/"># B <a href="./">c</a></embed></embed>`)
+	// The note embedding itself is not expanded again, even below the
+	// maximum depth.
+	test("x.md", `# X <embed src="../y/"># Y <a href="./">x</a></embed>`)
+	test("d.md", `# D <embed src="../e/"># E <embed src="../f/"># F <a href="../g/">g</a></embed></embed>`)
+	test("f.md", `# F <embed src="../g/"># G <embed src="../h/"># H</embed></embed>`)
+
+	// The embeds are linked from the note embedding them.
+	assert.True(t, site.links[[2]string{"c.md", "a.md"}])
+	assert.True(t, site.links[[2]string{"f.md", "g.md"}])
+}
+
+// newPublishTestSite creates a website publishing the given notes, indexed
+// by their path. The title of a note is its first line, without the leading
+// `# `.
+func newPublishTestSite(t *testing.T, notes map[string]string) *publishedSite {
+	contents := map[string]*NoteContent{}
+	for path, content := range notes {
+		title := strings.TrimPrefix(strings.SplitN(content, "\n", 2)[0], "# ")
+		contents[path] = &NoteContent{Title: opt.NewString(title)}
+	}
+	notebook, _ := newResolveTestNotebook(t, contents)
+	notebook.renderer = &publishRendererMock{}
+
+	site := &publishedSite{
+		notebook: notebook,
+		notes:    []*publishedNote{},
+		byPath:   map[string]*publishedNote{},
+		assets:   map[string]bool{},
+		links:    map[[2]string]bool{},
+	}
+	for path, content := range notes {
+		note := &publishedNote{
+			note: ContextualNote{Note: Note{Path: path, Title: contents[path].Title.String(), RawContent: content}},
+			page: publishedPage(MinimalNote{Path: path}),
+		}
+		site.notes = append(site.notes, note)
+		site.byPath[path] = note
+	}
+	return site
+}
+
+var wikiEmbedRegex = regexp.MustCompile(`(!?)\[\[([^\]]+)\]\]`)
+
+// publishRendererMock renders only the wiki-links and embeds of a note, e.g.
+// [[href]] and ![[href]], with their resolved URL or embedded HTML. The
+// unresolved links are kept as is.
+type publishRendererMock struct{}
+
+func (r *publishRendererMock) RenderHTML(content string, resolve LinkResolver, renderBlock BlockRenderer) (string, error) {
+	html := strings.Builder{}
+	last := 0
+	for _, match := range wikiEmbedRegex.FindAllStringSubmatchIndex(content, -1) {
+		html.WriteString(content[last:match[0]])
+		last = match[1]
+		href := content[match[4]:match[5]]
+		resolved, err := resolve(RenderedLink{Href: href, IsWikiLink: true, IsEmbed: match[3] > match[2]})
+		switch {
+		case err != nil:
+			return "", err
+		case resolved == nil:
+			html.WriteString(content[match[0]:match[1]])
+		case resolved.LabelOnly:
+			html.WriteString(href)
+		case resolved.HTML != "":
+			html.WriteString(`<embed src="` + resolved.URL + `">` + resolved.HTML + `</embed>`)
+		default:
+			html.WriteString(`<a href="` + resolved.URL + `">` + href + `</a>`)
+		}
+	}
+	html.WriteString(content[last:])
+	return html.String(), nil
+}
@@ -0,0 +1,83 @@
+package core
+
+// publishStyle is the stylesheet of the built-in website templates.
+const publishStyle = `<style>
+body { max-width: 42rem; margin: 2rem auto; padding: 0 1rem; font-family: sans-serif; line-height: 1.6; color: #222; }
+header { margin-bottom: 2rem; }
+header a { color: inherit; font-weight: bold; text-decoration: none; }
+a { color: #2a6bb5; }
+img { max-width: 100%; }
+.zk-embed { border-left: 3px solid #ddd; padding-left: 1rem; margin: 1rem 0; }
+.zk-tag, .tags a { color: #666; }
+.date { color: #666; font-size: 0.9rem; }
+footer { margin-top: 3rem; padding-top: 1rem; border-top: 1px solid #ddd; }
+</style>`
+
+// defaultPublishNoteTemplate renders the page of a published note.
+const defaultPublishNoteTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{note.title}} · {{site.title}}</title>
+` + publishStyle + `
+</head>
+<body>
+<header><a href="{{site.root}}">{{site.title}}</a></header>
+<main>
+<p class="date">{{date note.created "long"}}</p>
+{{{content}}}
+{{#if tags}}
+<p class="tags">{{#each tags}}<a href="{{url}}">#{{name}}</a> {{/each}}</p>
+{{/if}}
+</main>
+{{#if backlinks}}
+<footer>
+<h2>Backlinks</h2>
+<ul>
+{{#each backlinks}}
+<li><a href="{{url}}">{{title}}</a></li>
+{{/each}}
+</ul>
+</footer>
+{{/if}}
+</body>
+</html>
+`
+
+// defaultPublishListTemplate renders the home page of the website, and the
+// index pages of the tags and directories.
+const defaultPublishListTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{title}} · {{site.title}}</title>
+{{#if site.base-url}}<link rel="alternate" type="application/rss+xml" title="{{site.title}}" href="{{site.root}}feed.xml">{{/if}}
+` + publishStyle + `
+</head>
+<body>
+<header><a href="{{site.root}}">{{site.title}}</a></header>
+<main>
+<h1>{{title}}</h1>
+<ul>
+{{#each notes}}
+<li><a href="{{url}}">{{title}}</a> <span class="date">{{date created "medium"}}</span></li>
+{{/each}}
+</ul>
+{{#if tags}}
+<h2>Tags</h2>
+<p class="tags">{{#each tags}}<a href="{{url}}">{{name}}</a> ({{note-count}}) {{/each}}</p>
+{{/if}}
+{{#if dirs}}
+<h2>Directories</h2>
+<ul>
+{{#each dirs}}
+<li><a href="{{url}}">{{name}}</a> ({{note-count}})</li>
+{{/each}}
+</ul>
+{{/if}}
+</main>
+</body>
+</html>
+`
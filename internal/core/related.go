@@ -0,0 +1,51 @@
+package core
+
+import (
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// RelatedNote is a note sharing rare terms or tags with another note, which
+// is not linked to or from it yet.
+type RelatedNote struct {
+	// Path of the note, relative to the notebook root.
+	Path string `json:"path"`
+	// Title of the note.
+	Title string `json:"title"`
+	// TF-IDF similarity of the note with the target note. The higher, the
+	// more related.
+	Score float64 `json:"score"`
+	// Stemmed terms shared with the target note, from the most significant
+	// one.
+	Terms []string `json:"terms"`
+}
+
+// RelatedNotesOpts holds the options used to find the notes related to a
+// note.
+type RelatedNotesOpts struct {
+	// Maximum number of notes returned, by default 10.
+	Limit int
+}
+
+// relatedDefaultLimit is the default maximum number of related notes.
+const relatedDefaultLimit = 10
+
+// FindRelatedNotes returns the notes sharing rare terms or tags with the note
+// at the given path, which are not linked to or from it yet, from the most
+// related one.
+//
+// The notes are ranked with the TF-IDF weights of the terms indexed for the
+// full-text search, to discover missing connections between the notes.
+func (n *Notebook) FindRelatedNotes(path string, opts RelatedNotesOpts) ([]RelatedNote, error) {
+	wrap := errors.Wrapperf("%s: failed to find the related notes", path)
+
+	note, err := n.indexedNoteAt(path)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = relatedDefaultLimit
+	}
+
+	notes, err := n.index.FindRelated(note.Path, opts)
+	return notes, wrap(err)
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// IsInReview returns whether the given frontmatter metadata adds a note to
+// the review queue, with `review: true`.
+func IsInReview(metadata map[string]interface{}) bool {
+	review, ok := metadata["review"].(bool)
+	return ok && review
+}
+
+// Review is the spaced repetition schedule of a note in the review queue,
+// following the SM-2 algorithm.
+type Review struct {
+	// Date of the last review, or nil if the note was never reviewed.
+	Reviewed *time.Time `json:"reviewed,omitempty"`
+	// Date of the next review, or nil if the note was never reviewed, in
+	// which case it is due immediately.
+	Due *time.Time `json:"due,omitempty"`
+	// Number of days between the last review and the next one.
+	Interval int `json:"interval"`
+	// Factor used to increase the interval after a successful review.
+	Ease float64 `json:"ease"`
+	// Number of successful reviews in a row.
+	Repetitions int `json:"repetitions"`
+}
+
+// NoteReview is the review schedule of an indexed note.
+type NoteReview struct {
+	Review
+	// Path of the note, relative to the notebook root.
+	Path string `json:"path"`
+	// Title of the note.
+	Title string `json:"title"`
+}
+
+// ReviewFindOpts holds the criteria used to find the notes of the review
+// queue.
+type ReviewFindOpts struct {
+	// Only the notes due before this date, excluded. The notes never
+	// reviewed are always due.
+	DueBefore *time.Time
+	// Only the notes at these paths, relative to the notebook root.
+	Paths []string
+}
+
+// ReviewGrade rates how well a note was recalled during a review, on the
+// 0-5 scale of SM-2.
+type ReviewGrade int
+
+const (
+	// The note was forgotten, it will be reviewed again the next day.
+	ReviewGradeAgain ReviewGrade = 1
+	// The note was recalled with serious difficulty.
+	ReviewGradeHard ReviewGrade = 3
+	// The note was recalled after some hesitation.
+	ReviewGradeGood ReviewGrade = 4
+	// The note was recalled perfectly.
+	ReviewGradeEasy ReviewGrade = 5
+)
+
+var reviewGrades = map[string]ReviewGrade{
+	"again": ReviewGradeAgain,
+	"hard":  ReviewGradeHard,
+	"good":  ReviewGradeGood,
+	"easy":  ReviewGradeEasy,
+}
+
+// ReviewGradeFromString returns the grade matching the given name, among
+// again, hard, good and easy.
+func ReviewGradeFromString(str string) (ReviewGrade, error) {
+	grade, ok := reviewGrades[strings.ToLower(strings.TrimSpace(str))]
+	if !ok {
+		return 0, fmt.Errorf("%s: unknown review grade, expected one of: again, hard, good, easy", str)
+	}
+	return grade, nil
+}
+
+// defaultReviewEase is the ease of a note never reviewed.
+const defaultReviewEase = 2.5
+
+// minReviewEase prevents the interval of difficult notes from stagnating.
+const minReviewEase = 1.3
+
+// Next returns the schedule of the note after a review done at the given
+// date.
+func (r Review) Next(grade ReviewGrade, now time.Time) Review {
+	next := r
+	if next.Ease == 0 {
+		next.Ease = defaultReviewEase
+	}
+
+	if grade < ReviewGradeHard {
+		next.Repetitions = 0
+		next.Interval = 1
+	} else {
+		switch next.Repetitions {
+		case 0:
+			next.Interval = 1
+		case 1:
+			next.Interval = 6
+		default:
+			next.Interval = int(math.Round(float64(next.Interval) * next.Ease))
+		}
+		next.Repetitions++
+	}
+
+	q := float64(5 - grade)
+	next.Ease = math.Max(minReviewEase, next.Ease+0.1-q*(0.08+q*0.02))
+
+	reviewed := now
+	due := time.Date(now.Year(), now.Month(), now.Day()+next.Interval, 0, 0, 0, 0, now.Location())
+	next.Reviewed = &reviewed
+	next.Due = &due
+	return next
+}
+
+// FindReviews retrieves the notes of the review queue matching the given
+// criteria, ordered by due date. The notes never reviewed come last.
+func (n *Notebook) FindReviews(opts ReviewFindOpts) ([]NoteReview, error) {
+	reviews, err := n.index.FindReviews(opts)
+	return reviews, errors.Wrap(err, "failed to find the notes to review")
+}
+
+// RecordReview schedules the next review of the note at the given path,
+// according to how well it was recalled.
+func (n *Notebook) RecordReview(origin AuditOrigin, path string, grade ReviewGrade, now time.Time) (*NoteReview, error) {
+	wrap := errors.Wrapperf("%s: failed to record the review", path)
+
+	note, err := n.indexedNoteAt(path)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if !IsInReview(note.Metadata) {
+		return nil, wrap(errors.New("the note is not in the review queue, add `review: true` to its frontmatter"))
+	}
+
+	reviews, err := n.index.FindReviews(ReviewFindOpts{Paths: []string{note.Path}})
+	if err != nil {
+		return nil, wrap(err)
+	}
+	review := NoteReview{Path: note.Path, Title: note.Title}
+	if len(reviews) > 0 {
+		review = reviews[0]
+	}
+
+	review.Review = review.Next(grade, now)
+	err = n.index.SetReview(note.Path, review.Review)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	n.audit(origin, AuditOperationReview, fmt.Sprintf("next review in %d %s", review.Interval, strutil.Pluralize("day", review.Interval)), note.Path)
+	return &review, nil
+}
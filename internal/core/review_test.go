@@ -0,0 +1,56 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestIsInReview(t *testing.T) {
+	assert.False(t, IsInReview(nil))
+	assert.False(t, IsInReview(map[string]interface{}{"review": false}))
+	assert.False(t, IsInReview(map[string]interface{}{"review": "true"}))
+	assert.True(t, IsInReview(map[string]interface{}{"review": true}))
+}
+
+func TestReviewGradeFromString(t *testing.T) {
+	grade, err := ReviewGradeFromString(" Good ")
+	assert.Nil(t, err)
+	assert.Equal(t, grade, ReviewGradeGood)
+
+	_, err = ReviewGradeFromString("perfect")
+	assert.Err(t, err, "perfect: unknown review grade, expected one of: again, hard, good, easy")
+}
+
+func TestReviewNext(t *testing.T) {
+	now := time.Date(2021, 10, 1, 9, 30, 0, 0, time.UTC)
+	day := func(d int) *time.Time {
+		date := time.Date(2021, 10, d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+
+	// A new note is due the next day, then after 6 days.
+	review := Review{}.Next(ReviewGradeGood, now)
+	assert.Equal(t, review, Review{Reviewed: &now, Due: day(2), Interval: 1, Ease: 2.5, Repetitions: 1})
+	review = review.Next(ReviewGradeGood, now)
+	assert.Equal(t, review.Interval, 6)
+	assert.Equal(t, review.Due, day(7))
+
+	// Then the interval grows with the ease.
+	review = review.Next(ReviewGradeEasy, now)
+	assert.Equal(t, review.Interval, 15)
+	assert.Equal(t, review.Ease, 2.6)
+	assert.Equal(t, review.Repetitions, 3)
+
+	// A forgotten note starts over, and is harder.
+	review = review.Next(ReviewGradeAgain, now)
+	assert.Equal(t, review.Interval, 1)
+	assert.Equal(t, review.Repetitions, 0)
+	assert.Equal(t, review.Due, day(2))
+	assert.True(t, review.Ease < 2.6)
+
+	// The ease has a lower bound.
+	review = Review{Ease: 1.3}.Next(ReviewGradeAgain, now)
+	assert.Equal(t, review.Ease, 1.3)
+}
@@ -0,0 +1,323 @@
+package core
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// NotebookStats holds metrics about the health of a notebook, e.g. to monitor
+// it.
+type NotebookStats struct {
+	// Number of notes, excluding the trash.
+	Notes int `json:"notes"`
+	// Number of notes in the trash.
+	TrashedNotes int `json:"trashedNotes"`
+	// Number of internal links targeting neither a note nor a file.
+	DeadLinks int `json:"deadLinks"`
+	// Start date of the last indexing, zero if the notebook was never
+	// indexed.
+	LastIndexed time.Time `json:"lastIndexed"`
+	// Duration of the last indexing.
+	LastIndexingDuration time.Duration `json:"lastIndexingDuration"`
+	// Total size of the notes in bytes, excluding the trash.
+	NotesSize int64 `json:"notesSize"`
+	// Number of assets, e.g. images or attachments.
+	Assets int `json:"assets"`
+	// Total size of the assets in bytes.
+	AssetsSize int64 `json:"assetsSize"`
+	// Largest notes of the notebook, from the largest one.
+	LargestNotes []NoteSize `json:"largestNotes"`
+	// Average maturity of the notes from 0 to 100, excluding the trash. See
+	// NoteMaturity.
+	Maturity float64 `json:"maturity"`
+	// Least mature notes created before the last StatsGrowthPeriod, from the
+	// least mature one.
+	LeastMatureNotes []NoteMaturityScore `json:"leastMatureNotes"`
+	// Size of the top-level directories, from the largest one.
+	Dirs []AreaStats `json:"dirs"`
+	// Size of the config groups, from the largest one.
+	Groups []AreaStats `json:"groups"`
+}
+
+// NoteSize is the size of a single note.
+type NoteSize struct {
+	// Path relative to the root of the notebook.
+	Path string `json:"path"`
+	// Size of the note content, in bytes.
+	Size int64 `json:"size"`
+}
+
+// NoteMaturityScore is the maturity of a single note.
+type NoteMaturityScore struct {
+	// Path relative to the root of the notebook.
+	Path string `json:"path"`
+	// Maturity of the note, from 0 to 100.
+	Maturity float64 `json:"maturity"`
+}
+
+// AreaStats holds the size and growth of a section of the notebook, such as
+// a directory or a config group.
+type AreaStats struct {
+	// Name of the directory or group. The notes at the root of the notebook
+	// are in the "." directory.
+	Name string `json:"name"`
+	// Number of notes in the area.
+	Notes int `json:"notes"`
+	// Total size of the notes in bytes.
+	Size int64 `json:"size"`
+	// Number of notes created during the last StatsGrowthPeriod.
+	AddedNotes int `json:"addedNotes"`
+	// Total size of the notes created during the last StatsGrowthPeriod.
+	AddedSize int64 `json:"addedSize"`
+	// Average maturity of the notes, from 0 to 100.
+	Maturity float64 `json:"maturity"`
+}
+
+// StatsGrowthPeriod is the period used to measure the growth of the
+// notebook areas.
+const StatsGrowthPeriod = 30 * 24 * time.Hour
+
+// statsLargestNotes is the number of notes listed in the largest notes
+// report.
+const statsLargestNotes = 10
+
+// statsLeastMatureNotes is the number of notes listed in the least mature
+// notes report.
+const statsLeastMatureNotes = 10
+
+// IndexAge returns the time elapsed since the last indexing, at the given
+// date.
+func (s NotebookStats) IndexAge(now time.Time) time.Duration {
+	if s.LastIndexed.IsZero() {
+		return 0
+	}
+	return now.Sub(s.LastIndexed)
+}
+
+// Stats computes the metrics of the notebook from its index.
+func (n *Notebook) Stats() (NotebookStats, error) {
+	wrap := errors.Wrapper("failed to compute the notebook stats")
+
+	stats := NotebookStats{}
+
+	notes, err := n.FindNotes(NoteFindOpts{Trash: TrashFilterInclude})
+	if err != nil {
+		return stats, wrap(err)
+	}
+	sizes := newAreaSizes(time.Now().Add(-StatsGrowthPeriod))
+	for _, note := range notes {
+		if IsTrashed(note.Metadata) {
+			stats.TrashedNotes++
+			continue
+		}
+		stats.Notes++
+
+		group, err := n.Config.GroupNameForPath(note.Path)
+		if err != nil {
+			return stats, wrap(err)
+		}
+		sizes.add(note.Note, note.Maturity, group)
+	}
+	stats.NotesSize = sizes.total
+	stats.LargestNotes = sizes.largestNotes(statsLargestNotes)
+	stats.Maturity = averageMaturity(sizes.maturity, stats.Notes)
+	stats.LeastMatureNotes = sizes.leastMatureNotes(statsLeastMatureNotes)
+	stats.Dirs = sizes.sorted(sizes.dirs)
+	stats.Groups = sizes.sorted(sizes.groups)
+
+	stats.Assets, stats.AssetsSize, err = n.assetsSize()
+	if err != nil {
+		return stats, wrap(err)
+	}
+
+	deadLinks, err := n.findDeadLinks(n.index)
+	if err != nil {
+		return stats, wrap(err)
+	}
+	stats.DeadLinks = len(deadLinks)
+
+	stats.LastIndexed, stats.LastIndexingDuration, err = n.index.LastIndexing()
+	if err != nil {
+		return stats, wrap(err)
+	}
+
+	return stats, nil
+}
+
+// areaSizes aggregates the size and maturity of the notes per directory and
+// group.
+type areaSizes struct {
+	// Notes created after this date are counted in the growth of the areas.
+	growthStart time.Time
+	total       int64
+	// Sum of the maturity of the notes.
+	maturity float64
+	notes    []NoteSize
+	// Maturity of the notes created before growthStart.
+	oldNotes []NoteMaturityScore
+	dirs     map[string]*AreaStats
+	groups   map[string]*AreaStats
+}
+
+func newAreaSizes(growthStart time.Time) *areaSizes {
+	return &areaSizes{
+		growthStart: growthStart,
+		notes:       []NoteSize{},
+		oldNotes:    []NoteMaturityScore{},
+		dirs:        map[string]*AreaStats{},
+		groups:      map[string]*AreaStats{},
+	}
+}
+
+// add counts the given note in its top-level directory, and in its group
+// unless it is empty.
+func (a *areaSizes) add(note Note, maturity float64, group string) {
+	size := int64(len(note.RawContent))
+	a.total += size
+	a.maturity += maturity
+	a.notes = append(a.notes, NoteSize{Path: note.Path, Size: size})
+	if !note.Created.After(a.growthStart) {
+		a.oldNotes = append(a.oldNotes, NoteMaturityScore{Path: note.Path, Maturity: maturity})
+	}
+
+	dir := strings.SplitN(filepath.ToSlash(note.Path), "/", 2)[0]
+	if dir == note.Path {
+		dir = "."
+	}
+	areas := []*AreaStats{a.area(a.dirs, dir)}
+	if group != "" {
+		areas = append(areas, a.area(a.groups, group))
+	}
+
+	for _, area := range areas {
+		area.Notes++
+		area.Size += size
+		// Summed until the areas are sorted.
+		area.Maturity += maturity
+		if note.Created.After(a.growthStart) {
+			area.AddedNotes++
+			area.AddedSize += size
+		}
+	}
+}
+
+func (a *areaSizes) area(areas map[string]*AreaStats, name string) *AreaStats {
+	area, ok := areas[name]
+	if !ok {
+		area = &AreaStats{Name: name}
+		areas[name] = area
+	}
+	return area
+}
+
+// largestNotes returns the given number of largest notes, from the largest
+// one.
+func (a *areaSizes) largestNotes(count int) []NoteSize {
+	notes := append([]NoteSize{}, a.notes...)
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].Size != notes[j].Size {
+			return notes[i].Size > notes[j].Size
+		}
+		return notes[i].Path < notes[j].Path
+	})
+	if len(notes) > count {
+		notes = notes[:count]
+	}
+	return notes
+}
+
+// leastMatureNotes returns the given number of least mature notes created
+// before the growth period, from the least mature one.
+func (a *areaSizes) leastMatureNotes(count int) []NoteMaturityScore {
+	notes := append([]NoteMaturityScore{}, a.oldNotes...)
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].Maturity != notes[j].Maturity {
+			return notes[i].Maturity < notes[j].Maturity
+		}
+		return notes[i].Path < notes[j].Path
+	})
+	if len(notes) > count {
+		notes = notes[:count]
+	}
+	return notes
+}
+
+// sorted returns the given areas from the largest one.
+func (a *areaSizes) sorted(areas map[string]*AreaStats) []AreaStats {
+	res := []AreaStats{}
+	for _, area := range areas {
+		stats := *area
+		stats.Maturity = averageMaturity(area.Maturity, area.Notes)
+		res = append(res, stats)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Size != res[j].Size {
+			return res[i].Size > res[j].Size
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// averageMaturity returns the average of a sum of maturity scores, rounded
+// like NoteMaturity.
+func averageMaturity(sum float64, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return math.Round(sum/float64(count)*10) / 10
+}
+
+// assetsSize returns the number of assets and their total size in bytes.
+func (n *Notebook) assetsSize() (int, int64, error) {
+	assets, err := n.FindAssets()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var size int64
+	for _, asset := range assets {
+		info, err := os.Stat(filepath.Join(n.Path, asset.Path))
+		if err != nil {
+			return 0, 0, err
+		}
+		size += info.Size()
+	}
+	return len(assets), size, nil
+}
+
+// findDeadLinks returns the internal links of the index which target neither
+// a note nor a file, e.g. an attachment.
+//
+// Unlike `zk lint`, the notes are not parsed again, so the links are not
+// matched approximately.
+func (n *Notebook) findDeadLinks(index NoteIndex) ([]UnresolvedLink, error) {
+	links, err := index.FindUnresolvedLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	dead := []UnresolvedLink{}
+	for _, link := range links {
+		if strings.SplitN(link.Href, "#", 2)[0] == "" {
+			continue
+		}
+		if _, _, federated := n.FederatedHref(link.Href); federated {
+			continue
+		}
+		exists, err := n.fs.FileExists(filepath.Join(n.Path, assetHrefPath(link.Href)))
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			dead = append(dead, link)
+		}
+	}
+	return dead, nil
+}
@@ -0,0 +1,220 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// ActivityUnit is the granularity of the periods of the ActivityStats.
+type ActivityUnit string
+
+const (
+	ActivityDay   ActivityUnit = "day"
+	ActivityWeek  ActivityUnit = "week"
+	ActivityMonth ActivityUnit = "month"
+)
+
+// ActivityUnitFromString returns the ActivityUnit with the given name.
+func ActivityUnitFromString(name string) (ActivityUnit, error) {
+	switch unit := ActivityUnit(name); unit {
+	case ActivityDay, ActivityWeek, ActivityMonth:
+		return unit, nil
+	default:
+		return "", fmt.Errorf("%s: unknown activity unit, try day, week or month", name)
+	}
+}
+
+// ActivityStatsOpts holds the options used to compute the writing activity
+// of a notebook.
+type ActivityStatsOpts struct {
+	// Start of the period, included.
+	Start time.Time
+	// End of the period, excluded.
+	End time.Time
+	// Granularity of the reported periods.
+	Unit ActivityUnit
+	// Maximum number of tags whose trend is reported.
+	TagCount int
+}
+
+// ActivityStats holds the aggregate statistics of the notes created during a
+// period.
+type ActivityStats struct {
+	Start time.Time    `json:"start"`
+	End   time.Time    `json:"end"`
+	Unit  ActivityUnit `json:"unit"`
+	// Number of notes created during the period.
+	Notes int `json:"notes"`
+	// Number of words in the notes created during the period.
+	Words int `json:"words"`
+	// Number of links from the notes created during the period to other
+	// notes.
+	Links int `json:"links"`
+	// Average number of words of a note.
+	AverageWords float64 `json:"averageWords"`
+	// Average number of links to other notes from a note.
+	LinkDensity float64 `json:"linkDensity"`
+	// Activity of each unit of the period, from the oldest one.
+	Periods []ActivityPeriod `json:"periods"`
+	// Activity of each day of the period, from the oldest one.
+	Days []ActivityPeriod `json:"days"`
+	// Most frequent tags of the notes created during the period, from the
+	// most frequent one.
+	Tags []TagTrend `json:"tags"`
+}
+
+// ActivityPeriod is the activity of a day, week or month.
+type ActivityPeriod struct {
+	Start time.Time `json:"start"`
+	Notes int       `json:"notes"`
+	Words int       `json:"words"`
+	Links int       `json:"links"`
+}
+
+// TagTrend is the frequency of a tag over the periods of the ActivityStats.
+type TagTrend struct {
+	Name string `json:"name"`
+	// Number of notes created with this tag during the whole period.
+	Notes int `json:"notes"`
+	// Number of notes created with this tag during each unit of the period.
+	Periods []int `json:"periods"`
+}
+
+// ActivityStats computes the writing activity of the notebook from the notes
+// created during the given period.
+func (n *Notebook) ActivityStats(opts ActivityStatsOpts) (ActivityStats, error) {
+	wrap := errors.Wrapper("failed to compute the activity stats")
+
+	notes, err := n.FindNotes(NoteFindOpts{
+		CreatedStart: &opts.Start,
+		CreatedEnd:   &opts.End,
+	})
+	if err != nil {
+		return ActivityStats{}, wrap(err)
+	}
+	links, err := n.index.FindNoteLinks()
+	if err != nil {
+		return ActivityStats{}, wrap(err)
+	}
+
+	linkCounts := map[string]int{}
+	for _, link := range links {
+		if link.SourcePath != link.TargetPath {
+			linkCounts[link.SourcePath]++
+		}
+	}
+	activityNotes := []Note{}
+	for _, note := range notes {
+		activityNotes = append(activityNotes, note.Note)
+	}
+	return newActivityStats(opts, activityNotes, linkCounts), nil
+}
+
+// newActivityStats aggregates the activity of the given notes, with the
+// number of links to other notes indexed by the path of their source.
+func newActivityStats(opts ActivityStatsOpts, notes []Note, linkCounts map[string]int) ActivityStats {
+	stats := ActivityStats{
+		Start:   opts.Start,
+		End:     opts.End,
+		Unit:    opts.Unit,
+		Periods: activityPeriods(opts.Start, opts.End, opts.Unit),
+		Days:    activityPeriods(opts.Start, opts.End, ActivityDay),
+		Tags:    []TagTrend{},
+	}
+
+	// index returns the index of the period containing the given date.
+	index := func(periods []ActivityPeriod, date time.Time) int {
+		return sort.Search(len(periods), func(i int) bool {
+			return periods[i].Start.After(date)
+		}) - 1
+	}
+
+	tags := map[string]*TagTrend{}
+	for _, note := range notes {
+		if note.Created.Before(opts.Start) || !note.Created.Before(opts.End) {
+			continue
+		}
+		links := linkCounts[note.Path]
+		stats.Notes++
+		stats.Words += note.WordCount
+		stats.Links += links
+
+		for _, periods := range [][]ActivityPeriod{stats.Periods, stats.Days} {
+			if i := index(periods, note.Created); i >= 0 {
+				periods[i].Notes++
+				periods[i].Words += note.WordCount
+				periods[i].Links += links
+			}
+		}
+
+		period := index(stats.Periods, note.Created)
+		for _, name := range note.Tags {
+			tag, ok := tags[name]
+			if !ok {
+				tag = &TagTrend{Name: name, Periods: make([]int, len(stats.Periods))}
+				tags[name] = tag
+			}
+			tag.Notes++
+			if period >= 0 {
+				tag.Periods[period]++
+			}
+		}
+	}
+
+	if stats.Notes > 0 {
+		stats.AverageWords = math.Round(float64(stats.Words)/float64(stats.Notes)*10) / 10
+		stats.LinkDensity = math.Round(float64(stats.Links)/float64(stats.Notes)*100) / 100
+	}
+
+	for _, tag := range tags {
+		stats.Tags = append(stats.Tags, *tag)
+	}
+	sort.Slice(stats.Tags, func(i, j int) bool {
+		a, b := stats.Tags[i], stats.Tags[j]
+		return a.Notes > b.Notes || (a.Notes == b.Notes && a.Name < b.Name)
+	})
+	if opts.TagCount > 0 && len(stats.Tags) > opts.TagCount {
+		stats.Tags = stats.Tags[:opts.TagCount]
+	}
+
+	return stats
+}
+
+// activityPeriods returns the empty periods of the given unit covering the
+// range from start to end.
+func activityPeriods(start time.Time, end time.Time, unit ActivityUnit) []ActivityPeriod {
+	periods := []ActivityPeriod{}
+	for date := ActivityPeriodStart(start, unit); date.Before(end); date = nextActivityPeriod(date, unit) {
+		periods = append(periods, ActivityPeriod{Start: date})
+	}
+	return periods
+}
+
+// ActivityPeriodStart returns the start of the day, week or month containing
+// the given date. The weeks start on Monday.
+func ActivityPeriodStart(date time.Time, unit ActivityUnit) time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	switch unit {
+	case ActivityWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case ActivityMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+func nextActivityPeriod(date time.Time, unit ActivityUnit) time.Time {
+	switch unit {
+	case ActivityWeek:
+		return date.AddDate(0, 0, 7)
+	case ActivityMonth:
+		return date.AddDate(0, 1, 0)
+	default:
+		return date.AddDate(0, 0, 1)
+	}
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func activityDay(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestActivityPeriodStart(t *testing.T) {
+	test := func(unit ActivityUnit, expected time.Time) {
+		t.Helper()
+		// A Thursday.
+		actual := ActivityPeriodStart(time.Date(2021, 10, 14, 15, 30, 0, 0, time.UTC), unit)
+		assert.Equal(t, actual, expected)
+	}
+
+	test(ActivityDay, activityDay(2021, 10, 14))
+	test(ActivityWeek, activityDay(2021, 10, 11))
+	test(ActivityMonth, activityDay(2021, 10, 1))
+
+	// The weeks start on Monday.
+	assert.Equal(t, ActivityPeriodStart(activityDay(2021, 10, 17), ActivityWeek), activityDay(2021, 10, 11))
+	assert.Equal(t, ActivityPeriodStart(activityDay(2021, 10, 18), ActivityWeek), activityDay(2021, 10, 18))
+}
+
+func TestActivityUnitFromString(t *testing.T) {
+	unit, err := ActivityUnitFromString("week")
+	assert.Nil(t, err)
+	assert.Equal(t, unit, ActivityWeek)
+
+	_, err = ActivityUnitFromString("year")
+	assert.Err(t, err, "year: unknown activity unit, try day, week or month")
+}
+
+func TestNewActivityStats(t *testing.T) {
+	opts := ActivityStatsOpts{
+		Start:    activityDay(2021, 9, 15),
+		End:      activityDay(2021, 11, 2),
+		Unit:     ActivityMonth,
+		TagCount: 2,
+	}
+	notes := []Note{
+		{Path: "a.md", WordCount: 100, Tags: []string{"food"}, Created: time.Date(2021, 9, 20, 10, 0, 0, 0, time.UTC)},
+		{Path: "b.md", WordCount: 50, Tags: []string{"food", "italy"}, Created: time.Date(2021, 10, 3, 10, 0, 0, 0, time.UTC)},
+		{Path: "c.md", WordCount: 30, Tags: []string{"travel", "italy"}, Created: time.Date(2021, 10, 3, 18, 0, 0, 0, time.UTC)},
+		{Path: "d.md", WordCount: 20, Tags: []string{"food"}, Created: time.Date(2021, 11, 1, 23, 0, 0, 0, time.UTC)},
+		// Outside the period.
+		{Path: "e.md", WordCount: 1000, Tags: []string{"old"}, Created: activityDay(2021, 9, 14)},
+		{Path: "f.md", WordCount: 1000, Tags: []string{"new"}, Created: activityDay(2021, 11, 2)},
+	}
+	links := map[string]int{"a.md": 3, "c.md": 1, "e.md": 10}
+
+	stats := newActivityStats(opts, notes, links)
+
+	assert.Equal(t, stats.Notes, 4)
+	assert.Equal(t, stats.Words, 200)
+	assert.Equal(t, stats.Links, 4)
+	assert.Equal(t, stats.AverageWords, 50.0)
+	assert.Equal(t, stats.LinkDensity, 1.0)
+	assert.Equal(t, stats.Periods, []ActivityPeriod{
+		{Start: activityDay(2021, 9, 1), Notes: 1, Words: 100, Links: 3},
+		{Start: activityDay(2021, 10, 1), Notes: 2, Words: 80, Links: 1},
+		{Start: activityDay(2021, 11, 1), Notes: 1, Words: 20, Links: 0},
+	})
+	assert.Equal(t, len(stats.Days), 48)
+	assert.Equal(t, stats.Days[0], ActivityPeriod{Start: activityDay(2021, 9, 15)})
+	assert.Equal(t, stats.Days[18], ActivityPeriod{Start: activityDay(2021, 10, 3), Notes: 2, Words: 80, Links: 1})
+	assert.Equal(t, stats.Tags, []TagTrend{
+		{Name: "food", Notes: 3, Periods: []int{1, 1, 1}},
+		{Name: "italy", Notes: 2, Periods: []int{0, 2, 0}},
+	})
+}
+
+func TestNewActivityStatsEmpty(t *testing.T) {
+	stats := newActivityStats(ActivityStatsOpts{
+		Start: activityDay(2021, 10, 4),
+		End:   activityDay(2021, 10, 18),
+		Unit:  ActivityWeek,
+	}, []Note{}, map[string]int{})
+
+	assert.Equal(t, stats.Notes, 0)
+	assert.Equal(t, stats.AverageWords, 0.0)
+	assert.Equal(t, stats.Periods, []ActivityPeriod{
+		{Start: activityDay(2021, 10, 4)},
+		{Start: activityDay(2021, 10, 11)},
+	})
+	assert.Equal(t, stats.Tags, []TagTrend{})
+}
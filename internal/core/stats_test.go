@@ -0,0 +1,48 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestAreaSizes(t *testing.T) {
+	growthStart := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	old := growthStart.Add(-time.Hour)
+	recent := growthStart.Add(time.Hour)
+
+	sizes := newAreaSizes(growthStart)
+	sizes.add(Note{Path: "index.md", RawContent: "12345", Created: old}, 60, "")
+	sizes.add(Note{Path: "journal/2021-10-02.md", RawContent: "123", Created: recent}, 10, "journal")
+	sizes.add(Note{Path: "journal/2021-09-30.md", RawContent: "1234567", Created: old}, 35.5, "journal")
+	sizes.add(Note{Path: "projects/zk/todo.md", RawContent: "12", Created: recent}, 5, "")
+
+	assert.Equal(t, sizes.total, int64(17))
+	assert.Equal(t, sizes.largestNotes(2), []NoteSize{
+		{Path: "journal/2021-09-30.md", Size: 7},
+		{Path: "index.md", Size: 5},
+	})
+	assert.Equal(t, averageMaturity(sizes.maturity, 4), 27.6)
+	// Only the notes older than the growth period are reported.
+	assert.Equal(t, sizes.leastMatureNotes(10), []NoteMaturityScore{
+		{Path: "journal/2021-09-30.md", Maturity: 35.5},
+		{Path: "index.md", Maturity: 60},
+	})
+	assert.Equal(t, sizes.sorted(sizes.dirs), []AreaStats{
+		{Name: "journal", Notes: 2, Size: 10, AddedNotes: 1, AddedSize: 3, Maturity: 22.8},
+		{Name: ".", Notes: 1, Size: 5, Maturity: 60},
+		{Name: "projects", Notes: 1, Size: 2, AddedNotes: 1, AddedSize: 2, Maturity: 5},
+	})
+	assert.Equal(t, sizes.sorted(sizes.groups), []AreaStats{
+		{Name: "journal", Notes: 2, Size: 10, AddedNotes: 1, AddedSize: 3, Maturity: 22.8},
+	})
+}
+
+func TestAreaSizesEmpty(t *testing.T) {
+	sizes := newAreaSizes(time.Now())
+	assert.Equal(t, sizes.largestNotes(10), []NoteSize{})
+	assert.Equal(t, sizes.leastMatureNotes(10), []NoteMaturityScore{})
+	assert.Equal(t, averageMaturity(sizes.maturity, 0), 0.0)
+	assert.Equal(t, sizes.sorted(sizes.dirs), []AreaStats{})
+}
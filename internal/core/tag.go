@@ -0,0 +1,84 @@
+package core
+
+import (
+	"strings"
+)
+
+// TagSeparator delimits the segments of a hierarchical tag, e.g.
+// project/alpha is a child of the project tag.
+const TagSeparator = "/"
+
+// TagAncestors returns the parents of a hierarchical tag, from the closest
+// one. For example, the ancestors of a/b/c are a/b and a.
+func TagAncestors(tag string) []string {
+	ancestors := []string{}
+	for {
+		i := strings.LastIndex(tag, TagSeparator)
+		if i <= 0 {
+			return ancestors
+		}
+		tag = tag[:i]
+		ancestors = append(ancestors, tag)
+	}
+}
+
+// TagTree is a node in the hierarchy of tags.
+type TagTree struct {
+	// Last segment of the tag, e.g. alpha for project/alpha.
+	Name string `json:"name"`
+	// Full name of the tag, e.g. project/alpha.
+	Path string `json:"path"`
+	// Number of notes tagged with this exact tag, excluding its descendants.
+	// A parent tag which is only used through its children has no notes.
+	NoteCount int `json:"noteCount"`
+	// Children of the tag.
+	Children []TagTree `json:"children"`
+}
+
+// NewTagTrees builds the hierarchy of the given tags, keeping their order.
+// The missing parent tags are added, without notes.
+func NewTagTrees(tags []Collection) []TagTree {
+	type node struct {
+		tree     TagTree
+		children []string
+	}
+	nodes := map[string]*node{}
+	roots := []string{}
+
+	var add func(path string) *node
+	add = func(path string) *node {
+		if n, ok := nodes[path]; ok {
+			return n
+		}
+		n := &node{tree: TagTree{
+			Name: path[strings.LastIndex(path, TagSeparator)+1:],
+			Path: path,
+		}}
+		nodes[path] = n
+
+		if ancestors := TagAncestors(path); len(ancestors) > 0 {
+			parent := add(ancestors[0])
+			parent.children = append(parent.children, path)
+		} else {
+			roots = append(roots, path)
+		}
+		return n
+	}
+
+	for _, tag := range tags {
+		add(tag.Name).tree.NoteCount += tag.NoteCount
+	}
+
+	var build func(paths []string) []TagTree
+	build = func(paths []string) []TagTree {
+		trees := []TagTree{}
+		for _, path := range paths {
+			n := nodes[path]
+			tree := n.tree
+			tree.Children = build(n.children)
+			trees = append(trees, tree)
+		}
+		return trees
+	}
+	return build(roots)
+}
@@ -0,0 +1,240 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// CanonicalTag returns the name of the given tag after resolving the tag
+// aliases of the config. An alias applies to the descendants of the tag as
+// well, e.g. with golang -> go, golang/generics becomes go/generics.
+func (c Config) CanonicalTag(tag string) string {
+	// Bounded to prevent an infinite loop with cyclic aliases.
+	for i := 0; i <= len(c.TagAliases); i++ {
+		alias, canonical, ok := c.tagAliasOf(tag)
+		if !ok {
+			break
+		}
+		tag = canonical + tag[len(alias):]
+	}
+	return tag
+}
+
+// tagAliasOf returns the alias matching the given tag or its closest
+// ancestor, with its canonical name.
+func (c Config) tagAliasOf(tag string) (alias string, canonical string, ok bool) {
+	for _, candidate := range append([]string{tag}, TagAncestors(tag)...) {
+		if canonical, ok := c.TagAliases[candidate]; ok && canonical != candidate {
+			return candidate, canonical, true
+		}
+	}
+	return "", "", false
+}
+
+// CanonicalTags resolves the aliases of the given tags, merging the duplicates.
+func (c Config) CanonicalTags(tags []string) []string {
+	if len(c.TagAliases) == 0 {
+		return tags
+	}
+	res := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, c.CanonicalTag(tag))
+	}
+	return strutil.RemoveDuplicates(res)
+}
+
+// NormalizeTagsOpts holds the options used to rewrite the aliased tags of the
+// notes.
+type NormalizeTagsOpts struct {
+	// Paths of the notes to normalize, relative to the notebook root.
+	Paths []string
+	// Lists the tags to rewrite, leaving the notes untouched.
+	DryRun bool
+	// Command normalizing the tags.
+	Origin AuditOrigin
+}
+
+// NormalizeTags rewrites the aliased tags found in the given notes with their
+// canonical name, in the YAML frontmatter and in the content.
+//
+// Only the notes with aliased tags are returned.
+func (n *Notebook) NormalizeTags(opts NormalizeTagsOpts) ([]NoteReplacement, error) {
+	wrap := errors.Wrapper("failed to normalize the tags")
+
+	replacements := []NoteReplacement{}
+	if len(n.Config.TagAliases) == 0 {
+		return replacements, nil
+	}
+
+	contents := map[string]string{}
+	for _, path := range opts.Paths {
+		content, err := n.fs.Read(filepath.Join(n.Path, path))
+		if err != nil {
+			return nil, wrap(err)
+		}
+		newContent, replacement := normalizeTagsInNote(string(content), n.Config)
+		if len(replacement.Changes) == 0 {
+			continue
+		}
+		replacement.Path = path
+		replacements = append(replacements, replacement)
+		contents[path] = newContent
+	}
+
+	if opts.DryRun || len(contents) == 0 {
+		return replacements, nil
+	}
+
+	paths, err := n.writeReplacedNotes(contents)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	n.audit(opts.Origin, AuditOperationRenameTag,
+		fmt.Sprintf("normalized the tag aliases in %d %s", len(paths), strutil.Pluralize("note", len(paths))),
+		paths...,
+	)
+
+	return replacements, nil
+}
+
+var (
+	frontmatterTagKeyRegex  = regexp.MustCompile(`^(?:tags?|keywords?)[ \t]*:`)
+	frontmatterTagItemRegex = regexp.MustCompile(`^[ \t]*-[ \t]`)
+	frontmatterTagRegex     = regexp.MustCompile(`[^\s,\[\]"']+`)
+	codeRegexes             = []*regexp.Regexp{
+		regexp.MustCompile("(?ms)^[ \t]*```.*?^[ \t]*```"),
+		regexp.MustCompile("(?ms)^[ \t]*~~~.*?^[ \t]*~~~"),
+		regexp.MustCompile("`[^`\n]+`"),
+	}
+	hashtagRegex   = regexp.MustCompile(`#((?:[\p{L}\p{N}/@'~\-_$%&+=]|\\.)+)`)
+	colonTagsRegex = regexp.MustCompile(`:(?:[\p{L}\p{N}/@'~\-_$%&+=#]+:)+`)
+)
+
+// normalizeTagsInNote rewrites the aliased tags of the given note content.
+func normalizeTagsInNote(content string, config Config) (string, NoteReplacement) {
+	edits := []textEdit{}
+	rewrite := func(start, end int) {
+		tag := content[start:end]
+		if canonical := config.CanonicalTag(tag); canonical != tag {
+			edits = append(edits, textEdit{start, end, canonical})
+		}
+	}
+
+	// YAML frontmatter, in the values of the tags and keywords keys.
+	body := 0
+	if loc := frontmatterRegex.FindStringIndex(content); loc != nil {
+		body = loc[1]
+		inTags := false
+		offset := 0
+		for _, line := range strings.SplitAfter(content[:body], "\n") {
+			values := -1
+			if loc := frontmatterTagKeyRegex.FindStringIndex(line); loc != nil {
+				inTags = true
+				values = loc[1]
+			} else if loc := frontmatterTagItemRegex.FindStringIndex(line); inTags && loc != nil {
+				values = loc[1]
+			} else if len(strings.TrimSpace(line)) > 0 && !unicode.IsSpace(rune(line[0])) {
+				inTags = false
+			}
+			if values >= 0 {
+				for _, loc := range frontmatterTagRegex.FindAllStringIndex(line[values:], -1) {
+					start, end := offset+values+loc[0], offset+values+loc[1]
+					// Hashtags embedded in the frontmatter.
+					if content[start] == '#' {
+						start++
+					}
+					if start < end {
+						rewrite(start, end)
+					}
+				}
+			}
+			offset += len(line)
+		}
+	}
+
+	for _, tag := range inlineTagSpans(content, body, config.Format.Markdown) {
+		rewrite(tag.start, tag.end)
+	}
+
+	newContent, changes := applyTextEdits(content, edits)
+	return newContent, NoteReplacement{Changes: changes}
+}
+
+// textSpan is a range of bytes in the content of a note.
+type textSpan struct{ start, end int }
+
+// inlineTagSpans returns the sorted ranges of the tag names found in the
+// content after the offset body, except in code and link destinations.
+func inlineTagSpans(content string, body int, config MarkdownConfig) []textSpan {
+	protected := []textSpan{}
+	for _, regex := range codeRegexes {
+		for _, loc := range regex.FindAllStringIndex(content[body:], -1) {
+			protected = append(protected, textSpan{body + loc[0], body + loc[1]})
+		}
+	}
+	for _, regex := range []*regexp.Regexp{markdownLinkDestinationRegex, wikiLinkDestinationRegex} {
+		for _, match := range regex.FindAllStringSubmatchIndex(content[body:], -1) {
+			protected = append(protected, textSpan{body + match[2], body + match[3]})
+		}
+	}
+	isProtected := func(offset int) bool {
+		for _, s := range protected {
+			if offset >= s.start && offset < s.end {
+				return true
+			}
+		}
+		return false
+	}
+	// A tag must start a word, e.g. not an anchor in note.md#section.
+	startsWord := func(offset int) bool {
+		if offset == 0 {
+			return true
+		}
+		r, _ := utf8.DecodeLastRuneInString(content[:offset])
+		return unicode.IsSpace(r) || strings.ContainsRune("([{", r)
+	}
+
+	found := []textSpan{}
+	if config.Hashtags {
+		for _, match := range hashtagRegex.FindAllStringSubmatchIndex(content[body:], -1) {
+			start, end := body+match[2], body+match[3]
+			if startsWord(start-1) && !isProtected(start) {
+				found = append(found, textSpan{start, end})
+			}
+		}
+	}
+	if config.ColonTags {
+		for _, loc := range colonTagsRegex.FindAllStringIndex(content[body:], -1) {
+			start, end := body+loc[0], body+loc[1]
+			if !startsWord(start) || isProtected(start) {
+				continue
+			}
+			for offset := start + 1; offset < end; {
+				next := offset + strings.Index(content[offset:end], ":")
+				found = append(found, textSpan{offset, next})
+				offset = next + 1
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].start < found[j].start
+	})
+
+	tags := []textSpan{}
+	last := body
+	for _, tag := range found {
+		if tag.start >= last {
+			tags = append(tags, tag)
+			last = tag.end
+		}
+	}
+	return tags
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestCanonicalTag(t *testing.T) {
+	config := NewDefaultConfig()
+	config.TagAliases = map[string]string{
+		"golang":       "go",
+		"go/lang":      "go",
+		"js":           "javascript",
+		"javascript":   "ecmascript",
+		"a":            "b",
+		"b":            "a",
+		"self":         "self",
+		"web/frontend": "frontend",
+	}
+
+	assert.Equal(t, config.CanonicalTag("rust"), "rust")
+	assert.Equal(t, config.CanonicalTag("golang"), "go")
+	assert.Equal(t, config.CanonicalTag("golangx"), "golangx")
+	// Descendants of an aliased tag.
+	assert.Equal(t, config.CanonicalTag("golang/generics"), "go/generics")
+	assert.Equal(t, config.CanonicalTag("web/frontend/css"), "frontend/css")
+	assert.Equal(t, config.CanonicalTag("web"), "web")
+	// Chained aliases.
+	assert.Equal(t, config.CanonicalTag("js"), "ecmascript")
+	// Cyclic aliases don't loop forever.
+	assert.Equal(t, config.CanonicalTag("self"), "self")
+	config.CanonicalTag("a")
+
+	assert.Equal(t, config.CanonicalTags([]string{"golang", "go", "rust", "go/lang"}), []string{"go", "rust"})
+}
+
+func TestNormalizeTagsInNote(t *testing.T) {
+	config := NewDefaultConfig()
+	config.Format.Markdown.ColonTags = true
+	config.TagAliases = map[string]string{"golang": "go"}
+
+	test := func(content string, expectedContent string, expected []TextChange) {
+		actualContent, actual := normalizeTagsInNote(content, config)
+		assert.Equal(t, actualContent, expectedContent)
+		assert.Equal(t, actual, NoteReplacement{Changes: expected})
+	}
+
+	test("# No aliased tags\n\n#go #rust", "# No aliased tags\n\n#go #rust", []TextChange{})
+
+	test("#golang and #golang/generics, #golangx\n(#golang) `#golang` [link](page.md#golang)",
+		"#go and #go/generics, #golangx\n(#go) `#golang` [link](page.md#golang)",
+		[]TextChange{
+			{Line: 1, Old: "#golang and #golang/generics, #golangx", New: "#go and #go/generics, #golangx"},
+			{Line: 2, Old: "(#golang) `#golang` [link](page.md#golang)", New: "(#go) `#golang` [link](page.md#golang)"},
+		},
+	)
+
+	test("Colon :rust:golang: tags, at 12:golang:",
+		"Colon :rust:go: tags, at 12:golang:",
+		[]TextChange{{Line: 1, Old: "Colon :rust:golang: tags, at 12:golang:", New: "Colon :rust:go: tags, at 12:golang:"}},
+	)
+
+	test("```\n#golang\n```\n#golang",
+		"```\n#golang\n```\n#go",
+		[]TextChange{{Line: 4, Old: "#golang", New: "#go"}},
+	)
+
+	test(`---
+title: golang
+tags: [golang, "web", "#golang/generics"]
+keywords:
+  - golang
+  - rust
+aliases:
+  - golang
+---
+#golang`, `---
+title: golang
+tags: [go, "web", "#go/generics"]
+keywords:
+  - go
+  - rust
+aliases:
+  - golang
+---
+#go`,
+		[]TextChange{
+			{Line: 3, Old: `tags: [golang, "web", "#golang/generics"]`, New: `tags: [go, "web", "#go/generics"]`},
+			{Line: 5, Old: "  - golang", New: "  - go"},
+			{Line: 10, Old: "#golang", New: "#go"},
+		},
+	)
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// TagStyle holds the visual attributes of a tag, used by the user interfaces
+// to render the tags consistently, e.g. in pickers or graphs.
+type TagStyle struct {
+	// Color of the tag, either a hex color code such as #e06c75 or a color
+	// name such as red.
+	Color string
+	// Icon of the tag, usually an emoji or a Nerd Font glyph.
+	Icon string
+}
+
+var tagColorRegex = regexp.MustCompile(`^(?:#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})|[a-zA-Z]+)$`)
+
+// validateTagColor checks that the color of a tag is understood by most
+// interfaces, from CSS to terminal themes.
+func validateTagColor(color string) error {
+	if color != "" && !tagColorRegex.MatchString(color) {
+		return fmt.Errorf("%s: invalid tag color, expected a hex code such as #e06c75 or a color name", color)
+	}
+	return nil
+}
+
+// TagStyle returns the style of the given tag, declared in the config. The
+// attributes missing from the style of a tag are inherited from its closest
+// ancestor, e.g. project/alpha has the icon of project unless it has its own.
+func (c Config) TagStyle(tag string) TagStyle {
+	var style TagStyle
+	for _, candidate := range append([]string{tag}, TagAncestors(tag)...) {
+		ancestor, ok := c.TagStyles[candidate]
+		if !ok {
+			continue
+		}
+		if style.Color == "" {
+			style.Color = ancestor.Color
+		}
+		if style.Icon == "" {
+			style.Icon = ancestor.Icon
+		}
+	}
+	return style
+}
@@ -0,0 +1,24 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestTagStyle(t *testing.T) {
+	config := NewDefaultConfig()
+	config.TagStyles = map[string]TagStyle{
+		"project":         {Color: "#e06c75", Icon: "📁"},
+		"project/alpha":   {Icon: "🅰"},
+		"project/alpha/x": {Color: "teal"},
+	}
+
+	assert.Equal(t, config.TagStyle("inbox"), TagStyle{})
+	assert.Equal(t, config.TagStyle("project"), TagStyle{Color: "#e06c75", Icon: "📁"})
+	assert.Equal(t, config.TagStyle("projects"), TagStyle{})
+	// The missing attributes are inherited from the ancestors.
+	assert.Equal(t, config.TagStyle("project/beta"), TagStyle{Color: "#e06c75", Icon: "📁"})
+	assert.Equal(t, config.TagStyle("project/alpha"), TagStyle{Color: "#e06c75", Icon: "🅰"})
+	assert.Equal(t, config.TagStyle("project/alpha/x/y"), TagStyle{Color: "teal", Icon: "🅰"})
+}
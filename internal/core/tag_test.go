@@ -0,0 +1,37 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestTagAncestors(t *testing.T) {
+	assert.Equal(t, TagAncestors("project"), []string{})
+	assert.Equal(t, TagAncestors("project/alpha"), []string{"project"})
+	assert.Equal(t, TagAncestors("a/b/c"), []string{"a/b", "a"})
+	assert.Equal(t, TagAncestors("/root"), []string{})
+}
+
+func TestNewTagTrees(t *testing.T) {
+	assert.Equal(t, NewTagTrees([]Collection{}), []TagTree{})
+
+	assert.Equal(t, NewTagTrees([]Collection{
+		{Name: "area/health", NoteCount: 1},
+		{Name: "inbox", NoteCount: 4},
+		{Name: "project", NoteCount: 2},
+		{Name: "project/alpha", NoteCount: 3},
+		{Name: "project/beta/v2", NoteCount: 1},
+	}), []TagTree{
+		{Name: "area", Path: "area", Children: []TagTree{
+			{Name: "health", Path: "area/health", NoteCount: 1, Children: []TagTree{}},
+		}},
+		{Name: "inbox", Path: "inbox", NoteCount: 4, Children: []TagTree{}},
+		{Name: "project", Path: "project", NoteCount: 2, Children: []TagTree{
+			{Name: "alpha", Path: "project/alpha", NoteCount: 3, Children: []TagTree{}},
+			{Name: "beta", Path: "project/beta", Children: []TagTree{
+				{Name: "v2", Path: "project/beta/v2", NoteCount: 1, Children: []TagTree{}},
+			}},
+		}},
+	})
+}
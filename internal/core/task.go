@@ -0,0 +1,170 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// Task is a checkbox list item found in a note, e.g. `- [ ] Call Bob`.
+type Task struct {
+	// Text of the task, without the checkbox.
+	Text string `json:"text"`
+	// Indicates whether the task is checked.
+	Done bool `json:"done"`
+	// Line of the task in the note, starting from 1.
+	Line int `json:"line"`
+	// Due date of the task, given with an annotation such as
+	// `due:2021-10-12`, `@due(2021-10-12)` or `📅 2021-10-12`.
+	Due *time.Time `json:"due,omitempty"`
+}
+
+// NoteTask is a task found in an indexed note.
+type NoteTask struct {
+	Task
+	// Path of the note, relative to the notebook root.
+	Path string `json:"path"`
+	// Title of the note.
+	Title string `json:"title"`
+}
+
+// TaskFindOpts holds the criteria used to find the tasks of the notebook.
+type TaskFindOpts struct {
+	// Only the tasks which are not done.
+	Open bool
+	// Only the tasks due before this date, excluded.
+	DueBefore *time.Time
+	// Only the tasks of the notes at these paths, relative to the notebook
+	// root.
+	Paths []string
+}
+
+var (
+	taskRegex    = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+\[)([ xX])\]\s+(\S.*)$`)
+	taskDueRegex = regexp.MustCompile(`(?:\bdue:|@due\(|📅\s*)(\d{4}-\d{2}-\d{2})\)?`)
+	// Matches the done date annotations, with their leading spaces.
+	taskDoneRegex = regexp.MustCompile(`\s*(?:\bdone:|@done\(|✅\s*)\d{4}-\d{2}-\d{2}\)?`)
+)
+
+// ParseTask parses a task from a line of Markdown, e.g. `- [x] Call Bob`.
+// The line number is not set.
+func ParseTask(line string) (Task, bool) {
+	matches := taskRegex.FindStringSubmatch(strings.TrimRight(line, "\r"))
+	if matches == nil {
+		return Task{}, false
+	}
+
+	task := Task{
+		Text: strings.TrimSpace(matches[3]),
+		Done: matches[2] != " ",
+	}
+	if due := taskDueRegex.FindStringSubmatch(task.Text); due != nil {
+		if date, err := time.ParseInLocation("2006-01-02", due[1], time.Local); err == nil {
+			task.Due = &date
+		}
+	}
+	return task, true
+}
+
+// TaskCheckboxIndex returns the byte index of the checkbox mark in the given
+// task line, e.g. the `x` of `- [x] Done`, or -1 if it is not a task.
+func TaskCheckboxIndex(line string) int {
+	matches := taskRegex.FindStringSubmatchIndex(strings.TrimRight(line, "\r"))
+	if matches == nil {
+		return -1
+	}
+	return matches[4]
+}
+
+// FindTasks retrieves the tasks of the indexed notes matching the given
+// criteria, ordered by due date then by note path and line.
+func (n *Notebook) FindTasks(opts TaskFindOpts) ([]NoteTask, error) {
+	tasks, err := n.index.FindTasks(opts)
+	return tasks, errors.Wrap(err, "failed to find the tasks")
+}
+
+// ToggleTaskLine checks or unchecks the task of the given line of Markdown.
+// Returns false if the line is not a task.
+//
+// When the task is checked and doneDate is not nil, the date is appended to
+// the task with an annotation matching the style of its due date, e.g.
+// `done:2021-10-12`, `@done(2021-10-12)` or `✅ 2021-10-12`. The done dates
+// are removed when the task is unchecked.
+func ToggleTaskLine(line string, doneDate *time.Time) (string, bool) {
+	eol := ""
+	if strings.HasSuffix(line, "\r") {
+		line, eol = strings.TrimSuffix(line, "\r"), "\r"
+	}
+	checkbox := TaskCheckboxIndex(line)
+	if checkbox < 0 {
+		return "", false
+	}
+
+	done := line[checkbox] == ' '
+	mark := " "
+	if done {
+		mark = "x"
+	}
+	text := taskDoneRegex.ReplaceAllString(line[checkbox+1:], "")
+	if done && doneDate != nil {
+		date := doneDate.Format("2006-01-02")
+		switch {
+		case strings.Contains(text, "@due("):
+			text += " @done(" + date + ")"
+		case strings.Contains(text, "📅"):
+			text += " ✅ " + date
+		default:
+			text += " done:" + date
+		}
+	}
+
+	return line[:checkbox] + mark + text + eol, true
+}
+
+// TaskToggleOpts holds the options used to check or uncheck a task.
+type TaskToggleOpts struct {
+	// Date appended to the task when it is checked, see ToggleTaskLine.
+	DoneDate *time.Time
+	// Command toggling the task, e.g. from the editor.
+	Origin AuditOrigin
+}
+
+// ToggleTask checks or unchecks the task found at the given line of the note
+// at path, relative to the notebook root, and updates the index. Returns
+// the toggled task.
+func (n *Notebook) ToggleTask(path string, line int, opts TaskToggleOpts) (Task, error) {
+	wrap := errors.Wrapperf("%s:%d: failed to toggle the task", path, line)
+
+	content, err := n.ReadFile(filepath.Join(n.Path, path))
+	if err != nil {
+		return Task{}, wrap(err)
+	}
+	lines := strings.Split(string(content), "\n")
+	if line < 1 || line > len(lines) {
+		return Task{}, wrap(errors.New("line out of the note"))
+	}
+	newLine, ok := ToggleTaskLine(lines[line-1], opts.DoneDate)
+	if !ok {
+		return Task{}, wrap(errors.New("no task found on this line"))
+	}
+	lines[line-1] = newLine
+
+	_, err = n.writeReplacedNotes(map[string]string{path: strings.Join(lines, "\n")})
+	if err != nil {
+		return Task{}, wrap(err)
+	}
+
+	task, _ := ParseTask(newLine)
+	task.Line = line
+	status := "unchecked"
+	if task.Done {
+		status = "checked"
+	}
+	n.audit(opts.Origin, AuditOperationReplace, fmt.Sprintf("%s the task on line %d", status, line), path)
+	n.autoCommit("edit", path)
+	return task, nil
+}
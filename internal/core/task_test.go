@@ -0,0 +1,70 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestParseTask(t *testing.T) {
+	test := func(line string, expected Task, expectedOK bool) {
+		t.Helper()
+		task, ok := ParseTask(line)
+		assert.Equal(t, ok, expectedOK)
+		assert.Equal(t, task, expected)
+	}
+	date := func(value string) *time.Time {
+		res, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		assert.Nil(t, err)
+		return &res
+	}
+
+	test("", Task{}, false)
+	test("- Not a task", Task{}, false)
+	test("- [ ]", Task{}, false)
+	test("[ ] Not in a list", Task{}, false)
+
+	test("- [ ] Call Bob", Task{Text: "Call Bob"}, true)
+	test("  * [x] Done\r", Task{Text: "Done", Done: true}, true)
+	test("+ [X] Done", Task{Text: "Done", Done: true}, true)
+	test("12. [ ] Numbered", Task{Text: "Numbered"}, true)
+
+	// Due dates
+	test("- [ ] Pay due:2021-10-12", Task{Text: "Pay due:2021-10-12", Due: date("2021-10-12")}, true)
+	test("- [ ] Pay @due(2021-10-12)", Task{Text: "Pay @due(2021-10-12)", Due: date("2021-10-12")}, true)
+	test("- [ ] Pay 📅 2021-10-12", Task{Text: "Pay 📅 2021-10-12", Due: date("2021-10-12")}, true)
+	test("- [ ] Pay due:2021-13-45", Task{Text: "Pay due:2021-13-45"}, true)
+}
+
+func TestTaskCheckboxIndex(t *testing.T) {
+	assert.Equal(t, TaskCheckboxIndex("Not a task"), -1)
+	assert.Equal(t, TaskCheckboxIndex("- [ ] Call Bob"), 3)
+	assert.Equal(t, TaskCheckboxIndex("   12. [x] Done"), 8)
+}
+
+func TestToggleTaskLine(t *testing.T) {
+	doneDate := time.Date(2021, 10, 12, 15, 0, 0, 0, time.UTC)
+	test := func(line string, doneDate *time.Time, expected string, expectedOK bool) {
+		t.Helper()
+		actual, ok := ToggleTaskLine(line, doneDate)
+		assert.Equal(t, actual, expected)
+		assert.Equal(t, ok, expectedOK)
+	}
+
+	test("- Not a task", nil, "", false)
+	test("- [ ] Call Bob", nil, "- [x] Call Bob", true)
+	test("  12. [X] Done\r", nil, "  12. [ ] Done\r", true)
+
+	// The done date follows the style of the due date.
+	test("- [ ] Call Bob", &doneDate, "- [x] Call Bob done:2021-10-12", true)
+	test("- [ ] Pay due:2021-10-15\r", &doneDate, "- [x] Pay due:2021-10-15 done:2021-10-12\r", true)
+	test("- [ ] Pay @due(2021-10-15)", &doneDate, "- [x] Pay @due(2021-10-15) @done(2021-10-12)", true)
+	test("- [ ] Pay 📅 2021-10-15", &doneDate, "- [x] Pay 📅 2021-10-15 ✅ 2021-10-12", true)
+	test("- [ ] Stale done:2020-01-01", &doneDate, "- [x] Stale done:2021-10-12", true)
+
+	// The done dates are removed when unchecking.
+	test("- [x] Call Bob done:2021-10-12", &doneDate, "- [ ] Call Bob", true)
+	test("- [x] Pay @done(2021-10-12) later", nil, "- [ ] Pay later", true)
+	test("- [x] Pay 📅 2021-10-15 ✅ 2021-10-12", nil, "- [ ] Pay 📅 2021-10-15", true)
+}
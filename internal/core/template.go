@@ -36,11 +36,31 @@ func (t nullTemplate) Render(context interface{}) (string, error) {
 	return "", nil
 }
 
+// ErrTemplate is an error returned when a template can't be loaded or
+// rendered, e.g. because of a syntax error.
+type ErrTemplate struct {
+	// Content or path of the template.
+	Template string
+	Err      error
+}
+
+func (e ErrTemplate) Error() string {
+	return e.Err.Error()
+}
+
+func (e ErrTemplate) Unwrap() error {
+	return e.Err
+}
+
 // TemplateLoader parses a string into a new Template instance.
 type TemplateLoader interface {
 	// LoadTemplate creates a Template instance from a string template.
 	LoadTemplate(template string) (Template, error)
 
+	// LoadHTMLTemplate creates a Template instance rendering HTML from a
+	// string template. Unlike with LoadTemplate, the variables are escaped.
+	LoadHTMLTemplate(template string) (Template, error)
+
 	// LoadTemplate creates a Template instance from a template stored in the
 	// file at the given path.
 	// The path may be relative to template directories registered to the loader.
@@ -60,6 +80,10 @@ func (t nullTemplateLoader) LoadTemplate(template string) (Template, error) {
 	return &NullTemplate, nil
 }
 
+func (t nullTemplateLoader) LoadHTMLTemplate(template string) (Template, error) {
+	return &NullTemplate, nil
+}
+
 func (t nullTemplateLoader) LoadTemplateAt(path string) (Template, error) {
 	return &NullTemplate, nil
 }
@@ -44,6 +44,10 @@ func (l *templateLoaderMock) LoadTemplate(template string) (Template, error) {
 	return tpl, nil
 }
 
+func (l *templateLoaderMock) LoadHTMLTemplate(template string) (Template, error) {
+	return l.LoadTemplate(template)
+}
+
 func (l *templateLoaderMock) LoadTemplateAt(path string) (Template, error) {
 	tpl, ok := l.fileTemplates[path]
 	if !ok {
@@ -0,0 +1,258 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/mickael-menu/zk/internal/util/errors"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// TemplateVar is a variable declared in the comment header of a note
+// template with an `@var` line, e.g.
+//
+//	@var project "Project of the meeting" choices=alpha,beta default=alpha
+//
+// The user is prompted for its value when creating a note interactively. It
+// is available as an extra variable in the templates, e.g. {{extra.project}}.
+type TemplateVar struct {
+	Name string          `json:"name"`
+	Type TemplateVarType `json:"type"`
+	// Message shown to the user when prompting for the value.
+	Prompt string `json:"prompt"`
+	// Value used when the variable is not given, if not empty.
+	Default string `json:"default"`
+	// Values allowed for this variable, any value is allowed when empty.
+	Choices []string `json:"choices"`
+}
+
+// TemplateVarType is the type of the value of a template variable.
+type TemplateVarType string
+
+const (
+	TemplateVarString  TemplateVarType = "string"
+	TemplateVarNumber  TemplateVarType = "number"
+	TemplateVarBoolean TemplateVarType = "boolean"
+)
+
+// Parse checks that the given value is valid for this variable, and returns
+// it as given to the templates.
+//
+// A boolean is either "true" or empty, to be tested with {{#if}}.
+func (v TemplateVar) Parse(value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	if len(v.Choices) > 0 && !strutil.InList(v.Choices, value) {
+		return "", fmt.Errorf("%s: invalid value for %s, expected one of: %s", value, v.Name, strings.Join(v.Choices, ", "))
+	}
+
+	switch v.Type {
+	case TemplateVarNumber:
+		if value == "" {
+			return value, nil
+		}
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("%s: invalid value for %s, expected a number", value, v.Name)
+		}
+	case TemplateVarBoolean:
+		if value == "" {
+			return value, nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%s: invalid value for %s, expected true or false", value, v.Name)
+		}
+		if !b {
+			return "", nil
+		}
+		return "true", nil
+	}
+	return value, nil
+}
+
+var templateVarNameRegex = regexp.MustCompile(`^[\w-]+$`)
+
+// parseTemplateVars reads the variables declared with `@var` lines in the
+// comment header of a template.
+func parseTemplateVars(content string) ([]TemplateVar, error) {
+	vars := []TemplateVar{}
+	for _, line := range strings.Split(templateHeader(content), "\n") {
+		decl, ok := templateVarDeclaration(line)
+		if !ok {
+			continue
+		}
+		v, err := parseTemplateVar(decl)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid template variable: %s", strings.TrimSpace(line))
+		}
+		vars = append(vars, v)
+	}
+	return vars, nil
+}
+
+// templateVarDeclaration returns the declaration following `@var` in the
+// given line of a template header.
+func templateVarDeclaration(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "@var ") {
+		return "", false
+	}
+	return strings.TrimPrefix(line, "@var "), true
+}
+
+// parseTemplateVar parses the declaration of a variable: its name, an
+// optional prompt and the type, default and choices options.
+func parseTemplateVar(decl string) (TemplateVar, error) {
+	args, err := shellquote.Split(decl)
+	if err != nil {
+		return TemplateVar{}, err
+	}
+	if len(args) == 0 {
+		return TemplateVar{}, fmt.Errorf("missing variable name")
+	}
+
+	v := TemplateVar{
+		Name:    args[0],
+		Type:    TemplateVarString,
+		Choices: []string{},
+	}
+	if !templateVarNameRegex.MatchString(v.Name) {
+		return v, fmt.Errorf("%s: invalid variable name, expected letters, numbers, - or _", v.Name)
+	}
+
+	for _, arg := range args[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) < 2 {
+			if v.Prompt != "" {
+				return v, fmt.Errorf("%s: unexpected argument, the prompt is already given", arg)
+			}
+			v.Prompt = arg
+			continue
+		}
+
+		switch key, value := parts[0], parts[1]; key {
+		case "type":
+			v.Type = TemplateVarType(value)
+			if v.Type != TemplateVarString && v.Type != TemplateVarNumber && v.Type != TemplateVarBoolean {
+				return v, fmt.Errorf("%s: unknown type, expected string, number or boolean", value)
+			}
+		case "default":
+			v.Default = value
+		case "choices":
+			for _, choice := range strings.Split(value, ",") {
+				if choice = strings.TrimSpace(choice); choice != "" {
+					v.Choices = append(v.Choices, choice)
+				}
+			}
+		default:
+			return v, fmt.Errorf("%s: unknown option, expected type, default or choices", key)
+		}
+	}
+
+	if v.Prompt == "" {
+		v.Prompt = v.Name
+	}
+	for _, choice := range v.Choices {
+		if _, err := v.Parse(choice); err != nil {
+			return v, err
+		}
+	}
+	if v.Default != "" {
+		if _, err := v.Parse(v.Default); err != nil {
+			return v, errors.Wrap(err, "invalid default value")
+		}
+	}
+	return v, nil
+}
+
+// templateVarsAt returns the variables declared by the template at the given
+// path, relative to the templates directories.
+//
+// A missing template declares no variables, the error is reported when
+// loading it.
+func (n *Notebook) templateVarsAt(path string) ([]TemplateVar, error) {
+	if path == "" {
+		return []TemplateVar{}, nil
+	}
+
+	candidates := []string{path}
+	if !filepath.IsAbs(path) {
+		candidates = []string{}
+		for _, dir := range n.templateDirs {
+			candidates = append(candidates, filepath.Join(dir, path))
+		}
+	}
+
+	for _, candidate := range candidates {
+		exists, err := n.fs.FileExists(candidate)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		content, err := n.fs.Read(candidate)
+		if err != nil {
+			return nil, err
+		}
+		vars, err := parseTemplateVars(string(content))
+		return vars, errors.Wrap(err, path)
+	}
+	return []TemplateVar{}, nil
+}
+
+// MissingTemplateVars returns the variables declared by the template of a new
+// note, which are not set with the extra variables of the options or of the
+// note group. They can be prompted to the user before creating the note.
+func (n *Notebook) MissingTemplateVars(opts NewNoteOpts) ([]TemplateVar, error) {
+	_, config, err := n.newNoteGroup(opts)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := n.templateVarsAt(opts.Template.Or(config.Note.BodyTemplatePath).Unwrap())
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []TemplateVar{}
+	for _, v := range vars {
+		if _, ok := opts.Extra[v.Name]; ok {
+			continue
+		}
+		if _, ok := config.Extra[v.Name]; ok {
+			continue
+		}
+		missing = append(missing, v)
+	}
+	return missing, nil
+}
+
+// resolveTemplateVars checks the values of the variables declared by the
+// template at the given path, which are set in extra. The default values are
+// used for the missing ones.
+func (n *Notebook) resolveTemplateVars(templatePath string, extra map[string]string) error {
+	vars, err := n.templateVarsAt(templatePath)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range vars {
+		value, ok := extra[v.Name]
+		if !ok {
+			if v.Default == "" {
+				continue
+			}
+			value = v.Default
+		}
+		value, err := v.Parse(value)
+		if err != nil {
+			return err
+		}
+		extra[v.Name] = value
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestParseTemplateVars(t *testing.T) {
+	test := func(content string, expected []TemplateVar) {
+		vars, err := parseTemplateVars(content)
+		assert.Nil(t, err)
+		assert.Equal(t, vars, expected)
+	}
+
+	test("", []TemplateVar{})
+	test("# {{title}}\n@var project\n", []TemplateVar{})
+	test(`{{!--
+  Meeting minutes
+  @var project "Project of the meeting" choices=alpha,beta default=alpha
+  @var attendees
+  @var hours "Time spent" type=number
+  @var billable type=boolean default=true "Billable?"
+--}}
+# {{title}}
+`, []TemplateVar{
+		{Name: "project", Type: TemplateVarString, Prompt: "Project of the meeting", Default: "alpha", Choices: []string{"alpha", "beta"}},
+		{Name: "attendees", Type: TemplateVarString, Prompt: "attendees", Choices: []string{}},
+		{Name: "hours", Type: TemplateVarNumber, Prompt: "Time spent", Choices: []string{}},
+		{Name: "billable", Type: TemplateVarBoolean, Prompt: "Billable?", Default: "true", Choices: []string{}},
+	})
+}
+
+func TestParseInvalidTemplateVars(t *testing.T) {
+	test := func(decl string, expectedErr string) {
+		_, err := parseTemplateVars("{{!--\n@var " + decl + "\n--}}")
+		assert.Err(t, err, expectedErr)
+	}
+
+	test(`"a name"`, "a name: invalid variable name")
+	test(`hours type=date`, "date: unknown type")
+	test(`hours format=%d`, "format: unknown option")
+	test(`hours "Time spent" "Hours"`, "Hours: unexpected argument")
+	test(`hours type=number default=many`, "invalid default value: many: invalid value for hours, expected a number")
+	test(`project choices=alpha,beta default=gamma`, "invalid default value: gamma: invalid value for project, expected one of: alpha, beta")
+	test(`project "unclosed`, "Unterminated")
+}
+
+func TestTemplateVarParse(t *testing.T) {
+	test := func(v TemplateVar, value string, expected string) {
+		actual, err := v.Parse(value)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+	testErr := func(v TemplateVar, value string, expectedErr string) {
+		_, err := v.Parse(value)
+		assert.Err(t, err, expectedErr)
+	}
+
+	str := TemplateVar{Name: "author", Type: TemplateVarString}
+	test(str, " Jane Doe ", "Jane Doe")
+	test(str, "", "")
+
+	number := TemplateVar{Name: "hours", Type: TemplateVarNumber}
+	test(number, "1.5", "1.5")
+	testErr(number, "one", "one: invalid value for hours, expected a number")
+
+	boolean := TemplateVar{Name: "billable", Type: TemplateVarBoolean}
+	test(boolean, "true", "true")
+	test(boolean, "1", "true")
+	test(boolean, "false", "")
+	testErr(boolean, "maybe", "maybe: invalid value for billable, expected true or false")
+
+	choice := TemplateVar{Name: "project", Type: TemplateVarString, Choices: []string{"alpha", "beta"}}
+	test(choice, "beta", "beta")
+	testErr(choice, "gamma", "gamma: invalid value for project, expected one of: alpha, beta")
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// shellHelperRegex matches the invocations of the {{sh}} template helper.
+var shellHelperRegex = regexp.MustCompile(`\{\{~?[#]?\s*sh\b`)
+
+// ShellSnippets returns the locations of the shell commands defined by this
+// config on top of the base one, e.g. alias.ls or tool.editor.
+//
+// This is used to find the shell commands a notebook config would run on
+// behalf of the user, before trusting it.
+func (c Config) ShellSnippets(base Config) []string {
+	snippets := []string{}
+	add := func(name string, value string, baseValue string) {
+		if value != "" && value != baseValue {
+			snippets = append(snippets, name)
+		}
+	}
+
+	add("tool.editor", c.Tool.Editor.Unwrap(), base.Tool.Editor.Unwrap())
+	add("tool.pager", c.Tool.Pager.Unwrap(), base.Tool.Pager.Unwrap())
+	add("tool.fzf-preview", c.Tool.FzfPreview.Unwrap(), base.Tool.FzfPreview.Unwrap())
+	if shellHelperRegex.MatchString(c.Tool.FzfLine.Unwrap()) {
+		add("tool.fzf-line", c.Tool.FzfLine.Unwrap(), base.Tool.FzfLine.Unwrap())
+	}
+	add("tool.pdf-text", c.Tool.PDFText.Unwrap(), base.Tool.PDFText.Unwrap())
+	add("tool.ocr", c.Tool.OCR.Unwrap(), base.Tool.OCR.Unwrap())
+	add("archive.command", c.Archive.Command, base.Archive.Command)
+	add("hooks.post-new", c.Hooks.PostNew, base.Hooks.PostNew)
+	add("hooks.pre-index", c.Hooks.PreIndex, base.Hooks.PreIndex)
+	add("hooks.post-index", c.Hooks.PostIndex, base.Hooks.PostIndex)
+
+	for _, name := range sortedKeys(c.Aliases) {
+		add("alias."+name, c.Aliases[name], base.Aliases[name])
+	}
+	for _, lang := range sortedKeys(c.Renderers) {
+		add("renderer."+lang, c.Renderers[lang], base.Renderers[lang])
+	}
+	helperNames := []string{}
+	for name := range c.Helpers {
+		helperNames = append(helperNames, name)
+	}
+	sort.Strings(helperNames)
+	for _, name := range helperNames {
+		add("helper."+name, c.Helpers[name].Command, base.Helpers[name].Command)
+	}
+
+	return snippets
+}
+
+// WithoutShellSnippets returns a copy of the config where the shell commands
+// defined on top of the base config are reverted to the base settings. The
+// {{sh}} template helper is disabled as well.
+func (c Config) WithoutShellSnippets(base Config) Config {
+	c.Tool.Editor = base.Tool.Editor
+	c.Tool.Pager = base.Tool.Pager
+	c.Tool.FzfPreview = base.Tool.FzfPreview
+	if shellHelperRegex.MatchString(c.Tool.FzfLine.Unwrap()) {
+		c.Tool.FzfLine = base.Tool.FzfLine
+	}
+	c.Tool.PDFText = base.Tool.PDFText
+	c.Tool.OCR = base.Tool.OCR
+	c.Archive.Command = base.Archive.Command
+	c.Hooks = base.Hooks
+
+	c.Aliases = revertStrings(c.Aliases, base.Aliases)
+	c.Renderers = revertStrings(c.Renderers, base.Renderers)
+
+	helpers := map[string]HelperConfig{}
+	for name, helper := range c.Helpers {
+		if helper.Command == "" {
+			helpers[name] = helper
+		} else if baseHelper, ok := base.Helpers[name]; ok {
+			helpers[name] = baseHelper
+		}
+	}
+	c.Helpers = helpers
+
+	c.ShellDisabled = true
+	return c
+}
+
+// revertStrings returns a copy of values keeping only the entries of base.
+func revertStrings(values map[string]string, base map[string]string) map[string]string {
+	reverted := map[string]string{}
+	for key := range values {
+		if value, ok := base[key]; ok {
+			reverted[key] = value
+		}
+	}
+	return reverted
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// shellTemplates returns the paths of the templates found in the given
+// notebook's .zk/templates directory which call the {{sh}} helper, relative
+// to the notebook root.
+func shellTemplates(notebookDir string) []string {
+	templates := []string{}
+	dir := filepath.Join(notebookDir, ".zk/templates")
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil || !shellHelperRegex.Match(content) {
+			return nil
+		}
+		if relPath, err := filepath.Rel(notebookDir, path); err == nil {
+			templates = append(templates, relPath)
+		}
+		return nil
+	})
+	return templates
+}
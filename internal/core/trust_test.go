@@ -0,0 +1,123 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/opt"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestConfigShellSnippets(t *testing.T) {
+	base, err := ParseConfig([]byte(`
+		[tool]
+		editor = "vim"
+		[alias]
+		ls = "zk list $@"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, base.ShellSnippets(base), []string{})
+
+	conf, err := ParseConfig([]byte(`
+		[tool]
+		editor = "vim"
+		pager = "less"
+		fzf-line = "{{title}}"
+		[alias]
+		ls = "zk list $@"
+		hist = "git log"
+		[renderer]
+		mermaid = "mmdc"
+		[archive]
+		command = "archive $1"
+		[hooks]
+		post-new = "notify-send new"
+		[helper.greet]
+		template = "Hello"
+		[helper.today]
+		command = "date +%F"
+	`), ".zk/config.toml", base)
+	assert.Nil(t, err)
+
+	assert.Equal(t, conf.ShellSnippets(base), []string{
+		"tool.pager", "archive.command", "hooks.post-new", "alias.hist", "renderer.mermaid", "helper.today",
+	})
+
+	conf.Tool.FzfLine = opt.NewString(`{{sh "date"}} {{title}}`)
+	assert.Equal(t, conf.ShellSnippets(base), []string{
+		"tool.pager", "tool.fzf-line", "archive.command", "hooks.post-new", "alias.hist", "renderer.mermaid", "helper.today",
+	})
+}
+
+func TestConfigWithoutShellSnippets(t *testing.T) {
+	base, err := ParseConfig([]byte(`
+		[tool]
+		editor = "vim"
+		[alias]
+		ls = "zk list $@"
+		[helper.today]
+		command = "date +%F"
+	`), ".zk/config.toml", NewDefaultConfig())
+	assert.Nil(t, err)
+
+	conf, err := ParseConfig([]byte(`
+		[tool]
+		editor = "evil"
+		[alias]
+		ls = "evil"
+		hist = "git log"
+		[renderer]
+		mermaid = "mmdc"
+		[archive]
+		command = "archive $1"
+		[hooks]
+		pre-index = "evil"
+		[helper.greet]
+		template = "Hello"
+		[helper.today]
+		command = "evil"
+		[helper.now]
+		command = "date"
+	`), ".zk/config.toml", base)
+	assert.Nil(t, err)
+
+	conf = conf.WithoutShellSnippets(base)
+	assert.True(t, conf.ShellDisabled)
+	assert.Equal(t, conf.ShellSnippets(base), []string{})
+	assert.Equal(t, conf.Tool.Editor, opt.NewString("vim"))
+	assert.Equal(t, conf.Archive.Command, "")
+	assert.Equal(t, conf.Hooks, HooksConfig{})
+	assert.Equal(t, conf.Aliases, map[string]string{"ls": "zk list $@"})
+	assert.Equal(t, conf.Renderers, map[string]string{})
+	assert.Equal(t, conf.Helpers, map[string]HelperConfig{
+		"greet": {Template: "Hello"},
+		"today": {Command: "date +%F"},
+	})
+}
+
+func TestShellTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zk-trust")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(path string, content string) {
+		path = filepath.Join(dir, path)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+
+	assert.Equal(t, shellTemplates(dir), []string{})
+
+	write(".zk/templates/default.md", "# {{title}}")
+	write(".zk/templates/daily.md", "# {{sh \"date\"}}")
+	write(".zk/templates/journal/weekly.md", "{{#sh \"tr a-z A-Z\"}}{{title}}{{/sh}}")
+	write(".zk/templates/shout.md", "{{shout title}}")
+	write("note.md", "{{sh \"date\"}}")
+
+	assert.Equal(t, shellTemplates(dir), []string{
+		".zk/templates/daily.md",
+		".zk/templates/journal/weekly.md",
+	})
+}
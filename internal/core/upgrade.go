@@ -0,0 +1,219 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// notebookUpgrade rewrites a construct deprecated by a version of zk, found in
+// the config or the templates of a notebook.
+type notebookUpgrade struct {
+	// Version of zk which deprecated the construct.
+	version string
+	// Description of the change, for the summary of the upgrade.
+	description string
+	regex       *regexp.Regexp
+	// replace returns the new text of a match, from its submatches.
+	replace func(match []string) string
+}
+
+// notebookUpgrades lists the deprecated constructs rewritten by
+// Notebook.Upgrade, from the oldest. The zk command lines can appear in the
+// aliases and filters of the config, as well as in the {{sh}} helpers of the
+// templates, so the upgrades are applied to both.
+var notebookUpgrades = []notebookUpgrade{
+	{
+		version:     "0.2.0",
+		description: "renamed the --linking-to filtering option to --link-to",
+		regex:       regexp.MustCompile(`--linking-to\b`),
+		replace: func(match []string) string {
+			return "--link-to"
+		},
+	},
+	{
+		version:     "0.2.0",
+		description: "separated the --extra variables with , instead of ;",
+		regex:       regexp.MustCompile(`(\s(?:--extra[= ]|-x ?)\\?["']?)([\w-]+=[^,;"'\\\s]*(?:;[\w-]+=[^,;"'\\\s]*)+)`),
+		replace: func(match []string) string {
+			return match[1] + strings.Replace(match[2], ";", ",", -1)
+		},
+	},
+}
+
+// UpgradeNotebookOpts holds the options used to upgrade a notebook.
+type UpgradeNotebookOpts struct {
+	// Lists the pending upgrades without rewriting the notes.
+	DryRun bool
+}
+
+// NotebookUpgrade reports the changes made when upgrading a notebook.
+type NotebookUpgrade struct {
+	// Config and template files rewritten, relative to the notebook root.
+	Files []NoteReplacement `json:"files"`
+	// Upgrades applied to the files, from the oldest.
+	Applied []AppliedUpgrade `json:"applied"`
+}
+
+// AppliedUpgrade is a deprecated construct rewritten in the notebook.
+type AppliedUpgrade struct {
+	// Version of zk which deprecated the construct.
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	// Number of occurrences rewritten.
+	Count int `json:"count"`
+}
+
+// Upgrade rewrites the settings and template constructs deprecated by
+// previous versions of zk, in the notebook config and templates, keeping the
+// rest of the files as written.
+func (n *Notebook) Upgrade(opts UpgradeNotebookOpts) (*NotebookUpgrade, error) {
+	wrap := errors.Wrapper("failed to upgrade the notebook")
+
+	configPath := filepath.Join(n.Path, ".zk/config.toml")
+	paths := []string{}
+	if exists, err := n.fs.FileExists(configPath); err != nil {
+		return nil, wrap(err)
+	} else if exists {
+		paths = append(paths, configPath)
+	}
+	templatePaths, err := notebookTemplates(n.Path)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	paths = append(paths, templatePaths...)
+
+	upgrade := NotebookUpgrade{
+		Files:   []NoteReplacement{},
+		Applied: []AppliedUpgrade{},
+	}
+	counts := make([]int, len(notebookUpgrades))
+	contents := map[string]string{}
+	for _, path := range paths {
+		content, err := n.fs.Read(path)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		newContent, changes, fileCounts := upgradeContent(string(content))
+		if len(changes) == 0 {
+			continue
+		}
+		if path == configPath {
+			// Makes sure the upgraded config is still valid before saving it.
+			_, err := ParseConfig([]byte(newContent), path, NewDefaultConfig())
+			if err != nil {
+				return nil, wrap(errors.Wrap(err, "the upgraded config is invalid"))
+			}
+		}
+
+		relPath, err := filepath.Rel(n.Path, path)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		upgrade.Files = append(upgrade.Files, NoteReplacement{
+			Path:    relPath,
+			Changes: changes,
+		})
+		contents[path] = newContent
+		for i, count := range fileCounts {
+			counts[i] += count
+		}
+	}
+
+	for i, count := range counts {
+		if count > 0 {
+			upgrade.Applied = append(upgrade.Applied, AppliedUpgrade{
+				Version:     notebookUpgrades[i].version,
+				Description: notebookUpgrades[i].description,
+				Count:       count,
+			})
+		}
+	}
+
+	if opts.DryRun {
+		return &upgrade, nil
+	}
+	for _, path := range paths {
+		if content, ok := contents[path]; ok {
+			err := n.fs.Write(path, []byte(content))
+			if err != nil {
+				return nil, wrap(err)
+			}
+		}
+	}
+	return &upgrade, nil
+}
+
+// upgradeContent applies the notebook upgrades to the content of a config or
+// template file. It returns the modified lines and the number of occurrences
+// rewritten by each upgrade.
+func upgradeContent(content string) (string, []TextChange, []int) {
+	type upgradeEdit struct {
+		textEdit
+		upgrade int
+	}
+
+	edits := []upgradeEdit{}
+	for i, upgrade := range notebookUpgrades {
+		for _, loc := range upgrade.regex.FindAllStringSubmatchIndex(content, -1) {
+			match := []string{}
+			for j := 0; j < len(loc); j += 2 {
+				if loc[j] < 0 {
+					match = append(match, "")
+				} else {
+					match = append(match, content[loc[j]:loc[j+1]])
+				}
+			}
+			edits = append(edits, upgradeEdit{
+				textEdit: textEdit{start: loc[0], end: loc[1], text: upgrade.replace(match)},
+				upgrade:  i,
+			})
+		}
+	}
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	counts := make([]int, len(notebookUpgrades))
+	textEdits := []textEdit{}
+	for _, edit := range edits {
+		// The overlapping edits are left for the next upgrade, which is
+		// unlikely as the upgrades target different constructs.
+		if len(textEdits) > 0 && edit.start < textEdits[len(textEdits)-1].end {
+			continue
+		}
+		textEdits = append(textEdits, edit.textEdit)
+		counts[edit.upgrade]++
+	}
+	if len(textEdits) == 0 {
+		return content, []TextChange{}, counts
+	}
+
+	newContent, changes := applyTextEdits(content, textEdits)
+	return newContent, changes, counts
+}
+
+// notebookTemplates returns the paths of the files found in the given
+// notebook's .zk/templates directory, sorted alphabetically.
+func notebookTemplates(notebookDir string) ([]string, error) {
+	templates := []string{}
+	dir := filepath.Join(notebookDir, ".zk/templates")
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return templates, nil
+	}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			templates = append(templates, path)
+		}
+		return nil
+	})
+	sort.Strings(templates)
+	return templates, err
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestUpgradeContent(t *testing.T) {
+	test := func(content string, expectedContent string, expectedCounts []int) {
+		t.Helper()
+		actualContent, _, actualCounts := upgradeContent(content)
+		assert.Equal(t, actualContent, expectedContent)
+		assert.Equal(t, actualCounts, expectedCounts)
+	}
+
+	test("", "", []int{0, 0})
+	test(`[alias]
+ls = "zk list --link-to $@"
+journal = "zk new --extra author=me,mood=good journal"
+`, `[alias]
+ls = "zk list --link-to $@"
+journal = "zk new --extra author=me,mood=good journal"
+`, []int{0, 0})
+
+	test(`[filter]
+backlinks = "--linking-to index.md"
+
+[alias]
+bl = "zk list --linking-to=$1 --no-linking-to b.md"
+journal = "zk new --extra author=me;mood=good journal"
+quoted = "zk new --extra \"author=me;mood=good\""
+short = 'zk new -x "author=me;mood=good" -xa=1;b=2'
+shell = "zk new --extra author=me journal; echo done=1;ok"
+`, `[filter]
+backlinks = "--link-to index.md"
+
+[alias]
+bl = "zk list --link-to=$1 --no-linking-to b.md"
+journal = "zk new --extra author=me,mood=good journal"
+quoted = "zk new --extra \"author=me,mood=good\""
+short = 'zk new -x "author=me,mood=good" -xa=1,b=2'
+shell = "zk new --extra author=me journal; echo done=1;ok"
+`, []int{2, 4})
+
+	test(`{{sh "zk list --linking-to {{path}} --format {{title}}"}}`,
+		`{{sh "zk list --link-to {{path}} --format {{title}}"}}`,
+		[]int{1, 0},
+	)
+}
+
+func TestUpgradeContentReportsChanges(t *testing.T) {
+	_, changes, _ := upgradeContent("[filter]\nbacklinks = \"--linking-to index.md\"\n")
+	assert.Equal(t, changes, []TextChange{
+		{Line: 2, Old: `backlinks = "--linking-to index.md"`, New: `backlinks = "--link-to index.md"`},
+	})
+}
@@ -0,0 +1,252 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+	"github.com/mickael-menu/zk/internal/util/exec"
+	strutil "github.com/mickael-menu/zk/internal/util/strings"
+)
+
+// URLMetadata holds information fetched from an external URL linked in the
+// notes.
+type URLMetadata struct {
+	URL string `json:"url"`
+	// Title of the web page, if any.
+	Title string `json:"title"`
+	// HTTP status code returned by the server, or 0 if it couldn't be reached.
+	Status int `json:"status"`
+	// Date when the metadata was fetched.
+	Fetched time.Time `json:"fetched"`
+	// URL of an archived version of the web page, e.g. on the Wayback
+	// Machine.
+	Archive string `json:"archive,omitempty"`
+}
+
+// IsDead returns whether the URL couldn't be reached, in which case it might
+// be worth replacing it with an archived version.
+func (m URLMetadata) IsDead() bool {
+	return m.Status == 0 || m.Status >= 400
+}
+
+// StatusText returns a human readable description of the URL status.
+func (m URLMetadata) StatusText() string {
+	if m.Status == 0 {
+		return "unreachable"
+	}
+	return http.StatusText(m.Status)
+}
+
+// URLMetadataFetcher retrieves the metadata of an external URL, e.g. with an
+// HTTP request.
+type URLMetadataFetcher func(url string) (URLMetadata, error)
+
+// urlMetadataMaxAge is the duration after which the cached metadata of a URL
+// are fetched again.
+const urlMetadataMaxAge = 30 * 24 * time.Hour
+
+// FetchURLMetadata fetches and caches the metadata of at most limit external
+// URLs found in the notes, which were never fetched or are outdated.
+//
+// A negative limit fetches all of them. Returns the number of fetched URLs.
+func (n *Notebook) FetchURLMetadata(fetch URLMetadataFetcher, limit int) (int, error) {
+	wrap := errors.Wrapper("failed to fetch the URL metadata")
+
+	urls, err := n.index.FindUnfetchedURLs(time.Now().Add(-urlMetadataMaxAge), limit)
+	if err != nil {
+		return 0, wrap(err)
+	}
+
+	for _, url := range urls {
+		metadata, err := fetch(url)
+		if err != nil {
+			// Unreachable URLs are cached as well, to prevent fetching them
+			// again immediately.
+			n.logger.Printf("failed to fetch %s: %v", url, err)
+			metadata = URLMetadata{URL: url}
+		}
+		metadata.URL = url
+		metadata.Fetched = time.Now().UTC()
+
+		err = n.index.SetURLMetadata(metadata)
+		if err != nil {
+			return 0, wrap(err)
+		}
+	}
+
+	return len(urls), nil
+}
+
+// FindURLMetadata retrieves the cached metadata of the given external URL.
+// Returns nil if the URL was never fetched.
+func (n *Notebook) FindURLMetadata(url string) (*URLMetadata, error) {
+	return n.index.FindURLMetadata(url)
+}
+
+// URLArchiver submits an external URL to a web archive, e.g. the Wayback
+// Machine, and returns the URL of the archived version.
+type URLArchiver func(url string) (string, error)
+
+// ArchiveURLs submits to the web archive the external URLs found in the
+// notes, which were reachable when last fetched but never archived.
+//
+// The archive command configured in the notebook takes precedence over the
+// given archiver. A negative limit archives all of them. Returns the number
+// of archived URLs.
+func (n *Notebook) ArchiveURLs(archive URLArchiver, limit int) (int, error) {
+	wrap := errors.Wrapper("failed to archive the URLs")
+
+	if command := n.Config.Archive.Command; command != "" {
+		archive = newCommandURLArchiver(command)
+	}
+
+	urls, err := n.index.FindUnarchivedURLs(limit)
+	if err != nil {
+		return 0, wrap(err)
+	}
+
+	for i, url := range urls {
+		archived, err := archive(url)
+		if err != nil {
+			// The archive is most likely unavailable or rate limiting us,
+			// the remaining URLs are archived next time.
+			return i, wrap(errors.Wrap(err, url))
+		}
+		err = n.index.SetURLArchive(url, archived)
+		if err != nil {
+			return i, wrap(err)
+		}
+	}
+
+	return len(urls), nil
+}
+
+// newCommandURLArchiver creates a URLArchiver running a shell command, which
+// receives the URL as first argument and prints the archived URL.
+func newCommandURLArchiver(command string) URLArchiver {
+	return func(url string) (string, error) {
+		cmd := exec.CommandFromString(command, url)
+		stderr := bytes.Buffer{}
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = errors.New(msg)
+			}
+			return "", errors.Wrap(err, command)
+		}
+
+		archived := strings.TrimSpace(string(output))
+		if !strutil.IsURL(archived) {
+			return "", fmt.Errorf("%s: the archive command didn't print a URL, got: %s", command, archived)
+		}
+		return archived, nil
+	}
+}
+
+// ReplaceDeadURLs replaces the dead external links of the notes with their
+// archived version, when one is known.
+//
+// Only the notes with replaced links are returned.
+func (n *Notebook) ReplaceDeadURLs(origin AuditOrigin, dryRun bool) ([]NoteReplacement, error) {
+	wrap := errors.Wrapper("failed to replace the dead URLs")
+
+	deadURLs, err := n.index.FindDeadURLs()
+	if err != nil {
+		return nil, wrap(err)
+	}
+	archived := []URLMetadata{}
+	for _, metadata := range deadURLs {
+		if metadata.Archive != "" {
+			archived = append(archived, metadata)
+		}
+	}
+	if len(archived) == 0 {
+		return []NoteReplacement{}, nil
+	}
+
+	notes, err := n.FindMinimalNotes(NoteFindOpts{})
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	replacements := []NoteReplacement{}
+	contents := map[string]string{}
+	for _, note := range notes {
+		content, err := n.fs.Read(filepath.Join(n.Path, note.Path))
+		if err != nil {
+			return nil, wrap(err)
+		}
+
+		newContent := string(content)
+		replacement := NoteReplacement{Path: note.Path, Changes: []TextChange{}}
+		for _, metadata := range archived {
+			if !strings.Contains(newContent, metadata.URL) {
+				continue
+			}
+			var res NoteReplacement
+			newContent, res = replaceInNote(newContent, nil, ReplaceOpts{
+				Pattern:      deadURLPattern(metadata.URL),
+				Replacement:  "${1}" + strings.ReplaceAll(metadata.Archive, "$", "$$") + "${2}",
+				IncludeLinks: true,
+				IncludeTags:  true,
+			}, n.Config.Format.Markdown)
+			replacement.Changes = mergeTextChanges(replacement.Changes, res.Changes)
+		}
+
+		if len(replacement.Changes) > 0 {
+			replacements = append(replacements, replacement)
+			contents[note.Path] = newContent
+		}
+	}
+
+	if dryRun || len(contents) == 0 {
+		return replacements, nil
+	}
+
+	paths, err := n.writeReplacedNotes(contents)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	n.audit(origin, AuditOperationReplace,
+		fmt.Sprintf("dead URLs -> archived versions in %d %s", len(paths), strutil.Pluralize("note", len(paths))),
+		paths...,
+	)
+
+	return replacements, nil
+}
+
+// deadURLPattern matches the given URL when it is not part of a longer URL,
+// such as its archived version.
+func deadURLPattern(url string) *regexp.Regexp {
+	return regexp.MustCompile(`(^|[\s(<\["'])` + regexp.QuoteMeta(url) + `($|[\s)>\]"'])`)
+}
+
+// mergeTextChanges adds the changes of a new replacement pass to the
+// previous ones, merging those modifying the same line.
+func mergeTextChanges(changes []TextChange, newChanges []TextChange) []TextChange {
+	for _, newChange := range newChanges {
+		merged := false
+		for i, change := range changes {
+			if change.Line == newChange.Line {
+				changes[i].New = newChange.New
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			changes = append(changes, newChange)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Line < changes[j].Line
+	})
+	return changes
+}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	neturl "net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/errors"
+)
+
+// URLChecker requests an external URL, e.g. with an HTTP HEAD request, and
+// returns the HTTP status code of the response.
+type URLChecker func(url string) (int, error)
+
+// ChecksURL returns whether the given external URL is allowed by the host
+// lists of the configuration.
+func (c URLCheckConfig) ChecksURL(url string) bool {
+	u, err := neturl.Parse(url)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if matchesHosts(host, c.DenyHosts) {
+		return false
+	}
+	return len(c.AllowHosts) == 0 || matchesHosts(host, c.AllowHosts)
+}
+
+// matchesHosts returns whether the host is one of the given hosts, or one of
+// their subdomains.
+func matchesHosts(host string, hosts []string) bool {
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(h), "*."))
+		if h != "" && (host == h || strings.HasSuffix(host, "."+h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURLs checks whether the external URLs found in the notes are still
+// reachable, and caches their HTTP status for the dead URLs lint rule.
+//
+// The URLs checked recently are skipped, as well as the hosts excluded by
+// the configuration. Returns the checked URLs.
+func (n *Notebook) CheckURLs(check URLChecker) ([]URLMetadata, error) {
+	wrap := errors.Wrapper("failed to check the URLs")
+	config := n.Config.Lint.URLs
+
+	found, err := n.index.FindUnfetchedURLs(time.Now().Add(-config.MaxAge), -1)
+	if err != nil {
+		return nil, wrap(err)
+	}
+	urls := []string{}
+	for _, url := range found {
+		if config.ChecksURL(url) {
+			urls = append(urls, url)
+		}
+	}
+
+	checked := checkURLs(urls, check, config.Concurrency, config.HostDelay)
+	for _, result := range checked {
+		if result.err != nil {
+			n.logger.Printf("failed to check %s: %v", result.metadata.URL, result.err)
+		}
+		metadata := result.metadata
+		// The title and archive fetched previously are kept, as long as the
+		// URL is still alive.
+		cached, err := n.index.FindURLMetadata(metadata.URL)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		if cached != nil && !metadata.IsDead() {
+			metadata.Title = cached.Title
+		}
+
+		err = n.index.SetURLMetadata(metadata)
+		if err != nil {
+			return nil, wrap(err)
+		}
+	}
+
+	res := []URLMetadata{}
+	for _, result := range checked {
+		res = append(res, result.metadata)
+	}
+	return res, nil
+}
+
+type urlCheckResult struct {
+	metadata URLMetadata
+	err      error
+}
+
+// checkURLs checks the given URLs with at most concurrency requests at the
+// same time, waiting for hostDelay between two requests to the same host.
+//
+// The results are sorted by URL.
+func checkURLs(urls []string, check URLChecker, concurrency int, hostDelay time.Duration) []urlCheckResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	limiter := newHostLimiter(hostDelay)
+	jobs := make(chan string)
+	results := make(chan urlCheckResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range jobs {
+				limiter.Wait(url)
+				// Unreachable URLs are cached with a 0 status.
+				status, err := check(url)
+				if err != nil {
+					status = 0
+				}
+				results <- urlCheckResult{
+					metadata: URLMetadata{URL: url, Status: status, Fetched: time.Now().UTC()},
+					err:      err,
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for _, url := range urls {
+			jobs <- url
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	checked := []urlCheckResult{}
+	for result := range results {
+		checked = append(checked, result)
+	}
+	sort.Slice(checked, func(i, j int) bool {
+		return checked[i].metadata.URL < checked[j].metadata.URL
+	})
+	return checked
+}
+
+// hostLimiter spaces out the requests sent to the same host.
+type hostLimiter struct {
+	delay time.Duration
+	mutex sync.Mutex
+	// Earliest time of the next request, by host.
+	next map[string]time.Time
+}
+
+func newHostLimiter(delay time.Duration) *hostLimiter {
+	return &hostLimiter{
+		delay: delay,
+		next:  map[string]time.Time{},
+	}
+}
+
+// Wait blocks until a request to the host of the given URL is allowed.
+func (l *hostLimiter) Wait(url string) {
+	if l.delay <= 0 {
+		return
+	}
+	host := url
+	if u, err := neturl.Parse(url); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+
+	l.mutex.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.delay)
+	l.mutex.Unlock()
+
+	time.Sleep(at.Sub(now))
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestURLCheckConfigChecksURL(t *testing.T) {
+	test := func(config URLCheckConfig, url string, expected bool) {
+		t.Helper()
+		assert.Equal(t, config.ChecksURL(url), expected)
+	}
+
+	all := URLCheckConfig{}
+	test(all, "https://example.com/page", true)
+	test(all, "not a url", false)
+	test(all, "mailto:me@example.com", false)
+
+	allow := URLCheckConfig{AllowHosts: []string{"example.com", "*.wikipedia.org"}}
+	test(allow, "https://example.com/page", true)
+	test(allow, "https://www.EXAMPLE.com/page", true)
+	test(allow, "https://notexample.com/page", false)
+	test(allow, "https://en.wikipedia.org/wiki/Pizza", true)
+	test(allow, "https://github.com", false)
+
+	deny := URLCheckConfig{
+		AllowHosts: []string{"example.com"},
+		DenyHosts:  []string{"private.example.com", "localhost"},
+	}
+	test(deny, "https://example.com/page", true)
+	test(deny, "https://private.example.com/page", false)
+	test(deny, "https://a.private.example.com/page", false)
+	test(deny, "http://localhost:8080", false)
+}
+
+func TestCheckURLs(t *testing.T) {
+	var mutex sync.Mutex
+	running, maxRunning := 0, 0
+	check := func(url string) (int, error) {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+
+		switch url {
+		case "https://b.com/404":
+			return 404, nil
+		case "https://c.com":
+			return 0, errors.New("timeout")
+		default:
+			return 200, nil
+		}
+	}
+
+	results := checkURLs([]string{"https://c.com", "https://a.com", "https://b.com/404", "https://d.com", "https://e.com"}, check, 2, 0)
+
+	assert.Equal(t, maxRunning, 2)
+	statuses := map[string]int{}
+	urls := []string{}
+	for _, result := range results {
+		urls = append(urls, result.metadata.URL)
+		statuses[result.metadata.URL] = result.metadata.Status
+		assert.Equal(t, result.err != nil, result.metadata.URL == "https://c.com")
+	}
+	assert.Equal(t, urls, []string{"https://a.com", "https://b.com/404", "https://c.com", "https://d.com", "https://e.com"})
+	assert.Equal(t, statuses, map[string]int{
+		"https://a.com":     200,
+		"https://b.com/404": 404,
+		"https://c.com":     0,
+		"https://d.com":     200,
+		"https://e.com":     200,
+	})
+}
+
+func TestCheckURLsSpacesOutTheRequestsToAHost(t *testing.T) {
+	var mutex sync.Mutex
+	times := []time.Time{}
+	check := func(url string) (int, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		times = append(times, time.Now())
+		return 200, nil
+	}
+
+	start := time.Now()
+	checkURLs([]string{"https://a.com/1", "https://a.com/2", "https://a.com/3"}, check, 3, 50*time.Millisecond)
+
+	assert.Equal(t, len(times), 3)
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+}
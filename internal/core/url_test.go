@@ -0,0 +1,45 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestDeadURLPattern(t *testing.T) {
+	test := func(content string, expected string) {
+		actual, _ := replaceInNote(content, nil, ReplaceOpts{
+			Pattern:      deadURLPattern("https://example.com/page"),
+			Replacement:  "${1}https://web.archive.org/web/1/https://example.com/page${2}",
+			IncludeLinks: true,
+			IncludeTags:  true,
+		}, NewDefaultConfig().Format.Markdown)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("No URL", "No URL")
+	test("https://example.com/page", "https://web.archive.org/web/1/https://example.com/page")
+	test("See [a page](https://example.com/page).", "See [a page](https://web.archive.org/web/1/https://example.com/page).")
+	test("See <https://example.com/page> now", "See <https://web.archive.org/web/1/https://example.com/page> now")
+	// Longer URLs are left untouched.
+	test("https://example.com/page/2", "https://example.com/page/2")
+	test("https://web.archive.org/web/1/https://example.com/page", "https://web.archive.org/web/1/https://example.com/page")
+}
+
+func TestMergeTextChanges(t *testing.T) {
+	actual := mergeTextChanges(
+		[]TextChange{
+			{Line: 1, Old: "a b", New: "A b"},
+			{Line: 3, Old: "c", New: "C"},
+		},
+		[]TextChange{
+			{Line: 2, Old: "d", New: "D"},
+			{Line: 1, Old: "A b", New: "A B"},
+		},
+	)
+	assert.Equal(t, actual, []TextChange{
+		{Line: 1, Old: "a b", New: "A B"},
+		{Line: 2, Old: "d", New: "D"},
+		{Line: 3, Old: "c", New: "C"},
+	})
+}
@@ -1,9 +1,12 @@
 package date
 
 import (
+	"regexp"
 	"strconv"
 	"time"
 
+	"github.com/lestrrat-go/strftime"
+	"github.com/rvflash/elapsed"
 	"github.com/tj/go-naturaldate"
 )
 
@@ -36,7 +39,12 @@ func (n *Frozen) Date() time.Time {
 	return n.date
 }
 
+var isoWeekRegex = regexp.MustCompile(`^(\d{4})-?[Ww](\d{2})$`)
+
 // TimeFromNatural parses a human date into a time.Time.
+//
+// Besides the natural expressions, e.g. "next monday", it supports ISO dates
+// (2024-03-18) and weeks (2024-W12, which starts on Monday).
 func TimeFromNatural(date string) (time.Time, error) {
 	if date == "" {
 		return time.Now(), nil
@@ -44,5 +52,68 @@ func TimeFromNatural(date string) (time.Time, error) {
 	if i, err := strconv.ParseInt(date, 10, 0); err == nil && i >= 1000 && i < 5000 {
 		return time.Date(int(i), time.January, 0, 0, 0, 0, 0, time.UTC), nil
 	}
+	if t, err := time.Parse("2006-01-02", date); err == nil {
+		return t, nil
+	}
+	if match := isoWeekRegex.FindStringSubmatch(date); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		week, _ := strconv.Atoi(match[2])
+		if week >= 1 && week <= 53 {
+			return startOfISOWeek(year, week), nil
+		}
+	}
 	return naturaldate.Parse(date, time.Now().UTC(), naturaldate.WithDirection(naturaldate.Past))
 }
+
+// startOfISOWeek returns the Monday of the given ISO week.
+func startOfISOWeek(year int, week int) time.Time {
+	// January 4th is always in the first ISO week.
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	weekday := (int(jan4.Weekday()) + 6) % 7
+	return jan4.AddDate(0, 0, (week-1)*7-weekday)
+}
+
+// Format formats a date with one of the named styles: short, medium, long,
+// full, year, time, timestamp, timestamp-unix, elapsed, or with a custom
+// strftime format.
+func Format(date time.Time, format string) (string, error) {
+	format = findFormat(format)
+	if format == "elapsed" {
+		return elapsed.Time(date), nil
+	}
+	return strftime.Format(format, date, strftime.WithUnixSeconds('s'))
+}
+
+var (
+	shortFormat         = `%m/%d/%Y`
+	mediumFormat        = `%b %d, %Y`
+	longFormat          = `%B %d, %Y`
+	fullFormat          = `%A, %B %d, %Y`
+	yearFormat          = `%Y`
+	timeFormat          = `%H:%M`
+	timestampFormat     = `%Y%m%d%H%M`
+	timestampUnixFormat = `%s`
+)
+
+func findFormat(key string) string {
+	switch key {
+	case "short":
+		return shortFormat
+	case "medium":
+		return mediumFormat
+	case "long":
+		return longFormat
+	case "full":
+		return fullFormat
+	case "year":
+		return yearFormat
+	case "time":
+		return timeFormat
+	case "timestamp":
+		return timestampFormat
+	case "timestamp-unix":
+		return timestampUnixFormat
+	default:
+		return key
+	}
+}
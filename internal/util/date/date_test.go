@@ -0,0 +1,37 @@
+package date
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestTimeFromNaturalISO(t *testing.T) {
+	test := func(date string, expected string) {
+		actual, err := TimeFromNatural(date)
+		assert.Nil(t, err)
+		assert.Equal(t, actual.Format("2006-01-02"), expected)
+	}
+
+	test("2024-03-18", "2024-03-18")
+	test("2024-W12", "2024-03-18")
+	test("2024W01", "2024-01-01")
+	test("2024-w12", "2024-03-18")
+	test("2021-W01", "2021-01-04")
+	test("2020-W53", "2020-12-28")
+}
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2009, 11, 17, 20, 34, 58, 0, time.UTC)
+	test := func(format string, expected string) {
+		actual, err := Format(date, format)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("%Y-%m-%d", "2009-11-17")
+	test("short", "11/17/2009")
+	test("full", "Tuesday, November 17, 2009")
+	test("timestamp-unix", "1258490098")
+}
@@ -0,0 +1,20 @@
+package exec
+
+import (
+	"os"
+	"strconv"
+)
+
+// argsEnv returns the environment of a command receiving the given
+// arguments, which are exposed as the ZK_ARG1, ZK_ARG2… variables on every
+// platform. The environment is inherited when there are no arguments.
+func argsEnv(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	env := os.Environ()
+	for i, arg := range args {
+		env = append(env, "ZK_ARG"+strconv.Itoa(i+1)+"="+arg)
+	}
+	return env
+}
@@ -8,11 +8,14 @@ import (
 )
 
 // CommandFromString returns a Cmd running the given command with $SHELL.
+// The arguments are given as the positional parameters $1, $2…, and as the
+// ZK_ARG1, ZK_ARG2… environment variables.
 func CommandFromString(command string, args ...string) *exec.Cmd {
 	shell := os.Getenv("SHELL")
 	if len(shell) == 0 {
 		shell = "sh"
 	}
-	args = append([]string{"-c", command, "--"}, args...)
-	return exec.Command(shell, args...)
+	cmd := exec.Command(shell, append([]string{"-c", command, "--"}, args...)...)
+	cmd.Env = argsEnv(args)
+	return cmd
 }
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package exec
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestCommandFromString(t *testing.T) {
+	test := func(command string, args []string, expected string) {
+		t.Helper()
+		output, err := CommandFromString(command, args...).Output()
+		assert.Nil(t, err)
+		assert.Equal(t, string(output), expected)
+	}
+
+	test(`printf "%s"`, []string{}, "")
+	test(`printf "%s|%s" "$1" "$2"`, []string{"a b", "c"}, "a b|c")
+	test(`printf "%s|%s" "$ZK_ARG1" "$ZK_ARG2"`, []string{"a b", "c"}, "a b|c")
+	test(`printf "%s" "$ZK_ARG1"`, []string{}, "")
+}
+
+func TestArgsEnv(t *testing.T) {
+	assert.True(t, argsEnv([]string{}) == nil)
+
+	env := argsEnv([]string{"one", "two=2"})
+	assert.Equal(t, env[len(env)-2:], []string{"ZK_ARG1=one", "ZK_ARG2=two=2"})
+}
@@ -6,9 +6,12 @@ import (
 	"syscall"
 )
 
-// CommandFromString returns a Cmd running the given command.
-func CommandFromString(command string) *exec.Cmd {
+// CommandFromString returns a Cmd running the given command. cmd.exe has no
+// positional parameters, so the arguments are only given as the ZK_ARG1,
+// ZK_ARG2… environment variables, e.g. %ZK_ARG1%.
+func CommandFromString(command string, args ...string) *exec.Cmd {
 	cmd := exec.Command("cmd")
+	cmd.Env = argsEnv(args)
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		HideWindow:    false,
 		CmdLine:       fmt.Sprintf(` /v:on/s/c "%s"`, command),
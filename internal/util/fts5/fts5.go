@@ -4,7 +4,20 @@ import "strings"
 
 // ConvertQuery transforms a Google-like query into a SQLite FTS5 one.
 func ConvertQuery(query string) string {
+	out, _ := convertQuery(query)
+	return out
+}
+
+// ColumnFilters returns the names of the columns the query is restricted to,
+// e.g. title for `title: foo`.
+func ColumnFilters(query string) []string {
+	_, columns := convertQuery(query)
+	return columns
+}
+
+func convertQuery(query string) (string, []string) {
 	out := ""
+	columns := []string{}
 
 	// List of tokens which won't be automatically quoted in the output query.
 	passthroughTokens := map[string]bool{
@@ -71,6 +84,9 @@ func ConvertQuery(query string) string {
 		//   col:foo -> col:"foo"
 		case !inQuote && c == ':':
 			out += term + string(c)
+			if term != "" {
+				columns = append(columns, term)
+			}
 			term = ""
 
 		// - is an alias to NOT, but only at the start of a term, to allow
@@ -99,5 +115,5 @@ func ConvertQuery(query string) string {
 	}
 
 	closeTerm()
-	return out
+	return out, columns
 }
@@ -82,3 +82,14 @@ func TestConvertQuery(t *testing.T) {
 	// NEAR is not supported
 	test(`NEAR(foo, bar, 4)`, `"NEAR"("foo," "bar," "4")`)
 }
+
+func TestColumnFilters(t *testing.T) {
+	test := func(query string, expected []string) {
+		assert.Equal(t, ColumnFilters(query), expected)
+	}
+
+	test(`foo bar`, []string{})
+	test(`"col:foo"`, []string{})
+	test(`col:foo bar`, []string{"col"})
+	test(`title:foo OR -body:(bar qux)`, []string{"title", "body"})
+}
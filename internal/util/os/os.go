@@ -29,6 +29,13 @@ func ReadStdinPipe() (opt.String, error) {
 	return opt.NewNotEmptyString(string(bytes)), nil
 }
 
+// ReadStdin returns the whole content of the standard input, which can be a
+// pipe or a redirected file.
+func ReadStdin() (string, error) {
+	bytes, err := ioutil.ReadAll(bufio.NewReader(os.Stdin))
+	return string(bytes), err
+}
+
 // Getenv returns an optional String for the environment variable with given
 // key.
 func GetOptEnv(key string) opt.String {
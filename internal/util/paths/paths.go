@@ -11,6 +11,8 @@ import (
 type Metadata struct {
 	Path     string
 	Modified time.Time
+	// SHA-256 checksum of the file content, when known.
+	Checksum string
 }
 
 // Exists returns whether the given path exists on the file system.
@@ -76,3 +78,17 @@ func WriteString(path string, content string) error {
 	_, err = f.WriteString(content)
 	return err
 }
+
+// ExpandHome replaces the leading ~ of the given path with the home directory
+// of the user. The path is returned unchanged when the home directory is
+// unknown.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,19 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	assert.Nil(t, err)
+
+	assert.Equal(t, ExpandHome("~"), home)
+	assert.Equal(t, ExpandHome("~/notes"), filepath.Join(home, "notes"))
+	assert.Equal(t, ExpandHome("/notes/~"), "/notes/~")
+	assert.Equal(t, ExpandHome("~other/notes"), "~other/notes")
+}
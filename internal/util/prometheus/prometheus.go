@@ -0,0 +1,53 @@
+// Package prometheus renders metrics in the Prometheus text exposition
+// format.
+package prometheus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Writer renders metrics in the Prometheus text exposition format.
+type Writer struct {
+	out strings.Builder
+}
+
+// Label is a dimension of a metric sample.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Header writes the description and type of a metric, e.g. gauge or counter,
+// before its samples.
+func (w *Writer) Header(name string, help string, kind string) {
+	fmt.Fprintf(&w.out, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(&w.out, "# TYPE %s %s\n", name, kind)
+}
+
+// Sample writes a value of the given metric, with its labels.
+func (w *Writer) Sample(name string, labels []Label, value float64) {
+	w.out.WriteString(name)
+	if len(labels) > 0 {
+		pairs := []string{}
+		for _, label := range labels {
+			pairs = append(pairs, fmt.Sprintf(`%s="%s"`, label.Name, EscapeLabel(label.Value)))
+		}
+		w.out.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	w.out.WriteString(" " + strconv.FormatFloat(value, 'f', -1, 64) + "\n")
+}
+
+// String returns the metrics written so far.
+func (w *Writer) String() string {
+	return w.out.String()
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// EscapeLabel escapes the backslashes, double quotes and line feeds of a
+// label value.
+func EscapeLabel(value string) string {
+	return labelReplacer.Replace(value)
+}
@@ -0,0 +1,25 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestWriter(t *testing.T) {
+	w := Writer{}
+	w.Header("zk_notes", "Number of notes.", "gauge")
+	w.Sample("zk_notes", nil, 42)
+	w.Sample("zk_notes", []Label{{"notebook", "/notes"}, {"dir", "a"}}, 1.5)
+
+	assert.Equal(t, w.String(), `# HELP zk_notes Number of notes.
+# TYPE zk_notes gauge
+zk_notes 42
+zk_notes{notebook="/notes",dir="a"} 1.5
+`)
+}
+
+func TestEscapeLabel(t *testing.T) {
+	assert.Equal(t, EscapeLabel("plain"), "plain")
+	assert.Equal(t, EscapeLabel(`C:\notes "a"`+"\nb"), `C:\\notes \"a\"\nb`)
+}
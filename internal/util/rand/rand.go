@@ -1,6 +1,8 @@
 package rand
 
 import (
+	"encoding/binary"
+	"math/big"
 	"math/rand"
 	"time"
 	"unicode"
@@ -11,6 +13,13 @@ import (
 // NewIDGenerator returns a function generating string IDs using the given options.
 // Inspired by https://www.calhoun.io/creating-random-strings-in-go/
 func NewIDGenerator(options core.IDOptions) func() string {
+	switch options.Strategy {
+	case core.IDStrategyULID:
+		return newULIDGenerator()
+	case core.IDStrategyKSUID:
+		return newKSUIDGenerator()
+	}
+
 	if options.Length < 1 {
 		panic("IDOptions.Length must be at least 1")
 	}
@@ -41,3 +50,71 @@ func NewIDGenerator(options core.IDOptions) func() string {
 		return string(buf)
 	}
 }
+
+// ulidEncoding is the Crockford's base32 alphabet used by ULIDs.
+const ulidEncoding = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULIDGenerator returns a function generating ULIDs, which are sortable by
+// creation date.
+// See https://github.com/ulid/spec
+func newULIDGenerator() func() string {
+	rand := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	return func() string {
+		entropy := make([]byte, 10)
+		rand.Read(entropy)
+		return ulid(time.Now(), entropy)
+	}
+}
+
+// ulid encodes the 48-bit timestamp in milliseconds and the 80 bits of
+// entropy of a ULID.
+func ulid(date time.Time, entropy []byte) string {
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data[:8], uint64(date.Unix()*1000+int64(date.Nanosecond())/int64(time.Millisecond))<<16)
+	copy(data[6:], entropy)
+	return encode(data, ulidEncoding, 26)
+}
+
+// ksuidEncoding is the base62 alphabet used by KSUIDs.
+const ksuidEncoding = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ksuidEpoch is the start of the KSUID timestamps, in Unix time.
+const ksuidEpoch = 1400000000
+
+// newKSUIDGenerator returns a function generating KSUIDs, which are sortable
+// by creation date.
+// See https://github.com/segmentio/ksuid
+func newKSUIDGenerator() func() string {
+	rand := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	return func() string {
+		payload := make([]byte, 16)
+		rand.Read(payload)
+		return ksuid(time.Now(), payload)
+	}
+}
+
+// ksuid encodes the 32-bit timestamp in seconds and the 128 bits of payload
+// of a KSUID.
+func ksuid(date time.Time, payload []byte) string {
+	data := make([]byte, 20)
+	binary.BigEndian.PutUint32(data[:4], uint32(date.Unix()-ksuidEpoch))
+	copy(data[4:], payload)
+	return encode(data, ksuidEncoding, 27)
+}
+
+// encode writes the big-endian number in data with the given alphabet,
+// left-padded to length.
+func encode(data []byte, alphabet string, length int) string {
+	n := new(big.Int).SetBytes(data)
+	base := big.NewInt(int64(len(alphabet)))
+	digit := new(big.Int)
+
+	buf := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		n.DivMod(n, base, digit)
+		buf[i] = alphabet[digit.Int64()]
+	}
+	return string(buf)
+}
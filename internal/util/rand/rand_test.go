@@ -0,0 +1,42 @@
+package rand
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/mickael-menu/zk/internal/core"
+	"github.com/mickael-menu/zk/internal/util/test/assert"
+)
+
+func TestRandomID(t *testing.T) {
+	id := NewIDGenerator(core.IDOptions{
+		Strategy: core.IDStrategyRandom,
+		Length:   8,
+		Charset:  core.CharsetHex,
+		Case:     core.CaseUpper,
+	})()
+	assert.Equal(t, len(id), 8)
+	assert.Equal(t, len(bytes.Trim([]byte(id), "0123456789ABCDEF")), 0)
+}
+
+func TestULID(t *testing.T) {
+	date := time.Unix(0, 1469918176385*int64(time.Millisecond))
+	assert.Equal(t, ulid(date, make([]byte, 10)), "01ARYZ6S410000000000000000")
+	assert.Equal(t, ulid(time.Unix((1<<48-1)/1000, (1<<48-1)%1000*int64(time.Millisecond)), bytes.Repeat([]byte{0xff}, 10)), "7ZZZZZZZZZZZZZZZZZZZZZZZZZ")
+
+	gen := NewIDGenerator(core.IDOptions{Strategy: core.IDStrategyULID})
+	first := gen()
+	time.Sleep(2 * time.Millisecond)
+	second := gen()
+	assert.Equal(t, len(first), 26)
+	assert.True(t, first < second)
+}
+
+func TestKSUID(t *testing.T) {
+	assert.Equal(t, ksuid(time.Unix(ksuidEpoch, 0), make([]byte, 16)), "000000000000000000000000000")
+	assert.Equal(t, ksuid(time.Unix(ksuidEpoch+1<<32-1, 0), bytes.Repeat([]byte{0xff}, 16)), "aWgEPTl1tmebfsQzFP4bxwgy80V")
+
+	id := NewIDGenerator(core.IDOptions{Strategy: core.IDStrategyKSUID})()
+	assert.Equal(t, len(id), 27)
+}
@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 // Prepend prefixes each lines of a string with the given prefix.
@@ -109,3 +110,37 @@ func ExpandWhitespaceLiterals(s string) string {
 	s = strings.ReplaceAll(s, `\t`, "\t")
 	return s
 }
+
+// TrigramSimilarity returns how similar two strings are, from 0 to 1, by
+// comparing their sets of trigrams. The case and punctuation are ignored.
+func TrigramSimilarity(a, b string) float64 {
+	aTrigrams := trigrams(a)
+	bTrigrams := trigrams(b)
+	if len(aTrigrams) == 0 || len(bTrigrams) == 0 {
+		return 0
+	}
+
+	common := 0
+	for trigram := range aTrigrams {
+		if bTrigrams[trigram] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(aTrigrams)+len(bTrigrams)-common)
+}
+
+// trigrams returns the set of trigrams of each word in s, padded to match
+// the start and end of the words as well.
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = true
+		}
+	}
+	return set
+}
@@ -115,3 +115,19 @@ func TestExpandWhitespaceLiterals(t *testing.T) {
 	test(`nothing`, "nothing")
 	test(`newline\ntab\t`, "newline\ntab\t")
 }
+
+func TestTrigramSimilarity(t *testing.T) {
+	test := func(a, b string, expected float64) {
+		assert.Equal(t, TrigramSimilarity(a, b), expected)
+	}
+
+	test("", "", 0)
+	test("note", "", 0)
+	test("!!", "note", 0)
+	test("note", "note", 1)
+	// The case and punctuation are ignored.
+	test("My Note", "my-note", 1)
+	test("my_note!", "My note", 1)
+	test("abc", "xyz", 0)
+	test("note", "notes", 4.0/7.0)
+}
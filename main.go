@@ -13,23 +13,59 @@ import (
 	"github.com/mickael-menu/zk/internal/cli/cmd"
 	"github.com/mickael-menu/zk/internal/core"
 	executil "github.com/mickael-menu/zk/internal/util/exec"
+	"github.com/mickael-menu/zk/internal/util/paths"
 )
 
 var Version = "dev"
 var Build = "dev"
 
 var root struct {
-	Init  cmd.Init  `cmd group:"zk" help:"Create a new notebook in the given directory."`
-	Index cmd.Index `cmd group:"zk" help:"Index the notes to be searchable."`
-
-	New  cmd.New  `cmd group:"notes" help:"Create a new note in the given notebook directory."`
-	List cmd.List `cmd group:"notes" help:"List notes matching the given criteria."`
-	Edit cmd.Edit `cmd group:"notes" help:"Edit notes matching the given criteria."`
-	Tag  cmd.Tag  `cmd group:"notes" help:"Manage the note tags."`
+	Init            cmd.Init            `cmd group:"zk" help:"Create a new notebook in the given directory."`
+	Index           cmd.Index           `cmd group:"zk" help:"Index the notes to be searchable."`
+	Lint            cmd.Lint            `cmd group:"zk" help:"Check the notebook for problems, e.g. dead links."`
+	Manifest        cmd.Manifest        `cmd group:"zk" help:"Write or verify the checksums of the notes, e.g. after a sync."`
+	Maintenance     cmd.Maintenance     `cmd group:"zk" help:"Perform the periodic maintenance of the notebook, e.g. purge the trash."`
+	Stats           cmd.Stats           `cmd group:"zk" help:"Print metrics about the notebook, e.g. for Prometheus."`
+	Serve           cmd.Serve           `cmd group:"zk" help:"Serve local HTTP endpoints, e.g. to capture web pages from a browser."`
+	Events          cmd.Events          `cmd group:"zk" help:"Print the changes of the notebook index, e.g. to react to them."`
+	SuggestMocs     cmd.SuggestMocs     `cmd group:"zk" help:"Suggest maps of content for the clusters of notes lacking a hub note."`
+	Query           cmd.Query           `cmd group:"zk" help:"Run a read-only SQL query on the notebook index."`
+	Trust           cmd.Trust           `cmd group:"zk" help:"Allow the notebook to run the shell commands defined in its config and templates."`
+	MigrateLinks    cmd.MigrateLinks    `cmd group:"zk" help:"Convert the links of the notebook to another syntax or path style."`
+	FixLinks        cmd.FixLinks        `cmd group:"zk" help:"Fix all the dead links of the notebook, by retargeting them or creating stub notes."`
+	UpgradeNotebook cmd.UpgradeNotebook `cmd group:"zk" help:"Rewrite the settings and templates of the notebook deprecated by previous versions of zk."`
+	VerifyLinks     cmd.VerifyLinks     `cmd group:"zk" help:"Report the dead links of the notebook, and fix them interactively."`
+
+	New               cmd.New               `cmd group:"notes" help:"Create a new note in the given notebook directory."`
+	Capture           cmd.Capture           `cmd group:"notes" help:"Create a new note from the standard input, e.g. an email."`
+	ExtractHighlights cmd.ExtractHighlights `cmd group:"notes" help:"Create a note for each passage quoted or highlighted in a literature note."`
+	List              cmd.List              `cmd group:"notes" help:"List notes matching the given criteria."`
+	Edit              cmd.Edit              `cmd group:"notes" help:"Edit notes matching the given criteria."`
+	Browse            cmd.Browse            `cmd group:"notes" help:"Search and preview the notes interactively, with their backlinks."`
+	Mv                cmd.Mv                `cmd group:"notes" help:"Move or rename a note, updating the links to it."`
+	Rm                cmd.Rm                `cmd group:"notes" help:"Delete a note, reporting or rewriting the links to it."`
+	Relink            cmd.Relink            `cmd group:"notes" help:"Retarget the links to a note to another note."`
+	Merge             cmd.Merge             `cmd group:"notes" help:"Merge several notes into one, updating the links to them."`
+	Restore           cmd.Restore           `cmd group:"notes" help:"Restore a note from the trash."`
+	Archive           cmd.Archive           `cmd group:"notes" help:"Move notes to the archive directory, hidden from the searches."`
+	Replace           cmd.Replace           `cmd group:"notes" help:"Find and replace text in the notes matching the given criteria."`
+	TOC               cmd.TOC               `cmd group:"notes" name:"toc" help:"Print or update the table of contents of a note."`
+	Import            cmd.Import            `cmd group:"notes" help:"Import notes from another app, e.g. an Obsidian vault."`
+	Tag               cmd.Tag               `cmd group:"notes" help:"Manage the note tags."`
+	Task              cmd.Task              `cmd group:"notes" help:"Manage the tasks found in the notes."`
+	Review            cmd.Review            `cmd group:"notes" help:"Review the notes of the spaced repetition queue."`
+	Related           cmd.Related           `cmd group:"notes" help:"List the notes related to a note, which are not linked to it yet."`
+	Resolve           cmd.Resolve           `cmd group:"notes" help:"Print the note targeted by a link href, an ID or a title."`
+	ID                cmd.ID                `cmd group:"notes" name:"id" help:"Print the ID of a note."`
+	Log               cmd.Log               `cmd group:"notes" help:"Show the history of changes made to the notebook."`
+	Assets            cmd.Assets            `cmd group:"notes" help:"List the attachments of the notebook, e.g. images."`
+	Publish           cmd.Publish           `cmd group:"notes" help:"Generate a static website from the notes."`
 
 	NotebookDir string  `type:path placeholder:PATH help:"Turn off notebook auto-discovery and set manually the notebook where commands are run."`
 	WorkingDir  string  `short:W type:path placeholder:PATH help:"Run as if zk was started in <PATH> instead of the current working directory."`
 	NoInput     NoInput `help:"Never prompt or ask for confirmation."`
+	ErrorFormat string  `placeholder:FORMAT help:"Format of the error printed on failure, among: text, json."`
+	Profile     string  `placeholder:NAME help:"Use the settings of the given profile from the [profile] config section. Defaults to $ZK_PROFILE."`
 
 	ShowHelp ShowHelp         `cmd hidden default:"1"`
 	LSP      cmd.LSP          `cmd hidden`
@@ -59,17 +95,32 @@ func (cmd *ShowHelp) Run(container *cli.Container) error {
 	return ctx.Run(container)
 }
 
+// errorFormat is the format of the error printed on failure, set with the
+// --error-format flag.
+var errorFormat = "text"
+
 func main() {
 	args := os.Args[1:]
 
+	var err error
+	errorFormat, args, err = parseErrorFormat(args)
+	fatalIfError(err)
+
+	profile, args, err := parseProfile(args)
+	fatalIfError(err)
+
 	// Create the dependency graph.
-	container, err := cli.NewContainer(Version)
+	container, err := cli.NewContainer(Version, profile)
 	fatalIfError(err)
 
+	// Prompts are disabled before opening the notebook, which might ask
+	// whether it is trusted.
+	container.Terminal.NoInput = parseNoInput(args)
+
 	// Open the notebook if there's any.
 	dirs, args, err := parseDirs(args)
 	fatalIfError(err)
-	searchDirs, err := notebookSearchDirs(dirs)
+	searchDirs, err := notebookSearchDirs(dirs, container.Config.NotebookDir().Unwrap())
 	fatalIfError(err)
 	err = container.SetCurrentNotebook(searchDirs)
 	fatalIfError(err)
@@ -83,17 +134,24 @@ func main() {
 		ctx, err := parser.Parse(args)
 		fatalIfError(err)
 
-		// Index the current notebook except if the user is running the `index`
-		// command, otherwise it would hide the stats.
-		if ctx.Command() != "index" {
-			if notebook, err := container.CurrentNotebook(); err == nil {
-				_, err = notebook.Index(false)
-				ctx.FatalIfErrorf(err)
+		// Record the running command in the audit log.
+		container.Origin = core.AuditOrigin{
+			Interface: "cli",
+			Command:   commandName(ctx),
+		}
+
+		if notebook, err := container.CurrentNotebook(); err == nil {
+			// Index the current notebook except if the user is running the `index`
+			// command, otherwise it would hide the stats. `stats` reports the age
+			// of the index, which must not be refreshed either.
+			if command := ctx.Command(); command != "index" && command != "stats" {
+				_, err = container.IndexNotebook(notebook, false)
+				fatalIfError(err)
 			}
 		}
 
 		err = ctx.Run(container)
-		ctx.FatalIfErrorf(err)
+		fatalIfError(err)
 	}
 }
 
@@ -122,11 +180,32 @@ func options(container *cli.Container) []kong.Option {
 	}
 }
 
+// commandName returns the name of the command being run, without its
+// positional arguments.
+func commandName(ctx *kong.Context) string {
+	words := []string{}
+	for _, word := range strings.Fields(ctx.Command()) {
+		if !strings.HasPrefix(word, "<") {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// fatalIfError exits with a status matching the kind of err, to let scripts
+// branch on it.
 func fatalIfError(err error) {
-	if err != nil {
+	if err == nil {
+		return
+	}
+
+	status := cli.ExitCode(err)
+	if errorFormat == "json" {
+		fmt.Fprintln(os.Stderr, cli.ErrorJSON(err))
+	} else if status != cli.ExitNoResults {
 		fmt.Fprintf(os.Stderr, "zk: error: %v\n", err)
-		os.Exit(1)
 	}
+	os.Exit(status)
 }
 
 // runAlias will execute a user alias if the command is one of them.
@@ -173,10 +252,11 @@ func runAlias(container *cli.Container, args []string) (bool, error) {
 // which path arguments are relative from.
 //
 // By order of precedence:
-//   1. --notebook-dir flag
-//   2. current working directory
-//   3. ZK_NOTEBOOK_DIR environment variable
-func notebookSearchDirs(dirs cli.Dirs) ([]cli.Dirs, error) {
+//  1. --notebook-dir flag
+//  2. current working directory
+//  3. ZK_NOTEBOOK_DIR environment variable
+//  4. profileNotebookDir, set by the active config profile
+func notebookSearchDirs(dirs cli.Dirs, profileNotebookDir string) ([]cli.Dirs, error) {
 	wd, err := os.Getwd()
 	if err != nil {
 		return nil, err
@@ -212,9 +292,48 @@ func notebookSearchDirs(dirs cli.Dirs) ([]cli.Dirs, error) {
 		candidates = append(candidates, dirs)
 	}
 
+	// 4. notebook-dir of the active profile
+	if profileNotebookDir != "" {
+		dirs := dirs
+		dirs.NotebookDir = paths.ExpandHome(profileNotebookDir)
+		if dirs.WorkingDir == "" {
+			dirs.WorkingDir = dirs.NotebookDir
+		}
+		candidates = append(candidates, dirs)
+	}
+
 	return candidates, nil
 }
 
+// parseProfile returns the config profile given with the --profile flag, or
+// the ZK_PROFILE environment variable.
+//
+// We need to parse this flag before Kong, because the profile is applied when
+// loading the config, before parsing the CLI.
+func parseProfile(args []string) (string, []string, error) {
+	profile := os.Getenv("ZK_PROFILE")
+	newArgs := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			newArgs = append(newArgs, args[i:]...)
+			i = len(args)
+		case arg == "--profile":
+			if i+1 >= len(args) {
+				return profile, newArgs, errors.New("--profile requires a name argument")
+			}
+			i++
+			profile = args[i]
+		case strings.HasPrefix(arg, "--profile="):
+			profile = strings.TrimPrefix(arg, "--profile=")
+		default:
+			newArgs = append(newArgs, arg)
+		}
+	}
+	return profile, newArgs, nil
+}
+
 // parseDirs returns the paths specified with the --notebook-dir and
 // --working-dir flags.
 //
@@ -256,3 +375,51 @@ func parseDirs(args []string) (cli.Dirs, []string, error) {
 
 	return d, args, nil
 }
+
+// parseNoInput returns whether the --no-input flag is given.
+//
+// We need to parse this flag before Kong, to not prompt the user when opening
+// the notebook. The flag is kept in the arguments for Kong.
+func parseNoInput(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "--":
+			return false
+		case "--no-input":
+			return true
+		}
+	}
+	return false
+}
+
+// parseErrorFormat returns the format given with the --error-format flag.
+//
+// We need to parse this flag before Kong, to report the errors occurring
+// before parsing the CLI in the expected format.
+func parseErrorFormat(args []string) (string, []string, error) {
+	format := "text"
+	newArgs := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			newArgs = append(newArgs, args[i:]...)
+			i = len(args)
+		case arg == "--error-format":
+			if i+1 >= len(args) {
+				return format, newArgs, errors.New("--error-format requires a format argument")
+			}
+			i++
+			format = args[i]
+		case strings.HasPrefix(arg, "--error-format="):
+			format = strings.TrimPrefix(arg, "--error-format=")
+		default:
+			newArgs = append(newArgs, arg)
+		}
+	}
+
+	if format != "text" && format != "json" {
+		return "text", newArgs, fmt.Errorf("%s: unknown error format, try text or json", format)
+	}
+	return format, newArgs, nil
+}